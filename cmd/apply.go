@@ -2,23 +2,36 @@
 package cmd
 
 import (
-	"bufio"
+	"context"
 	"fmt"
 	"os"
-	"strings"
+	"path/filepath"
+	"strconv"
+	"time"
+	"unicode/utf8"
 
 	"github.com/spf13/cobra"
 
+	"github.com/kreigan/powerdns-zone-manager/internal/annotation"
 	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/confirm"
+	"github.com/kreigan/powerdns-zone-manager/internal/history"
+	"github.com/kreigan/powerdns-zone-manager/internal/i18n"
 	"github.com/kreigan/powerdns-zone-manager/internal/logger"
 	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+	"github.com/kreigan/powerdns-zone-manager/internal/manifest"
+	"github.com/kreigan/powerdns-zone-manager/internal/policy"
 	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+	"github.com/kreigan/powerdns-zone-manager/internal/report"
+	"github.com/kreigan/powerdns-zone-manager/internal/settings"
+	"github.com/kreigan/powerdns-zone-manager/pkg/output"
 )
 
 var applyCmd = &cobra.Command{
 	Use:   "apply [config-file]",
 	Short: "Apply zone configuration from YAML file",
 	Long: `Apply zone and record configuration from a YAML file.
+Use "-" as the config file to read the configuration from standard input.
 
 This command:
 1. Creates absent zones (marked as managed with the configured account)
@@ -29,29 +42,130 @@ A record set is considered managed if it has at least one comment where its
 'account' property value matches the configured account name.`,
 	Args:         cobra.ExactArgs(1),
 	SilenceUsage: true,
-	RunE:         runApply,
+	RunE:         withProfiling(runApply),
 }
 
 var dryRun bool
 var autoConfirm bool
+var reportFormat string
+var reportFile string
+var manifestOut string
+var manifestIn string
+var rectify bool
+var approvalOut string
+var approvalTTL time.Duration
+var lockZones bool
+var lockTTL time.Duration
+var forceUnlock bool
+var strictNames bool
+var annotationFormat string
+var annotationFile string
+var danglingTargets string
+var resolveTargets bool
+var showSinceLast bool
+var historyFile string
+var confirmWith string
+var confirmTimeout time.Duration
+var opaURL string
+var opaFallback string
+var slackListen string
+var policyFile string
+var policyAllow []string
+var checkQueries bool
+var force bool
+var largeZoneRRsets int
+var patchBatchSize int
+var patchPause time.Duration
+var patchSizeLimit int
+var maxChanges int
+var maxDeletions int
+var resumeApply bool
+var serialPrecondition bool
+var onlyChanges bool
+var jsonSummaryOnly bool
 
 func init() {
 	rootCmd.AddCommand(applyCmd)
 	applyCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be changed without applying")
 	applyCmd.Flags().BoolVarP(&autoConfirm, "auto-confirm", "y", false, "Skip confirmation prompt")
+	applyCmd.Flags().StringVar(&reportFormat, "report", "", "Write a change report in the given format (html)")
+	applyCmd.Flags().StringVar(&reportFile, "report-file", "", "Path of the change report file")
+	applyCmd.Flags().StringVar(&manifestOut, "manifest-out", "",
+		"Write a signed manifest of the change set to this file (key from "+manifestKeyEnv+")")
+	applyCmd.Flags().StringVar(&manifestIn, "manifest", "",
+		"Only apply if the change set matches this signed manifest (key from "+manifestKeyEnv+")")
+	applyCmd.Flags().BoolVar(&rectify, "rectify", false,
+		"Rectify changed DNSSEC zones that do not have API-RECTIFY enabled")
+	applyCmd.Flags().StringVar(&approvalOut, "request-approval", "",
+		"Write a pending change bundle to this file instead of applying (implies --dry-run, key from "+
+			manifestKeyEnv+")")
+	applyCmd.Flags().DurationVar(&approvalTTL, "approval-ttl", 24*time.Hour,
+		"How long a pending change bundle can be approved")
+	applyCmd.Flags().BoolVar(&lockZones, "lock", false,
+		"Lock existing zones with a TXT record while they are applied, failing if another run holds the lock")
+	applyCmd.Flags().DurationVar(&lockTTL, "lock-ttl", 15*time.Minute,
+		"How long zone locks are valid, after which they can be taken over")
+	applyCmd.Flags().BoolVar(&forceUnlock, "force-unlock", false,
+		"Take over zone locks held by other runs, e.g. after a crashed run (implies --lock)")
+	applyCmd.Flags().BoolVar(&strictNames, "strict-names", false,
+		"Reject fully qualified rrset names outside of their zone unless marked with external: true")
+	applyCmd.Flags().StringVar(&annotationFormat, "annotations", "",
+		"Report validation errors and dry run drift as CI annotations (github, gitlab)")
+	applyCmd.Flags().StringVar(&annotationFile, "annotations-file", "",
+		"Path of the annotations (default: stdout for github, "+defaultCodeQualityFile+" for gitlab)")
+	applyCmd.Flags().StringVar(&danglingTargets, "dangling-targets", "",
+		"How to report CNAME, MX, SRV and NS targets that do not exist: warn, error, off (default: warn)")
+	applyCmd.Flags().BoolVar(&resolveTargets, "resolve-targets", false,
+		"Look up targets outside of the configured zones in DNS when checking for dangling targets")
+	applyCmd.Flags().BoolVar(&showSinceLast, "show-since-last", false,
+		"Report the configuration changes since the last recorded apply")
+	applyCmd.Flags().StringVar(&historyFile, "history-file", "",
+		"Path of the last apply record (default: "+history.FileName+" next to the config file)")
+	applyCmd.Flags().StringVar(&confirmWith, "confirm", confirm.ProviderTerminal,
+		"How changes are confirmed: terminal, fail (non-interactive), slack, opa")
+	applyCmd.Flags().DurationVar(&confirmTimeout, "confirm-timeout", 30*time.Minute,
+		"How long to wait for a Slack approval")
+	applyCmd.Flags().StringVar(&opaURL, "opa-url", "",
+		"OPA data API URL of the confirmation decision, e.g. http://localhost:8181/v1/data/dns/confirm")
+	applyCmd.Flags().StringVar(&opaFallback, "opa-fallback", confirm.ProviderTerminal,
+		"Provider asked when the OPA policy decides \"ask\": terminal, fail, slack")
+	applyCmd.Flags().StringVar(&slackListen, "slack-listen", ":8089",
+		"Address receiving the Slack interactivity requests of approval buttons")
+	applyCmd.Flags().StringVar(&policyFile, "policy", "",
+		"Policy file the changes must comply with (default: policy of the project settings)")
+	applyCmd.Flags().StringArrayVar(&policyAllow, "allow", nil,
+		"Allow changes guarded by policy rules with this require_allow token (repeatable)")
+	applyCmd.Flags().BoolVar(&checkQueries, "check-queries", false,
+		"Refuse to delete orphaned or absent rrsets that still receive queries according to the server statistics")
+	applyCmd.Flags().BoolVar(&force, "force", false,
+		"Delete orphaned or absent rrsets that still receive queries (with --check-queries)")
+	applyCmd.Flags().IntVar(&largeZoneRRsets, "large-zone-rrsets", manager.DefaultPacing.LargeZoneRRsets,
+		"Send the changes of zones with at least this many rrsets in batches (default 0, no pacing)")
+	applyCmd.Flags().IntVar(&patchBatchSize, "patch-batch-size", manager.DefaultPacing.BatchSize,
+		"Maximum number of rrset changes per patch of a large zone")
+	applyCmd.Flags().DurationVar(&patchPause, "patch-pause", manager.DefaultPacing.Pause,
+		"Pause between the patches of a large zone")
+	applyCmd.Flags().IntVar(&patchSizeLimit, "patch-size-limit", manager.DefaultPacing.SizeLimit,
+		"Request body limit of the server in bytes, warn about patches close to it (0 disables)")
+	applyCmd.Flags().IntVar(&maxChanges, "max-changes", 0,
+		"Refuse to apply more rrset changes than this (default: safety.max_changes of the project settings, "+
+			"0 no limit)")
+	applyCmd.Flags().IntVar(&maxDeletions, "max-deletions", 0,
+		"Refuse to delete more rrsets than this (default: safety.max_deletions of the project settings, "+
+			"0 no limit)")
+	applyCmd.Flags().BoolVar(&resumeApply, "resume", false,
+		"Skip the zones that the last failed run applied, unless their configuration changed since")
+	applyCmd.Flags().BoolVar(&serialPrecondition, "serial-precondition", false,
+		"Record the SOA serials of the changed zones in the manifest or pending change bundle, "+
+			"so that it is not applied if someone modified the zones since")
+	applyCmd.Flags().BoolVar(&onlyChanges, "only-changes", false,
+		"Only show the zones and RRsets with changes, without the tables of current and desired records")
+	applyCmd.Flags().BoolVar(&jsonSummaryOnly, "json-summary-only", false,
+		"Only print the result of the run to stdout, as a single JSON object; errors go to stderr (implies --json)")
+	addProfileFlags(applyCmd)
 }
 
 func runApply(cmd *cobra.Command, args []string) error {
-	apiURL, err := cmd.Flags().GetString("api-url")
-	if err != nil {
-		return fmt.Errorf("failed to get api-url flag: %w", err)
-	}
-
-	apiKey, err := cmd.Flags().GetString("api-key")
-	if err != nil {
-		return fmt.Errorf("failed to get api-key flag: %w", err)
-	}
-
 	verbose, err := cmd.Flags().GetBool("verbose")
 	if err != nil {
 		return fmt.Errorf("failed to get verbose flag: %w", err)
@@ -61,14 +175,61 @@ func runApply(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to get json flag: %w", err)
 	}
+	jsonOutput = jsonOutput || jsonSummaryOnly
 
 	noColor, err := cmd.Flags().GetBool("no-color")
 	if err != nil {
 		return fmt.Errorf("failed to get no-color flag: %w", err)
 	}
 
+	if err := validateReportFlags(); err != nil {
+		return err
+	}
+	if annotationFormat != "" {
+		if err := annotation.ValidateFormat(annotationFormat); err != nil {
+			return err
+		}
+		// GitHub workflow commands would be printed next to the result
+		if jsonSummaryOnly && annotationFormat == annotation.FormatGitHub && annotationFile == "" {
+			return fmt.Errorf("--json-summary-only requires --annotations-file with --annotations %s",
+				annotation.FormatGitHub)
+		}
+	}
+	if err := validateConfirmFlags(); err != nil {
+		return err
+	}
+	if err := config.ValidateDanglingMode(danglingTargets); err != nil {
+		return err
+	}
+
+	if serialPrecondition && manifestOut == "" && approvalOut == "" {
+		return fmt.Errorf("--serial-precondition requires --manifest-out or --request-approval")
+	}
+	// A pending change bundle is applied by "approve", not by this run
+	if approvalOut != "" {
+		dryRun = true
+	}
+
+	runID, err := getRunID(cmd)
+	if err != nil {
+		return err
+	}
+
 	configFile := args[0]
-	accountName := getAccountName()
+	project, err := loadSettings(configFile)
+	if err != nil {
+		return err
+	}
+	if err := applySafetyFlags(cmd, project); err != nil {
+		return err
+	}
+
+	// The confirmation prompt reads from stdin, which is taken by the config
+	terminal := confirmWith == confirm.ProviderTerminal ||
+		(confirmWith == confirm.ProviderOPA && opaFallback == confirm.ProviderTerminal)
+	if configFile == stdinPath && terminal && !autoConfirm && !dryRun && !jsonOutput {
+		return fmt.Errorf("reading the config from stdin requires --auto-confirm or --dry-run")
+	}
 
 	// Initialize logger
 	log := logger.New(logger.Options{
@@ -77,76 +238,575 @@ func runApply(cmd *cobra.Command, args []string) error {
 		NoColor: noColor,
 	})
 	log.SetDryRun(dryRun)
+	log.SetRunID(runID)
+	// Only the result is printed, everything else but errors is discarded
+	resultLog := log
+	if jsonSummaryOnly {
+		log = log.Quiet()
+	}
+	log.Info("Run ID: %s", runID)
+	if project.Path != "" {
+		log.Debug("Using settings from %s", project.Path)
+	}
 
-	log.Info("Loading configuration from %s", configFile)
-	log.Debug("API URL: %s", apiURL)
-	log.Debug("API Key: %s", logger.MaskSecret(apiKey))
-	log.Debug("Account name: %s", accountName)
+	log.Info("Loading configuration from %s", configSource(configFile))
 
 	// Load configuration
-	cfg, err := config.LoadFromFile(configFile)
+	cfg, configData, err := loadConfig(configFile)
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return fmt.Errorf("failed to load config from %s: %w", configSource(configFile), err)
+	}
+	if len(cfg.Partitions) > 0 {
+		return runPartitions(cmd, log, project, cfg, configFile, runID)
 	}
 	log.Info("Loaded %d zone(s) from configuration", len(cfg.Zones))
+	applyProjectDefaults(cfg, project)
 
-	// Create PowerDNS client
-	client := powerdns.NewClient(apiURL, apiKey, log)
+	// Create PowerDNS client (write credentials are not needed for a dry run)
+	client, err := newAPIClient(cmd, log, !dryRun)
+	if err != nil {
+		return err
+	}
 
-	// Create manager
-	mgr := manager.NewManager(client, accountName, log)
+	accountName, err := getAccountName(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	log.Debug("Account name: %s", accountName)
 
-	// Set confirmation function (skip in JSON mode or auto-confirm)
-	if !jsonOutput && !autoConfirm && !dryRun {
-		mgr.SetConfirmFunc(func(prompt string) bool {
-			fmt.Printf("%s [y/N]: ", prompt)
-			reader := bufio.NewReader(os.Stdin)
-			response, err := reader.ReadString('\n')
-			if err != nil {
-				return false
+	mgr, opts, err := newApplyManager(log, client, accountName, runID, jsonOutput)
+	if err != nil {
+		return err
+	}
+	if opts.Pacing, err = patchPacing(cmd, project); err != nil {
+		return err
+	}
+	if resumeApply {
+		if opts.Completed, err = resumeZones(log, cfg, accountName, journalPath(configFile)); err != nil {
+			return err
+		}
+	}
+
+	var approved *manifest.Manifest
+	if manifestIn != "" {
+		if approved, err = loadManifest(manifestIn); err != nil {
+			return err
+		}
+		log.Info("Verifying change set against manifest %s...", manifestIn)
+	}
+	// Hooks of a config from stdin run in the working directory
+	run := &applyRun{
+		log:      log,
+		client:   client,
+		mgr:      mgr,
+		project:  project,
+		cfg:      cfg,
+		opts:     opts,
+		approved: approved,
+		hooks:    &hookInput{RunID: runID, Account: accountName, Config: configSource(configFile)},
+		hookDir:  filepath.Dir(configFile),
+		journal:  journalPath(configFile),
+		history:  historyPath(configFile),
+	}
+	if err := run.check(cmd.Context()); err != nil {
+		return err
+	}
+	// Reported before this apply is recorded
+	if showSinceLast {
+		if err := printSinceLast(log, cfg, historyPath(configFile), jsonOutput); err != nil {
+			return err
+		}
+	}
+
+	log.Info("Applying configuration...")
+	result, err := run.apply(cmd.Context())
+	if result != nil {
+		// Print results, including partial results of a failed apply
+		printApplyResult(resultLog, result, dryRun, jsonOutput)
+	}
+	if verbose || jsonOutput {
+		printAPIStats(log, client.Stats(), jsonOutput)
+	}
+	// A failed apply is returned as such, failing to write the report or the
+	// annotations of it is only logged
+	if reportFormat != "" {
+		if reportErr := writeReport(configSource(configFile), runID, result, err); reportErr == nil {
+			log.Info("Change report written to %s", reportFile)
+		} else if err != nil {
+			log.Error("%v", reportErr)
+		} else {
+			return reportErr
+		}
+	}
+	if annotationFormat != "" {
+		findings := annotation.FromError(err)
+		if dryRun {
+			findings = append(findings, annotation.FromResult(result, cfg)...)
+		}
+		if annotationErr := writeAnnotations(findings); annotationErr != nil {
+			if err == nil {
+				return annotationErr
 			}
-			response = strings.TrimSpace(strings.ToLower(response))
-			return response == "y" || response == "yes"
-		})
+			log.Error("%v", annotationErr)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if manifestOut != "" {
+		if err := writeManifest(log, result, accountName, manifestOut); err != nil {
+			return err
+		}
+	}
+	if approvalOut != "" {
+		var defaultTTL uint32
+		if project.DefaultTTL != nil {
+			defaultTTL = *project.DefaultTTL
+		}
+		return writeApprovalBundle(log, result, accountName, configFile, configData, defaultTTL)
+	}
+	return nil
+}
+
+// applyProjectDefaults applies the project settings and the flags that
+// override config keys to cfg.
+func applyProjectDefaults(cfg *config.Config, project *settings.Settings) {
+	if project.DefaultTTL != nil {
+		cfg.SetDefaultTTL(*project.DefaultTTL)
+	}
+	if strictNames || project.StrictNames {
+		cfg.StrictNames = true
+	}
+	switch {
+	case danglingTargets != "":
+		cfg.DanglingTargets = danglingTargets
+	case cfg.DanglingTargets == "":
+		cfg.DanglingTargets = project.DanglingTargets
+	}
+	if cfg.DualStack == "" {
+		cfg.DualStack = project.DualStack
+	}
+}
+
+// newApplyManager creates the manager of an apply run with the confirmation
+// provider of the flags, and the options to apply with.
+func newApplyManager(
+	log *logger.Logger,
+	client manager.Provider,
+	accountName, runID string,
+	jsonOutput bool,
+) (*manager.Manager, manager.ApplyOptions, error) {
+	mgr := manager.NewManager(client, accountName, log)
+	mgr.SetToolVersion(version)
+	mgr.SetRunID(runID)
+
+	// Stream progress events as NDJSON lines for wrapping tools
+	if jsonOutput {
+		streamEvents(log, mgr)
+	}
+
+	// Set the confirmation provider; JSON output skips the terminal prompt
+	skipConfirm := autoConfirm || (jsonOutput && confirmWith == confirm.ProviderTerminal)
+	if !skipConfirm && !dryRun {
+		confirmer, err := newConfirmer(confirmWith, log)
+		if err != nil {
+			return nil, manager.ApplyOptions{}, err
+		}
+		mgr.SetConfirmer(confirmer)
 	}
 
-	// Apply configuration
 	opts := manager.ApplyOptions{
 		DryRun:      dryRun,
-		AutoConfirm: jsonOutput || autoConfirm,
+		AutoConfirm: skipConfirm,
+		Rectify:     rectify,
+		Lock:        lockZones || forceUnlock,
+		ForceUnlock: forceUnlock,
+		LockTTL:     lockTTL,
+
+		ResolveTargets: resolveTargets,
+		CheckQueries:   checkQueries,
+		Force:          force,
+		OnlyChanges:    onlyChanges,
 	}
+	return mgr, opts, nil
+}
 
-	log.Info("Applying configuration...")
-	result, err := mgr.Apply(cmd.Context(), cfg, opts)
+// patchPacing returns the pacing of large zones: the defaults, overridden by
+// the project settings and then by the flags of the command, if it has them.
+func patchPacing(cmd *cobra.Command, project *settings.Settings) (manager.Pacing, error) {
+	pacing := manager.DefaultPacing
+	if project.Pacing.LargeZoneRRsets != nil {
+		pacing.LargeZoneRRsets = *project.Pacing.LargeZoneRRsets
+	}
+	if project.Pacing.BatchSize != 0 {
+		pacing.BatchSize = project.Pacing.BatchSize
+	}
+	if project.Pacing.Pause != 0 {
+		pacing.Pause = project.Pacing.Pause
+	}
+	if project.Pacing.SizeLimit != nil {
+		pacing.SizeLimit = *project.Pacing.SizeLimit
+	}
+
+	flags := cmd.Flags()
+	if flags.Changed("large-zone-rrsets") {
+		pacing.LargeZoneRRsets = largeZoneRRsets
+	}
+	if flags.Changed("patch-batch-size") {
+		pacing.BatchSize = patchBatchSize
+	}
+	if flags.Changed("patch-pause") {
+		pacing.Pause = patchPause
+	}
+	if flags.Changed("patch-size-limit") {
+		pacing.SizeLimit = patchSizeLimit
+	}
+	if pacing.LargeZoneRRsets < 0 || pacing.BatchSize <= 0 || pacing.Pause < 0 || pacing.SizeLimit < 0 {
+		return manager.Pacing{}, fmt.Errorf("invalid pacing: --large-zone-rrsets, --patch-pause and " +
+			"--patch-size-limit cannot be negative, --patch-batch-size must be positive")
+	}
+	return pacing, nil
+}
+
+// checkPlan computes the changes about to be applied once, with a quiet dry
+// run, and checks them before anything is applied: against the safety
+// thresholds and the change policy of the project, the validation hooks of
+// the zones (which run in hookDir) and the approved manifest, if any, and with
+// the pre_apply hooks unless this is a dry run.
+// The checks approve this plan only, so the serials of its zones are pinned in
+// opts: a zone modified before it is applied fails with ErrZoneModified
+// instead of being applied unchecked. No plan is computed without checks.
+// A failed check returns a *rejectedError.
+func checkPlan(
+	ctx context.Context,
+	log *logger.Logger,
+	client manager.Provider,
+	cfg *config.Config,
+	project *settings.Settings,
+	approved *manifest.Manifest,
+	hooks *hookInput,
+	hookDir string,
+	opts *manager.ApplyOptions,
+) error {
+	changePolicy, err := loadPolicy(project)
+	if err != nil {
+		return err
+	}
+	preApply := !opts.DryRun && len(project.Hooks.PreApply) > 0
+	limited := project.Safety != settings.Safety{}
+	if changePolicy == nil && !hasValidateHooks(cfg) && approved == nil && !preApply && !limited {
+		return nil
+	}
+
+	var serials map[string]uint32
+	if approved != nil {
+		serials = approved.Serials()
+	}
+	plan, err := planChanges(ctx, log, client, cfg, hooks.Account, serials)
+	if err != nil {
+		return err
+	}
+	if err := checkChanges(ctx, log, plan, cfg, project, changePolicy, approved, hooks, hookDir, preApply); err != nil {
+		return &rejectedError{err: err}
+	}
+	opts.Serials = planSerials(plan)
+	return nil
+}
+
+// checkChanges runs the checks of checkPlan on plan.
+func checkChanges(
+	ctx context.Context,
+	log *logger.Logger,
+	plan *manager.ApplyResult,
+	cfg *config.Config,
+	project *settings.Settings,
+	changePolicy *policy.Policy,
+	approved *manifest.Manifest,
+	hooks *hookInput,
+	hookDir string,
+	preApply bool,
+) error {
+	if err := checkSafety(plan, project.Safety); err != nil {
+		return err
+	}
+	if changePolicy != nil {
+		if err := checkPolicy(log, plan, changePolicy); err != nil {
+			return err
+		}
+	}
+	if err := checkValidateHooks(ctx, log, plan, cfg, hooks.RunID, hookDir); err != nil {
+		return err
+	}
+	if approved != nil {
+		if err := matchManifest(log, plan, hooks.Account, approved); err != nil {
+			return err
+		}
+	}
+	if preApply {
+		if err := runPreApplyHooks(ctx, log, project, plan, hooks); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// planChanges computes the changes about to be applied with a quiet dry run.
+// Zones whose serials differ from serials, e.g. of a manifest, fail the plan.
+func planChanges(
+	ctx context.Context,
+	log *logger.Logger,
+	client manager.Provider,
+	cfg *config.Config,
+	accountName string,
+	serials map[string]uint32,
+) (*manager.ApplyResult, error) {
+	planMgr := manager.NewManager(client, accountName, log.Quiet())
+	plan, err := planMgr.Apply(ctx, cfg, manager.ApplyOptions{DryRun: true, AutoConfirm: true, Serials: serials})
 	if err != nil {
-		return fmt.Errorf("failed to apply configuration: %w", err)
+		return nil, fmt.Errorf("failed to compute changes: %w", err)
 	}
+	return plan, nil
+}
 
-	// Print results
-	printApplyResult(log, result, dryRun, jsonOutput)
+// planSerials returns the serials of the zones of a plan by zone name, for
+// manager.ApplyOptions.Serials. Zones that do not exist have serial 0.
+func planSerials(plan *manager.ApplyResult) map[string]uint32 {
+	serials := make(map[string]uint32, len(plan.Zones))
+	for _, zr := range plan.Zones {
+		serials[zr.Name] = zr.Serial
+	}
+	return serials
+}
 
+// defaultCodeQualityFile is the default path of GitLab Code Quality reports.
+const defaultCodeQualityFile = "gl-code-quality-report.json"
+
+// writeAnnotations writes CI annotations to the annotations file, or to
+// stdout for GitHub workflow commands which are read from the job output.
+func writeAnnotations(findings []annotation.Finding) error {
+	path := annotationFile
+	if path == "" && annotationFormat == annotation.FormatGitHub {
+		return annotation.Write(os.Stdout, annotationFormat, findings)
+	}
+	if path == "" {
+		path = defaultCodeQualityFile
+	}
+
+	f, err := os.Create(path) //nolint:gosec // path is from CLI argument
+	if err != nil {
+		return fmt.Errorf("failed to create annotations file: %w", err)
+	}
+	defer func() {
+		_ = f.Close() //nolint:errcheck // best effort close, write errors are reported below
+	}()
+	if err := annotation.Write(f, annotationFormat, findings); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// validateReportFlags checks the report flags before any changes are made.
+func validateReportFlags() error {
+	if reportFormat == "" {
+		return nil
+	}
+	if reportFormat != "html" {
+		return fmt.Errorf("unsupported report format %q, must be: html", reportFormat)
+	}
+	if reportFile == "" {
+		return fmt.Errorf("--report-file is required when --report is set")
+	}
 	return nil
 }
 
+// writeReport writes the change report of an apply run to the report file.
+func writeReport(configFile, runID string, result *manager.ApplyResult, applyErr error) error {
+	f, err := os.Create(reportFile)
+	if err != nil {
+		return fmt.Errorf("failed to create report file: %w", err)
+	}
+	defer func() {
+		_ = f.Close() //nolint:errcheck // best effort close, write errors are reported below
+	}()
+
+	r := &report.Report{
+		GeneratedAt: time.Now(),
+		Result:      result,
+		ConfigFile:  configFile,
+		RunID:       runID,
+		DryRun:      dryRun,
+	}
+	if applyErr != nil {
+		r.Error = applyErr.Error()
+	}
+
+	if err := report.WriteHTML(f, r); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
 func printApplyResult(log *logger.Logger, result *manager.ApplyResult, isDryRun, jsonOutput bool) {
 	if jsonOutput {
-		log.InfoWithData("Apply completed", map[string]interface{}{
-			"zonesCreated":  result.ZonesCreated,
-			"rrsetsCreated": result.RRsetsCreated,
-			"rrsetsUpdated": result.RRsetsUpdated,
-			"rrsetsDeleted": result.RRsetsDeleted,
+		zones := make([]output.ZoneResult, len(result.Zones))
+		for i, zr := range result.Zones {
+			zones[i] = output.ZoneResult{
+				Zone:          zr.Name,
+				Labels:        zr.Labels,
+				Status:        string(zr.Status),
+				Error:         zr.Error,
+				ZoneCreated:   zr.Created,
+				RRsetsCreated: zr.RRsetsCreated,
+				RRsetsUpdated: zr.RRsetsUpdated,
+				RRsetsDeleted: zr.RRsetsDeleted,
+				Scheduled:     len(zr.Scheduled),
+				Metadata:      len(zr.Metadata),
+				PatchRecords:  zr.PatchRecords,
+				PatchBytes:    zr.PatchBytes,
+				DurationMs:    zr.Duration.Milliseconds(),
+			}
+		}
+		log.InfoWithData(output.MessageApplyCompleted, output.ApplyCompleted{
+			ZonesCreated:  result.ZonesCreated,
+			RRsetsCreated: result.RRsetsCreated,
+			RRsetsUpdated: result.RRsetsUpdated,
+			RRsetsDeleted: result.RRsetsDeleted,
+			Scheduled:     result.RRsetsScheduled,
+			Metadata:      result.MetadataUpdated,
+			PatchRecords:  result.PatchRecords,
+			PatchBytes:    result.PatchBytes,
+			Zones:         zones,
 		})
 		return
 	}
 
+	printZoneSummary(log, result)
+
 	prefix := ""
 	if isDryRun {
 		prefix = "[DRY RUN] "
 	}
 
-	fmt.Printf("\n%sResults:\n", prefix)
-	fmt.Printf("  Zones created:  %d\n", result.ZonesCreated)
-	fmt.Printf("  RRsets created: %d\n", result.RRsetsCreated)
-	fmt.Printf("  RRsets updated: %d\n", result.RRsetsUpdated)
-	fmt.Printf("  RRsets deleted: %d\n", result.RRsetsDeleted)
+	lines := [][2]string{
+		{i18n.T("Zones created:"), strconv.Itoa(result.ZonesCreated)},
+		{i18n.T("RRsets created:"), strconv.Itoa(result.RRsetsCreated)},
+		{i18n.T("RRsets updated:"), strconv.Itoa(result.RRsetsUpdated)},
+		{i18n.T("RRsets deleted:"), strconv.Itoa(result.RRsetsDeleted)},
+	}
+	if result.RRsetsScheduled > 0 {
+		lines = append(lines, [2]string{i18n.T("Scheduled:"), strconv.Itoa(result.RRsetsScheduled)})
+	}
+	if result.MetadataUpdated > 0 {
+		lines = append(lines, [2]string{i18n.T("Metadata:"), strconv.Itoa(result.MetadataUpdated)})
+	}
+	if result.PatchBytes > 0 {
+		lines = append(lines, [2]string{i18n.T("Patches:"),
+			i18n.T("%d record(s), %s", result.PatchRecords, logger.FormatBytes(result.PatchBytes))})
+	}
+
+	// Labels are padded to the longest one, which depends on the language
+	width := 0
+	for _, line := range lines {
+		width = max(width, utf8.RuneCountInString(line[0]))
+	}
+	fmt.Printf("\n%s%s\n", prefix, i18n.T("Results:"))
+	for _, line := range lines {
+		fmt.Printf("  %-*s %s\n", width, line[0], line[1])
+	}
+}
+
+// streamEvents logs the apply progress events of the manager as JSON entries
+// with the message "Apply event".
+func streamEvents(log *logger.Logger, mgr *manager.Manager) {
+	mgr.SetEventFunc(func(ev manager.Event) {
+		data := output.ApplyEvent{
+			Event: string(ev.Type),
+			Zone:  ev.Zone,
+		}
+		switch ev.Type {
+		case manager.EventRRset:
+			data.Action = string(ev.Change.Action)
+			data.Name = ev.Change.Name
+			data.Type = ev.Change.Type
+			data.Labels = ev.Change.Labels
+		case manager.EventPatchSent:
+			data.RRsets = ev.RRsets
+		case manager.EventZoneFinished:
+			data.Status = string(ev.Status)
+			data.DurationMs = ev.Duration.Milliseconds()
+			data.Error = ev.Error
+		}
+		log.InfoWithData(output.MessageApplyEvent, data)
+	})
+}
+
+// printZoneSummary displays per-zone apply results in table format.
+func printZoneSummary(log *logger.Logger, result *manager.ApplyResult) {
+	rows := make([][]string, 0, len(result.Zones))
+	for _, zr := range result.Zones {
+		if onlyChanges && zr.Status == manager.ZoneStatusOK && !zr.HasChanges() {
+			continue
+		}
+		zone := zr.Name
+		if zr.Created {
+			zone += " (new)"
+		}
+		patch := ""
+		if zr.PatchBytes > 0 {
+			patch = i18n.T("%d record(s), %s", zr.PatchRecords, logger.FormatBytes(zr.PatchBytes))
+		}
+		rows = append(rows, []string{
+			zone,
+			strconv.Itoa(zr.RRsetsCreated),
+			strconv.Itoa(zr.RRsetsUpdated),
+			strconv.Itoa(zr.RRsetsDeleted),
+			patch,
+			string(zr.Status),
+			zr.Duration.Round(time.Millisecond).String(),
+		})
+	}
+
+	fmt.Println()
+	headers := []string{"ZONE", "CREATED", "UPDATED", "DELETED", "PATCH", "STATUS", "DURATION"}
+	for i, header := range headers {
+		headers[i] = i18n.T(header)
+	}
+	log.Table(i18n.T("Zone summary"), headers, rows)
+}
+
+// printAPIStats displays API request timing statistics.
+func printAPIStats(log *logger.Logger, stats []powerdns.RequestStats, jsonOutput bool) {
+	if jsonOutput {
+		data := make([]output.RequestStats, len(stats))
+		for i, s := range stats {
+			data[i] = output.RequestStats{
+				Method:    s.Method,
+				Count:     s.Count,
+				Errors:    s.Errors,
+				ErrorRate: s.ErrorRate(),
+				MinMs:     s.Min.Milliseconds(),
+				AvgMs:     s.Avg().Milliseconds(),
+				MaxMs:     s.Max.Milliseconds(),
+			}
+		}
+		log.InfoWithData(output.MessageAPIPerformance, output.APIPerformance{Requests: data})
+		return
+	}
+
+	rows := make([][]string, len(stats))
+	for i, s := range stats {
+		rows[i] = []string{
+			s.Method,
+			strconv.Itoa(s.Count),
+			fmt.Sprintf("%d (%.0f%%)", s.Errors, s.ErrorRate()*100),
+			s.Min.Round(time.Microsecond).String(),
+			s.Avg().Round(time.Microsecond).String(),
+			s.Max.Round(time.Microsecond).String(),
+		}
+	}
+
+	fmt.Println()
+	headers := []string{"METHOD", "COUNT", "ERRORS", "MIN", "AVG", "MAX"}
+	log.Table("API performance", headers, rows)
 }
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/confirm"
+	"github.com/kreigan/powerdns-zone-manager/internal/logger"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+)
+
+// Environment variables of the Slack confirmation provider.
+const (
+	slackWebhookEnv   = "SLACK_WEBHOOK_URL"
+	slackSecretEnv    = "SLACK_SIGNING_SECRET"
+	slackApproversEnv = "SLACK_APPROVERS"
+)
+
+// validateConfirmFlags checks the confirmation provider flags.
+func validateConfirmFlags() error {
+	for _, name := range []string{confirmWith, opaFallback} {
+		if !slices.Contains(confirm.Providers, name) {
+			return fmt.Errorf("unsupported confirmation provider %q, must be: %s",
+				name, strings.Join(confirm.Providers, ", "))
+		}
+	}
+	if opaFallback == confirm.ProviderOPA {
+		return fmt.Errorf("--opa-fallback cannot be %s", confirm.ProviderOPA)
+	}
+	if confirmWith == confirm.ProviderOPA && opaURL == "" {
+		return fmt.Errorf("--opa-url is required with --confirm %s", confirm.ProviderOPA)
+	}
+	return nil
+}
+
+// newConfirmer creates the confirmation provider of the --confirm flag.
+func newConfirmer(name string, log *logger.Logger) (manager.Confirmer, error) {
+	switch name {
+	case confirm.ProviderFail:
+		return confirm.Fail{}, nil
+	case confirm.ProviderSlack:
+		webhook, secret := os.Getenv(slackWebhookEnv), os.Getenv(slackSecretEnv)
+		approvers := strings.FieldsFunc(os.Getenv(slackApproversEnv), func(r rune) bool {
+			return r == ',' || r == ' '
+		})
+		if webhook == "" || secret == "" || len(approvers) == 0 {
+			return nil, fmt.Errorf("%s, %s and %s environment variables are required for Slack approval",
+				slackWebhookEnv, slackSecretEnv, slackApproversEnv)
+		}
+		return &confirm.Slack{
+			WebhookURL:    webhook,
+			SigningSecret: secret,
+			Listen:        slackListen,
+			Timeout:       confirmTimeout,
+			Approvers:     approvers,
+		}, nil
+	case confirm.ProviderOPA:
+		fallback, err := newConfirmer(opaFallback, log)
+		if err != nil {
+			return nil, err
+		}
+		return &confirm.OPA{URL: opaURL, Fallback: fallback, Logf: log.Info}, nil
+	default:
+		return confirm.NewTerminal(os.Stdin, os.Stdout), nil
+	}
+}
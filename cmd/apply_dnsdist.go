@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/dnsdist"
+	"github.com/kreigan/powerdns-zone-manager/internal/logger"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+	"github.com/kreigan/powerdns-zone-manager/internal/settings"
+)
+
+// purgeDnsdistCaches clears the dnsdist caches of the settings for the rrsets
+// changed in the zones that were applied. Every endpoint is tried; the run
+// fails if any of them could not be cleared.
+func purgeDnsdistCaches(
+	ctx context.Context,
+	log *logger.Logger,
+	project *settings.Settings,
+	result *manager.ApplyResult,
+) error {
+	if len(project.Dnsdist) == 0 || result == nil {
+		return nil
+	}
+	type query struct{ name, rtype string }
+	seen := make(map[query]bool)
+	var queries []query
+	for _, zr := range result.Zones {
+		if zr.Status != manager.ZoneStatusOK {
+			continue
+		}
+		for _, change := range zr.Changes {
+			q := query{change.Name, change.Type}
+			if !seen[q] {
+				seen[q] = true
+				queries = append(queries, q)
+			}
+		}
+	}
+	if len(queries) == 0 {
+		return nil
+	}
+	sort.Slice(queries, func(i, j int) bool {
+		if queries[i].name != queries[j].name {
+			return queries[i].name < queries[j].name
+		}
+		return queries[i].rtype < queries[j].rtype
+	})
+
+	failed := 0
+endpoints:
+	for _, endpoint := range project.Dnsdist {
+		client, err := dnsdist.NewClient(endpoint)
+		if err != nil {
+			log.Error("Failed to clear dnsdist cache: %v", err)
+			failed++
+			continue
+		}
+		purged := 0
+		for _, q := range queries {
+			count, err := client.Purge(ctx, q.name, q.rtype)
+			if err != nil {
+				log.Error("Failed to clear dnsdist cache of %s for %s %s: %v", client.URL(), q.name, q.rtype, err)
+				failed++
+				continue endpoints
+			}
+			purged += count
+		}
+		log.Info("Cleared %d cached answer(s) for %d rrset(s) from dnsdist %s", purged, len(queries), client.URL())
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to clear the cache of %d dnsdist endpoint(s)", failed)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/history"
+	"github.com/kreigan/powerdns-zone-manager/internal/logger"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+	"github.com/kreigan/powerdns-zone-manager/pkg/output"
+)
+
+// historyPath returns the path of the apply record of a config file: the
+// --history-file flag, or history.FileName next to the config file (in the
+// working directory for stdin).
+func historyPath(configFile string) string {
+	if historyFile != "" {
+		return historyFile
+	}
+	if configFile == stdinPath {
+		return history.FileName
+	}
+	return filepath.Join(filepath.Dir(configFile), history.FileName)
+}
+
+// printSinceLast reports the configuration changes since the last recorded
+// apply, along with the results of that apply.
+func printSinceLast(log *logger.Logger, cfg *config.Config, path string, jsonOutput bool) error {
+	last, err := history.Load(path)
+	if err != nil {
+		return err
+	}
+	if last == nil {
+		log.Info("No previous apply recorded in %s", path)
+		return nil
+	}
+	state, err := history.Snapshot(cfg)
+	if err != nil {
+		return err
+	}
+	diff := history.Compare(last.State, state)
+
+	if jsonOutput {
+		data := output.SinceLast{
+			AppliedAt: last.AppliedAt,
+			RunID:     last.RunID,
+			StateHash: last.StateHash,
+			Diff: output.Diff{
+				ZonesAdded:   diff.ZonesAdded,
+				ZonesRemoved: diff.ZonesRemoved,
+			},
+		}
+		if last.Result != nil {
+			data.Result = &output.ApplyTotals{
+				ZonesCreated:  last.Result.ZonesCreated,
+				RRsetsCreated: last.Result.RRsetsCreated,
+				RRsetsUpdated: last.Result.RRsetsUpdated,
+				RRsetsDeleted: last.Result.RRsetsDeleted,
+			}
+		}
+		for _, zd := range diff.Zones {
+			data.Diff.Zones = append(data.Diff.Zones, output.ZoneDiff(zd))
+		}
+		log.InfoWithData(output.MessageSinceLast, data)
+		return nil
+	}
+
+	fmt.Printf("\nChanges since last apply (run %s at %s):\n",
+		last.RunID, last.AppliedAt.Format("2006-01-02 15:04:05Z"))
+	if last.Result != nil {
+		fmt.Printf("  Last apply: %d zone(s) created, %d rrset(s) created, %d updated, %d deleted\n",
+			last.Result.ZonesCreated, last.Result.RRsetsCreated, last.Result.RRsetsUpdated, last.Result.RRsetsDeleted)
+	}
+	if diff.Empty() {
+		fmt.Println("  No configuration changes")
+		return nil
+	}
+	for _, zone := range diff.ZonesAdded {
+		fmt.Printf("  + zone %s\n", zone)
+	}
+	for _, zone := range diff.ZonesRemoved {
+		fmt.Printf("  - zone %s\n", zone)
+	}
+	for _, zd := range diff.Zones {
+		fmt.Printf("  %s:\n", zd.Zone)
+		for _, key := range zd.Added {
+			fmt.Printf("    + %s\n", key)
+		}
+		for _, key := range zd.Changed {
+			fmt.Printf("    ~ %s\n", key)
+		}
+		for _, key := range zd.Removed {
+			fmt.Printf("    - %s\n", key)
+		}
+	}
+	return nil
+}
+
+// recordApply records a completed apply for later --show-since-last and
+// cleanup runs.
+func recordApply(
+	log *logger.Logger,
+	cfg *config.Config,
+	account, runID string,
+	result *manager.ApplyResult,
+	path string,
+) error {
+	record, err := history.New(cfg, account, runID, result)
+	if err != nil {
+		return err
+	}
+	// A record that cannot be read is replaced, losing rrsets removed earlier
+	prev, err := history.Load(path)
+	if err != nil {
+		log.Warn("Replacing apply record: %v", err)
+	}
+	record.Merge(prev)
+	if err := record.Save(path); err != nil {
+		return err
+	}
+	log.Debug("Apply recorded in %s", path)
+	return nil
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/confirm"
+	"github.com/kreigan/powerdns-zone-manager/internal/hook"
+	"github.com/kreigan/powerdns-zone-manager/internal/logger"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+	"github.com/kreigan/powerdns-zone-manager/internal/settings"
+)
+
+// hookInput is the JSON document apply hooks receive on stdin.
+type hookInput struct {
+	// Plan are the changes about to be applied (pre_apply)
+	Plan *manager.ApplyResult `json:"plan,omitempty"`
+	// Result is the result of the apply, partial if it failed (post_apply)
+	Result  *manager.ApplyResult `json:"result,omitempty"`
+	Hook    string               `json:"hook"`
+	RunID   string               `json:"runId"`
+	Account string               `json:"account"`
+	Config  string               `json:"config"`
+	// Partition is the config partition being applied, if partitioned
+	Partition string `json:"partition,omitempty"`
+	// Error is the error of a failed apply (post_apply)
+	Error string `json:"error,omitempty"`
+}
+
+// runHooks runs the commands of a hook in the directory of the settings file.
+// Their output goes to stderr, which keeps stdout clean for --json.
+func runHooks(
+	ctx context.Context,
+	log *logger.Logger,
+	project *settings.Settings,
+	commands []string,
+	input *hookInput,
+) error {
+	dir := filepath.Dir(project.Path)
+	for _, command := range commands {
+		log.Info("Running %s hook: %s", input.Hook, command)
+		if err := hook.Run(ctx, command, dir, input, os.Stderr); err != nil {
+			return fmt.Errorf("%s hook failed: %w", input.Hook, err)
+		}
+	}
+	return nil
+}
+
+// runPreApplyHooks runs the pre_apply hooks with the planned changes.
+func runPreApplyHooks(
+	ctx context.Context,
+	log *logger.Logger,
+	project *settings.Settings,
+	plan *manager.ApplyResult,
+	input *hookInput,
+) error {
+	if len(project.Hooks.PreApply) == 0 {
+		return nil
+	}
+	input.Hook, input.Plan = hook.PreApply, plan
+	return runHooks(ctx, log, project, project.Hooks.PreApply, input)
+}
+
+// runPostApplyHooks runs the post_apply hooks with the result of the apply.
+func runPostApplyHooks(
+	ctx context.Context,
+	log *logger.Logger,
+	project *settings.Settings,
+	input *hookInput,
+	result *manager.ApplyResult,
+	applyErr error,
+) error {
+	if len(project.Hooks.PostApply) == 0 {
+		return nil
+	}
+	input.Hook, input.Plan, input.Result = hook.PostApply, nil, result
+	if applyErr != nil {
+		input.Error = applyErr.Error()
+	}
+	return runHooks(ctx, log, project, project.Hooks.PostApply, input)
+}
+
+// hasValidateHooks returns true if a zone of cfg has a validate_hook.
+func hasValidateHooks(cfg *config.Config) bool {
+	for _, zone := range cfg.Zones {
+		if zone.ValidateHook != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// checkValidateHooks runs the validate_hook of every zone with planned changes
+// in dir, the directory of the config file. The input is the same as of the
+// OPA confirmation provider. It fails if any hook rejects the changes.
+func checkValidateHooks(
+	ctx context.Context,
+	log *logger.Logger,
+	plan *manager.ApplyResult,
+	cfg *config.Config,
+	runID, dir string,
+) error {
+	rejected := 0
+	for _, zr := range plan.Zones {
+		zone := cfg.Zones[zr.Name]
+		if zone.ValidateHook == "" || len(zr.Changes)+len(zr.Metadata) == 0 {
+			continue
+		}
+		log.Info("Running validation hook of zone %s: %s", zr.Name, zone.ValidateHook)
+		input := confirm.NewInput(&manager.ConfirmRequest{
+			Zone:     zr.Name,
+			RunID:    runID,
+			Changes:  zr.Changes,
+			Metadata: zr.Metadata,
+		})
+		if err := hook.Run(ctx, zone.ValidateHook, dir, input, os.Stderr); err != nil {
+			log.Error("Changes of zone %s (%s) rejected: %v", zr.Name, zone.Location(), err)
+			rejected++
+		}
+	}
+	if rejected > 0 {
+		return fmt.Errorf("refusing to apply: %d zone validation hook(s) rejected the changes", rejected)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApply_ValidateHook(t *testing.T) {
+	tests := []struct {
+		name    string
+		hook    string
+		applied bool
+	}{
+		{name: "rejected", hook: "exit 1", applied: false},
+		{name: "accepted", hook: "exit 0", applied: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			configFile := filepath.Join(dir, "zones.yml")
+			data := "zones:\n  example.com:\n    nameservers: [ns1.example.com.]\n" +
+				"    validate_hook: " + tt.hook + "\n" +
+				"    rrsets:\n      - {name: www, type: A, records: 192.0.2.1}\n"
+			if err := os.WriteFile(configFile, []byte(data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			providerDir := filepath.Join(dir, "provider")
+
+			rootCmd.SetArgs([]string{"apply", "-y", "--provider", "file", "--provider-dir", providerDir,
+				"--account", "test", configFile})
+			err := rootCmd.Execute()
+			_, statErr := os.Stat(filepath.Join(providerDir, "example.com.json"))
+			if tt.applied {
+				if err != nil || statErr != nil {
+					t.Errorf("Expected the zone to be applied, got %v (%v)", err, statErr)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), "validation hook(s) rejected the changes") {
+				t.Errorf("Expected the hook to reject the changes, got %v", err)
+			}
+			if statErr == nil {
+				t.Error("Expected the zone not to be created")
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/logger"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+	"github.com/kreigan/powerdns-zone-manager/internal/manifest"
+)
+
+// manifestKeyEnv is the environment variable holding the manifest signing key.
+const manifestKeyEnv = "MANIFEST_KEY"
+
+// getManifestKey returns the manifest signing key from the environment.
+func getManifestKey() ([]byte, error) {
+	key := os.Getenv(manifestKeyEnv)
+	if key == "" {
+		return nil, fmt.Errorf("%s environment variable is required to sign or verify manifests", manifestKeyEnv)
+	}
+	return []byte(key), nil
+}
+
+// loadManifest loads and verifies the signed manifest of the --manifest flag.
+func loadManifest(path string) (*manifest.Manifest, error) {
+	key, err := getManifestKey()
+	if err != nil {
+		return nil, err
+	}
+
+	approved, err := manifest.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := approved.Verify(key); err != nil {
+		return nil, fmt.Errorf("manifest %s: %w", path, err)
+	}
+	return approved, nil
+}
+
+// matchManifest checks that the planned changes match an approved manifest.
+func matchManifest(
+	log *logger.Logger,
+	plan *manager.ApplyResult,
+	accountName string,
+	approved *manifest.Manifest,
+) error {
+	planned := manifest.New(accountName, plan)
+	if len(approved.Serials()) > 0 {
+		planned.RecordSerials(plan)
+	}
+	if err := approved.Matches(planned); err != nil {
+		return fmt.Errorf("refusing to apply: %w", err)
+	}
+	log.Info("Change set matches manifest (digest %s)", approved.Digest)
+	return nil
+}
+
+// writeManifest writes a signed manifest of the change set to path.
+func writeManifest(log *logger.Logger, result *manager.ApplyResult, accountName, path string) error {
+	if result == nil {
+		return errors.New("no change set to write to manifest")
+	}
+
+	key, err := getManifestKey()
+	if err != nil {
+		return err
+	}
+
+	m := manifest.New(accountName, result)
+	if serialPrecondition {
+		m.RecordSerials(result)
+	}
+	m.Sign(key)
+	if err := m.Save(path); err != nil {
+		return err
+	}
+	log.Info("Signed manifest written to %s (digest %s)", path, m.Digest)
+	return nil
+}
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/logger"
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+	"github.com/kreigan/powerdns-zone-manager/internal/settings"
+)
+
+// runPartitions applies the partitions of a partitions file one after the
+// other, each with its own account and API client. A failing partition does
+// not stop the others; the run fails if any of them failed.
+func runPartitions(
+	cmd *cobra.Command,
+	log *logger.Logger,
+	project *settings.Settings,
+	cfg *config.Config,
+	configFile, runID string,
+) error {
+	if err := checkPartitionFlags(cmd); err != nil {
+		return err
+	}
+	// Paths of partitions from stdin are relative to the working directory
+	dir := filepath.Dir(configFile)
+	configs, err := cfg.LoadPartitions(dir)
+	if err != nil {
+		return fmt.Errorf("failed to load partitions of %s: %w", configSource(configFile), err)
+	}
+	log.Info("Loaded %d partition(s) from configuration", len(configs))
+
+	var failed []string
+	for _, name := range cfg.PartitionNames() {
+		partition := cfg.Partitions[name]
+		plog := log.WithPartition(name)
+		plog.Info("Applying partition %s with account %s (%d zone(s))", name, partition.Account,
+			len(configs[name].Zones))
+		if err := applyPartition(cmd, plog, project, name, &partition, configs[name], configFile, runID); err != nil {
+			plog.Error("Partition %s failed: %v", name, err)
+			failed = append(failed, name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d partition(s) failed: %s", len(failed), len(configs), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// applyPartition applies the configuration of a partition. Hooks run in the
+// directory of the partitions file; partitions are not recorded in the journal
+// or the history of the partitions file.
+func applyPartition(
+	cmd *cobra.Command,
+	log *logger.Logger,
+	project *settings.Settings,
+	name string,
+	partition *config.Partition,
+	cfg *config.Config,
+	configFile, runID string,
+) error {
+	jsonOutput, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		return fmt.Errorf("failed to get json flag: %w", err)
+	}
+	verbose, err := cmd.Flags().GetBool("verbose")
+	if err != nil {
+		return fmt.Errorf("failed to get verbose flag: %w", err)
+	}
+	applyProjectDefaults(cfg, project)
+
+	client, err := newPartitionClient(cmd, log, name, partition, !dryRun)
+	if err != nil {
+		return err
+	}
+	mgr, opts, err := newApplyManager(log, client, partition.Account, runID, jsonOutput)
+	if err != nil {
+		return err
+	}
+	if opts.Pacing, err = patchPacing(cmd, project); err != nil {
+		return err
+	}
+	run := &applyRun{
+		log:     log,
+		client:  client,
+		mgr:     mgr,
+		project: project,
+		cfg:     cfg,
+		opts:    opts,
+		hooks: &hookInput{
+			RunID:     runID,
+			Account:   partition.Account,
+			Config:    configSource(configFile),
+			Partition: name,
+		},
+		hookDir: filepath.Dir(configFile),
+	}
+	result, err := run.execute(cmd.Context())
+	if result != nil {
+		printApplyResult(log, result, dryRun, jsonOutput)
+	}
+	if verbose || jsonOutput {
+		printAPIStats(log, client.Stats(), jsonOutput)
+	}
+	return err
+}
+
+// checkPartitionFlags rejects the flags that do not apply to partitioned
+// configurations: accounts are set by the partitions, and the outputs that
+// describe a single change set would mix the partitions.
+func checkPartitionFlags(cmd *cobra.Command) error {
+	account, err := cmd.Flags().GetString("account")
+	if err != nil {
+		return fmt.Errorf("failed to get account flag: %w", err)
+	}
+	unsupported := []struct {
+		flag string
+		set  bool
+	}{
+		{"--account", account != ""},
+		{"--manifest", manifestIn != ""},
+		{"--manifest-out", manifestOut != ""},
+		{"--request-approval", approvalOut != ""},
+		{"--report", reportFormat != ""},
+		{"--annotations", annotationFormat != ""},
+		{"--show-since-last", showSinceLast},
+		{"--history-file", historyFile != ""},
+		{"--resume", resumeApply},
+		{"--json-summary-only", jsonSummaryOnly},
+	}
+	for _, u := range unsupported {
+		if u.set {
+			return fmt.Errorf("%s is not supported with partitions", u.flag)
+		}
+	}
+	return nil
+}
+
+// newPartitionClient creates the API client of a partition with its API URL
+// and key, falling back to those of the run. Partitions without credentials
+// of their own, and the file providers, use the client of the run.
+func newPartitionClient(
+	cmd *cobra.Command,
+	log *logger.Logger,
+	name string,
+	partition *config.Partition,
+	write bool,
+) (apiClient, error) {
+	provider, err := cmd.Flags().GetString("provider")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider flag: %w", err)
+	}
+	if provider != providerPowerDNS || (partition.APIURL == "" && partition.APIKeyEnv == "") {
+		return newAPIClient(cmd, log, write)
+	}
+
+	apiURL := partition.APIURL
+	if apiURL == "" {
+		if apiURL, err = cmd.Flags().GetString("api-url"); err != nil {
+			return nil, fmt.Errorf("failed to get api-url flag: %w", err)
+		}
+	}
+	if apiURL == "" {
+		project, err := currentSettings()
+		if err != nil {
+			return nil, err
+		}
+		apiURL = project.APIURL
+	}
+	apiKey, err := cmd.Flags().GetString("api-key")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api-key flag: %w", err)
+	}
+	if partition.APIKeyEnv != "" {
+		if apiKey = os.Getenv(partition.APIKeyEnv); apiKey == "" {
+			return nil, fmt.Errorf("the API key of partition %s is not set in %s", name, partition.APIKeyEnv)
+		}
+	}
+	if apiURL == "" || apiKey == "" {
+		return nil, errors.New(`required flag(s) "api-url", "api-key" not set`)
+	}
+
+	log.Debug("API URL: %s", apiURL)
+	log.Debug("API Key: %s", logger.MaskSecret(apiKey))
+	opts, err := getClientOptions(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return powerdns.NewClientWithOptions(apiURL, apiKey, opts, log), nil
+}
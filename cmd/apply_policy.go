@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/logger"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+	"github.com/kreigan/powerdns-zone-manager/internal/policy"
+	"github.com/kreigan/powerdns-zone-manager/internal/settings"
+)
+
+// loadPolicy loads the policy of the --policy flag or the project settings,
+// whose path is relative to the settings file. It returns nil without a policy.
+func loadPolicy(project *settings.Settings) (*policy.Policy, error) {
+	path := policyFile
+	if path == "" && project.Policy != "" {
+		path = project.Policy
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(project.Path), path)
+		}
+	}
+	if path == "" {
+		return nil, nil
+	}
+	return policy.Load(path)
+}
+
+// checkPolicy fails if the policy does not permit the planned changes.
+func checkPolicy(log *logger.Logger, plan *manager.ApplyResult, p *policy.Policy) error {
+	violations := p.Evaluate(plan, policyAllow)
+	if len(violations) == 0 {
+		log.Debug("Changes comply with %d policy rule(s)", len(p.Rules))
+		return nil
+	}
+	for _, v := range violations {
+		log.Error("Policy violation in %s: %s", v.Zone, v)
+	}
+	return fmt.Errorf("refusing to apply: %d policy violation(s)", len(violations))
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/journal"
+	"github.com/kreigan/powerdns-zone-manager/internal/logger"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+)
+
+// journalPath returns the path of the journal of failed runs of a config
+// file: journal.FileName in the directory of the apply record.
+func journalPath(configFile string) string {
+	return filepath.Join(filepath.Dir(historyPath(configFile)), journal.FileName)
+}
+
+// resumeZones returns the zones that the failed run recorded in the journal
+// at path applied, for --resume. Without a journal, all zones are applied.
+func resumeZones(log *logger.Logger, cfg *config.Config, account, path string) (map[string]bool, error) {
+	j, err := journal.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if j == nil {
+		log.Info("No failed run recorded in %s, applying all zones", path)
+		return nil, nil
+	}
+	if j.Account != account {
+		return nil, fmt.Errorf("the failed run in %s was applied with account %q, not %q", path, j.Account, account)
+	}
+	completed, err := j.Completed(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if changed := len(j.Zones) - len(completed); changed > 0 {
+		log.Info("%d zone(s) applied by the failed run were changed or removed since, they are applied again",
+			changed)
+	}
+	log.Info("Resuming run %s of %s: skipping %d zone(s) applied before", j.RunID,
+		j.FailedAt.Format("2006-01-02 15:04:05 UTC"), len(completed))
+	return completed, nil
+}
+
+// recordJournal records the zones a failed apply completed for a later
+// --resume, or removes the journal after a successful apply. The journal is
+// kept if the apply failed before any zone was applied.
+func recordJournal(
+	log *logger.Logger,
+	cfg *config.Config,
+	account, runID string,
+	result *manager.ApplyResult,
+	applyErr error,
+	path string,
+) error {
+	if applyErr == nil {
+		return journal.Remove(path)
+	}
+	if result == nil {
+		// No zone was applied, e.g. the configuration is invalid
+		return nil
+	}
+	j, err := journal.New(cfg, account, runID, result)
+	if err != nil {
+		return err
+	}
+	if len(j.Zones) == 0 {
+		return journal.Remove(path)
+	}
+	if err := j.Save(path); err != nil {
+		return err
+	}
+	log.Info("%d completed zone(s) recorded in %s, apply again with --resume to skip them", len(j.Zones), path)
+	return nil
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/logger"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+	"github.com/kreigan/powerdns-zone-manager/internal/manifest"
+	"github.com/kreigan/powerdns-zone-manager/internal/settings"
+)
+
+// applyRun applies a configuration the way apply does, for apply, its
+// partitions, approve and serve-api: the plan is checked first (see
+// checkPlan), and unless this is a dry run, the dnsdist caches are purged,
+// the post_apply hooks run and the journal and the history are recorded after
+// the apply. Locks are taken by the manager if opts enables them.
+type applyRun struct {
+	log     *logger.Logger
+	client  manager.Provider
+	mgr     *manager.Manager
+	project *settings.Settings
+	cfg     *config.Config
+	opts    manager.ApplyOptions
+	// approved is the manifest the plan must match, if any
+	approved *manifest.Manifest
+	hooks    *hookInput
+	// hookDir is the directory the validation hooks run in
+	hookDir string
+	// journal and history are the files the run is recorded in; nothing is
+	// recorded in an empty one
+	journal string
+	history string
+}
+
+// rejectedError is a plan rejected by a check of checkPlan, as opposed to a
+// plan that could not be computed.
+type rejectedError struct {
+	err error
+}
+
+func (e *rejectedError) Error() string { return e.err.Error() }
+
+func (e *rejectedError) Unwrap() error { return e.err }
+
+// check checks the plan before anything is applied, see checkPlan.
+func (r *applyRun) check(ctx context.Context) error {
+	return checkPlan(ctx, r.log, r.client, r.cfg, r.project, r.approved, r.hooks, r.hookDir, &r.opts)
+}
+
+// apply applies the checked configuration and runs the follow-ups of a real
+// apply. The result is returned even if the apply failed, as partial result;
+// a failing cache purge or post_apply hook fails the run after the apply is
+// recorded.
+func (r *applyRun) apply(ctx context.Context) (*manager.ApplyResult, error) {
+	result, err := r.mgr.Apply(ctx, r.cfg, r.opts)
+	if r.opts.DryRun {
+		if err != nil {
+			return result, fmt.Errorf("failed to apply configuration: %w", err)
+		}
+		return result, nil
+	}
+
+	hookErr := errors.Join(
+		purgeDnsdistCaches(ctx, r.log, r.project, result),
+		runPostApplyHooks(ctx, r.log, r.project, r.hooks, result, err),
+	)
+	if r.journal != "" {
+		// A failed journal only makes a later --resume apply more zones
+		journalErr := recordJournal(r.log, r.cfg, r.hooks.Account, r.hooks.RunID, result, err, r.journal)
+		if journalErr != nil {
+			r.log.Warn("Failed to record journal: %v", journalErr)
+		}
+	}
+	if err != nil {
+		if hookErr != nil {
+			r.log.Error("%v", hookErr)
+		}
+		return result, fmt.Errorf("failed to apply configuration: %w", err)
+	}
+	if r.history != "" {
+		// A failed record only affects later --show-since-last reports
+		historyErr := recordApply(r.log, r.cfg, r.hooks.Account, r.hooks.RunID, result, r.history)
+		if historyErr != nil {
+			r.log.Warn("Failed to record apply: %v", historyErr)
+		}
+	}
+	return result, hookErr
+}
+
+// execute checks the plan and applies the configuration.
+func (r *applyRun) execute(ctx context.Context) (*manager.ApplyResult, error) {
+	if err := r.check(ctx); err != nil {
+		return nil, err
+	}
+	return r.apply(ctx)
+}
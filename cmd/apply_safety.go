@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+	"github.com/kreigan/powerdns-zone-manager/internal/settings"
+)
+
+// applySafetyFlags overrides the safety thresholds of the project settings
+// with the --max-changes and --max-deletions flags, if set.
+func applySafetyFlags(cmd *cobra.Command, project *settings.Settings) error {
+	flags := cmd.Flags()
+	if flags.Changed("max-changes") {
+		project.Safety.MaxChanges = maxChanges
+	}
+	if flags.Changed("max-deletions") {
+		project.Safety.MaxDeletions = maxDeletions
+	}
+	if project.Safety.MaxChanges < 0 || project.Safety.MaxDeletions < 0 {
+		return fmt.Errorf("--max-changes and --max-deletions cannot be negative")
+	}
+	return nil
+}
+
+// checkSafety fails if the planned changes exceed the safety thresholds.
+func checkSafety(plan *manager.ApplyResult, limits settings.Safety) error {
+	changes := plan.RRsetsCreated + plan.RRsetsUpdated + plan.RRsetsDeleted
+	if limits.MaxChanges > 0 && changes > limits.MaxChanges {
+		return fmt.Errorf("refusing to apply %d rrset change(s), more than the limit of %d (--max-changes)",
+			changes, limits.MaxChanges)
+	}
+	if limits.MaxDeletions > 0 && plan.RRsetsDeleted > limits.MaxDeletions {
+		return fmt.Errorf("refusing to delete %d rrset(s), more than the limit of %d (--max-deletions)",
+			plan.RRsetsDeleted, limits.MaxDeletions)
+	}
+	return nil
+}
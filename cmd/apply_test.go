@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kreigan/powerdns-zone-manager/pkg/output"
+)
+
+// captureStdout returns what run writes to stdout, and the error of run.
+func captureStdout(t *testing.T, run func() error) (string, error) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	data := make(chan []byte)
+	go func() {
+		b, _ := io.ReadAll(r) //nolint:errcheck // a short read fails the test below
+		data <- b
+	}()
+	runErr := run()
+	_ = w.Close() //nolint:errcheck // the reader sees EOF either way
+	return string(<-data), runErr
+}
+
+func TestApply_JSONSummaryOnly(t *testing.T) {
+	tests := []struct {
+		name string
+		// owner is the account that created the zone before, if any
+		owner  string
+		status string
+	}{
+		{name: "applied", status: "ok"},
+		{name: "failed", owner: "other", status: "failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(func() { jsonSummaryOnly = false })
+			dir := t.TempDir()
+			configFile := filepath.Join(dir, "zones.yml")
+			data := "zones:\n  example.com:\n    nameservers: [ns1.example.com.]\n" +
+				"    rrsets:\n      - {name: www, type: A, records: 192.0.2.1}\n"
+			if err := os.WriteFile(configFile, []byte(data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			apply := func(account string) func() error {
+				return func() error {
+					rootCmd.SetArgs([]string{"apply", "-y", "--json-summary-only", "--provider", "file",
+						"--provider-dir", filepath.Join(dir, "provider"), "--account", account, configFile})
+					return rootCmd.Execute()
+				}
+			}
+			if tt.owner != "" {
+				if _, err := captureStdout(t, apply(tt.owner)); err != nil {
+					t.Fatalf("Failed to create the zone: %v", err)
+				}
+			}
+
+			out, err := captureStdout(t, apply("test"))
+			if (err != nil) != (tt.status == "failed") {
+				t.Errorf("Unexpected apply error: %v", err)
+			}
+			dec := json.NewDecoder(strings.NewReader(out))
+			var entries []output.Entry
+			for {
+				var entry output.Entry
+				if err := dec.Decode(&entry); errors.Is(err, io.EOF) {
+					break
+				} else if err != nil {
+					t.Fatalf("stdout is not JSON: %v\n%s", err, out)
+				}
+				entries = append(entries, entry)
+			}
+			if len(entries) != 1 || entries[0].Message != output.MessageApplyCompleted {
+				t.Fatalf("Expected exactly one %q object on stdout, got:\n%s", output.MessageApplyCompleted, out)
+			}
+			var result output.ApplyCompleted
+			if err := entries[0].DecodeData(&result); err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Zones) != 1 || result.Zones[0].Status != tt.status {
+				t.Errorf("Expected zone status %s, got %+v", tt.status, result.Zones)
+			}
+		})
+	}
+}
+
+func TestApply_Safety(t *testing.T) {
+	tests := []struct {
+		name string
+		// settings is the content of the project settings file
+		settings string
+		args     []string
+		wantErr  string
+	}{
+		{name: "no limit"},
+		{
+			name:     "settings",
+			settings: "safety:\n  max_changes: 2\n",
+			// The nameservers of the created zone are updated as well
+			wantErr: "refusing to apply 3 rrset change(s), more than the limit of 2",
+		},
+		{name: "flag", args: []string{"--max-changes", "2"}, wantErr: "more than the limit of 2"},
+		{
+			name:     "flag overrides settings",
+			settings: "safety:\n  max_changes: 2\n",
+			args:     []string{"--max-changes", "3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				// Flags keep their values between runs
+				maxChanges = 0
+				applyCmd.Flags().Lookup("max-changes").Changed = false
+			})
+			dir := t.TempDir()
+			configFile := filepath.Join(dir, "zones.yml")
+			data := "zones:\n  example.com:\n    nameservers: [ns1.example.com.]\n" +
+				"    rrsets:\n      - {name: www, type: A, records: 192.0.2.1}\n" +
+				"      - {name: mail, type: A, records: 192.0.2.2}\n"
+			if err := os.WriteFile(configFile, []byte(data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if tt.settings != "" {
+				if err := os.WriteFile(filepath.Join(dir, ".pdns-zm.yaml"), []byte(tt.settings), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			providerDir := filepath.Join(dir, "provider")
+			args := []string{"apply", "-y", "--provider", "file", "--provider-dir", providerDir, "--account", "test"}
+			rootCmd.SetArgs(append(append(args, tt.args...), configFile))
+			err := rootCmd.Execute()
+			_, statErr := os.Stat(filepath.Join(providerDir, "example.com.json"))
+			if tt.wantErr == "" {
+				if err != nil || statErr != nil {
+					t.Errorf("Expected the zone to be applied, got %v (%v)", err, statErr)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if statErr == nil {
+				t.Error("Expected the zone not to be created")
+			}
+		})
+	}
+}
+
+func TestApply_ReportError(t *testing.T) {
+	t.Cleanup(func() { reportFormat, reportFile = "", "" })
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "zones.yml")
+	data := "zones:\n  example.com:\n    nameservers: [ns1.example.com.]\n" +
+		"    rrsets:\n      - {name: www, type: A, records: 192.0.2.1}\n"
+	if err := os.WriteFile(configFile, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	apply := func(account string, args ...string) error {
+		rootCmd.SetArgs(append([]string{"apply", "-y", "--provider", "file",
+			"--provider-dir", filepath.Join(dir, "provider"), "--account", account, configFile}, args...))
+		return rootCmd.Execute()
+	}
+	if err := apply("other"); err != nil {
+		t.Fatalf("Failed to create the zone: %v", err)
+	}
+
+	// The zone of another account fails the apply, and the report cannot be
+	// written either: the apply error is the one returned
+	err := apply("test", "--report", "html", "--report-file", filepath.Join(dir, "missing", "report.html"))
+	if err == nil || !strings.Contains(err.Error(), "failed to apply configuration") {
+		t.Errorf("Expected the apply error, got %v", err)
+	}
+}
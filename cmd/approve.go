@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/approval"
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/logger"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+	"github.com/kreigan/powerdns-zone-manager/internal/manifest"
+)
+
+var approveCmd = &cobra.Command{
+	Use:   "approve bundle-file",
+	Short: "Approve and apply a pending change bundle",
+	Long: `Approve and apply a pending change bundle written by "apply --request-approval".
+
+The bundle is only applied if its signature is valid (key from ` + manifestKeyEnv + `),
+it has not expired, the token matches, the approving operator is not the one
+who requested it and the change set is still exactly the one in the bundle.
+Operators are the users the commands run as; they cannot be named on the
+command line, so that the requester cannot approve in someone else's name.
+
+The bundle is applied like "apply --auto-confirm --manifest": the project
+settings of the bundled config's directory are checked and its hooks run,
+and the apply is recorded next to the config.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runApprove,
+}
+
+var approvalToken string
+
+func init() {
+	rootCmd.AddCommand(approveCmd)
+	approveCmd.Flags().StringVar(&approvalToken, "token", "", "Approval token printed when the bundle was written")
+	if err := approveCmd.MarkFlagRequired("token"); err != nil {
+		panic(fmt.Sprintf("failed to mark token flag as required: %v", err))
+	}
+	approveCmd.Flags().BoolVar(&lockZones, "lock", false,
+		"Lock existing zones with a TXT record while they are applied, failing if another run holds the lock")
+	approveCmd.Flags().DurationVar(&lockTTL, "lock-ttl", 15*time.Minute,
+		"How long zone locks are valid, after which they can be taken over")
+}
+
+func runApprove(cmd *cobra.Command, args []string) error {
+	log, err := newLogger(cmd)
+	if err != nil {
+		return err
+	}
+	jsonOutput, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		return fmt.Errorf("failed to get json flag: %w", err)
+	}
+	runID, err := getRunID(cmd)
+	if err != nil {
+		return err
+	}
+	log.SetRunID(runID)
+	log.Info("Run ID: %s", runID)
+	key, err := getManifestKey()
+	if err != nil {
+		return err
+	}
+	approver, err := operatorName()
+	if err != nil {
+		return err
+	}
+
+	path := args[0]
+	bundle, err := approval.Load(path)
+	if err != nil {
+		return err
+	}
+	if err := bundle.Approve(key, approvalToken, approver, time.Now()); err != nil {
+		return fmt.Errorf("bundle %s: %w", path, err)
+	}
+	log.Info("Bundle requested by %s at %s, approved by %s",
+		bundle.RequestedBy, bundle.RequestedAt.Format(time.RFC3339), approver)
+
+	// The config was read from this file, or from stdin in the working directory
+	configFile := bundle.ConfigSource
+	if configFile == configSource(stdinPath) {
+		configFile = stdinPath
+	}
+	project, err := loadSettings(configFile)
+	if err != nil {
+		return err
+	}
+	cfg, err := config.LoadFromNamedReader(bytes.NewReader(bundle.Config), bundle.ConfigSource)
+	if err != nil {
+		return fmt.Errorf("failed to load config from bundle: %w", err)
+	}
+	if bundle.DefaultTTL != 0 {
+		cfg.SetDefaultTTL(bundle.DefaultTTL)
+	}
+	accountName := bundle.Manifest.Account
+	log.Debug("Account name: %s", accountName)
+
+	client, err := newAPIClient(cmd, log, true)
+	if err != nil {
+		return err
+	}
+	pacing, err := patchPacing(cmd, project)
+	if err != nil {
+		return err
+	}
+
+	mgr := manager.NewManager(client, accountName, log)
+	mgr.SetToolVersion(version)
+	mgr.SetRunID(runID)
+	if jsonOutput {
+		streamEvents(log, mgr)
+	}
+	run := &applyRun{
+		log:     log,
+		client:  client,
+		mgr:     mgr,
+		project: project,
+		cfg:     cfg,
+		opts: manager.ApplyOptions{
+			AutoConfirm: true,
+			Pacing:      pacing,
+			Lock:        lockZones,
+			LockTTL:     lockTTL,
+		},
+		approved: bundle.Manifest,
+		hooks:    &hookInput{RunID: runID, Account: accountName, Config: bundle.ConfigSource},
+		hookDir:  filepath.Dir(configFile),
+		journal:  journalPath(configFile),
+		history:  historyPath(configFile),
+	}
+	if err := run.check(cmd.Context()); err != nil {
+		return err
+	}
+	log.Info("Applying configuration from %s...", bundle.ConfigSource)
+	result, err := run.apply(cmd.Context())
+	if result != nil {
+		printApplyResult(log, result, false, jsonOutput)
+	}
+	return err
+}
+
+// operatorName returns the name of the operator requesting or approving a
+// bundle, the user the command runs as.
+func operatorName() (string, error) {
+	u, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine the operator: %w", err)
+	}
+	return u.Username, nil
+}
+
+// writeApprovalBundle writes a signed pending change bundle and prints the
+// approval token, which is not stored in the bundle.
+func writeApprovalBundle(
+	log *logger.Logger,
+	result *manager.ApplyResult,
+	accountName, configFile string,
+	configData []byte,
+	defaultTTL uint32,
+) error {
+	if result == nil {
+		return errors.New("no change set to write to the bundle")
+	}
+	key, err := getManifestKey()
+	if err != nil {
+		return err
+	}
+	requester, err := operatorName()
+	if err != nil {
+		return err
+	}
+
+	m := manifest.New(accountName, result)
+	if serialPrecondition {
+		m.RecordSerials(result)
+	}
+	bundle, token, err := approval.New(m, configData,
+		configSource(configFile), requester, approvalTTL)
+	if err != nil {
+		return err
+	}
+	bundle.DefaultTTL = defaultTTL
+	bundle.Sign(key)
+	if err := bundle.Save(approvalOut); err != nil {
+		return err
+	}
+
+	log.Info("Pending change bundle written to %s (expires %s)", approvalOut, bundle.ExpiresAt.Format(time.RFC3339))
+	log.Info("Approval token: %s", token)
+	log.Info("Another operator must run: approve --token %s %s", token, approvalOut)
+	return nil
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/logger"
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+var autoprimaryCmd = &cobra.Command{
+	Use:   "autoprimary",
+	Short: "Manage PowerDNS autoprimaries (supermasters)",
+	Long: `Manage PowerDNS autoprimaries (supermasters).
+
+An autoprimary is a primary server that may provision secondary zones on the
+PowerDNS server by sending a NOTIFY, as long as it is listed under the given
+nameserver name in the zone's NS records.`,
+}
+
+var autoprimaryListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List autoprimaries",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         runAutoprimaryList,
+}
+
+var autoprimaryAddCmd = &cobra.Command{
+	Use:          "add ip nameserver",
+	Short:        "Add an autoprimary",
+	Long:         "Add an autoprimary. Provisioned zones are assigned the configured account name.",
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+	RunE:         runAutoprimaryAdd,
+}
+
+var autoprimaryDeleteCmd = &cobra.Command{
+	Use:          "delete ip nameserver",
+	Short:        "Delete an autoprimary",
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+	RunE:         runAutoprimaryDelete,
+}
+
+func init() {
+	rootCmd.AddCommand(autoprimaryCmd)
+	autoprimaryCmd.AddCommand(autoprimaryListCmd, autoprimaryAddCmd, autoprimaryDeleteCmd)
+}
+
+// autoprimaryClient is implemented by providers that support autoprimaries.
+type autoprimaryClient interface {
+	ListAutoprimaries(ctx context.Context) ([]powerdns.Autoprimary, error)
+	AddAutoprimary(ctx context.Context, autoprimary *powerdns.Autoprimary) error
+	DeleteAutoprimary(ctx context.Context, ip, nameserver string) error
+}
+
+func newAutoprimaryClient(cmd *cobra.Command, log *logger.Logger, write bool) (autoprimaryClient, error) {
+	client, err := newAPIClient(cmd, log, write)
+	if err != nil {
+		return nil, err
+	}
+	ap, ok := client.(autoprimaryClient)
+	if !ok {
+		return nil, fmt.Errorf("the configured provider does not support autoprimaries")
+	}
+	return ap, nil
+}
+
+// validateAutoprimary checks the ip and nameserver arguments.
+func validateAutoprimary(ip, nameserver string) error {
+	if net.ParseIP(ip) == nil {
+		return fmt.Errorf("invalid IP address %q", ip)
+	}
+	if nameserver == "" {
+		return fmt.Errorf("nameserver cannot be empty")
+	}
+	return nil
+}
+
+func runAutoprimaryList(cmd *cobra.Command, _ []string) error {
+	log, err := newLogger(cmd)
+	if err != nil {
+		return err
+	}
+	client, err := newAutoprimaryClient(cmd, log, false)
+	if err != nil {
+		return err
+	}
+
+	autoprimaries, err := client.ListAutoprimaries(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to list autoprimaries: %w", err)
+	}
+
+	rows := make([][]string, len(autoprimaries))
+	for i, ap := range autoprimaries {
+		rows[i] = []string{ap.IP, ap.Nameserver, ap.Account}
+	}
+	log.Table("Autoprimaries", []string{"IP", "NAMESERVER", "ACCOUNT"}, rows)
+	return nil
+}
+
+func runAutoprimaryAdd(cmd *cobra.Command, args []string) error {
+	ip, nameserver := args[0], args[1]
+	if err := validateAutoprimary(ip, nameserver); err != nil {
+		return err
+	}
+
+	log, err := newLogger(cmd)
+	if err != nil {
+		return err
+	}
+	client, err := newAutoprimaryClient(cmd, log, true)
+	if err != nil {
+		return err
+	}
+
+	account, err := getAccountName(cmd, nil)
+	if err != nil {
+		return err
+	}
+	autoprimary := &powerdns.Autoprimary{IP: ip, Nameserver: nameserver, Account: account}
+	if err := client.AddAutoprimary(context.Background(), autoprimary); err != nil {
+		return fmt.Errorf("failed to add autoprimary: %w", err)
+	}
+
+	log.Info("Added autoprimary %s (%s)", ip, nameserver)
+	return nil
+}
+
+func runAutoprimaryDelete(cmd *cobra.Command, args []string) error {
+	ip, nameserver := args[0], args[1]
+	if err := validateAutoprimary(ip, nameserver); err != nil {
+		return err
+	}
+
+	log, err := newLogger(cmd)
+	if err != nil {
+		return err
+	}
+	client, err := newAutoprimaryClient(cmd, log, true)
+	if err != nil {
+		return err
+	}
+
+	if err := client.DeleteAutoprimary(context.Background(), ip, nameserver); err != nil {
+		return fmt.Errorf("failed to delete autoprimary: %w", err)
+	}
+
+	log.Info("Deleted autoprimary %s (%s)", ip, nameserver)
+	return nil
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/backup"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Back up the managed zones to an archive",
+	Long: `Back up all zones of the configured account to a single archive.
+
+The archive (a gzip compressed tar file) contains every managed zone with all
+its RRsets, including unmanaged ones, and its metadata, plus a backup.json
+describing the backup. Restore it with "restore", e.g. on a fresh PowerDNS
+instance after the backend database was lost.
+
+backup.json records a hash of the content of every zone. With
+--incremental-from, only the zones that changed since the given archive are
+included; the others are restored from it, so it must be kept next to the
+incremental archive. Incremental archives can be the base of further
+incremental archives.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         runBackup,
+}
+
+var backupOutput string
+var backupIncrementalFrom string
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+	backupCmd.Flags().StringVarP(&backupOutput, "output", "o", "", "Path of the backup archive, e.g. backup.tar.gz")
+	backupCmd.Flags().StringVar(&backupIncrementalFrom, "incremental-from", "",
+		"Only include the zones that changed since this backup archive")
+
+	if err := backupCmd.MarkFlagRequired("output"); err != nil {
+		panic(fmt.Sprintf("failed to mark output as required: %v", err))
+	}
+}
+
+func runBackup(cmd *cobra.Command, _ []string) error {
+	log, err := newLogger(cmd)
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cmd, log, false)
+	if err != nil {
+		return err
+	}
+	lister, ok := client.(zoneLister)
+	if !ok {
+		return fmt.Errorf("the configured provider does not support listing zones")
+	}
+	accountName, err := getAccountName(cmd, nil)
+	if err != nil {
+		return err
+	}
+	var base *backup.Archive
+	if backupIncrementalFrom != "" {
+		if base, err = backup.Load(backupIncrementalFrom); err != nil {
+			return err
+		}
+	}
+
+	ctx := cmd.Context()
+	list, err := lister.ListZones(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list zones: %w", err)
+	}
+
+	metadata, hasMetadata := client.(manager.MetadataProvider)
+	var zones []backup.Zone
+	for _, info := range list {
+		if info.Account != accountName {
+			continue
+		}
+		zone, err := client.GetZone(ctx, info.Name)
+		if err != nil {
+			return fmt.Errorf("failed to get zone %s: %w", info.Name, err)
+		}
+		if zone == nil {
+			log.Warn("Zone %s was removed while backing up, skipped", info.Name)
+			continue
+		}
+		z := backup.Zone{Zone: zone}
+		if hasMetadata {
+			if z.Metadata, err = metadata.GetMetadata(ctx, zone.Name); err != nil {
+				return fmt.Errorf("failed to get metadata of zone %s: %w", zone.Name, err)
+			}
+		}
+		log.Debug("Backing up zone %s (%d RRsets)", zone.Name, len(zone.RRsets))
+		zones = append(zones, z)
+	}
+	if len(zones) == 0 {
+		log.Warn("No zones of account %s found", accountName)
+	}
+
+	archive := backup.New(accountName, version, zones)
+	if base == nil {
+		if err := archive.Save(backupOutput); err != nil {
+			return err
+		}
+		log.Info("Backed up %d zone(s) to %s", len(zones), backupOutput)
+		return nil
+	}
+
+	baseFile, err := relativeBase(backupIncrementalFrom, backupOutput)
+	if err != nil {
+		return err
+	}
+	unchanged := archive.Incremental(base, baseFile)
+	if err := archive.Save(backupOutput); err != nil {
+		return err
+	}
+	log.Info("Backed up %d changed zone(s) to %s, %d zone(s) unchanged since %s",
+		len(archive.Zones), backupOutput, unchanged, backupIncrementalFrom)
+	return nil
+}
+
+// relativeBase returns the path of the base archive relative to the directory
+// of the incremental archive.
+func relativeBase(base, output string) (string, error) {
+	absBase, err := filepath.Abs(base)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", base, err)
+	}
+	absOutput, err := filepath.Abs(output)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", output, err)
+	}
+	if absBase == absOutput {
+		return "", fmt.Errorf("--incremental-from and --output must be different files")
+	}
+	rel, err := filepath.Rel(filepath.Dir(absOutput), absBase)
+	if err != nil {
+		return absBase, nil //nolint:nilerr // not relative to each other, e.g. on Windows drives
+	}
+	return rel, nil
+}
@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/confirm"
+	"github.com/kreigan/powerdns-zone-manager/internal/history"
+	"github.com/kreigan/powerdns-zone-manager/internal/i18n"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+	"github.com/kreigan/powerdns-zone-manager/internal/ownership"
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+var cleanupCmd = &cobra.Command{
+	Use:   "cleanup [config-file]",
+	Short: "Delete managed RRsets that were removed from the configuration long ago",
+	Long: `Find managed RRsets that have not been in the configuration for a long time
+but still exist on the server, e.g. because their zone was removed from the
+configuration or a past apply failed, and offer to delete them.
+
+RRsets are found in the apply record next to the config file (see apply
+--show-since-last), which keeps when every RRset was last configured in an
+apply. Only RRsets that are still managed by the configured account are
+deleted. RRsets that no longer exist are dropped from the record.
+Use "-" as the config file to read the configuration from standard input.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runCleanup,
+}
+
+var cleanupOlderThan string
+var cleanupDryRun bool
+var cleanupAutoConfirm bool
+
+func init() {
+	rootCmd.AddCommand(cleanupCmd)
+	cleanupCmd.Flags().StringVar(&cleanupOlderThan, "older-than", "90d",
+		"Only RRsets last configured longer ago than this, e.g. 90d or 36h")
+	cleanupCmd.Flags().BoolVar(&cleanupDryRun, "dry-run", false, "Only report the stale RRsets")
+	cleanupCmd.Flags().BoolVarP(&cleanupAutoConfirm, "auto-confirm", "y", false, "Skip confirmation prompt")
+	cleanupCmd.Flags().StringVar(&historyFile, "history-file", "",
+		"Path of the apply record (default: "+history.FileName+" next to the config file)")
+}
+
+// parseAge parses a duration that may also be given in days, e.g. 90d.
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// staleRRset is a managed RRset that is no longer configured.
+type staleRRset struct {
+	lastConfigured time.Time
+	zoneID         string
+	key            string
+	rrset          powerdns.RRset
+}
+
+func runCleanup(cmd *cobra.Command, args []string) error {
+	age, err := parseAge(cleanupOlderThan)
+	if err != nil {
+		return fmt.Errorf("--older-than: %w", err)
+	}
+	log, err := newLogger(cmd)
+	if err != nil {
+		return err
+	}
+	jsonOutput, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		return fmt.Errorf("failed to get json flag: %w", err)
+	}
+	if !cleanupDryRun && !cleanupAutoConfirm && (jsonOutput || args[0] == stdinPath) {
+		return fmt.Errorf("cleanup with --json or the config from stdin requires --auto-confirm or --dry-run")
+	}
+	if _, err := loadSettings(args[0]); err != nil {
+		return err
+	}
+	cfg, _, err := loadConfig(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to load config from %s: %w", configSource(args[0]), err)
+	}
+	accountName, err := getAccountName(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	path := historyPath(args[0])
+	record, err := history.Load(path)
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		return fmt.Errorf("no apply recorded in %s, cleanup needs the record of past applies", path)
+	}
+	if record.Account != accountName {
+		return fmt.Errorf("apply record %s is of account %s, not %s", path, record.Account, accountName)
+	}
+	state, err := history.Snapshot(cfg)
+	if err != nil {
+		return err
+	}
+
+	client, err := newAPIClient(cmd, log, !cleanupDryRun)
+	if err != nil {
+		return err
+	}
+	ctx := cmd.Context()
+
+	unconfigured := record.Unconfigured(state, time.Now().Add(-age))
+	zoneIDs := make([]string, 0, len(unconfigured))
+	for zoneID := range unconfigured {
+		zoneIDs = append(zoneIDs, zoneID)
+	}
+	sort.Strings(zoneIDs)
+
+	var stale []staleRRset
+	pruned := 0
+	for _, zoneID := range zoneIDs {
+		zone, err := client.GetZone(ctx, zoneID)
+		if err != nil {
+			return fmt.Errorf("failed to get zone %s: %w", zoneID, err)
+		}
+		for _, key := range unconfigured[zoneID] {
+			rrset, found := findRRset(zone, key)
+			if !found {
+				record.Forget(zoneID, key)
+				pruned++
+				continue
+			}
+			if !ownership.OwnedBy(&rrset, accountName) {
+				continue
+			}
+			stale = append(stale, staleRRset{
+				zoneID:         zoneID,
+				key:            key,
+				rrset:          rrset,
+				lastConfigured: record.Seen[zoneID][key],
+			})
+		}
+	}
+
+	rows := make([][]string, len(stale))
+	for i, s := range stale {
+		rows[i] = []string{s.zoneID, s.rrset.Name, s.rrset.Type, s.lastConfigured.Format(time.RFC3339)}
+	}
+	log.Table("Stale managed RRsets", []string{"ZONE", "NAME", "TYPE", "LAST CONFIGURED"}, rows)
+
+	if len(stale) > 0 && !cleanupDryRun {
+		deleted, err := deleteStale(cmd, client, stale, record)
+		if deleted > 0 {
+			log.Info("Deleted %d stale RRset(s)", deleted)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if pruned > 0 || (len(stale) > 0 && !cleanupDryRun) {
+		if cleanupDryRun {
+			log.Info("%d RRset(s) of the apply record no longer exist", pruned)
+			return nil
+		}
+		if err := record.Save(path); err != nil {
+			return err
+		}
+		log.Debug("Apply record %s updated", path)
+	}
+	return nil
+}
+
+// deleteStale deletes the stale RRsets, by zone, after confirmation, and
+// drops them from the record. It returns the number of deleted RRsets.
+func deleteStale(
+	cmd *cobra.Command,
+	client manager.Provider,
+	stale []staleRRset,
+	record *history.Record,
+) (int, error) {
+	ctx := cmd.Context()
+	if !cleanupAutoConfirm {
+		req := &manager.ConfirmRequest{Prompt: i18n.T("Delete %d stale RRset(s)?", len(stale))}
+		ok, err := confirm.NewTerminal(os.Stdin, os.Stdout).Confirm(ctx, req)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			return 0, manager.ErrAborted
+		}
+	}
+
+	deleted := 0
+	for start := 0; start < len(stale); {
+		end := start
+		patch := &powerdns.ZonePatch{}
+		for end < len(stale) && stale[end].zoneID == stale[start].zoneID {
+			patch.RRsets = append(patch.RRsets, powerdns.RRset{
+				Name:       stale[end].rrset.Name,
+				Type:       stale[end].rrset.Type,
+				ChangeType: "DELETE",
+			})
+			end++
+		}
+		if err := client.PatchZone(ctx, stale[start].zoneID, patch); err != nil {
+			return deleted, fmt.Errorf("failed to delete stale RRsets of %s: %w", stale[start].zoneID, err)
+		}
+		for _, s := range stale[start:end] {
+			record.Forget(s.zoneID, s.key)
+		}
+		deleted += end - start
+		start = end
+	}
+	return deleted, nil
+}
+
+// findRRset returns the RRset of a zone with the key "name TYPE". zone may be
+// nil if the zone does not exist.
+func findRRset(zone *powerdns.Zone, key string) (powerdns.RRset, bool) {
+	if zone == nil {
+		return powerdns.RRset{}, false
+	}
+	i := strings.LastIndex(key, " ")
+	if i < 0 {
+		return powerdns.RRset{}, false
+	}
+	name, rtype := key[:i], key[i+1:]
+	for _, rrset := range zone.RRsets {
+		if strings.EqualFold(rrset.Name, name) && strings.EqualFold(rrset.Type, rtype) {
+			return rrset, true
+		}
+	}
+	return powerdns.RRset{}, false
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/compare"
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/logger"
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+var compareCmd = &cobra.Command{
+	Use:   "compare [zone...]",
+	Short: "Compare the zones and RRsets of two PowerDNS servers",
+	Long: `Compare the zones and RRsets of two PowerDNS servers, e.g. before and after
+a migration, or a primary and its disaster recovery server, and list the zones
+and RRsets that only exist on one server or differ in TTL or records.
+
+Without zone arguments, all zones of both servers are compared. With --managed,
+only the zones and RRsets of the configured account are compared: RRsets with
+its ownership comments on either server.
+
+Comments are not compared, and the serial of SOA records is ignored. The
+command fails if the servers differ.`,
+	SilenceUsage: true,
+	RunE:         runCompare,
+}
+
+var (
+	compareSource    string
+	compareTarget    string
+	compareSourceKey string
+	compareTargetKey string
+	compareManaged   bool
+)
+
+func init() {
+	rootCmd.AddCommand(compareCmd)
+	compareCmd.Flags().StringVar(&compareSource, "source", "", "PowerDNS API base URL of the source server")
+	compareCmd.Flags().StringVar(&compareTarget, "target", "", "PowerDNS API base URL of the target server")
+	compareCmd.Flags().StringVar(&compareSourceKey, "source-api-key", "",
+		"PowerDNS API key of the source server (defaults to --api-key)")
+	compareCmd.Flags().StringVar(&compareTargetKey, "target-api-key", "",
+		"PowerDNS API key of the target server (defaults to --api-key)")
+	compareCmd.Flags().BoolVar(&compareManaged, "managed", false,
+		"Only compare the zones and RRsets managed by the configured account")
+}
+
+func runCompare(cmd *cobra.Command, args []string) error {
+	log, err := newLogger(cmd)
+	if err != nil {
+		return err
+	}
+	source, err := newServerClient(cmd, log, "source", compareSource, compareSourceKey)
+	if err != nil {
+		return err
+	}
+	target, err := newServerClient(cmd, log, "target", compareTarget, compareTargetKey)
+	if err != nil {
+		return err
+	}
+	var opts compare.Options
+	if compareManaged {
+		if opts.Account, err = getAccountName(cmd, nil); err != nil {
+			return err
+		}
+	}
+
+	ctx := cmd.Context()
+	names := make([]string, len(args))
+	for i, arg := range args {
+		names[i] = config.CanonicalZoneName(arg)
+	}
+	if len(names) == 0 {
+		if names, err = serverZoneNames(cmd, opts.Account, source, target); err != nil {
+			return err
+		}
+	}
+
+	var rows [][]string
+	differences := 0
+	for _, name := range names {
+		sourceZone, err := source.GetZone(ctx, name)
+		if err != nil {
+			return fmt.Errorf("failed to get zone %s from the source server: %w", name, err)
+		}
+		targetZone, err := target.GetZone(ctx, name)
+		if err != nil {
+			return fmt.Errorf("failed to get zone %s from the target server: %w", name, err)
+		}
+		for _, diff := range compare.Zone(name, sourceZone, targetZone, opts) {
+			rows = append(rows, []string{
+				diff.Zone, diff.Name, diff.Type, string(diff.Status),
+				compare.Records(diff.Source), compare.Records(diff.Target),
+			})
+			differences++
+		}
+	}
+
+	log.Table("Differences", []string{"ZONE", "NAME", "TYPE", "STATUS", "SOURCE", "TARGET"}, rows)
+	if differences > 0 {
+		return fmt.Errorf("%d difference(s) in %d zone(s) between %s and %s",
+			differences, len(names), compareSource, compareTarget)
+	}
+	log.Info("All %d zone(s) match", len(names))
+	return nil
+}
+
+// newServerClient creates the client of one of the compared servers. The API
+// key defaults to --api-key.
+func newServerClient(cmd *cobra.Command, log *logger.Logger, role, url, key string) (*powerdns.Client, error) {
+	if url == "" {
+		return nil, fmt.Errorf(`required flag "%s" not set`, role)
+	}
+	if key == "" {
+		var err error
+		if key, err = cmd.Flags().GetString("api-key"); err != nil {
+			return nil, fmt.Errorf("failed to get api-key flag: %w", err)
+		}
+	}
+	if key == "" {
+		return nil, fmt.Errorf(`either "%s-api-key" or "api-key" must be set`, role)
+	}
+	log.Debug("%s API URL: %s", role, url)
+	log.Debug("%s API Key: %s", role, logger.MaskSecret(key))
+
+	opts, err := getClientOptions(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return powerdns.NewClientWithOptions(url, key, opts, log), nil
+}
+
+// serverZoneNames returns the sorted names of the zones of both servers. With
+// an account, only the zones of the account are returned.
+func serverZoneNames(cmd *cobra.Command, account string, servers ...*powerdns.Client) ([]string, error) {
+	seen := make(map[string]bool)
+	for _, server := range servers {
+		zones, err := server.ListZones(cmd.Context())
+		if err != nil {
+			return nil, fmt.Errorf("failed to list zones: %w", err)
+		}
+		for _, zone := range zones {
+			if account == "" || zone.Account == account {
+				seen[zone.Name] = true
+			}
+		}
+	}
+	if len(seen) == 0 {
+		return nil, errors.New("no zones to compare")
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,304 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/logger"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+	"github.com/kreigan/powerdns-zone-manager/internal/settings"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor [config-file]",
+	Short: "Diagnose the environment and API connectivity",
+	Long: `Run diagnostic checks and print how to fix any problem found.
+
+The checks cover the settings and configuration file (if given), the account
+name sources, API reachability, API key validity and the server version. If a
+configuration file is given, its zones are checked for accounts conflicting
+with the configured one and the configuration is validated against the server.
+
+Comment support depends on the PowerDNS backend. Pass --probe-zone to write a
+temporary ` + doctorProbeName + ` TXT record with a comment to that zone,
+read it back and delete it again. Without it, doctor only reads from the API.`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE:         runDoctor,
+}
+
+var doctorProbeZone string
+
+// doctorProbeName is the name of the temporary record written by the comment check.
+const doctorProbeName = "_zone-manager-doctor"
+
+// Diagnostic check statuses.
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+	checkSkip = "skip"
+)
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().StringVar(&doctorProbeZone, "probe-zone", "",
+		"Zone to write a temporary record with a comment to, checking that comments are writable")
+}
+
+// doctorCheck is the result of a single diagnostic check.
+type doctorCheck struct {
+	Name   string
+	Status string
+	Detail string
+	Fix    string
+}
+
+// doctor collects the results of diagnostic checks.
+type doctor struct {
+	checks []doctorCheck
+	// serverVersion is the version of the server, empty if unknown
+	serverVersion string
+}
+
+func (d *doctor) add(name, status, detail, fix string) {
+	d.checks = append(d.checks, doctorCheck{Name: name, Status: status, Detail: detail, Fix: fix})
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	log, err := newLogger(cmd)
+	if err != nil {
+		return err
+	}
+	d := &doctor{}
+	ctx := cmd.Context()
+
+	var cfg *config.Config
+	if len(args) == 1 {
+		cfg = d.checkConfig(args[0])
+	}
+	accountName := d.checkAccount(cmd, cfg)
+
+	if client := d.checkClient(cmd, log); client != nil && d.checkServer(ctx, client) {
+		if cfg != nil && accountName != "" {
+			d.checkZones(ctx, client, cfg, accountName)
+		}
+		d.checkComments(ctx, client, accountName)
+	}
+
+	return d.report(log)
+}
+
+// checkConfig loads the project settings and the configuration file.
+func (d *doctor) checkConfig(path string) *config.Config {
+	if _, err := loadSettings(path); err != nil {
+		d.add("settings", checkFail, err.Error(), "Fix or remove the "+settings.FileName+" settings file")
+		return nil
+	}
+	cfg, _, err := loadConfig(path)
+	if err != nil {
+		d.add("config", checkFail, err.Error(),
+			"Fix the configuration file; run 'init' to scaffold a valid starter config")
+		return nil
+	}
+	d.add("config", checkOK, fmt.Sprintf("%d zone(s) in %s", len(cfg.Zones), configSource(path)), "")
+	return cfg
+}
+
+// checkAccount resolves the account name and reports conflicting sources.
+func (d *doctor) checkAccount(cmd *cobra.Command, cfg *config.Config) string {
+	accountName, err := getAccountName(cmd, cfg)
+	if err != nil {
+		d.add("account", checkFail, err.Error(),
+			"Set the account name in one place: --account, ACCOUNT_NAME, the config file or the settings file")
+		return ""
+	}
+	d.add("account", checkOK, accountName, "")
+	return accountName
+}
+
+// checkClient creates the API client. Write credentials are only required
+// for the comment probe.
+func (d *doctor) checkClient(cmd *cobra.Command, log *logger.Logger) apiClient {
+	client, err := newAPIClient(cmd, log, doctorProbeZone != "")
+	if err != nil {
+		d.add("api credentials", checkFail, err.Error(),
+			"Pass --api-url and --api-key, or --read-api-key for read-only checks")
+		return nil
+	}
+	return client
+}
+
+// checkServer checks API reachability, key validity and the server version.
+// It returns whether the API can be used for further checks.
+func (d *doctor) checkServer(ctx context.Context, client apiClient) bool {
+	server, ok := client.(manager.ServerProvider)
+	if !ok {
+		d.add("api", checkSkip, "the configured provider has no API", "")
+		return true
+	}
+	info, err := server.GetServer(ctx)
+	var statusErr *powerdns.StatusError
+	switch {
+	case errors.As(err, &statusErr) &&
+		(statusErr.StatusCode == http.StatusUnauthorized || statusErr.StatusCode == http.StatusForbidden):
+		d.add("api reachable", checkOK, "", "")
+		d.add("api key", checkFail, err.Error(),
+			"Use the api-key from pdns.conf; with --read-api-key, check the read-only key too")
+		return false
+	case errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound:
+		d.add("api reachable", checkFail, err.Error(),
+			"The API URL must include the server, e.g. http://localhost:8081/api/v1/servers/localhost")
+		return false
+	case err != nil:
+		d.add("api reachable", checkFail, err.Error(),
+			"Check that PowerDNS runs with api=yes and webserver-address/webserver-allow-from admit this host")
+		return false
+	}
+	d.add("api reachable", checkOK, "", "")
+	d.add("api key", checkOK, "accepted", "")
+
+	detail := strings.TrimSpace(info.DaemonType + " " + info.Version)
+	if info.DaemonType != "" && info.DaemonType != "authoritative" {
+		d.add("server version", checkFail, detail, "Point --api-url at the authoritative server, not the recursor")
+		return false
+	}
+	if powerdns.SupportsSetPTR(info.Version) {
+		detail += " (set_ptr is passed to the server)"
+	}
+	d.add("server version", checkOK, detail, "")
+	d.serverVersion = info.Version
+	return true
+}
+
+// checkZones reports configured zones owned by other accounts and validates
+// the configuration against the server state.
+func (d *doctor) checkZones(ctx context.Context, client apiClient, cfg *config.Config, accountName string) {
+	names := make([]string, 0, len(cfg.Zones))
+	for name := range cfg.Zones {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	states := make(map[string]config.ZoneState)
+	var conflicts []string
+	for _, name := range names {
+		zoneID := config.CanonicalZoneName(name)
+		zone, err := client.GetZoneInfo(ctx, zoneID)
+		if err != nil {
+			d.add("zones", checkFail, fmt.Sprintf("failed to check zone %s: %v", zoneID, err), "")
+			return
+		}
+		states[zoneID] = config.ZoneState{Exists: zone != nil, IsManaged: zone != nil && zone.Account == accountName}
+		if zone != nil && zone.Account != accountName {
+			conflicts = append(conflicts, fmt.Sprintf("%s (account %q)", zoneID, zone.Account))
+		}
+	}
+
+	if len(conflicts) > 0 {
+		d.add("account conflicts", checkWarn, strings.Join(conflicts, ", "),
+			"Use the account owning these zones, or remove them from the config")
+	} else {
+		d.add("account conflicts", checkOK, "none", "")
+	}
+
+	if errs := cfg.Validate(states); errs != nil {
+		d.add("validation", checkFail, strings.Join(errs.Errors, "; "), "Fix the configuration errors listed")
+		return
+	}
+	var typesErr *config.ValidationError
+	if err := manager.CheckRecordTypes(cfg, d.serverVersion); errors.As(err, &typesErr) {
+		d.add("validation", checkFail, strings.Join(typesErr.Errors, "; "),
+			"Upgrade PowerDNS, or remove the records of types it does not support")
+		return
+	}
+	d.add("validation", checkOK, "configuration is valid against the server", "")
+}
+
+// checkComments writes a temporary record with a comment to the probe zone
+// and checks that the comment was stored. Some backends, e.g. bind, do not
+// support comments, which are required to mark managed records.
+func (d *doctor) checkComments(ctx context.Context, client apiClient, accountName string) {
+	if doctorProbeZone == "" {
+		d.add("comments", checkSkip, "not probed", "Pass --probe-zone to check that comments are writable")
+		return
+	}
+	zoneID := config.CanonicalZoneName(doctorProbeZone)
+	rrset := powerdns.RRset{
+		Name:       doctorProbeName + "." + zoneID,
+		Type:       "TXT",
+		TTL:        60,
+		ChangeType: "REPLACE",
+		Records:    []powerdns.Record{{Content: `"powerdns-zone-manager doctor probe"`}},
+		Comments:   []powerdns.Comment{{Content: "powerdns-zone-manager doctor probe", Account: accountName}},
+	}
+	backendFix := "Use a backend that supports comments, e.g. gmysql, gpgsql, gsqlite3 or lmdb"
+
+	if err := client.PatchZone(ctx, zoneID, &powerdns.ZonePatch{RRsets: []powerdns.RRset{rrset}}); err != nil {
+		var statusErr *powerdns.StatusError
+		fix := backendFix
+		if errors.As(err, &statusErr) &&
+			(statusErr.StatusCode == http.StatusUnauthorized || statusErr.StatusCode == http.StatusForbidden) {
+			fix = "The write API key was rejected; check --api-key"
+		}
+		d.add("comments", checkFail, fmt.Sprintf("failed to write probe record to %s: %v", zoneID, err), fix)
+		return
+	}
+	defer func() {
+		cleanup := powerdns.RRset{Name: rrset.Name, Type: rrset.Type, ChangeType: "DELETE"}
+		if err := client.PatchZone(ctx, zoneID, &powerdns.ZonePatch{RRsets: []powerdns.RRset{cleanup}}); err != nil {
+			d.add("probe cleanup", checkWarn, err.Error(), "Delete the "+rrset.Name+" TXT record manually")
+		}
+	}()
+
+	zone, err := client.GetZone(ctx, zoneID)
+	if err != nil || zone == nil {
+		d.add("comments", checkFail, fmt.Sprintf("failed to read back zone %s: %v", zoneID, err), "")
+		return
+	}
+	for _, rs := range zone.RRsets {
+		if rs.Name == rrset.Name && rs.Type == rrset.Type && len(rs.Comments) > 0 {
+			d.add("comments", checkOK, "writable in "+zoneID, "")
+			return
+		}
+	}
+	d.add("comments", checkFail, "the probe record was stored without its comment", backendFix)
+}
+
+// report prints the check results and the remediation of failed checks.
+func (d *doctor) report(log *logger.Logger) error {
+	rows := make([][]string, 0, len(d.checks))
+	failed := 0
+	for _, check := range d.checks {
+		rows = append(rows, []string{check.Name, check.Status, check.Detail})
+		if check.Status == checkFail {
+			failed++
+		}
+	}
+	log.Table("Diagnostics", []string{"CHECK", "STATUS", "DETAILS"}, rows)
+
+	for _, check := range d.checks {
+		if check.Fix == "" || check.Status == checkOK {
+			continue
+		}
+		if check.Status == checkFail {
+			log.Error("%s: %s", check.Name, check.Fix)
+		} else {
+			log.Info("%s: %s", check.Name, check.Fix)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	log.Info("All checks passed")
+	return nil
+}
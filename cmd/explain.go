@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+)
+
+var explainCmd = &cobra.Command{
+	Use:   "explain config-file zone [name] [type]",
+	Short: "Show the configured RRsets of a zone as they are sent to PowerDNS",
+	Long: `Show the RRsets of a configured zone as they are sent to PowerDNS, to debug
+why the server receives what it does: the fully qualified name, the TTL and
+where it comes from (the ttl of the RRset, a migration, the default_ttl of
+the project settings or the default), the contents after normalization,
+e.g. quoted TXT records, and the key and file position the RRset is declared
+with, including the apex shorthand keys and delegations.
+Use "-" as the config file to read the configuration from standard input.
+
+The name is relative to the zone ("@" for the apex) or fully qualified.
+Without a name and type, all RRsets of the zone are shown. The API is not
+contacted, so the RRsets are shown whether or not they differ from the
+server.
+
+  powerdns-zone-manager explain zones.yml example.com www A`,
+	Args:         cobra.RangeArgs(2, 4),
+	SilenceUsage: true,
+	RunE:         runExplain,
+}
+
+func init() {
+	rootCmd.AddCommand(explainCmd)
+}
+
+func runExplain(cmd *cobra.Command, args []string) error {
+	log, err := newLogger(cmd)
+	if err != nil {
+		return err
+	}
+	project, err := loadSettings(args[0])
+	if err != nil {
+		return err
+	}
+	cfg, _, err := loadConfig(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to load config from %s: %w", configSource(args[0]), err)
+	}
+	if project.DefaultTTL != nil {
+		cfg.SetDefaultTTL(*project.DefaultTTL)
+	}
+
+	var name, rtype string
+	if len(args) > 2 {
+		name = args[2]
+	}
+	if len(args) > 3 {
+		rtype = args[3]
+	}
+
+	zoneID := config.CanonicalZoneName(args[1])
+	for zoneName, zone := range cfg.Zones {
+		if !strings.EqualFold(config.CanonicalZoneName(zoneName), zoneID) {
+			continue
+		}
+		explanations, err := zone.Explain(zoneName, name, rtype)
+		if err != nil {
+			return err
+		}
+		if len(explanations) == 0 {
+			log.Info("Zone %s has no RRsets", zoneID)
+		}
+		for i := range explanations {
+			e := &explanations[i]
+			log.Table(e.Name+" "+e.Type, []string{"FIELD", "VALUE"}, explainRows(e))
+		}
+		return nil
+	}
+	return fmt.Errorf("zone %s is not configured in %s", zoneID, configSource(args[0]))
+}
+
+// explainRows returns the fields of an explained RRset, leaving out unset ones.
+func explainRows(e *config.Explanation) [][]string {
+	source := e.Source
+	if source == "a" || source == "aaaa" || source == "mx" {
+		source += " (apex shorthand)"
+	}
+	rows := [][]string{{"declared by", source}}
+	if pos := e.Location.String(); pos != "" {
+		rows = append(rows, []string{"location", pos})
+	}
+	if e.Absent {
+		return append(rows, []string{"state", config.StateAbsent + " (deleted)"})
+	}
+	ttl := strconv.FormatUint(uint64(e.TTL), 10)
+	rows = append(rows, []string{"ttl", ttl + " (" + explainTTLSource(e.TTLSource) + ")"})
+	for _, r := range e.Records {
+		value := r.Content
+		if r.Disabled {
+			value += " (disabled)"
+		}
+		if r.SetPTR {
+			value += " (set_ptr)"
+		}
+		if r.Comment != "" {
+			value += " # " + r.Comment
+		}
+		rows = append(rows, []string{"record", value})
+	}
+	if e.Comment != "" {
+		rows = append(rows, []string{"comment", e.Comment})
+	}
+	if e.CreateOnly {
+		rows = append(rows, []string{"change policy", config.ChangePolicyCreateOnly})
+	}
+	if !e.ApplyAfter.IsZero() {
+		rows = append(rows, []string{"apply after", e.ApplyAfter.Format(time.RFC3339)})
+	}
+	if len(e.Labels) > 0 {
+		labels := make([]string, 0, len(e.Labels))
+		for k, v := range e.Labels {
+			labels = append(labels, k+"="+v)
+		}
+		sort.Strings(labels)
+		rows = append(rows, []string{"labels", strings.Join(labels, ", ")})
+	}
+	return rows
+}
+
+// explainTTLSource describes where the TTL of an explained RRset comes from.
+func explainTTLSource(source string) string {
+	switch source {
+	case config.TTLFromRRset:
+		return "ttl of the RRset"
+	case config.TTLFromMigration:
+		return "lowered by its migration"
+	case config.TTLFromSettings:
+		return "default_ttl of the project settings"
+	default:
+		return "default"
+	}
+}
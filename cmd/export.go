@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/exporter"
+	"github.com/kreigan/powerdns-zone-manager/internal/logger"
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+	"github.com/kreigan/powerdns-zone-manager/internal/zonefile"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export zone...",
+	Short: "Export zone records to another format",
+	Long: `Export the records of existing zones to another format.
+
+Supported formats:
+  csv   one row per record: zone,name,type,ttl,content,disabled,comment
+  bind  RFC 1035 master files, e.g. for backups or "pdnsutil load-zone"
+
+All records are exported, managed or not. CSV omits SOA records and can be
+converted back with "import --format csv". Zone files include the SOA, keep
+RRset comments as comment lines and comment out disabled records. With -o
+naming an existing directory, each zone is written to <zone>.zone in it.
+
+--verify reads the written zone files back and checks that they are
+equivalent to the zones on the server.`,
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE:         runExport,
+}
+
+var exportFormat string
+var exportOutput string
+var exportVerify bool
+
+var exportFormats = []string{"csv", "bind"}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().StringVar(&exportFormat, "format", "", "Output format (csv, bind)")
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Write the export to a file, or a directory (bind)")
+	exportCmd.Flags().BoolVar(&exportVerify, "verify", false,
+		"Read the zone files back and compare them with the server (bind)")
+
+	if err := exportCmd.MarkFlagRequired("format"); err != nil {
+		panic(fmt.Sprintf("failed to mark format as required: %v", err))
+	}
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	if !slices.Contains(exportFormats, exportFormat) {
+		return fmt.Errorf("unsupported export format %q, must be: %s", exportFormat, strings.Join(exportFormats, ", "))
+	}
+	if exportVerify && exportFormat != "bind" {
+		return fmt.Errorf("--verify requires --format bind")
+	}
+
+	log, err := newLogger(cmd)
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cmd, log, false)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	zones := make([]*powerdns.Zone, 0, len(args))
+	for _, name := range args {
+		zone, err := getExportZone(ctx, client, name)
+		if err != nil {
+			return err
+		}
+		zones = append(zones, zone)
+	}
+
+	if exportFormat == "bind" {
+		return exportZoneFiles(ctx, client, log, zones)
+	}
+	if exportOutput == "" {
+		return exporter.CSV(os.Stdout, zones)
+	}
+	if err := writeExport(exportOutput, func(w io.Writer) error { return exporter.CSV(w, zones) }); err != nil {
+		return err
+	}
+	log.Info("Exported %d zone(s) to %s", len(zones), exportOutput)
+	return nil
+}
+
+// getExportZone returns an existing zone.
+func getExportZone(ctx context.Context, client apiClient, name string) (*powerdns.Zone, error) {
+	zone, err := client.GetZone(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get zone %s: %w", name, err)
+	}
+	if zone == nil {
+		return nil, fmt.Errorf("zone %s does not exist", name)
+	}
+	return zone, nil
+}
+
+// exportZoneFiles writes the zones as master files to stdout, the output file
+// or one file per zone in the output directory, then verifies them if
+// requested.
+func exportZoneFiles(ctx context.Context, client apiClient, log *logger.Logger, zones []*powerdns.Zone) error {
+	files := make([][]byte, len(zones))
+	for i, zone := range zones {
+		var buf bytes.Buffer
+		if err := zonefile.Write(&buf, zone); err != nil {
+			return err
+		}
+		files[i] = buf.Bytes()
+	}
+
+	var paths []string
+	if info, err := os.Stat(exportOutput); exportOutput != "" && err == nil && info.IsDir() {
+		for i, zone := range zones {
+			path := filepath.Join(exportOutput, strings.TrimSuffix(zone.Name, ".")+".zone")
+			if err := writeExport(path, writeData(files[i])); err != nil {
+				return err
+			}
+			paths = append(paths, path)
+		}
+		log.Info("Exported %d zone(s) to %s", len(zones), exportOutput)
+	} else {
+		all := bytes.Join(files, []byte("\n"))
+		if exportOutput == "" {
+			if _, err := os.Stdout.Write(all); err != nil {
+				return fmt.Errorf("failed to write zone files: %w", err)
+			}
+		} else {
+			if err := writeExport(exportOutput, writeData(all)); err != nil {
+				return err
+			}
+			log.Info("Exported %d zone(s) to %s", len(zones), exportOutput)
+		}
+	}
+
+	if !exportVerify {
+		return nil
+	}
+	failed := 0
+	for i, zone := range zones {
+		data := files[i]
+		if paths != nil {
+			var err error
+			if data, err = os.ReadFile(paths[i]); err != nil {
+				return fmt.Errorf("failed to read back zone file: %w", err)
+			}
+		}
+		diffs, err := verifyZoneFile(ctx, client, zone.Name, data)
+		if err != nil {
+			return err
+		}
+		for _, diff := range diffs {
+			log.Error("Zone file of %s differs: %s", zone.Name, diff)
+		}
+		if len(diffs) > 0 {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("zone file verification failed for %d of %d zone(s)", failed, len(zones))
+	}
+	log.Info("Verified %d zone file(s) against the server", len(zones))
+	return nil
+}
+
+// verifyZoneFile re-imports a zone file and compares it with the current
+// state of the zone on the server.
+func verifyZoneFile(ctx context.Context, client apiClient, name string, data []byte) ([]string, error) {
+	parsed, err := zonefile.Read(bytes.NewReader(data), name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read back zone file of %s: %w", name, err)
+	}
+	zone, err := getExportZone(ctx, client, name)
+	if err != nil {
+		return nil, err
+	}
+	return zonefile.Compare(zone, parsed), nil
+}
+
+// writeExport creates path and writes the export to it.
+func writeExport(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path) //nolint:gosec // path is from CLI argument
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer func() {
+		_ = f.Close() //nolint:errcheck // best effort close, write errors are reported below
+	}()
+	if err := write(f); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	return nil
+}
+
+// writeData returns a writeExport function that writes data.
+func writeData(data []byte) func(io.Writer) error {
+	return func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	}
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/graph"
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+var graphCmd = &cobra.Command{
+	Use:   "graph [config-file]",
+	Short: "Render the zones of a configuration as a graph",
+	Long: `Render the zones of a configuration as a DOT or Mermaid graph of their
+delegations, CNAME chains and MX and SRV targets.
+Use "-" as the config file to read the configuration from standard input.
+
+Names that are referenced but not defined in their zone are dangling and
+highlighted in red; names outside of the configured zones are dashed. With
+--live, the current records of the zones are merged in, and references that
+only exist on the server are dotted.
+
+  powerdns-zone-manager graph zones.yml | dot -Tsvg > zones.svg`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runGraph,
+}
+
+var graphFormat string
+var graphOutput string
+var graphLive bool
+
+func init() {
+	rootCmd.AddCommand(graphCmd)
+	graphCmd.Flags().StringVar(&graphFormat, "format", graph.FormatDOT, "Output format (dot, mermaid)")
+	graphCmd.Flags().StringVarP(&graphOutput, "output", "o", "", "Write the graph to a file")
+	graphCmd.Flags().BoolVar(&graphLive, "live", false, "Merge in the current records of the zones from the API")
+	addCacheFlags(graphCmd)
+}
+
+func runGraph(cmd *cobra.Command, args []string) error {
+	if graphFormat != graph.FormatDOT && graphFormat != graph.FormatMermaid {
+		return fmt.Errorf("unsupported graph format %q, must be: %s, %s",
+			graphFormat, graph.FormatDOT, graph.FormatMermaid)
+	}
+	log, err := newLogger(cmd)
+	if err != nil {
+		return err
+	}
+	if _, err := loadSettings(args[0]); err != nil {
+		return err
+	}
+	cfg, _, err := loadConfig(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to load config from %s: %w", configSource(args[0]), err)
+	}
+
+	var live []*powerdns.Zone
+	if graphLive {
+		client, err := newAPIClient(cmd, log, false)
+		if err != nil {
+			return err
+		}
+		for name := range cfg.Zones {
+			zone, err := client.GetZone(cmd.Context(), config.CanonicalZoneName(name))
+			if err != nil {
+				return fmt.Errorf("failed to get zone %s: %w", name, err)
+			}
+			if zone != nil {
+				live = append(live, zone)
+			}
+		}
+	}
+
+	g, err := graph.Build(cfg, live)
+	if err != nil {
+		return err
+	}
+
+	if graphOutput == "" {
+		return g.Write(os.Stdout, graphFormat)
+	}
+	if err := writeGraph(g); err != nil {
+		return err
+	}
+	// The graph is not on stdout, so the dangling references can be listed there
+	for _, edge := range g.Dangling() {
+		log.Warn("Dangling reference: %s %s -> %s", edge.From, edge.Label, edge.To)
+	}
+	log.Info("Graph written to %s", graphOutput)
+	return nil
+}
+
+// writeGraph writes the graph to the output file.
+func writeGraph(g *graph.Graph) error {
+	f, err := os.Create(graphOutput)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer func() {
+		_ = f.Close() //nolint:errcheck // best effort close, write errors are reported below
+	}()
+	if err := g.Write(f, graphFormat); err != nil {
+		return err
+	}
+	return f.Close()
+}
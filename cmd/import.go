@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/importer"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import [input-file]",
+	Short: "Convert records from another format into zone configuration",
+	Long: `Convert records from another format into a zone configuration YAML file.
+
+Supported formats:
+  hosts      /etc/hosts-style file, producing A/AAAA (and optionally PTR) rrsets
+  csv        one row per record: zone,name,type,ttl,content,disabled,comment
+             (the header row is optional; see "export --format csv")
+  terraform  Terraform state file or "terraform show -json" output, converting
+             powerdns_zone and powerdns_record resources
+  octodns    octoDNS zone file; the zone defaults to the file name without
+             the .yaml/.yml extension
+
+Use "-" as the input file to read from standard input. The generated
+configuration is written to standard output unless --output is set.
+Input that cannot be translated is reported as comments in the output.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runImport,
+}
+
+var importFormat string
+var importZone string
+var importPTR bool
+var importNameservers []string
+var importOutput string
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	importCmd.Flags().StringVar(&importFormat, "format", "", "Input format (hosts, csv, terraform, octodns)")
+	importCmd.Flags().StringVar(&importZone, "zone", "", "Zone to import records into")
+	importCmd.Flags().BoolVar(&importPTR, "ptr", false, "Also generate PTR records in reverse zones (hosts format)")
+	importCmd.Flags().StringSliceVar(&importNameservers, "nameserver", nil, "Nameservers of the generated zones")
+	importCmd.Flags().StringVarP(&importOutput, "output", "o", "", "Write the configuration to a file")
+
+	if err := importCmd.MarkFlagRequired("format"); err != nil {
+		panic(fmt.Sprintf("failed to mark format as required: %v", err))
+	}
+}
+
+func runImport(cmd *cobra.Command, args []string) error {
+	log, err := newLogger(cmd)
+	if err != nil {
+		return err
+	}
+
+	input := args[0]
+	var r io.Reader = os.Stdin
+	if input != stdinPath {
+		f, err := os.Open(input) //nolint:gosec // path is from CLI argument
+		if err != nil {
+			return fmt.Errorf("failed to open input: %w", err)
+		}
+		defer func() {
+			_ = f.Close() //nolint:errcheck // best effort close
+		}()
+		r = f
+	}
+
+	var res *importer.Result
+	switch importFormat {
+	case "hosts":
+		if importZone == "" {
+			return fmt.Errorf("--zone is required for the hosts format")
+		}
+		res, err = importer.Hosts(r, importer.HostsOptions{
+			Zone:        importZone,
+			Nameservers: importNameservers,
+			PTR:         importPTR,
+		})
+	case "csv":
+		res, err = importer.CSV(r, importer.CSVOptions{Nameservers: importNameservers})
+	case "terraform":
+		res, err = importer.Terraform(r, importer.TerraformOptions{Nameservers: importNameservers})
+	case "octodns":
+		zone := importZone
+		if zone == "" && input != stdinPath {
+			zone = strings.TrimSuffix(strings.TrimSuffix(filepath.Base(input), ".yaml"), ".yml")
+		}
+		if zone == "" {
+			return fmt.Errorf("--zone is required when reading an octoDNS zone file from stdin")
+		}
+		res, err = importer.OctoDNS(r, importer.OctoDNSOptions{Zone: zone, Nameservers: importNameservers})
+	default:
+		return fmt.Errorf("unsupported import format %q, must be: hosts, csv, terraform, octodns", importFormat)
+	}
+	if err != nil {
+		return err
+	}
+
+	if importOutput == "" {
+		return importer.Write(os.Stdout, res, configSource(input))
+	}
+
+	f, err := os.Create(importOutput)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer func() {
+		_ = f.Close() //nolint:errcheck // best effort close, write errors are reported below
+	}()
+	if err := importer.Write(f, res, configSource(input)); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+
+	for _, warning := range res.Warnings {
+		log.Warn("%s", warning)
+	}
+	log.Info("Imported %d zone(s) into %s", len(res.Config.Zones), importOutput)
+	return nil
+}
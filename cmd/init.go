@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/i18n"
+	"github.com/kreigan/powerdns-zone-manager/internal/scaffold"
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init [config-file]",
+	Short: "Write a starter zone configuration",
+	Long: `Write a commented starter configuration for a zone, with nameservers, apex
+A/AAAA records, a www CNAME and MX records (default file: zones.yml).
+
+Without --zone, the values are asked for interactively when standard input is
+a terminal. Lists are entered comma-separated, empty answers are skipped.`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE:         runInit,
+}
+
+var initOpts scaffold.Options
+var initForce bool
+
+func init() {
+	rootCmd.AddCommand(initCmd)
+	initCmd.Flags().StringVar(&initOpts.Zone, "zone", "", "Zone name")
+	initCmd.Flags().StringSliceVar(&initOpts.Nameservers, "nameserver", nil, "Nameserver, fully qualified (repeatable)")
+	initCmd.Flags().StringSliceVar(&initOpts.IPv4, "a", nil, "Apex IPv4 address (repeatable)")
+	initCmd.Flags().StringSliceVar(&initOpts.IPv6, "aaaa", nil, "Apex IPv6 address (repeatable)")
+	initCmd.Flags().StringSliceVar(&initOpts.MX, "mx", nil, `Mail exchanger as "<preference> <host.>" (repeatable)`)
+	initCmd.Flags().Uint32Var(&initOpts.TTL, "ttl", scaffold.DefaultTTL, "TTL of the generated rrsets")
+	initCmd.Flags().BoolVar(&initForce, "force", false, "Overwrite an existing file")
+}
+
+func runInit(_ *cobra.Command, args []string) error {
+	path := "zones.yml"
+	if len(args) == 1 {
+		path = args[0]
+	}
+	if !initForce {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%s already exists, use --force to overwrite it", path)
+		}
+	}
+
+	if initOpts.Zone == "" {
+		if !isTerminal(os.Stdin) {
+			return errors.New("--zone is required when standard input is not a terminal")
+		}
+		if err := promptInitOptions(bufio.NewReader(os.Stdin), os.Stdout, &initOpts); err != nil {
+			return err
+		}
+	}
+
+	data, err := scaffold.Render(initOpts)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil { //nolint:gosec // config files are not secret
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	fmt.Println(i18n.T("Configuration for %s written to %s", initOpts.Zone, path))
+	fmt.Println(i18n.T("Preview the changes with: %s", "powerdns-zone-manager apply --dry-run "+path))
+	return nil
+}
+
+// promptInitOptions asks for the scaffold options that are not set by flags.
+func promptInitOptions(in *bufio.Reader, out io.Writer, opts *scaffold.Options) error {
+	prompts := []struct {
+		list   *[]string
+		value  *string
+		prompt string
+	}{
+		{value: &opts.Zone, prompt: "Zone name (e.g. example.com)"},
+		{list: &opts.Nameservers, prompt: "Nameservers (e.g. ns1.example.com.,ns2.example.com.)"},
+		{list: &opts.IPv4, prompt: "Apex IPv4 addresses"},
+		{list: &opts.IPv6, prompt: "Apex IPv6 addresses"},
+		{list: &opts.MX, prompt: "Mail exchangers (e.g. 10 mail.example.com.)"},
+	}
+	for _, p := range prompts {
+		if p.list != nil && len(*p.list) > 0 {
+			continue
+		}
+		fmt.Fprintf(out, "%s: ", i18n.T(p.prompt))
+		answer, err := in.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to read answer: %w", err)
+		}
+		answer = strings.TrimSpace(answer)
+		if p.value != nil {
+			*p.value = answer
+			continue
+		}
+		for _, item := range strings.Split(answer, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*p.list = append(*p.list, item)
+			}
+		}
+	}
+	return nil
+}
+
+// isTerminal reports whether f is a character device, i.e. an interactive terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List zones with their account, serial, DNSSEC state and description",
+	Long: `List the zones of the server with their kind, account, SOA serial, DNSSEC
+state, masters, last check of secondary zones and description, as an
+inventory of the server.
+
+The description is the zone "description" from the configuration, stored in
+the ` + config.MetadataDescription + ` zone metadata.
+
+The zone list of the API does not include records, so record counts are only
+listed with --records, which fetches all RRsets of every listed zone.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         runList,
+}
+
+var listManaged bool
+var listRecords bool
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+	listCmd.Flags().BoolVar(&listManaged, "managed", false, "Only list zones managed by the configured account")
+	listCmd.Flags().BoolVar(&listRecords, "records", false, "Count the records of each zone (fetches all RRsets)")
+	addCacheFlags(listCmd)
+}
+
+// zoneLister is implemented by providers that can list zones.
+type zoneLister interface {
+	ListZones(ctx context.Context) ([]powerdns.Zone, error)
+}
+
+func runList(cmd *cobra.Command, _ []string) error {
+	log, err := newLogger(cmd)
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cmd, log, false)
+	if err != nil {
+		return err
+	}
+	lister, ok := client.(zoneLister)
+	if !ok {
+		return fmt.Errorf("the configured provider does not support listing zones")
+	}
+	accountName, err := getAccountName(cmd, nil)
+	if err != nil {
+		return err
+	}
+
+	ctx := cmd.Context()
+	zones, err := lister.ListZones(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list zones: %w", err)
+	}
+	sort.Slice(zones, func(i, j int) bool { return zones[i].Name < zones[j].Name })
+
+	metadata, hasMetadata := client.(manager.MetadataProvider)
+	rows := make([][]string, 0, len(zones))
+	for _, zone := range zones {
+		if listManaged && zone.Account != accountName {
+			continue
+		}
+		description := ""
+		if hasMetadata {
+			description, err = zoneDescription(ctx, metadata, zone.Name)
+			if err != nil {
+				return err
+			}
+		}
+		row := []string{
+			zone.Name,
+			zone.Kind,
+			zone.Account,
+			strconv.FormatUint(uint64(zone.Serial), 10),
+			dnssecState(zone.DNSSEC),
+			strings.Join(zone.Masters, ","),
+			lastCheck(zone.LastCheck),
+		}
+		if listRecords {
+			count, err := countRecords(ctx, client, zone.Name)
+			if err != nil {
+				return err
+			}
+			row = append(row, strconv.Itoa(count))
+		}
+		rows = append(rows, append(row, description))
+	}
+
+	headers := []string{"ZONE", "KIND", "ACCOUNT", "SERIAL", "DNSSEC", "MASTERS", "LAST CHECK"}
+	if listRecords {
+		headers = append(headers, "RECORDS")
+	}
+	log.Table("Zones", append(headers, "DESCRIPTION"), rows)
+	return nil
+}
+
+func dnssecState(enabled bool) string {
+	if enabled {
+		return "signed"
+	}
+	return "unsigned"
+}
+
+// lastCheck formats the last check time of a secondary zone, empty if the
+// zone was never checked.
+func lastCheck(unix int64) string {
+	if unix == 0 {
+		return ""
+	}
+	return time.Unix(unix, 0).UTC().Format(time.RFC3339)
+}
+
+// countRecords returns the number of records of a zone.
+func countRecords(ctx context.Context, provider manager.Provider, zoneID string) (int, error) {
+	zone, err := provider.GetZone(ctx, zoneID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get zone %s: %w", zoneID, err)
+	}
+	if zone == nil {
+		return 0, nil // removed since listing
+	}
+	count := 0
+	for _, rrset := range zone.RRsets {
+		count += len(rrset.Records)
+	}
+	return count, nil
+}
+
+// zoneDescription returns the description stored in the zone metadata.
+func zoneDescription(ctx context.Context, provider manager.MetadataProvider, zoneID string) (string, error) {
+	metadata, err := provider.GetMetadata(ctx, zoneID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get metadata of zone %s: %w", zoneID, err)
+	}
+	for _, md := range metadata {
+		if md.Kind == config.MetadataDescription {
+			return strings.Join(md.Metadata, " "), nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+	"github.com/kreigan/powerdns-zone-manager/internal/ownership"
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate [zone...]",
+	Short: "Copy zones between servers, or lower and restore RRset TTLs",
+	Long: `Copy zones from one PowerDNS server to another with --source and --target,
+e.g. to move to a new instance. Zones that do not exist on the target are
+created with their kind, account and RRsets; of existing zones, the RRsets
+that are missing or differ (see "compare") are replaced. RRsets only on the
+target are kept. Comments, including ownership comments, are copied as is.
+
+--all copies all zones of the source. With --managed, only the zones and
+RRsets of the configured account are copied; zones that are created get the
+SOA and apex NS RRsets of the source as well.
+
+The "prepare" and "restore" subcommands lower the TTL of a managed RRset
+ahead of a planned content change and restore it afterwards, so that
+resolvers pick up the new content quickly. The original TTL is kept in a
+comment of the RRset until it is restored. "apply" restores the configured
+TTL as well; for RRsets that are applied regularly, use the rrset "migration"
+setting in the configuration instead.`,
+	Args:         cobra.ArbitraryArgs,
+	SilenceUsage: true,
+	RunE:         runMigrateZones,
+}
+
+var migratePrepareCmd = &cobra.Command{
+	Use:          "prepare zone name type",
+	Short:        "Lower the TTL of an RRset",
+	Args:         cobra.ExactArgs(3),
+	SilenceUsage: true,
+	RunE:         runMigratePrepare,
+}
+
+var migrateRestoreCmd = &cobra.Command{
+	Use:          "restore zone name type",
+	Short:        "Restore the original TTL of an RRset",
+	Args:         cobra.ExactArgs(3),
+	SilenceUsage: true,
+	RunE:         runMigrateRestore,
+}
+
+var migrateTTL uint32
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+	migrateCmd.AddCommand(migratePrepareCmd, migrateRestoreCmd)
+	migratePrepareCmd.Flags().Uint32Var(&migrateTTL, "ttl", 60, "Temporary TTL in seconds")
+}
+
+// originalTTLPrefix starts the comment that keeps the original TTL of an RRset.
+const originalTTLPrefix = "original-ttl="
+
+// migrationTarget is the RRset a migrate command works on.
+type migrationTarget struct {
+	client  manager.Provider
+	account string
+	zoneID  string
+	rrset   powerdns.RRset
+}
+
+// loadMigrationTarget fetches the managed RRset named by the command arguments.
+func loadMigrationTarget(ctx context.Context, cmd *cobra.Command, args []string) (*migrationTarget, error) {
+	log, err := newLogger(cmd)
+	if err != nil {
+		return nil, err
+	}
+	account, err := getAccountName(cmd, nil)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newAPIClient(cmd, log, true)
+	if err != nil {
+		return nil, err
+	}
+
+	zoneID := config.CanonicalZoneName(args[0])
+	zone, err := client.GetZone(ctx, zoneID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch zone %s: %w", zoneID, err)
+	}
+	if zone == nil {
+		return nil, fmt.Errorf("zone %s does not exist", zoneID)
+	}
+
+	name := config.QualifyName(args[1], zoneID)
+	rtype := strings.ToUpper(args[2])
+	for _, rrset := range zone.RRsets {
+		if !strings.EqualFold(rrset.Name, name) || rrset.Type != rtype {
+			continue
+		}
+		if !ownership.OwnedBy(&rrset, account) {
+			return nil, fmt.Errorf("RRset %s %s is not managed by %s", rrset.Name, rrset.Type, account)
+		}
+		return &migrationTarget{client: client, account: account, zoneID: zoneID, rrset: rrset}, nil
+	}
+	return nil, fmt.Errorf("RRset %s %s does not exist", name, rtype)
+}
+
+// originalTTL returns the TTL kept by migrate prepare, if any.
+func (t *migrationTarget) originalTTL() (uint32, bool) {
+	for _, c := range t.rrset.Comments {
+		value, ok := strings.CutPrefix(c.Content, originalTTLPrefix)
+		if !ok || c.Account != t.account {
+			continue
+		}
+		ttl, err := strconv.ParseUint(value, 10, 32)
+		if err == nil {
+			return uint32(ttl), true
+		}
+	}
+	return 0, false
+}
+
+// replace replaces the RRset with a new TTL and comments, keeping its records.
+func (t *migrationTarget) replace(ctx context.Context, ttl uint32, comments []powerdns.Comment) error {
+	patch := &powerdns.ZonePatch{RRsets: []powerdns.RRset{{
+		Name:       t.rrset.Name,
+		Type:       t.rrset.Type,
+		TTL:        ttl,
+		ChangeType: "REPLACE",
+		Records:    t.rrset.Records,
+		Comments:   comments,
+	}}}
+	if err := t.client.PatchZone(ctx, t.zoneID, patch); err != nil {
+		return fmt.Errorf("failed to update RRset %s %s: %w", t.rrset.Name, t.rrset.Type, err)
+	}
+	return nil
+}
+
+func runMigratePrepare(cmd *cobra.Command, args []string) error {
+	if err := config.ValidateTTL("--ttl", migrateTTL); err != nil {
+		return err
+	}
+	ctx := cmd.Context()
+	target, err := loadMigrationTarget(ctx, cmd, args)
+	if err != nil {
+		return err
+	}
+	rrset := &target.rrset
+	if original, ok := target.originalTTL(); ok {
+		return fmt.Errorf("TTL of %s %s is already lowered (original TTL %d)", rrset.Name, rrset.Type, original)
+	}
+	if migrateTTL >= rrset.TTL {
+		return fmt.Errorf("TTL %d is not lower than the current TTL %d", migrateTTL, rrset.TTL)
+	}
+
+	comments := append(append([]powerdns.Comment(nil), rrset.Comments...), powerdns.Comment{
+		Content: originalTTLPrefix + strconv.FormatUint(uint64(rrset.TTL), 10),
+		Account: target.account,
+	})
+	if err := target.replace(ctx, migrateTTL, comments); err != nil {
+		return err
+	}
+
+	ready := time.Now().Add(time.Duration(rrset.TTL) * time.Second)
+	fmt.Printf("Lowered TTL of %s %s from %d to %d.\n", rrset.Name, rrset.Type, rrset.TTL, migrateTTL)
+	fmt.Printf("Change the content after %s, then run: migrate restore %s %s %s\n",
+		ready.Format(time.RFC3339), args[0], args[1], args[2])
+	return nil
+}
+
+func runMigrateRestore(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	target, err := loadMigrationTarget(ctx, cmd, args)
+	if err != nil {
+		return err
+	}
+	rrset := &target.rrset
+	original, ok := target.originalTTL()
+	if !ok {
+		return fmt.Errorf("TTL of %s %s was not lowered with migrate prepare", rrset.Name, rrset.Type)
+	}
+
+	comments := make([]powerdns.Comment, 0, len(rrset.Comments))
+	for _, c := range rrset.Comments {
+		if c.Account != target.account || !strings.HasPrefix(c.Content, originalTTLPrefix) {
+			comments = append(comments, c)
+		}
+	}
+	if err := target.replace(ctx, original, comments); err != nil {
+		return err
+	}
+
+	fmt.Printf("Restored TTL of %s %s from %d to %d.\n", rrset.Name, rrset.Type, rrset.TTL, original)
+	return nil
+}
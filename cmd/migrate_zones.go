@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/compare"
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/confirm"
+	"github.com/kreigan/powerdns-zone-manager/internal/i18n"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+var (
+	migrateSource      string
+	migrateTarget      string
+	migrateSourceKey   string
+	migrateTargetKey   string
+	migrateAll         bool
+	migrateManaged     bool
+	migrateDryRun      bool
+	migrateAutoConfirm bool
+)
+
+func init() {
+	flags := migrateCmd.Flags()
+	flags.StringVar(&migrateSource, "source", "", "PowerDNS API base URL of the server to copy zones from")
+	flags.StringVar(&migrateTarget, "target", "", "PowerDNS API base URL of the server to copy zones to")
+	flags.StringVar(&migrateSourceKey, "source-api-key", "",
+		"PowerDNS API key of the source server (defaults to --api-key)")
+	flags.StringVar(&migrateTargetKey, "target-api-key", "",
+		"PowerDNS API key of the target server (defaults to --api-key)")
+	flags.BoolVar(&migrateAll, "all", false, "Copy all zones of the source server")
+	flags.BoolVar(&migrateManaged, "managed", false, "Only copy the zones and RRsets managed by the configured account")
+	flags.BoolVar(&migrateDryRun, "dry-run", false, "Only list the changes that would be made on the target")
+	flags.BoolVarP(&migrateAutoConfirm, "auto-confirm", "y", false, "Skip confirmation prompt")
+}
+
+// zoneMigration are the changes that copy a zone to the target server: the
+// zone to create, or the rrsets to replace in the existing zone.
+type zoneMigration struct {
+	create *powerdns.Zone
+	name   string
+	rrsets []powerdns.RRset
+}
+
+func runMigrateZones(cmd *cobra.Command, args []string) error {
+	if migrateSource == "" && migrateTarget == "" && len(args) == 0 {
+		return cmd.Help()
+	}
+	log, err := newLogger(cmd)
+	if err != nil {
+		return err
+	}
+	jsonOutput, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		return fmt.Errorf("failed to get json flag: %w", err)
+	}
+	if jsonOutput && !migrateDryRun && !migrateAutoConfirm {
+		return fmt.Errorf("migrate with --json requires --auto-confirm or --dry-run")
+	}
+	if migrateAll == (len(args) > 0) {
+		return errors.New("either name the zones to migrate or use --all")
+	}
+
+	source, err := newServerClient(cmd, log, "source", migrateSource, migrateSourceKey)
+	if err != nil {
+		return err
+	}
+	target, err := newServerClient(cmd, log, "target", migrateTarget, migrateTargetKey)
+	if err != nil {
+		return err
+	}
+	account := ""
+	if migrateManaged {
+		if account, err = getAccountName(cmd, nil); err != nil {
+			return err
+		}
+	}
+
+	ctx := cmd.Context()
+	names := make([]string, len(args))
+	for i, arg := range args {
+		names[i] = config.CanonicalZoneName(arg)
+	}
+	if migrateAll {
+		if names, err = serverZoneNames(cmd, account, source); err != nil {
+			return err
+		}
+	}
+
+	var pending []zoneMigration
+	var rows [][]string
+	for _, name := range names {
+		sourceZone, err := source.GetZone(ctx, name)
+		if err != nil {
+			return fmt.Errorf("failed to get zone %s from the source server: %w", name, err)
+		}
+		if sourceZone == nil {
+			return fmt.Errorf("zone %s does not exist on the source server", name)
+		}
+		targetZone, err := target.GetZone(ctx, name)
+		if err != nil {
+			return fmt.Errorf("failed to get zone %s from the target server: %w", name, err)
+		}
+
+		if targetZone == nil {
+			zone := compare.NewZone(sourceZone, account)
+			pending = append(pending, zoneMigration{name: name, create: zone})
+			rows = append(rows, []string{name, "", "", "create zone", strconv.Itoa(len(zone.RRsets)) + " RRsets"})
+			continue
+		}
+		if targetZone.Account != sourceZone.Account {
+			log.Warn("Zone %s has account %q on the target server and %q on the source server",
+				name, targetZone.Account, sourceZone.Account)
+		}
+		diffs := compare.Zone(name, sourceZone, targetZone, compare.Options{Account: account, Comments: true})
+		rrsets := compare.Copies(diffs, account)
+		if len(rrsets) == 0 {
+			continue
+		}
+		pending = append(pending, zoneMigration{name: name, rrsets: rrsets})
+		for i := range rrsets {
+			rows = append(rows, []string{name, rrsets[i].Name, rrsets[i].Type, "replace", compare.Records(&rrsets[i])})
+		}
+	}
+
+	log.Table("Migration", []string{"ZONE", "NAME", "TYPE", "ACTION", "RECORDS"}, rows)
+	if len(pending) == 0 {
+		log.Info("All %d zone(s) are up to date on the target server", len(names))
+		return nil
+	}
+	if migrateDryRun {
+		return nil
+	}
+
+	if !migrateAutoConfirm {
+		req := &manager.ConfirmRequest{Prompt: i18n.T("Copy %d zone(s) to %s?", len(pending), migrateTarget)}
+		ok, err := confirm.NewTerminal(os.Stdin, os.Stdout).Confirm(ctx, req)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return manager.ErrAborted
+		}
+	}
+
+	for _, zm := range pending {
+		if zm.create != nil {
+			if _, err := target.CreateZone(ctx, zm.create); err != nil {
+				return fmt.Errorf("failed to create zone %s: %w", zm.name, err)
+			}
+			log.Info("Created zone %s (%d RRsets)", zm.name, len(zm.create.RRsets))
+			if zm.create.DNSSEC {
+				log.Warn("Zone %s is signed with new DNSSEC keys, update its DS records at the parent", zm.name)
+			}
+			continue
+		}
+		if err := target.PatchZone(ctx, zm.name, &powerdns.ZonePatch{RRsets: zm.rrsets}); err != nil {
+			return fmt.Errorf("failed to copy RRsets to zone %s: %w", zm.name, err)
+		}
+		log.Info("Copied %d RRset(s) to zone %s", len(zm.rrsets), zm.name)
+	}
+	return nil
+}
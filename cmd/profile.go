@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+
+	"github.com/spf13/cobra"
+)
+
+var cpuProfile string
+var memProfile string
+
+// addProfileFlags adds the --cpuprofile and --memprofile flags to a command
+// whose run function is wrapped with withProfiling.
+func addProfileFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&cpuProfile, "cpuprofile", "",
+		"Write a CPU profile of the command to this file (go tool pprof)")
+	cmd.Flags().StringVar(&memProfile, "memprofile", "",
+		"Write a heap profile to this file when the command finishes (go tool pprof)")
+}
+
+// withProfiling wraps a run function to write the profiles requested with
+// --cpuprofile and --memprofile, also when it fails.
+func withProfiling(run func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if cpuProfile != "" {
+			f, err := os.Create(cpuProfile) //nolint:gosec // path is from CLI argument
+			if err != nil {
+				return fmt.Errorf("failed to create CPU profile: %w", err)
+			}
+			if err := pprof.StartCPUProfile(f); err != nil {
+				_ = f.Close() //nolint:errcheck // the start error is reported
+				return fmt.Errorf("failed to start CPU profile: %w", err)
+			}
+			defer func() {
+				pprof.StopCPUProfile()
+				_ = f.Close() //nolint:errcheck // best effort close
+			}()
+		}
+
+		runErr := run(cmd, args)
+		if memProfile == "" {
+			return runErr
+		}
+		return errors.Join(runErr, writeHeapProfile(memProfile))
+	}
+}
+
+// writeHeapProfile writes the heap profile, with up-to-date statistics, to path.
+func writeHeapProfile(path string) error {
+	f, err := os.Create(path) //nolint:gosec // path is from CLI argument
+	if err != nil {
+		return fmt.Errorf("failed to create memory profile: %w", err)
+	}
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		_ = f.Close() //nolint:errcheck // the write error is reported
+		return fmt.Errorf("failed to write memory profile: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write memory profile: %w", err)
+	}
+	return nil
+}
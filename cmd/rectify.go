@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+)
+
+var rectifyCmd = &cobra.Command{
+	Use:   "rectify zone...",
+	Short: "Rectify DNSSEC zones",
+	Long: `Rectify DNSSEC zones, updating the ordering and auth data PowerDNS needs
+to serve signed answers after records were changed.
+
+Zones with API-RECTIFY enabled are rectified by PowerDNS on every API change.
+Use "apply --rectify" to rectify changed zones automatically.`,
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE:         runRectify,
+}
+
+func init() {
+	rootCmd.AddCommand(rectifyCmd)
+}
+
+func runRectify(cmd *cobra.Command, args []string) error {
+	log, err := newLogger(cmd)
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cmd, log, true)
+	if err != nil {
+		return err
+	}
+	rectifier, ok := client.(manager.Rectifier)
+	if !ok {
+		return fmt.Errorf("the configured provider does not support rectifying zones")
+	}
+
+	for _, zone := range args {
+		status, err := rectifier.RectifyZone(context.Background(), zone)
+		if err != nil {
+			return fmt.Errorf("failed to rectify zone %s: %w", zone, err)
+		}
+		log.Info("%s: %s", zone, status)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+	"github.com/kreigan/powerdns-zone-manager/internal/ownership"
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Report on the zones of a configuration",
+}
+
+var reportOwnershipCmd = &cobra.Command{
+	Use:   "ownership [config-file]",
+	Short: "Report which accounts own the records of the configured zones",
+	Long: `Report, per configured zone, which accounts own RRsets (based on their
+ownership comments), how many RRsets are unmanaged, and which RRsets of the
+configured account would change on the next apply.
+Use "-" as the config file to read the configuration from standard input.
+
+RRsets with ownership comments of several accounts are counted for each of
+them. This is useful for shared zones that are written by several tools.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runReportOwnership,
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+	reportCmd.AddCommand(reportOwnershipCmd)
+	addCacheFlags(reportOwnershipCmd)
+}
+
+// unmanagedAccount is the account column of RRsets without ownership comments.
+const unmanagedAccount = "(unmanaged)"
+
+func runReportOwnership(cmd *cobra.Command, args []string) error {
+	log, err := newLogger(cmd)
+	if err != nil {
+		return err
+	}
+	jsonOutput, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		return fmt.Errorf("failed to get json flag: %w", err)
+	}
+	project, err := loadSettings(args[0])
+	if err != nil {
+		return err
+	}
+	cfg, _, err := loadConfig(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to load config from %s: %w", configSource(args[0]), err)
+	}
+	if project.DefaultTTL != nil {
+		cfg.SetDefaultTTL(*project.DefaultTTL)
+	}
+	accountName, err := getAccountName(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cmd, log, false)
+	if err != nil {
+		return err
+	}
+
+	ctx := cmd.Context()
+	names := make([]string, 0, len(cfg.Zones))
+	for name := range cfg.Zones {
+		names = append(names, config.CanonicalZoneName(name))
+	}
+	sort.Strings(names)
+
+	var ownerRows [][]string
+	for _, zoneID := range names {
+		zone, err := client.GetZone(ctx, zoneID)
+		if err != nil {
+			return fmt.Errorf("failed to get zone %s: %w", zoneID, err)
+		}
+		if zone == nil {
+			ownerRows = append(ownerRows, []string{zoneID, "(zone does not exist)", "0"})
+			continue
+		}
+
+		counts := make(map[string]int)
+		for _, rrset := range zone.RRsets {
+			contents := make([]string, len(rrset.Comments))
+			for i, comment := range rrset.Comments {
+				contents[i] = comment.Content
+			}
+			accounts := ownership.Accounts(contents)
+			if len(accounts) == 0 {
+				accounts = []string{unmanagedAccount}
+			}
+			for _, account := range accounts {
+				counts[account]++
+			}
+		}
+		accounts := make([]string, 0, len(counts))
+		for account := range counts {
+			accounts = append(accounts, account)
+		}
+		sort.Strings(accounts)
+		for _, account := range accounts {
+			ownerRows = append(ownerRows, []string{zoneID, account, strconv.Itoa(counts[account])})
+		}
+	}
+	log.Table("RRset ownership", []string{"ZONE", "ACCOUNT", "RRSETS"}, ownerRows)
+
+	// The pending changes are computed with a quiet dry run
+	planMgr := manager.NewManager(client, accountName, log.Quiet())
+	plan, err := planMgr.Apply(ctx, cfg, manager.ApplyOptions{DryRun: true, AutoConfirm: true})
+	if err != nil {
+		return fmt.Errorf("failed to compute pending changes: %w", err)
+	}
+	var changeRows [][]string
+	for _, zr := range plan.Zones {
+		for _, change := range zr.Changes {
+			changeRows = append(changeRows, []string{
+				config.CanonicalZoneName(zr.Name), string(change.Action), change.Name, change.Type,
+			})
+		}
+	}
+	if !jsonOutput {
+		fmt.Println()
+	}
+	log.Table("Pending changes of "+accountName, []string{"ZONE", "ACTION", "NAME", "TYPE"}, changeRows)
+	return nil
+}
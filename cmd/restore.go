@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/backup"
+	"github.com/kreigan/powerdns-zone-manager/internal/confirm"
+	"github.com/kreigan/powerdns-zone-manager/internal/i18n"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore archive",
+	Short: "Restore zones from a backup archive",
+	Long: `Re-create the zones of a backup archive (see "backup") with their RRsets
+and metadata, e.g. on a fresh PowerDNS instance.
+
+Zones that already exist are skipped; delete them first to restore them.
+--zone restores only the given zones. DNSSEC keys are not part of the
+PowerDNS API and therefore not of the backup: signed zones are re-signed
+with new keys, so their DS records must be updated at the parent.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runRestore,
+}
+
+var restoreZones []string
+var restoreDryRun bool
+var restoreAutoConfirm bool
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+	restoreCmd.Flags().StringArrayVar(&restoreZones, "zone", nil, "Only restore this zone (repeatable)")
+	restoreCmd.Flags().BoolVar(&restoreDryRun, "dry-run", false, "Only list the zones that would be restored")
+	restoreCmd.Flags().BoolVarP(&restoreAutoConfirm, "auto-confirm", "y", false, "Skip confirmation prompt")
+}
+
+func runRestore(cmd *cobra.Command, args []string) error {
+	log, err := newLogger(cmd)
+	if err != nil {
+		return err
+	}
+	jsonOutput, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		return fmt.Errorf("failed to get json flag: %w", err)
+	}
+	if jsonOutput && !restoreDryRun && !restoreAutoConfirm {
+		return fmt.Errorf("restore with --json requires --auto-confirm or --dry-run")
+	}
+
+	archive, err := backup.Load(args[0])
+	if err != nil {
+		return err
+	}
+	zones, err := archive.Select(restoreZones)
+	if err != nil {
+		return err
+	}
+	log.Info("Backup of account %s from %s with %d zone(s)", archive.Metadata.Account,
+		archive.Metadata.CreatedAt.Format("2006-01-02 15:04:05 MST"), len(archive.Zones))
+
+	client, err := newAPIClient(cmd, log, !restoreDryRun)
+	if err != nil {
+		return err
+	}
+	ctx := cmd.Context()
+
+	var pending []backup.Zone
+	rows := make([][]string, 0, len(zones))
+	for _, z := range zones {
+		existing, err := client.GetZoneInfo(ctx, z.Zone.Name)
+		if err != nil {
+			return fmt.Errorf("failed to get zone %s: %w", z.Zone.Name, err)
+		}
+		status := "restore"
+		if existing != nil {
+			status = "exists, skipped"
+		} else {
+			pending = append(pending, z)
+		}
+		rows = append(rows, []string{z.Zone.Name, z.Zone.Kind, z.Zone.Account,
+			strconv.Itoa(len(z.Zone.RRsets)), status})
+	}
+	log.Table("Zones", []string{"ZONE", "KIND", "ACCOUNT", "RRSETS", "STATUS"}, rows)
+	if len(pending) == 0 || restoreDryRun {
+		return nil
+	}
+
+	if !restoreAutoConfirm {
+		req := &manager.ConfirmRequest{Prompt: i18n.T("Restore %d zone(s)?", len(pending))}
+		ok, err := confirm.NewTerminal(os.Stdin, os.Stdout).Confirm(ctx, req)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return manager.ErrAborted
+		}
+	}
+
+	metadata, hasMetadata := client.(manager.MetadataProvider)
+	for _, z := range pending {
+		if _, err := client.CreateZone(ctx, z.RestoreZone()); err != nil {
+			return fmt.Errorf("failed to restore zone %s: %w", z.Zone.Name, err)
+		}
+		for _, md := range z.Metadata {
+			if !hasMetadata || slices.Contains(backup.ReadOnlyMetadata, md.Kind) {
+				continue
+			}
+			if err := metadata.SetMetadata(ctx, z.Zone.Name, md.Kind, md.Metadata); err != nil {
+				return fmt.Errorf("failed to restore metadata %s of zone %s: %w", md.Kind, z.Zone.Name, err)
+			}
+		}
+		log.Info("Restored zone %s (%d RRsets)", z.Zone.Name, len(z.Zone.RRsets))
+		if z.Zone.DNSSEC {
+			log.Warn("Zone %s is signed with new DNSSEC keys, update its DS records at the parent", z.Zone.Name)
+		}
+	}
+	return nil
+}
@@ -2,16 +2,41 @@
 package cmd
 
 import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/confirm"
+	"github.com/kreigan/powerdns-zone-manager/internal/fileprovider"
+	"github.com/kreigan/powerdns-zone-manager/internal/i18n"
+	"github.com/kreigan/powerdns-zone-manager/internal/logger"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+	"github.com/kreigan/powerdns-zone-manager/internal/settings"
 )
 
 const (
 	defaultAccountName = "zone-manager"
 )
 
+// Supported DNS providers.
+const (
+	providerPowerDNS = "powerdns"
+	providerFile     = "file"
+	providerZoneFile = "zonefile"
+)
+
 var (
 	version = "dev"
 	commit  = "none"
@@ -28,36 +53,451 @@ name in comments. Only managed records are modified; other records are left unto
 
 A record set is considered managed if it has at least one comment where its
 'account' property value matches the configured account name (default: zone-manager,
-configurable via ACCOUNT_NAME environment variable).`,
+configurable via --account, the ACCOUNT_NAME environment variable, the config
+file 'account' key or the .pdns-zm.yaml settings file).`,
 	Version:       fmt.Sprintf("%s (commit: %s, built: %s)", version, commit, date),
 	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+		lang, err := cmd.Flags().GetString("lang")
+		if err != nil {
+			return fmt.Errorf("failed to get lang flag: %w", err)
+		}
+		if lang == "" {
+			return nil
+		}
+		return i18n.Set(lang)
+	},
 }
 
 // Execute runs the root command.
 func Execute() error {
+	// Errors of flag parsing are reported before --lang is read
+	if err := i18n.Set(i18n.Detect()); err != nil {
+		return err
+	}
 	return rootCmd.Execute()
 }
 
+// localizedErrors are the errors whose message is translated wherever it
+// appears in the message of a failed command.
+var localizedErrors = []error{manager.ErrAborted, manager.ErrZoneModified, confirm.ErrConfirmationRequired}
+
+// ErrorMessage formats the error of a failed command for the user, in the
+// language of the run (see package i18n).
+func ErrorMessage(err error) string {
+	msg := err.Error()
+	for _, known := range localizedErrors {
+		if errors.Is(err, known) {
+			msg = strings.Replace(msg, known.Error(), i18n.T(known.Error()), 1)
+		}
+	}
+	return i18n.T("Error: %v", msg)
+}
+
 func init() {
-	rootCmd.PersistentFlags().String(
-		"api-url", "", "PowerDNS API base URL (e.g., http://localhost:8081/api/v1/servers/localhost)")
+	rootCmd.PersistentFlags().String("api-url", "",
+		"PowerDNS API base URL (e.g., http://localhost:8081/api/v1/servers/localhost, or unix:///path/to/api.sock)")
 	rootCmd.PersistentFlags().String("api-key", "", "PowerDNS API key")
+	rootCmd.PersistentFlags().String(
+		"read-api-url", "", "PowerDNS API base URL for read-only operations (defaults to --api-url)")
+	rootCmd.PersistentFlags().String(
+		"read-api-key", "", "PowerDNS API key for read-only operations (plans do not need --api-key)")
+	rootCmd.PersistentFlags().String("recursor-api-url", "",
+		"PowerDNS Recursor API base URL managing the zones with target recursor")
+	rootCmd.PersistentFlags().String("recursor-api-key", "", "PowerDNS Recursor API key")
+	rootCmd.PersistentFlags().String(
+		"provider", providerPowerDNS, "DNS provider to reconcile zones against (powerdns, file, zonefile)")
+	rootCmd.PersistentFlags().String(
+		"provider-dir", "zones", "Directory of the file and zonefile providers")
+	rootCmd.PersistentFlags().String("simulate-failures", "",
+		"Fail calls of the file and zonefile providers with simulated API errors, e.g. rate=0.1 or rate=0.1,seed=42")
+	rootCmd.PersistentFlags().String(
+		"account", "", "Account name marking managed zones and records (default: zone-manager)")
+	rootCmd.PersistentFlags().Bool("require-explicit-account", false,
+		"Fail instead of falling back to the default account name")
+	rootCmd.PersistentFlags().String("api-basic-auth", "",
+		"HTTP basic auth credentials (user:password) for a reverse proxy in front of the API")
+	rootCmd.PersistentFlags().StringArray("api-header", nil,
+		"Extra header added to every API request, e.g. 'X-Change-Ticket: CHG-42' (repeatable)")
+	rootCmd.PersistentFlags().Int("http-max-idle-conns", 0,
+		"Idle API connections kept for reuse (default: net/http defaults)")
+	rootCmd.PersistentFlags().Int("http-max-conns", 0, "Maximum API connections, 0 for no limit")
+	rootCmd.PersistentFlags().Duration("http-idle-timeout", 0, "How long idle API connections are kept (default 90s)")
+	rootCmd.PersistentFlags().Bool("http-disable-keepalives", false, "Open a new API connection for every request")
+	rootCmd.PersistentFlags().Bool("http2", true, "Use HTTP/2 if the API endpoint supports it")
+	rootCmd.PersistentFlags().Duration("rate-limit-max-wait", powerdns.DefaultMaxRateLimitWait,
+		"How long a request waits for the rate limit of an API gateway (429, Retry-After), 0 to fail right away")
+	rootCmd.PersistentFlags().String("run-id", "",
+		"ID of this run recorded in ownership comments, logs and reports (default: generated)")
 	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Enable verbose/debug output")
 	rootCmd.PersistentFlags().Bool("json", false, "Output in JSON format (structured logging)")
-	rootCmd.PersistentFlags().Bool("no-color", false, "Disable colored output")
+	rootCmd.PersistentFlags().Bool("no-color", false,
+		"Disable colored output (default: colored on terminals, unless NO_COLOR is set)")
+	rootCmd.PersistentFlags().String("lang", "", "Language of prompts, summaries and errors: "+
+		strings.Join(i18n.Languages(), ", ")+" (default: from LC_ALL, LC_MESSAGES or LANG)")
+}
 
-	if err := rootCmd.MarkPersistentFlagRequired("api-url"); err != nil {
-		panic(fmt.Sprintf("failed to mark api-url as required: %v", err))
+// newLogger creates a logger from the output flags.
+func newLogger(cmd *cobra.Command) (*logger.Logger, error) {
+	var opts logger.Options
+	flags := map[string]*bool{
+		"verbose":  &opts.Verbose,
+		"json":     &opts.JSON,
+		"no-color": &opts.NoColor,
 	}
-	if err := rootCmd.MarkPersistentFlagRequired("api-key"); err != nil {
-		panic(fmt.Sprintf("failed to mark api-key as required: %v", err))
+	for name, target := range flags {
+		value, err := cmd.Flags().GetBool(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s flag: %w", name, err)
+		}
+		*target = value
 	}
+	return logger.New(opts), nil
+}
+
+// apiClient is the DNS provider client used by commands.
+type apiClient interface {
+	manager.Provider
+	Stats() []powerdns.RequestStats
 }
 
-// getAccountName returns the account name from environment or default
-func getAccountName() string {
-	if name := os.Getenv("ACCOUNT_NAME"); name != "" {
-		return name
+// newAPIClient creates the DNS provider client from the provider and API flags.
+// If a read-only key is configured, reads use the read-only credentials and
+// the write credentials are only required when write is true.
+func newAPIClient(cmd *cobra.Command, log *logger.Logger, write bool) (apiClient, error) {
+	provider, err := cmd.Flags().GetString("provider")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider flag: %w", err)
+	}
+	simulate, err := cmd.Flags().GetString("simulate-failures")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get simulate-failures flag: %w", err)
+	}
+	if provider == providerFile || provider == providerZoneFile {
+		dir, err := cmd.Flags().GetString("provider-dir")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get provider-dir flag: %w", err)
+		}
+		log.Debug("File provider directory: %s", dir)
+		opts := fileprovider.Options{ZoneFiles: provider == providerZoneFile}
+		if simulate != "" {
+			if opts.Failures, err = fileprovider.ParseFailures(simulate); err != nil {
+				return nil, fmt.Errorf("invalid --simulate-failures: %w", err)
+			}
+			log.Warn("Simulating API failures of %.0f%% of the calls (seed %d)",
+				opts.Failures.Rate*100, opts.Failures.Seed)
+		}
+		return fileprovider.New(dir, opts), nil
+	}
+	// Failures are never injected into calls of a real server
+	if simulate != "" {
+		return nil, fmt.Errorf("--simulate-failures requires the %s or %s provider", providerFile, providerZoneFile)
+	}
+	if provider != providerPowerDNS {
+		return nil, fmt.Errorf("unsupported provider %q, must be: %s, %s, %s",
+			provider, providerPowerDNS, providerFile, providerZoneFile)
+	}
+
+	flags := make(map[string]string)
+	for _, name := range []string{"api-url", "api-key", "read-api-url", "read-api-key"} {
+		value, err := cmd.Flags().GetString(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s flag: %w", name, err)
+		}
+		flags[name] = value
+	}
+	project, err := currentSettings()
+	if err != nil {
+		return nil, err
+	}
+	if flags["api-url"] == "" {
+		flags["api-url"] = project.APIURL
+	}
+	if flags["read-api-url"] == "" {
+		flags["read-api-url"] = project.ReadAPIURL
+	}
+	apiURL, apiKey := flags["api-url"], flags["api-key"]
+	readURL, readKey := flags["read-api-url"], flags["read-api-key"]
+
+	log.Debug("API URL: %s", apiURL)
+	log.Debug("API Key: %s", logger.MaskSecret(apiKey))
+
+	opts, err := getClientOptions(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	// No role separation: a single client for everything
+	if readKey == "" {
+		if apiURL == "" || apiKey == "" {
+			return nil, errors.New(`required flag(s) "api-url", "api-key" not set`)
+		}
+		return powerdns.NewClientWithOptions(apiURL, apiKey, opts, log), nil
+	}
+
+	if readURL == "" {
+		readURL = apiURL
+	}
+	if readURL == "" {
+		return nil, errors.New(`either "api-url" or "read-api-url" must be set`)
+	}
+	log.Debug("Read API URL: %s", readURL)
+	log.Debug("Read API Key: %s", logger.MaskSecret(readKey))
+	reader := powerdns.NewClientWithOptions(readURL, readKey, opts, log)
+
+	var writer *powerdns.Client
+	if write {
+		if apiURL == "" || apiKey == "" {
+			return nil, errors.New(`required flag(s) "api-url", "api-key" not set (needed to apply changes)`)
+		}
+		writer = powerdns.NewClientWithOptions(apiURL, apiKey, opts, log)
+	}
+	return powerdns.NewRoleClient(reader, writer), nil
+}
+
+// getRunID returns the run ID from the --run-id flag, e.g. a CI pipeline ID,
+// or generates one from the current time and a random suffix.
+func getRunID(cmd *cobra.Command) (string, error) {
+	id, err := cmd.Flags().GetString("run-id")
+	if err != nil {
+		return "", fmt.Errorf("failed to get run-id flag: %w", err)
+	}
+	if id != "" {
+		return id, nil
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("failed to generate run ID: %w", err)
+	}
+	return time.Now().UTC().Format("20060102T150405") + "-" + hex.EncodeToString(suffix), nil
+}
+
+// getClientOptions returns the HTTP connection options from the http and
+// api-basic-auth flags.
+func getClientOptions(cmd *cobra.Command) (powerdns.ClientOptions, error) {
+	var opts powerdns.ClientOptions
+	var err error
+	flags := cmd.Flags()
+	if opts.MaxIdleConns, err = flags.GetInt("http-max-idle-conns"); err != nil {
+		return opts, fmt.Errorf("failed to get http-max-idle-conns flag: %w", err)
+	}
+	if opts.MaxConnsPerHost, err = flags.GetInt("http-max-conns"); err != nil {
+		return opts, fmt.Errorf("failed to get http-max-conns flag: %w", err)
+	}
+	if opts.IdleConnTimeout, err = flags.GetDuration("http-idle-timeout"); err != nil {
+		return opts, fmt.Errorf("failed to get http-idle-timeout flag: %w", err)
+	}
+	if opts.DisableKeepAlives, err = flags.GetBool("http-disable-keepalives"); err != nil {
+		return opts, fmt.Errorf("failed to get http-disable-keepalives flag: %w", err)
+	}
+	http2, err := flags.GetBool("http2")
+	if err != nil {
+		return opts, fmt.Errorf("failed to get http2 flag: %w", err)
+	}
+	opts.DisableHTTP2 = !http2
+	if opts.MaxRateLimitWait, err = flags.GetDuration("rate-limit-max-wait"); err != nil {
+		return opts, fmt.Errorf("failed to get rate-limit-max-wait flag: %w", err)
+	}
+
+	basicAuth, err := flags.GetString("api-basic-auth")
+	if err != nil {
+		return opts, fmt.Errorf("failed to get api-basic-auth flag: %w", err)
+	}
+	if basicAuth != "" {
+		user, password, ok := strings.Cut(basicAuth, ":")
+		if !ok || user == "" {
+			return opts, errors.New("--api-basic-auth must be in the form user:password")
+		}
+		opts.BasicAuthUser, opts.BasicAuthPassword = user, password
+	}
+
+	if opts.RecursorURL, err = flags.GetString("recursor-api-url"); err != nil {
+		return opts, fmt.Errorf("failed to get recursor-api-url flag: %w", err)
+	}
+	if opts.RecursorAPIKey, err = flags.GetString("recursor-api-key"); err != nil {
+		return opts, fmt.Errorf("failed to get recursor-api-key flag: %w", err)
+	}
+	if opts.RecursorURL == "" {
+		project, err := currentSettings()
+		if err != nil {
+			return opts, err
+		}
+		opts.RecursorURL = project.RecursorAPIURL
+	}
+
+	if opts.Headers, err = getAPIHeaders(cmd); err != nil {
+		return opts, err
+	}
+	if opts.Cache, err = getDiskCache(cmd); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// getAPIHeaders returns the extra API request headers of the project
+// settings, overridden by the --api-header flags.
+func getAPIHeaders(cmd *cobra.Command) (map[string]string, error) {
+	values, err := cmd.Flags().GetStringArray("api-header")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api-header flag: %w", err)
+	}
+	project, err := currentSettings()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 && len(project.APIHeaders) == 0 {
+		return nil, nil
+	}
+
+	headers := make(map[string]string, len(project.APIHeaders)+len(values))
+	for name, value := range project.APIHeaders {
+		headers[http.CanonicalHeaderKey(name)] = value
+	}
+	for _, header := range values {
+		name, value, err := powerdns.ParseHeader(header)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --api-header: %w", err)
+		}
+		headers[http.CanonicalHeaderKey(name)] = value
+	}
+	return headers, nil
+}
+
+// addCacheFlags adds the disk cache flags to a read-only command.
+func addCacheFlags(cmd *cobra.Command) {
+	cmd.Flags().Duration("cache-max-age", powerdns.DefaultCacheMaxAge,
+		"Reuse API responses cached on disk up to this age, 0 to disable the cache")
+	cmd.Flags().Bool("no-cache", false, "Bypass the API response cache")
+}
+
+// getDiskCache returns the disk cache of commands with cache flags, see
+// addCacheFlags, or nil if the command does not use it or it is disabled.
+func getDiskCache(cmd *cobra.Command) (*powerdns.DiskCache, error) {
+	if cmd.Flags().Lookup("cache-max-age") == nil {
+		return nil, nil
+	}
+	maxAge, err := cmd.Flags().GetDuration("cache-max-age")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cache-max-age flag: %w", err)
+	}
+	noCache, err := cmd.Flags().GetBool("no-cache")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get no-cache flag: %w", err)
+	}
+	if noCache || maxAge <= 0 {
+		return nil, nil
+	}
+	dir, err := powerdns.DefaultCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return powerdns.NewDiskCache(dir, maxAge), nil
+}
+
+// stdinPath is the config path that reads the configuration from standard input.
+const stdinPath = "-"
+
+// loadConfig loads the configuration from a file, or from stdin if path is "-".
+// The raw configuration is returned along with the parsed one.
+func loadConfig(path string) (*config.Config, []byte, error) {
+	var data []byte
+	var err error
+	if path == stdinPath {
+		if data, err = io.ReadAll(os.Stdin); err != nil {
+			return nil, nil, fmt.Errorf("failed to read input: %w", err)
+		}
+	} else if data, err = os.ReadFile(path); err != nil { //nolint:gosec // path is from CLI argument
+		return nil, nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	cfg, err := config.LoadFromNamedReader(bytes.NewReader(data), configSource(path))
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, data, nil
+}
+
+// configSource returns a human-readable name of the configuration source.
+func configSource(path string) string {
+	if path == stdinPath {
+		return "stdin"
+	}
+	return path
+}
+
+// projectSettings are the project settings, see loadSettings.
+var projectSettings *settings.Settings
+
+// loadSettings discovers the project settings file starting from the
+// directory of the config file, or the working directory for stdin.
+func loadSettings(configPath string) (*settings.Settings, error) {
+	dir := "."
+	if configPath != stdinPath {
+		dir = filepath.Dir(configPath)
+	}
+	s, err := settings.Discover(dir)
+	if err != nil {
+		return nil, err
+	}
+	projectSettings = s
+	return s, nil
+}
+
+// currentSettings returns the loaded project settings, discovering them
+// from the working directory if no config file was loaded.
+func currentSettings() (*settings.Settings, error) {
+	if projectSettings != nil {
+		return projectSettings, nil
+	}
+	return loadSettings(stdinPath)
+}
+
+// getAccountName returns the account name from the --account flag, the
+// ACCOUNT_NAME environment variable, the config file (cfg may be nil) or the
+// project settings, falling back to the default unless an explicit account is
+// required. Sources that set different names are an error: a wrong account
+// silently adopts someone else's records.
+func getAccountName(cmd *cobra.Command, cfg *config.Config) (string, error) {
+	flag, err := cmd.Flags().GetString("account")
+	if err != nil {
+		return "", fmt.Errorf("failed to get account flag: %w", err)
+	}
+
+	sources := []struct{ name, value string }{
+		{"--account", flag},
+		{"ACCOUNT_NAME", os.Getenv("ACCOUNT_NAME")},
+	}
+	if cfg != nil {
+		sources = append(sources, struct{ name, value string }{"config file", cfg.Account})
+	}
+	if projectSettings != nil {
+		sources = append(sources, struct{ name, value string }{projectSettings.Path, projectSettings.Account})
+	}
+
+	account, from := "", ""
+	for _, source := range sources {
+		switch {
+		case source.value == "":
+		case account == "":
+			account, from = source.value, source.name
+		case source.value != account:
+			return "", fmt.Errorf("conflicting account names: %q from %s, %q from %s",
+				account, from, source.value, source.name)
+		}
+	}
+
+	if account != "" {
+		return account, nil
+	}
+
+	required, err := cmd.Flags().GetBool("require-explicit-account")
+	if err != nil {
+		return "", fmt.Errorf("failed to get require-explicit-account flag: %w", err)
+	}
+	if required || (projectSettings != nil && projectSettings.RequireExplicitAccount) {
+		return "", errors.New("no account name configured and an explicit account is required " +
+			"(set --account, ACCOUNT_NAME, the config file account or the settings file account)")
 	}
-	return defaultAccountName
+	return defaultAccountName, nil
 }
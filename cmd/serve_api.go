@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/logger"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+	"github.com/kreigan/powerdns-zone-manager/internal/server"
+	"github.com/kreigan/powerdns-zone-manager/internal/settings"
+)
+
+// serveAPITokenEnv is the environment variable holding the API bearer token.
+const serveAPITokenEnv = "SERVE_API_TOKEN"
+
+var serveAPICmd = &cobra.Command{
+	Use:   "serve-api",
+	Short: "Serve plan, apply and check as an HTTP API",
+	Long: `Serve plan, apply and check as an authenticated HTTP API, so that other tools
+can change DNS through the same validation and ownership rules as "apply".
+
+Requests post a zone configuration (YAML) to /v1/plan, /v1/apply or /v1/check
+with an "Authorization: Bearer <token>" header, where the token is read from
+` + serveAPITokenEnv + `. Requests are processed one at a time.
+
+Applies are checked and followed up like "apply --auto-confirm" with the
+project settings of the working directory: changes rejected by the safety
+thresholds, the change policy or a validate or pre_apply hook fail with 422,
+and the dnsdist caches, post_apply hooks, journal and history are handled as
+for a config from stdin.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         runServeAPI,
+}
+
+var serveAPIListen string
+
+func init() {
+	rootCmd.AddCommand(serveAPICmd)
+	serveAPICmd.Flags().StringVar(&serveAPIListen, "listen", ":8080", "Address to listen on")
+	serveAPICmd.Flags().BoolVar(&lockZones, "lock", false,
+		"Lock existing zones with a TXT record while they are applied, failing if another run holds the lock")
+	serveAPICmd.Flags().DurationVar(&lockTTL, "lock-ttl", 15*time.Minute,
+		"How long zone locks are valid, after which they can be taken over")
+}
+
+func runServeAPI(cmd *cobra.Command, _ []string) error {
+	log, err := newLogger(cmd)
+	if err != nil {
+		return err
+	}
+
+	token := os.Getenv(serveAPITokenEnv)
+	if token == "" {
+		return fmt.Errorf("%s environment variable is required", serveAPITokenEnv)
+	}
+
+	// Settings are discovered from the working directory, as for a config from stdin
+	project, err := loadSettings(stdinPath)
+	if err != nil {
+		return err
+	}
+	accountName, err := getAccountName(cmd, nil)
+	if err != nil {
+		return err
+	}
+	log.Debug("Account name: %s", accountName)
+
+	client, err := newAPIClient(cmd, log, true)
+	if err != nil {
+		return err
+	}
+	srv, err := newAPIServer(cmd, log, client, project, accountName, token)
+	if err != nil {
+		return err
+	}
+
+	httpServer := &http.Server{
+		Addr:              serveAPIListen,
+		Handler:           srv.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			log.Error("Failed to shut down the API server: %v", err)
+		}
+	}()
+
+	log.Info("Serving API on %s", serveAPIListen)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("API server failed: %w", err)
+	}
+	log.Info("API server stopped")
+	return nil
+}
+
+// cacheResetter is implemented by clients that cache zones, see
+// powerdns.Client.ResetCache.
+type cacheResetter interface {
+	ResetCache()
+}
+
+// newAPIServer creates the API server of serve-api, applying to client.
+// Cached zones are dropped at the start of every request.
+func newAPIServer(
+	cmd *cobra.Command,
+	log *logger.Logger,
+	client manager.Provider,
+	project *settings.Settings,
+	accountName, token string,
+) (*server.Server, error) {
+	pacing, err := patchPacing(cmd, project)
+	if err != nil {
+		return nil, err
+	}
+	run := func(ctx context.Context, cfg *config.Config, runID string, dryRun bool) (*manager.ApplyResult, error) {
+		if cache, ok := client.(cacheResetter); ok {
+			cache.ResetCache()
+		}
+		mgr := manager.NewManager(client, accountName, log.Quiet())
+		mgr.SetToolVersion(version)
+		mgr.SetRunID(runID)
+		r := &applyRun{
+			log:     log,
+			client:  client,
+			mgr:     mgr,
+			project: project,
+			cfg:     cfg,
+			opts: manager.ApplyOptions{
+				DryRun:      dryRun,
+				AutoConfirm: true,
+				Pacing:      pacing,
+				Lock:        lockZones,
+				LockTTL:     lockTTL,
+			},
+			hooks:   &hookInput{RunID: runID, Account: accountName, Config: "serve-api"},
+			hookDir: ".",
+			journal: journalPath(stdinPath),
+			history: historyPath(stdinPath),
+		}
+		result, err := r.execute(ctx)
+		var rejected *rejectedError
+		if errors.As(err, &rejected) {
+			return nil, fmt.Errorf("%w: %w", server.ErrRejected, err)
+		}
+		return result, err
+	}
+
+	opts := server.Options{
+		Token:           token,
+		Account:         accountName,
+		StrictNames:     project.StrictNames,
+		DanglingTargets: project.DanglingTargets,
+		DualStack:       project.DualStack,
+	}
+	if project.DefaultTTL != nil {
+		opts.DefaultTTL = *project.DefaultTTL
+	}
+	return server.New(run, opts, log)
+}
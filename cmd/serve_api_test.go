@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/fileprovider"
+	"github.com/kreigan/powerdns-zone-manager/internal/logger"
+	"github.com/kreigan/powerdns-zone-manager/internal/settings"
+)
+
+func TestServeAPI_Safety(t *testing.T) {
+	const zones = "zones:\n  example.com:\n    nameservers: [ns1.example.com.]\n" +
+		"    rrsets:\n      - {name: www, type: A, records: 192.0.2.1}\n" +
+		"      - {name: mail, type: A, records: 192.0.2.2}\n"
+
+	tests := []struct {
+		name       string
+		maxChanges int
+		wantStatus int
+	}{
+		{"within limit", 3, http.StatusOK},
+		// The nameservers of the created zone are updated as well
+		{"over limit", 2, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The journal and the history are recorded in the working directory
+			dir := t.TempDir()
+			t.Chdir(dir)
+			providerDir := filepath.Join(dir, "provider")
+			log := logger.New(logger.Options{NoColor: true}).Quiet()
+			client := fileprovider.New(providerDir, fileprovider.Options{})
+			project := &settings.Settings{Safety: settings.Safety{MaxChanges: tt.maxChanges}}
+			srv, err := newAPIServer(serveAPICmd, log, client, project, "test", "secret")
+			if err != nil {
+				t.Fatalf("newAPIServer failed: %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/apply", strings.NewReader(zones))
+			req.Header.Set("Authorization", "Bearer secret")
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			_, statErr := os.Stat(filepath.Join(providerDir, "example.com.json"))
+			if applied := statErr == nil; applied != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("Expected the zone to be applied: %v, got %v", tt.wantStatus == http.StatusOK, applied)
+			}
+			if tt.wantStatus != http.StatusOK && !strings.Contains(rec.Body.String(), "more than the limit of 2") {
+				t.Errorf("Expected the safety threshold in the response, got %s", rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+	"github.com/kreigan/powerdns-zone-manager/internal/serverconfig"
+)
+
+var serverConfigCmd = &cobra.Command{
+	Use:   "server-config",
+	Short: "Inspect the PowerDNS server configuration",
+}
+
+var serverConfigAuditCmd = &cobra.Command{
+	Use:   "audit baseline-file",
+	Short: "Compare the server configuration against a baseline",
+	Long: `Compare the configuration settings of the PowerDNS server against the
+expected values of a baseline file and report the settings that drifted.
+
+The baseline maps setting names to their expected values. Lists are compared
+regardless of their order, booleans in any spelling (yes, true, on):
+
+  settings:
+    api: "yes"
+    default-ksk-algorithm: ecdsa256
+    allow-axfr-ips: [127.0.0.0/8, "::1"]
+
+The command exits non-zero if any setting differs from the baseline or is not
+reported by the server.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runServerConfigAudit,
+}
+
+func init() {
+	rootCmd.AddCommand(serverConfigCmd)
+	serverConfigCmd.AddCommand(serverConfigAuditCmd)
+}
+
+// serverConfigClient is implemented by providers that report the server
+// configuration.
+type serverConfigClient interface {
+	GetConfig(ctx context.Context) ([]powerdns.ConfigSetting, error)
+}
+
+func runServerConfigAudit(cmd *cobra.Command, args []string) error {
+	baseline, err := serverconfig.Load(args[0])
+	if err != nil {
+		return err
+	}
+
+	log, err := newLogger(cmd)
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cmd, log, false)
+	if err != nil {
+		return err
+	}
+	sc, ok := client.(serverConfigClient)
+	if !ok {
+		return fmt.Errorf("the configured provider does not support the server configuration")
+	}
+
+	settings, err := sc.GetConfig(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("failed to get server configuration: %w", err)
+	}
+
+	results := baseline.Audit(settings)
+	rows := make([][]string, len(results))
+	for i, r := range results {
+		rows[i] = []string{r.Setting, r.Status, r.Expected, r.Actual}
+	}
+	log.Table("Server configuration", []string{"SETTING", "STATUS", "EXPECTED", "ACTUAL"}, rows)
+
+	if drifted := serverconfig.Drifted(results); drifted > 0 {
+		return fmt.Errorf("%d of %d setting(s) drifted from the baseline", drifted, len(results))
+	}
+	log.Info("All %d setting(s) match the baseline", len(results))
+	return nil
+}
@@ -0,0 +1,230 @@
+// Package annotation formats validation errors and drift findings as CI
+// annotations, so that they show inline on pull and merge requests.
+package annotation
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+)
+
+// Supported annotation formats.
+const (
+	// FormatGitHub prints GitHub Actions workflow commands.
+	FormatGitHub = "github"
+	// FormatGitLab writes a GitLab Code Quality report.
+	FormatGitLab = "gitlab"
+)
+
+// Severity is the severity of a finding.
+type Severity string
+
+// Finding severities.
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Finding is a single annotation at a configuration location.
+type Finding struct {
+	Location config.Location
+	Severity Severity
+	Title    string
+	Message  string
+}
+
+// ValidateFormat returns an error if format is not a supported annotation format.
+func ValidateFormat(format string) error {
+	if format != FormatGitHub && format != FormatGitLab {
+		return fmt.Errorf("unsupported annotation format %q, must be: %s, %s", format, FormatGitHub, FormatGitLab)
+	}
+	return nil
+}
+
+// FromError returns the findings of a validation error, or nil if err is not one.
+func FromError(err error) []Finding {
+	var validationErr *config.ValidationError
+	if !errors.As(err, &validationErr) {
+		return nil
+	}
+	findings := make([]Finding, len(validationErr.Errors))
+	for i, msg := range validationErr.Errors {
+		var loc config.Location
+		if i < len(validationErr.Locations) {
+			loc = validationErr.Locations[i]
+		}
+		findings[i] = Finding{
+			Location: loc,
+			Severity: SeverityError,
+			Title:    "Invalid zone configuration",
+			Message:  strings.TrimPrefix(msg, loc.Position()+": "),
+		}
+	}
+	return findings
+}
+
+// FromResult returns a drift finding for each change of a dry run: the
+// server state differs from the configuration.
+func FromResult(result *manager.ApplyResult, cfg *config.Config) []Finding {
+	if result == nil {
+		return nil
+	}
+	zones := make(map[string]config.Location, len(cfg.Zones))
+	for name, zone := range cfg.Zones {
+		zones[config.CanonicalZoneName(name)] = zone.Location()
+	}
+	zoneLocation := func(name string) config.Location {
+		return zones[config.CanonicalZoneName(name)]
+	}
+
+	var findings []Finding
+	for _, zr := range result.Zones {
+		if zr.Created {
+			findings = append(findings, Finding{
+				Location: zoneLocation(zr.Name),
+				Severity: SeverityWarning,
+				Title:    "Zone drift",
+				Message:  fmt.Sprintf("zone %s does not exist and would be created", zr.Name),
+			})
+		}
+		for _, change := range zr.Changes {
+			findings = append(findings, Finding{
+				Location: change.Location,
+				Severity: SeverityWarning,
+				Title:    "RRset drift",
+				Message:  fmt.Sprintf("%s %s would be %s", change.Name, change.Type, pastTense(change.Action)),
+			})
+		}
+		for _, md := range zr.Metadata {
+			findings = append(findings, Finding{
+				Location: zoneLocation(zr.Name),
+				Severity: SeverityWarning,
+				Title:    "Metadata drift",
+				Message:  fmt.Sprintf("metadata %s of zone %s would be changed", md.Kind, zr.Name),
+			})
+		}
+	}
+	return findings
+}
+
+func pastTense(action manager.ChangeAction) string {
+	switch action {
+	case manager.ChangeCreate:
+		return "created"
+	case manager.ChangeDelete:
+		return "deleted"
+	default:
+		return "updated"
+	}
+}
+
+// Write writes the findings in the given format.
+func Write(w io.Writer, format string, findings []Finding) error {
+	switch format {
+	case FormatGitHub:
+		return WriteGitHub(w, findings)
+	case FormatGitLab:
+		return WriteGitLab(w, findings)
+	default:
+		return ValidateFormat(format)
+	}
+}
+
+// WriteGitHub writes the findings as GitHub Actions workflow commands, e.g.
+// "::error file=zones.yml,line=12,col=9,title=...::message".
+// See: https://docs.github.com/actions/reference/workflow-commands-for-github-actions
+func WriteGitHub(w io.Writer, findings []Finding) error {
+	for _, f := range findings {
+		var props []string
+		if f.Location.File != "" && f.Location.Line > 0 {
+			props = append(props,
+				"file="+escapeProperty(f.Location.File),
+				fmt.Sprintf("line=%d", f.Location.Line),
+				fmt.Sprintf("col=%d", f.Location.Column),
+			)
+		}
+		if f.Title != "" {
+			props = append(props, "title="+escapeProperty(f.Title))
+		}
+		command := "::" + string(f.Severity)
+		if len(props) > 0 {
+			command += " " + strings.Join(props, ",")
+		}
+		if _, err := fmt.Fprintf(w, "%s::%s\n", command, escapeData(message(f))); err != nil {
+			return fmt.Errorf("failed to write annotation: %w", err)
+		}
+	}
+	return nil
+}
+
+// message returns the finding message, including the key path that GitHub
+// annotations have no property for.
+func message(f Finding) string {
+	if f.Location.Path == "" {
+		return f.Message
+	}
+	return f.Message + " (" + f.Location.Path + ")"
+}
+
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// codeQualityIssue is an issue of a GitLab Code Quality report.
+// See: https://docs.gitlab.com/ci/testing/code_quality/#code-quality-report-format
+type codeQualityIssue struct {
+	Location    codeQualityLocation `json:"location"`
+	Description string              `json:"description"`
+	CheckName   string              `json:"check_name"`
+	Fingerprint string              `json:"fingerprint"`
+	Severity    string              `json:"severity"`
+}
+
+type codeQualityLocation struct {
+	Path  string `json:"path"`
+	Lines struct {
+		Begin int `json:"begin"`
+	} `json:"lines"`
+}
+
+// WriteGitLab writes the findings as a GitLab Code Quality report, shown
+// inline in merge requests when uploaded as a codequality report artifact.
+// Findings without a file are reported at the first line of an empty path.
+func WriteGitLab(w io.Writer, findings []Finding) error {
+	issues := make([]codeQualityIssue, len(findings))
+	for i, f := range findings {
+		severity := "major"
+		if f.Severity == SeverityWarning {
+			severity = "minor"
+		}
+		issue := codeQualityIssue{
+			Description: message(f),
+			CheckName:   f.Title,
+			Severity:    severity,
+		}
+		issue.Location.Path = f.Location.File
+		issue.Location.Lines.Begin = max(f.Location.Line, 1)
+		sum := sha256.Sum256([]byte(strings.Join(
+			[]string{f.Title, f.Location.String(), f.Message}, "\x00")))
+		issue.Fingerprint = hex.EncodeToString(sum[:16])
+		issues[i] = issue
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(issues); err != nil {
+		return fmt.Errorf("failed to write code quality report: %w", err)
+	}
+	return nil
+}
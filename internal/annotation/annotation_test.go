@@ -0,0 +1,121 @@
+package annotation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+)
+
+func TestWriteGitHub(t *testing.T) {
+	tests := []struct {
+		name     string
+		finding  Finding
+		expected string
+	}{
+		{
+			name: "located error",
+			finding: Finding{
+				Location: config.Location{File: "zones.yml", Path: "zones.a.com.rrsets[0]", Line: 12, Column: 9},
+				Severity: SeverityError,
+				Title:    "Invalid zone configuration",
+				Message:  "unknown record type \"CNAM\"",
+			},
+			expected: "::error file=zones.yml,line=12,col=9,title=Invalid zone configuration::" +
+				"unknown record type \"CNAM\" (zones.a.com.rrsets[0])\n",
+		},
+		{
+			name:     "escaping",
+			finding:  Finding{Severity: SeverityWarning, Title: "a:b,c", Message: "100%\nnext"},
+			expected: "::warning title=a%3Ab%2Cc::100%25%0Anext\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteGitHub(&buf, []Finding{tt.finding}); err != nil {
+				t.Fatalf("WriteGitHub failed: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestWriteGitLab(t *testing.T) {
+	findings := []Finding{
+		{Location: config.Location{File: "zones.yml", Line: 3, Column: 5}, Severity: SeverityError, Message: "bad"},
+		{Severity: SeverityWarning, Message: "drift"},
+	}
+	var buf bytes.Buffer
+	if err := WriteGitLab(&buf, findings); err != nil {
+		t.Fatalf("WriteGitLab failed: %v", err)
+	}
+
+	var issues []codeQualityIssue
+	if err := json.Unmarshal(buf.Bytes(), &issues); err != nil {
+		t.Fatalf("Invalid report: %v", err)
+	}
+	if len(issues) != 2 {
+		t.Fatalf("Expected 2 issues, got %d", len(issues))
+	}
+	if issues[0].Location.Path != "zones.yml" || issues[0].Location.Lines.Begin != 3 || issues[0].Severity != "major" {
+		t.Errorf("Unexpected issue: %+v", issues[0])
+	}
+	if issues[1].Location.Lines.Begin != 1 || issues[1].Severity != "minor" {
+		t.Errorf("Unexpected issue: %+v", issues[1])
+	}
+	if issues[0].Fingerprint == issues[1].Fingerprint {
+		t.Error("Expected distinct fingerprints")
+	}
+}
+
+func TestFromError(t *testing.T) {
+	cfg, err := config.LoadFromNamedReader(strings.NewReader("zones:\n  a.com:\n    kind: Nativ\n"), "zones.yml")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	validationErr := cfg.Validate(map[string]config.ZoneState{})
+
+	findings := FromError(fmt.Errorf("failed: %w", validationErr))
+	if len(findings) != len(validationErr.Errors) {
+		t.Fatalf("Expected a finding per error, got %+v", findings)
+	}
+	for _, f := range findings {
+		if f.Location.File != "zones.yml" || strings.HasPrefix(f.Message, "zones.yml") {
+			t.Errorf("Expected located finding without position prefix, got %+v", f)
+		}
+	}
+
+	if findings := FromError(fmt.Errorf("other")); findings != nil {
+		t.Errorf("Expected no findings for other errors, got %+v", findings)
+	}
+}
+
+func TestFromResult(t *testing.T) {
+	cfg, err := config.LoadFromNamedReader(strings.NewReader("zones:\n  a.com:\n    kind: Native\n"), "zones.yml")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	result := &manager.ApplyResult{Zones: []manager.ZoneResult{{
+		Name:    "a.com",
+		Created: true,
+		Changes: []manager.Change{{Action: manager.ChangeDelete, Name: "old.a.com.", Type: "A"}},
+	}}}
+
+	findings := FromResult(result, cfg)
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %+v", findings)
+	}
+	if findings[0].Location.Line != 3 || findings[0].Severity != SeverityWarning {
+		t.Errorf("Expected zone creation at the zone, got %+v", findings[0])
+	}
+	if findings[1].Message != "old.a.com. A would be deleted" {
+		t.Errorf("Unexpected message: %q", findings[1].Message)
+	}
+}
@@ -0,0 +1,145 @@
+// Package approval implements the two-person rule for apply.
+//
+// A pending change bundle holds the configuration and the signed manifest of
+// the change set it produces. It is written by one operator and can only be
+// applied by a different operator who knows the approval token, before the
+// bundle expires.
+package approval
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/manifest"
+)
+
+// Version is the current bundle format version.
+const Version = 1
+
+// Errors returned by Bundle.Approve.
+var (
+	ErrInvalidSignature = errors.New("bundle signature is invalid")
+	ErrExpired          = errors.New("bundle has expired")
+	ErrInvalidToken     = errors.New("approval token is invalid")
+	ErrSameOperator     = errors.New("bundle must be approved by a different operator")
+)
+
+// Bundle is a pending change set awaiting approval.
+type Bundle struct {
+	RequestedAt  time.Time          `json:"requestedAt"`
+	ExpiresAt    time.Time          `json:"expiresAt"`
+	Manifest     *manifest.Manifest `json:"manifest"`
+	RequestedBy  string             `json:"requestedBy"`
+	ConfigSource string             `json:"configSource"`
+	TokenHash    string             `json:"tokenHash"`
+	Signature    string             `json:"signature,omitempty"`
+	Config       []byte             `json:"config"`
+	DefaultTTL   uint32             `json:"defaultTTL,omitempty"`
+	Version      int                `json:"version"`
+}
+
+// New creates an unsigned bundle for the change set described by m and
+// returns it with the approval token, which is not stored in the bundle.
+func New(m *manifest.Manifest, config []byte, source, requestedBy string, ttl time.Duration) (*Bundle, string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", fmt.Errorf("failed to generate approval token: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	return &Bundle{
+		Version:      Version,
+		Manifest:     m,
+		Config:       config,
+		ConfigSource: source,
+		RequestedBy:  requestedBy,
+		RequestedAt:  now,
+		ExpiresAt:    now.Add(ttl),
+		TokenHash:    hashToken(token),
+	}, token, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Sign signs the bundle, including its manifest, with the given key.
+func (b *Bundle) Sign(key []byte) {
+	b.Manifest.Sign(key)
+	b.Signature = b.sign(key)
+}
+
+func (b *Bundle) sign(key []byte) string {
+	unsigned := *b
+	unsigned.Signature = ""
+	data, err := json.Marshal(&unsigned)
+	if err != nil {
+		// Bundles only contain plain data types, marshaling cannot fail
+		panic(fmt.Sprintf("failed to marshal bundle: %v", err))
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Approve checks that the bundle is intact, has not expired at now, that token
+// is its approval token and that approver is not the operator who requested it.
+func (b *Bundle) Approve(key []byte, token, approver string, now time.Time) error {
+	if b.Version != Version {
+		return fmt.Errorf("unsupported bundle version %d", b.Version)
+	}
+	if b.Manifest == nil {
+		return errors.New("bundle has no manifest")
+	}
+	if !hmac.Equal([]byte(b.sign(key)), []byte(b.Signature)) {
+		return ErrInvalidSignature
+	}
+	if err := b.Manifest.Verify(key); err != nil {
+		return err
+	}
+	if !now.Before(b.ExpiresAt) {
+		return fmt.Errorf("%w at %s", ErrExpired, b.ExpiresAt.Format(time.RFC3339))
+	}
+	if !hmac.Equal([]byte(hashToken(token)), []byte(b.TokenHash)) {
+		return ErrInvalidToken
+	}
+	if approver == "" || approver == b.RequestedBy {
+		return fmt.Errorf("%w (requested by %q)", ErrSameOperator, b.RequestedBy)
+	}
+	return nil
+}
+
+// Load reads a bundle from a JSON file.
+func Load(path string) (*Bundle, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path is from CLI argument
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+
+	var b Bundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle: %w", err)
+	}
+	return &b, nil
+}
+
+// Save writes the bundle to a JSON file.
+func (b *Bundle) Save(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal bundle: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return nil
+}
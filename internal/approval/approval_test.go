@@ -0,0 +1,109 @@
+package approval
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+	"github.com/kreigan/powerdns-zone-manager/internal/manifest"
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+func testBundle(t *testing.T, key []byte) (*Bundle, string) {
+	t.Helper()
+	result := &manager.ApplyResult{Zones: []manager.ZoneResult{{
+		Name: "example.com",
+		Changes: []manager.Change{{
+			Action: manager.ChangeCreate,
+			Name:   "www.example.com.",
+			Type:   "A",
+			After:  []powerdns.Record{{Content: "192.0.2.1"}},
+			NewTTL: 300,
+		}},
+	}}}
+	config := []byte("zones:\n  example.com:\n    a: 192.0.2.1\n")
+
+	b, token, err := New(manifest.New("zone-manager", result), config, "zones.yml", "alice", time.Hour)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	b.Sign(key)
+	return b, token
+}
+
+func TestBundle_SaveAndLoad(t *testing.T) {
+	key := []byte("secret")
+	b, token := testBundle(t, key)
+
+	path := filepath.Join(t.TempDir(), "pending.json")
+	if err := b.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if err := loaded.Approve(key, token, "bob", time.Now()); err != nil {
+		t.Errorf("Expected loaded bundle to be approved, got: %v", err)
+	}
+	if string(loaded.Config) != string(b.Config) {
+		t.Errorf("Expected config %q, got %q", b.Config, loaded.Config)
+	}
+}
+
+func TestBundle_Approve(t *testing.T) {
+	key := []byte("secret")
+
+	tests := []struct {
+		name     string
+		modify   func(b *Bundle)
+		key      string
+		token    string
+		approver string
+		at       time.Duration
+		wantErr  error
+	}{
+		{name: "approved", approver: "bob"},
+		{name: "same operator", approver: "alice", wantErr: ErrSameOperator},
+		{name: "no operator", approver: "", wantErr: ErrSameOperator},
+		{name: "wrong token", approver: "bob", token: "0123", wantErr: ErrInvalidToken},
+		{name: "expired", approver: "bob", at: 2 * time.Hour, wantErr: ErrExpired},
+		{name: "wrong key", approver: "bob", key: "other", wantErr: ErrInvalidSignature},
+		{
+			name:     "extended expiry",
+			approver: "bob",
+			modify:   func(b *Bundle) { b.ExpiresAt = b.ExpiresAt.Add(24 * time.Hour) },
+			wantErr:  ErrInvalidSignature,
+		},
+		{
+			name:     "changed config",
+			approver: "bob",
+			modify:   func(b *Bundle) { b.Config = []byte("zones: {}\n") },
+			wantErr:  ErrInvalidSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, token := testBundle(t, key)
+			if tt.modify != nil {
+				tt.modify(b)
+			}
+			if tt.token != "" {
+				token = tt.token
+			}
+			verifyKey := key
+			if tt.key != "" {
+				verifyKey = []byte(tt.key)
+			}
+
+			err := b.Approve(verifyKey, token, tt.approver, time.Now().Add(tt.at))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Approve() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,378 @@
+// Package backup reads and writes backup archives of managed zones.
+//
+// An archive is a gzip compressed tar file with a backup.json describing the
+// backup and one zones/<zone>.json per zone, holding the zone with all its
+// RRsets and the zone metadata:
+//
+//	backup.json
+//	zones/example.com.json
+//	zones/example.org.json
+//
+// backup.json lists every zone with a hash of its content. An incremental
+// archive only contains the zones whose hash changed since its base archive
+// and refers to the base for the unchanged ones.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+// FormatVersion is the version of the archive layout. Version 2 added
+// content hashes and incremental archives.
+const FormatVersion = 2
+
+const (
+	metadataName = "backup.json"
+	zonesDir     = "zones/"
+)
+
+// ReadOnlyMetadata are the metadata kinds that the PowerDNS API does not
+// allow to set. They are backed up but not restored.
+var ReadOnlyMetadata = []string{
+	"API-RECTIFY", "AXFR-MASTER-TSIG", "LUA-AXFR-SCRIPT", "NSEC3NARROW", "NSEC3PARAM", "PRESIGNED", "SOA-EDIT-API",
+}
+
+// Archive is the content of a backup archive.
+type Archive struct {
+	Zones    []Zone
+	Metadata Metadata
+}
+
+// Metadata describes a backup.
+type Metadata struct {
+	CreatedAt time.Time `json:"createdAt"`
+	// Base is the archive an incremental archive is based on
+	Base    *Base      `json:"base,omitempty"`
+	Account string     `json:"account"`
+	Tool    string     `json:"tool"`
+	Zones   []ZoneInfo `json:"zones"`
+	Version int        `json:"version"`
+}
+
+// Base identifies the base archive of an incremental archive.
+type Base struct {
+	CreatedAt time.Time `json:"createdAt"`
+	// File is the path of the base archive, relative to the directory of
+	// the incremental archive unless absolute
+	File string `json:"file"`
+}
+
+// ZoneInfo summarizes a zone of the backup.
+type ZoneInfo struct {
+	Name string `json:"name"`
+	// Hash is the Hash of the zone content
+	Hash    string `json:"hash,omitempty"`
+	RRsets  int    `json:"rrsets"`
+	Records int    `json:"records"`
+	// Unchanged zones are not in an incremental archive but in its base
+	Unchanged bool `json:"unchanged,omitempty"`
+}
+
+// Zone is a backed up zone.
+type Zone struct {
+	Zone     *powerdns.Zone      `json:"zone"`
+	Metadata []powerdns.Metadata `json:"metadata,omitempty"`
+}
+
+// New returns an archive of zones, sorted by name.
+func New(account, tool string, zones []Zone) *Archive {
+	zones = slices.Clone(zones)
+	slices.SortFunc(zones, func(a, b Zone) int { return strings.Compare(a.Zone.Name, b.Zone.Name) })
+	a := &Archive{
+		Zones: zones,
+		Metadata: Metadata{
+			Version:   FormatVersion,
+			CreatedAt: time.Now().UTC().Truncate(time.Second),
+			Account:   account,
+			Tool:      tool,
+			Zones:     make([]ZoneInfo, len(zones)),
+		},
+	}
+	for i, z := range zones {
+		info := ZoneInfo{Name: z.Zone.Name, Hash: Hash(&z), RRsets: len(z.Zone.RRsets)}
+		for _, rrset := range z.Zone.RRsets {
+			info.Records += len(rrset.Records)
+		}
+		a.Metadata.Zones[i] = info
+	}
+	return a
+}
+
+// Write writes the archive.
+func (a *Archive) Write(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	if err := writeJSON(tw, metadataName, a.Metadata, a.Metadata.CreatedAt); err != nil {
+		return err
+	}
+	for _, z := range a.Zones {
+		name := zonesDir + strings.TrimSuffix(z.Zone.Name, ".") + ".json"
+		if err := writeJSON(tw, name, z, a.Metadata.CreatedAt); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	return nil
+}
+
+// Save writes the archive to a file.
+func (a *Archive) Save(filePath string) error {
+	f, err := os.Create(filePath) //nolint:gosec // path is from CLI argument
+	if err != nil {
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+	defer func() {
+		_ = f.Close() //nolint:errcheck // best effort close, write errors are reported below
+	}()
+	if err := a.Write(f); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	return nil
+}
+
+func writeJSON(tw *tar.Writer, name string, v any, modTime time.Time) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", name, err)
+	}
+	header := &tar.Header{Name: name, Mode: 0o600, Size: int64(len(data)), ModTime: modTime}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	return nil
+}
+
+// Read reads an archive and checks that it contains all zones it lists.
+func Read(r io.Reader) (*Archive, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a backup archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	var a Archive
+	hasMetadata := false
+	zones := make(map[string]Zone)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backup: %w", err)
+		}
+		switch {
+		case header.Name == metadataName:
+			if err := json.NewDecoder(tr).Decode(&a.Metadata); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", metadataName, err)
+			}
+			hasMetadata = true
+		case strings.HasPrefix(header.Name, zonesDir) && path.Ext(header.Name) == ".json":
+			var z Zone
+			if err := json.NewDecoder(tr).Decode(&z); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", header.Name, err)
+			}
+			if z.Zone == nil {
+				return nil, fmt.Errorf("%s has no zone", header.Name)
+			}
+			zones[z.Zone.Name] = z
+		}
+	}
+	if !hasMetadata {
+		return nil, fmt.Errorf("not a backup archive: %s is missing", metadataName)
+	}
+	if a.Metadata.Version > FormatVersion {
+		return nil, fmt.Errorf("unsupported backup version %d", a.Metadata.Version)
+	}
+	for _, info := range a.Metadata.Zones {
+		if info.Unchanged {
+			continue
+		}
+		z, ok := zones[info.Name]
+		if !ok {
+			return nil, fmt.Errorf("backup is incomplete: zone %s is missing", info.Name)
+		}
+		if info.Hash != "" && Hash(&z) != info.Hash {
+			return nil, fmt.Errorf("backup is corrupt: zone %s does not match its hash", info.Name)
+		}
+		a.Zones = append(a.Zones, z)
+	}
+	return &a, nil
+}
+
+// Load reads an archive from a file. The unchanged zones of an incremental
+// archive are loaded from its base archives, so the archive always contains
+// all zones it lists.
+func Load(filePath string) (*Archive, error) {
+	a, err := load(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if a.Metadata.Base == nil {
+		return a, nil
+	}
+
+	baseFile := a.Metadata.Base.File
+	if !filepath.IsAbs(baseFile) {
+		baseFile = filepath.Join(filepath.Dir(filePath), baseFile)
+	}
+	base, err := Load(baseFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load base of %s: %w", filePath, err)
+	}
+	if !base.Metadata.CreatedAt.Equal(a.Metadata.Base.CreatedAt) {
+		return nil, fmt.Errorf("base %s of %s is from %s, not %s", baseFile, filePath,
+			base.Metadata.CreatedAt.Format(time.RFC3339), a.Metadata.Base.CreatedAt.Format(time.RFC3339))
+	}
+
+	included := a.Zones
+	a.Zones = make([]Zone, 0, len(a.Metadata.Zones))
+	for _, info := range a.Metadata.Zones {
+		zones := included
+		if info.Unchanged {
+			zones = base.Zones
+		}
+		i := slices.IndexFunc(zones, func(z Zone) bool { return z.Zone.Name == info.Name })
+		if i < 0 || Hash(&zones[i]) != info.Hash {
+			return nil, fmt.Errorf("zone %s of %s is missing from its base %s", info.Name, filePath, baseFile)
+		}
+		a.Zones = append(a.Zones, zones[i])
+	}
+	return a, nil
+}
+
+// load reads a single archive file.
+func load(filePath string) (*Archive, error) {
+	f, err := os.Open(filePath) //nolint:gosec // path is from CLI argument
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer func() {
+		_ = f.Close() //nolint:errcheck // read-only file
+	}()
+	a, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+	return a, nil
+}
+
+// Incremental turns the archive into an incremental archive of base, stored
+// at baseFile: zones with the same hash as in base are only listed as
+// unchanged. The zones of the archive must be in the order of its metadata,
+// as returned by New. It returns the number of unchanged zones.
+func (a *Archive) Incremental(base *Archive, baseFile string) int {
+	hashes := make(map[string]string, len(base.Metadata.Zones))
+	for _, info := range base.Metadata.Zones {
+		hashes[info.Name] = info.Hash
+	}
+
+	unchanged := 0
+	zones := a.Zones[:0:0]
+	for i := range a.Metadata.Zones {
+		info := &a.Metadata.Zones[i]
+		if hash, ok := hashes[info.Name]; ok && hash != "" && hash == info.Hash {
+			info.Unchanged = true
+			unchanged++
+			continue
+		}
+		zones = append(zones, a.Zones[i])
+	}
+	a.Zones = zones
+	a.Metadata.Base = &Base{CreatedAt: base.Metadata.CreatedAt, File: baseFile}
+	return unchanged
+}
+
+// Hash returns a hash of the content of a zone: its settings, RRsets and
+// metadata, regardless of their order. Fields that the server changes on
+// its own, like the serial, are not included.
+func Hash(z *Zone) string {
+	rrsets := make([]powerdns.RRset, len(z.Zone.RRsets))
+	for i, rrset := range z.Zone.RRsets {
+		rrset.ChangeType = ""
+		rrset.Records = slices.Clone(rrset.Records)
+		slices.SortFunc(rrset.Records, func(a, b powerdns.Record) int { return strings.Compare(a.Content, b.Content) })
+		rrsets[i] = rrset
+	}
+	slices.SortFunc(rrsets, func(a, b powerdns.RRset) int {
+		return strings.Compare(a.Name+" "+a.Type, b.Name+" "+b.Type)
+	})
+	metadata := slices.Clone(z.Metadata)
+	slices.SortFunc(metadata, func(a, b powerdns.Metadata) int { return strings.Compare(a.Kind, b.Kind) })
+
+	content := struct {
+		Kind       string              `json:"kind"`
+		Account    string              `json:"account"`
+		Masters    []string            `json:"masters"`
+		RRsets     []powerdns.RRset    `json:"rrsets"`
+		Metadata   []powerdns.Metadata `json:"metadata"`
+		DNSSEC     bool                `json:"dnssec"`
+		APIRectify bool                `json:"api_rectify"`
+	}{z.Zone.Kind, z.Zone.Account, z.Zone.Masters, rrsets, metadata, z.Zone.DNSSEC, z.Zone.APIRectify}
+	h := sha256.New()
+	_ = json.NewEncoder(h).Encode(content) //nolint:errcheck // plain types cannot fail to encode
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Select returns the zones with the given names, all zones if names is
+// empty. Names may omit the trailing dot.
+func (a *Archive) Select(names []string) ([]Zone, error) {
+	if len(names) == 0 {
+		return a.Zones, nil
+	}
+	selected := make([]Zone, 0, len(names))
+	for _, name := range names {
+		canonical := strings.ToLower(strings.TrimSuffix(name, ".")) + "."
+		i := slices.IndexFunc(a.Zones, func(z Zone) bool { return strings.EqualFold(z.Zone.Name, canonical) })
+		if i < 0 {
+			return nil, fmt.Errorf("zone %s is not in the backup", name)
+		}
+		selected = append(selected, a.Zones[i])
+	}
+	return selected, nil
+}
+
+// RestoreZone returns the zone to create to restore a backed up zone: the
+// zone with its RRsets, without the read-only fields of the server.
+func (z *Zone) RestoreZone() *powerdns.Zone {
+	zone := &powerdns.Zone{
+		Name:       z.Zone.Name,
+		Kind:       z.Zone.Kind,
+		Account:    z.Zone.Account,
+		Masters:    z.Zone.Masters,
+		DNSSEC:     z.Zone.DNSSEC,
+		APIRectify: z.Zone.APIRectify,
+		RRsets:     make([]powerdns.RRset, len(z.Zone.RRsets)),
+	}
+	for i, rrset := range z.Zone.RRsets {
+		rrset.ChangeType = ""
+		zone.RRsets[i] = rrset
+	}
+	return zone
+}
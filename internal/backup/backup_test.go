@@ -0,0 +1,187 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+func testZones() []Zone {
+	return []Zone{
+		{Zone: &powerdns.Zone{Name: "example.org.", Kind: "Native", Account: "zone-manager", Serial: 7,
+			RRsets: []powerdns.RRset{
+				{Name: "example.org.", Type: "NS", TTL: 3600,
+					Records: []powerdns.Record{{Content: "ns1.example.net."}}},
+			}}},
+		{
+			Zone: &powerdns.Zone{Name: "example.com.", Kind: "Master", Account: "zone-manager", DNSSEC: true,
+				RRsets: []powerdns.RRset{
+					{Name: "www.example.com.", Type: "A", TTL: 300, ChangeType: "REPLACE",
+						Records:  []powerdns.Record{{Content: "192.0.2.1"}, {Content: "192.0.2.2", Disabled: true}},
+						Comments: []powerdns.Comment{{Content: "web", Account: "zone-manager"}}},
+				}},
+			Metadata: []powerdns.Metadata{{Kind: "ALSO-NOTIFY", Metadata: []string{"192.0.2.53"}}},
+		},
+	}
+}
+
+func TestArchive_RoundTrip(t *testing.T) {
+	archive := New("zone-manager", "1.4.0", testZones())
+	var buf bytes.Buffer
+	if err := archive.Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, archive) {
+		t.Errorf("Archive changed in round trip:\n%+v\nwant:\n%+v", got, archive)
+	}
+
+	expected := []ZoneInfo{{Name: "example.com.", RRsets: 1, Records: 2}, {Name: "example.org.", RRsets: 1, Records: 1}}
+	for i := range got.Metadata.Zones {
+		expected[i].Hash = Hash(&got.Zones[i])
+	}
+	if !reflect.DeepEqual(got.Metadata.Zones, expected) {
+		t.Errorf("Unexpected zone infos %+v", got.Metadata.Zones)
+	}
+	if got.Metadata.Version != FormatVersion || got.Metadata.Tool != "1.4.0" {
+		t.Errorf("Unexpected metadata %+v", got.Metadata)
+	}
+}
+
+func TestArchive_Select(t *testing.T) {
+	archive := New("zone-manager", "dev", testZones())
+	zones, err := archive.Select([]string{"Example.org"})
+	if err != nil || len(zones) != 1 || zones[0].Zone.Name != "example.org." {
+		t.Errorf("Select() = %v, %v", zones, err)
+	}
+	if _, err := archive.Select([]string{"example.net"}); err == nil ||
+		!strings.Contains(err.Error(), "zone example.net is not in the backup") {
+		t.Errorf("Expected unknown zone error, got %v", err)
+	}
+}
+
+func TestRead_Errors(t *testing.T) {
+	// archive writes a tar.gz with the given files
+	archive := func(files map[string]string) *bytes.Buffer {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		for name, content := range files {
+			header := &tar.Header{Name: name, Mode: 0o600, Size: int64(len(content))}
+			_ = tw.WriteHeader(header)       //nolint:errcheck // test archive
+			_, _ = tw.Write([]byte(content)) //nolint:errcheck // test archive
+		}
+		_ = tw.Close() //nolint:errcheck // test
+		_ = gz.Close() //nolint:errcheck // test
+		return &buf
+	}
+
+	tests := []struct {
+		name    string
+		data    *bytes.Buffer
+		wantErr string
+	}{
+		{"not gzip", bytes.NewBufferString("zones:"), "not a backup archive"},
+		{"no metadata", archive(map[string]string{"zones/a.json": `{"zone":{"name":"a."}}`}), "backup.json is missing"},
+		{"missing zone", archive(map[string]string{"backup.json": `{"version":1,"zones":[{"name":"a."}]}`}),
+			"zone a. is missing"},
+		{"newer version", archive(map[string]string{"backup.json": `{"version":99}`}), "unsupported backup version 99"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Read(tt.data); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestZone_RestoreZone(t *testing.T) {
+	z := testZones()[1]
+	zone := z.RestoreZone()
+	if zone.Name != "example.com." || zone.Kind != "Master" || !zone.DNSSEC || zone.Serial != 0 {
+		t.Errorf("Unexpected zone %+v", zone)
+	}
+	if zone.RRsets[0].ChangeType != "" || len(zone.RRsets[0].Comments) != 1 {
+		t.Errorf("Unexpected RRset %+v", zone.RRsets[0])
+	}
+	if z.Zone.RRsets[0].ChangeType != "REPLACE" {
+		t.Error("RestoreZone modified the backed up zone")
+	}
+}
+
+func TestHash(t *testing.T) {
+	z := testZones()[1]
+	hash := Hash(&z)
+
+	// Order of records and volatile server fields do not matter
+	reordered := testZones()[1]
+	records := reordered.Zone.RRsets[0].Records
+	records[0], records[1] = records[1], records[0]
+	reordered.Zone.Serial = 42
+	if got := Hash(&reordered); got != hash {
+		t.Errorf("Hash changed with record order or serial: %s != %s", got, hash)
+	}
+
+	changed := testZones()[1]
+	changed.Zone.RRsets[0].TTL = 60
+	if Hash(&changed) == hash {
+		t.Error("Hash did not change with the TTL")
+	}
+}
+
+func TestArchive_Incremental(t *testing.T) {
+	dir := t.TempDir()
+	base := New("zone-manager", "dev", testZones())
+	if err := base.Save(filepath.Join(dir, "full.tar.gz")); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	zones := testZones()
+	zones[0].Zone.RRsets[0].TTL = 60 // example.org. changed
+	zones = append(zones, Zone{Zone: &powerdns.Zone{Name: "example.net.", Kind: "Native"}})
+	inc := New("zone-manager", "dev", zones)
+	inc.Metadata.CreatedAt = base.Metadata.CreatedAt.Add(time.Hour)
+	if unchanged := inc.Incremental(base, "full.tar.gz"); unchanged != 1 {
+		t.Errorf("Expected 1 unchanged zone, got %d", unchanged)
+	}
+	if len(inc.Zones) != 2 || inc.Zones[0].Zone.Name != "example.net." || inc.Zones[1].Zone.Name != "example.org." {
+		t.Errorf("Unexpected zones in incremental archive: %v", inc.Zones)
+	}
+	if err := inc.Save(filepath.Join(dir, "inc.tar.gz")); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := Load(filepath.Join(dir, "inc.tar.gz"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	var names []string
+	for _, z := range loaded.Zones {
+		names = append(names, z.Zone.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"example.com.", "example.net.", "example.org."}) {
+		t.Errorf("Unexpected zones %v", names)
+	}
+	if loaded.Zones[2].Zone.RRsets[0].TTL != 60 {
+		t.Error("Expected the changed zone from the incremental archive")
+	}
+
+	// A replaced base is detected
+	if err := New("zone-manager", "dev", nil).Save(filepath.Join(dir, "full.tar.gz")); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if _, err := Load(filepath.Join(dir, "inc.tar.gz")); err == nil {
+		t.Error("Expected an error with a replaced base archive")
+	}
+}
@@ -0,0 +1,176 @@
+// Package compare diffs the zones of two PowerDNS servers, e.g. before and
+// after a migration, or a primary and its disaster recovery copy, and plans
+// the changes that copy zones from one server to the other.
+//
+// RRsets are compared by TTL and records, and optionally comments. The serial
+// of SOA records is ignored: it differs between servers that sign or increase
+// it on their own.
+package compare
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/ownership"
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+// Status is the kind of a difference.
+type Status string
+
+const (
+	// StatusOnlySource is a zone or rrset that only exists on the source server.
+	StatusOnlySource Status = "only in source"
+	// StatusOnlyTarget is a zone or rrset that only exists on the target server.
+	StatusOnlyTarget Status = "only in target"
+	// StatusDifferent is an rrset with different TTLs, records or comments.
+	StatusDifferent Status = "different"
+)
+
+// Difference is a difference of a zone between the servers. Name and Type
+// are empty if the whole zone only exists on one server.
+type Difference struct {
+	// Source and Target are the rrset on each server, nil if it is missing
+	Source *powerdns.RRset
+	Target *powerdns.RRset
+	Zone   string
+	Name   string
+	Type   string
+	Status Status
+}
+
+// Options select the rrsets that are compared.
+type Options struct {
+	// Account limits the comparison to the rrsets managed by the account,
+	// by their ownership comments on either server. Empty compares all rrsets.
+	Account string
+	// Comments also compares the comments of the rrsets
+	Comments bool
+}
+
+// Zone compares a zone on the source and target servers. source or target is
+// nil if the zone does not exist on that server.
+func Zone(name string, source, target *powerdns.Zone, opts Options) []Difference {
+	switch {
+	case source == nil && target == nil:
+		return nil
+	case target == nil:
+		return []Difference{{Zone: name, Status: StatusOnlySource}}
+	case source == nil:
+		return []Difference{{Zone: name, Status: StatusOnlyTarget}}
+	}
+
+	sourceRRsets, targetRRsets := rrsetsByKey(source), rrsetsByKey(target)
+	keys := make([]string, 0, len(sourceRRsets)+len(targetRRsets))
+	for key := range sourceRRsets {
+		keys = append(keys, key)
+	}
+	for key := range targetRRsets {
+		if _, ok := sourceRRsets[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	var diffs []Difference
+	for _, key := range keys {
+		s, t := sourceRRsets[key], targetRRsets[key]
+		// An rrset managed on one server only is compared with the rrset of
+		// the other server, e.g. one that lost its ownership comments
+		if opts.Account != "" && !managedBy(s, opts.Account) && !managedBy(t, opts.Account) {
+			continue
+		}
+		diff := Difference{Zone: name, Source: s, Target: t}
+		switch {
+		case t == nil:
+			diff.Status = StatusOnlySource
+		case s == nil:
+			diff.Status = StatusOnlyTarget
+		case !equal(s, t, opts.Comments):
+			diff.Status = StatusDifferent
+		default:
+			continue
+		}
+		if s != nil {
+			diff.Name, diff.Type = s.Name, s.Type
+		} else {
+			diff.Name, diff.Type = t.Name, t.Type
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs
+}
+
+// rrsetsByKey returns the rrsets of a zone by "name TYPE".
+func rrsetsByKey(zone *powerdns.Zone) map[string]*powerdns.RRset {
+	rrsets := make(map[string]*powerdns.RRset, len(zone.RRsets))
+	for i := range zone.RRsets {
+		rrset := &zone.RRsets[i]
+		rrsets[rrset.Name+" "+rrset.Type] = rrset
+	}
+	return rrsets
+}
+
+// managedBy reports whether the ownership comments of an rrset name the
+// account. A nil rrset is not managed.
+func managedBy(rrset *powerdns.RRset, account string) bool {
+	if rrset == nil {
+		return false
+	}
+	return ownership.OwnedBy(rrset, account)
+}
+
+// equal reports whether two rrsets have the same TTL and records, and with
+// comments the same comments.
+func equal(a, b *powerdns.RRset, comments bool) bool {
+	if a.TTL != b.TTL || !slices.Equal(recordKeys(a), recordKeys(b)) {
+		return false
+	}
+	return !comments || slices.Equal(commentKeys(a), commentKeys(b))
+}
+
+// recordKeys returns the sorted records of an rrset, without the serial of
+// SOA records.
+func recordKeys(rrset *powerdns.RRset) []string {
+	keys := make([]string, len(rrset.Records))
+	for i, record := range rrset.Records {
+		content := record.Content
+		if rrset.Type == "SOA" {
+			if fields := strings.Fields(content); len(fields) == 7 {
+				fields[2] = ""
+				content = strings.Join(fields, " ")
+			}
+		}
+		keys[i] = content + " " + strconv.FormatBool(record.Disabled)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// commentKeys returns the sorted comments of an rrset.
+func commentKeys(rrset *powerdns.RRset) []string {
+	keys := make([]string, len(rrset.Comments))
+	for i, comment := range rrset.Comments {
+		keys[i] = comment.Account + " " + comment.Content
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Records formats the TTL and records of an rrset for display, e.g.
+// "300 192.0.2.1, 192.0.2.2". It returns "" for nil.
+func Records(rrset *powerdns.RRset) string {
+	if rrset == nil {
+		return ""
+	}
+	contents := make([]string, len(rrset.Records))
+	for i, record := range rrset.Records {
+		contents[i] = record.Content
+		if record.Disabled {
+			contents[i] += " (disabled)"
+		}
+	}
+	slices.Sort(contents)
+	return strconv.FormatUint(uint64(rrset.TTL), 10) + " " + strings.Join(contents, ", ")
+}
@@ -0,0 +1,138 @@
+package compare
+
+import (
+	"testing"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+func rrset(name, rrtype string, ttl uint32, owner string, contents ...string) powerdns.RRset {
+	rs := powerdns.RRset{Name: name, Type: rrtype, TTL: ttl}
+	for _, content := range contents {
+		rs.Records = append(rs.Records, powerdns.Record{Content: content})
+	}
+	if owner != "" {
+		rs.Comments = []powerdns.Comment{{Content: "owner=" + owner}}
+	}
+	return rs
+}
+
+func TestZone(t *testing.T) {
+	source := &powerdns.Zone{Name: "example.com.", RRsets: []powerdns.RRset{
+		rrset("example.com.", "SOA", 3600, "", "ns1.example.com. admin.example.com. 2024010101 10800 3600 604800 3600"),
+		rrset("www.example.com.", "A", 300, "team-a", "192.0.2.1", "192.0.2.2"),
+		rrset("api.example.com.", "A", 300, "team-a", "192.0.2.3"),
+		rrset("old.example.com.", "A", 300, "team-a", "192.0.2.4"),
+		rrset("mail.example.com.", "MX", 300, "", "10 mx.example.com."),
+		rrset("txt.example.com.", "TXT", 300, "team-b", `"v=1"`),
+	}}
+	target := &powerdns.Zone{Name: "example.com.", RRsets: []powerdns.RRset{
+		// Only the serial and the comment differ
+		rrset("example.com.", "SOA", 3600, "dns-team",
+			"ns1.example.com. admin.example.com. 2024020202 10800 3600 604800 3600"),
+		// Same records in another order
+		rrset("www.example.com.", "A", 300, "team-a", "192.0.2.2", "192.0.2.1"),
+		rrset("api.example.com.", "A", 60, "team-a", "192.0.2.3"),
+		rrset("new.example.com.", "A", 300, "team-a", "192.0.2.5"),
+		// Lost its ownership comment
+		rrset("mail.example.com.", "MX", 300, "team-a", "20 mx.example.com."),
+		rrset("txt.example.com.", "TXT", 300, "team-b", `"v=2"`),
+	}}
+
+	tests := []struct {
+		name     string
+		opts     Options
+		expected []string
+	}{
+		{
+			name: "all rrsets",
+			expected: []string{
+				"api.example.com. A different",
+				"mail.example.com. MX different",
+				"new.example.com. A only in target",
+				"old.example.com. A only in source",
+				"txt.example.com. TXT different",
+			},
+		},
+		{
+			name: "managed rrsets",
+			opts: Options{Account: "team-a"},
+			expected: []string{
+				"api.example.com. A different",
+				"mail.example.com. MX different",
+				"new.example.com. A only in target",
+				"old.example.com. A only in source",
+			},
+		},
+		{
+			name: "with comments",
+			opts: Options{Comments: true},
+			expected: []string{
+				"api.example.com. A different",
+				"example.com. SOA different",
+				"mail.example.com. MX different",
+				"new.example.com. A only in target",
+				"old.example.com. A only in source",
+				"txt.example.com. TXT different",
+			},
+		},
+		{
+			name:     "no managed rrsets",
+			opts:     Options{Account: "team-c"},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffs := Zone("example.com.", source, target, tt.opts)
+			var got []string
+			for _, d := range diffs {
+				got = append(got, d.Name+" "+d.Type+" "+string(d.Status))
+			}
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected differences %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("Expected difference %q, got %q", tt.expected[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestZone_Missing(t *testing.T) {
+	zone := &powerdns.Zone{Name: "example.com."}
+	tests := []struct {
+		name     string
+		source   *powerdns.Zone
+		target   *powerdns.Zone
+		expected Status
+	}{
+		{"only in source", zone, nil, StatusOnlySource},
+		{"only in target", nil, zone, StatusOnlyTarget},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffs := Zone("example.com.", tt.source, tt.target, Options{})
+			if len(diffs) != 1 || diffs[0].Status != tt.expected || diffs[0].Name != "" {
+				t.Errorf("Expected a zone difference %q, got %+v", tt.expected, diffs)
+			}
+		})
+	}
+	if diffs := Zone("example.com.", nil, nil, Options{}); len(diffs) != 0 {
+		t.Errorf("Expected no differences for a missing zone, got %+v", diffs)
+	}
+}
+
+func TestRecords(t *testing.T) {
+	rs := rrset("www.example.com.", "A", 300, "", "192.0.2.2", "192.0.2.1")
+	rs.Records[0].Disabled = true
+	if got, expected := Records(&rs), "300 192.0.2.1, 192.0.2.2 (disabled)"; got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+	if got := Records(nil); got != "" {
+		t.Errorf("Expected no records for nil, got %q", got)
+	}
+}
@@ -0,0 +1,56 @@
+package compare
+
+import (
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+// Copies returns the rrsets to copy from the source to the target server to
+// resolve the differences of a zone that exists on both: the rrsets that only
+// exist on the source or differ, with their comments. With an account, only
+// the rrsets the account manages on the source are copied. RRsets that only
+// exist on the target are kept.
+func Copies(diffs []Difference, account string) []powerdns.RRset {
+	var rrsets []powerdns.RRset
+	for _, diff := range diffs {
+		if diff.Source == nil || diff.Name == "" {
+			continue
+		}
+		if account != "" && !managedBy(diff.Source, account) {
+			continue
+		}
+		rrsets = append(rrsets, powerdns.RRset{
+			Name:       diff.Source.Name,
+			Type:       diff.Source.Type,
+			TTL:        diff.Source.TTL,
+			ChangeType: "REPLACE",
+			Records:    diff.Source.Records,
+			Comments:   diff.Source.Comments,
+		})
+	}
+	return rrsets
+}
+
+// NewZone returns the zone to create on the target server for a zone that
+// only exists on the source: its kind, account, masters and settings with its
+// rrsets and their comments. With an account, only the rrsets the account
+// manages are copied, plus the SOA and apex NS rrsets every zone needs.
+func NewZone(source *powerdns.Zone, account string) *powerdns.Zone {
+	zone := &powerdns.Zone{
+		Name:       source.Name,
+		Kind:       source.Kind,
+		Account:    source.Account,
+		Masters:    source.Masters,
+		DNSSEC:     source.DNSSEC,
+		APIRectify: source.APIRectify,
+	}
+	for i := range source.RRsets {
+		rrset := source.RRsets[i]
+		apex := rrset.Name == source.Name && (rrset.Type == "SOA" || rrset.Type == "NS")
+		if account != "" && !apex && !managedBy(&rrset, account) {
+			continue
+		}
+		rrset.ChangeType = ""
+		zone.RRsets = append(zone.RRsets, rrset)
+	}
+	return zone
+}
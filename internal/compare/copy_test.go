@@ -0,0 +1,80 @@
+package compare
+
+import (
+	"testing"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+func TestCopies(t *testing.T) {
+	source := &powerdns.Zone{Name: "example.com.", RRsets: []powerdns.RRset{
+		rrset("www.example.com.", "A", 300, "team-a", "192.0.2.1"),
+		rrset("mail.example.com.", "MX", 300, "", "10 mx.example.com."),
+		rrset("api.example.com.", "A", 300, "team-a", "192.0.2.3"),
+	}}
+	target := &powerdns.Zone{Name: "example.com.", RRsets: []powerdns.RRset{
+		rrset("www.example.com.", "A", 60, "team-a", "192.0.2.1"),
+		rrset("mail.example.com.", "MX", 300, "team-a", "20 mx.example.com."),
+		rrset("new.example.com.", "A", 300, "team-a", "192.0.2.5"),
+	}}
+
+	tests := []struct {
+		name     string
+		account  string
+		expected []string
+	}{
+		{"all rrsets", "", []string{"api.example.com. A", "mail.example.com. MX", "www.example.com. A"}},
+		// mail is only managed on the target
+		{"managed rrsets", "team-a", []string{"api.example.com. A", "www.example.com. A"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffs := Zone("example.com.", source, target, Options{Account: tt.account, Comments: true})
+			copies := Copies(diffs, tt.account)
+			if len(copies) != len(tt.expected) {
+				t.Fatalf("Expected copies %v, got %+v", tt.expected, copies)
+			}
+			for i, rs := range copies {
+				if got := rs.Name + " " + rs.Type; got != tt.expected[i] {
+					t.Errorf("Expected copy %q, got %q", tt.expected[i], got)
+				}
+				if rs.ChangeType != "REPLACE" {
+					t.Errorf("Expected REPLACE for %s, got %q", rs.Name, rs.ChangeType)
+				}
+			}
+			if copies[len(copies)-1].TTL != 300 || len(copies[len(copies)-1].Comments) != 1 {
+				t.Errorf("Expected the source TTL and comments, got %+v", copies[len(copies)-1])
+			}
+		})
+	}
+}
+
+func TestNewZone(t *testing.T) {
+	source := &powerdns.Zone{Name: "example.com.", Kind: "Native", Account: "team-a", Serial: 7,
+		RRsets: []powerdns.RRset{
+			rrset("example.com.", "SOA", 3600, "", "ns1.example.com. admin.example.com. 7 10800 3600 604800 3600"),
+			rrset("example.com.", "NS", 3600, "", "ns1.example.com."),
+			rrset("www.example.com.", "A", 300, "team-a", "192.0.2.1"),
+			rrset("mail.example.com.", "MX", 300, "", "10 mx.example.com."),
+		}}
+
+	tests := []struct {
+		name     string
+		account  string
+		expected int
+	}{
+		{"all rrsets", "", 4},
+		{"managed rrsets", "team-a", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zone := NewZone(source, tt.account)
+			if zone.Name != source.Name || zone.Kind != "Native" || zone.Account != "team-a" || zone.Serial != 0 {
+				t.Errorf("Expected the name, kind and account of the source zone, got %+v", zone)
+			}
+			if len(zone.RRsets) != tt.expected {
+				t.Errorf("Expected %d rrsets, got %+v", tt.expected, zone.RRsets)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// caaTags are the property tags of CAA records registered with IANA
+// (RFC 8659, RFC 9495 and others).
+var caaTags = []string{"issue", "issuewild", "iodef", "issuemail", "issuevmc", "contactemail", "contactphone"}
+
+// caaIssuerPattern matches the issuer domain names of issue properties.
+var caaIssuerPattern = regexp.MustCompile(`^` + caaLabel + `(\.` + caaLabel + `)*$`)
+
+// caaLabel matches a label of an issuer domain name.
+const caaLabel = `[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?`
+
+// caaContent returns the content of a CAA record in the structured form:
+//
+//	caa: {flags: 0, tag: issue, value: letsencrypt.org}
+//
+// is `0 issue "letsencrypt.org"`. flags defaults to 0; 128 marks the
+// property critical.
+func caaContent(m map[string]interface{}) (string, error) {
+	opts, err := helperOptions(m, "caa")
+	if err != nil {
+		return "", err
+	}
+	flags, err := intOption(opts, "flags", 0, 255)
+	if err != nil {
+		return "", err
+	}
+	tag, ok := opts["tag"].(string)
+	if !ok || tag == "" {
+		return "", fmt.Errorf("tag is required")
+	}
+	value, ok := opts["value"].(string)
+	if !ok {
+		return "", fmt.Errorf("value must be a string")
+	}
+	if strings.Contains(value, `"`) {
+		return "", fmt.Errorf("value cannot contain quotes")
+	}
+	return fmt.Sprintf("%d %s %s", flags, tag, CharacterString(value)), nil
+}
+
+// checkCAA checks the flags, tag and value of the content of a CAA record,
+// e.g. `0 issue "letsencrypt.org"`: the tag must be registered, issuers
+// must be domain names and iodef values mailto, http or https URLs.
+func checkCAA(content string) error {
+	fields := strings.SplitN(content, " ", 3)
+	if len(fields) != 3 {
+		return fmt.Errorf("CAA content %q must be: flags tag \"value\"", content)
+	}
+	if flags, err := strconv.Atoi(fields[0]); err != nil || flags < 0 || flags > 255 {
+		return fmt.Errorf("CAA flags %q must be an integer between 0 and 255", fields[0])
+	}
+
+	tag := strings.ToLower(fields[1])
+	if !slices.Contains(caaTags, tag) {
+		best, bestDistance := "", 3
+		for _, known := range caaTags {
+			if d := editDistance(tag, known); d < bestDistance {
+				best, bestDistance = known, d
+			}
+		}
+		if best != "" {
+			return fmt.Errorf("unknown CAA tag %q, did you mean %q?", fields[1], best)
+		}
+		return fmt.Errorf("unknown CAA tag %q, must be one of %s", fields[1], strings.Join(caaTags, ", "))
+	}
+
+	value := strings.TrimSuffix(strings.TrimPrefix(fields[2], `"`), `"`)
+	switch tag {
+	case "issue", "issuewild", "issuemail":
+		issuer, _, _ := strings.Cut(value, ";")
+		if issuer = strings.TrimSpace(issuer); issuer != "" && !caaIssuerPattern.MatchString(issuer) {
+			return fmt.Errorf("CAA %s issuer %q must be a domain name, e.g. letsencrypt.org", tag, issuer)
+		}
+	case "iodef":
+		if !strings.HasPrefix(value, "mailto:") && !strings.HasPrefix(value, "http://") &&
+			!strings.HasPrefix(value, "https://") {
+			return fmt.Errorf("CAA iodef %q must be a mailto:, http:// or https:// URL", value)
+		}
+	}
+	return nil
+}
@@ -2,23 +2,239 @@
 package config
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/netip"
 	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Config represents the zone configuration.
 type Config struct {
-	Zones map[string]Zone `yaml:"zones"`
+	// Account is the account name that marks managed zones and rrsets.
+	Account string          `yaml:"account,omitempty"`
+	Zones   map[string]Zone `yaml:"zones"`
+
+	// StrictNames rejects fully qualified rrset names outside of their zone
+	// unless the rrset is marked as external.
+	StrictNames bool `yaml:"strict_names,omitempty"`
+
+	// DanglingTargets is how CNAME, MX, SRV and NS targets that are not
+	// defined in the configured zones are reported: warn (default), error or off.
+	DanglingTargets string `yaml:"dangling_targets,omitempty"`
+
+	// DualStack is how names with A records but no AAAA records, or the other
+	// way around, are reported: off (default), warn or error. Zones can
+	// override it.
+	DualStack string `yaml:"dual_stack,omitempty"`
+
+	// Partitions split a monorepo configuration by team: each partition has
+	// its own configuration files, account and API credentials, and is
+	// applied in isolation from the others (see Partition). A configuration
+	// with partitions has no zones of its own.
+	Partitions map[string]Partition `yaml:"partitions,omitempty"`
+
+	// hash identifies the configuration source, see Hash.
+	hash string
+}
+
+// Modes of Config.DanglingTargets.
+const (
+	DanglingWarn  = "warn"
+	DanglingError = "error"
+	DanglingOff   = "off"
+)
+
+// ValidateDanglingMode returns an error if mode is not a dangling targets mode.
+// An empty mode is the default, DanglingWarn.
+func ValidateDanglingMode(mode string) error {
+	switch mode {
+	case "", DanglingWarn, DanglingError, DanglingOff:
+		return nil
+	}
+	return fmt.Errorf("invalid dangling_targets %q, must be: %s, %s, %s",
+		mode, DanglingWarn, DanglingError, DanglingOff)
+}
+
+// Modes of Config.DualStack and Zone.DualStack.
+const (
+	DualStackOff   = "off"
+	DualStackWarn  = "warn"
+	DualStackError = "error"
+)
+
+// ValidateDualStackMode returns an error if mode is not a dual stack mode.
+// An empty mode is the default, DualStackOff.
+func ValidateDualStackMode(mode string) error {
+	switch mode {
+	case "", DualStackOff, DualStackWarn, DualStackError:
+		return nil
+	}
+	return fmt.Errorf("invalid dual_stack %q, must be: %s, %s, %s",
+		mode, DualStackOff, DualStackWarn, DualStackError)
+}
+
+// States of RRsetInput.State.
+const (
+	StatePresent = "present"
+	StateAbsent  = "absent"
+)
+
+// ChangePolicyCreateOnly is the change policy of RRsetInput.ChangePolicy that
+// creates an rrset if it does not exist and leaves it alone afterwards.
+const ChangePolicyCreateOnly = "create_only"
+
+// KindSlave is the zone kind whose content is transferred from masters.
+const KindSlave = "Slave"
+
+// Targets of Zone.Target.
+const (
+	TargetAuthoritative = "authoritative"
+	TargetRecursor      = "recursor"
+)
+
+// Kinds of recursor zones, see Zone.Target.
+const (
+	KindNative    = "Native"
+	KindForwarded = "Forwarded"
+)
+
+// DefaultTTL is the TTL of rrsets without an explicit ttl.
+const DefaultTTL uint32 = 300
+
+// MaxTTL is the largest valid TTL: RFC 2181 section 8 limits TTLs to 31 bits.
+const MaxTTL uint32 = 1<<31 - 1
+
+// ValidateTTL returns an error naming field, e.g. "ttl" or "--ttl", if ttl is
+// 0 or larger than MaxTTL.
+func ValidateTTL(field string, ttl uint32) error {
+	if ttl == 0 || ttl > MaxTTL {
+		return fmt.Errorf("%s must be between 1 and %d, got %d", field, MaxTTL, ttl)
+	}
+	return nil
 }
 
 // Zone represents a DNS zone configuration.
 type Zone struct {
 	Kind        string       `yaml:"kind,omitempty"`
 	Nameservers []string     `yaml:"nameservers,omitempty"`
+	Masters     []string     `yaml:"masters,omitempty"`
 	RRsets      []RRsetInput `yaml:"rrsets,omitempty"`
+
+	// Shorthand keys for common apex records, expanded into rrsets.
+	// A and AAAA accept the same formats as rrset records.
+	// MX accepts "10 mail.example.com." strings or {10: mail.example.com.} maps.
+	A    interface{} `yaml:"a,omitempty"`
+	AAAA interface{} `yaml:"aaaa,omitempty"`
+	MX   interface{} `yaml:"mx,omitempty"`
+
+	// Delegations of subdomains to other nameservers, expanded into NS
+	// rrsets and glue A/AAAA rrsets.
+	Delegations []Delegation `yaml:"delegations,omitempty"`
+
+	// Zone metadata. Unset fields leave the metadata untouched;
+	// an empty list removes it.
+	AllowAxfrFrom []string `yaml:"allow_axfr_from,omitempty"` // IPs, CIDRs or AUTO-NS
+	AlsoNotify    []string `yaml:"also_notify,omitempty"`     // IPs with optional port
+	TSIGAllowAxfr []string `yaml:"tsig_allow_axfr,omitempty"` // TSIG key names
+	APIRectify    *bool    `yaml:"api_rectify,omitempty"`
+	// Description documents the purpose or owner of the zone; an empty
+	// string removes it.
+	Description *string `yaml:"description,omitempty"`
+
+	// ManagedSubtree limits management of a shared zone to a subdomain,
+	// relative to the zone or fully qualified. RRsets outside of it are
+	// never changed, even if they are marked as managed.
+	ManagedSubtree string `yaml:"managed_subtree,omitempty"`
+
+	// IgnoreTypes are record types that are never deleted from the zone,
+	// even if they are marked as managed, e.g. TXT records written by an
+	// ACME client with the same account. Configured rrsets of these types
+	// are still created and updated.
+	IgnoreTypes []string `yaml:"ignore_types,omitempty"`
+	// IgnoreNames are glob patterns of names whose rrsets are never deleted,
+	// like IgnoreTypes, e.g. "_acme-challenge.*" or "*.dyn". Patterns are
+	// matched against names relative to the zone ("@" for the apex), or
+	// fully qualified names if they end with a dot.
+	IgnoreNames []string `yaml:"ignore_names,omitempty"`
+
+	// ApplyAfter schedules the changes of the zone: they are planned but not
+	// applied before this time. RRsets can set their own apply_after.
+	ApplyAfter *time.Time `yaml:"apply_after,omitempty"`
+
+	// ValidateHook is a shell command that receives the planned changes of
+	// the zone as JSON on stdin and vetoes the apply with a non-zero exit
+	// status, e.g. to check custom invariants of the zone.
+	ValidateHook string `yaml:"validate_hook,omitempty"`
+
+	// DualStack overrides the dual_stack mode of the configuration for the
+	// zone, e.g. off for zones of IPv4-only legacy hosts.
+	DualStack string `yaml:"dual_stack,omitempty"`
+
+	// Labels are free-form provenance labels of the zone and its rrsets,
+	// e.g. team, service or ticket, shown in plans and reports and recorded
+	// in the apply history. They are not sent to PowerDNS.
+	Labels map[string]string `yaml:"labels,omitempty"`
+
+	// Target is the server the zone is managed on: the authoritative server
+	// (default) or the recursor, whose zones are forward zones (kind
+	// Forwarded, the default) or auth-zones without records (kind Native).
+	Target string `yaml:"target,omitempty"`
+	// Forwarders are the servers queries of a forward zone are sent to, IPs
+	// with optional ports
+	Forwarders []string `yaml:"forwarders,omitempty"`
+	// RecursionDesired forwards queries to resolvers instead of
+	// authoritative servers
+	RecursionDesired bool `yaml:"recursion_desired,omitempty"`
+
+	// keys are the positions of the keys of the zone, see Zone.at
+	keys map[string]Location
+	// loc is the position of the zone in the configuration source
+	loc Location
+	// defaultTTL overrides DefaultTTL, see Config.SetDefaultTTL
+	defaultTTL uint32
+	// order is the position of the zone in the declaration order of the
+	// configuration, starting at 1; 0 if the zone was not loaded from YAML
+	order int
+}
+
+// Zone metadata kinds managed by the typed zone fields.
+const (
+	MetadataAllowAxfrFrom = "ALLOW-AXFR-FROM"
+	MetadataAlsoNotify    = "ALSO-NOTIFY"
+	MetadataTSIGAllowAxfr = "TSIG-ALLOW-AXFR"
+	MetadataAPIRectify    = "API-RECTIFY"
+	// MetadataDescription is a custom kind (PowerDNS requires the X- prefix)
+	MetadataDescription = "X-ZONE-DESCRIPTION"
+)
+
+// Delegation delegates a subdomain of a zone (a child zone) to nameservers.
+type Delegation struct {
+	// Name of the child zone, relative to the parent zone or fully qualified.
+	Name string `yaml:"name"`
+	// Nameservers of the child zone, fully qualified.
+	Nameservers []string `yaml:"nameservers"`
+	// Glue maps nameservers inside the child zone, lowercase, to their
+	// addresses.
+	Glue map[string][]string `yaml:"glue,omitempty"`
+	TTL  *uint32             `yaml:"ttl,omitempty"`
+
+	// loc is the position of the delegation in the configuration source
+	loc Location
 }
 
 // RRsetInput represents a resource record set as provided in YAML.
@@ -28,6 +244,38 @@ type RRsetInput struct {
 	Records interface{} `yaml:"records"` // Can be string, []string, []RecordInput, or mixed
 	TTL     *uint32     `yaml:"ttl,omitempty"`
 	Comment string      `yaml:"comment,omitempty"`
+	// ApplyAfter schedules changes of the rrset, overriding the zone's apply_after
+	ApplyAfter *time.Time `yaml:"apply_after,omitempty"`
+	// Migration temporarily lowers the TTL ahead of a content change
+	Migration *Migration `yaml:"migration,omitempty"`
+	// Labels are free-form provenance labels, e.g. team or ticket, merged
+	// with the labels of the zone. They are not sent to PowerDNS.
+	Labels map[string]string `yaml:"labels,omitempty"`
+	// External marks a fully qualified name outside of the zone as intended,
+	// see Config.StrictNames
+	External bool `yaml:"external,omitempty"`
+	// ChangePolicy is empty to keep the rrset as configured, or
+	// ChangePolicyCreateOnly to only create it, e.g. for bootstrap values that
+	// other teams tune by hand afterwards
+	ChangePolicy string `yaml:"change_policy,omitempty"`
+	// State is StatePresent (the default), or StateAbsent to delete the
+	// rrset, whether it is managed or not, e.g. after it was added by hand
+	State string `yaml:"state,omitempty"`
+
+	// loc is the position of the rrset in the configuration source
+	loc Location
+	// shorthand is the zone-level key this rrset was expanded from, if any
+	shorthand string
+	// delegation is true for NS and glue rrsets expanded from delegations
+	delegation bool
+}
+
+// Migration lowers the TTL of an rrset until a planned content change is
+// done, so that resolvers pick up the new content quickly.
+type Migration struct {
+	// Until is when the rrset TTL is restored; unset keeps the TTL lowered.
+	Until *time.Time `yaml:"until,omitempty"`
+	TTL   uint32     `yaml:"ttl"`
 }
 
 // RecordInput represents a single DNS record as provided in YAML.
@@ -35,15 +283,25 @@ type RecordInput struct {
 	Content  string `yaml:"content"`
 	Comment  string `yaml:"comment,omitempty"`
 	Disabled bool   `yaml:"disabled,omitempty"`
+	SetPTR   bool   `yaml:"set_ptr,omitempty"`
 }
 
 // RRset represents a normalized resource record set.
 type RRset struct {
+	// ApplyAfter is the time before which changes are not applied, zero if unscheduled.
+	ApplyAfter time.Time
+	// Labels are the labels of the rrset merged with those of its zone
+	Labels  map[string]string
 	Name    string
 	Type    string
 	Comment string
 	Records []Record
-	TTL     uint32
+	// Location is where the rrset is defined in the configuration
+	Location Location
+	TTL      uint32
+	// CreateOnly creates the rrset if it does not exist, without marking it
+	// as managed, and never changes it afterwards
+	CreateOnly bool
 }
 
 // Record represents a normalized single DNS record.
@@ -51,26 +309,230 @@ type Record struct {
 	Content  string
 	Comment  string
 	Disabled bool
+	// SetPTR creates a PTR record for the address of an A/AAAA record
+	SetPTR bool
+
+	// form is the key of the structured form the content was computed from,
+	// empty for records with content, see recordForms
+	form string
+}
+
+// recordForms are the structured forms of records by key, with the types
+// they are allowed for and how they are named in errors.
+var recordForms = map[string]struct {
+	types []string
+	name  string
+}{
+	"priority": {[]string{"SVCB", "HTTPS"}, "priority, target and params are"},
+	"tlsa":     {[]string{"TLSA"}, "tlsa is"},
+	"sshfp":    {[]string{"SSHFP"}, "sshfp is"},
+	"caa":      {[]string{"CAA"}, "caa is"},
+	"naptr":    {[]string{"NAPTR"}, "naptr is"},
 }
 
 // LoadFromFile loads configuration from a YAML file.
+// The file may contain multiple YAML documents separated by "---", each with
+// its own zones map; they are merged into a single configuration.
 func LoadFromFile(path string) (*Config, error) {
 	data, err := os.ReadFile(path) //nolint:gosec // path is from CLI argument
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
 
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	return parse(data, path)
+}
+
+// LoadFromReader loads configuration from a reader, e.g. a request body.
+// Records cannot read files (tlsa cert_file, sshfp key_file) and sops
+// encrypted configurations are not decrypted, as the configuration may come
+// from someone without access to the local files and keys.
+func LoadFromReader(r io.Reader) (*Config, error) {
+	return LoadFromNamedReader(r, "")
+}
+
+// LoadFromNamedReader loads configuration from a reader, recording name as
+// the source in the locations of zones and rrsets. Relative file paths of
+// records are resolved in the directory of name.
+func LoadFromNamedReader(r io.Reader, name string) (*Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+
+	return parse(data, name)
+}
+
+// parse decodes all YAML documents in data and merges their zones.
+// source is the name of the configuration source, e.g. the file path.
+func parse(data []byte, source string) (*Config, error) {
+	sum := sha256.Sum256(data)
+	cfg := &Config{Zones: make(map[string]Zone), hash: hex.EncodeToString(sum[:8])}
+	// The hash is of the encrypted file, the decrypted one is never stored.
+	// Configurations without a source (LoadFromReader), e.g. posted to
+	// serve-api, are not decrypted: the keys of the host would decrypt
+	// anything posted to it.
+	if isSopsEncrypted(data) {
+		if source == "" {
+			return nil, errors.New("configuration is encrypted with sops, which is only decrypted for files")
+		}
+		var err error
+		if data, err = decryptSops(data, source); err != nil {
+			return nil, err
+		}
+	}
+	origins := make(map[string]zoneOrigin)
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for doc := 1; ; doc++ {
+		var node yaml.Node
+		err := decoder.Decode(&node)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse YAML document %d: %w", doc, err)
+		}
+		// Decoding into the zones map would silently keep one of two zones
+		// with the same name
+		if err := checkDuplicateZones(&node, source, doc); err != nil {
+			return nil, err
+		}
+		// A TTL that does not fit in 32 bits fails decoding with an unmarshal
+		// error that does not name the offending key
+		if err := checkTTLOverflow(&node, source, doc); err != nil {
+			return nil, err
+		}
+		var part Config
+		if err := node.Decode(&part); err != nil {
+			return nil, fmt.Errorf("failed to parse YAML document %d: %w", doc, err)
+		}
+		for name, zone := range part.Zones {
+			zone.locate(source, name)
+			part.Zones[name] = zone
+		}
+		if err := cfg.merge(&part, fmt.Sprintf("document %d", doc), origins); err != nil {
+			return nil, err
+		}
+	}
+	if len(cfg.Partitions) > 0 && (len(cfg.Zones) > 0 || cfg.Account != "") {
+		return nil, errors.New("a configuration with partitions cannot define zones or an account, " +
+			"they are defined by the configurations of the partitions")
+	}
+
+	return cfg, nil
+}
+
+// zoneOrigin is where a zone of a merged configuration is defined: the
+// document or file, and the position in it.
+type zoneOrigin struct {
+	where string
+	loc   Location
+}
+
+// merge merges part, a YAML document or a configuration file described by
+// where, into c. origins maps the canonical lowercase names of the zones of c
+// to where they are defined.
+func (c *Config) merge(part *Config, where string, origins map[string]zoneOrigin) error {
+	if part.Account != "" {
+		if c.Account != "" && c.Account != part.Account {
+			return fmt.Errorf("account %q in %s conflicts with account %q", part.Account, where, c.Account)
+		}
+		c.Account = part.Account
+	}
+	c.StrictNames = c.StrictNames || part.StrictNames
+	if err := ValidateDanglingMode(part.DanglingTargets); err != nil {
+		return fmt.Errorf("%s: %w", where, err)
+	}
+	if part.DanglingTargets != "" {
+		if c.DanglingTargets != "" && c.DanglingTargets != part.DanglingTargets {
+			return fmt.Errorf("dangling_targets %q in %s conflicts with %q",
+				part.DanglingTargets, where, c.DanglingTargets)
+		}
+		c.DanglingTargets = part.DanglingTargets
+	}
+	if err := ValidateDualStackMode(part.DualStack); err != nil {
+		return fmt.Errorf("%s: %w", where, err)
+	}
+	if part.DualStack != "" {
+		if c.DualStack != "" && c.DualStack != part.DualStack {
+			return fmt.Errorf("dual_stack %q in %s conflicts with %q", part.DualStack, where, c.DualStack)
+		}
+		c.DualStack = part.DualStack
+	}
+
+	// The zones of part are a map, their lines restore the declaration order
+	for _, name := range part.zonesByLine() {
+		zone := part.Zones[name]
+		canonical := strings.ToLower(CanonicalZoneName(name))
+		if prev, ok := origins[canonical]; ok {
+			return fmt.Errorf("zone %q in %s is already defined in %s (%s, first at %s)",
+				name, where, prev.where, zone.Location().Position(), prev.loc.Position())
+		}
+		origins[canonical] = zoneOrigin{where: where, loc: zone.Location()}
+		zone.order = len(origins)
+		c.Zones[name] = zone
 	}
+	for _, name := range part.PartitionNames() {
+		partition := part.Partitions[name]
+		if _, ok := c.Partitions[name]; ok {
+			return fmt.Errorf("partition %q in %s is already defined", name, where)
+		}
+		if c.Partitions == nil {
+			c.Partitions = make(map[string]Partition)
+		}
+		c.Partitions[name] = partition
+	}
+	return nil
+}
 
-	return &cfg, nil
+// ZoneNames returns the names of the zones in declaration order: the order
+// of the documents and files of the configuration, then of the zones in
+// them. Zones that were not loaded from YAML are sorted by name.
+func (c *Config) ZoneNames() []string {
+	names := make([]string, 0, len(c.Zones))
+	for name := range c.Zones {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := c.Zones[names[i]].order, c.Zones[names[j]].order
+		if a != b {
+			return a < b
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// zonesByLine returns the names of the zones of a single configuration
+// source in the order of their lines.
+func (c *Config) zonesByLine() []string {
+	names := make([]string, 0, len(c.Zones))
+	for name := range c.Zones {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := c.Zones[names[i]].loc.Line, c.Zones[names[j]].loc.Line
+		if a != b {
+			return a < b
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// Hash returns a short hash of the configuration source, recorded in the
+// ownership comments of the rrsets it writes. It is empty for configurations
+// that were not loaded from YAML.
+func (c *Config) Hash() string {
+	return c.hash
 }
 
 // ValidationError holds all validation errors.
 type ValidationError struct {
 	Errors []string
+	// Locations are the locations of Errors in the configuration, in the
+	// same order; zero if unknown.
+	Locations []Location
 }
 
 func (e *ValidationError) Error() string {
@@ -83,7 +545,18 @@ func (e *ValidationError) Error() string {
 
 // Add appends a formatted error message to the validation errors.
 func (e *ValidationError) Add(format string, args ...interface{}) {
-	e.Errors = append(e.Errors, fmt.Sprintf(format, args...))
+	e.AddAt(Location{}, format, args...)
+}
+
+// AddAt appends a formatted error message for an entry at loc, prefixed with
+// its position in the configuration source.
+func (e *ValidationError) AddAt(loc Location, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if pos := loc.Position(); pos != "" {
+		msg = pos + ": " + msg
+	}
+	e.Errors = append(e.Errors, msg)
+	e.Locations = append(e.Locations, loc)
 }
 
 // HasErrors returns true if there are any validation errors.
@@ -102,7 +575,8 @@ type ZoneState struct {
 func (c *Config) Validate(existingZones map[string]ZoneState) *ValidationError {
 	errs := &ValidationError{}
 
-	for zoneName, zone := range c.Zones {
+	for _, zoneName := range c.ZoneNames() {
+		zone := c.Zones[zoneName]
 		c.validateZone(zoneName, &zone, existingZones, errs)
 	}
 
@@ -118,12 +592,61 @@ func (c *Config) validateZone(
 	existingZones map[string]ZoneState,
 	errs *ValidationError,
 ) {
+	// Zone names are file names of the file providers
+	if strings.ContainsAny(zoneName, `/\`) || strings.Contains(zoneName, "..") {
+		errs.AddAt(zone.loc, "zone %q: invalid zone name, cannot contain path separators or empty labels", zoneName)
+		return
+	}
 	canonicalName := CanonicalZoneName(zoneName)
 	state := existingZones[canonicalName]
 
+	validateMetadata(zoneName, zone, errs)
+	if err := ValidateDualStackMode(zone.DualStack); err != nil {
+		errs.AddAt(zone.at("dual_stack"), "zone %q: %v", zoneName, err)
+	}
+	if err := validateLabels(zone.Labels); err != nil {
+		errs.AddAt(zone.at("labels"), "zone %q: labels: %v", zoneName, err)
+	}
+	switch zone.Target {
+	case "", TargetAuthoritative:
+		if len(zone.Forwarders) > 0 || zone.RecursionDesired {
+			errs.AddAt(zone.at("forwarders"), "zone %q: forwarders and recursion_desired can only be specified "+
+				"for zones with target %s", zoneName, TargetRecursor)
+		}
+	case TargetRecursor:
+		validateRecursorZone(zoneName, zone, errs)
+		return
+	default:
+		errs.AddAt(zone.at("target"), "zone %q: invalid target %q, must be: %s, %s",
+			zoneName, zone.Target, TargetAuthoritative, TargetRecursor)
+		return
+	}
+	if zone.ManagedSubtree != "" {
+		validateManagedSubtree(zoneName, zone, state, errs)
+	}
+	for i, rtype := range zone.IgnoreTypes {
+		if err := validateType(rtype); err != nil {
+			errs.AddAt(zone.at("ignore_types"), "zone %q: ignore_types[%d]: %v", zoneName, i, err)
+		}
+	}
+	for i, pattern := range zone.IgnoreNames {
+		if _, err := path.Match(pattern, ""); pattern == "" || err != nil {
+			errs.AddAt(zone.at("ignore_names"), "zone %q: ignore_names[%d]: invalid pattern %q", zoneName, i, pattern)
+		}
+	}
+
+	if zone.Kind == KindSlave {
+		validateSlaveZone(zoneName, zone, state, errs)
+		return
+	}
+
 	// Nameservers is mandatory only if zone is absent
 	if !state.Exists && len(zone.Nameservers) == 0 {
-		errs.Add("zone %q: nameservers are required when creating a new zone", zoneName)
+		errs.AddAt(zone.Location(), "zone %q: nameservers are required when creating a new zone", zoneName)
+	}
+
+	if len(zone.Masters) > 0 {
+		errs.AddAt(zone.at("masters"), "zone %q: masters can only be specified for %s zones", zoneName, KindSlave)
 	}
 
 	// Note: If zone exists but is not managed, nameservers in config are silently ignored
@@ -132,7 +655,7 @@ func (c *Config) validateZone(
 	// Validate nameservers format
 	for i, ns := range zone.Nameservers {
 		if ns == "" {
-			errs.Add("zone %q: nameserver[%d] cannot be empty", zoneName, i)
+			errs.AddAt(zone.at("nameservers"), "zone %q: nameserver[%d] cannot be empty", zoneName, i)
 		}
 	}
 
@@ -147,105 +670,913 @@ func (c *Config) validateZone(
 			}
 		}
 		if !isValid {
-			errs.Add(
+			errs.AddAt(zone.at("kind"),
 				"zone %q: invalid kind %q, must be one of: Native, Master, Slave, Producer, Consumer",
 				zoneName, zone.Kind,
 			)
 		}
 	}
 
-	// Validate RRsets
-	c.validateRRsets(zoneName, zone.RRsets, errs)
+	c.validateDelegations(zoneName, zone, errs)
+
+	// Validate RRsets, including those expanded from shorthand keys and delegations
+	rrsets, err := zone.ExpandedRRsets()
+	if err != nil {
+		errs.AddAt(zone.at("mx"), "zone %q: %v", zoneName, err)
+		rrsets = zone.RRsets
+	}
+	c.validateRRsets(zoneName, rrsets, errs)
+
+	if zone.ManagedSubtree != "" {
+		parent := strings.ToLower(CanonicalZoneName(zoneName))
+		for i, rrset := range rrsets {
+			if rrset.Name != "" && !zone.InManagedSubtree(QualifyName(rrset.Name, parent), zoneName) {
+				errs.AddAt(rrset.loc, "zone %q, rrset[%d] (%s/%s): outside of managed_subtree %q",
+					zoneName, i, rrset.Name, rrset.Type, zone.ManagedSubtree)
+			}
+		}
+	}
+	c.validateOverlap(zoneName, rrsets, errs)
+}
+
+// validateOverlap rejects rrsets inside a more specific zone of the
+// configuration, e.g. foo.sub in example.com if sub.example.com is
+// configured too: PowerDNS answers for them from that zone, so they are never
+// served. Rrsets at or below delegations are checked by validateDelegations.
+func (c *Config) validateOverlap(zoneName string, rrsets []RRsetInput, errs *ValidationError) {
+	parent := strings.ToLower(CanonicalZoneName(zoneName))
+	var children []string
+	for name, zone := range c.Zones {
+		if zone.IsRecursor() {
+			continue // served by another server
+		}
+		if child := strings.ToLower(CanonicalZoneName(name)); child != parent && IsSubdomain(child, parent) {
+			children = append(children, child)
+		}
+	}
+	if len(children) == 0 {
+		return
+	}
+
+	var delegated []string
+	for _, rrset := range rrsets {
+		name := strings.ToLower(QualifyName(rrset.Name, parent))
+		if strings.EqualFold(rrset.Type, "NS") && name != parent {
+			delegated = append(delegated, name)
+		}
+	}
+
+	for i, rrset := range rrsets {
+		name := strings.ToLower(QualifyName(rrset.Name, parent))
+		if rrset.delegation || slices.ContainsFunc(delegated, func(d string) bool { return IsSubdomain(name, d) }) {
+			continue
+		}
+		best := ""
+		for _, child := range children {
+			if IsSubdomain(name, child) && len(child) > len(best) {
+				best = child
+			}
+		}
+		if best != "" {
+			errs.AddAt(rrset.loc, "zone %q, rrset[%d] (%s/%s): is inside zone %s of the configuration, "+
+				"which serves it instead", zoneName, i, rrset.Name, rrset.Type, best)
+		}
+	}
+}
+
+// validateManagedSubtree checks the managed subtree of a shared zone. Zone
+// level settings affect the whole zone and cannot be combined with it.
+func validateManagedSubtree(zoneName string, zone *Zone, state ZoneState, errs *ValidationError) {
+	loc := zone.at("managed_subtree")
+	parent := strings.ToLower(CanonicalZoneName(zoneName))
+	if subtree := strings.ToLower(QualifyName(zone.ManagedSubtree, parent)); !IsSubdomain(subtree, parent) {
+		errs.AddAt(loc, "zone %q: managed_subtree %s is not inside the zone", zoneName, subtree)
+	}
+	if !state.Exists {
+		errs.AddAt(loc, "zone %q: managed_subtree can only be used for existing zones", zoneName)
+	}
+	if zone.Kind == KindSlave {
+		errs.AddAt(loc, "zone %q: managed_subtree cannot be used for %s zones", zoneName, KindSlave)
+	}
+	if len(zone.Nameservers) > 0 {
+		errs.AddAt(zone.at("nameservers"), "zone %q: nameservers cannot be specified with managed_subtree", zoneName)
+	}
+	if len(zone.Metadata()) > 0 {
+		errs.AddAt(loc, "zone %q: zone metadata cannot be specified with managed_subtree", zoneName)
+	}
+}
+
+// IgnoresType returns true if rrsets of the record type are never deleted,
+// see IgnoreTypes.
+func (z *Zone) IgnoresType(rtype string) bool {
+	for _, ignored := range z.IgnoreTypes {
+		if strings.EqualFold(ignored, rtype) {
+			return true
+		}
+	}
+	return false
+}
+
+// IgnoresName returns true if rrsets of the fully qualified name are never
+// deleted from the zone zoneName, see IgnoreNames.
+func (z *Zone) IgnoresName(name, zoneName string) bool {
+	name, zoneName = strings.ToLower(name), strings.ToLower(zoneName)
+	relative := "@"
+	if name != zoneName {
+		relative = strings.TrimSuffix(name, "."+zoneName)
+	}
+	for _, pattern := range z.IgnoreNames {
+		pattern = strings.ToLower(pattern)
+		subject := relative
+		if strings.HasSuffix(pattern, ".") {
+			subject = name
+		}
+		if ok, err := path.Match(pattern, subject); ok && err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// InManagedSubtree returns true if the fully qualified name may be managed in
+// the zone, which is always the case without managed_subtree.
+func (z *Zone) InManagedSubtree(name, zoneName string) bool {
+	if z.ManagedSubtree == "" {
+		return true
+	}
+	zoneName = strings.ToLower(CanonicalZoneName(zoneName))
+	return IsSubdomain(strings.ToLower(name), strings.ToLower(QualifyName(z.ManagedSubtree, zoneName)))
+}
+
+// validateDelegations checks the delegations of a zone: nameservers must be
+// fully qualified, nameservers inside the child zone need glue, and glue is
+// only allowed for them. If the child zone is configured too, its
+// nameservers must match the delegation. NS rrsets below the apex are
+// delegations as well; records hidden by any delegation are rejected.
+func (c *Config) validateDelegations(zoneName string, zone *Zone, errs *ValidationError) {
+	parent := strings.ToLower(CanonicalZoneName(zoneName))
+	// Child zone -> lowercase nameservers
+	children := make(map[string][]string)
+
+	for i, d := range zone.Delegations {
+		id := fmt.Sprintf("zone %q, delegations[%d] (%s)", zoneName, i, d.Name)
+		if d.Name == "" || d.Name == "@" {
+			errs.AddAt(d.loc, "%s: name of a subdomain is required", id)
+			continue
+		}
+		child := strings.ToLower(QualifyName(d.Name, parent))
+		if !IsSubdomain(child, parent) || child == parent {
+			errs.AddAt(d.loc, "%s: %s is not a subdomain of the zone", id, child)
+			continue
+		}
+		if _, ok := children[child]; ok {
+			errs.AddAt(d.loc, "%s: duplicate delegation", id)
+		}
+		children[child] = lowerAll(d.Nameservers)
+
+		if len(d.Nameservers) == 0 {
+			errs.AddAt(d.loc, "%s: at least one nameserver is required", id)
+		}
+		for j, ns := range d.Nameservers {
+			switch {
+			case ns == "":
+				errs.AddAt(d.loc, "%s: nameserver[%d] cannot be empty", id, j)
+			case !strings.HasSuffix(ns, "."):
+				errs.AddAt(d.loc, "%s: nameserver %q must be fully qualified (end with a dot)", id, ns)
+			case IsSubdomain(strings.ToLower(ns), child) && len(d.Glue[strings.ToLower(ns)]) == 0:
+				errs.AddAt(d.loc, "%s: nameserver %s is inside the delegated zone and requires glue", id, ns)
+			}
+		}
+
+		for host, addresses := range d.Glue {
+			if !containsFold(d.Nameservers, host) {
+				errs.AddAt(d.loc, "%s: glue for %s which is not a nameserver of the delegation", id, host)
+			} else if !IsSubdomain(strings.ToLower(host), child) {
+				errs.AddAt(d.loc, "%s: glue for %s which is outside the delegated zone", id, host)
+			}
+			for _, addr := range addresses {
+				if net.ParseIP(addr) == nil {
+					errs.AddAt(d.loc, "%s: glue for %s: invalid address %q", id, host, addr)
+				}
+			}
+		}
+
+		c.validateChildNameservers(id, d.loc, child, d.Nameservers, errs)
+	}
+
+	for _, rrset := range zone.RRsets {
+		name := strings.ToLower(QualifyName(rrset.Name, parent))
+		if strings.EqualFold(rrset.Type, "NS") && name != parent {
+			records, _ := normalizeRecords(rrset.Records, rrset.dir()) //nolint:errcheck // reported by validateRRsets
+			nameservers := make([]string, len(records))
+			for i, rec := range records {
+				nameservers[i] = strings.ToLower(rec.Content)
+			}
+			children[name] = nameservers
+		}
+	}
+
+	// Records at or below a delegation point are hidden by it, except the
+	// delegation itself, its DS record and glue for its nameservers
+	for i, rrset := range zone.RRsets {
+		name := strings.ToLower(QualifyName(rrset.Name, parent))
+		rtype := strings.ToUpper(rrset.Type)
+		for child, nameservers := range children {
+			switch {
+			case !IsSubdomain(name, child):
+			case name == child && (rtype == "NS" || rtype == "DS"):
+			case (rtype == "A" || rtype == "AAAA") && slices.Contains(nameservers, name):
+			default:
+				errs.AddAt(rrset.loc, "zone %q, rrset[%d] (%s/%s): is inside delegated zone %s",
+					zoneName, i, rrset.Name, rrset.Type, child)
+			}
+		}
+	}
+}
+
+// validateChildNameservers checks that a configured child zone uses the
+// nameservers of its delegation.
+func (c *Config) validateChildNameservers(
+	id string,
+	loc Location,
+	child string,
+	nameservers []string,
+	errs *ValidationError,
+) {
+	for name, zone := range c.Zones {
+		if strings.ToLower(CanonicalZoneName(name)) != child || len(zone.Nameservers) == 0 {
+			continue
+		}
+
+		want := make([]string, len(nameservers))
+		for i, ns := range nameservers {
+			want[i] = strings.ToLower(ns)
+		}
+		got := make([]string, len(zone.Nameservers))
+		for i, ns := range zone.Nameservers {
+			got[i] = strings.ToLower(QualifyName(ns, child))
+		}
+		sort.Strings(want)
+		sort.Strings(got)
+
+		if strings.Join(want, " ") != strings.Join(got, " ") {
+			errs.AddAt(loc, "%s: nameservers %v do not match the nameservers %v of zone %q",
+				id, nameservers, zone.Nameservers, name)
+		}
+	}
+}
+
+// IsSubdomain returns true if name equals zone or is below it.
+// Both names must be lowercase and fully qualified.
+func IsSubdomain(name, zone string) bool {
+	return name == zone || strings.HasSuffix(name, "."+zone)
+}
+
+func lowerAll(list []string) []string {
+	lower := make([]string, len(list))
+	for i, item := range list {
+		lower[i] = strings.ToLower(item)
+	}
+	return lower
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateMetadata checks the values of the typed metadata fields.
+func validateMetadata(zoneName string, zone *Zone, errs *ValidationError) {
+	for i, v := range zone.AllowAxfrFrom {
+		if v == "AUTO-NS" || net.ParseIP(v) != nil {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(v); err != nil {
+			errs.AddAt(zone.at("allow_axfr_from"),
+				"zone %q: allow_axfr_from[%d]: %q is not an IP address, CIDR or AUTO-NS", zoneName, i, v)
+		}
+	}
+
+	for i, v := range zone.AlsoNotify {
+		if net.ParseIP(v) != nil {
+			continue
+		}
+		host, port, err := net.SplitHostPort(v)
+		if err == nil && net.ParseIP(host) != nil && validPort(port) {
+			continue
+		}
+		errs.AddAt(zone.at("also_notify"),
+			"zone %q: also_notify[%d]: %q is not an IP address with optional port", zoneName, i, v)
+	}
+
+	for i, v := range zone.TSIGAllowAxfr {
+		if strings.TrimSpace(v) == "" {
+			errs.AddAt(zone.at("tsig_allow_axfr"),
+				"zone %q: tsig_allow_axfr[%d]: key name cannot be empty", zoneName, i)
+		}
+	}
+
+	if zone.Description != nil && strings.ContainsAny(*zone.Description, "\r\n") {
+		errs.AddAt(zone.at("description"), "zone %q: description must be a single line", zoneName)
+	}
+}
+
+// hasControl reports whether s contains control characters. Comments are
+// stored next to the one-line ownership marker and written as single comment
+// lines to zone files, so they cannot span lines.
+func hasControl(s string) bool {
+	return strings.ContainsFunc(s, unicode.IsControl)
+}
+
+func validPort(port string) bool {
+	p, err := strconv.ParseUint(port, 10, 16)
+	return err == nil && p > 0
+}
+
+// Metadata returns the zone metadata declared with the typed fields, by kind.
+// An empty list means the metadata kind is to be removed.
+func (z *Zone) Metadata() map[string][]string {
+	metadata := make(map[string][]string)
+	if z.AllowAxfrFrom != nil {
+		metadata[MetadataAllowAxfrFrom] = z.AllowAxfrFrom
+	}
+	if z.AlsoNotify != nil {
+		metadata[MetadataAlsoNotify] = z.AlsoNotify
+	}
+	if z.TSIGAllowAxfr != nil {
+		metadata[MetadataTSIGAllowAxfr] = z.TSIGAllowAxfr
+	}
+	if z.APIRectify != nil {
+		value := "0"
+		if *z.APIRectify {
+			value = "1"
+		}
+		metadata[MetadataAPIRectify] = []string{value}
+	}
+	if z.Description != nil {
+		metadata[MetadataDescription] = []string{}
+		if *z.Description != "" {
+			metadata[MetadataDescription] = []string{*z.Description}
+		}
+	}
+	return metadata
+}
+
+// IsRecursor returns true if the zone is managed on the recursor.
+func (z *Zone) IsRecursor() bool {
+	return z.Target == TargetRecursor
+}
+
+// RecursorKind returns the kind of a recursor zone, KindForwarded by default.
+func (z *Zone) RecursorKind() string {
+	if z.Kind == "" {
+		return KindForwarded
+	}
+	return z.Kind
+}
+
+// validateRecursorZone checks a zone of the recursor: a forward zone, or an
+// auth-zone whose records cannot be managed through the recursor API.
+func validateRecursorZone(zoneName string, zone *Zone, errs *ValidationError) {
+	switch zone.RecursorKind() {
+	case KindForwarded:
+		if len(zone.Forwarders) == 0 {
+			errs.AddAt(zone.Location(), "zone %q: forwarders are required for %s zones", zoneName, KindForwarded)
+		}
+		for i, forwarder := range zone.Forwarders {
+			if !validForwarder(forwarder) {
+				errs.AddAt(zone.at("forwarders"), "zone %q: forwarders[%d]: invalid address %q, must be an IP "+
+					"with an optional port", zoneName, i, forwarder)
+			}
+		}
+	case KindNative:
+		if len(zone.Forwarders) > 0 || zone.RecursionDesired {
+			errs.AddAt(zone.at("forwarders"), "zone %q: forwarders and recursion_desired can only be specified "+
+				"for %s zones", zoneName, KindForwarded)
+		}
+	default:
+		errs.AddAt(zone.at("kind"), "zone %q: invalid kind %q for target %s, must be: %s, %s",
+			zoneName, zone.Kind, TargetRecursor, KindForwarded, KindNative)
+	}
+	if len(zone.Nameservers) > 0 || len(zone.Masters) > 0 || len(zone.RRsets) > 0 || zone.hasShorthand() ||
+		len(zone.Delegations) > 0 || len(zone.Metadata()) > 0 || zone.ManagedSubtree != "" || zone.ApplyAfter != nil {
+		errs.AddAt(zone.Location(), "zone %q: zones with target %s only support kind, forwarders and "+
+			"recursion_desired", zoneName, TargetRecursor)
+	}
+}
+
+// validForwarder returns true if forwarder is an IP with an optional port,
+// e.g. 192.0.2.1, 192.0.2.1:5300 or [2001:db8::1]:5300.
+func validForwarder(forwarder string) bool {
+	if _, err := netip.ParseAddr(forwarder); err == nil {
+		return true
+	}
+	_, err := netip.ParseAddrPort(forwarder)
+	return err == nil
+}
+
+// validateSlaveZone checks a Slave zone, whose records come from zone transfers.
+func validateSlaveZone(zoneName string, zone *Zone, state ZoneState, errs *ValidationError) {
+	if !state.Exists && len(zone.Masters) == 0 {
+		errs.AddAt(zone.Location(), "zone %q: masters are required when creating a %s zone", zoneName, KindSlave)
+	}
+
+	for i, master := range zone.Masters {
+		if master == "" {
+			errs.AddAt(zone.at("masters"), "zone %q: master[%d] cannot be empty", zoneName, i)
+		}
+	}
+
+	if len(zone.Nameservers) > 0 {
+		errs.AddAt(zone.at("nameservers"), "zone %q: nameservers cannot be specified for %s zones", zoneName, KindSlave)
+	}
+
+	if len(zone.RRsets) > 0 || zone.hasShorthand() || len(zone.Delegations) > 0 {
+		errs.AddAt(zone.Location(),
+			"zone %q: rrsets cannot be specified for %s zones (records come from zone transfers)",
+			zoneName, KindSlave)
+	}
 }
 
 func (c *Config) validateRRsets(zoneName string, rrsets []RRsetInput, errs *ValidationError) {
+	parent := strings.ToLower(CanonicalZoneName(zoneName))
 	seenRRsets := make(map[string]bool)
 
 	for i, rrset := range rrsets {
-		rrsetID := fmt.Sprintf("zone %q, rrset[%d] (%s/%s)", zoneName, i, rrset.Name, rrset.Type)
+		rrsetID := rrset.id(zoneName, i)
 
-		// NS records must be managed via nameservers property
-		if strings.EqualFold(rrset.Type, "NS") {
-			errs.Add("%s: NS records must be managed via 'nameservers' property, not in rrsets", rrsetID)
+		// Apex NS records must be managed via nameservers property
+		isNS := strings.EqualFold(rrset.Type, "NS")
+		if isNS && !rrset.delegation && strings.ToLower(QualifyName(rrset.Name, parent)) == parent {
+			errs.AddAt(rrset.loc,
+				"%s: apex NS records must be managed via 'nameservers' property, not in rrsets", rrsetID)
 			continue
 		}
 
 		// SOA records cannot be managed
 		if strings.EqualFold(rrset.Type, "SOA") {
-			errs.Add("%s: SOA records are managed by PowerDNS and cannot be specified", rrsetID)
+			errs.AddAt(rrset.loc, "%s: SOA records are managed by PowerDNS and cannot be specified", rrsetID)
 			continue
 		}
 
 		if rrset.Name == "" {
-			errs.Add("%s: name is required", rrsetID)
+			errs.AddAt(rrset.loc, "%s: name is required", rrsetID)
 		}
 
 		if rrset.Type == "" {
-			errs.Add("%s: type is required", rrsetID)
+			errs.AddAt(rrset.loc, "%s: type is required", rrsetID)
+		} else if err := validateType(rrset.Type); err != nil {
+			errs.AddAt(rrset.loc, "%s: %v", rrsetID, err)
+			continue
+		}
+
+		c.validateName(rrsetID, &rrset, parent, errs)
+
+		if rrset.TTL != nil {
+			if err := ValidateTTL("ttl", *rrset.TTL); err != nil {
+				errs.AddAt(rrset.loc, "%s: %v", rrsetID, err)
+			}
+		}
+		if rrset.Migration != nil {
+			if err := ValidateTTL("migration ttl", rrset.Migration.TTL); err != nil {
+				errs.AddAt(rrset.loc, "%s: %v", rrsetID, err)
+			}
+		}
+		if rrset.ChangePolicy != "" && rrset.ChangePolicy != ChangePolicyCreateOnly {
+			errs.AddAt(rrset.loc, "%s: invalid change_policy %q, must be: %s", rrsetID, rrset.ChangePolicy,
+				ChangePolicyCreateOnly)
+		}
+		if rrset.State != "" && rrset.State != StatePresent && rrset.State != StateAbsent {
+			errs.AddAt(rrset.loc, "%s: invalid state %q, must be: %s, %s", rrsetID, rrset.State,
+				StatePresent, StateAbsent)
+		}
+		if err := validateLabels(rrset.Labels); err != nil {
+			errs.AddAt(rrset.loc, "%s: labels: %v", rrsetID, err)
+		}
+		if hasControl(rrset.Comment) {
+			errs.AddAt(rrset.loc, "%s: comment cannot contain line breaks or other control characters", rrsetID)
 		}
 
 		// Check for duplicate RRsets
 		key := fmt.Sprintf("%s/%s", strings.ToLower(rrset.Name), strings.ToUpper(rrset.Type))
 		if seenRRsets[key] {
-			errs.Add("%s: duplicate RRset definition", rrsetID)
+			errs.AddAt(rrset.loc, "%s: duplicate RRset definition", rrsetID)
 		}
 		seenRRsets[key] = true
 
+		if rrset.State == StateAbsent {
+			if rrset.Records != nil {
+				errs.AddAt(rrset.loc, "%s: records cannot be set for an absent rrset", rrsetID)
+			}
+			continue
+		}
+
 		// Validate records
-		records, err := normalizeRecords(rrset.Records)
+		records, err := normalizeRecords(rrset.Records, rrset.dir())
 		if err != nil {
-			errs.Add("%s: %v", rrsetID, err)
+			errs.AddAt(rrset.loc, "%s: %v", rrsetID, err)
 			continue
 		}
 
 		if len(records) == 0 {
-			errs.Add("%s: at least one record is required", rrsetID)
+			errs.AddAt(rrset.loc, "%s: at least one record is required", rrsetID)
 		}
 
 		for j, rec := range records {
-			if rec.Content == "" {
-				errs.Add("%s, record[%d]: content cannot be empty", rrsetID, j)
+			switch {
+			case rec.Content == "":
+				errs.AddAt(rrset.loc, "%s, record[%d]: content cannot be empty", rrsetID, j)
+			case isNS && !strings.HasSuffix(rec.Content, "."):
+				errs.AddAt(rrset.loc, "%s, record[%d]: nameserver %q must be fully qualified (end with a dot)",
+					rrsetID, j, rec.Content)
+			case rec.SetPTR && !strings.EqualFold(rrset.Type, "A") && !strings.EqualFold(rrset.Type, "AAAA"):
+				errs.AddAt(rrset.loc, "%s, record[%d]: set_ptr is only supported for A and AAAA records", rrsetID, j)
+			case rec.form != "" && !slices.Contains(recordForms[rec.form].types, strings.ToUpper(rrset.Type)):
+				form := recordForms[rec.form]
+				errs.AddAt(rrset.loc, "%s, record[%d]: %s only supported for %s records",
+					rrsetID, j, form.name, strings.Join(form.types, " and "))
+			case strings.EqualFold(rrset.Type, "CAA"):
+				if err := checkCAA(rec.Content); err != nil {
+					errs.AddAt(rrset.loc, "%s, record[%d]: %v", rrsetID, j, err)
+				}
+			}
+			if hasControl(rec.Comment) {
+				errs.AddAt(rrset.loc, "%s, record[%d]: comment cannot contain line breaks or other control characters",
+					rrsetID, j)
+			}
+		}
+	}
+}
+
+// recordTypes are the record types supported by PowerDNS. Types can also be
+// written in the generic RFC 3597 form, e.g. TYPE65534.
+var recordTypes = []string{
+	"A", "AAAA", "AFSDB", "ALIAS", "APL", "CAA", "CDNSKEY", "CDS", "CERT", "CNAME", "CSYNC", "DHCID",
+	"DNAME", "DNSKEY", "DS", "EUI48", "EUI64", "HINFO", "HTTPS", "IPSECKEY", "KEY", "KX", "L32", "L64",
+	"LOC", "LP", "LUA", "MINFO", "MR", "MX", "NAPTR", "NID", "NS", "NSEC", "NSEC3", "NSEC3PARAM",
+	"OPENPGPKEY", "PTR", "RKEY", "RP", "RRSIG", "SMIMEA", "SOA", "SPF", "SRV", "SSHFP", "SVCB", "TLSA",
+	"TXT", "URI", "ZONEMD",
+}
+
+// id identifies the rrset in validation errors: its index in the rrsets of
+// the zone, or the shorthand key or delegation it was expanded from.
+func (r *RRsetInput) id(zoneName string, index int) string {
+	if r.shorthand != "" {
+		return fmt.Sprintf("zone %q, %s (%s/%s)", zoneName, r.shorthand, r.Name, r.Type)
+	}
+	return fmt.Sprintf("zone %q, rrset[%d] (%s/%s)", zoneName, index, r.Name, r.Type)
+}
+
+// CheckRecordTypes checks the record types of the configured rrsets with
+// supported, e.g. against the types the PowerDNS server supports, and
+// returns all errors at once. Recursor zones, which have no rrsets, and
+// rrsets with state absent are not checked.
+func (c *Config) CheckRecordTypes(supported func(rtype string) error) *ValidationError {
+	errs := &ValidationError{}
+	for _, zoneName := range c.ZoneNames() {
+		zone := c.Zones[zoneName]
+		if zone.IsRecursor() {
+			continue
+		}
+		rrsets, err := zone.ExpandedRRsets()
+		if err != nil {
+			continue // reported by Validate
+		}
+		for i := range rrsets {
+			rrset := &rrsets[i]
+			if rrset.State == StateAbsent {
+				continue
+			}
+			if err := supported(rrset.Type); err != nil {
+				errs.AddAt(rrset.loc, "%s: %v", rrset.id(zoneName, i), err)
+			}
+		}
+	}
+	if errs.HasErrors() {
+		return errs
+	}
+	return nil
+}
+
+// validateType checks that a record type is supported, case-insensitively,
+// and suggests the closest known type for typos.
+func validateType(rtype string) error {
+	upper := strings.ToUpper(rtype)
+	if slices.Contains(recordTypes, upper) {
+		return nil
+	}
+	if number, ok := strings.CutPrefix(upper, "TYPE"); ok {
+		if _, err := strconv.ParseUint(number, 10, 16); err == nil {
+			return nil
+		}
+	}
+
+	best, bestDistance := "", 3
+	for _, known := range recordTypes {
+		if d := editDistance(upper, known); d < bestDistance {
+			best, bestDistance = known, d
+		}
+	}
+	if best != "" {
+		return fmt.Errorf("unknown record type %q, did you mean %q?", rtype, best)
+	}
+	return fmt.Errorf("unknown record type %q", rtype)
+}
+
+// editDistance returns the Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
 			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
 		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// validateName checks fully qualified rrset names against the enclosing zone
+// in strict mode, where names outside of it must be marked as external.
+func (c *Config) validateName(rrsetID string, rrset *RRsetInput, parent string, errs *ValidationError) {
+	absolute := strings.HasSuffix(rrset.Name, ".")
+	if rrset.External && !absolute {
+		errs.AddAt(rrset.loc, "%s: external names must be fully qualified (end with a dot)", rrsetID)
+		return
+	}
+	if c.StrictNames && absolute && !rrset.External && !IsSubdomain(strings.ToLower(rrset.Name), parent) {
+		errs.AddAt(rrset.loc, "%s: name %s is outside of the zone (set external: true if this is intended)",
+			rrsetID, rrset.Name)
 	}
 }
 
+// SetDefaultTTL sets the TTL of rrsets without an explicit ttl in all zones.
+func (c *Config) SetDefaultTTL(ttl uint32) {
+	for name, zone := range c.Zones {
+		zone.defaultTTL = ttl
+		c.Zones[name] = zone
+	}
+}
+
+// DefaultTTL returns the TTL of rrsets without an explicit ttl.
+func (z *Zone) DefaultTTL() uint32 {
+	if z.defaultTTL != 0 {
+		return z.defaultTTL
+	}
+	return DefaultTTL
+}
+
+// defaultTTLSource returns where DefaultTTL comes from, TTLFromSettings or
+// TTLFromDefault.
+func (z *Zone) defaultTTLSource() string {
+	if z.defaultTTL != 0 {
+		return TTLFromSettings
+	}
+	return TTLFromDefault
+}
+
 // NormalizeZone applies defaults and normalizes the zone configuration.
 func (z *Zone) NormalizeZone() {
 	if z.Kind == "" {
-		z.Kind = "Native"
+		z.Kind = KindNative
+		if z.IsRecursor() {
+			z.Kind = KindForwarded
+		}
+	}
+}
+
+// ApplyAfterTime returns the time before which changes of the zone are not
+// applied, zero if the zone is not scheduled.
+func (z *Zone) ApplyAfterTime() time.Time {
+	if z.ApplyAfter == nil {
+		return time.Time{}
+	}
+	return *z.ApplyAfter
+}
+
+// hasShorthand returns true if any apex shorthand key is set.
+func (z *Zone) hasShorthand() bool {
+	return z.A != nil || z.AAAA != nil || z.MX != nil
+}
+
+// ExpandedRRsets returns the configured rrsets followed by the apex rrsets
+// declared with the a, aaaa and mx shorthand keys and the NS and glue rrsets
+// of delegations.
+func (z *Zone) ExpandedRRsets() ([]RRsetInput, error) {
+	if !z.hasShorthand() && len(z.Delegations) == 0 {
+		return z.RRsets, nil
+	}
+
+	rrsets := make([]RRsetInput, len(z.RRsets), len(z.RRsets)+3+len(z.Delegations))
+	copy(rrsets, z.RRsets)
+
+	if z.A != nil {
+		rrsets = append(rrsets, RRsetInput{Name: "@", Type: "A", Records: z.A, shorthand: "a", loc: z.at("a")})
+	}
+	if z.AAAA != nil {
+		rrsets = append(rrsets, RRsetInput{
+			Name: "@", Type: "AAAA", Records: z.AAAA, shorthand: "aaaa", loc: z.at("aaaa"),
+		})
+	}
+	if z.MX != nil {
+		records, err := expandMX(z.MX)
+		if err != nil {
+			return nil, fmt.Errorf("mx: %w", err)
+		}
+		rrsets = append(rrsets, RRsetInput{Name: "@", Type: "MX", Records: records, shorthand: "mx", loc: z.at("mx")})
+	}
+
+	for i := range z.Delegations {
+		rrsets = append(rrsets, z.Delegations[i].rrsets(i)...)
 	}
+
+	return rrsets, nil
+}
+
+// rrsets returns the NS rrset of the delegation followed by its glue
+// A/AAAA rrsets, sorted by nameserver.
+func (d *Delegation) rrsets(index int) []RRsetInput {
+	id := fmt.Sprintf("delegations[%d]", index)
+	nameservers := make([]interface{}, len(d.Nameservers))
+	for i, ns := range d.Nameservers {
+		nameservers[i] = ns
+	}
+	rrsets := []RRsetInput{{
+		Name: d.Name, Type: "NS", Records: nameservers, TTL: d.TTL, shorthand: id, delegation: true, loc: d.loc,
+	}}
+
+	hosts := make([]string, 0, len(d.Glue))
+	for host := range d.Glue {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	for _, host := range hosts {
+		var a, aaaa []interface{}
+		for _, addr := range d.Glue[host] {
+			if ip := net.ParseIP(addr); ip != nil && ip.To4() == nil {
+				aaaa = append(aaaa, addr)
+			} else {
+				a = append(a, addr)
+			}
+		}
+		glueID := fmt.Sprintf("%s glue", id)
+		if a != nil {
+			rrsets = append(rrsets, RRsetInput{
+				Name: host, Type: "A", Records: a, TTL: d.TTL, shorthand: glueID, delegation: true, loc: d.loc,
+			})
+		}
+		if aaaa != nil {
+			rrsets = append(rrsets, RRsetInput{
+				Name: host, Type: "AAAA", Records: aaaa, TTL: d.TTL, shorthand: glueID, delegation: true, loc: d.loc,
+			})
+		}
+	}
+	return rrsets
+}
+
+// expandMX converts the mx shorthand into a list of MX record contents.
+func expandMX(input interface{}) ([]interface{}, error) {
+	items, ok := input.([]interface{})
+	if !ok {
+		items = []interface{}{input}
+	}
+
+	records := make([]interface{}, 0, len(items))
+	for i, item := range items {
+		if m, ok := item.(map[string]interface{}); ok {
+			// Quoted priorities are decoded as string keys
+			converted := make(map[interface{}]interface{}, len(m))
+			for priority, host := range m {
+				converted[priority] = host
+			}
+			item = converted
+		}
+		switch v := item.(type) {
+		case string:
+			records = append(records, v)
+		case map[interface{}]interface{}:
+			if len(v) != 1 {
+				return nil, fmt.Errorf("record[%d]: must have exactly one priority: host entry", i)
+			}
+			for priority, host := range v {
+				p, ok := intValue(priority, 65535)
+				if !ok {
+					return nil, fmt.Errorf("record[%d]: priority must be an integer between 0 and 65535", i)
+				}
+				h, ok := host.(string)
+				if !ok || h == "" {
+					return nil, fmt.Errorf("record[%d]: host must be a non-empty string", i)
+				}
+				records = append(records, fmt.Sprintf("%d %s", p, h))
+			}
+		default:
+			return nil, fmt.Errorf("record[%d]: unsupported type %T", i, item)
+		}
+	}
+	return records, nil
 }
 
 // NormalizeRRsets normalizes RRsets by applying defaults and parsing records.
 func (z *Zone) NormalizeRRsets() ([]RRset, error) {
-	var rrsets []RRset
+	inputs, err := z.ExpandedRRsets()
+	if err != nil {
+		return nil, err
+	}
 
-	for _, input := range z.RRsets {
-		records, err := normalizeRecords(input.Records)
+	rrsets := make([]RRset, 0, len(inputs))
+	for _, input := range inputs {
+		if input.State == StateAbsent {
+			continue
+		}
+		rrset, _, err := z.normalizeRRset(&input)
 		if err != nil {
-			return nil, fmt.Errorf("rrset %s/%s: %w", input.Name, input.Type, err)
+			return nil, err
 		}
+		rrsets = append(rrsets, rrset)
+	}
+
+	return rrsets, nil
+}
+
+// normalizeRRset normalizes a present rrset and returns where its TTL comes
+// from, one of the TTLFrom constants.
+func (z *Zone) normalizeRRset(input *RRsetInput) (RRset, string, error) {
+	records, err := normalizeRecords(input.Records, input.dir())
+	if err != nil {
+		return RRset{}, "", fmt.Errorf("rrset %s/%s: %w", input.Name, input.Type, err)
+	}
+
+	ttl, ttlSource := z.DefaultTTL(), z.defaultTTLSource()
+	if input.TTL != nil {
+		ttl, ttlSource = *input.TTL, TTLFromRRset
+	}
+	if m := input.Migration; m != nil && (m.Until == nil || time.Now().Before(*m.Until)) {
+		ttl, ttlSource = m.TTL, TTLFromMigration
+	}
+
+	rrset := z.absentRRset(input)
+	rrset.TTL = ttl
+	rrset.Records = records
+	rrset.Comment = input.Comment
+	rrset.CreateOnly = input.ChangePolicy == ChangePolicyCreateOnly
+	return rrset, ttlSource, nil
+}
 
-		ttl := uint32(300) // Default TTL
-		if input.TTL != nil {
-			ttl = *input.TTL
+// AbsentRRsets returns the rrsets declared with state absent, which are
+// deleted. Their records are empty.
+func (z *Zone) AbsentRRsets() []RRset {
+	var rrsets []RRset
+	for i := range z.RRsets {
+		if z.RRsets[i].State == StateAbsent {
+			rrsets = append(rrsets, z.absentRRset(&z.RRsets[i]))
 		}
+	}
+	return rrsets
+}
 
-		rrsets = append(rrsets, RRset{
-			Name:    input.Name,
-			Type:    strings.ToUpper(input.Type),
-			TTL:     ttl,
-			Records: records,
-			Comment: input.Comment,
-		})
+// absentRRset returns the rrset without TTL and records, as it is deleted.
+func (z *Zone) absentRRset(input *RRsetInput) RRset {
+	applyAfter := z.ApplyAfterTime()
+	if input.ApplyAfter != nil {
+		applyAfter = *input.ApplyAfter
 	}
+	return RRset{
+		Name:       input.Name,
+		Type:       strings.ToUpper(input.Type),
+		ApplyAfter: applyAfter,
+		Labels:     MergeLabels(z.Labels, input.Labels),
+		Location:   input.loc,
+	}
+}
 
-	return rrsets, nil
+// dir returns the directory relative file paths of the records of the rrset
+// are resolved in, e.g. of tlsa certificates: the directory of the
+// configuration file, or the working directory for other named sources such
+// as stdin. It is empty for configurations without a source (LoadFromReader),
+// e.g. posted to serve-api, which cannot read files.
+func (r *RRsetInput) dir() string {
+	if r.loc.File == "" {
+		return ""
+	}
+	return filepath.Dir(r.loc.File)
 }
 
 // normalizeRecords converts various record input formats to normalized []Record.
-func normalizeRecords(input interface{}) ([]Record, error) {
+// Relative file paths of records are resolved in dir.
+func normalizeRecords(input interface{}, dir string) ([]Record, error) {
 	if input == nil {
 		return nil, nil
 	}
@@ -257,11 +1588,11 @@ func normalizeRecords(input interface{}) ([]Record, error) {
 
 	case []interface{}:
 		// List of mixed values
-		return normalizeRecordsList(v)
+		return normalizeRecordsList(v, dir)
 
 	case map[string]interface{}:
 		// Single object
-		rec, err := parseRecordMap(v)
+		rec, err := parseRecordMap(v, dir)
 		if err != nil {
 			return nil, err
 		}
@@ -272,14 +1603,14 @@ func normalizeRecords(input interface{}) ([]Record, error) {
 	}
 }
 
-func normalizeRecordsList(items []interface{}) ([]Record, error) {
+func normalizeRecordsList(items []interface{}, dir string) ([]Record, error) {
 	var records []Record
 	for i, item := range items {
 		switch r := item.(type) {
 		case string:
 			records = append(records, Record{Content: r, Disabled: false})
 		case map[string]interface{}:
-			rec, err := parseRecordMap(r)
+			rec, err := parseRecordMap(r, dir)
 			if err != nil {
 				return nil, fmt.Errorf("record[%d]: %w", i, err)
 			}
@@ -291,10 +1622,39 @@ func normalizeRecordsList(items []interface{}) ([]Record, error) {
 	return records, nil
 }
 
-func parseRecordMap(m map[string]interface{}) (Record, error) {
+func parseRecordMap(m map[string]interface{}, dir string) (Record, error) {
 	rec := Record{Disabled: false} // Default disabled to false
 
-	if content, ok := m["content"]; ok {
+	var err error
+	switch {
+	case isSVCBRecord(m):
+		rec.Content, err = svcbContent(m)
+		rec.form = "priority"
+	case m["tlsa"] != nil:
+		if rec.Content, err = tlsaContent(m, dir); err != nil {
+			err = fmt.Errorf("tlsa: %w", err)
+		}
+		rec.form = "tlsa"
+	case m["sshfp"] != nil:
+		if rec.Content, err = sshfpContent(m, dir); err != nil {
+			err = fmt.Errorf("sshfp: %w", err)
+		}
+		rec.form = "sshfp"
+	case m["caa"] != nil:
+		if rec.Content, err = caaContent(m); err != nil {
+			err = fmt.Errorf("caa: %w", err)
+		}
+		rec.form = "caa"
+	case m["naptr"] != nil:
+		if rec.Content, err = naptrContent(m); err != nil {
+			err = fmt.Errorf("naptr: %w", err)
+		}
+		rec.form = "naptr"
+	}
+	if err != nil {
+		return Record{}, err
+	}
+	if content, ok := m["content"]; ok && rec.form == "" {
 		if s, ok := content.(string); ok {
 			rec.Content = s
 		} else {
@@ -318,9 +1678,40 @@ func parseRecordMap(m map[string]interface{}) (Record, error) {
 		}
 	}
 
+	if setPTR, ok := m["set_ptr"]; ok {
+		if b, ok := setPTR.(bool); ok {
+			rec.SetPTR = b
+		} else {
+			return Record{}, fmt.Errorf("set_ptr must be a boolean")
+		}
+	}
+
 	return rec, nil
 }
 
+// QualifyName returns the fully qualified name of an rrset name in a zone,
+// "@" (or an empty name) being the zone itself. zoneID must be canonical;
+// the case of name is kept.
+func QualifyName(name, zoneID string) string {
+	switch {
+	case name == "@" || name == "":
+		return zoneID
+	case strings.HasSuffix(name, "."):
+		return name
+	default:
+		return name + "." + zoneID
+	}
+}
+
+// RecordContent returns the content of a record as it is sent to PowerDNS:
+// TXT contents are quoted as a character-string unless they already are.
+func RecordContent(rtype, content string) string {
+	if strings.EqualFold(rtype, "TXT") && !strings.HasPrefix(content, "\"") {
+		return CharacterString(content)
+	}
+	return content
+}
+
 // CanonicalZoneName ensures zone name ends with a dot.
 func CanonicalZoneName(name string) string {
 	if !strings.HasSuffix(name, ".") {
@@ -1,8 +1,25 @@
 package config
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestValidate_NameserversRequired(t *testing.T) {
@@ -215,6 +232,69 @@ func TestValidate_InvalidKind(t *testing.T) {
 	}
 }
 
+func TestValidate_ZoneName(t *testing.T) {
+	for _, name := range []string{"../../etc/x", "a/b.com", `a\b.com`, "example..com"} {
+		cfg := &Config{Zones: map[string]Zone{name: {Nameservers: []string{"ns1.example.com."}}}}
+		err := cfg.Validate(nil)
+		if err == nil || !strings.Contains(err.Error(), "invalid zone name") {
+			t.Errorf("Expected invalid zone name error for %q, got %v", name, err)
+		}
+	}
+}
+
+func TestValidate_SlaveZone(t *testing.T) {
+	tests := []struct {
+		name        string
+		zone        Zone
+		expectedErr string
+	}{
+		{
+			name: "valid",
+			zone: Zone{Kind: "Slave", Masters: []string{"192.0.2.1"}},
+		},
+		{
+			name:        "masters required",
+			zone:        Zone{Kind: "Slave"},
+			expectedErr: "masters are required",
+		},
+		{
+			name:        "nameservers not allowed",
+			zone:        Zone{Kind: "Slave", Masters: []string{"192.0.2.1"}, Nameservers: []string{"ns1."}},
+			expectedErr: "nameservers cannot be specified",
+		},
+		{
+			name: "rrsets not allowed",
+			zone: Zone{
+				Kind:    "Slave",
+				Masters: []string{"192.0.2.1"},
+				RRsets:  []RRsetInput{{Name: "www", Type: "A", Records: "192.168.1.1"}},
+			},
+			expectedErr: "rrsets cannot be specified",
+		},
+		{
+			name:        "masters on non-slave zone",
+			zone:        Zone{Nameservers: []string{"ns1."}, Masters: []string{"192.0.2.1"}},
+			expectedErr: "masters can only be specified",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Zones: map[string]Zone{"example.com": tt.zone}}
+			err := cfg.Validate(map[string]ZoneState{})
+			if tt.expectedErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
 func TestNormalizeZone_Defaults(t *testing.T) {
 	zone := &Zone{
 		Nameservers: []string{"ns1.example.com."},
@@ -250,6 +330,1071 @@ func TestNormalizeRRsets_DefaultTTL(t *testing.T) {
 	}
 }
 
+func TestParse_MultiDocument(t *testing.T) {
+	data := []byte(`
+zones:
+  example.com:
+    nameservers: [ns1.example.com.]
+---
+# empty document
+---
+zones:
+  example.net:
+    nameservers: [ns1.example.net.]
+`)
+
+	cfg, err := parse(data, "")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	if len(cfg.Zones) != 2 {
+		t.Errorf("Expected 2 zones from both documents, got %d", len(cfg.Zones))
+	}
+	if _, ok := cfg.Zones["example.net"]; !ok {
+		t.Error("Expected zone from second document")
+	}
+}
+
+func TestConfig_ZoneNames(t *testing.T) {
+	cfg, err := parse([]byte("zones:\n  zeta.com: {}\n  alpha.com: {}\n---\nzones:\n  beta.com: {}\n"), "")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	expected := []string{"zeta.com", "alpha.com", "beta.com"}
+	for range 10 {
+		if names := cfg.ZoneNames(); !slices.Equal(names, expected) {
+			t.Fatalf("Expected declaration order %v, got %v", expected, names)
+		}
+	}
+
+	// Zones that were not loaded are sorted by name
+	cfg = &Config{Zones: map[string]Zone{"b.com": {}, "a.com": {}}}
+	if names := cfg.ZoneNames(); !slices.Equal(names, []string{"a.com", "b.com"}) {
+		t.Errorf("Expected sorted names, got %v", names)
+	}
+}
+
+func TestParse_MultiDocumentDuplicateZone(t *testing.T) {
+	data := []byte(`
+zones:
+  example.com:
+    nameservers: [ns1.example.com.]
+---
+zones:
+  example.com.:
+    nameservers: [ns2.example.com.]
+`)
+
+	_, err := parse(data, "")
+	if err == nil {
+		t.Fatal("Expected error for zone defined in two documents, got nil")
+	}
+	if !strings.Contains(err.Error(), "document 2 is already defined in document 1 (8:5, first at 4:5)") {
+		t.Errorf("Expected duplicate zone error, got: %v", err)
+	}
+}
+
+func TestParse_DuplicateZoneKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name: "same key",
+			data: "zones:\n  example.com: {}\n  example.net: {}\n" +
+				"  example.com:\n    nameservers: [ns1.example.com.]\n",
+			wantErr: `zone "example.com" is defined twice in document 1 (zones.yml:4:3, first at zones.yml:2:3)`,
+		},
+		{
+			name:    "same zone",
+			data:    "account: team-a\n---\nzones:\n  example.com.: {}\n  Example.COM: {}\n",
+			wantErr: `zone "Example.COM" is defined twice in document 2 (zones.yml:5:3, first at zones.yml:4:3)`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse([]byte(tt.data), "zones.yml")
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	// Other keys may repeat in different mappings
+	data := "zones:\n  example.com:\n    rrsets: []\n  example.net:\n    rrsets: []\n"
+	if _, err := parse([]byte(data), "zones.yml"); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestParse_Account(t *testing.T) {
+	data := []byte("account: team-a\nzones:\n  a.com: {}\n---\nzones:\n  b.com: {}\n---\naccount: team-a\n")
+	cfg, err := parse(data, "")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if cfg.Account != "team-a" {
+		t.Errorf("Expected account team-a, got %q", cfg.Account)
+	}
+
+	_, err = parse([]byte("account: team-a\n---\naccount: team-b\n"), "")
+	if err == nil || !strings.Contains(err.Error(), `account "team-b" in document 2 conflicts with account "team-a"`) {
+		t.Errorf("Expected account conflict error, got: %v", err)
+	}
+}
+
+func TestParse_DanglingTargets(t *testing.T) {
+	cfg, err := parse([]byte("dangling_targets: error\nzones:\n  a.com: {}\n---\ndangling_targets: error\n"), "")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if cfg.DanglingTargets != DanglingError {
+		t.Errorf("Expected dangling_targets error, got %q", cfg.DanglingTargets)
+	}
+
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"invalid mode", "dangling_targets: fail\n", `invalid dangling_targets "fail"`},
+		{"conflict", "dangling_targets: warn\n---\ndangling_targets: off\n", `dangling_targets "off" in document 2`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse([]byte(tt.data), "")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got: %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestConfig_Hash(t *testing.T) {
+	a, err := parse([]byte("zones:\n  a.com: {}\n"), "")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	b, err := parse([]byte("zones:\n  b.com: {}\n"), "")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if len(a.Hash()) != 16 {
+		t.Errorf("Expected a 16 character hash, got %q", a.Hash())
+	}
+	if a.Hash() == b.Hash() {
+		t.Errorf("Expected different hashes for different configs, got %q", a.Hash())
+	}
+}
+
+func TestLoadFromReader(t *testing.T) {
+	cfg, err := LoadFromReader(strings.NewReader("zones:\n  example.com:\n    nameservers: [ns1.example.com.]\n"))
+	if err != nil {
+		t.Fatalf("LoadFromReader failed: %v", err)
+	}
+	if _, ok := cfg.Zones["example.com"]; !ok {
+		t.Error("Expected zone example.com to be loaded")
+	}
+}
+
+func TestLoadFromNamedReader_Locations(t *testing.T) {
+	cfg, err := LoadFromNamedReader(strings.NewReader(`zones:
+  a.com:
+    a: 192.0.2.1
+---
+zones:
+  b.com:
+    rrsets:
+      - name: www
+        type: A
+        records: 192.0.2.1
+    delegations:
+      - name: sub
+        nameservers: [ns1.sub.b.com.]
+        glue: {ns1.sub.b.com.: [192.0.2.53]}
+`), "zones.yml")
+	if err != nil {
+		t.Fatalf("LoadFromNamedReader failed: %v", err)
+	}
+
+	expected := map[string][]string{
+		"a.com": {"zones.yml:3:5 zones.a.com.a"},
+		"b.com": {
+			"zones.yml:8:9 zones.b.com.rrsets[0]",
+			"zones.yml:12:9 zones.b.com.delegations[0]",
+			"zones.yml:12:9 zones.b.com.delegations[0]",
+		},
+	}
+	for name, want := range expected {
+		zone := cfg.Zones[name]
+		rrsets, err := zone.NormalizeRRsets()
+		if err != nil {
+			t.Fatalf("NormalizeRRsets failed: %v", err)
+		}
+		var got []string
+		for _, rrset := range rrsets {
+			got = append(got, rrset.Location.String())
+		}
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("Zone %s: expected locations %q, got %q", name, want, got)
+		}
+	}
+
+	if loc := (Location{Path: "zones.a.com"}); loc.String() != "zones.a.com" {
+		t.Errorf("Expected path only for locations without position, got %q", loc.String())
+	}
+}
+
+func TestValidate_Locations(t *testing.T) {
+	cfg, err := LoadFromNamedReader(strings.NewReader(`zones:
+  example.com:
+    kind: Nativ
+    nameservers: [ns1.example.com.]
+    rrsets:
+      - name: www
+        type: AAA
+        records: 2001:db8::1
+    delegations:
+      - name: sub
+        nameservers: [ns1.sub.example.com]
+`), "zones.yml")
+	if err != nil {
+		t.Fatalf("LoadFromNamedReader failed: %v", err)
+	}
+
+	errs := cfg.Validate(map[string]ZoneState{})
+	if errs == nil {
+		t.Fatal("Expected validation errors")
+	}
+	expected := []string{"zones.yml:3:5: ", "zones.yml:6:9: ", "zones.yml:10:9: "}
+	for _, prefix := range expected {
+		found := false
+		for _, msg := range errs.Errors {
+			found = found || strings.HasPrefix(msg, prefix)
+		}
+		if !found {
+			t.Errorf("Expected an error at %s, got %v", prefix, errs.Errors)
+		}
+	}
+	if len(errs.Locations) != len(errs.Errors) {
+		t.Errorf("Expected a location per error, got %d for %d errors", len(errs.Locations), len(errs.Errors))
+	}
+}
+
+func TestNormalizeRRsets_ApexShorthand(t *testing.T) {
+	cfg, err := parse([]byte(`
+zones:
+  example.com:
+    nameservers: [ns1.example.com.]
+    a: [192.0.2.1, 192.0.2.2]
+    aaaa: 2001:db8::1
+    mx:
+      - {10: mail.example.com.}
+      - 20 backup.example.com.
+      - {"30": last.example.com.}
+`), "")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	zone := cfg.Zones["example.com"]
+	rrsets, err := zone.NormalizeRRsets()
+	if err != nil {
+		t.Fatalf("NormalizeRRsets failed: %v", err)
+	}
+
+	byType := make(map[string]RRset)
+	for _, rrset := range rrsets {
+		if rrset.Name != "@" {
+			t.Errorf("Expected shorthand rrsets at the apex, got name %q", rrset.Name)
+		}
+		byType[rrset.Type] = rrset
+	}
+
+	if len(byType["A"].Records) != 2 {
+		t.Errorf("Expected 2 A records, got %+v", byType["A"].Records)
+	}
+	if len(byType["AAAA"].Records) != 1 || byType["AAAA"].Records[0].Content != "2001:db8::1" {
+		t.Errorf("Unexpected AAAA records: %+v", byType["AAAA"].Records)
+	}
+	mx := byType["MX"].Records
+	// Quoted priorities are accepted like the other integers
+	if len(mx) != 3 || mx[0].Content != "10 mail.example.com." || mx[1].Content != "20 backup.example.com." ||
+		mx[2].Content != "30 last.example.com." {
+		t.Errorf("Unexpected MX records: %+v", mx)
+	}
+
+	if err := cfg.Validate(map[string]ZoneState{}); err != nil {
+		t.Errorf("Expected valid config, got: %v", err)
+	}
+}
+
+func TestValidate_ApexShorthandConflicts(t *testing.T) {
+	cfg := &Config{
+		Zones: map[string]Zone{
+			"example.com": {
+				Nameservers: []string{"ns1.example.com."},
+				A:           "192.0.2.1",
+				RRsets: []RRsetInput{
+					{Name: "@", Type: "A", Records: "192.0.2.2"},
+				},
+			},
+		},
+	}
+
+	err := cfg.Validate(map[string]ZoneState{})
+	if err == nil || !strings.Contains(err.Error(), "a (@/A): duplicate RRset definition") {
+		t.Errorf("Expected duplicate error naming the shorthand key, got: %v", err)
+	}
+}
+
+func TestValidate_InvalidMXShorthand(t *testing.T) {
+	cfg := &Config{
+		Zones: map[string]Zone{
+			"example.com": {
+				Nameservers: []string{"ns1.example.com."},
+				MX:          []interface{}{map[interface{}]interface{}{"high": "mail.example.com."}},
+			},
+		},
+	}
+
+	err := cfg.Validate(map[string]ZoneState{})
+	if err == nil || !strings.Contains(err.Error(), "mx: record[0]: priority must be an integer") {
+		t.Errorf("Expected MX priority error, got: %v", err)
+	}
+}
+
+func TestNormalizeRRsets_Delegation(t *testing.T) {
+	ttl := uint32(3600)
+	zone := Zone{
+		Nameservers: []string{"ns1.example.com."},
+		Delegations: []Delegation{{
+			Name:        "sub",
+			Nameservers: []string{"ns1.sub.example.com.", "ns.other.net."},
+			Glue:        map[string][]string{"ns1.sub.example.com.": {"192.0.2.53", "2001:db8::53"}},
+			TTL:         &ttl,
+		}},
+	}
+
+	rrsets, err := zone.NormalizeRRsets()
+	if err != nil {
+		t.Fatalf("NormalizeRRsets failed: %v", err)
+	}
+	if len(rrsets) != 3 {
+		t.Fatalf("Expected NS, glue A and glue AAAA rrsets, got %+v", rrsets)
+	}
+
+	expected := []struct{ name, rtype, content string }{
+		{"sub", "NS", "ns1.sub.example.com."},
+		{"ns1.sub.example.com.", "A", "192.0.2.53"},
+		{"ns1.sub.example.com.", "AAAA", "2001:db8::53"},
+	}
+	for i, want := range expected {
+		got := rrsets[i]
+		if got.Name != want.name || got.Type != want.rtype || got.Records[0].Content != want.content || got.TTL != ttl {
+			t.Errorf("rrset[%d]: expected %s %s %s, got %+v", i, want.name, want.rtype, want.content, got)
+		}
+	}
+
+	cfg := &Config{Zones: map[string]Zone{"example.com": zone}}
+	if err := cfg.Validate(map[string]ZoneState{}); err != nil {
+		t.Errorf("Expected valid delegation, got: %v", err)
+	}
+}
+
+func TestValidate_Delegations(t *testing.T) {
+	tests := []struct {
+		name       string
+		delegation Delegation
+		rrsets     []RRsetInput
+		child      *Zone
+		wantErr    string
+	}{
+		{
+			name:       "relative nameserver",
+			delegation: Delegation{Name: "sub", Nameservers: []string{"ns1"}},
+			wantErr:    `nameserver "ns1" must be fully qualified`,
+		},
+		{
+			name:       "missing glue",
+			delegation: Delegation{Name: "sub", Nameservers: []string{"ns1.sub.example.com."}},
+			wantErr:    "requires glue",
+		},
+		{
+			name: "glue outside the child zone",
+			delegation: Delegation{
+				Name:        "sub",
+				Nameservers: []string{"ns.other.net."},
+				Glue:        map[string][]string{"ns.other.net.": {"192.0.2.1"}},
+			},
+			wantErr: "outside the delegated zone",
+		},
+		{
+			name: "invalid glue address",
+			delegation: Delegation{
+				Name:        "sub",
+				Nameservers: []string{"ns1.sub.example.com."},
+				Glue:        map[string][]string{"ns1.sub.example.com.": {"not-an-ip"}},
+			},
+			wantErr: `invalid address "not-an-ip"`,
+		},
+		{
+			name:       "not a subdomain",
+			delegation: Delegation{Name: "example.org.", Nameservers: []string{"ns.other.net."}},
+			wantErr:    "is not a subdomain of the zone",
+		},
+		{
+			name:       "record inside the delegated zone",
+			delegation: Delegation{Name: "sub", Nameservers: []string{"ns.other.net."}},
+			rrsets:     []RRsetInput{{Name: "www.sub", Type: "A", Records: "192.0.2.1"}},
+			wantErr:    "is inside delegated zone sub.example.com.",
+		},
+		{
+			name:       "child nameservers mismatch",
+			delegation: Delegation{Name: "sub", Nameservers: []string{"ns.other.net."}},
+			child:      &Zone{Nameservers: []string{"ns1.sub.example.com."}},
+			wantErr:    `do not match the nameservers [ns1.sub.example.com.] of zone "sub.example.com"`,
+		},
+		{
+			name:       "child nameservers match",
+			delegation: Delegation{Name: "sub", Nameservers: []string{"ns.other.net."}},
+			rrsets:     []RRsetInput{{Name: "sub", Type: "DS", Records: "12345 13 2 abcdef"}},
+			child:      &Zone{Nameservers: []string{"ns.other.net."}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Zones: map[string]Zone{
+				"example.com": {
+					Nameservers: []string{"ns1.example.com."},
+					RRsets:      tt.rrsets,
+					Delegations: []Delegation{tt.delegation},
+				},
+			}}
+			if tt.child != nil {
+				cfg.Zones["sub.example.com"] = *tt.child
+			}
+
+			err := cfg.Validate(map[string]ZoneState{})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoad_DelegationGlueCase(t *testing.T) {
+	cfg, err := LoadFromReader(strings.NewReader(`zones:
+  example.com:
+    nameservers: [ns1.example.com.]
+    delegations:
+      - name: sub
+        nameservers: [ns1.sub.example.com.]
+        glue:
+          NS1.Sub.Example.com.: [192.0.2.53]
+`))
+	if err != nil {
+		t.Fatalf("LoadFromReader failed: %v", err)
+	}
+	if err := cfg.Validate(map[string]ZoneState{}); err != nil {
+		t.Errorf("Expected glue to match the nameserver regardless of case, got: %v", err)
+	}
+	if glue := cfg.Zones["example.com"].Delegations[0].Glue; len(glue["ns1.sub.example.com."]) != 1 {
+		t.Errorf("Expected lowercase glue keys, got %v", glue)
+	}
+}
+
+func TestValidate_DelegationNSRRsets(t *testing.T) {
+	tests := []struct {
+		name    string
+		rrsets  []RRsetInput
+		wantErr string
+	}{
+		{
+			name: "delegation with glue and DS",
+			rrsets: []RRsetInput{
+				{Name: "sub", Type: "NS", Records: []interface{}{"ns1.sub.example.com.", "ns.other.net."}},
+				{Name: "sub", Type: "DS", Records: "12345 13 2 abcdef"},
+				{Name: "ns1.sub", Type: "A", Records: "192.0.2.53"},
+			},
+		},
+		{
+			name:    "apex NS",
+			rrsets:  []RRsetInput{{Name: "example.com.", Type: "NS", Records: "ns1.example.com."}},
+			wantErr: "apex NS records must be managed via 'nameservers'",
+		},
+		{
+			name:    "relative nameserver",
+			rrsets:  []RRsetInput{{Name: "sub", Type: "NS", Records: "ns1.sub"}},
+			wantErr: `nameserver "ns1.sub" must be fully qualified`,
+		},
+		{
+			name: "record hidden by delegation",
+			rrsets: []RRsetInput{
+				{Name: "sub", Type: "NS", Records: "ns.other.net."},
+				{Name: "www.sub", Type: "A", Records: "192.0.2.1"},
+			},
+			wantErr: "is inside delegated zone sub.example.com.",
+		},
+		{
+			name: "DS below the delegation point",
+			rrsets: []RRsetInput{
+				{Name: "sub", Type: "NS", Records: "ns.other.net."},
+				{Name: "deeper.sub", Type: "DS", Records: "12345 13 2 abcdef"},
+			},
+			wantErr: "is inside delegated zone sub.example.com.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Zones: map[string]Zone{
+				"example.com": {Nameservers: []string{"ns1.example.com."}, RRsets: tt.rrsets},
+			}}
+
+			err := cfg.Validate(map[string]ZoneState{})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_Metadata(t *testing.T) {
+	multiline := "Team A\nproduction"
+	cfg := &Config{
+		Zones: map[string]Zone{
+			"example.com": {
+				Nameservers:   []string{"ns1.example.com."},
+				AllowAxfrFrom: []string{"192.0.2.1", "2001:db8::/32", "AUTO-NS", "example.org"},
+				AlsoNotify:    []string{"192.0.2.53", "192.0.2.54:5300", "[2001:db8::53]:53", "192.0.2.55:0"},
+				TSIGAllowAxfr: []string{"transfer-key", ""},
+				Description:   &multiline,
+			},
+		},
+	}
+
+	err := cfg.Validate(map[string]ZoneState{})
+	if err == nil {
+		t.Fatal("Expected validation errors, got nil")
+	}
+	if len(err.Errors) != 4 {
+		t.Errorf("Expected 4 errors, got %d: %v", len(err.Errors), err)
+	}
+	for _, want := range []string{
+		`allow_axfr_from[3]: "example.org" is not an IP address, CIDR or AUTO-NS`,
+		`also_notify[3]: "192.0.2.55:0" is not an IP address with optional port`,
+		"tsig_allow_axfr[1]: key name cannot be empty",
+		"description must be a single line",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error %q, got: %v", want, err)
+		}
+	}
+}
+
+func TestZone_Metadata(t *testing.T) {
+	rectify := false
+	description := "Team A production zone"
+	zone := Zone{AllowAxfrFrom: []string{}, APIRectify: &rectify, Description: &description}
+
+	metadata := zone.Metadata()
+	if len(metadata) != 3 {
+		t.Fatalf("Expected only the set fields, got %v", metadata)
+	}
+	if values, ok := metadata[MetadataAllowAxfrFrom]; !ok || len(values) != 0 {
+		t.Errorf("Expected empty ALLOW-AXFR-FROM to remove the metadata, got %v", metadata)
+	}
+	if values := metadata[MetadataAPIRectify]; len(values) != 1 || values[0] != "0" {
+		t.Errorf("Expected API-RECTIFY 0, got %v", values)
+	}
+	if values := metadata[MetadataDescription]; len(values) != 1 || values[0] != description {
+		t.Errorf("Expected description %q, got %v", description, values)
+	}
+
+	empty := ""
+	zone = Zone{Description: &empty}
+	if values, ok := zone.Metadata()[MetadataDescription]; !ok || len(values) != 0 {
+		t.Errorf("Expected empty description to remove the metadata, got %v", values)
+	}
+}
+
+func TestValidate_ManagedSubtree(t *testing.T) {
+	existing := map[string]ZoneState{"example.com.": {Exists: true}}
+	tests := []struct {
+		name    string
+		zone    Zone
+		state   map[string]ZoneState
+		wantErr string
+	}{
+		{
+			name: "rrsets inside the subtree",
+			zone: Zone{ManagedSubtree: "k8s", RRsets: []RRsetInput{
+				{Name: "k8s", Type: "A", Records: "192.0.2.1"},
+				{Name: "api.k8s", Type: "A", Records: "192.0.2.2"},
+				{Name: "ingress.K8S.example.com.", Type: "A", Records: "192.0.2.3"},
+			}},
+			state: existing,
+		},
+		{
+			name: "fully qualified subtree",
+			zone: Zone{ManagedSubtree: "k8s.example.com.", RRsets: []RRsetInput{
+				{Name: "api.k8s", Type: "A", Records: "192.0.2.2"},
+			}},
+			state: existing,
+		},
+		{
+			name: "rrset outside the subtree",
+			zone: Zone{ManagedSubtree: "k8s", RRsets: []RRsetInput{
+				{Name: "www", Type: "A", Records: "192.0.2.1"},
+			}},
+			state:   existing,
+			wantErr: `rrset[0] (www/A): outside of managed_subtree "k8s"`,
+		},
+		{
+			name:    "apex shorthand outside the subtree",
+			zone:    Zone{ManagedSubtree: "k8s", A: "192.0.2.1"},
+			state:   existing,
+			wantErr: `(@/A): outside of managed_subtree "k8s"`,
+		},
+		{
+			name:    "subtree outside the zone",
+			zone:    Zone{ManagedSubtree: "k8s.example.org."},
+			state:   existing,
+			wantErr: "managed_subtree k8s.example.org. is not inside the zone",
+		},
+		{
+			name:    "new zone",
+			zone:    Zone{ManagedSubtree: "k8s", Nameservers: []string{"ns1.example.com."}},
+			state:   map[string]ZoneState{},
+			wantErr: "managed_subtree can only be used for existing zones",
+		},
+		{
+			name:    "nameservers",
+			zone:    Zone{ManagedSubtree: "k8s", Nameservers: []string{"ns1.example.com."}},
+			state:   existing,
+			wantErr: "nameservers cannot be specified with managed_subtree",
+		},
+		{
+			name:    "metadata",
+			zone:    Zone{ManagedSubtree: "k8s", AlsoNotify: []string{"192.0.2.53"}},
+			state:   existing,
+			wantErr: "zone metadata cannot be specified with managed_subtree",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Zones: map[string]Zone{"example.com": tt.zone}}
+			err := cfg.Validate(tt.state)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_IgnoreTypes(t *testing.T) {
+	existing := map[string]ZoneState{"example.com.": {Exists: true}}
+
+	cfg := &Config{Zones: map[string]Zone{"example.com": {IgnoreTypes: []string{"txt", "TYPE65534"}}}}
+	if err := cfg.Validate(existing); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+	if zone := cfg.Zones["example.com"]; !zone.IgnoresType("TXT") || zone.IgnoresType("A") {
+		t.Errorf("Unexpected ignored types of %v", zone.IgnoreTypes)
+	}
+
+	cfg = &Config{Zones: map[string]Zone{"example.com": {IgnoreTypes: []string{"TXT", "TXR"}}}}
+	want := `ignore_types[1]: unknown record type "TXR", did you mean "TXT"?`
+	if err := cfg.Validate(existing); err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("Expected error containing %q, got: %v", want, err)
+	}
+}
+
+func TestValidate_DualStack(t *testing.T) {
+	existing := map[string]ZoneState{"example.com.": {Exists: true}}
+
+	cfg := &Config{Zones: map[string]Zone{"example.com": {DualStack: DualStackWarn}}}
+	if err := cfg.Validate(existing); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	cfg = &Config{Zones: map[string]Zone{"example.com": {DualStack: "ipv6"}}}
+	want := `zone "example.com": invalid dual_stack "ipv6"`
+	if err := cfg.Validate(existing); err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("Expected error containing %q, got: %v", want, err)
+	}
+}
+
+func TestValidate_Recursor(t *testing.T) {
+	tests := []struct {
+		name    string
+		zone    Zone
+		wantErr string
+	}{
+		{
+			name: "forward zone",
+			zone: Zone{
+				Target:           TargetRecursor,
+				Forwarders:       []string{"192.0.2.1", "[2001:db8::1]:5300"},
+				RecursionDesired: true,
+			},
+		},
+		{name: "auth-zone", zone: Zone{Target: TargetRecursor, Kind: KindNative}},
+		{
+			name:    "no forwarders",
+			zone:    Zone{Target: TargetRecursor},
+			wantErr: `zone "corp.example": forwarders are required for Forwarded zones`,
+		},
+		{
+			name:    "invalid forwarder",
+			zone:    Zone{Target: TargetRecursor, Forwarders: []string{"ns1.example.com"}},
+			wantErr: `forwarders[0]: invalid address "ns1.example.com"`,
+		},
+		{
+			name:    "auth-zone with forwarders",
+			zone:    Zone{Target: TargetRecursor, Kind: KindNative, Forwarders: []string{"192.0.2.1"}},
+			wantErr: "forwarders and recursion_desired can only be specified for Forwarded zones",
+		},
+		{
+			name:    "invalid kind",
+			zone:    Zone{Target: TargetRecursor, Kind: "Master"},
+			wantErr: `invalid kind "Master" for target recursor`,
+		},
+		{
+			name: "records",
+			zone: Zone{Target: TargetRecursor, Forwarders: []string{"192.0.2.1"},
+				RRsets: []RRsetInput{{Name: "www", Type: "A", Records: "192.0.2.2"}}},
+			wantErr: "zones with target recursor only support kind, forwarders and recursion_desired",
+		},
+		{
+			name:    "forwarders on the authoritative server",
+			zone:    Zone{Nameservers: []string{"ns1.example.net."}, Forwarders: []string{"192.0.2.1"}},
+			wantErr: "forwarders and recursion_desired can only be specified for zones with target recursor",
+		},
+		{
+			name:    "invalid target",
+			zone:    Zone{Target: "resolver"},
+			wantErr: `invalid target "resolver", must be: authoritative, recursor`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Zones: map[string]Zone{"corp.example": tt.zone}}
+			err := cfg.Validate(map[string]ZoneState{})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestZone_IgnoresName(t *testing.T) {
+	zone := Zone{IgnoreNames: []string{"_acme-challenge", "_acme-challenge.*", "*.DYN", "static.example.com."}}
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"_acme-challenge.example.com.", true},
+		{"_acme-challenge.www.example.com.", true},
+		{"host.dyn.example.com.", true},
+		{"Host.Dyn.Example.com.", true},
+		{"static.example.com.", true},
+		{"dyn.example.com.", false},
+		{"www.example.com.", false},
+		{"example.com.", false},
+	}
+	for _, tt := range tests {
+		if got := zone.IgnoresName(tt.name, "example.com."); got != tt.want {
+			t.Errorf("IgnoresName(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	cfg := &Config{Zones: map[string]Zone{"example.com": {IgnoreNames: []string{"[dyn"}}}}
+	want := `ignore_names[0]: invalid pattern "[dyn"`
+	if err := cfg.Validate(map[string]ZoneState{"example.com.": {Exists: true}}); err == nil ||
+		!strings.Contains(err.Error(), want) {
+		t.Errorf("Expected error containing %q, got: %v", want, err)
+	}
+}
+
+func TestValidate_Overlap(t *testing.T) {
+	child := Zone{Nameservers: []string{"ns1.example.net."}}
+	tests := []struct {
+		name    string
+		parent  Zone
+		wantErr string
+	}{
+		{
+			name: "records outside the child zone",
+			parent: Zone{Nameservers: []string{"ns1.example.net."}, RRsets: []RRsetInput{
+				{Name: "www", Type: "A", Records: "192.0.2.1"},
+				{Name: "notsub", Type: "A", Records: "192.0.2.2"},
+			}},
+		},
+		{
+			name: "record inside the child zone",
+			parent: Zone{Nameservers: []string{"ns1.example.net."}, RRsets: []RRsetInput{
+				{Name: "foo.sub", Type: "A", Records: "192.0.2.1"},
+			}},
+			wantErr: `rrset[0] (foo.sub/A): is inside zone sub.example.com. of the configuration`,
+		},
+		{
+			name: "fully qualified record in the most specific zone",
+			parent: Zone{Nameservers: []string{"ns1.example.net."}, RRsets: []RRsetInput{
+				{Name: "a.deep.sub.example.com.", Type: "TXT", Records: `"x"`},
+			}},
+			wantErr: "is inside zone deep.sub.example.com. of the configuration",
+		},
+		{
+			name: "delegation of the child zone",
+			parent: Zone{
+				Nameservers: []string{"ns1.example.net."},
+				Delegations: []Delegation{{Name: "sub", Nameservers: []string{"ns1.example.net."}}},
+				RRsets:      []RRsetInput{{Name: "sub", Type: "DS", Records: "1 13 2 ABCD"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Zones: map[string]Zone{
+				"example.com":          tt.parent,
+				"sub.example.com":      child,
+				"deep.sub.example.com": child,
+			}}
+			err := cfg.Validate(map[string]ZoneState{})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNormalizeRRsets_Migration(t *testing.T) {
+	ttl := uint32(3600)
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	zone := Zone{RRsets: []RRsetInput{
+		{Name: "lowered", Type: "A", Records: "192.0.2.1", TTL: &ttl, Migration: &Migration{TTL: 60}},
+		{Name: "until", Type: "A", Records: "192.0.2.2", TTL: &ttl, Migration: &Migration{TTL: 60, Until: &future}},
+		{Name: "restored", Type: "A", Records: "192.0.2.3", TTL: &ttl, Migration: &Migration{TTL: 60, Until: &past}},
+	}}
+
+	rrsets, err := zone.NormalizeRRsets()
+	if err != nil {
+		t.Fatalf("NormalizeRRsets failed: %v", err)
+	}
+	want := map[string]uint32{"lowered": 60, "until": 60, "restored": 3600}
+	for _, rrset := range rrsets {
+		if rrset.TTL != want[rrset.Name] {
+			t.Errorf("%s: expected TTL %d, got %d", rrset.Name, want[rrset.Name], rrset.TTL)
+		}
+	}
+
+	cfg := &Config{Zones: map[string]Zone{"example.com": {
+		Nameservers: []string{"ns1.example.com."},
+		RRsets:      []RRsetInput{{Name: "www", Type: "A", Records: "192.0.2.1", Migration: &Migration{}}},
+	}}}
+	verr := cfg.Validate(map[string]ZoneState{})
+	if verr == nil || !strings.Contains(verr.Error(), "migration ttl must be between 1 and") {
+		t.Errorf("Expected migration ttl error, got: %v", verr)
+	}
+}
+
+func TestValidate_TTL(t *testing.T) {
+	tests := []struct {
+		name    string
+		ttl     string
+		wantErr string
+	}{
+		{"minimum", "1", ""},
+		{"maximum", "2147483647", ""},
+		{"zero", "0", "ttl must be between 1 and 2147483647, got 0"},
+		{"above maximum", "2147483648", "ttl must be between 1 and 2147483647, got 2147483648"},
+		{"negative", "-1", "ttl must be between 1 and 2147483647, got -1 in document 1 (zones.yml:6:14)"},
+		{"overflow", "4294967296",
+			"ttl must be between 1 and 2147483647, got 4294967296 in document 1 (zones.yml:6:14)"},
+		{"int64 overflow", "99999999999999999999",
+			"ttl must be between 1 and 2147483647, got 99999999999999999999 in document 1 (zones.yml:6:14)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := "zones:\n  example.com:\n    nameservers: [ns1.example.com.]\n    rrsets:\n" +
+				"      - name: www\n        ttl: " + tt.ttl + "\n        type: A\n        records: [192.0.2.1]\n"
+			cfg, err := parse([]byte(data), "zones.yml")
+			if err == nil {
+				if verr := cfg.Validate(map[string]ZoneState{}); verr != nil {
+					err = verr
+				}
+			}
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Expected no error, got %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("Expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	// Delegations and migrations are checked too
+	zero := uint32(0)
+	cfg := &Config{Zones: map[string]Zone{"example.com": {
+		Nameservers: []string{"ns1.example.com."},
+		Delegations: []Delegation{{Name: "sub", Nameservers: []string{"ns1.other.net."}, TTL: &zero}},
+		RRsets: []RRsetInput{{Name: "www", Type: "A", Records: "192.0.2.1",
+			Migration: &Migration{TTL: MaxTTL + 1}}},
+	}}}
+	verr := cfg.Validate(map[string]ZoneState{})
+	if verr == nil || len(verr.Errors) != 2 {
+		t.Fatalf("Expected 2 errors, got: %v", verr)
+	}
+	if !strings.Contains(verr.Error(), "migration ttl must be between 1 and 2147483647, got 2147483648") {
+		t.Errorf("Expected migration ttl error, got: %v", verr)
+	}
+}
+
+func TestValidate_ChangePolicy(t *testing.T) {
+	cfg := &Config{Zones: map[string]Zone{"example.com": {
+		Nameservers: []string{"ns1.example.com."},
+		RRsets: []RRsetInput{
+			{Name: "@", Type: "TXT", Records: "v=spf1 -all", ChangePolicy: ChangePolicyCreateOnly},
+			{Name: "www", Type: "A", Records: "192.0.2.1", ChangePolicy: "createOnly"},
+		},
+	}}}
+	verr := cfg.Validate(map[string]ZoneState{})
+	if verr == nil || len(verr.Errors) != 1 ||
+		!strings.Contains(verr.Error(), `invalid change_policy "createOnly", must be: create_only`) {
+		t.Fatalf("Expected a single change_policy error, got: %v", verr)
+	}
+
+	zone := cfg.Zones["example.com"]
+	rrsets, err := zone.NormalizeRRsets()
+	if err != nil {
+		t.Fatalf("NormalizeRRsets failed: %v", err)
+	}
+	if !rrsets[0].CreateOnly || rrsets[1].CreateOnly {
+		t.Errorf("Expected only the TXT rrset to be create only, got %+v", rrsets)
+	}
+}
+
+func TestValidate_State(t *testing.T) {
+	cfg := &Config{Zones: map[string]Zone{"example.com": {
+		Nameservers: []string{"ns1.example.com."},
+		RRsets: []RRsetInput{
+			{Name: "old", Type: "a", State: StateAbsent},
+			{Name: "www", Type: "A", Records: "192.0.2.1", State: StatePresent},
+			{Name: "ftp", Type: "A", Records: "192.0.2.2", State: StateAbsent},
+			{Name: "mail", Type: "A", Records: "192.0.2.3", State: "deleted"},
+		},
+	}}}
+	verr := cfg.Validate(map[string]ZoneState{})
+	if verr == nil || len(verr.Errors) != 2 {
+		t.Fatalf("Expected 2 errors, got: %v", verr)
+	}
+	for _, want := range []string{
+		"(ftp/A): records cannot be set for an absent rrset",
+		`(mail/A): invalid state "deleted", must be: present, absent`,
+	} {
+		if !strings.Contains(verr.Error(), want) {
+			t.Errorf("Expected error containing %q, got: %v", want, verr)
+		}
+	}
+
+	zone := cfg.Zones["example.com"]
+	rrsets, err := zone.NormalizeRRsets()
+	if err != nil {
+		t.Fatalf("NormalizeRRsets failed: %v", err)
+	}
+	if len(rrsets) != 2 || rrsets[0].Name != "www" || rrsets[1].Name != "mail" {
+		t.Errorf("Expected the present rrsets, got %+v", rrsets)
+	}
+	absent := zone.AbsentRRsets()
+	if len(absent) != 2 || absent[0].Name != "old" || absent[0].Type != "A" || absent[1].Name != "ftp" {
+		t.Errorf("Expected the absent rrsets, got %+v", absent)
+	}
+}
+
+func TestNormalizeRRsets_Labels(t *testing.T) {
+	zone := Zone{
+		Labels: map[string]string{"team": "core", "service": "dns"},
+		A:      "192.0.2.1",
+		RRsets: []RRsetInput{
+			{
+				Name: "www", Type: "A", Records: "192.0.2.2",
+				Labels: map[string]string{"service": "web", "ticket": "OPS-1"},
+			},
+		},
+	}
+	rrsets, err := zone.NormalizeRRsets()
+	if err != nil {
+		t.Fatalf("NormalizeRRsets failed: %v", err)
+	}
+	want := map[string]string{
+		"www": "service=web, team=core, ticket=OPS-1",
+		"@":   "service=dns, team=core",
+	}
+	for _, rrset := range rrsets {
+		if got := FormatLabels(rrset.Labels); got != want[rrset.Name] {
+			t.Errorf("%s: expected labels %q, got %q", rrset.Name, want[rrset.Name], got)
+		}
+	}
+	if len(zone.Labels) != 2 {
+		t.Errorf("NormalizeRRsets modified the zone labels: %v", zone.Labels)
+	}
+
+	cfg := &Config{Zones: map[string]Zone{"example.com": {
+		Nameservers: []string{"ns1.example.com."},
+		Labels:      map[string]string{"cost center": "42"},
+		RRsets: []RRsetInput{
+			{Name: "www", Type: "A", Records: "192.0.2.1", Labels: map[string]string{"team": "a\nb"}},
+		},
+	}}}
+	verr := cfg.Validate(map[string]ZoneState{})
+	for _, want := range []string{
+		`labels: invalid label key "cost center"`,
+		"label team: value cannot contain line breaks",
+	} {
+		if verr == nil || !strings.Contains(verr.Error(), want) {
+			t.Errorf("Expected error containing %q, got: %v", want, verr)
+		}
+	}
+}
+
 func TestCanonicalZoneName(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -267,3 +1412,641 @@ func TestCanonicalZoneName(t *testing.T) {
 		}
 	}
 }
+
+func TestValidate_StrictNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		rrset   RRsetInput
+		strict  bool
+		wantErr string
+	}{
+		{"relative name", RRsetInput{Name: "www"}, true, ""},
+		{"absolute name in zone", RRsetInput{Name: "www.Example.com."}, true, ""},
+		{"absolute name outside zone", RRsetInput{Name: "www.example.net."}, true, "outside of the zone"},
+		{"suffix but not subdomain", RRsetInput{Name: "www.notexample.com."}, true, "outside of the zone"},
+		{"external name", RRsetInput{Name: "www.example.net.", External: true}, true, ""},
+		{"not strict", RRsetInput{Name: "www.example.net."}, false, ""},
+		{"relative external name", RRsetInput{Name: "www", External: true}, false, "must be fully qualified"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rrset := tt.rrset
+			rrset.Type, rrset.Records = "A", "192.0.2.1"
+			cfg := &Config{
+				StrictNames: tt.strict,
+				Zones: map[string]Zone{
+					"example.com": {Nameservers: []string{"ns1.example.com."}, RRsets: []RRsetInput{rrset}},
+				},
+			}
+			err := cfg.Validate(map[string]ZoneState{})
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Expected no error, got %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateType(t *testing.T) {
+	tests := []struct {
+		rtype   string
+		wantErr string
+	}{
+		{"AAAA", ""},
+		{"cname", ""},
+		{"TYPE65534", ""},
+		{"AAAAA", `did you mean "AAAA"?`},
+		{"CNMAE", `did you mean "CNAME"?`},
+		{"txt1", `did you mean "TXT"?`},
+		{"TYPE70000", "unknown record type"},
+		{"WHATEVER", `unknown record type "WHATEVER"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rtype, func(t *testing.T) {
+			err := validateType(tt.rtype)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Expected %s to be valid, got %v", tt.rtype, err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// syntheticZone returns a zone with n rrsets of mixed types and record formats.
+func syntheticZone(n int) Zone {
+	zone := Zone{Nameservers: []string{"ns1.example.net.", "ns2.example.net."}}
+	zone.RRsets = make([]RRsetInput, 0, n)
+	for i := range n {
+		name := fmt.Sprintf("host%d", i)
+		switch i % 4 {
+		case 0:
+			zone.RRsets = append(zone.RRsets, RRsetInput{Name: name, Type: "A", Records: "192.0.2.1"})
+		case 1:
+			zone.RRsets = append(zone.RRsets, RRsetInput{
+				Name: name, Type: "AAAA", Records: []interface{}{"2001:db8::1", "2001:db8::2"},
+			})
+		case 2:
+			record := map[string]interface{}{"content": "10 mx", "disabled": true}
+			zone.RRsets = append(zone.RRsets, RRsetInput{Name: name, Type: "MX", Records: []interface{}{record}})
+		default:
+			zone.RRsets = append(zone.RRsets, RRsetInput{Name: name, Type: "CNAME", Records: "host0"})
+		}
+	}
+	return zone
+}
+
+func BenchmarkNormalizeRRsets(b *testing.B) {
+	zone := syntheticZone(100000)
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := zone.NormalizeRRsets(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkValidate(b *testing.B) {
+	cfg := &Config{Zones: map[string]Zone{"example.com": syntheticZone(100000)}}
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := cfg.Validate(map[string]ZoneState{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+const encryptedConfig = `zones:
+    example.com:
+        nameservers: ENC[AES256_GCM,data:...,type:str]
+sops:
+    age:
+        - recipient: age1...
+    mac: ENC[AES256_GCM,data:...,type:str]
+    version: 3.9.0
+`
+
+func TestParse_Sops(t *testing.T) {
+	// A fake sops that checks its input and prints the decrypted configuration
+	dir := t.TempDir()
+	script := "#!/bin/sh\nfor last; do :; done\ngrep -q '^sops:' \"$last\" || exit 1\n" +
+		"printf 'zones:\\n  example.com:\\n    nameservers: [ns1.example.com.]\\n'\n"
+	writeFiles(t, dir, map[string]string{"sops": script})
+	if err := os.Chmod(filepath.Join(dir, "sops"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	defer func(cmd string) { sopsCommand = cmd }(sopsCommand)
+	sopsCommand = filepath.Join(dir, "sops")
+
+	cfg, err := parse([]byte(encryptedConfig), "zones.enc.yml")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if ns := cfg.Zones["example.com"].Nameservers; len(ns) != 1 || ns[0] != "ns1.example.com." {
+		t.Errorf("Expected decrypted nameservers, got %v", ns)
+	}
+
+	writeFiles(t, dir, map[string]string{
+		"sops": "#!/bin/sh\necho 'Error: no key could decrypt the data key' >&2\nexit 128\n",
+	})
+	_, err = parse([]byte(encryptedConfig), "zones.enc.yml")
+	expected := "failed to decrypt zones.enc.yml with sops: Error: no key could decrypt the data key"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error %q, got %v", expected, err)
+	}
+
+	// Configurations that are not loaded from a file are not decrypted
+	_, err = LoadFromReader(strings.NewReader(encryptedConfig))
+	if err == nil || !strings.Contains(err.Error(), "only decrypted for files") {
+		t.Errorf("Expected an error for an encrypted reader, got %v", err)
+	}
+
+	sopsCommand = filepath.Join(dir, "missing")
+	_, err = parse([]byte(encryptedConfig), "zones.enc.yml")
+	if err == nil || !strings.Contains(err.Error(), "zones.enc.yml is encrypted with sops, but sops is not installed") {
+		t.Errorf("Expected missing sops error, got %v", err)
+	}
+}
+
+func TestIsSopsEncrypted(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want bool
+	}{
+		{name: "encrypted", data: encryptedConfig, want: true},
+		{name: "second document", data: "account: team-a\n---\n" + encryptedConfig, want: true},
+		{name: "plain", data: "zones:\n  example.com: {}\n", want: false},
+		{name: "sops without mac", data: "sops:\n  version: 3.9.0\n", want: false},
+		{name: "nested sops key", data: "zones:\n  sops:\n    mac: x\n", want: false},
+		{name: "invalid", data: "sops: [\n", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isSopsEncrypted([]byte(tt.data)); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestZone_Explain(t *testing.T) {
+	cfg, err := parse([]byte(`
+zones:
+  example.com:
+    nameservers: [ns1, ns2.example.net.]
+    a: 192.0.2.1
+    rrsets:
+      - name: "@"
+        type: txt
+        ttl: 600
+        records: [v=spf1 -all, '"quoted"']
+      - name: old
+        type: A
+        state: absent
+    delegations:
+      - name: sub
+        nameservers: [ns.sub.example.com.]
+        glue: {ns.sub.example.com.: [192.0.2.53]}
+`), "zones.yml")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	zone := cfg.Zones["example.com"]
+	zone.defaultTTL = 7200
+
+	tests := []struct {
+		name     string
+		rtype    string
+		expected []string
+	}{
+		{"", "", []string{
+			"example.com. NS nameservers default_ttl 7200 [ns1.example.com. ns2.example.net.]",
+			`example.com. TXT rrsets ttl 600 ["v=spf1 -all" "quoted"]`,
+			"old.example.com. A rrsets  0 [] absent",
+			"example.com. A a default_ttl 7200 [192.0.2.1]",
+			"sub.example.com. NS delegations[0] default_ttl 7200 [ns.sub.example.com.]",
+			"ns.sub.example.com. A delegations[0] glue default_ttl 7200 [192.0.2.53]",
+		}},
+		{"@", "TXT", []string{`example.com. TXT rrsets ttl 600 ["v=spf1 -all" "quoted"]`}},
+		{"SUB.example.com.", "", []string{
+			"sub.example.com. NS delegations[0] default_ttl 7200 [ns.sub.example.com.]",
+		}},
+	}
+
+	for _, tt := range tests {
+		explanations, err := zone.Explain("example.com", tt.name, tt.rtype)
+		if err != nil {
+			t.Fatalf("Explain(%q, %q) failed: %v", tt.name, tt.rtype, err)
+		}
+		got := make([]string, len(explanations))
+		for i, e := range explanations {
+			contents := make([]string, len(e.Records))
+			for j, r := range e.Records {
+				contents[j] = r.Content
+			}
+			got[i] = fmt.Sprintf("%s %s %s %s %d %v", e.Name, e.Type, e.Source, e.TTLSource, e.TTL, contents)
+			if e.Absent {
+				got[i] += " absent"
+			}
+		}
+		if !slices.Equal(got, tt.expected) {
+			t.Errorf("Explain(%q, %q) = %q, want %q", tt.name, tt.rtype, got, tt.expected)
+		}
+	}
+
+	explanations, err := zone.Explain("example.com", "@", "TXT")
+	if err == nil && explanations[0].Location.String() != "zones.yml:7:9 zones.example.com.rrsets[0]" {
+		t.Errorf("Unexpected location %s", explanations[0].Location)
+	}
+	_, err = zone.Explain("example.com", "www", "A")
+	if err == nil || err.Error() != "no rrset www A in zone example.com." {
+		t.Errorf("Expected an error for a missing rrset, got %v", err)
+	}
+}
+
+func TestNormalizeRecords_SVCB(t *testing.T) {
+	tests := []struct {
+		name     string
+		record   string
+		expected string
+		err      string
+	}{
+		{"service", `{priority: 1, target: ., params: {ipv4hint: [192.0.2.1, 192.0.2.2], alpn: [h2, h3], port: 8443}}`,
+			"1 . alpn=h2,h3 port=8443 ipv4hint=192.0.2.1,192.0.2.2", ""},
+		{"alias", `{priority: 0, target: svc.example.net.}`, "0 svc.example.net.", ""},
+		{"all params", `{priority: 2, target: svc.example.com., params: {key65000: x, ipv6hint: "2001:db8::1", ` +
+			`ech: AEX+, no-default-alpn: true, alpn: h3, mandatory: [alpn, port], port: 443}}`,
+			"2 svc.example.com. mandatory=alpn,port alpn=h3 no-default-alpn port=443 ech=AEX+ " +
+				"ipv6hint=2001:db8::1 key65000=x", ""},
+		{"with options", `{priority: 1, target: ., disabled: true}`, "1 .", ""},
+		{"content", `{priority: 1, target: ., content: "1 ."}`, "",
+			"content cannot be combined with priority, target and params"},
+		{"priority", `{priority: 70000, target: .}`, "", "priority must be an integer between 0 and 65535"},
+		{"relative target", `{priority: 1, target: svc}`, "",
+			`target "svc" must be fully qualified (end with a dot), or "." for the owner name`},
+		{"alias params", `{priority: 0, target: ., params: {alpn: h2}}`, "",
+			"params cannot be set with priority 0 (alias mode)"},
+		{"unknown param", `{priority: 1, target: ., params: {alpns: h2}}`, "", `unknown param "alpns"`},
+		{"generic named key", `{priority: 1, target: ., params: {key3: "443"}}`, "",
+			"param key3 must be written as port"},
+		{"ipv4hint", `{priority: 1, target: ., params: {ipv4hint: "2001:db8::1"}}`, "",
+			`params: ipv4hint: "2001:db8::1" is not an IPv4 address`},
+		{"alpn comma", `{priority: 1, target: ., params: {alpn: "h2,h3"}}`, "",
+			`params: alpn: value "h2,h3" cannot contain commas, whitespace, quotes or backslashes`},
+		{"no-default-alpn", `{priority: 1, target: ., params: {no-default-alpn: true}}`, "",
+			"params: no-default-alpn: requires alpn"},
+		{"mandatory", `{priority: 1, target: ., params: {mandatory: port}}`, "",
+			"params: mandatory: lists port, which is not set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var records interface{}
+			if err := yaml.Unmarshal([]byte("["+tt.record+"]"), &records); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			got, err := normalizeRecords(records, ".")
+			switch {
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("Expected error %q, got %v", tt.err, err)
+			case tt.err == "" && err != nil:
+				t.Errorf("normalizeRecords failed: %v", err)
+			case tt.err == "" && got[0].Content != tt.expected:
+				t.Errorf("Expected %q, got %q", tt.expected, got[0].Content)
+			}
+		})
+	}
+}
+
+func TestValidate_SVCBType(t *testing.T) {
+	cfg, err := parse([]byte(`
+zones:
+  example.com:
+    nameservers: [ns1.example.com.]
+    rrsets:
+      - {name: "@", type: HTTPS, records: [{priority: 1, target: ., params: {alpn: h2}}]}
+      - {name: www, type: A, records: [{priority: 1, target: .}]}
+`), "")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	errs := cfg.Validate(nil)
+	want := `7:9: zone "example.com", rrset[1] (www/A), record[0]: priority, target and params are only ` +
+		"supported for SVCB and HTTPS records"
+	if errs == nil || len(errs.Errors) != 1 || errs.Errors[0] != want {
+		t.Errorf("Expected %q, got %v", want, errs)
+	}
+}
+
+func TestNormalizeRecords_Fingerprints(t *testing.T) {
+	dir := t.TempDir()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), NotAfter: time.Now().Add(time.Hour)}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate failed: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate failed: %v", err)
+	}
+	// The certificate follows a key, like in a combined PEM file
+	certPEM := append(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("key")}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	hostKey := []byte("\x00\x00\x00\x0bssh-ed25519host key")
+	hostKeyLine := "ssh-ed25519 " + base64.StdEncoding.EncodeToString(hostKey) + " root@www\n"
+	files := map[string][]byte{
+		"certs/www.pem":              certPEM,
+		"ssh_host_ed25519_key.pub":   []byte(hostKeyLine),
+		"ssh_host_unknown_key.pub":   []byte("ssh-unknown AAAA root@www\n"),
+		"certs/not-a-certificate.md": []byte("# certs\n"),
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatalf("MkdirAll failed: %v", err)
+		}
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+	}
+	spki := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	full := sha512.Sum512(der)
+	hostKeySum := sha256.Sum256(hostKey)
+
+	tests := []struct {
+		name     string
+		rtype    string
+		record   string
+		expected string
+	}{
+		{"tlsa defaults", "TLSA", "{tlsa: {cert_file: certs/www.pem}}", "3 1 1 " + hex.EncodeToString(spki[:])},
+		{"tlsa full certificate", "TLSA", "{tlsa: {cert_file: certs/www.pem, usage: 1, selector: 0, matching: 2}}",
+			"1 0 2 " + hex.EncodeToString(full[:])},
+		{"tlsa absolute path", "TLSA", "{tlsa: {cert_file: " + filepath.Join(dir, "certs/www.pem") + ", matching: 0}}",
+			"3 1 0 " + hex.EncodeToString(cert.RawSubjectPublicKeyInfo)},
+		{"sshfp", "SSHFP", "{sshfp: {key_file: ssh_host_ed25519_key.pub}}",
+			"4 2 " + hex.EncodeToString(hostKeySum[:])},
+		{"tlsa matching", "TLSA", "{tlsa: {cert_file: certs/www.pem, matching: 3}}",
+			"tlsa: matching must be an integer between 0 and 2"},
+		{"tlsa missing file", "TLSA", "{tlsa: {cert_file: certs/missing.pem}}", "tlsa: failed to read cert_file"},
+		{"tlsa no certificate", "TLSA", "{tlsa: {cert_file: certs/not-a-certificate.md}}",
+			"tlsa: cert_file certs/not-a-certificate.md has no PEM certificate"},
+		{"tlsa with content", "TLSA", "{content: 3 1 1 00, tlsa: {cert_file: certs/www.pem}}",
+			"tlsa: cannot be combined with content"},
+		{"sshfp key type", "SSHFP", "{sshfp: {key_file: ssh_host_unknown_key.pub}}",
+			`sshfp: key_file ssh_host_unknown_key.pub has unsupported key type "ssh-unknown"`},
+		{"sshfp fingerprint", "SSHFP", "{sshfp: {key_file: ssh_host_ed25519_key.pub, fingerprint: 0}}",
+			"sshfp: fingerprint must be 1 (SHA-1) or 2 (SHA-256)"},
+		{"wrong type", "TXT", "{sshfp: {key_file: ssh_host_ed25519_key.pub}}",
+			"record[0]: sshfp is only supported for SSHFP records"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parse([]byte(`
+zones:
+  example.com:
+    nameservers: [ns1.example.com.]
+    rrsets:
+      - name: www
+        type: `+tt.rtype+`
+        records: [`+tt.record+`]
+`), filepath.Join(dir, "zones.yml"))
+			if err != nil {
+				t.Fatalf("parse failed: %v", err)
+			}
+			if errs := cfg.Validate(nil); errs != nil {
+				if !strings.Contains(errs.Error(), tt.expected) {
+					t.Errorf("Expected %q, got %v", tt.expected, errs)
+				}
+				return
+			}
+			zone := cfg.Zones["example.com"]
+			rrsets, err := zone.NormalizeRRsets()
+			if err != nil {
+				t.Fatalf("NormalizeRRsets failed: %v", err)
+			}
+			if got := rrsets[0].Records[0].Content; got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLoadFromReader_NoFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "www.pem")
+	if err := os.WriteFile(path, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	cfg, err := LoadFromReader(strings.NewReader(`
+zones:
+  example.com:
+    nameservers: [ns1.example.com.]
+    rrsets:
+      - {name: www, type: TLSA, records: [{tlsa: {cert_file: ` + path + `}}]}
+`))
+	if err != nil {
+		t.Fatalf("LoadFromReader failed: %v", err)
+	}
+	errs := cfg.Validate(nil)
+	expected := "tlsa: cert_file can only be used in configurations loaded from a file"
+	if errs == nil || !strings.Contains(errs.Error(), expected) {
+		t.Errorf("Expected %q, got %v", expected, errs)
+	}
+}
+
+func TestValidate_CAA(t *testing.T) {
+	tests := []struct {
+		name     string
+		records  string
+		expected string
+		err      string
+	}{
+		{"structured", "[{caa: {tag: issue, value: letsencrypt.org}}]", `0 issue "letsencrypt.org"`, ""},
+		{"quoted flags", `[{caa: {flags: "128", tag: issue, value: letsencrypt.org}}]`,
+			`128 issue "letsencrypt.org"`, ""},
+		{"critical", "[{caa: {flags: 128, tag: iodef, value: 'mailto:security@example.com'}}]",
+			`128 iodef "mailto:security@example.com"`, ""},
+		{"no issuer", "[{caa: {tag: issuewild, value: ';'}}]", `0 issuewild ";"`, ""},
+		{"escaped", `[{caa: {tag: issue, value: "letsencrypt.org; note=caf\u00e9\\"}}]`,
+			`0 issue "letsencrypt.org; note=caf\195\169\\"`, ""},
+		{"parameters", `['0 issue "letsencrypt.org; validationmethods=dns-01"']`,
+			`0 issue "letsencrypt.org; validationmethods=dns-01"`, ""},
+		{"typo", `['0 isue "letsencrypt.org"']`, "", `unknown CAA tag "isue", did you mean "issue"?`},
+		{"unknown tag", "[{caa: {tag: policy, value: x}}]", "", `unknown CAA tag "policy", must be one of issue,`},
+		{"flags", "[{caa: {flags: 256, tag: issue, value: x}}]", "",
+			"caa: flags must be an integer between 0 and 255"},
+		{"issuer", "[{caa: {tag: issue, value: 'https://letsencrypt.org'}}]", "",
+			`CAA issue issuer "https://letsencrypt.org" must be a domain name`},
+		{"iodef", "[{caa: {tag: iodef, value: security@example.com}}]", "",
+			`CAA iodef "security@example.com" must be a mailto:, http:// or https:// URL`},
+		{"malformed", "[0 issue]", "", `CAA content "0 issue" must be: flags tag "value"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parse([]byte(`
+zones:
+  example.com:
+    nameservers: [ns1.example.com.]
+    rrsets:
+      - {name: "@", type: CAA, records: `+tt.records+`}
+`), "")
+			if err != nil {
+				t.Fatalf("parse failed: %v", err)
+			}
+			errs := cfg.Validate(nil)
+			if tt.err != "" {
+				if errs == nil || !strings.Contains(errs.Error(), tt.err) {
+					t.Errorf("Expected error %q, got %v", tt.err, errs)
+				}
+				return
+			}
+			if errs != nil {
+				t.Fatalf("Validate failed: %v", errs)
+			}
+			zone := cfg.Zones["example.com"]
+			rrsets, err := zone.NormalizeRRsets()
+			if err != nil {
+				t.Fatalf("NormalizeRRsets failed: %v", err)
+			}
+			if got := rrsets[0].Records[0].Content; got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestValidate_NAPTR(t *testing.T) {
+	tests := []struct {
+		name     string
+		rtype    string
+		records  string
+		expected string
+		err      string
+	}{
+		{"regexp", "NAPTR", `[{naptr: {order: 100, preference: 10, flags: U, service: E2U+sip,
+          regexp: '!^\+(.*)$!sip:\1@example.com!'}}]`,
+			`100 10 "U" "E2U+sip" "!^\\+(.*)$!sip:\\1@example.com!" .`, ""},
+		{"replacement", "NAPTR", "[{naptr: {order: 10, preference: 0, flags: S, service: SIP+D2U, " +
+			"replacement: _sip._udp.example.com.}}]", `10 0 "S" "SIP+D2U" "" _sip._udp.example.com.`, ""},
+		{"escaped delimiter", "NAPTR", `[{naptr: {order: 1, preference: 1, regexp: '#a\#b#c#i'}}]`,
+			`1 1 "" "" "#a\\#b#c#i" .`, ""},
+		{"quotes", "NAPTR", `[{naptr: {order: 1, preference: 1, regexp: '!"(.*)"!\1!'}}]`,
+			`1 1 "" "" "!\"(.*)\"!\\1!" .`, ""},
+		{"order", "NAPTR", "[{naptr: {preference: 10}}]", "", "naptr: order is required"},
+		{"preference", "NAPTR", "[{naptr: {order: 1, preference: 70000}}]", "",
+			"naptr: preference must be an integer between 0 and 65535"},
+		{"flags", "NAPTR", "[{naptr: {order: 1, preference: 1, flags: 'U!'}}]", "",
+			`naptr: flags "U!" must be letters and digits`},
+		{"both", "NAPTR", "[{naptr: {order: 1, preference: 1, regexp: '!a!b!', replacement: example.com.}}]", "",
+			"naptr: regexp and replacement cannot both be set"},
+		{"relative replacement", "NAPTR", "[{naptr: {order: 1, preference: 1, replacement: example.com}}]", "",
+			`naptr: replacement "example.com" must be a fully qualified name`},
+		{"delimiters", "NAPTR", "[{naptr: {order: 1, preference: 1, regexp: '!a!b'}}]", "",
+			`naptr: regexp "!a!b" must be: !regexp!replacement! with an optional i flag`},
+		{"invalid regexp", "NAPTR", "[{naptr: {order: 1, preference: 1, regexp: '!(a!b!'}}]", "",
+			`naptr: regexp "!(a!b!": error parsing regexp`},
+		{"type", "TXT", "[{naptr: {order: 1, preference: 1}}]", "", "naptr is only supported for NAPTR records"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parse([]byte(`
+zones:
+  example.com:
+    nameservers: [ns1.example.com.]
+    rrsets:
+      - {name: "@", type: `+tt.rtype+`, records: `+tt.records+`}
+`), "")
+			if tt.err != "" {
+				if err == nil {
+					err = cfg.Validate(nil)
+				}
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("Expected error %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse failed: %v", err)
+			}
+			if errs := cfg.Validate(nil); errs != nil {
+				t.Fatalf("Validate failed: %v", errs)
+			}
+			zone := cfg.Zones["example.com"]
+			rrsets, err := zone.NormalizeRRsets()
+			if err != nil {
+				t.Fatalf("NormalizeRRsets failed: %v", err)
+			}
+			if got := rrsets[0].Records[0].Content; got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRecordContent(t *testing.T) {
+	tests := []struct {
+		rtype, content, expected string
+	}{
+		{"TXT", "v=spf1 -all", `"v=spf1 -all"`},
+		{"TXT", `"already quoted"`, `"already quoted"`},
+		{"TXT", `say "hi" \ bye`, `"say \"hi\" \\ bye"`},
+		{"TXT", "caf\u00e9\tbar", `"caf\195\169\009bar"`},
+		{"A", "192.0.2.1", "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		if got := RecordContent(tt.rtype, tt.content); got != tt.expected {
+			t.Errorf("RecordContent(%s, %q) = %s, expected %s", tt.rtype, tt.content, got, tt.expected)
+		}
+	}
+}
+
+func TestValidate_CommentControlCharacters(t *testing.T) {
+	tests := []struct {
+		name    string
+		records string
+		comment string
+		err     string
+	}{
+		{"valid", "192.0.2.1", "web server", ""},
+		{"rrset", "192.0.2.1", "web\nowner=other", "www/A): comment cannot contain line breaks"},
+		{"record", "[{content: 192.0.2.1, comment: \"web\\towner\"}]", "",
+			"record[0]: comment cannot contain line breaks"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Zones: map[string]Zone{"example.com": {
+				Nameservers: []string{"ns1.example.com."},
+				RRsets:      []RRsetInput{{Name: "www", Type: "A", Comment: tt.comment}},
+			}}}
+			var records interface{}
+			if err := yaml.Unmarshal([]byte(tt.records), &records); err != nil {
+				t.Fatal(err)
+			}
+			cfg.Zones["example.com"].RRsets[0].Records = records
+			errs := cfg.Validate(map[string]ZoneState{})
+			if tt.err == "" {
+				if errs != nil {
+					t.Errorf("Validate failed: %v", errs)
+				}
+				return
+			}
+			if errs == nil || !strings.Contains(errs.Error(), tt.err) {
+				t.Errorf("Expected error %q, got %v", tt.err, errs)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Sources of the TTL of an explained rrset.
+const (
+	// TTLFromRRset is the ttl of the rrset or of its delegation
+	TTLFromRRset = "ttl"
+	// TTLFromMigration is the lowered TTL of an active migration
+	TTLFromMigration = "migration"
+	// TTLFromSettings is the default TTL set by SetDefaultTTL, e.g. from the
+	// default_ttl of the project settings
+	TTLFromSettings = "default_ttl"
+	// TTLFromDefault is DefaultTTL
+	TTLFromDefault = "default"
+)
+
+// Explanation is a configured rrset as it is sent to PowerDNS, with where
+// its values come from, see Zone.Explain.
+type Explanation struct {
+	// RRset has the fully qualified name and the contents as they are sent,
+	// e.g. quoted TXT contents
+	RRset
+	// Source is the key the rrset is declared with: rrsets, nameservers, the
+	// a, aaaa and mx shorthand keys, or a delegation, e.g. delegations[0] for
+	// its NS rrset and "delegations[0] glue" for its glue rrsets
+	Source string
+	// TTLSource is where the TTL comes from, one of the TTLFrom constants
+	TTLSource string
+	// Absent is set for rrsets with state absent, which are deleted
+	Absent bool
+}
+
+// Explain returns the rrsets of the zone with the given name and type, or
+// all of them for empty ones, normalized like they are applied: the name
+// is fully qualified, defaults, shorthand keys and delegations are expanded
+// and TXT contents quoted. Names may be relative to the zone or fully
+// qualified; both are matched case-insensitively. zoneName is the name of
+// the zone in the configuration.
+//
+// The NS rrset of the nameservers is only applied to new and managed zones,
+// and PTR rrsets generated for set_ptr records are not included.
+func (z *Zone) Explain(zoneName, name, rtype string) ([]Explanation, error) {
+	zoneID := CanonicalZoneName(zoneName)
+	inputs, err := z.ExpandedRRsets()
+	if err != nil {
+		return nil, err
+	}
+
+	var explanations []Explanation
+	if len(z.Nameservers) > 0 {
+		records := make([]Record, len(z.Nameservers))
+		for i, ns := range z.Nameservers {
+			records[i] = Record{Content: QualifyName(ns, zoneID)}
+		}
+		explanations = append(explanations, Explanation{
+			RRset: RRset{
+				Name:       zoneID,
+				Type:       "NS",
+				TTL:        z.DefaultTTL(),
+				Records:    records,
+				ApplyAfter: z.ApplyAfterTime(),
+				Labels:     z.Labels,
+				Location:   z.NameserversLocation(),
+			},
+			Source:    "nameservers",
+			TTLSource: z.defaultTTLSource(),
+		})
+	}
+
+	for i := range inputs {
+		input := &inputs[i]
+		explanation := Explanation{Source: "rrsets", Absent: input.State == StateAbsent}
+		if input.shorthand != "" {
+			explanation.Source = input.shorthand
+		}
+		if explanation.Absent {
+			explanation.RRset = z.absentRRset(input)
+		} else if explanation.RRset, explanation.TTLSource, err = z.normalizeRRset(input); err != nil {
+			return nil, err
+		}
+		explanation.Name = QualifyName(input.Name, zoneID)
+		for j := range explanation.Records {
+			explanation.Records[j].Content = RecordContent(explanation.Type, explanation.Records[j].Content)
+		}
+		explanations = append(explanations, explanation)
+	}
+
+	matched := explanations[:0]
+	for _, explanation := range explanations {
+		if name != "" && !strings.EqualFold(explanation.Name, QualifyName(name, zoneID)) {
+			continue
+		}
+		if rtype != "" && !strings.EqualFold(explanation.Type, rtype) {
+			continue
+		}
+		matched = append(matched, explanation)
+	}
+	if len(matched) == 0 && (name != "" || rtype != "") {
+		return nil, fmt.Errorf("no rrset %s in zone %s", strings.TrimSpace(name+" "+strings.ToUpper(rtype)), zoneID)
+	}
+	return matched, nil
+}
@@ -0,0 +1,197 @@
+package config
+
+import (
+	"crypto/sha1" //nolint:gosec // SHA-1 is SSHFP fingerprint type 1, not used for security here
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// sshfpAlgorithms are the SSHFP algorithm numbers of OpenSSH key types.
+var sshfpAlgorithms = map[string]int{
+	"ssh-rsa":             1,
+	"ssh-dss":             2,
+	"ecdsa-sha2-nistp256": 3,
+	"ecdsa-sha2-nistp384": 3,
+	"ecdsa-sha2-nistp521": 3,
+	"ssh-ed25519":         4,
+	"ssh-ed448":           6,
+}
+
+// tlsaContent returns the content of a TLSA record computed from a PEM
+// certificate file, so that the record follows the certificate when it is
+// renewed:
+//
+//	tlsa: {cert_file: certs/www.pem, usage: 3, selector: 1, matching: 1}
+//
+// usage, selector and matching default to 3 1 1 (DANE-EE, public key,
+// SHA-256). Relative paths are resolved in dir.
+func tlsaContent(m map[string]interface{}, dir string) (string, error) {
+	opts, err := helperOptions(m, "tlsa")
+	if err != nil {
+		return "", err
+	}
+	usage, err := intOption(opts, "usage", 3, 3)
+	if err != nil {
+		return "", err
+	}
+	selector, err := intOption(opts, "selector", 1, 1)
+	if err != nil {
+		return "", err
+	}
+	matching, err := intOption(opts, "matching", 1, 2)
+	if err != nil {
+		return "", err
+	}
+	data, err := readOption(opts, "cert_file", dir)
+	if err != nil {
+		return "", err
+	}
+
+	var block *pem.Block
+	for rest := data; ; {
+		if block, rest = pem.Decode(rest); block == nil || block.Type == "CERTIFICATE" {
+			break
+		}
+	}
+	if block == nil {
+		return "", fmt.Errorf("cert_file %s has no PEM certificate", opts["cert_file"])
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", fmt.Errorf("cert_file %s: %w", opts["cert_file"], err)
+	}
+
+	selected := cert.Raw
+	if selector == 1 {
+		selected = cert.RawSubjectPublicKeyInfo
+	}
+	var digest []byte
+	switch matching {
+	case 0:
+		digest = selected
+	case 1:
+		sum := sha256.Sum256(selected)
+		digest = sum[:]
+	default:
+		sum := sha512.Sum512(selected)
+		digest = sum[:]
+	}
+	return fmt.Sprintf("%d %d %d %s", usage, selector, matching, hex.EncodeToString(digest)), nil
+}
+
+// sshfpContent returns the content of an SSHFP record computed from an
+// OpenSSH public host key file, so that the record follows the key when it
+// is rotated:
+//
+//	sshfp: {key_file: keys/ssh_host_ed25519_key.pub, fingerprint: 2}
+//
+// The algorithm is that of the key, fingerprint is 1 (SHA-1) or 2 (SHA-256,
+// the default). Relative paths are resolved in dir.
+func sshfpContent(m map[string]interface{}, dir string) (string, error) {
+	opts, err := helperOptions(m, "sshfp")
+	if err != nil {
+		return "", err
+	}
+	fingerprint, err := intOption(opts, "fingerprint", 2, 2)
+	if err != nil {
+		return "", err
+	}
+	if fingerprint == 0 {
+		return "", fmt.Errorf("fingerprint must be 1 (SHA-1) or 2 (SHA-256)")
+	}
+	data, err := readOption(opts, "key_file", dir)
+	if err != nil {
+		return "", err
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return "", fmt.Errorf("key_file %s is not an OpenSSH public key", opts["key_file"])
+	}
+	algorithm, ok := sshfpAlgorithms[fields[0]]
+	if !ok {
+		return "", fmt.Errorf("key_file %s has unsupported key type %q", opts["key_file"], fields[0])
+	}
+	key, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return "", fmt.Errorf("key_file %s is not an OpenSSH public key", opts["key_file"])
+	}
+
+	var digest []byte
+	if fingerprint == 1 {
+		sum := sha1.Sum(key) //nolint:gosec // SSHFP fingerprint type 1
+		digest = sum[:]
+	} else {
+		sum := sha256.Sum256(key)
+		digest = sum[:]
+	}
+	return fmt.Sprintf("%d %d %s", algorithm, fingerprint, hex.EncodeToString(digest)), nil
+}
+
+// helperOptions returns the options of a record helper key, e.g. tlsa.
+func helperOptions(m map[string]interface{}, key string) (map[string]interface{}, error) {
+	if _, ok := m["content"]; ok {
+		return nil, fmt.Errorf("cannot be combined with content")
+	}
+	opts, ok := m[key].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("must be a mapping of options")
+	}
+	return opts, nil
+}
+
+// intOption returns an integer option between 0 and maxValue, or def if it
+// is not set.
+func intOption(opts map[string]interface{}, key string, def, maxValue int) (int, error) {
+	value, ok := opts[key]
+	if !ok {
+		return def, nil
+	}
+	n, ok := intValue(value, maxValue)
+	if !ok {
+		return 0, fmt.Errorf("%s must be an integer between 0 and %d", key, maxValue)
+	}
+	return n, nil
+}
+
+// intValue returns value as an integer between 0 and maxValue. Quoted
+// numbers (e.g. "10") are accepted, as YAML keeps them strings.
+func intValue(value interface{}, maxValue int) (int, bool) {
+	n, ok := value.(int)
+	if s, isString := value.(string); isString {
+		var err error
+		n, err = strconv.Atoi(s)
+		ok = err == nil
+	}
+	return n, ok && n >= 0 && n <= maxValue
+}
+
+// readOption reads the file of a path option, resolved in dir if relative.
+// dir is empty for configurations that are not loaded from a file, which
+// cannot read files.
+func readOption(opts map[string]interface{}, key, dir string) ([]byte, error) {
+	path, ok := opts[key].(string)
+	if !ok || path == "" {
+		return nil, fmt.Errorf("%s is required", key)
+	}
+	if dir == "" {
+		return nil, fmt.Errorf("%s can only be used in configurations loaded from a file", key)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	data, err := os.ReadFile(path) //nolint:gosec // path is from the configuration
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	return data, nil
+}
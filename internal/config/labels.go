@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// labelKeyPattern matches valid label keys, e.g. team or cost-center.
+var labelKeyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// validateLabels checks the keys and values of labels.
+func validateLabels(labels map[string]string) error {
+	for _, key := range SortedLabelKeys(labels) {
+		if !labelKeyPattern.MatchString(key) {
+			return fmt.Errorf("invalid label key %q", key)
+		}
+		if strings.ContainsAny(labels[key], "\r\n") {
+			return fmt.Errorf("label %s: value cannot contain line breaks", key)
+		}
+	}
+	return nil
+}
+
+// MergeLabels returns the labels of base overridden by those of override,
+// nil if both are empty. Neither map is modified.
+func MergeLabels(base, override map[string]string) map[string]string {
+	if len(base)+len(override) == 0 {
+		return nil
+	}
+	merged := make(map[string]string, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
+
+// SortedLabelKeys returns the keys of labels in sorted order.
+func SortedLabelKeys(labels map[string]string) []string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// FormatLabels formats labels as "key=value" pairs sorted by key, e.g.
+// "service=web, team=core". It is empty if there are no labels.
+func FormatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for _, k := range SortedLabelKeys(labels) {
+		pairs = append(pairs, k+"="+labels[k])
+	}
+	return strings.Join(pairs, ", ")
+}
@@ -0,0 +1,212 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Location is the position of a zone, rrset or delegation in the
+// configuration source, used to point errors at the offending entry.
+type Location struct {
+	// File is the configuration source, empty if unknown.
+	File string
+	// Path is the key path of the entry, e.g. zones.example.com.rrsets[3].
+	Path string
+	// Line and Column are 1-based; zero if the entry was not loaded from YAML.
+	Line   int
+	Column int
+}
+
+// String formats the location as file:line:column followed by the path.
+func (l Location) String() string {
+	pos := l.Position()
+	switch {
+	case pos == "":
+		return l.Path
+	case l.Path == "":
+		return pos
+	default:
+		return pos + " " + l.Path
+	}
+}
+
+// Position formats the position as file:line:column, or line:column if the
+// source is unknown. It is empty if the entry was not loaded from YAML.
+func (l Location) Position() string {
+	if l.Line == 0 {
+		return ""
+	}
+	pos := fmt.Sprintf("%d:%d", l.Line, l.Column)
+	if l.File != "" {
+		pos = l.File + ":" + pos
+	}
+	return pos
+}
+
+// Location returns the location of the zone in the configuration source.
+func (z *Zone) Location() Location {
+	return z.loc
+}
+
+// UnmarshalYAML decodes a zone and records its position and the positions
+// of its keys.
+func (z *Zone) UnmarshalYAML(value *yaml.Node) error {
+	type plain Zone
+	if err := value.Decode((*plain)(z)); err != nil {
+		return err
+	}
+	z.loc = Location{Line: value.Line, Column: value.Column}
+	z.keys = make(map[string]Location)
+	if value.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(value.Content); i += 2 {
+			key := value.Content[i]
+			z.keys[key.Value] = Location{Line: key.Line, Column: key.Column}
+		}
+	}
+	return nil
+}
+
+// UnmarshalYAML decodes an rrset and records its position.
+func (r *RRsetInput) UnmarshalYAML(value *yaml.Node) error {
+	type plain RRsetInput
+	if err := value.Decode((*plain)(r)); err != nil {
+		return err
+	}
+	r.loc = Location{Line: value.Line, Column: value.Column}
+	return nil
+}
+
+// UnmarshalYAML decodes a delegation and records its position. Glue
+// nameservers are lowercased, as names are case-insensitive.
+func (d *Delegation) UnmarshalYAML(value *yaml.Node) error {
+	type plain Delegation
+	if err := value.Decode((*plain)(d)); err != nil {
+		return err
+	}
+	d.loc = Location{Line: value.Line, Column: value.Column}
+	if d.Glue != nil {
+		glue := make(map[string][]string, len(d.Glue))
+		for host, addresses := range d.Glue {
+			host = strings.ToLower(host)
+			glue[host] = append(glue[host], addresses...)
+		}
+		d.Glue = glue
+	}
+	return nil
+}
+
+// locate sets the source and key paths of the locations of the zone and its
+// rrsets and delegations. name is the zone key in the configuration.
+func (z *Zone) locate(file, name string) {
+	z.loc.File, z.loc.Path = file, "zones."+name
+	for i := range z.RRsets {
+		loc := &z.RRsets[i].loc
+		loc.File, loc.Path = file, fmt.Sprintf("%s.rrsets[%d]", z.loc.Path, i)
+	}
+	for i := range z.Delegations {
+		loc := &z.Delegations[i].loc
+		loc.File, loc.Path = file, fmt.Sprintf("%s.delegations[%d]", z.loc.Path, i)
+	}
+}
+
+// at returns the location of a key of the zone, e.g. its nameservers, or
+// the location of the zone if the key is not set.
+func (z *Zone) at(key string) Location {
+	loc := z.loc
+	if pos, ok := z.keys[key]; ok {
+		loc.Line, loc.Column = pos.Line, pos.Column
+	}
+	if loc.Path != "" {
+		loc.Path += "." + key
+	}
+	return loc
+}
+
+// NameserversLocation returns the location of the zone nameservers.
+func (z *Zone) NameserversLocation() Location {
+	return z.at("nameservers")
+}
+
+// checkDuplicateZones reports a zone that is defined twice in the zones
+// mapping of a YAML document, also under names that only differ in case or
+// the trailing dot, with the positions of both keys. doc is the number of the
+// document in the source.
+func checkDuplicateZones(document *yaml.Node, source string, doc int) error {
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 {
+		return nil
+	}
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		zones := root.Content[i+1]
+		if root.Content[i].Value != "zones" || zones.Kind != yaml.MappingNode {
+			continue
+		}
+		seen := make(map[string]Location, len(zones.Content)/2)
+		for j := 0; j+1 < len(zones.Content); j += 2 {
+			key := zones.Content[j]
+			loc := Location{File: source, Line: key.Line, Column: key.Column}
+			canonical := strings.ToLower(CanonicalZoneName(key.Value))
+			if first, ok := seen[canonical]; ok {
+				return fmt.Errorf("zone %q is defined twice in document %d (%s, first at %s)",
+					key.Value, doc, loc.Position(), first.Position())
+			}
+			seen[canonical] = loc
+		}
+	}
+	return nil
+}
+
+// checkTTLOverflow reports a ttl key of a YAML document whose number value
+// is negative or does not fit in 32 bits, with its position. Such values
+// fail decoding, the in range values are checked by Validate. doc is the
+// number of the document in the source.
+func checkTTLOverflow(node *yaml.Node, source string, doc int) error {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value != "ttl" || value.Kind != yaml.ScalarNode || fitsUint32(value) {
+				continue
+			}
+			loc := Location{File: source, Line: value.Line, Column: value.Column}
+			return fmt.Errorf("ttl must be between 1 and %d, got %s in document %d (%s)",
+				MaxTTL, value.Value, doc, loc.Position())
+		}
+	}
+	for _, child := range node.Content {
+		if err := checkTTLOverflow(child, source, doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fitsUint32 reports whether a scalar node can be decoded into a uint32: it
+// is not a number, which fails decoding with a type error, or a number from
+// 0 to math.MaxUint32. Integers too large for int64 resolve to floats.
+func fitsUint32(value *yaml.Node) bool {
+	var n float64
+	switch value.ShortTag() {
+	case "!!int":
+		i, err := strconv.ParseInt(value.Value, 0, 64)
+		if err != nil {
+			return false
+		}
+		n = float64(i)
+	case "!!float":
+		f, err := strconv.ParseFloat(value.Value, 64)
+		if err != nil {
+			return true
+		}
+		n = f
+	default:
+		return true
+	}
+	return n >= 0 && n <= math.MaxUint32
+}
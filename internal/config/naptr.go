@@ -0,0 +1,143 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// naptrFlagsPattern and naptrServicePattern match the flags and services of
+// NAPTR records, e.g. U and E2U+sip.
+var (
+	naptrFlagsPattern   = regexp.MustCompile(`^[A-Za-z0-9]*$`)
+	naptrServicePattern = regexp.MustCompile(`^[A-Za-z0-9+:._-]*$`)
+)
+
+// naptrContent returns the content of a NAPTR record in the structured form:
+//
+//	naptr:
+//	  order: 100
+//	  preference: 10
+//	  flags: U
+//	  service: E2U+sip
+//	  regexp: '!^\+(.*)$!sip:\1@example.com!'
+//
+// is `100 10 "U" "E2U+sip" "!^\\+(.*)$!sip:\\1@example.com!" .`. The strings
+// are written as they are used, quotes and backslashes are escaped in the
+// content. flags, service and regexp default to empty, replacement to ".";
+// only one of regexp and replacement can be set (RFC 3403).
+func naptrContent(m map[string]interface{}) (string, error) {
+	opts, err := helperOptions(m, "naptr")
+	if err != nil {
+		return "", err
+	}
+	for _, key := range []string{"order", "preference"} {
+		if _, ok := opts[key]; !ok {
+			return "", fmt.Errorf("%s is required", key)
+		}
+	}
+	order, err := intOption(opts, "order", 0, 65535)
+	if err != nil {
+		return "", err
+	}
+	preference, err := intOption(opts, "preference", 0, 65535)
+	if err != nil {
+		return "", err
+	}
+
+	fields := make(map[string]string, 4)
+	for _, key := range []string{"flags", "service", "regexp", "replacement"} {
+		value, ok := opts[key]
+		if !ok {
+			continue
+		}
+		s, ok := value.(string)
+		if !ok {
+			return "", fmt.Errorf("%s must be a string", key)
+		}
+		fields[key] = s
+	}
+	switch {
+	case !naptrFlagsPattern.MatchString(fields["flags"]):
+		return "", fmt.Errorf("flags %q must be letters and digits, e.g. U or S", fields["flags"])
+	case !naptrServicePattern.MatchString(fields["service"]):
+		return "", fmt.Errorf("service %q must be letters, digits and + : . _ -, e.g. E2U+sip", fields["service"])
+	}
+	if err := checkNAPTRRegexp(fields["regexp"]); err != nil {
+		return "", err
+	}
+
+	replacement := fields["replacement"]
+	switch {
+	case replacement == "":
+		replacement = "."
+	case replacement != "." && fields["regexp"] != "":
+		return "", fmt.Errorf("regexp and replacement cannot both be set")
+	case !strings.HasSuffix(replacement, ".") || strings.ContainsAny(replacement, " \t\"\\"):
+		return "", fmt.Errorf("replacement %q must be a fully qualified name (end with a dot), or \".\"", replacement)
+	}
+
+	return fmt.Sprintf("%d %d %s %s %s %s", order, preference, CharacterString(fields["flags"]),
+		CharacterString(fields["service"]), CharacterString(fields["regexp"]), replacement), nil
+}
+
+// checkNAPTRRegexp checks a substitution expression of a NAPTR record:
+// delimiter, extended regular expression, delimiter, replacement, delimiter
+// and optional i flag, e.g. !^.*$!sip:info@example.com!.
+func checkNAPTRRegexp(expr string) error {
+	if expr == "" {
+		return nil
+	}
+	delim := expr[0]
+	if delim == '\\' || delim == 'i' || delim >= '0' && delim <= '9' {
+		return fmt.Errorf("regexp %q: delimiter %q cannot be a digit, a backslash or i", expr, delim)
+	}
+
+	// The parts between unescaped delimiters
+	var parts []string
+	var part strings.Builder
+	for i := 1; i < len(expr); i++ {
+		switch c := expr[i]; {
+		case c == '\\' && i+1 < len(expr):
+			i++
+			if expr[i] != delim {
+				part.WriteByte(c)
+			}
+			part.WriteByte(expr[i])
+		case c == delim:
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteByte(c)
+		}
+	}
+	if len(parts) != 2 || (part.Len() > 0 && part.String() != "i") {
+		return fmt.Errorf("regexp %q must be: %cregexp%creplacement%c with an optional i flag",
+			expr, delim, delim, delim)
+	}
+	if _, err := regexp.Compile(parts[0]); err != nil {
+		return fmt.Errorf("regexp %q: %w", expr, err)
+	}
+	return nil
+}
+
+// CharacterString returns s as a quoted DNS character-string (RFC 1035,
+// section 5.1): quotes and backslashes are escaped with a backslash, control
+// characters and bytes outside of ASCII as \DDD, like PowerDNS returns them.
+func CharacterString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&b, "\\%03d", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Partition is the part of a monorepo configuration owned by a team. Its
+// zones are loaded from its own configuration files and applied with its own
+// account and API credentials:
+//
+//	partitions:
+//	  payments:
+//	    account: team-payments
+//	    paths: [teams/payments]
+//	    zones: [pay.example.com, "*.pay.example.com"]
+//	    api_key_env: PDNS_API_KEY_PAYMENTS
+type Partition struct {
+	// Account marks the managed zones and rrsets of the partition. The
+	// configuration files of the partition may only set the same account.
+	Account string `yaml:"account"`
+	// APIURL is the PowerDNS API URL of the partition, default: the API URL
+	// of the run
+	APIURL string `yaml:"api_url,omitempty"`
+	// APIKeyEnv is the environment variable holding the API key of the
+	// partition, default: the API key of the run
+	APIKeyEnv string `yaml:"api_key_env,omitempty"`
+	// Paths are configuration files, or directories whose .yml and .yaml
+	// files are loaded recursively, relative to the partitions file
+	Paths []string `yaml:"paths"`
+	// Zones are glob patterns of the zone names the partition may configure,
+	// e.g. "*.pay.example.com"; empty allows any zone
+	Zones []string `yaml:"zones,omitempty"`
+}
+
+// PartitionNames returns the names of the partitions in sorted order.
+func (c *Config) PartitionNames() []string {
+	names := make([]string, 0, len(c.Partitions))
+	for name := range c.Partitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadPartitions loads and merges the configuration files of every partition.
+// dir is the directory the paths of the partitions are relative to. A zone can
+// only be configured by one partition.
+func (c *Config) LoadPartitions(dir string) (map[string]*Config, error) {
+	configs := make(map[string]*Config, len(c.Partitions))
+	// Canonical zone name -> partition that configures it
+	owners := make(map[string]zoneOrigin)
+	for _, name := range c.PartitionNames() {
+		p := c.Partitions[name]
+		cfg, err := p.load(dir)
+		if err != nil {
+			return nil, fmt.Errorf("partition %s: %w", name, err)
+		}
+		for zoneName, zone := range cfg.Zones {
+			canonical := strings.ToLower(CanonicalZoneName(zoneName))
+			if owner, ok := owners[canonical]; ok {
+				return nil, fmt.Errorf("partition %s: zone %s is already configured by partition %s (%s, first at %s)",
+					name, zoneName, owner.where, zone.Location().Position(), owner.loc.Position())
+			}
+			owners[canonical] = zoneOrigin{where: name, loc: zone.Location()}
+		}
+		configs[name] = cfg
+	}
+	return configs, nil
+}
+
+// load loads and merges the configuration files of the partition.
+func (p *Partition) load(dir string) (*Config, error) {
+	if p.Account == "" {
+		return nil, errors.New("account is required")
+	}
+	if len(p.Paths) == 0 {
+		return nil, errors.New("paths is required")
+	}
+	patterns := make([]string, len(p.Zones))
+	for i, pattern := range p.Zones {
+		patterns[i] = strings.ToLower(CanonicalZoneName(pattern))
+		if _, err := path.Match(patterns[i], ""); err != nil {
+			return nil, fmt.Errorf("invalid zone pattern %q: %w", pattern, err)
+		}
+	}
+
+	files, err := p.files(dir)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{Account: p.Account, Zones: make(map[string]Zone)}
+	origins := make(map[string]zoneOrigin)
+	h := sha256.New()
+	for _, file := range files {
+		part, err := LoadFromFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", file, err)
+		}
+		if len(part.Partitions) > 0 {
+			return nil, fmt.Errorf("%s: partitions cannot be nested", file)
+		}
+		for zoneName, zone := range part.Zones {
+			if !matchesAny(patterns, strings.ToLower(CanonicalZoneName(zoneName))) {
+				return nil, fmt.Errorf("%s: zone %s is not in the zones of the partition (%s)",
+					zone.Location().Position(), zoneName, strings.Join(p.Zones, ", "))
+			}
+		}
+		if err := cfg.merge(part, file, origins); err != nil {
+			return nil, err
+		}
+		h.Write([]byte(part.hash))
+	}
+	cfg.hash = hex.EncodeToString(h.Sum(nil)[:8])
+	return cfg, nil
+}
+
+// files returns the configuration files of the partition in a stable order.
+func (p *Partition) files(dir string) ([]string, error) {
+	var files []string
+	for _, entry := range p.Paths {
+		root := entry
+		if !filepath.IsAbs(root) {
+			root = filepath.Join(dir, root)
+		}
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, fmt.Errorf("paths: %w", err)
+		}
+		if !info.IsDir() {
+			files = append(files, root)
+			continue
+		}
+		var found []string
+		err = filepath.WalkDir(root, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			// Hidden files and directories, e.g. settings and history files
+			if strings.HasPrefix(d.Name(), ".") && file != root {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if ext := filepath.Ext(file); !d.IsDir() && (ext == ".yml" || ext == ".yaml") {
+				found = append(found, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("paths: %w", err)
+		}
+		if len(found) == 0 {
+			return nil, fmt.Errorf("paths: no configuration files in %s", root)
+		}
+		sort.Strings(found)
+		files = append(files, found...)
+	}
+	return files, nil
+}
+
+// matchesAny returns true if patterns is empty or name matches any of them.
+func matchesAny(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok { //nolint:errcheck // patterns are validated
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes files relative to dir, creating their directories.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+const testPartitions = `partitions:
+  payments:
+    account: team-payments
+    paths: [teams/payments]
+    zones: [pay.example.com, "*.pay.example.com"]
+    api_key_env: PDNS_API_KEY_PAYMENTS
+  web:
+    account: team-web
+    paths: [web.yml]
+`
+
+func TestConfig_LoadPartitions(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"teams/payments/pay.yml":         "zones:\n  pay.example.com: {}\n",
+		"teams/payments/eu/eu.yaml":      "account: team-payments\nzones:\n  eu.pay.example.com: {}\n",
+		"teams/payments/.pdns-zm.yaml":   "strict_names: true\n",
+		"teams/payments/README.md":       "not a config",
+		"web.yml":                        "zones:\n  example.com: {}\n",
+		"teams/payments/.hidden/old.yml": "zones:\n  old.example.com: {}\n",
+	})
+
+	cfg, err := parse([]byte(testPartitions), "partitions.yml")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.PartitionNames(), []string{"payments", "web"}) {
+		t.Errorf("Unexpected partitions %v", cfg.PartitionNames())
+	}
+	configs, err := cfg.LoadPartitions(dir)
+	if err != nil {
+		t.Fatalf("LoadPartitions failed: %v", err)
+	}
+
+	payments := configs["payments"]
+	if payments.Account != "team-payments" || len(payments.Zones) != 2 || payments.Hash() == "" {
+		t.Errorf("Unexpected payments config: account %q, zones %v", payments.Account, payments.Zones)
+	}
+	zone := payments.Zones["eu.pay.example.com"]
+	if loc := zone.Location(); loc.File != filepath.Join(dir, "teams/payments/eu/eu.yaml") || loc.Line != 3 {
+		t.Errorf("Unexpected location %v", loc)
+	}
+	if web := configs["web"]; web.Account != "team-web" || len(web.Zones) != 1 {
+		t.Errorf("Unexpected web config: account %q, zones %v", web.Account, web.Zones)
+	}
+}
+
+func TestConfig_LoadPartitions_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		partitions string
+		files      map[string]string
+		wantErr    string
+	}{
+		{
+			name:       "no account",
+			partitions: "partitions:\n  a:\n    paths: [a.yml]\n",
+			wantErr:    "partition a: account is required",
+		},
+		{
+			name:       "other account",
+			partitions: "partitions:\n  a:\n    account: team-a\n    paths: [a.yml]\n",
+			files:      map[string]string{"a.yml": "account: team-b\nzones:\n  a.com: {}\n"},
+			wantErr:    `account "team-b" in ` + "%s/a.yml conflicts with account \"team-a\"",
+		},
+		{
+			name:       "zone outside of the partition",
+			partitions: "partitions:\n  a:\n    account: team-a\n    paths: [a.yml]\n    zones: ['*.a.com']\n",
+			files:      map[string]string{"a.yml": "zones:\n  b.com: {}\n"},
+			wantErr:    "zone b.com is not in the zones of the partition (*.a.com)",
+		},
+		{
+			name: "zone in two partitions",
+			partitions: "partitions:\n  a:\n    account: team-a\n    paths: [a.yml]\n" +
+				"  b:\n    account: team-b\n    paths: [b.yml]\n",
+			files: map[string]string{
+				"a.yml": "zones:\n  shared.com: {}\n",
+				"b.yml": "zones:\n  Shared.com.: {}\n",
+			},
+			wantErr: "partition b: zone Shared.com. is already configured by partition a " +
+				"(%s/b.yml:2:16, first at %s/a.yml:2:15)",
+		},
+		{
+			name:       "empty directory",
+			partitions: "partitions:\n  a:\n    account: team-a\n    paths: [a]\n",
+			files:      map[string]string{"a/README.md": ""},
+			wantErr:    "no configuration files in",
+		},
+		{
+			name:       "nested partitions",
+			partitions: "partitions:\n  a:\n    account: team-a\n    paths: [a.yml]\n",
+			files:      map[string]string{"a.yml": "partitions:\n  b:\n    account: team-b\n"},
+			wantErr:    "partitions cannot be nested",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			cfg, err := parse([]byte(tt.partitions), "partitions.yml")
+			if err != nil {
+				t.Fatalf("parse failed: %v", err)
+			}
+			want := tt.wantErr
+			if strings.Contains(want, "%s") {
+				want = strings.ReplaceAll(want, "%s", dir)
+			}
+			if _, err := cfg.LoadPartitions(dir); err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected error containing %q, got %v", want, err)
+			}
+		})
+	}
+}
+
+func TestParse_PartitionsWithZones(t *testing.T) {
+	_, err := parse([]byte(testPartitions+"---\nzones:\n  example.com: {}\n"), "")
+	if err == nil || !strings.Contains(err.Error(), "a configuration with partitions cannot define zones") {
+		t.Errorf("Expected partitions with zones error, got %v", err)
+	}
+	_, err = parse([]byte(testPartitions+"---\n"+testPartitions), "")
+	if err == nil || !strings.Contains(err.Error(), `partition "payments" in document 2 is already defined`) {
+		t.Errorf("Expected duplicate partition error, got %v", err)
+	}
+}
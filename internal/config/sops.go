@@ -0,0 +1,102 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sopsCommand is the sops binary that decrypts encrypted configuration files.
+// It finds the keys, e.g. age keys in SOPS_AGE_KEY_FILE, on its own.
+var sopsCommand = "sops"
+
+// isSopsEncrypted reports whether data is a YAML file encrypted with sops:
+// a document has the sops metadata, a top-level "sops" mapping with a MAC.
+func isSopsEncrypted(data []byte) bool {
+	if !bytes.HasPrefix(data, []byte("sops:")) && !bytes.Contains(data, []byte("\nsops:")) {
+		return false
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var node yaml.Node
+		if err := decoder.Decode(&node); err != nil {
+			// Invalid YAML is reported by parse
+			return false
+		}
+		if len(node.Content) == 0 || node.Content[0].Kind != yaml.MappingNode {
+			continue
+		}
+		root := node.Content[0]
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if root.Content[i].Value != "sops" || root.Content[i+1].Kind != yaml.MappingNode {
+				continue
+			}
+			metadata := root.Content[i+1]
+			for j := 0; j+1 < len(metadata.Content); j += 2 {
+				if metadata.Content[j].Value == "mac" {
+					return true
+				}
+			}
+		}
+	}
+}
+
+// sopsTimeout bounds a sops run, which may ask a key service over the network.
+const sopsTimeout = time.Minute
+
+// decryptSops decrypts a sops-encrypted YAML configuration with the sops
+// binary. source is the name of the configuration source for errors.
+func decryptSops(data []byte, source string) ([]byte, error) {
+	path, err := exec.LookPath(sopsCommand)
+	if err != nil {
+		return nil, fmt.Errorf("%s is encrypted with sops, but sops is not installed: %w", source, err)
+	}
+
+	// The data may not come from a file (stdin, approval bundles), so it is
+	// passed in a temporary file; it is still encrypted
+	file, err := os.CreateTemp("", "powerdns-zone-manager-*.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s with sops: %w", source, err)
+	}
+	defer func() {
+		_ = os.Remove(file.Name()) //nolint:errcheck // best effort cleanup
+	}()
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s with sops: %w", source, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sopsTimeout)
+	defer cancel()
+	c := exec.CommandContext(ctx, path, //nolint:gosec // the sops binary with fixed arguments
+		"--decrypt", "--input-type", "yaml", "--output-type", "yaml", file.Name())
+	var stdout, stderr bytes.Buffer
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	if err := c.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && ctx.Err() == nil {
+			// The last line of sops explains the failure, e.g. that no key
+			// could decrypt the data key
+			lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+			if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+				return nil, fmt.Errorf("failed to decrypt %s with sops: %s", source, last)
+			}
+		}
+		if ctx.Err() != nil {
+			err = fmt.Errorf("sops did not finish within %s", sopsTimeout)
+		}
+		return nil, fmt.Errorf("failed to decrypt %s with sops: %w", source, err)
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,200 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// svcbKeys are the named SvcParamKeys of SVCB and HTTPS records, indexed by
+// their key number (RFC 9460). Other keys are written as keyNNNNN.
+var svcbKeys = []string{"mandatory", "alpn", "no-default-alpn", "port", "ipv4hint", "ech", "ipv6hint"}
+
+// isSVCBRecord returns true if a record object is in the structured form of
+// SVCB and HTTPS records, with priority, target and params instead of content.
+func isSVCBRecord(m map[string]interface{}) bool {
+	for _, key := range []string{"priority", "target", "params"} {
+		if _, ok := m[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// svcbContent returns the content of a structured SVCB or HTTPS record:
+//
+//	priority: 1
+//	target: .
+//	params: {alpn: [h2, h3], ipv4hint: [192.0.2.1]}
+//
+// is "1 . alpn=h2,h3 ipv4hint=192.0.2.1". Params are written in the order of
+// their key numbers, like PowerDNS returns them, so that unchanged records
+// are not updated.
+func svcbContent(m map[string]interface{}) (string, error) {
+	if _, ok := m["content"]; ok {
+		return "", fmt.Errorf("content cannot be combined with priority, target and params")
+	}
+	priority, ok := intValue(m["priority"], 65535)
+	if !ok {
+		return "", fmt.Errorf("priority must be an integer between 0 and 65535")
+	}
+	target, ok := m["target"].(string)
+	if !ok || target == "" {
+		return "", fmt.Errorf("target must be a non-empty string")
+	}
+	if target != "." && !strings.HasSuffix(target, ".") {
+		return "", fmt.Errorf("target %q must be fully qualified (end with a dot), or \".\" for the owner name",
+			target)
+	}
+
+	var params map[string]interface{}
+	if p, ok := m["params"]; ok {
+		if params, ok = p.(map[string]interface{}); !ok {
+			return "", fmt.Errorf("params must be a mapping of parameter names to values")
+		}
+	}
+	if priority == 0 && len(params) > 0 {
+		return "", fmt.Errorf("params cannot be set with priority 0 (alias mode)")
+	}
+
+	type param struct {
+		number int
+		text   string
+	}
+	list := make([]param, 0, len(params))
+	for key, value := range params {
+		number, ok := svcbKeyNumber(key)
+		switch {
+		case !ok:
+			return "", fmt.Errorf("unknown param %q, must be one of %s or keyNNNNN",
+				key, strings.Join(svcbKeys, ", "))
+		case number < len(svcbKeys) && key != svcbKeys[number]:
+			return "", fmt.Errorf("param %s must be written as %s", key, svcbKeys[number])
+		}
+		text, err := svcbParam(key, value, params)
+		if err != nil {
+			return "", fmt.Errorf("params: %s: %w", key, err)
+		}
+		list = append(list, param{number: number, text: text})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].number < list[j].number })
+
+	content := strconv.Itoa(priority) + " " + target
+	for _, p := range list {
+		content += " " + p.text
+	}
+	return content, nil
+}
+
+// svcbKeyNumber returns the key number of a param name, a name of svcbKeys
+// or the generic keyNNNNN form.
+func svcbKeyNumber(key string) (int, bool) {
+	for i, name := range svcbKeys {
+		if key == name {
+			return i, true
+		}
+	}
+	if digits, ok := strings.CutPrefix(key, "key"); ok && digits != "" {
+		if number, err := strconv.ParseUint(digits, 10, 16); err == nil {
+			return int(number), true
+		}
+	}
+	return 0, false
+}
+
+// svcbParam returns a param in presentation format, e.g. alpn=h2,h3, and
+// checks its value. params are all params of the record.
+func svcbParam(key string, value interface{}, params map[string]interface{}) (string, error) {
+	switch key {
+	case "no-default-alpn":
+		if b, ok := value.(bool); !ok || !b {
+			return "", fmt.Errorf("must be true")
+		}
+		if _, ok := params["alpn"]; !ok {
+			return "", fmt.Errorf("requires alpn")
+		}
+		return key, nil
+	case "port":
+		port, ok := value.(int)
+		if !ok || port < 0 || port > 65535 {
+			return "", fmt.Errorf("must be an integer between 0 and 65535")
+		}
+		return key + "=" + strconv.Itoa(port), nil
+	}
+
+	values, err := svcbValues(value)
+	if err != nil {
+		return "", err
+	}
+	if len(values) > 1 && (key == "ech" || strings.HasPrefix(key, "key")) {
+		return "", fmt.Errorf("must be a single value")
+	}
+	for _, v := range values {
+		if err := checkSVCBValue(key, v, params); err != nil {
+			return "", err
+		}
+	}
+	return key + "=" + strings.Join(values, ","), nil
+}
+
+// svcbValues returns the values of a param given as a string or a list of
+// strings.
+func svcbValues(value interface{}) ([]string, error) {
+	var items []interface{}
+	switch v := value.(type) {
+	case string:
+		items = []interface{}{v}
+	case []interface{}:
+		items = v
+	default:
+		return nil, fmt.Errorf("must be a string or a list of strings")
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("must have at least one value")
+	}
+
+	values := make([]string, len(items))
+	for i, item := range items {
+		s, ok := item.(string)
+		if !ok || s == "" {
+			return nil, fmt.Errorf("value[%d] must be a non-empty string", i)
+		}
+		if strings.ContainsAny(s, ", \t\"\\") {
+			return nil, fmt.Errorf("value %q cannot contain commas, whitespace, quotes or backslashes", s)
+		}
+		values[i] = s
+	}
+	return values, nil
+}
+
+// checkSVCBValue checks a single value of a param.
+func checkSVCBValue(key, value string, params map[string]interface{}) error {
+	switch key {
+	case "mandatory":
+		number, ok := svcbKeyNumber(value)
+		switch {
+		case !ok:
+			return fmt.Errorf("unknown param %q", value)
+		case number == 0:
+			return fmt.Errorf("cannot list mandatory itself")
+		case params[value] == nil:
+			return fmt.Errorf("lists %s, which is not set", value)
+		}
+	case "ipv4hint":
+		if ip := net.ParseIP(value); ip == nil || ip.To4() == nil {
+			return fmt.Errorf("%q is not an IPv4 address", value)
+		}
+	case "ipv6hint":
+		if ip := net.ParseIP(value); ip == nil || ip.To4() != nil {
+			return fmt.Errorf("%q is not an IPv6 address", value)
+		}
+	case "ech":
+		if _, err := base64.StdEncoding.DecodeString(value); err != nil {
+			return fmt.Errorf("%q is not base64", value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+// Package confirm provides the confirmation providers asked before apply
+// sends changes: an interactive terminal prompt, a non-interactive provider
+// that always fails, Slack interactive approval and an OPA policy that can
+// approve changes itself or hand them on to another provider.
+package confirm
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/i18n"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+// Provider names.
+const (
+	ProviderTerminal = "terminal"
+	ProviderFail     = "fail"
+	ProviderSlack    = "slack"
+	ProviderOPA      = "opa"
+)
+
+// Providers are the names of the built-in providers.
+var Providers = []string{ProviderTerminal, ProviderFail, ProviderSlack, ProviderOPA}
+
+// defaultHTTPClient is used by the providers without an HTTP client of their
+// own. Its timeout keeps an unresponsive server from stalling the apply.
+var defaultHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// ErrConfirmationRequired is returned by Fail.
+var ErrConfirmationRequired = errors.New("changes require confirmation, but confirmation is not available " +
+	"(use --auto-confirm to apply without confirmation)")
+
+// Terminal asks for confirmation on a terminal.
+type Terminal struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// NewTerminal creates a provider that prints prompts to out and reads the
+// answers from in.
+func NewTerminal(in io.Reader, out io.Writer) *Terminal {
+	return &Terminal{in: bufio.NewReader(in), out: out}
+}
+
+// Confirm implements manager.Confirmer. Only "y" and "yes", or yes in the
+// current language (see i18n.IsYes), confirm.
+func (t *Terminal) Confirm(_ context.Context, req *manager.ConfirmRequest) (bool, error) {
+	fmt.Fprintf(t.out, "%s %s: ", req.Prompt, i18n.T("[y/N]"))
+	response, err := t.in.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || response == "") {
+		return false, nil
+	}
+	return i18n.IsYes(response), nil
+}
+
+// Fail fails every confirmation, for non-interactive runs that must not
+// apply changes without an explicit --auto-confirm.
+type Fail struct{}
+
+// Confirm implements manager.Confirmer.
+func (Fail) Confirm(context.Context, *manager.ConfirmRequest) (bool, error) {
+	return false, ErrConfirmationRequired
+}
+
+// Input is the description of a confirmation request passed to policies and
+// shown in approval messages.
+type Input struct {
+	Zone     string           `json:"zone"`
+	RunID    string           `json:"runId,omitempty"`
+	Changes  []ChangeInput    `json:"changes"`
+	Metadata []MetadataChange `json:"metadata"`
+	Summary  Summary          `json:"summary"`
+}
+
+// ChangeInput is an rrset change of Input.
+type ChangeInput struct {
+	// Labels are the config labels of the rrset
+	Labels map[string]string `json:"labels,omitempty"`
+	Action string            `json:"action"`
+	Name   string            `json:"name"`
+	Type   string            `json:"type"`
+	Before []string          `json:"before,omitempty"`
+	After  []string          `json:"after,omitempty"`
+	OldTTL uint32            `json:"oldTtl,omitempty"`
+	NewTTL uint32            `json:"newTtl,omitempty"`
+}
+
+// MetadataChange is a metadata change of Input.
+type MetadataChange struct {
+	Kind   string   `json:"kind"`
+	Before []string `json:"before,omitempty"`
+	After  []string `json:"after,omitempty"`
+}
+
+// Summary counts the rrset changes of Input by action.
+type Summary struct {
+	Creates  int `json:"creates"`
+	Updates  int `json:"updates"`
+	Deletes  int `json:"deletes"`
+	Metadata int `json:"metadata"`
+}
+
+// NewInput describes a confirmation request.
+func NewInput(req *manager.ConfirmRequest) *Input {
+	in := &Input{
+		Zone:     req.Zone,
+		RunID:    req.RunID,
+		Changes:  make([]ChangeInput, 0, len(req.Changes)),
+		Metadata: make([]MetadataChange, 0, len(req.Metadata)),
+	}
+	for _, change := range req.Changes {
+		in.Changes = append(in.Changes, ChangeInput{
+			Action: string(change.Action),
+			Name:   change.Name,
+			Type:   change.Type,
+			Before: contents(change.Before),
+			After:  contents(change.After),
+			OldTTL: change.OldTTL,
+			NewTTL: change.NewTTL,
+			Labels: change.Labels,
+		})
+		switch change.Action {
+		case manager.ChangeCreate:
+			in.Summary.Creates++
+		case manager.ChangeUpdate:
+			in.Summary.Updates++
+		case manager.ChangeDelete:
+			in.Summary.Deletes++
+		}
+	}
+	for _, change := range req.Metadata {
+		in.Metadata = append(in.Metadata, MetadataChange(change))
+	}
+	in.Summary.Metadata = len(req.Metadata)
+	return in
+}
+
+// contents returns the contents of records, marking disabled ones.
+func contents(records []powerdns.Record) []string {
+	if len(records) == 0 {
+		return nil
+	}
+	out := make([]string, len(records))
+	for i, rec := range records {
+		out[i] = rec.Content
+		if rec.Disabled {
+			out[i] += " (disabled)"
+		}
+	}
+	return out
+}
+
+// Text returns a plain text description of the changes, one change per line.
+func (in *Input) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Zone %s: %d create(s), %d update(s), %d delete(s), %d metadata change(s)",
+		in.Zone, in.Summary.Creates, in.Summary.Updates, in.Summary.Deletes, in.Summary.Metadata)
+	if in.RunID != "" {
+		fmt.Fprintf(&b, " (run %s)", in.RunID)
+	}
+	for _, change := range in.Changes {
+		fmt.Fprintf(&b, "\n%s %s %s", change.Action, change.Name, change.Type)
+	}
+	for _, change := range in.Metadata {
+		fmt.Fprintf(&b, "\nmetadata %s: %v -> %v", change.Kind, change.Before, change.After)
+	}
+	return b.String()
+}
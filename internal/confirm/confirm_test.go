@@ -0,0 +1,244 @@
+package confirm
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+func testRequest() *manager.ConfirmRequest {
+	return &manager.ConfirmRequest{
+		Zone:   "example.com.",
+		Prompt: "Apply these changes?",
+		RunID:  "run-1",
+		Changes: []manager.Change{
+			{Action: manager.ChangeCreate, Name: "www.example.com.", Type: "A",
+				After: []powerdns.Record{{Content: "192.0.2.1"}}},
+			{Action: manager.ChangeDelete, Name: "old.example.com.", Type: "A",
+				Before: []powerdns.Record{{Content: "192.0.2.2", Disabled: true}}},
+		},
+	}
+}
+
+func TestTerminal(t *testing.T) {
+	tests := map[string]bool{"y\n": true, "YES\n": true, "yes": true, "n\n": false, "\n": false, "": false}
+	for input, want := range tests {
+		var out bytes.Buffer
+		got, err := NewTerminal(strings.NewReader(input), &out).Confirm(context.Background(), testRequest())
+		if err != nil || got != want {
+			t.Errorf("Confirm(%q) = %v, %v, want %v", input, got, err, want)
+		}
+		if out.String() != "Apply these changes? [y/N]: " {
+			t.Errorf("Unexpected prompt %q", out.String())
+		}
+	}
+}
+
+func TestFail(t *testing.T) {
+	ok, err := (Fail{}).Confirm(context.Background(), testRequest())
+	if ok || !errors.Is(err, ErrConfirmationRequired) {
+		t.Errorf("Expected ErrConfirmationRequired, got %v, %v", ok, err)
+	}
+}
+
+func TestNewInput(t *testing.T) {
+	in := NewInput(testRequest())
+	if in.Summary != (Summary{Creates: 1, Deletes: 1}) {
+		t.Errorf("Unexpected summary %+v", in.Summary)
+	}
+	if got := in.Changes[1].Before; len(got) != 1 || got[0] != "192.0.2.2 (disabled)" {
+		t.Errorf("Unexpected contents %v", got)
+	}
+	text := in.Text()
+	if !strings.HasPrefix(text, "Zone example.com.: 1 create(s), 0 update(s), 1 delete(s)") ||
+		!strings.Contains(text, "\ndelete old.example.com. A") {
+		t.Errorf("Unexpected text %q", text)
+	}
+}
+
+// staticConfirmer returns a fixed answer and counts calls.
+type staticConfirmer struct {
+	calls int
+	ok    bool
+}
+
+func (c *staticConfirmer) Confirm(context.Context, *manager.ConfirmRequest) (bool, error) {
+	c.calls++
+	return c.ok, nil
+}
+
+func TestOPA(t *testing.T) {
+	tests := []struct {
+		name      string
+		response  string
+		want      bool
+		wantErr   string
+		fallbacks int
+	}{
+		{name: "approve", response: `{"result": {"decision": "approve"}}`, want: true},
+		{name: "deny", response: `{"result": {"decision": "deny", "reason": "deletions in prod"}}`,
+			wantErr: "denied by policy: deletions in prod"},
+		{name: "ask", response: `{"result": {"decision": "ask"}}`, want: true, fallbacks: 1},
+		{name: "undefined", response: `{}`, wantErr: "policy decision is undefined"},
+		{name: "invalid", response: `{"result": {"decision": "maybe"}}`, wantErr: `invalid policy decision "maybe"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var input Input
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body struct {
+					Input Input `json:"input"`
+				}
+				_ = json.NewDecoder(r.Body).Decode(&body) //nolint:errcheck // checked below
+				input = body.Input
+				_, _ = w.Write([]byte(tt.response)) //nolint:errcheck // test server
+			}))
+			t.Cleanup(srv.Close)
+
+			fallback := &staticConfirmer{ok: true}
+			opa := &OPA{URL: srv.URL + "/v1/data/dns/confirm", Fallback: fallback}
+			got, err := opa.Confirm(context.Background(), testRequest())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+			} else if err != nil || got != tt.want {
+				t.Fatalf("Confirm() = %v, %v, want %v", got, err, tt.want)
+			}
+			if fallback.calls != tt.fallbacks {
+				t.Errorf("Expected %d fallback call(s), got %d", tt.fallbacks, fallback.calls)
+			}
+			if input.Zone != "example.com." || input.Summary.Deletes != 1 {
+				t.Errorf("Unexpected policy input %+v", input)
+			}
+		})
+	}
+}
+
+// freeAddr returns a free local TCP address.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	_ = l.Close() //nolint:errcheck // only used to find a port
+	return addr
+}
+
+// slackClick sends a signed button click of user to the interactivity
+// listener.
+func slackClick(addr, secret, user, actionID, token string, sign bool) (int, error) {
+	payload := fmt.Sprintf(`{"user":{"id":"U0%s","username":%q},"actions":[{"action_id":%q,"value":%q}]}`,
+		strings.ToUpper(user), user, actionID, token)
+	body := url.Values{"payload": {payload}}.Encode()
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + ts + ":" + body))
+	req, err := http.NewRequest(http.MethodPost, "http://"+addr, strings.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Slack-Request-Timestamp", ts)
+	if sign {
+		req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	_ = resp.Body.Close() //nolint:errcheck // test client
+	return resp.StatusCode, nil
+}
+
+func TestSlack(t *testing.T) {
+	for _, actionID := range []string{slackApprove, slackReject} {
+		t.Run(actionID, func(t *testing.T) {
+			addr := freeAddr(t)
+			tokens := make(chan string, 1)
+			webhook := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				var msg struct {
+					Blocks []struct {
+						Elements []struct {
+							Value string `json:"value"`
+						} `json:"elements"`
+					} `json:"blocks"`
+				}
+				_ = json.NewDecoder(r.Body).Decode(&msg) //nolint:errcheck // checked by the token
+				tokens <- msg.Blocks[1].Elements[0].Value
+			}))
+			t.Cleanup(webhook.Close)
+
+			slack := &Slack{
+				WebhookURL:    webhook.URL,
+				SigningSecret: "secret",
+				Listen:        addr,
+				Timeout:       5 * time.Second,
+				Approvers:     []string{"U0BOB", "mallory"},
+			}
+			type result struct {
+				err error
+				ok  bool
+			}
+			done := make(chan result, 1)
+			go func() {
+				ok, err := slack.Confirm(context.Background(), testRequest())
+				done <- result{ok: ok, err: err}
+			}()
+
+			token := <-tokens
+			if status, err := slackClick(addr, "secret", "alice", actionID, token, false); err != nil ||
+				status != http.StatusUnauthorized {
+				t.Errorf("Expected unsigned request to be rejected, got %d, %v", status, err)
+			}
+			// A click of a channel member who is not an approver is ignored,
+			// also if the username is listed
+			if status, err := slackClick(addr, "secret", "mallory", slackApprove, token, true); err != nil ||
+				status != http.StatusOK {
+				t.Fatalf("Click failed: %d, %v", status, err)
+			}
+			select {
+			case r := <-done:
+				t.Fatalf("Expected click of a non-approver to be ignored, got %v, %v", r.ok, r.err)
+			case <-time.After(50 * time.Millisecond):
+			}
+			// bob is allowed by user ID
+			if status, err := slackClick(addr, "secret", "bob", actionID, token, true); err != nil ||
+				status != http.StatusOK {
+				t.Fatalf("Click failed: %d, %v", status, err)
+			}
+			r := <-done
+			if r.err != nil || r.ok != (actionID == slackApprove) {
+				t.Errorf("Confirm() = %v, %v after %s", r.ok, r.err, actionID)
+			}
+		})
+	}
+}
+
+func TestSlack_Timeout(t *testing.T) {
+	webhook := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	t.Cleanup(webhook.Close)
+	slack := &Slack{WebhookURL: webhook.URL, SigningSecret: "secret", Listen: freeAddr(t), Timeout: time.Millisecond}
+	if _, err := slack.Confirm(context.Background(), testRequest()); err == nil ||
+		!strings.Contains(err.Error(), "no Slack approval within") {
+		t.Errorf("Expected timeout error, got %v", err)
+	}
+}
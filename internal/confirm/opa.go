@@ -0,0 +1,121 @@
+package confirm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+)
+
+// Decisions of an OPA policy.
+const (
+	DecisionApprove = "approve"
+	DecisionDeny    = "deny"
+	DecisionAsk     = "ask"
+)
+
+// OPA evaluates an Open Policy Agent policy through the OPA REST API. The
+// policy gets the Input as its input and decides:
+//
+//	{"decision": "approve"}                   apply without asking
+//	{"decision": "deny", "reason": "..."}     reject the changes
+//	{"decision": "ask"}                       ask the Fallback provider
+//
+// e.g. to approve low-risk changes and require a human for deletions.
+type OPA struct {
+	// HTTPClient queries OPA, a client with a 30s timeout if nil
+	HTTPClient *http.Client
+	// Fallback is asked for "ask" decisions; without one they fail.
+	Fallback manager.Confirmer
+	// Logf reports the decisions, if set
+	Logf func(format string, args ...interface{})
+	// URL is the data API URL of the decision, e.g.
+	// http://localhost:8181/v1/data/dns/confirm
+	URL string
+}
+
+// opaDecision is the result of the policy.
+type opaDecision struct {
+	Decision string `json:"decision"`
+	Reason   string `json:"reason"`
+}
+
+// Confirm implements manager.Confirmer.
+func (o *OPA) Confirm(ctx context.Context, req *manager.ConfirmRequest) (bool, error) {
+	d, err := o.evaluate(ctx, NewInput(req))
+	if err != nil {
+		return false, err
+	}
+	o.logf("Policy decision for %s: %s %s", req.Zone, d.Decision, d.Reason)
+
+	switch d.Decision {
+	case DecisionApprove:
+		return true, nil
+	case DecisionDeny:
+		if d.Reason == "" {
+			d.Reason = "no reason given"
+		}
+		return false, fmt.Errorf("changes of %s denied by policy: %s", req.Zone, d.Reason)
+	case DecisionAsk:
+		if o.Fallback == nil {
+			return false, fmt.Errorf("changes of %s require approval by policy: %s", req.Zone, d.Reason)
+		}
+		return o.Fallback.Confirm(ctx, req)
+	default:
+		return false, fmt.Errorf("invalid policy decision %q, must be: %s, %s, %s",
+			d.Decision, DecisionApprove, DecisionDeny, DecisionAsk)
+	}
+}
+
+// evaluate queries the decision for the input.
+func (o *OPA) evaluate(ctx context.Context, in *Input) (*opaDecision, error) {
+	data, err := json.Marshal(map[string]interface{}{"input": in})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal policy input: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.URL, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create policy request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := o.HTTPClient
+	if client == nil {
+		client = defaultHTTPClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query policy: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close() //nolint:errcheck // best effort close
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("policy request failed with status %d: %s", resp.StatusCode, body)
+	}
+	var result struct {
+		Result *opaDecision `json:"result"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse policy response: %w", err)
+	}
+	if result.Result == nil {
+		return nil, errors.New("policy decision is undefined, check the policy path")
+	}
+	return result.Result, nil
+}
+
+func (o *OPA) logf(format string, args ...interface{}) {
+	if o.Logf != nil {
+		o.Logf(format, args...)
+	}
+}
@@ -0,0 +1,278 @@
+package confirm
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+)
+
+// Slack action IDs of the approval buttons.
+const (
+	slackApprove = "approve"
+	slackReject  = "reject"
+)
+
+// slackMaxAge is the maximum age of signed Slack requests, see verify.
+const slackMaxAge = 5 * time.Minute
+
+// maxSlackPayload is the maximum size of an interactivity request.
+const maxSlackPayload = 1 << 20
+
+// Slack asks for approval in a Slack channel: a message with Approve and
+// Reject buttons is posted to an incoming webhook, and the button clicks are
+// received on the interactivity request URL of the Slack app, which must
+// point to the Listen address (e.g. through a tunnel or ingress).
+type Slack struct {
+	// HTTPClient posts the messages, a client with a 30s timeout if nil
+	HTTPClient *http.Client
+	// WebhookURL is the incoming webhook of the approval channel.
+	WebhookURL string
+	// SigningSecret verifies that interactivity requests come from Slack.
+	SigningSecret string
+	// Listen is the address the interactivity requests are received on.
+	Listen string
+	// Timeout is how long to wait for a decision.
+	Timeout time.Duration
+	// Approvers are the Slack user IDs whose clicks count; clicks from other
+	// channel members are ignored. Usernames are not accepted since users can
+	// change them.
+	Approvers []string
+}
+
+// slackDecision is a button click.
+type slackDecision struct {
+	user     string
+	approved bool
+}
+
+// slackPayload is the part of an interactivity request that is used.
+type slackPayload struct {
+	User struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	} `json:"user"`
+	ResponseURL string `json:"response_url"`
+	Actions     []struct {
+		ActionID string `json:"action_id"`
+		Value    string `json:"value"`
+	} `json:"actions"`
+}
+
+// Confirm implements manager.Confirmer. It waits for a button click on the
+// posted message until the timeout expires.
+func (s *Slack) Confirm(ctx context.Context, req *manager.ConfirmRequest) (bool, error) {
+	token, err := newToken()
+	if err != nil {
+		return false, err
+	}
+
+	listener, err := (&net.ListenConfig{}).Listen(ctx, "tcp", s.Listen)
+	if err != nil {
+		return false, fmt.Errorf("failed to listen for Slack interactions: %w", err)
+	}
+	decisions := make(chan slackDecision, 1)
+	srv := &http.Server{
+		Handler:           s.handler(token, decisions),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		_ = srv.Serve(listener) //nolint:errcheck // stopped by Close below
+	}()
+	defer func() {
+		_ = srv.Close() //nolint:errcheck // best effort close
+	}()
+
+	text := NewInput(req).Text()
+	if err := s.post(ctx, s.WebhookURL, approvalMessage(text, token)); err != nil {
+		return false, err
+	}
+
+	timeout := time.NewTimer(s.Timeout)
+	defer timeout.Stop()
+	select {
+	case d := <-decisions:
+		return d.approved, nil
+	case <-timeout.C:
+		return false, fmt.Errorf("no Slack approval within %s", s.Timeout)
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// handler receives the interactivity requests of the message with token.
+func (s *Slack) handler(token string, decisions chan<- slackDecision) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxSlackPayload))
+		if err != nil {
+			http.Error(w, "failed to read request", http.StatusBadRequest)
+			return
+		}
+		if err := s.verify(r.Header, body, time.Now()); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		// The body is only read once, so the form is parsed from a copy
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		var payload slackPayload
+		if err := json.Unmarshal([]byte(r.PostFormValue("payload")), &payload); err != nil {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		for _, action := range payload.Actions {
+			if action.Value != token || (action.ActionID != slackApprove && action.ActionID != slackReject) {
+				continue
+			}
+			if !s.approver(payload.User.ID) {
+				s.deny(r.Context(), payload.ResponseURL)
+				break
+			}
+			d := slackDecision{user: payload.User.Username, approved: action.ActionID == slackApprove}
+			select {
+			case decisions <- d:
+				s.respond(r.Context(), payload.ResponseURL, d)
+			default:
+				// Only the first click counts
+			}
+			break
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// approver reports whether the Slack user with id may decide.
+func (s *Slack) approver(id string) bool {
+	return id != "" && slices.Contains(s.Approvers, id)
+}
+
+// verify checks the request signature of Slack, see
+// https://api.slack.com/authentication/verifying-requests-from-slack
+func (s *Slack) verify(header http.Header, body []byte, now time.Time) error {
+	ts := header.Get("X-Slack-Request-Timestamp")
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return errors.New("missing request timestamp")
+	}
+	if age := now.Sub(time.Unix(sec, 0)); age > slackMaxAge || age < -slackMaxAge {
+		return errors.New("request timestamp too old")
+	}
+	mac := hmac.New(sha256.New, []byte(s.SigningSecret))
+	mac.Write([]byte("v0:" + ts + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature"))) {
+		return errors.New("invalid request signature")
+	}
+	return nil
+}
+
+// respond replaces the approval message with the decision.
+func (s *Slack) respond(ctx context.Context, responseURL string, d slackDecision) {
+	if responseURL == "" {
+		return
+	}
+	verdict := "Rejected"
+	if d.approved {
+		verdict = "Approved"
+	}
+	msg := map[string]interface{}{
+		"replace_original": true,
+		"text":             fmt.Sprintf("%s by %s", verdict, d.user),
+	}
+	_ = s.post(ctx, responseURL, msg) //nolint:errcheck // the decision is already taken
+}
+
+// deny tells a user who is not an approver that the click was ignored,
+// visible only to that user.
+func (s *Slack) deny(ctx context.Context, responseURL string) {
+	if responseURL == "" {
+		return
+	}
+	msg := map[string]interface{}{
+		"response_type":    "ephemeral",
+		"replace_original": false,
+		"text":             "You are not an approver of these changes, the click was ignored",
+	}
+	_ = s.post(ctx, responseURL, msg) //nolint:errcheck // informational only
+}
+
+// post sends a JSON message to a Slack URL.
+func (s *Slack) post(ctx context.Context, url string, msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal Slack message: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create Slack request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := s.HTTPClient
+	if client == nil {
+		client = defaultHTTPClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post Slack message: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close() //nolint:errcheck // best effort close
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to post Slack message: status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// approvalMessage returns a Block Kit message with the changes and the
+// approval buttons, whose value identifies the request.
+func approvalMessage(text, token string) map[string]interface{} {
+	button := func(actionID, label, style string) map[string]interface{} {
+		return map[string]interface{}{
+			"type":      "button",
+			"action_id": actionID,
+			"value":     token,
+			"style":     style,
+			"text":      map[string]string{"type": "plain_text", "text": label},
+		}
+	}
+	return map[string]interface{}{
+		"text": "DNS changes need approval",
+		"blocks": []interface{}{
+			map[string]interface{}{
+				"type": "section",
+				"text": map[string]string{"type": "mrkdwn", "text": "```" + text + "```"},
+			},
+			map[string]interface{}{
+				"type": "actions",
+				"elements": []interface{}{
+					button(slackApprove, "Approve", "primary"),
+					button(slackReject, "Reject", "danger"),
+				},
+			},
+		},
+	}
+}
+
+// newToken returns a random token identifying an approval request.
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate approval token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,135 @@
+// Package dnsdist clears the packet cache of dnsdist instances through the
+// REST API of their webserver, so that changed records are not served stale
+// until their cached answers expire.
+//
+// See: https://www.dnsdist.org/guides/webserver.html
+package dnsdist
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Endpoint is a dnsdist webserver configured in the settings file:
+//
+//	dnsdist:
+//	  - url: http://edge1:8083
+//	    api_key_env: DNSDIST_API_KEY
+//	    pools: ["", resolvers]
+type Endpoint struct {
+	// URL is the address of the webserver, e.g. http://edge1:8083
+	URL string `yaml:"url"`
+	// APIKeyEnv is the environment variable holding the API key of the
+	// webserver (setWebserverConfig apiKey)
+	APIKeyEnv string `yaml:"api_key_env"`
+	// Pools are the server pools whose caches are cleared, default: the
+	// default pool ""
+	Pools []string `yaml:"pools,omitempty"`
+}
+
+// Validate checks the URL and API key variable of the endpoint.
+func (e *Endpoint) Validate() error {
+	u, err := url.Parse(e.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid url %q, must be an http or https URL", e.URL)
+	}
+	if e.APIKeyEnv == "" {
+		return fmt.Errorf("%s: api_key_env is required", e.URL)
+	}
+	return nil
+}
+
+// Client clears the caches of an endpoint.
+type Client struct {
+	// HTTPClient sends the requests, http.DefaultClient if nil
+	HTTPClient *http.Client
+	endpoint   Endpoint
+	apiKey     string
+}
+
+// NewClient creates a client of an endpoint with the API key from its
+// environment variable.
+func NewClient(endpoint Endpoint) (*Client, error) {
+	apiKey := os.Getenv(endpoint.APIKeyEnv)
+	if apiKey == "" {
+		return nil, fmt.Errorf("the API key of %s is not set in %s", endpoint.URL, endpoint.APIKeyEnv)
+	}
+	return &Client{endpoint: endpoint, apiKey: apiKey}, nil
+}
+
+// URL returns the URL of the endpoint.
+func (c *Client) URL() string {
+	return c.endpoint.URL
+}
+
+// Purge removes the cached answers for name and type from the caches of all
+// pools of the endpoint and returns the number of removed entries.
+// DELETE /api/v1/cache?pool={pool}&name={name}&type={type}
+func (c *Client) Purge(ctx context.Context, name, rtype string) (int, error) {
+	pools := c.endpoint.Pools
+	if len(pools) == 0 {
+		pools = []string{""}
+	}
+	total := 0
+	for _, pool := range pools {
+		count, err := c.purge(ctx, pool, name, rtype)
+		if err != nil {
+			return total, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// purge removes the cached answers for name and type from the cache of a pool.
+func (c *Client) purge(ctx context.Context, pool, name, rtype string) (int, error) {
+	query := url.Values{"pool": {pool}, "name": {name}, "type": {rtype}}
+	target := strings.TrimSuffix(c.endpoint.URL, "/") + "/api/v1/cache?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, target, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create cache request: %w", err)
+	}
+	req.Header.Set("X-API-Key", c.apiKey)
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear cache: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close() //nolint:errcheck // best effort close
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read cache response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("clearing cache of pool %q failed with status %d: %s",
+			pool, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var result struct {
+		Count  string `json:"count"`
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, fmt.Errorf("failed to parse cache response: %w", err)
+	}
+	if result.Status != "purged" {
+		return 0, fmt.Errorf("unexpected cache response status %q", result.Status)
+	}
+	count, err := strconv.Atoi(result.Count)
+	if err != nil {
+		return 0, fmt.Errorf("invalid count %q in cache response", result.Count)
+	}
+	return count, nil
+}
@@ -0,0 +1,83 @@
+package dnsdist
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEndpoint_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint Endpoint
+		wantErr  string
+	}{
+		{name: "valid", endpoint: Endpoint{URL: "https://edge1:8083", APIKeyEnv: "KEY"}},
+		{name: "no scheme", endpoint: Endpoint{URL: "edge1:8083", APIKeyEnv: "KEY"}, wantErr: "invalid url"},
+		{name: "no key", endpoint: Endpoint{URL: "http://edge1:8083"}, wantErr: "api_key_env is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.endpoint.Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestClient_Purge(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		requests = append(requests, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery)
+		if r.URL.Query().Get("pool") == "broken" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"No pool named broken"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"count":"2","status":"purged"}`))
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("DNSDIST_API_KEY", "secret")
+
+	client, err := NewClient(Endpoint{URL: srv.URL + "/", APIKeyEnv: "DNSDIST_API_KEY", Pools: []string{"", "edge"}})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	count, err := client.Purge(context.Background(), "www.example.com.", "A")
+	if err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	if count != 4 {
+		t.Errorf("Expected 4 purged entries, got %d", count)
+	}
+	expected := []string{
+		"DELETE /api/v1/cache?name=www.example.com.&pool=&type=A",
+		"DELETE /api/v1/cache?name=www.example.com.&pool=edge&type=A",
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected requests:\n%s", strings.Join(requests, "\n"))
+	}
+
+	client, err = NewClient(Endpoint{URL: srv.URL, APIKeyEnv: "DNSDIST_API_KEY", Pools: []string{"broken"}})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if _, err := client.Purge(context.Background(), "www.example.com.", "A"); err == nil ||
+		!strings.Contains(err.Error(), "No pool named broken") {
+		t.Errorf("Expected pool error, got %v", err)
+	}
+
+	if _, err := NewClient(Endpoint{URL: srv.URL, APIKeyEnv: "DNSDIST_UNSET_KEY"}); err == nil {
+		t.Error("Expected error for an unset API key")
+	}
+}
@@ -0,0 +1,94 @@
+// Package exporter writes zone contents to other formats.
+package exporter
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/importer"
+	"github.com/kreigan/powerdns-zone-manager/internal/ownership"
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+// CSV writes the RRsets of zones as CSV rows in the importer.CSVHeader layout.
+// SOA records are omitted. RRset comments (except ownership markers) are
+// written on the first record of each RRset.
+func CSV(w io.Writer, zones []*powerdns.Zone) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(importer.CSVHeader); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+
+	for _, zone := range zones {
+		for _, rrset := range sortedRRsets(zone) {
+			if rrset.Type == "SOA" {
+				continue
+			}
+			comment := rrsetComment(&rrset)
+			for i, rec := range rrset.Records {
+				row := []string{
+					strings.TrimSuffix(zone.Name, "."),
+					relativeName(rrset.Name, zone.Name),
+					rrset.Type,
+					strconv.FormatUint(uint64(rrset.TTL), 10),
+					rec.Content,
+					strconv.FormatBool(rec.Disabled),
+					"",
+				}
+				if i == 0 {
+					row[6] = comment
+				}
+				if err := writer.Write(row); err != nil {
+					return fmt.Errorf("failed to write CSV: %w", err)
+				}
+			}
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
+
+// sortedRRsets returns the RRsets of a zone sorted by name and type.
+func sortedRRsets(zone *powerdns.Zone) []powerdns.RRset {
+	rrsets := append([]powerdns.RRset(nil), zone.RRsets...)
+	sort.Slice(rrsets, func(i, j int) bool {
+		if rrsets[i].Name != rrsets[j].Name {
+			return rrsets[i].Name < rrsets[j].Name
+		}
+		return rrsets[i].Type < rrsets[j].Type
+	})
+	return rrsets
+}
+
+// rrsetComment joins the RRset comments, skipping ownership markers.
+func rrsetComment(rrset *powerdns.RRset) string {
+	var comments []string
+	for _, c := range rrset.Comments {
+		if marker, ok := ownership.Parse(c.Content); ok && marker.Account == c.Account {
+			continue
+		}
+		comments = append(comments, c.Content)
+	}
+	return strings.Join(comments, "; ")
+}
+
+// relativeName returns the record name relative to the zone, "@" for the apex.
+func relativeName(name, zone string) string {
+	name = strings.TrimSuffix(name, ".")
+	zone = strings.TrimSuffix(zone, ".")
+	if strings.EqualFold(name, zone) {
+		return "@"
+	}
+	if rel, ok := strings.CutSuffix(name, "."+zone); ok {
+		return rel
+	}
+	return name + "."
+}
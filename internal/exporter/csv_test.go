@@ -0,0 +1,97 @@
+package exporter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/importer"
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+func testZone() *powerdns.Zone {
+	return &powerdns.Zone{
+		Name: "example.com.",
+		RRsets: []powerdns.RRset{
+			{
+				Name: "www.example.com.",
+				Type: "A",
+				TTL:  300,
+				Records: []powerdns.Record{
+					{Content: "192.0.2.1"},
+					{Content: "192.0.2.2", Disabled: true},
+				},
+				Comments: []powerdns.Comment{
+					{Content: "web servers"},
+					{Content: "owner=zone-manager", Account: "zone-manager"},
+				},
+			},
+			{
+				Name:    "example.com.",
+				Type:    "SOA",
+				TTL:     3600,
+				Records: []powerdns.Record{{Content: "ns1.example.com. hostmaster.example.com. 1 10800 3600 604800 3600"}},
+			},
+			{
+				Name:    "example.com.",
+				Type:    "NS",
+				TTL:     3600,
+				Records: []powerdns.Record{{Content: "ns1.example.com."}},
+				Comments: []powerdns.Comment{
+					{Content: `owner=zone-manager {"v":1,"tool":"1.4.0"}`, Account: "zone-manager"},
+				},
+			},
+		},
+	}
+}
+
+func TestCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := CSV(&buf, []*powerdns.Zone{testZone()}); err != nil {
+		t.Fatalf("CSV failed: %v", err)
+	}
+
+	expected := `zone,name,type,ttl,content,disabled,comment
+example.com,@,NS,3600,ns1.example.com.,false,
+example.com,www,A,300,192.0.2.1,false,web servers
+example.com,www,A,300,192.0.2.2,true,
+`
+	if buf.String() != expected {
+		t.Errorf("Unexpected CSV output:\n%s\nwant:\n%s", buf.String(), expected)
+	}
+}
+
+func TestCSV_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := CSV(&buf, []*powerdns.Zone{testZone()}); err != nil {
+		t.Fatalf("CSV failed: %v", err)
+	}
+
+	res, err := importer.CSV(strings.NewReader(buf.String()), importer.CSVOptions{})
+	if err != nil {
+		t.Fatalf("importer.CSV failed: %v", err)
+	}
+
+	zone, ok := res.Config.Zones["example.com"]
+	if !ok {
+		t.Fatalf("Expected zone example.com, got %v", res.Config.Zones)
+	}
+	if len(zone.Nameservers) != 1 || zone.Nameservers[0] != "ns1.example.com." {
+		t.Errorf("Expected apex NS to become nameservers, got %v", zone.Nameservers)
+	}
+
+	rrsets, err := zone.NormalizeRRsets()
+	if err != nil {
+		t.Fatalf("NormalizeRRsets failed: %v", err)
+	}
+	if len(rrsets) != 1 {
+		t.Fatalf("Expected 1 rrset, got %+v", rrsets)
+	}
+	www := rrsets[0]
+	if www.Name != "www" || www.TTL != 300 || len(www.Records) != 2 {
+		t.Errorf("Unexpected rrset: %+v", www)
+	}
+	if www.Records[0].Comment != "web servers" || !www.Records[1].Disabled {
+		t.Errorf("Expected comment and disabled flag to survive, got %+v", www.Records)
+	}
+}
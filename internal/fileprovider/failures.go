@@ -0,0 +1,90 @@
+package fileprovider
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+// Failures injects simulated API errors into the calls of the provider, to
+// exercise the handling of failed applies, e.g. partial results, lock release
+// and --resume, in game days without a DNS server.
+type Failures struct {
+	// Rate is the probability, from 0 to 1, that a call fails
+	Rate float64
+	// Seed makes the failures reproducible, runs with the same seed and
+	// calls fail the same calls
+	Seed uint64
+}
+
+// ParseFailures parses a failure specification of comma-separated key=value
+// pairs, e.g. "rate=0.1" or "rate=0.25,seed=42". Without a seed, a random
+// one is picked.
+func ParseFailures(spec string) (Failures, error) {
+	f := Failures{Seed: rand.Uint64()} //nolint:gosec // simulated failures need no secure randomness
+	hasRate := false
+	for pair := range strings.SplitSeq(spec, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return f, fmt.Errorf("invalid failure setting %q, must be key=value", pair)
+		}
+		var err error
+		switch key {
+		case "rate":
+			f.Rate, err = strconv.ParseFloat(value, 64)
+			if err == nil && (f.Rate < 0 || f.Rate > 1) {
+				err = fmt.Errorf("must be between 0 and 1")
+			}
+			hasRate = true
+		case "seed":
+			f.Seed, err = strconv.ParseUint(value, 10, 64)
+		default:
+			return f, fmt.Errorf("unknown failure setting %q, must be: rate, seed", key)
+		}
+		if err != nil {
+			return f, fmt.Errorf("invalid failure %s %q: %w", key, value, err)
+		}
+	}
+	if !hasRate {
+		return f, fmt.Errorf("failure rate is required, e.g. rate=0.1")
+	}
+	return f, nil
+}
+
+// injector decides which calls fail.
+type injector struct {
+	mu   sync.Mutex
+	rate float64
+	rng  *rand.Rand
+}
+
+func newInjector(f Failures) *injector {
+	if f.Rate <= 0 {
+		return nil
+	}
+	//nolint:gosec // simulated failures need no secure randomness
+	return &injector{rate: f.Rate, rng: rand.New(rand.NewPCG(f.Seed, f.Seed))}
+}
+
+// fail returns a simulated API error for a call, or nil if it succeeds.
+func (i *injector) fail(method, path string) error {
+	if i == nil {
+		return nil
+	}
+	i.mu.Lock()
+	failed := i.rng.Float64() < i.rate
+	i.mu.Unlock()
+	if !failed {
+		return nil
+	}
+	return &powerdns.StatusError{
+		Message: fmt.Sprintf("API error (status %d): simulated failure of %s %s",
+			http.StatusServiceUnavailable, method, path),
+		StatusCode: http.StatusServiceUnavailable,
+	}
+}
@@ -0,0 +1,334 @@
+// Package fileprovider implements a DNS provider that keeps zones in local
+// JSON files instead of a DNS server. It is useful for testing configurations
+// and for offline reconciliation.
+package fileprovider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+	"github.com/kreigan/powerdns-zone-manager/internal/zonefile"
+)
+
+// Default SOA timers of created zones, matching PowerDNS defaults.
+const (
+	defaultSOATimers = "10800 3600 604800 3600"
+	defaultTTL       = 3600
+)
+
+// ErrTransferNotSupported is returned for zone transfers, which need a DNS server.
+var ErrTransferNotSupported = errors.New("zone transfers are not supported by the file provider")
+
+// ErrInvalidZoneName is returned for zone names that cannot be file names.
+var ErrInvalidZoneName = errors.New("invalid zone name, cannot contain path separators or empty labels")
+
+// Options configures the file provider.
+type Options struct {
+	// ZoneFiles also renders every written zone as an RFC 1035 master file,
+	// <dir>/<zone>.zone, for bind-style backends or archiving.
+	ZoneFiles bool
+	// Failures injects simulated API errors, the zero value none
+	Failures Failures
+}
+
+// Provider stores each zone as <dir>/<zone>.json using the PowerDNS API
+// zone representation.
+type Provider struct {
+	dir      string
+	opts     Options
+	failures *injector
+}
+
+// New creates a file provider storing zones in dir. The directory is created
+// on the first write.
+func New(dir string, opts Options) *Provider {
+	return &Provider{dir: dir, opts: opts, failures: newInjector(opts.Failures)}
+}
+
+// GetZone returns a zone with its RRsets, or nil if it does not exist.
+func (p *Provider) GetZone(_ context.Context, zoneID string) (*powerdns.Zone, error) {
+	if err := p.failures.fail("GET", "/zones/"+canonical(zoneID)); err != nil {
+		return nil, err
+	}
+	return p.load(zoneID)
+}
+
+// ListZones returns all zones in the directory, without their RRsets.
+func (p *Provider) ListZones(_ context.Context) ([]powerdns.Zone, error) {
+	if err := p.failures.fail("GET", "/zones"); err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(p.dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list zone files: %w", err)
+	}
+	zones := make([]powerdns.Zone, 0, len(paths))
+	for _, path := range paths {
+		zone, err := p.load(strings.TrimSuffix(filepath.Base(path), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		if zone == nil {
+			continue // removed since listing
+		}
+		zone.RRsets = nil
+		zones = append(zones, *zone)
+	}
+	return zones, nil
+}
+
+// GetZoneInfo returns a zone without its RRsets, or nil if it does not exist.
+func (p *Provider) GetZoneInfo(_ context.Context, zoneID string) (*powerdns.Zone, error) {
+	if err := p.failures.fail("GET", "/zones/"+canonical(zoneID)); err != nil {
+		return nil, err
+	}
+	zone, err := p.load(zoneID)
+	if zone != nil {
+		zone.RRsets = nil
+	}
+	return zone, err
+}
+
+// CreateZone creates a zone with SOA and NS RRsets, like PowerDNS does.
+func (p *Provider) CreateZone(_ context.Context, zone *powerdns.Zone) (*powerdns.Zone, error) {
+	if err := p.failures.fail("POST", "/zones"); err != nil {
+		return nil, err
+	}
+	existing, err := p.load(zone.Name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		// As the API, so that a concurrently created zone is detected
+		return nil, &powerdns.StatusError{
+			Message: fmt.Sprintf("API error (status %d): Domain '%s' already exists",
+				http.StatusConflict, canonical(zone.Name)),
+			StatusCode: http.StatusConflict,
+		}
+	}
+
+	created := *zone
+	created.Name = canonical(zone.Name)
+	created.ID = created.Name
+	created.RRsets = append([]powerdns.RRset(nil), zone.RRsets...)
+
+	primary := "a.misconfigured.dns.server.invalid."
+	if len(zone.Nameservers) > 0 {
+		primary = zone.Nameservers[0]
+
+		ns := powerdns.RRset{Name: created.Name, Type: "NS", TTL: defaultTTL}
+		for _, server := range zone.Nameservers {
+			ns.Records = append(ns.Records, powerdns.Record{Content: server})
+		}
+		created.RRsets = append(created.RRsets, ns)
+	}
+	created.RRsets = append(created.RRsets, powerdns.RRset{
+		Name: created.Name,
+		Type: "SOA",
+		TTL:  defaultTTL,
+		Records: []powerdns.Record{{
+			Content: fmt.Sprintf("%s hostmaster.%s 1 %s", primary, created.Name, defaultSOATimers),
+		}},
+	})
+	created.Nameservers = nil
+
+	if err := p.save(&created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// PatchZone replaces or deletes the RRsets in the patch. RRsets replaced
+// without comments keep their existing comments, as in PowerDNS.
+// The SOA serial is incremented unless the patch changes the SOA itself.
+func (p *Provider) PatchZone(_ context.Context, zoneID string, patch *powerdns.ZonePatch) error {
+	if err := p.failures.fail("PATCH", "/zones/"+canonical(zoneID)); err != nil {
+		return err
+	}
+	zone, err := p.load(zoneID)
+	if err != nil {
+		return err
+	}
+	if zone == nil {
+		return fmt.Errorf("zone %s does not exist", canonical(zoneID))
+	}
+
+	bumpSerial := len(patch.RRsets) > 0
+	for _, change := range patch.RRsets {
+		if change.Type == "SOA" {
+			bumpSerial = false
+		}
+		i := indexOf(zone.RRsets, change.Name, change.Type)
+		switch change.ChangeType {
+		case "DELETE":
+			if i >= 0 {
+				zone.RRsets = append(zone.RRsets[:i], zone.RRsets[i+1:]...)
+			}
+		case "REPLACE":
+			rrset := change
+			rrset.ChangeType = ""
+			if i >= 0 {
+				if rrset.Comments == nil {
+					rrset.Comments = zone.RRsets[i].Comments
+				}
+				zone.RRsets[i] = rrset
+			} else {
+				zone.RRsets = append(zone.RRsets, rrset)
+			}
+		default:
+			return fmt.Errorf("RRset %s %s: unsupported changetype %q", change.Name, change.Type, change.ChangeType)
+		}
+	}
+
+	if bumpSerial {
+		incrementSerial(zone)
+	}
+	return p.save(zone)
+}
+
+// AxfrRetrieve always fails: zone transfers need a DNS server.
+func (p *Provider) AxfrRetrieve(_ context.Context, _ string) (string, error) {
+	return "", ErrTransferNotSupported
+}
+
+// Stats returns no statistics: the file provider makes no API requests.
+func (p *Provider) Stats() []powerdns.RequestStats {
+	return nil
+}
+
+func (p *Provider) path(zoneID string) (string, error) {
+	return p.filePath(zoneID, ".json")
+}
+
+// filePath returns the file of a zone in the directory. Zone names with path
+// separators or empty labels are rejected, so that a zone name, e.g. from a
+// config posted to serve-api, cannot point outside of the directory.
+func (p *Provider) filePath(zoneID, ext string) (string, error) {
+	if strings.ContainsAny(zoneID, `/\`) || strings.Contains(zoneID, "..") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidZoneName, zoneID)
+	}
+	return filepath.Join(p.dir, strings.TrimSuffix(canonical(zoneID), ".")+ext), nil
+}
+
+func (p *Provider) load(zoneID string) (*powerdns.Zone, error) {
+	path, err := p.path(zoneID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil // Zone not found is not an error
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zone file: %w", err)
+	}
+
+	var zone powerdns.Zone
+	if err := json.Unmarshal(data, &zone); err != nil {
+		return nil, fmt.Errorf("failed to parse zone file %s: %w", path, err)
+	}
+	zone.Serial = soaSerial(&zone)
+	return &zone, nil
+}
+
+func (p *Provider) save(zone *powerdns.Zone) error {
+	sort.Slice(zone.RRsets, func(i, j int) bool {
+		if zone.RRsets[i].Name != zone.RRsets[j].Name {
+			return zone.RRsets[i].Name < zone.RRsets[j].Name
+		}
+		return zone.RRsets[i].Type < zone.RRsets[j].Type
+	})
+	zone.Serial = soaSerial(zone)
+
+	path, err := p.path(zone.Name)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(zone, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode zone: %w", err)
+	}
+	if err := os.MkdirAll(p.dir, 0o750); err != nil {
+		return fmt.Errorf("failed to create zone directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write zone file: %w", err)
+	}
+
+	if p.opts.ZoneFiles {
+		var buf bytes.Buffer
+		if err := zonefile.Write(&buf, zone); err != nil {
+			return err
+		}
+		zonePath, err := p.filePath(zone.Name, ".zone")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(zonePath, buf.Bytes(), 0o600); err != nil {
+			return fmt.Errorf("failed to write zone file: %w", err)
+		}
+	}
+	return nil
+}
+
+// incrementSerial increments the serial of the zone SOA record, if any.
+func incrementSerial(zone *powerdns.Zone) {
+	i := indexOf(zone.RRsets, zone.Name, "SOA")
+	if i < 0 || len(zone.RRsets[i].Records) == 0 {
+		return
+	}
+	rec := &zone.RRsets[i].Records[0]
+	fields := strings.Fields(rec.Content)
+	if len(fields) != 7 {
+		return
+	}
+	serial, err := strconv.ParseUint(fields[2], 10, 32)
+	if err != nil {
+		return
+	}
+	fields[2] = strconv.FormatUint(uint64(uint32(serial+1)), 10)
+	rec.Content = strings.Join(fields, " ")
+}
+
+// soaSerial returns the serial of the zone SOA record, 0 if there is none.
+func soaSerial(zone *powerdns.Zone) uint32 {
+	i := indexOf(zone.RRsets, zone.Name, "SOA")
+	if i < 0 || len(zone.RRsets[i].Records) == 0 {
+		return 0
+	}
+	fields := strings.Fields(zone.RRsets[i].Records[0].Content)
+	if len(fields) != 7 {
+		return 0
+	}
+	serial, err := strconv.ParseUint(fields[2], 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint32(serial)
+}
+
+func indexOf(rrsets []powerdns.RRset, name, rtype string) int {
+	for i := range rrsets {
+		if strings.EqualFold(rrsets[i].Name, name) && rrsets[i].Type == rtype {
+			return i
+		}
+	}
+	return -1
+}
+
+func canonical(name string) string {
+	if !strings.HasSuffix(name, ".") {
+		return name + "."
+	}
+	return name
+}
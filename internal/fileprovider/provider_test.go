@@ -0,0 +1,214 @@
+package fileprovider
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+func TestProvider_CreateAndPatch(t *testing.T) {
+	ctx := context.Background()
+	p := New(t.TempDir(), Options{})
+
+	zone, err := p.GetZone(ctx, "example.com")
+	if err != nil || zone != nil {
+		t.Fatalf("Expected missing zone, got %+v, %v", zone, err)
+	}
+
+	created, err := p.CreateZone(ctx, &powerdns.Zone{
+		Name:        "example.com.",
+		Kind:        "Native",
+		Nameservers: []string{"ns1.example.com."},
+	})
+	if err != nil {
+		t.Fatalf("CreateZone failed: %v", err)
+	}
+	if len(created.RRsets) != 2 {
+		t.Fatalf("Expected NS and SOA rrsets, got %+v", created.RRsets)
+	}
+	if _, err := p.CreateZone(ctx, &powerdns.Zone{Name: "example.com."}); err == nil {
+		t.Error("Expected error creating an existing zone")
+	}
+
+	owner := []powerdns.Comment{{Content: "owner=test", Account: "test"}}
+	err = p.PatchZone(ctx, "example.com", &powerdns.ZonePatch{RRsets: []powerdns.RRset{
+		{
+			Name: "www.example.com.", Type: "A", TTL: 300, ChangeType: "REPLACE",
+			Records: []powerdns.Record{{Content: "192.0.2.1"}}, Comments: owner,
+		},
+	}})
+	if err != nil {
+		t.Fatalf("PatchZone failed: %v", err)
+	}
+
+	// Replacing without comments keeps the existing comments
+	err = p.PatchZone(ctx, "example.com.", &powerdns.ZonePatch{RRsets: []powerdns.RRset{
+		{
+			Name: "www.example.com.", Type: "A", TTL: 600, ChangeType: "REPLACE",
+			Records: []powerdns.Record{{Content: "192.0.2.2"}},
+		},
+		{Name: "example.com.", Type: "NS", ChangeType: "DELETE"},
+	}})
+	if err != nil {
+		t.Fatalf("PatchZone failed: %v", err)
+	}
+
+	zone, err = p.GetZone(ctx, "example.com.")
+	if err != nil {
+		t.Fatalf("GetZone failed: %v", err)
+	}
+	if len(zone.RRsets) != 2 {
+		t.Fatalf("Expected SOA and www rrsets, got %+v", zone.RRsets)
+	}
+	www := zone.RRsets[1]
+	if www.Type != "A" || www.TTL != 600 || www.Records[0].Content != "192.0.2.2" || www.ChangeType != "" {
+		t.Errorf("Unexpected www rrset: %+v", www)
+	}
+	if len(www.Comments) != 1 || www.Comments[0].Account != "test" {
+		t.Errorf("Expected comments to be kept, got %+v", www.Comments)
+	}
+
+	info, err := p.GetZoneInfo(ctx, "example.com")
+	if err != nil || info == nil || info.RRsets != nil || info.Kind != "Native" {
+		t.Errorf("Unexpected zone info: %+v, %v", info, err)
+	}
+	zones, err := p.ListZones(ctx)
+	if err != nil || len(zones) != 1 || zones[0].Name != "example.com." || zones[0].RRsets != nil {
+		t.Fatalf("Unexpected zone list: %+v, %v", zones, err)
+	}
+	if zones[0].Serial != 3 {
+		t.Errorf("Expected serial 3 after two patches, got %d", zones[0].Serial)
+	}
+}
+
+func TestProvider_Errors(t *testing.T) {
+	ctx := context.Background()
+	p := New(t.TempDir(), Options{})
+
+	if err := p.PatchZone(ctx, "missing.com", &powerdns.ZonePatch{}); err == nil {
+		t.Error("Expected error patching a missing zone")
+	}
+	if _, err := p.AxfrRetrieve(ctx, "example.com"); !errors.Is(err, ErrTransferNotSupported) {
+		t.Errorf("Expected ErrTransferNotSupported, got %v", err)
+	}
+	for _, name := range []string{"../../etc/x", "a/b.com.", `a\b.com.`, "..example.com."} {
+		if _, err := p.GetZone(ctx, name); !errors.Is(err, ErrInvalidZoneName) {
+			t.Errorf("GetZone(%q): expected ErrInvalidZoneName, got %v", name, err)
+		}
+		if _, err := p.CreateZone(ctx, &powerdns.Zone{Name: name}); !errors.Is(err, ErrInvalidZoneName) {
+			t.Errorf("CreateZone(%q): expected ErrInvalidZoneName, got %v", name, err)
+		}
+	}
+}
+
+func TestProvider_ZoneFiles(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	p := New(dir, Options{ZoneFiles: true})
+
+	_, err := p.CreateZone(ctx, &powerdns.Zone{Name: "example.com.", Nameservers: []string{"ns1.example.com."}})
+	if err != nil {
+		t.Fatalf("CreateZone failed: %v", err)
+	}
+	err = p.PatchZone(ctx, "example.com.", &powerdns.ZonePatch{RRsets: []powerdns.RRset{
+		{
+			Name: "www.example.com.", Type: "A", TTL: 300, ChangeType: "REPLACE",
+			Records: []powerdns.Record{{Content: "192.0.2.1"}},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("PatchZone failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "example.com.zone"))
+	if err != nil {
+		t.Fatalf("Expected zone file: %v", err)
+	}
+	content := string(data)
+	for _, want := range []string{
+		"$ORIGIN example.com.",
+		"IN SOA    ns1.example.com. hostmaster.example.com. 2 10800 3600 604800 3600",
+		"www 300    IN A      192.0.2.1",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("Expected zone file to contain %q, got:\n%s", want, content)
+		}
+	}
+}
+
+func TestParseFailures(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Failures
+		wantErr string
+	}{
+		{spec: "rate=0.1,seed=42", want: Failures{Rate: 0.1, Seed: 42}},
+		{spec: "seed=7, rate=1", want: Failures{Rate: 1, Seed: 7}},
+		{spec: "seed=7", wantErr: "failure rate is required"},
+		{spec: "rate=1.5", wantErr: `invalid failure rate "1.5": must be between 0 and 1`},
+		{spec: "rate=x", wantErr: `invalid failure rate "x"`},
+		{spec: "rate", wantErr: "must be key=value"},
+		{spec: "rate=0.1,delay=1s", wantErr: `unknown failure setting "delay"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseFailures(tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Expected %+v, got %+v, %v", tt.want, got, err)
+			}
+		})
+	}
+}
+
+func TestProvider_Failures(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	if _, err := New(dir, Options{}).CreateZone(ctx, &powerdns.Zone{Name: "example.com."}); err != nil {
+		t.Fatalf("CreateZone failed: %v", err)
+	}
+
+	p := New(dir, Options{Failures: Failures{Rate: 1}})
+	_, err := p.GetZone(ctx, "example.com")
+	var statusErr *powerdns.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != 503 {
+		t.Fatalf("Expected a simulated 503 error, got %v", err)
+	}
+	err = p.PatchZone(ctx, "example.com", &powerdns.ZonePatch{RRsets: []powerdns.RRset{
+		{
+			Name: "www.example.com.", Type: "A", ChangeType: "REPLACE",
+			Records: []powerdns.Record{{Content: "192.0.2.1"}},
+		},
+	}})
+	if err == nil || !strings.Contains(err.Error(), "simulated failure of PATCH /zones/example.com.") {
+		t.Fatalf("Expected a simulated PATCH failure, got %v", err)
+	}
+	if zone, _ := New(dir, Options{}).GetZone(ctx, "example.com"); len(zone.RRsets) != 1 {
+		t.Errorf("Expected the failed patch to change nothing, got %+v", zone.RRsets)
+	}
+
+	// The same seed fails the same calls
+	failed := func(seed uint64) []bool {
+		p := New(dir, Options{Failures: Failures{Rate: 0.5, Seed: seed}})
+		var calls []bool
+		for range 20 {
+			_, err := p.GetZoneInfo(ctx, "example.com")
+			calls = append(calls, err != nil)
+		}
+		return calls
+	}
+	if a, b := failed(42), failed(42); !slices.Equal(a, b) || !slices.Contains(a, true) || !slices.Contains(a, false) {
+		t.Errorf("Expected the same mix of failures for the same seed, got %v and %v", a, b)
+	}
+}
@@ -0,0 +1,344 @@
+// Package graph builds a graph of the names of configured zones and the
+// references between them: delegations, nameservers, CNAME chains and MX and
+// SRV targets. References to names that are not defined in their zone are
+// marked as dangling, so that they stand out when the graph is rendered.
+package graph
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+// Supported output formats.
+const (
+	FormatDOT     = "dot"
+	FormatMermaid = "mermaid"
+)
+
+// Node is a fully qualified name in the graph.
+type Node struct {
+	Name string
+	// Zone is the most specific configured zone containing the name,
+	// empty for names outside of all configured zones.
+	Zone string
+	// Dangling is set for referenced names that are not defined in their zone.
+	Dangling bool
+}
+
+// Edge is a reference from one name to another.
+type Edge struct {
+	// Location is where the reference is configured, zero for live references.
+	Location config.Location
+	From     string
+	To       string
+	Label    string
+	// Live is set for references that only exist in the live zone state.
+	Live bool
+}
+
+// Graph is the graph of configured zones.
+type Graph struct {
+	// Zones are the configured zone names, sorted.
+	Zones []string
+	// Nodes are the names of the graph, sorted by name.
+	Nodes []Node
+	Edges []Edge
+
+	defined   map[string]bool
+	delegated []string
+	// edges are the added edges by from, to and label
+	edges map[[3]string]bool
+}
+
+// Build builds the graph of the configuration. Live zones, if any, add the
+// names and references of their current records.
+func Build(cfg *config.Config, live []*powerdns.Zone) (*Graph, error) {
+	g := &Graph{defined: make(map[string]bool), edges: make(map[[3]string]bool)}
+	names := make([]string, 0, len(cfg.Zones))
+	for name := range cfg.Zones {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		zone := cfg.Zones[name]
+		if zone.IsRecursor() {
+			continue // no records, names in it are looked up like external ones
+		}
+		zoneID := strings.ToLower(config.CanonicalZoneName(name))
+		g.Zones = append(g.Zones, zoneID)
+		g.defined[zoneID] = true
+
+		nameservers := make([]string, len(zone.Nameservers))
+		for i, ns := range zone.Nameservers {
+			nameservers[i] = strings.ToLower(config.QualifyName(ns, zoneID))
+		}
+		g.addRRset(zoneID, zoneID, "NS", nameservers, zone.NameserversLocation(), false)
+
+		rrsets, err := zone.NormalizeRRsets()
+		if err != nil {
+			return nil, fmt.Errorf("zone %s: %w", name, err)
+		}
+		for _, rrset := range rrsets {
+			contents := make([]string, len(rrset.Records))
+			for i, rec := range rrset.Records {
+				contents[i] = rec.Content
+			}
+			owner := strings.ToLower(config.QualifyName(rrset.Name, zoneID))
+			g.addRRset(zoneID, owner, rrset.Type, contents, rrset.Location, false)
+		}
+	}
+	sort.Strings(g.Zones)
+
+	for _, zone := range live {
+		zoneID := strings.ToLower(config.CanonicalZoneName(zone.Name))
+		for _, rrset := range zone.RRsets {
+			contents := make([]string, 0, len(rrset.Records))
+			for _, rec := range rrset.Records {
+				if !rec.Disabled {
+					contents = append(contents, rec.Content)
+				}
+			}
+			g.addRRset(zoneID, strings.ToLower(rrset.Name), rrset.Type, contents, config.Location{}, true)
+		}
+	}
+
+	g.resolve()
+	return g, nil
+}
+
+// addRRset defines the owner name and adds the references of the records.
+// Null MX and SRV targets (".") are not references.
+func (g *Graph) addRRset(zoneID, owner, rtype string, contents []string, loc config.Location, live bool) {
+	g.defined[owner] = true
+	rtype = strings.ToUpper(rtype)
+	for _, content := range contents {
+		fields := strings.Fields(content)
+		edge := Edge{From: owner, Location: loc, Live: live}
+		switch {
+		case rtype == "NS" && len(fields) == 1:
+			if owner != zoneID {
+				if !live {
+					g.delegated = append(g.delegated, owner)
+				}
+				g.addEdge(Edge{From: zoneID, To: owner, Label: "delegation", Location: loc, Live: live})
+			}
+			edge.To, edge.Label = strings.ToLower(config.QualifyName(fields[0], zoneID)), "NS"
+		case (rtype == "CNAME" || rtype == "ALIAS" || rtype == "DNAME") && len(fields) == 1:
+			edge.To, edge.Label = strings.ToLower(config.QualifyName(fields[0], zoneID)), rtype
+		case rtype == "MX" && len(fields) == 2 && fields[1] != ".":
+			edge.To, edge.Label = strings.ToLower(config.QualifyName(fields[1], zoneID)), "MX "+fields[0]
+		case rtype == "SRV" && len(fields) == 4 && fields[3] != ".":
+			edge.To, edge.Label = strings.ToLower(config.QualifyName(fields[3], zoneID)), "SRV :"+fields[2]
+		default:
+			continue
+		}
+		g.addEdge(edge)
+	}
+}
+
+// addEdge adds an edge unless it exists. Configured edges are added first,
+// so live edges that are configured as well are not marked as live.
+func (g *Graph) addEdge(edge Edge) {
+	key := [3]string{edge.From, edge.To, edge.Label}
+	if g.edges[key] {
+		return
+	}
+	g.edges[key] = true
+	g.Edges = append(g.Edges, edge)
+}
+
+// resolve creates the nodes of all names referenced by edges and marks
+// dangling references.
+func (g *Graph) resolve() {
+	nodes := make(map[string]bool)
+	for _, zoneID := range g.Zones {
+		nodes[zoneID] = true
+	}
+	for _, edge := range g.Edges {
+		nodes[edge.From] = true
+		nodes[edge.To] = true
+	}
+
+	for name := range nodes {
+		node := Node{Name: name, Zone: g.zoneOf(name)}
+		node.Dangling = node.Zone != "" && !g.isDefined(name) && !g.isDelegated(name, node.Zone)
+		g.Nodes = append(g.Nodes, node)
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool { return g.Nodes[i].Name < g.Nodes[j].Name })
+	sort.SliceStable(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+}
+
+// zoneOf returns the most specific configured zone containing name.
+func (g *Graph) zoneOf(name string) string {
+	best := ""
+	for _, zoneID := range g.Zones {
+		if config.IsSubdomain(name, zoneID) && len(zoneID) > len(best) {
+			best = zoneID
+		}
+	}
+	return best
+}
+
+// isDefined returns true if name has records or is covered by a wildcard.
+func (g *Graph) isDefined(name string) bool {
+	if g.defined[name] {
+		return true
+	}
+	for parent := name; strings.Contains(parent, "."); {
+		_, parent, _ = strings.Cut(parent, ".")
+		if parent != "" && g.defined["*."+parent] {
+			return true
+		}
+	}
+	return false
+}
+
+// isDelegated returns true if name is below a delegation of its zone, where
+// the zone has no authority.
+func (g *Graph) isDelegated(name, zoneID string) bool {
+	for _, child := range g.delegated {
+		if config.IsSubdomain(child, zoneID) && config.IsSubdomain(name, child) {
+			return true
+		}
+	}
+	return false
+}
+
+// Dangling returns the edges pointing to dangling names.
+func (g *Graph) Dangling() []Edge {
+	return g.edgesTo(func(node Node) bool { return node.Dangling })
+}
+
+// External returns the edges pointing to names outside of the configured zones.
+func (g *Graph) External() []Edge {
+	return g.edgesTo(func(node Node) bool { return node.Zone == "" })
+}
+
+func (g *Graph) edgesTo(match func(Node) bool) []Edge {
+	targets := make(map[string]bool)
+	for _, node := range g.Nodes {
+		if match(node) {
+			targets[node.Name] = true
+		}
+	}
+	var edges []Edge
+	for _, edge := range g.Edges {
+		if targets[edge.To] {
+			edges = append(edges, edge)
+		}
+	}
+	return edges
+}
+
+// Write renders the graph in the given format.
+func (g *Graph) Write(w io.Writer, format string) error {
+	var out strings.Builder
+	switch format {
+	case FormatDOT:
+		g.writeDOT(&out)
+	case FormatMermaid:
+		g.writeMermaid(&out)
+	default:
+		return fmt.Errorf("unsupported graph format %q, must be: %s, %s", format, FormatDOT, FormatMermaid)
+	}
+	if _, err := io.WriteString(w, out.String()); err != nil {
+		return fmt.Errorf("failed to write graph: %w", err)
+	}
+	return nil
+}
+
+// writeDOT renders the graph in the Graphviz DOT language, with a cluster per
+// zone. Dangling names are red, external names dashed and live-only
+// references dotted.
+func (g *Graph) writeDOT(out *strings.Builder) {
+	out.WriteString("digraph zones {\n  rankdir=LR;\n  node [shape=box];\n")
+	for i, zoneID := range g.Zones {
+		fmt.Fprintf(out, "  subgraph cluster_%d {\n    label=%q;\n", i, zoneID)
+		for _, node := range g.Nodes {
+			if node.Zone == zoneID {
+				fmt.Fprintf(out, "    %s\n", dotNode(node))
+			}
+		}
+		out.WriteString("  }\n")
+	}
+	for _, node := range g.Nodes {
+		if node.Zone == "" {
+			fmt.Fprintf(out, "  %s\n", dotNode(node))
+		}
+	}
+	for _, edge := range g.Edges {
+		attrs := fmt.Sprintf("label=%q", edge.Label)
+		if edge.Live {
+			attrs += ", style=dotted"
+		}
+		fmt.Fprintf(out, "  %q -> %q [%s];\n", edge.From, edge.To, attrs)
+	}
+	out.WriteString("}\n")
+}
+
+func dotNode(node Node) string {
+	switch {
+	case node.Dangling:
+		return fmt.Sprintf("%q [label=%q, color=red, fontcolor=red];", node.Name, node.Name+"\n(dangling)")
+	case node.Zone == "":
+		return fmt.Sprintf("%q [style=dashed];", node.Name)
+	default:
+		return fmt.Sprintf("%q;", node.Name)
+	}
+}
+
+// writeMermaid renders the graph as a Mermaid flowchart with a subgraph per zone.
+func (g *Graph) writeMermaid(out *strings.Builder) {
+	ids := make(map[string]string, len(g.Nodes))
+	for i, node := range g.Nodes {
+		ids[node.Name] = fmt.Sprintf("n%d", i)
+	}
+
+	out.WriteString("flowchart LR\n")
+	for i, zoneID := range g.Zones {
+		fmt.Fprintf(out, "  subgraph z%d[%q]\n", i, zoneID)
+		for _, node := range g.Nodes {
+			if node.Zone == zoneID {
+				fmt.Fprintf(out, "    %s[%q]\n", ids[node.Name], node.Name)
+			}
+		}
+		out.WriteString("  end\n")
+	}
+	var dangling, external []string
+	for _, node := range g.Nodes {
+		if node.Zone == "" {
+			fmt.Fprintf(out, "  %s[%q]\n", ids[node.Name], node.Name)
+			external = append(external, ids[node.Name])
+		}
+		if node.Dangling {
+			dangling = append(dangling, ids[node.Name])
+		}
+	}
+	for _, edge := range g.Edges {
+		arrow := "-->"
+		if edge.Live {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(out, "  %s %s|%q| %s\n", ids[edge.From], arrow, edge.Label, ids[edge.To])
+	}
+	out.WriteString("  classDef dangling stroke:#d00,color:#d00\n")
+	out.WriteString("  classDef external stroke-dasharray:5 5\n")
+	if len(dangling) > 0 {
+		fmt.Fprintf(out, "  class %s dangling\n", strings.Join(dangling, ","))
+	}
+	if len(external) > 0 {
+		fmt.Fprintf(out, "  class %s external\n", strings.Join(external, ","))
+	}
+}
@@ -0,0 +1,139 @@
+package graph
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+const testConfig = `zones:
+  example.com:
+    nameservers: [ns1.example.com.]
+    mx: 10 mail.example.com.
+    rrsets:
+      - name: www
+        type: CNAME
+        records: web.example.com.
+      - name: web
+        type: A
+        records: 192.0.2.1
+      - name: ns1
+        type: A
+        records: 192.0.2.53
+      - name: '*.apps'
+        type: A
+        records: 192.0.2.80
+      - name: shop
+        type: CNAME
+        records: shop.apps.example.com.
+      - name: ext
+        type: CNAME
+        records: cdn.example.net.
+      - name: old
+        type: CNAME
+        records: gone.dev.example.com.
+    delegations:
+      - name: dev
+        nameservers: [ns.example.net.]
+`
+
+func TestBuild(t *testing.T) {
+	cfg, err := config.LoadFromReader(strings.NewReader(testConfig))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	live := []*powerdns.Zone{{
+		Name: "example.com.",
+		RRsets: []powerdns.RRset{
+			{Name: "mail.example.com.", Type: "A", Records: []powerdns.Record{{Content: "192.0.2.25"}}},
+			{Name: "www.example.com.", Type: "CNAME", Records: []powerdns.Record{{Content: "web.example.com."}}},
+			{Name: "api.example.com.", Type: "CNAME", Records: []powerdns.Record{{Content: "app.example.com."}}},
+		},
+	}}
+
+	tests := []struct {
+		name         string
+		live         []*powerdns.Zone
+		wantDangling []string
+		wantLive     int
+	}{
+		{name: "config only", wantDangling: []string{"mail.example.com."}},
+		{name: "merged with live state", live: live, wantDangling: []string{"app.example.com."}, wantLive: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := Build(cfg, tt.live)
+			if err != nil {
+				t.Fatalf("Build failed: %v", err)
+			}
+
+			var dangling []string
+			for _, edge := range g.Dangling() {
+				dangling = append(dangling, edge.To)
+			}
+			if strings.Join(dangling, ",") != strings.Join(tt.wantDangling, ",") {
+				t.Errorf("Expected dangling %v, got %v", tt.wantDangling, dangling)
+			}
+
+			live := 0
+			for _, edge := range g.Edges {
+				if edge.Live {
+					live++
+				}
+			}
+			if live != tt.wantLive {
+				t.Errorf("Expected %d live edges, got %d: %+v", tt.wantLive, live, g.Edges)
+			}
+
+			for _, node := range g.Nodes {
+				if node.Name == "cdn.example.net." && node.Zone != "" {
+					t.Errorf("Expected external node outside of the zones, got %+v", node)
+				}
+			}
+		})
+	}
+}
+
+func TestGraph_Write(t *testing.T) {
+	cfg, err := config.LoadFromReader(strings.NewReader(testConfig))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	g, err := Build(cfg, nil)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{FormatDOT, []string{
+			`"www.example.com." -> "web.example.com." [label="CNAME"];`,
+			`"example.com." -> "dev.example.com." [label="delegation"];`,
+			`"mail.example.com." [label="mail.example.com.\n(dangling)", color=red, fontcolor=red];`,
+			`"cdn.example.net." [style=dashed];`,
+		}},
+		{FormatMermaid, []string{"flowchart LR", `subgraph z0["example.com."]`, `-->|"MX 10"|`, "dangling"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := g.Write(&buf, tt.format); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("Expected output to contain %q, got:\n%s", want, buf.String())
+				}
+			}
+		})
+	}
+
+	if err := g.Write(&bytes.Buffer{}, "svg"); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
@@ -0,0 +1,280 @@
+// Package history records the last apply of a configuration, so that later
+// runs can report what changed in the configuration since then.
+//
+// The record keeps the apply result and a snapshot of the desired state: a
+// digest of every configured rrset by zone. Comparing the snapshot with the
+// current configuration lists the zones and rrsets that were added, removed
+// or changed, e.g. for a changelog.
+package history
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+)
+
+// Version is the current record format version.
+const Version = 1
+
+// FileName is the default name of the record, next to the config file.
+const FileName = ".pdns-zm-last.json"
+
+// State is the desired state of a configuration: rrset digests by rrset
+// ("name TYPE") by canonical zone name.
+type State map[string]map[string]string
+
+// Record is the record of an apply run.
+type Record struct {
+	AppliedAt time.Time            `json:"appliedAt"`
+	Result    *manager.ApplyResult `json:"result"`
+	State     State                `json:"state"`
+	// Seen is when each rrset ("name TYPE") by zone was last configured in
+	// a recorded apply, including rrsets that were removed from the
+	// configuration since (see Merge)
+	Seen    map[string]map[string]time.Time `json:"seen,omitempty"`
+	Account string                          `json:"account"`
+	RunID   string                          `json:"runId,omitempty"`
+	// ConfigHash is the hash of the configuration source, see config.Config.Hash
+	ConfigHash string `json:"configHash,omitempty"`
+	// StateHash is the hash of State
+	StateHash string `json:"stateHash"`
+	Version   int    `json:"version"`
+}
+
+// New creates the record of an apply of cfg.
+func New(cfg *config.Config, account, runID string, result *manager.ApplyResult) (*Record, error) {
+	state, err := Snapshot(cfg)
+	if err != nil {
+		return nil, err
+	}
+	appliedAt := time.Now().UTC().Truncate(time.Second)
+	seen := make(map[string]map[string]time.Time, len(state))
+	for zoneID, digests := range state {
+		seen[zoneID] = make(map[string]time.Time, len(digests))
+		for key := range digests {
+			seen[zoneID][key] = appliedAt
+		}
+	}
+	return &Record{
+		Version:    Version,
+		AppliedAt:  appliedAt,
+		Account:    account,
+		RunID:      runID,
+		ConfigHash: cfg.Hash(),
+		StateHash:  state.Hash(),
+		State:      state,
+		Seen:       seen,
+		Result:     result,
+	}, nil
+}
+
+// Merge carries over when the rrsets of the previous record that are no
+// longer configured were last configured, so that stale rrsets can be found
+// later. Records of other accounts are not merged.
+func (r *Record) Merge(prev *Record) {
+	if prev == nil || prev.Account != r.Account {
+		return
+	}
+	for zoneID, keys := range prev.Seen {
+		for key, at := range keys {
+			if _, ok := r.Seen[zoneID][key]; ok {
+				continue
+			}
+			if r.Seen[zoneID] == nil {
+				r.Seen[zoneID] = make(map[string]time.Time)
+			}
+			r.Seen[zoneID][key] = at
+		}
+	}
+}
+
+// Forget drops an rrset from Seen, e.g. after it was deleted.
+func (r *Record) Forget(zoneID, key string) {
+	delete(r.Seen[zoneID], key)
+	if len(r.Seen[zoneID]) == 0 {
+		delete(r.Seen, zoneID)
+	}
+}
+
+// Unconfigured returns the rrsets of Seen ("name TYPE" by zone) that are not
+// configured in state and were last configured before cutoff.
+func (r *Record) Unconfigured(state State, cutoff time.Time) map[string][]string {
+	stale := make(map[string][]string)
+	for _, zoneID := range sortedKeys(r.Seen) {
+		for _, key := range sortedKeys(r.Seen[zoneID]) {
+			if _, ok := state[zoneID][key]; ok || !r.Seen[zoneID][key].Before(cutoff) {
+				continue
+			}
+			stale[zoneID] = append(stale[zoneID], key)
+		}
+	}
+	return stale
+}
+
+// Snapshot returns the desired state of cfg. The apex NS rrset of zones with
+// nameservers is included.
+func Snapshot(cfg *config.Config) (State, error) {
+	state := make(State, len(cfg.Zones))
+	for name, zone := range cfg.Zones {
+		zoneID := config.CanonicalZoneName(name)
+		rrsets, err := zone.NormalizeRRsets()
+		if err != nil {
+			return nil, fmt.Errorf("zone %s: %w", name, err)
+		}
+
+		digests := make(map[string]string, len(rrsets)+1)
+		if len(zone.Nameservers) > 0 {
+			nameservers := make([]string, len(zone.Nameservers))
+			for i, ns := range zone.Nameservers {
+				nameservers[i] = config.QualifyName(ns, zoneID)
+			}
+			digests[zoneID+" NS"] = digest(nameservers)
+		}
+		for _, rrset := range rrsets {
+			contents := []string{fmt.Sprintf("ttl=%d", rrset.TTL)}
+			for _, rec := range rrset.Records {
+				contents = append(contents, fmt.Sprintf("%s disabled=%t", rec.Content, rec.Disabled))
+			}
+			key := config.QualifyName(rrset.Name, zoneID) + " " + strings.ToUpper(rrset.Type)
+			digests[key] = digest(contents)
+		}
+		state[zoneID] = digests
+	}
+	return state, nil
+}
+
+// Hash returns a hash of the state.
+func (s State) Hash() string {
+	data, err := json.Marshal(s)
+	if err != nil {
+		// State only contains strings, marshaling cannot fail
+		panic(fmt.Sprintf("failed to marshal state: %v", err))
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// digest returns a digest of the values, independent of their order.
+func digest(values []string) string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	sum := sha256.Sum256([]byte(strings.Join(sorted, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ZoneDiff lists the rrsets ("name TYPE") of a zone that differ between two
+// states.
+type ZoneDiff struct {
+	Zone    string   `json:"zone"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+}
+
+// Diff lists the differences between two states.
+type Diff struct {
+	ZonesAdded   []string   `json:"zonesAdded,omitempty"`
+	ZonesRemoved []string   `json:"zonesRemoved,omitempty"`
+	Zones        []ZoneDiff `json:"zones,omitempty"`
+}
+
+// Empty returns true if the states are equal.
+func (d *Diff) Empty() bool {
+	return len(d.ZonesAdded) == 0 && len(d.ZonesRemoved) == 0 && len(d.Zones) == 0
+}
+
+// Compare returns the differences from the state of prev to cur. The rrsets
+// of added and removed zones are listed as added and removed as well.
+func Compare(prev, cur State) *Diff {
+	d := &Diff{}
+	for _, zoneID := range sortedKeys(cur) {
+		if _, ok := prev[zoneID]; !ok {
+			d.ZonesAdded = append(d.ZonesAdded, zoneID)
+		}
+		if zd := compareZone(zoneID, prev[zoneID], cur[zoneID]); zd != nil {
+			d.Zones = append(d.Zones, *zd)
+		}
+	}
+	for _, zoneID := range sortedKeys(prev) {
+		if _, ok := cur[zoneID]; !ok {
+			d.ZonesRemoved = append(d.ZonesRemoved, zoneID)
+			d.Zones = append(d.Zones, *compareZone(zoneID, prev[zoneID], nil))
+		}
+	}
+	sort.Slice(d.Zones, func(i, j int) bool { return d.Zones[i].Zone < d.Zones[j].Zone })
+	return d
+}
+
+func compareZone(zoneID string, prev, cur map[string]string) *ZoneDiff {
+	zd := &ZoneDiff{Zone: zoneID}
+	for _, key := range sortedKeys(cur) {
+		before, ok := prev[key]
+		switch {
+		case !ok:
+			zd.Added = append(zd.Added, key)
+		case before != cur[key]:
+			zd.Changed = append(zd.Changed, key)
+		}
+	}
+	for _, key := range sortedKeys(prev) {
+		if _, ok := cur[key]; !ok {
+			zd.Removed = append(zd.Removed, key)
+		}
+	}
+	if len(zd.Added) == 0 && len(zd.Removed) == 0 && len(zd.Changed) == 0 {
+		return nil
+	}
+	return zd
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Load reads a record from a JSON file. It returns nil without error if the
+// file does not exist.
+func Load(path string) (*Record, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path is from CLI argument
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read apply record: %w", err)
+	}
+
+	var r Record
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse apply record %s: %w", path, err)
+	}
+	if r.Version != Version {
+		return nil, fmt.Errorf("unsupported apply record version %d in %s", r.Version, path)
+	}
+	return &r, nil
+}
+
+// Save writes the record to a JSON file.
+func (r *Record) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal apply record: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write apply record: %w", err)
+	}
+	return nil
+}
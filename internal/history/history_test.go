@@ -0,0 +1,155 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+)
+
+func loadConfig(t *testing.T, data string) *config.Config {
+	t.Helper()
+	cfg, err := config.LoadFromReader(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	return cfg
+}
+
+func TestCompare(t *testing.T) {
+	prev, err := Snapshot(loadConfig(t, `zones:
+  example.com:
+    nameservers: [ns1.example.net.]
+    rrsets:
+      - name: www
+        type: A
+        records: 192.0.2.1
+      - name: old
+        type: A
+        records: 192.0.2.2
+  example.org:
+    a: 192.0.2.3
+`))
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	cur, err := Snapshot(loadConfig(t, `zones:
+  example.com:
+    nameservers: [ns1.example.net.]
+    rrsets:
+      - name: www
+        type: a
+        records: [192.0.2.1, 192.0.2.4]
+      - name: api
+        type: CNAME
+        records: www
+  example.net:
+    a: 192.0.2.5
+`))
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+
+	expected := &Diff{
+		ZonesAdded:   []string{"example.net."},
+		ZonesRemoved: []string{"example.org."},
+		Zones: []ZoneDiff{
+			{
+				Zone:    "example.com.",
+				Added:   []string{"api.example.com. CNAME"},
+				Changed: []string{"www.example.com. A"},
+				Removed: []string{"old.example.com. A"},
+			},
+			{Zone: "example.net.", Added: []string{"example.net. A"}},
+			{Zone: "example.org.", Removed: []string{"example.org. A"}},
+		},
+	}
+	if diff := Compare(prev, cur); !reflect.DeepEqual(diff, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, diff)
+	}
+	if diff := Compare(cur, cur); !diff.Empty() {
+		t.Errorf("Expected no differences, got %+v", diff)
+	}
+	if prev.Hash() == cur.Hash() {
+		t.Error("Expected different state hashes")
+	}
+}
+
+func TestRecord_Merge(t *testing.T) {
+	old := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	prev := &Record{Account: "zone-manager", Seen: map[string]map[string]time.Time{
+		"example.com.":  {"example.com. A": old, "old.example.com. TXT": old},
+		"gone.example.": {"gone.example. A": old},
+	}}
+	cfg := loadConfig(t, "zones:\n  example.com:\n    a: 192.0.2.1\n")
+	r, err := New(cfg, "zone-manager", "run-2", nil)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	r.Merge(prev)
+	r.Merge(&Record{Account: "other", Seen: map[string]map[string]time.Time{"other.example.": {"x A": old}}})
+
+	if !r.Seen["example.com."]["example.com. A"].Equal(r.AppliedAt) {
+		t.Errorf("Expected configured rrset to keep the current apply time, got %v", r.Seen)
+	}
+	state, err := Snapshot(cfg)
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	expected := map[string][]string{
+		"example.com.":  {"old.example.com. TXT"},
+		"gone.example.": {"gone.example. A"},
+	}
+	if stale := r.Unconfigured(state, old.Add(time.Hour)); !reflect.DeepEqual(stale, expected) {
+		t.Errorf("Expected %v, got %v", expected, stale)
+	}
+	if stale := r.Unconfigured(state, old); len(stale) != 0 {
+		t.Errorf("Expected no rrsets older than the cutoff, got %v", stale)
+	}
+
+	r.Forget("gone.example.", "gone.example. A")
+	if _, ok := r.Seen["gone.example."]; ok {
+		t.Error("Expected the zone to be dropped with its last rrset")
+	}
+}
+
+func TestRecord_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	if r, err := Load(path); r != nil || err != nil {
+		t.Fatalf("Expected no record and no error for a missing file, got %v, %v", r, err)
+	}
+
+	cfg := loadConfig(t, "zones:\n  example.com:\n    a: 192.0.2.1\n")
+	result := &manager.ApplyResult{ZonesCreated: 1, RRsetsCreated: 1}
+	r, err := New(cfg, "zone-manager", "run-1", result)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := r.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.StateHash != r.StateHash || loaded.RunID != "run-1" || loaded.Result.RRsetsCreated != 1 {
+		t.Errorf("Unexpected record: %+v", loaded)
+	}
+
+	if !loaded.Seen["example.com."]["example.com. A"].Equal(r.AppliedAt) {
+		t.Errorf("Expected the configured rrset to be seen at the apply, got %v", loaded.Seen)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"version": 2}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "unsupported apply record version 2") {
+		t.Errorf("Expected version error, got %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+// Package hook runs user-defined commands that integrate the zone manager
+// with other systems, e.g. ticketing, cache purges or monitoring silences.
+//
+// A hook command is run with "sh -c" and receives a JSON document on stdin.
+// Its output is passed through; a non-zero exit status is an error.
+package hook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+)
+
+// Names of the hooks, as passed in the "hook" field of the input.
+const (
+	PreApply  = "pre_apply"
+	PostApply = "post_apply"
+)
+
+// Run runs command in dir with input encoded as JSON on stdin. Both stdout and
+// stderr of the command are written to out.
+func Run(ctx context.Context, command, dir string, input any, out io.Writer) error {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("failed to encode hook input: %w", err)
+	}
+
+	c := exec.CommandContext(ctx, "sh", "-c", command)
+	c.Dir = dir
+	c.Stdin = bytes.NewReader(data)
+	c.Stdout = out
+	c.Stderr = out
+	if err := c.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return fmt.Errorf("hook %q exited with status %d", command, exitErr.ExitCode())
+		}
+		return fmt.Errorf("hook %q failed: %w", command, err)
+	}
+	return nil
+}
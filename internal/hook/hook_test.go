@@ -0,0 +1,29 @@
+package hook
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	var out bytes.Buffer
+	input := map[string]string{"hook": PreApply}
+	if err := Run(context.Background(), `cat; pwd; echo oops >&2`, dir, input, &out); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	expected := `{"hook":"pre_apply"}` + dir + "\noops\n"
+	if out.String() != expected {
+		t.Errorf("Unexpected output %q, want %q", out.String(), expected)
+	}
+}
+
+func TestRun_Failure(t *testing.T) {
+	var out bytes.Buffer
+	err := Run(context.Background(), "exit 3", t.TempDir(), nil, &out)
+	if err == nil || !strings.Contains(err.Error(), `hook "exit 3" exited with status 3`) {
+		t.Errorf("Expected exit status error, got %v", err)
+	}
+}
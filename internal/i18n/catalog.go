@@ -0,0 +1,158 @@
+package i18n
+
+// catalogs map the English messages to their translations, by language.
+// Translations keep the verbs of the English message in the same order, and
+// every catalog has the same messages, also those that read the same.
+var catalogs = map[string]map[string]string{
+	"de": {
+		// Confirmation prompts
+		"Apply these changes?":          "Diese Änderungen anwenden?",
+		"Apply these metadata changes?": "Diese Metadatenänderungen anwenden?",
+		"Replace the recursor zone?":    "Die Recursor-Zone ersetzen?",
+		"Delete %d stale RRset(s)?":     "%d veraltete(s) RRset(s) löschen?",
+		"Restore %d zone(s)?":           "%d Zone(n) wiederherstellen?",
+		"Copy %d zone(s) to %s?":        "%d Zone(n) nach %s kopieren?",
+		"[y/N]":                         "[j/N]",
+
+		// Errors
+		"Error: %v":                 "Fehler: %v",
+		"operation aborted by user": "Vorgang vom Benutzer abgebrochen",
+		"changes require confirmation, but confirmation is not available " +
+			"(use --auto-confirm to apply without confirmation)": "Änderungen erfordern eine Bestätigung, " +
+			"die nicht verfügbar ist (--auto-confirm wendet sie ohne Bestätigung an)",
+		"zone was modified since the changes were planned, plan them again": "die Zone wurde seit der " +
+			"Planung der Änderungen geändert, planen Sie sie erneut",
+
+		// Apply summary
+		"Results:":         "Ergebnis:",
+		"Zones created:":   "Zonen erstellt:",
+		"RRsets created:":  "RRsets erstellt:",
+		"RRsets updated:":  "RRsets geändert:",
+		"RRsets deleted:":  "RRsets gelöscht:",
+		"Scheduled:":       "Geplant:",
+		"Metadata:":        "Metadaten:",
+		"Patches:":         "Patches:",
+		"%d record(s), %s": "%d Record(s), %s",
+		"Zone summary":     "Zonenübersicht",
+		"ZONE":             "ZONE",
+		"CREATED":          "ERSTELLT",
+		"UPDATED":          "GEÄNDERT",
+		"DELETED":          "GELÖSCHT",
+		"PATCH":            "PATCH",
+		"STATUS":           "STATUS",
+		"DURATION":         "DAUER",
+
+		// init
+		"Zone name (e.g. example.com)": "Zonenname (z. B. example.com)",
+		"Nameservers (e.g. ns1.example.com.,ns2.example.com.)": "Nameserver " +
+			"(z. B. ns1.example.com.,ns2.example.com.)",
+		"Apex IPv4 addresses":                         "IPv4-Adressen des Apex",
+		"Apex IPv6 addresses":                         "IPv6-Adressen des Apex",
+		"Mail exchangers (e.g. 10 mail.example.com.)": "Mailserver (z. B. 10 mail.example.com.)",
+		"Configuration for %s written to %s":          "Konfiguration für %s nach %s geschrieben",
+		"Preview the changes with: %s":                "Vorschau der Änderungen mit: %s",
+	},
+	"es": {
+		// Confirmation prompts
+		"Apply these changes?":          "¿Aplicar estos cambios?",
+		"Apply these metadata changes?": "¿Aplicar estos cambios de metadatos?",
+		"Replace the recursor zone?":    "¿Reemplazar la zona del recursor?",
+		"Delete %d stale RRset(s)?":     "¿Eliminar %d RRset(s) obsoleto(s)?",
+		"Restore %d zone(s)?":           "¿Restaurar %d zona(s)?",
+		"Copy %d zone(s) to %s?":        "¿Copiar %d zona(s) a %s?",
+		"[y/N]":                         "[s/N]",
+
+		// Errors
+		"Error: %v":                 "Error: %v",
+		"operation aborted by user": "operación cancelada por el usuario",
+		"changes require confirmation, but confirmation is not available " +
+			"(use --auto-confirm to apply without confirmation)": "los cambios requieren confirmación, " +
+			"pero la confirmación no está disponible (use --auto-confirm para aplicarlos sin confirmación)",
+		"zone was modified since the changes were planned, plan them again": "la zona se modificó " +
+			"después de planificar los cambios, vuelva a planificarlos",
+
+		// Apply summary
+		"Results:":         "Resultados:",
+		"Zones created:":   "Zonas creadas:",
+		"RRsets created:":  "RRsets creados:",
+		"RRsets updated:":  "RRsets actualizados:",
+		"RRsets deleted:":  "RRsets eliminados:",
+		"Scheduled:":       "Programados:",
+		"Metadata:":        "Metadatos:",
+		"Patches:":         "Parches:",
+		"%d record(s), %s": "%d registro(s), %s",
+		"Zone summary":     "Resumen de zonas",
+		"ZONE":             "ZONA",
+		"CREATED":          "CREADOS",
+		"UPDATED":          "ACTUALIZADOS",
+		"DELETED":          "ELIMINADOS",
+		"PATCH":            "PARCHE",
+		"STATUS":           "ESTADO",
+		"DURATION":         "DURACIÓN",
+
+		// init
+		"Zone name (e.g. example.com)": "Nombre de la zona (p. ej. example.com)",
+		"Nameservers (e.g. ns1.example.com.,ns2.example.com.)": "Servidores de nombres " +
+			"(p. ej. ns1.example.com.,ns2.example.com.)",
+		"Apex IPv4 addresses":                         "Direcciones IPv4 del ápex",
+		"Apex IPv6 addresses":                         "Direcciones IPv6 del ápex",
+		"Mail exchangers (e.g. 10 mail.example.com.)": "Servidores de correo (p. ej. 10 mail.example.com.)",
+		"Configuration for %s written to %s":          "Configuración de %s escrita en %s",
+		"Preview the changes with: %s":                "Vista previa de los cambios con: %s",
+	},
+	"fr": {
+		// Confirmation prompts
+		"Apply these changes?":          "Appliquer ces modifications ?",
+		"Apply these metadata changes?": "Appliquer ces modifications de métadonnées ?",
+		"Replace the recursor zone?":    "Remplacer la zone du recurseur ?",
+		"Delete %d stale RRset(s)?":     "Supprimer %d RRset(s) obsolète(s) ?",
+		"Restore %d zone(s)?":           "Restaurer %d zone(s) ?",
+		"Copy %d zone(s) to %s?":        "Copier %d zone(s) vers %s ?",
+		"[y/N]":                         "[o/N]",
+
+		// Errors
+		"Error: %v":                 "Erreur : %v",
+		"operation aborted by user": "opération annulée par l'utilisateur",
+		"changes require confirmation, but confirmation is not available " +
+			"(use --auto-confirm to apply without confirmation)": "les modifications doivent être confirmées, " +
+			"mais aucune confirmation n'est disponible (utilisez --auto-confirm pour les appliquer sans confirmation)",
+		"zone was modified since the changes were planned, plan them again": "la zone a été modifiée depuis " +
+			"la planification des modifications, planifiez-les à nouveau",
+
+		// Apply summary
+		"Results:":         "Résultats :",
+		"Zones created:":   "Zones créées :",
+		"RRsets created:":  "RRsets créés :",
+		"RRsets updated:":  "RRsets mis à jour :",
+		"RRsets deleted:":  "RRsets supprimés :",
+		"Scheduled:":       "Planifiés :",
+		"Metadata:":        "Métadonnées :",
+		"Patches:":         "Patchs :",
+		"%d record(s), %s": "%d enregistrement(s), %s",
+		"Zone summary":     "Résumé des zones",
+		"ZONE":             "ZONE",
+		"CREATED":          "CRÉÉS",
+		"UPDATED":          "MIS À JOUR",
+		"DELETED":          "SUPPRIMÉS",
+		"PATCH":            "PATCH",
+		"STATUS":           "STATUT",
+		"DURATION":         "DURÉE",
+
+		// init
+		"Zone name (e.g. example.com)": "Nom de la zone (p. ex. example.com)",
+		"Nameservers (e.g. ns1.example.com.,ns2.example.com.)": "Serveurs de noms " +
+			"(p. ex. ns1.example.com.,ns2.example.com.)",
+		"Apex IPv4 addresses":                         "Adresses IPv4 de l'apex",
+		"Apex IPv6 addresses":                         "Adresses IPv6 de l'apex",
+		"Mail exchangers (e.g. 10 mail.example.com.)": "Serveurs de messagerie (p. ex. 10 mail.example.com.)",
+		"Configuration for %s written to %s":          "Configuration de %s écrite dans %s",
+		"Preview the changes with: %s":                "Aperçu des modifications avec : %s",
+	},
+}
+
+// yes are the answers that confirm a prompt besides y and yes, by language.
+var yes = map[string][]string{
+	"de": {"j", "ja"},
+	"es": {"s", "si", "sí"},
+	"fr": {"o", "oui"},
+}
@@ -0,0 +1,102 @@
+// Package i18n translates the user-facing messages of the CLI: confirmation
+// prompts, summaries and the errors operators act on. Messages are looked up
+// by their English text in the catalog of the current language and fall back
+// to English if they are not translated. Log lines and JSON output are never
+// translated, so that they stay the same for tools and searches.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// English is the language of the messages in the source code.
+const English = "en"
+
+// lang is the current language, see Set.
+var lang = English
+
+// Languages returns the supported languages: English and the languages with
+// a catalog.
+func Languages() []string {
+	langs := make([]string, 0, len(catalogs))
+	for l := range catalogs {
+		langs = append(langs, l)
+	}
+	sort.Strings(langs)
+	return append([]string{English}, langs...)
+}
+
+// Lang returns the current language.
+func Lang() string {
+	return lang
+}
+
+// Set sets the current language from a language code or a locale name, e.g.
+// de or de_DE.UTF-8. It returns an error for languages without a catalog.
+func Set(locale string) error {
+	l := Parse(locale)
+	if l != English {
+		if _, ok := catalogs[l]; !ok {
+			return fmt.Errorf("unsupported language %q, must be: %s", locale, strings.Join(Languages(), ", "))
+		}
+	}
+	lang = l
+	return nil
+}
+
+// Detect returns the language of the locale environment: the first set of
+// LC_ALL, LC_MESSAGES and LANG, or English if it has no catalog.
+func Detect() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if locale := os.Getenv(name); locale != "" {
+			if l := Parse(locale); catalogs[l] != nil {
+				return l
+			}
+			return English
+		}
+	}
+	return English
+}
+
+// Parse returns the language code of a locale name: de_DE.UTF-8, de-DE and
+// de@euro are all de. The C and POSIX locales are English.
+func Parse(locale string) string {
+	l := strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(l, "_-.@"); i >= 0 {
+		l = l[:i]
+	}
+	if l == "" || l == "c" || l == "posix" {
+		return English
+	}
+	return l
+}
+
+// T translates a message to the current language and formats it with args
+// like fmt.Sprintf.
+func T(msg string, args ...interface{}) string {
+	if translated, ok := catalogs[lang][msg]; ok {
+		msg = translated
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// IsYes reports whether an answer to a confirmation prompt confirms, in the
+// current language or in English: y, yes, and e.g. j and ja in German.
+func IsYes(answer string) bool {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer == "y" || answer == "yes" {
+		return true
+	}
+	for _, word := range yes[lang] {
+		if answer == word {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package i18n
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+// setLang sets the language for a test and restores English afterwards.
+func setLang(t *testing.T, locale string) {
+	t.Helper()
+	if err := Set(locale); err != nil {
+		t.Fatalf("Set(%q) failed: %v", locale, err)
+	}
+	t.Cleanup(func() { lang = English })
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		locale   string
+		expected string
+	}{
+		{"", "en"},
+		{"C", "en"},
+		{"POSIX", "en"},
+		{"C.UTF-8", "en"},
+		{"de", "de"},
+		{"de_DE.UTF-8", "de"},
+		{"fr-CA", "fr"},
+		{"es_ES@euro", "es"},
+	}
+	for _, tt := range tests {
+		if got := Parse(tt.locale); got != tt.expected {
+			t.Errorf("Parse(%q) = %q, want %q", tt.locale, got, tt.expected)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected string
+	}{
+		{"unset", nil, "en"},
+		{"lang", map[string]string{"LANG": "de_DE.UTF-8"}, "de"},
+		{"lc_all first", map[string]string{"LC_ALL": "fr_FR.UTF-8", "LANG": "de_DE.UTF-8"}, "fr"},
+		{"lc_messages", map[string]string{"LC_MESSAGES": "es_ES", "LANG": "C"}, "es"},
+		{"without catalog", map[string]string{"LC_ALL": "ja_JP.UTF-8", "LANG": "de_DE.UTF-8"}, "en"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+				t.Setenv(name, tt.env[name])
+			}
+			if got := Detect(); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	setLang(t, "de_DE.UTF-8")
+	if Lang() != "de" {
+		t.Errorf("Expected de, got %q", Lang())
+	}
+	if err := Set("ja"); err == nil || Lang() != "de" {
+		t.Errorf("Expected an error keeping de, got %v and %q", err, Lang())
+	}
+	if got := Languages(); !slices.Equal(got, []string{"en", "de", "es", "fr"}) {
+		t.Errorf("Unexpected languages %v", got)
+	}
+}
+
+func TestT(t *testing.T) {
+	if got := T("Restore %d zone(s)?", 2); got != "Restore 2 zone(s)?" {
+		t.Errorf("Unexpected English message %q", got)
+	}
+
+	setLang(t, "de")
+	if got := T("Restore %d zone(s)?", 2); got != "2 Zone(n) wiederherstellen?" {
+		t.Errorf("Unexpected German message %q", got)
+	}
+	if got := T("Not translated %s", "yet"); got != "Not translated yet" {
+		t.Errorf("Expected the English fallback, got %q", got)
+	}
+}
+
+func TestIsYes(t *testing.T) {
+	tests := []struct {
+		lang     string
+		answer   string
+		expected bool
+	}{
+		{"en", "y", true},
+		{"en", " YES\n", true},
+		{"en", "ja", false},
+		{"en", "", false},
+		{"de", "j", true},
+		{"de", "Ja", true},
+		{"de", "y", true},
+		{"de", "n", false},
+		{"es", "sí", true},
+		{"fr", "oui", true},
+		{"fr", "non", false},
+	}
+	for _, tt := range tests {
+		setLang(t, tt.lang)
+		if got := IsYes(tt.answer); got != tt.expected {
+			t.Errorf("IsYes(%q) in %s = %v, want %v", tt.answer, tt.lang, got, tt.expected)
+		}
+	}
+}
+
+// TestCatalogs checks that the catalogs translate the same messages, and that
+// translations have the verbs of the English messages in the same order.
+func TestCatalogs(t *testing.T) {
+	verbs := regexp.MustCompile(`%[a-z]`)
+	for l, catalog := range catalogs {
+		for other, otherCatalog := range catalogs {
+			for msg := range otherCatalog {
+				if _, ok := catalog[msg]; !ok {
+					t.Errorf("%s: no translation of %q, which %s has", l, msg, other)
+				}
+			}
+		}
+		for msg, translated := range catalog {
+			want, got := verbs.FindAllString(msg, -1), verbs.FindAllString(translated, -1)
+			if !slices.Equal(want, got) {
+				t.Errorf("%s: %q has verbs %v, want %v", l, translated, got, want)
+			}
+		}
+		if _, ok := yes[l]; !ok {
+			t.Errorf("%s: no yes answers", l)
+		}
+	}
+}
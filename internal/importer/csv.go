@@ -0,0 +1,102 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// CSVHeader is the column layout of CSV imports and exports.
+var CSVHeader = []string{"zone", "name", "type", "ttl", "content", "disabled", "comment"}
+
+// CSVOptions configures the CSV import.
+type CSVOptions struct {
+	// Nameservers are set on zones without apex NS rows.
+	Nameservers []string
+}
+
+// CSV converts CSV rows (see CSVHeader) into rrsets. The header row is optional.
+// Names are relative to the zone ("@" for the apex). Apex NS rows become the
+// zone nameservers; SOA rows and NS rows below the apex are skipped.
+func CSV(r io.Reader, opts CSVOptions) (*Result, error) {
+	res := &Result{}
+	b := newBuilder()
+	nameservers := make(map[string][]string)
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(CSVHeader)
+	reader.Comment = '#'
+
+	for rowNo := 1; ; rowNo++ {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		if rowNo == 1 && strings.EqualFold(row[0], CSVHeader[0]) {
+			continue
+		}
+
+		rec, err := parseCSVRow(row)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", rowNo, err)
+		}
+
+		switch {
+		case rec.rtype == "SOA":
+			res.warn("row %d: SOA records are managed by PowerDNS, skipped", rowNo)
+		case rec.rtype == "NS" && rec.name == "@":
+			nameservers[rec.zone] = append(nameservers[rec.zone], rec.content)
+			b.touch(rec.zone)
+		case rec.rtype == "NS":
+			res.warn("row %d: NS records below the zone apex are not supported, skipped %s", rowNo, rec.name)
+		default:
+			b.add(rec)
+		}
+	}
+
+	res.Config = b.config(opts.Nameservers)
+	for zone, ns := range nameservers {
+		z := res.Config.Zones[zone]
+		z.Nameservers = ns
+		res.Config.Zones[zone] = z
+	}
+	return res, nil
+}
+
+func parseCSVRow(row []string) (*record, error) {
+	rec := &record{
+		zone:    strings.TrimSuffix(strings.TrimSpace(row[0]), "."),
+		name:    strings.TrimSpace(row[1]),
+		rtype:   strings.ToUpper(strings.TrimSpace(row[2])),
+		content: row[4],
+		comment: row[6],
+	}
+	if rec.zone == "" || rec.name == "" || rec.rtype == "" {
+		return nil, errors.New("zone, name and type are required")
+	}
+
+	if ttl := strings.TrimSpace(row[3]); ttl != "" {
+		v, err := strconv.ParseUint(ttl, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ttl %q", ttl)
+		}
+		t := uint32(v)
+		rec.ttl = &t
+	}
+
+	if disabled := strings.TrimSpace(row[5]); disabled != "" {
+		v, err := strconv.ParseBool(disabled)
+		if err != nil {
+			return nil, fmt.Errorf("invalid disabled value %q", disabled)
+		}
+		rec.disabled = v
+	}
+
+	return rec, nil
+}
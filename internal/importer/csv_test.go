@@ -0,0 +1,84 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+)
+
+const testCSV = `zone,name,type,ttl,content,disabled,comment
+example.com,@,NS,3600,ns1.example.com.,,
+example.com,@,SOA,3600,ns1.example.com. hostmaster.example.com. 1 10800 3600 604800 3600,,
+example.com,www,A,300,192.0.2.1,false,web servers
+example.com,www,A,300,192.0.2.2,true,
+example.com,sub,NS,3600,ns.sub.example.com.,,
+example.org.,@,TXT,,"""v=spf1 -all""",,
+`
+
+func TestCSV(t *testing.T) {
+	res, err := CSV(strings.NewReader(testCSV), CSVOptions{Nameservers: []string{"ns.default."}})
+	if err != nil {
+		t.Fatalf("CSV failed: %v", err)
+	}
+
+	com, ok := res.Config.Zones["example.com"]
+	if !ok {
+		t.Fatalf("Expected zone example.com, got %v", res.Config.Zones)
+	}
+	if len(com.Nameservers) != 1 || com.Nameservers[0] != "ns1.example.com." {
+		t.Errorf("Expected apex NS rows as nameservers, got %v", com.Nameservers)
+	}
+	if len(com.RRsets) != 1 {
+		t.Fatalf("Expected only the www A rrset, got %+v", com.RRsets)
+	}
+	www := com.RRsets[0]
+	if www.TTL == nil || *www.TTL != 300 {
+		t.Errorf("Expected TTL 300, got %v", www.TTL)
+	}
+	records, ok := www.Records.([]interface{})
+	if !ok || len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %#v", www.Records)
+	}
+	first, ok := records[0].(map[string]interface{})
+	if !ok || first["comment"] != "web servers" {
+		t.Errorf("Expected comment on first record, got %#v", records[0])
+	}
+	second, ok := records[1].(map[string]interface{})
+	if !ok || second["disabled"] != true {
+		t.Errorf("Expected second record disabled, got %#v", records[1])
+	}
+
+	org, ok := res.Config.Zones["example.org"]
+	if !ok {
+		t.Fatalf("Expected zone example.org (trailing dot stripped), got %v", res.Config.Zones)
+	}
+	if len(org.Nameservers) != 1 || org.Nameservers[0] != "ns.default." {
+		t.Errorf("Expected default nameservers, got %v", org.Nameservers)
+	}
+	if len(org.RRsets) != 1 || org.RRsets[0].TTL != nil || org.RRsets[0].Records != `"v=spf1 -all"` {
+		t.Errorf("Unexpected example.org rrsets: %+v", org.RRsets)
+	}
+
+	if len(res.Warnings) != 2 {
+		t.Errorf("Expected SOA and sub NS warnings, got %v", res.Warnings)
+	}
+}
+
+func TestCSV_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"invalid ttl", "example.com,www,A,abc,192.0.2.1,,\n"},
+		{"invalid disabled", "example.com,www,A,300,192.0.2.1,maybe,\n"},
+		{"missing type", "example.com,www,,300,192.0.2.1,,\n"},
+		{"wrong column count", "example.com,www,A\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := CSV(strings.NewReader(tt.input), CSVOptions{}); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
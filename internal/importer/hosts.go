@@ -0,0 +1,113 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// HostsOptions configures the hosts file import.
+type HostsOptions struct {
+	// Zone is the zone that receives the A/AAAA records.
+	Zone string
+	// Nameservers are set on every generated zone.
+	Nameservers []string
+	// PTR generates PTR records in reverse zones (/24 for IPv4, /64 for IPv6).
+	PTR bool
+}
+
+// Hosts converts a hosts file into A/AAAA (and optionally PTR) rrsets.
+// Hostnames without a dot are treated as relative to the zone; names outside
+// the zone and addresses that are not global unicast (loopback, multicast,
+// link-local and unspecified addresses) are skipped with a warning.
+func Hosts(r io.Reader, opts HostsOptions) (*Result, error) {
+	res := &Result{}
+	b := newBuilder()
+	zone := strings.TrimSuffix(opts.Zone, ".")
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			res.warn("line %d: no hostname for address %s", lineNo, fields[0])
+			continue
+		}
+
+		ip := net.ParseIP(fields[0])
+		if ip == nil {
+			res.warn("line %d: invalid address %q", lineNo, fields[0])
+			continue
+		}
+		if !ip.IsGlobalUnicast() {
+			res.warn("line %d: %s is not a global unicast address, skipped", lineNo, ip)
+			continue
+		}
+
+		rtype := "A"
+		if ip.To4() == nil {
+			rtype = "AAAA"
+		}
+
+		for _, host := range fields[1:] {
+			name, ok := hostName(host, zone)
+			if !ok {
+				res.warn("line %d: %s is not in zone %s, skipped", lineNo, host, zone)
+				continue
+			}
+			b.add(&record{zone: zone, name: name, rtype: rtype, content: ip.String()})
+
+			if opts.PTR {
+				addPTR(b, ip, fqdn(name, zone))
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read hosts file: %w", err)
+	}
+
+	res.Config = b.config(opts.Nameservers)
+	return res, nil
+}
+
+// hostName returns the record name of a hosts file entry relative to zone.
+func hostName(host, zone string) (string, bool) {
+	if !strings.Contains(strings.TrimSuffix(host, "."), ".") {
+		return strings.ToLower(host), true
+	}
+	return relativeName(host, zone)
+}
+
+func fqdn(name, zone string) string {
+	if name == "@" {
+		return zone + "."
+	}
+	return name + "." + zone + "."
+}
+
+// addPTR adds a PTR record for ip to its reverse zone.
+func addPTR(b *builder, ip net.IP, target string) {
+	var zone, name string
+	if v4 := ip.To4(); v4 != nil {
+		zone = fmt.Sprintf("%d.%d.%d.in-addr.arpa", v4[2], v4[1], v4[0])
+		name = fmt.Sprintf("%d", v4[3])
+	} else {
+		const hexDigits = "0123456789abcdef"
+		nibbles := make([]string, 0, 32)
+		for i := len(ip) - 1; i >= 0; i-- {
+			nibbles = append(nibbles, string(hexDigits[ip[i]&0x0f]), string(hexDigits[ip[i]>>4]))
+		}
+		// The first 16 nibbles (reversed) are the interface identifier
+		name = strings.Join(nibbles[:16], ".")
+		zone = strings.Join(nibbles[16:], ".") + ".ip6.arpa"
+	}
+	b.add(&record{zone: zone, name: name, rtype: "PTR", content: target})
+}
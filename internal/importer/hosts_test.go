@@ -0,0 +1,131 @@
+package importer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const testHosts = `
+# comment line
+127.0.0.1   localhost
+::1         localhost ip6-localhost
+192.0.2.10  web web.example.com   # inline comment
+192.0.2.11  web
+2001:db8::10 web.example.com
+192.0.2.20  db.other.net
+not-an-ip   broken
+`
+
+func TestHosts(t *testing.T) {
+	res, err := Hosts(strings.NewReader(testHosts), HostsOptions{Zone: "example.com"})
+	if err != nil {
+		t.Fatalf("Hosts failed: %v", err)
+	}
+
+	zone, ok := res.Config.Zones["example.com"]
+	if !ok {
+		t.Fatalf("Expected zone example.com, got %v", res.Config.Zones)
+	}
+	if len(zone.RRsets) != 2 {
+		t.Fatalf("Expected web A and web AAAA rrsets, got %+v", zone.RRsets)
+	}
+
+	a := zone.RRsets[0]
+	if a.Name != "web" || a.Type != "A" {
+		t.Errorf("Expected web A first, got %s %s", a.Name, a.Type)
+	}
+	records, ok := a.Records.([]interface{})
+	if !ok || len(records) != 2 {
+		t.Errorf("Expected 2 A records (aliases of the same address deduplicated), got %#v", a.Records)
+	}
+
+	aaaa := zone.RRsets[1]
+	if aaaa.Type != "AAAA" || aaaa.Records != "2001:db8::10" {
+		t.Errorf("Unexpected AAAA rrset: %+v", aaaa)
+	}
+
+	if len(res.Warnings) != 4 {
+		t.Errorf("Expected warnings for loopback addresses, foreign name and bad address, got %v", res.Warnings)
+	}
+}
+
+func TestHosts_Stock(t *testing.T) {
+	// /etc/hosts of a fresh Debian install, with the link-local and
+	// unspecified addresses that are commonly added to it
+	const debian = `127.0.0.1	localhost
+127.0.1.1	web.example.com	web
+
+# The following lines are desirable for IPv6 capable hosts
+::1     localhost ip6-localhost ip6-loopback
+ff02::1 ip6-allnodes
+ff02::2 ip6-allrouters
+fe80::1 gateway
+0.0.0.0 ads.example.net
+`
+	res, err := Hosts(strings.NewReader(debian), HostsOptions{Zone: "example.com"})
+	if err != nil {
+		t.Fatalf("Hosts failed: %v", err)
+	}
+	if len(res.Config.Zones) != 0 {
+		t.Errorf("Expected nothing to be imported, got %+v", res.Config.Zones)
+	}
+	if len(res.Warnings) != 7 {
+		t.Errorf("Expected a warning for every address, got %v", res.Warnings)
+	}
+}
+
+func TestHosts_PTR(t *testing.T) {
+	res, err := Hosts(strings.NewReader("192.0.2.10 web\n2001:db8::10 web\n"), HostsOptions{
+		Zone:        "example.com.",
+		Nameservers: []string{"ns1.example.com."},
+		PTR:         true,
+	})
+	if err != nil {
+		t.Fatalf("Hosts failed: %v", err)
+	}
+
+	v4, ok := res.Config.Zones["2.0.192.in-addr.arpa"]
+	if !ok {
+		t.Fatalf("Expected IPv4 reverse zone, got %v", res.Config.Zones)
+	}
+	if v4.RRsets[0].Name != "10" || v4.RRsets[0].Records != "web.example.com." {
+		t.Errorf("Unexpected IPv4 PTR: %+v", v4.RRsets[0])
+	}
+	if len(v4.Nameservers) != 1 {
+		t.Errorf("Expected nameservers on reverse zone, got %v", v4.Nameservers)
+	}
+
+	v6, ok := res.Config.Zones["0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa"]
+	if !ok {
+		t.Fatalf("Expected IPv6 reverse zone, got %v", res.Config.Zones)
+	}
+	if v6.RRsets[0].Name != "0.1.0.0.0.0.0.0.0.0.0.0.0.0.0.0" {
+		t.Errorf("Unexpected IPv6 PTR name: %s", v6.RRsets[0].Name)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	res, err := Hosts(strings.NewReader("192.0.2.10 web\n192.0.2.20 db.other.net\n"), HostsOptions{Zone: "example.com"})
+	if err != nil {
+		t.Fatalf("Hosts failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, res, "hosts"); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	output := buf.String()
+	for _, expected := range []string{
+		"# Generated by powerdns-zone-manager import from hosts",
+		"# WARNING: line 2: db.other.net is not in zone example.com, skipped",
+		"example.com:",
+		"name: web",
+		"records: 192.0.2.10",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Package importer converts records from other formats into zone configuration.
+package importer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+)
+
+// Result is the outcome of an import: the generated configuration and
+// warnings about input that could not be translated.
+type Result struct {
+	Config   *config.Config
+	Warnings []string
+}
+
+func (r *Result) warn(format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// builder collects records into rrsets, keeping the order of first appearance.
+type builder struct {
+	zones map[string]*zoneBuilder
+}
+
+type zoneBuilder struct {
+	index    map[string]int  // name/type -> position in rrsets
+	contents map[string]bool // name/type/content already added
+	rrsets   []config.RRsetInput
+}
+
+// record is a single imported record.
+type record struct {
+	ttl      *uint32
+	zone     string
+	name     string
+	rtype    string
+	content  string
+	comment  string
+	disabled bool
+}
+
+func newBuilder() *builder {
+	return &builder{zones: make(map[string]*zoneBuilder)}
+}
+
+// zone returns the builder of a zone, creating it if needed.
+func (b *builder) zone(name string) *zoneBuilder {
+	zb, ok := b.zones[name]
+	if !ok {
+		zb = &zoneBuilder{index: make(map[string]int), contents: make(map[string]bool)}
+		b.zones[name] = zb
+	}
+	return zb
+}
+
+// touch makes sure a zone exists even if it has no rrsets.
+func (b *builder) touch(zone string) {
+	b.zone(zone)
+}
+
+// add appends a record to the rrset identified by zone, name and type.
+// The TTL of the first record of an rrset wins; duplicate contents are dropped.
+func (b *builder) add(rec *record) {
+	zb := b.zone(rec.zone)
+
+	key := strings.ToLower(rec.name) + "/" + strings.ToUpper(rec.rtype)
+	if zb.contents[key+"/"+rec.content] {
+		return
+	}
+	zb.contents[key+"/"+rec.content] = true
+
+	i, ok := zb.index[key]
+	if !ok {
+		i = len(zb.rrsets)
+		zb.index[key] = i
+		zb.rrsets = append(zb.rrsets, config.RRsetInput{
+			Name: rec.name,
+			Type: strings.ToUpper(rec.rtype),
+			TTL:  rec.ttl,
+		})
+	}
+
+	rrset := &zb.rrsets[i]
+	var value interface{} = rec.content
+	if rec.comment != "" || rec.disabled {
+		m := map[string]interface{}{"content": rec.content}
+		if rec.comment != "" {
+			m["comment"] = rec.comment
+		}
+		if rec.disabled {
+			m["disabled"] = true
+		}
+		value = m
+	}
+
+	switch existing := rrset.Records.(type) {
+	case nil:
+		rrset.Records = value
+	case []interface{}:
+		rrset.Records = append(existing, value)
+	default:
+		rrset.Records = []interface{}{existing, value}
+	}
+}
+
+// config returns the collected zones, each with the given nameservers.
+func (b *builder) config(nameservers []string) *config.Config {
+	cfg := &config.Config{Zones: make(map[string]config.Zone, len(b.zones))}
+	for name, zb := range b.zones {
+		cfg.Zones[name] = config.Zone{
+			Nameservers: nameservers,
+			RRsets:      zb.rrsets,
+		}
+	}
+	return cfg
+}
+
+// relativeName returns the record name relative to zone, "@" for the apex.
+// ok is false if the name is not within the zone.
+func relativeName(fqdn, zone string) (name string, ok bool) {
+	fqdn = strings.ToLower(strings.TrimSuffix(fqdn, "."))
+	zone = strings.ToLower(strings.TrimSuffix(zone, "."))
+	if fqdn == zone {
+		return "@", true
+	}
+	if strings.HasSuffix(fqdn, "."+zone) {
+		return strings.TrimSuffix(fqdn, "."+zone), true
+	}
+	return "", false
+}
+
+// Write writes the imported configuration as YAML, preceded by a header
+// comment and the import warnings as comments.
+func Write(w io.Writer, res *Result, source string) error {
+	header := fmt.Sprintf("# Generated by powerdns-zone-manager import from %s\n", source)
+	for _, warning := range res.Warnings {
+		header += "# WARNING: " + warning + "\n"
+	}
+	if _, err := io.WriteString(w, header); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(res.Config); err != nil {
+		return fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+)
+
+// OctoDNSOptions configures the octoDNS zone file import.
+type OctoDNSOptions struct {
+	// Zone is the zone described by the file.
+	Zone string
+	// Nameservers are set on the zone if it has no apex NS record.
+	Nameservers []string
+}
+
+// octoRecord is a record in an octoDNS zone file.
+type octoRecord struct {
+	Type    string                 `yaml:"type"`
+	TTL     *uint32                `yaml:"ttl"`
+	Value   interface{}            `yaml:"value"`
+	Values  []interface{}          `yaml:"values"`
+	Octo    map[string]interface{} `yaml:"octodns"`
+	Geo     interface{}            `yaml:"geo"`
+	Dynamic interface{}            `yaml:"dynamic"`
+}
+
+// octoValueFields lists the fields of structured octoDNS values in the
+// order of the PowerDNS record content.
+var octoValueFields = map[string][]string{
+	"MX":    {"preference", "exchange"},
+	"SRV":   {"priority", "weight", "port", "target"},
+	"CAA":   {"flags", "tag", "value"},
+	"SSHFP": {"algorithm", "fingerprint_type", "fingerprint"},
+	"NAPTR": {"order", "preference", "flags", "service", "regexp", "replacement"},
+	"TLSA":  {"certificate_usage", "selector", "matching_type", "certificate_association_data"},
+}
+
+// octoLegacyFields maps deprecated octoDNS MX value fields to their current names.
+var octoLegacyFields = map[string]string{"priority": "preference", "value": "exchange"}
+
+// octoQuotedFields are string fields that PowerDNS expects quoted.
+var octoQuotedFields = map[string]bool{
+	"CAA/value": true, "NAPTR/flags": true, "NAPTR/service": true, "NAPTR/regexp": true,
+}
+
+// OctoDNS converts an octoDNS zone file (record name -> record or list of
+// records, with value/values, ttl and type) into rrsets. Features without an
+// equivalent here (geo and dynamic records, octodns settings, unsupported
+// types) are skipped or ignored with a warning.
+func OctoDNS(r io.Reader, opts OctoDNSOptions) (*Result, error) {
+	zone := strings.TrimSuffix(opts.Zone, ".")
+	if zone == "" {
+		return nil, errors.New("zone is required")
+	}
+
+	var file map[string]yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse octoDNS zone file: %w", err)
+	}
+
+	names := make([]string, 0, len(file))
+	for name := range file {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	res := &Result{}
+	b := newBuilder()
+	b.touch(zone)
+	var nameservers []string
+
+	for _, name := range names {
+		node := file[name]
+		var records []octoRecord
+		if node.Kind == yaml.SequenceNode {
+			if err := node.Decode(&records); err != nil {
+				return nil, fmt.Errorf("record %q: %w", name, err)
+			}
+		} else {
+			var rec octoRecord
+			if err := node.Decode(&rec); err != nil {
+				return nil, fmt.Errorf("record %q: %w", name, err)
+			}
+			records = []octoRecord{rec}
+		}
+
+		recName := name
+		if recName == "" {
+			recName = "@"
+		}
+		for i := range records {
+			rec := &records[i]
+			rtype := strings.ToUpper(rec.Type)
+			id := fmt.Sprintf("%s/%s", recName, rtype)
+
+			if rec.Geo != nil || rec.Dynamic != nil {
+				res.warn("%s: geo and dynamic records are not supported, only the default values are imported", id)
+			}
+			if len(rec.Octo) > 0 {
+				res.warn("%s: octodns settings are ignored", id)
+			}
+
+			values := rec.Values
+			if rec.Value != nil {
+				values = append([]interface{}{rec.Value}, values...)
+			}
+
+			var contents []string
+			for _, value := range values {
+				content, err := octoContent(rtype, value)
+				if err != nil {
+					res.warn("%s: %v, skipped", id, err)
+					contents = nil
+					break
+				}
+				contents = append(contents, content)
+			}
+
+			switch {
+			case len(contents) == 0:
+				continue
+			case rtype == "NS" && recName == "@":
+				nameservers = append(nameservers, contents...)
+				continue
+			}
+			for _, content := range contents {
+				b.add(&record{ttl: rec.TTL, zone: zone, name: recName, rtype: rtype, content: content})
+			}
+		}
+	}
+
+	res.Config = b.config(opts.Nameservers)
+	if nameservers != nil {
+		z := res.Config.Zones[zone]
+		z.Nameservers = nameservers
+		res.Config.Zones[zone] = z
+	}
+	return res, nil
+}
+
+// octoContent converts an octoDNS value into PowerDNS record content.
+func octoContent(rtype string, value interface{}) (string, error) {
+	switch rtype {
+	case "A", "AAAA", "CNAME", "NS", "PTR", "ALIAS", "DNAME":
+		s, ok := value.(string)
+		if !ok {
+			return "", fmt.Errorf("invalid value %v", value)
+		}
+		return s, nil
+	case "TXT", "SPF":
+		s, ok := value.(string)
+		if !ok {
+			return "", fmt.Errorf("invalid value %v", value)
+		}
+		// octoDNS escapes semicolons and leaves the quoting to the provider
+		return config.CharacterString(strings.ReplaceAll(s, `\;`, ";")), nil
+	}
+
+	fields, ok := octoValueFields[rtype]
+	if !ok {
+		return "", fmt.Errorf("record type %s is not supported", rtype)
+	}
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("invalid value %v", value)
+	}
+	if rtype == "MX" {
+		for legacy, field := range octoLegacyFields {
+			if v, ok := m[legacy]; ok && m[field] == nil {
+				m[field] = v
+			}
+		}
+	}
+
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		v, ok := m[field]
+		if !ok {
+			return "", fmt.Errorf("value is missing %s", field)
+		}
+		parts[i] = fmt.Sprint(v)
+		if octoQuotedFields[rtype+"/"+field] {
+			parts[i] = config.CharacterString(parts[i])
+		}
+	}
+	return strings.Join(parts, " "), nil
+}
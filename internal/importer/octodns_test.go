@@ -0,0 +1,111 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testOctoDNS = `---
+'':
+  - type: NS
+    values:
+      - ns1.example.com.
+      - ns2.example.com.
+  - type: MX
+    ttl: 3600
+    values:
+      - exchange: mx1.example.com.
+        preference: 10
+      - priority: 20
+        value: mx2.example.com.
+  - type: TXT
+    value: v=spf1 include:_spf.example.com \; -all
+  - type: CAA
+    value:
+      flags: 0
+      tag: issue
+      value: letsencrypt.org
+notes:
+  type: TXT
+  value: 'say "héllo" \ there'
+_sip._tcp:
+  type: SRV
+  values:
+    - priority: 10
+      weight: 20
+      port: 5060
+      target: sip.example.com.
+www:
+  type: A
+  values: [192.0.2.1, 192.0.2.2]
+  octodns:
+    healthcheck:
+      path: /health
+geo:
+  type: A
+  value: 192.0.2.10
+  geo:
+    NA-US: [192.0.2.20]
+loc:
+  type: LOC
+  value:
+    lat_degrees: 31
+`
+
+func TestOctoDNS(t *testing.T) {
+	res, err := OctoDNS(strings.NewReader(testOctoDNS), OctoDNSOptions{Zone: "example.com."})
+	if err != nil {
+		t.Fatalf("OctoDNS failed: %v", err)
+	}
+
+	zone, ok := res.Config.Zones["example.com"]
+	if !ok {
+		t.Fatalf("Expected zone example.com, got %v", res.Config.Zones)
+	}
+	if len(zone.Nameservers) != 2 {
+		t.Errorf("Expected apex NS values as nameservers, got %v", zone.Nameservers)
+	}
+
+	expected := map[string]interface{}{
+		"@/MX":          []interface{}{"10 mx1.example.com.", "20 mx2.example.com."},
+		"@/TXT":         `"v=spf1 include:_spf.example.com ; -all"`,
+		"@/CAA":         `0 issue "letsencrypt.org"`,
+		"notes/TXT":     `"say \"h\195\169llo\" \\ there"`,
+		"_sip._tcp/SRV": "10 20 5060 sip.example.com.",
+		"www/A":         []interface{}{"192.0.2.1", "192.0.2.2"},
+		"geo/A":         "192.0.2.10",
+	}
+	if len(zone.RRsets) != len(expected) {
+		t.Fatalf("Expected %d rrsets, got %+v", len(expected), zone.RRsets)
+	}
+	for _, rrset := range zone.RRsets {
+		key := rrset.Name + "/" + rrset.Type
+		want, ok := expected[key]
+		if !ok {
+			t.Errorf("Unexpected rrset %s", key)
+			continue
+		}
+		if got := rrset.Records; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected records %#v, got %#v", key, want, got)
+		}
+	}
+
+	if len(res.Warnings) != 3 {
+		t.Errorf("Expected octodns, geo and LOC warnings, got %v", res.Warnings)
+	}
+}
+
+func TestOctoDNS_InvalidValue(t *testing.T) {
+	input := "mail:\n  type: MX\n  value:\n    exchange: mx.example.com.\n"
+	res, err := OctoDNS(strings.NewReader(input), OctoDNSOptions{Zone: "example.com"})
+	if err != nil {
+		t.Fatalf("OctoDNS failed: %v", err)
+	}
+	if len(res.Config.Zones["example.com"].RRsets) != 0 {
+		t.Errorf("Expected MX without preference to be skipped, got %+v", res.Config.Zones)
+	}
+	if len(res.Warnings) != 1 {
+		t.Errorf("Expected 1 warning, got %v", res.Warnings)
+	}
+}
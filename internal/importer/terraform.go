@@ -0,0 +1,216 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+)
+
+// Terraform resource types of the PowerDNS provider.
+const (
+	tfRecordType = "powerdns_record"
+	tfZoneType   = "powerdns_zone"
+)
+
+// tfState is the subset of a Terraform state file (format version 4) used by the import.
+type tfState struct {
+	Values        *tfValues    `json:"values"`
+	PlannedValues *tfValues    `json:"planned_values"`
+	Resources     []tfResource `json:"resources"`
+}
+
+// tfValues is the "values" section of "terraform show -json" output.
+type tfValues struct {
+	RootModule tfModule `json:"root_module"`
+}
+
+type tfModule struct {
+	Resources    []tfResource `json:"resources"`
+	ChildModules []tfModule   `json:"child_modules"`
+}
+
+// tfResource is a resource of either a state file (with instances) or of
+// "terraform show -json" output (with values).
+type tfResource struct {
+	Values    *tfAttributes `json:"values"`
+	Mode      string        `json:"mode"`
+	Type      string        `json:"type"`
+	Name      string        `json:"name"`
+	Address   string        `json:"address"`
+	Instances []struct {
+		Attributes tfAttributes `json:"attributes"`
+	} `json:"instances"`
+}
+
+// tfAttributes holds the attributes of powerdns_record and powerdns_zone resources.
+type tfAttributes struct {
+	TTL         *uint32  `json:"ttl"`
+	Zone        string   `json:"zone"`
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Kind        string   `json:"kind"`
+	Records     []string `json:"records"`
+	Nameservers []string `json:"nameservers"`
+	Masters     []string `json:"masters"`
+	SetPTR      bool     `json:"set_ptr"`
+}
+
+// TerraformOptions configures the Terraform import.
+type TerraformOptions struct {
+	// Nameservers are set on zones without a powerdns_zone resource or apex NS records.
+	Nameservers []string
+}
+
+// terraformImport collects the zones and records of Terraform resources.
+type terraformImport struct {
+	res         *Result
+	b           *builder
+	zones       map[string]config.Zone
+	nameservers map[string][]string
+}
+
+// Terraform converts powerdns_zone and powerdns_record resources into zone
+// configuration. The input is either a Terraform state file or the output of
+// "terraform show -json" (of a state or a saved plan). HCL is not parsed.
+func Terraform(r io.Reader, opts TerraformOptions) (*Result, error) {
+	var state tfState
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return nil, fmt.Errorf("failed to parse Terraform JSON (HCL is not supported, use terraform show -json): %w", err)
+	}
+
+	imp := &terraformImport{
+		res:         &Result{},
+		b:           newBuilder(),
+		zones:       make(map[string]config.Zone),
+		nameservers: make(map[string][]string),
+	}
+
+	switch {
+	case state.Values != nil:
+		imp.module(&state.Values.RootModule)
+	case state.PlannedValues != nil:
+		imp.module(&state.PlannedValues.RootModule)
+	case state.Resources != nil:
+		for i := range state.Resources {
+			res := &state.Resources[i]
+			for j := range res.Instances {
+				imp.resource(res, &res.Instances[j].Attributes)
+			}
+		}
+	default:
+		return nil, errors.New("input is neither a Terraform state nor terraform show -json output")
+	}
+
+	return imp.result(opts.Nameservers), nil
+}
+
+func (imp *terraformImport) module(m *tfModule) {
+	for i := range m.Resources {
+		if res := &m.Resources[i]; res.Values != nil {
+			imp.resource(res, res.Values)
+		}
+	}
+	for i := range m.ChildModules {
+		imp.module(&m.ChildModules[i])
+	}
+}
+
+func (imp *terraformImport) resource(res *tfResource, attrs *tfAttributes) {
+	if res.Mode == "data" {
+		return
+	}
+	address := res.Address
+	if address == "" {
+		address = res.Type + "." + res.Name
+	}
+
+	switch res.Type {
+	case tfZoneType:
+		imp.zone(address, attrs)
+	case tfRecordType:
+		imp.record(address, attrs)
+	}
+}
+
+func (imp *terraformImport) zone(address string, attrs *tfAttributes) {
+	name := strings.TrimSuffix(attrs.Name, ".")
+	if name == "" {
+		imp.res.warn("%s: zone name is unknown, skipped", address)
+		return
+	}
+
+	zone := config.Zone{Masters: attrs.Masters}
+	if attrs.Kind != "" {
+		zone.Kind = strings.ToUpper(attrs.Kind[:1]) + strings.ToLower(attrs.Kind[1:])
+	}
+	if zone.Kind != config.KindSlave {
+		zone.Nameservers = attrs.Nameservers
+	}
+	imp.zones[name] = zone
+	imp.b.touch(name)
+}
+
+func (imp *terraformImport) record(address string, attrs *tfAttributes) {
+	zone := strings.TrimSuffix(attrs.Zone, ".")
+	name, ok := relativeName(attrs.Name, zone)
+	rtype := strings.ToUpper(attrs.Type)
+	switch {
+	case zone == "" || rtype == "":
+		imp.res.warn("%s: zone or type is unknown, skipped", address)
+		return
+	case !ok:
+		imp.res.warn("%s: %s is not within zone %s, skipped", address, attrs.Name, zone)
+		return
+	case rtype == "SOA":
+		imp.res.warn("%s: SOA records are managed by PowerDNS, skipped", address)
+		return
+	case rtype == "NS" && name == "@":
+		imp.nameservers[zone] = append(imp.nameservers[zone], attrs.Records...)
+		imp.b.touch(zone)
+		return
+	}
+
+	if attrs.SetPTR {
+		imp.res.warn("%s: set_ptr is not supported, manage the PTR records explicitly", address)
+	}
+	for _, content := range attrs.Records {
+		imp.b.add(&record{ttl: attrs.TTL, zone: zone, name: name, rtype: rtype, content: content})
+	}
+}
+
+// result merges zone settings and apex NS records into the collected rrsets.
+func (imp *terraformImport) result(nameservers []string) *Result {
+	imp.res.Config = imp.b.config(nameservers)
+	names := make([]string, 0, len(imp.res.Config.Zones))
+	for name := range imp.res.Config.Zones {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		cfg := imp.res.Config.Zones[name]
+		settings, ok := imp.zones[name]
+		if ok {
+			cfg.Kind = settings.Kind
+			cfg.Masters = settings.Masters
+			if settings.Nameservers != nil || settings.Kind == config.KindSlave {
+				cfg.Nameservers = settings.Nameservers
+			}
+		}
+		if cfg.Kind == config.KindSlave {
+			if len(cfg.RRsets) > 0 {
+				imp.res.warn("zone %s: records of %s zones are transferred from masters, skipped", name, config.KindSlave)
+				cfg.RRsets = nil
+			}
+		} else if ns, ok := imp.nameservers[name]; ok {
+			cfg.Nameservers = ns
+		}
+		imp.res.Config.Zones[name] = cfg
+	}
+	return imp.res
+}
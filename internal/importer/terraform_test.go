@@ -0,0 +1,161 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+)
+
+const testTerraformState = `{
+  "version": 4,
+  "resources": [
+    {
+      "mode": "managed",
+      "type": "powerdns_zone",
+      "name": "example",
+      "instances": [
+        {"attributes": {"name": "example.com.", "kind": "native", "nameservers": ["ns1.example.com."]}}
+      ]
+    },
+    {
+      "mode": "managed",
+      "type": "powerdns_record",
+      "name": "www",
+      "instances": [
+        {"attributes": {"zone": "example.com.", "name": "www.example.com.", "type": "A", "ttl": 300,
+          "records": ["192.0.2.1", "192.0.2.2"], "set_ptr": true}}
+      ]
+    },
+    {
+      "mode": "managed",
+      "type": "powerdns_record",
+      "name": "outside",
+      "instances": [
+        {"attributes": {"zone": "example.com.", "name": "www.example.org.", "type": "A", "records": ["192.0.2.3"]}}
+      ]
+    },
+    {
+      "mode": "data",
+      "type": "powerdns_record",
+      "name": "ignored",
+      "instances": [
+        {"attributes": {"zone": "example.com.", "name": "data.example.com.", "type": "A", "records": ["192.0.2.4"]}}
+      ]
+    },
+    {
+      "mode": "managed",
+      "type": "powerdns_zone",
+      "name": "secondary",
+      "instances": [
+        {"attributes": {"name": "secondary.com.", "kind": "Slave", "masters": ["192.0.2.53"]}}
+      ]
+    }
+  ]
+}`
+
+const testTerraformShow = `{
+  "format_version": "1.0",
+  "values": {
+    "root_module": {
+      "resources": [
+        {
+          "address": "powerdns_record.apex_ns",
+          "mode": "managed",
+          "type": "powerdns_record",
+          "name": "apex_ns",
+          "values": {"zone": "example.net.", "name": "example.net.", "type": "NS", "ttl": 3600,
+            "records": ["ns1.example.net.", "ns2.example.net."]}
+        }
+      ],
+      "child_modules": [
+        {
+          "resources": [
+            {
+              "address": "module.mail.powerdns_record.mx",
+              "mode": "managed",
+              "type": "powerdns_record",
+              "name": "mx",
+              "values": {"zone": "example.net.", "name": "example.net.", "type": "MX", "ttl": 3600,
+                "records": ["10 mail.example.net."]}
+            }
+          ]
+        }
+      ]
+    }
+  }
+}`
+
+func TestTerraform_State(t *testing.T) {
+	res, err := Terraform(strings.NewReader(testTerraformState), TerraformOptions{})
+	if err != nil {
+		t.Fatalf("Terraform failed: %v", err)
+	}
+
+	zone, ok := res.Config.Zones["example.com"]
+	if !ok {
+		t.Fatalf("Expected zone example.com, got %v", res.Config.Zones)
+	}
+	if zone.Kind != "Native" {
+		t.Errorf("Expected kind Native, got %q", zone.Kind)
+	}
+	if len(zone.Nameservers) != 1 || zone.Nameservers[0] != "ns1.example.com." {
+		t.Errorf("Expected nameservers from powerdns_zone, got %v", zone.Nameservers)
+	}
+	if len(zone.RRsets) != 1 {
+		t.Fatalf("Expected only the www rrset, got %+v", zone.RRsets)
+	}
+	www := zone.RRsets[0]
+	if www.Name != "www" || www.Type != "A" || www.TTL == nil || *www.TTL != 300 {
+		t.Errorf("Unexpected rrset: %+v", www)
+	}
+	if records, ok := www.Records.([]interface{}); !ok || len(records) != 2 {
+		t.Errorf("Expected 2 records, got %#v", www.Records)
+	}
+
+	secondary, ok := res.Config.Zones["secondary.com"]
+	if !ok {
+		t.Fatalf("Expected zone secondary.com, got %v", res.Config.Zones)
+	}
+	if secondary.Kind != "Slave" || len(secondary.Masters) != 1 || secondary.Nameservers != nil {
+		t.Errorf("Unexpected secondary zone: %+v", secondary)
+	}
+
+	if len(res.Warnings) != 2 {
+		t.Errorf("Expected set_ptr and out-of-zone warnings, got %v", res.Warnings)
+	}
+}
+
+func TestTerraform_ShowJSON(t *testing.T) {
+	res, err := Terraform(strings.NewReader(testTerraformShow), TerraformOptions{Nameservers: []string{"ns.default."}})
+	if err != nil {
+		t.Fatalf("Terraform failed: %v", err)
+	}
+
+	zone, ok := res.Config.Zones["example.net"]
+	if !ok {
+		t.Fatalf("Expected zone example.net, got %v", res.Config.Zones)
+	}
+	if len(zone.Nameservers) != 2 {
+		t.Errorf("Expected apex NS records as nameservers, got %v", zone.Nameservers)
+	}
+	if len(zone.RRsets) != 1 || zone.RRsets[0].Type != "MX" || zone.RRsets[0].Name != "@" {
+		t.Errorf("Expected MX rrset from child module, got %+v", zone.RRsets)
+	}
+}
+
+func TestTerraform_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"hcl", `resource "powerdns_record" "www" {}`},
+		{"unknown json", `{"foo": "bar"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Terraform(strings.NewReader(tt.input), TerraformOptions{}); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+// Package journal records the progress of an apply run that failed, so that
+// the next run can resume it: the zones the failed run applied are skipped
+// instead of being compared with the server again.
+//
+// A zone is only skipped while its configuration is the one the failed run
+// applied; zones that were changed since are applied again.
+package journal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+)
+
+// Version is the current journal format version.
+const Version = 1
+
+// FileName is the default name of the journal, next to the config file.
+const FileName = ".pdns-zm-journal.json"
+
+// Journal is the journal of a failed apply run.
+type Journal struct {
+	FailedAt time.Time `json:"failedAt"`
+	Account  string    `json:"account"`
+	RunID    string    `json:"runId,omitempty"`
+	// Zones are the digests of the configuration of the zones the run, or
+	// the runs it resumed, applied, by configured zone name
+	Zones   map[string]string `json:"zones"`
+	Version int               `json:"version"`
+}
+
+// New creates the journal of a failed apply of cfg from its result. Zones
+// that were applied, or skipped as completed by an earlier run, are recorded.
+func New(cfg *config.Config, account, runID string, result *manager.ApplyResult) (*Journal, error) {
+	zones := make(map[string]string)
+	for _, zr := range result.Zones {
+		if zr.Status != manager.ZoneStatusOK && zr.Status != manager.ZoneStatusCompleted {
+			continue
+		}
+		zone, ok := cfg.Zones[zr.Name]
+		if !ok {
+			continue
+		}
+		d, err := digest(zone)
+		if err != nil {
+			return nil, fmt.Errorf("zone %s: %w", zr.Name, err)
+		}
+		zones[zr.Name] = d
+	}
+	return &Journal{
+		Version:  Version,
+		FailedAt: time.Now().UTC().Truncate(time.Second),
+		Account:  account,
+		RunID:    runID,
+		Zones:    zones,
+	}, nil
+}
+
+// Completed returns the zones of cfg that the failed run applied and that
+// were not changed since, see manager.ApplyOptions.Completed.
+func (j *Journal) Completed(cfg *config.Config) (map[string]bool, error) {
+	completed := make(map[string]bool, len(j.Zones))
+	for name, want := range j.Zones {
+		zone, ok := cfg.Zones[name]
+		if !ok {
+			continue
+		}
+		d, err := digest(zone)
+		if err != nil {
+			return nil, fmt.Errorf("zone %s: %w", name, err)
+		}
+		if d == want {
+			completed[name] = true
+		}
+	}
+	return completed, nil
+}
+
+// digest returns a digest of the configuration of a zone, including the
+// default TTL of its rrsets.
+func digest(zone config.Zone) (string, error) {
+	data, err := json.Marshal(struct {
+		Zone       config.Zone
+		DefaultTTL uint32
+	}{zone, zone.DefaultTTL()})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal zone: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Load reads a journal from a JSON file. It returns nil without error if the
+// file does not exist.
+func Load(path string) (*Journal, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path is from CLI argument
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	var j Journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, fmt.Errorf("failed to parse journal %s: %w", path, err)
+	}
+	if j.Version != Version {
+		return nil, fmt.Errorf("unsupported journal version %d in %s", j.Version, path)
+	}
+	return &j, nil
+}
+
+// Save writes the journal to a JSON file.
+func (j *Journal) Save(path string) error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return nil
+}
+
+// Remove removes the journal file, e.g. after a successful run. A missing
+// file is not an error.
+func Remove(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove journal: %w", err)
+	}
+	return nil
+}
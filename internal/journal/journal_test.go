@@ -0,0 +1,88 @@
+package journal
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+)
+
+func loadConfig(t *testing.T, data string) *config.Config {
+	t.Helper()
+	cfg, err := config.LoadFromReader(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	return cfg
+}
+
+func TestJournal(t *testing.T) {
+	cfg := loadConfig(t, `zones:
+  a.example:
+    a: 192.0.2.1
+  b.example:
+    a: 192.0.2.2
+  c.example:
+    a: 192.0.2.3
+  d.example:
+    a: 192.0.2.4
+`)
+	result := &manager.ApplyResult{Zones: []manager.ZoneResult{
+		{Name: "a.example", Status: manager.ZoneStatusCompleted},
+		{Name: "b.example", Status: manager.ZoneStatusOK},
+		{Name: "c.example", Status: manager.ZoneStatusOK},
+		{Name: "d.example", Status: manager.ZoneStatusFailed},
+	}}
+	j, err := New(cfg, "team-a", "run-1", result)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := j.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, j) {
+		t.Errorf("Loaded journal %+v differs from %+v", loaded, j)
+	}
+
+	// b.example was changed since the failed run, c.example removed
+	cfg = loadConfig(t, `zones:
+  a.example:
+    a: 192.0.2.1
+  b.example:
+    a: 192.0.2.20
+  d.example:
+    a: 192.0.2.4
+`)
+	completed, err := loaded.Completed(cfg)
+	if err != nil {
+		t.Fatalf("Completed failed: %v", err)
+	}
+	if expected := map[string]bool{"a.example": true}; !reflect.DeepEqual(completed, expected) {
+		t.Errorf("Expected completed zones %v, got %v", expected, completed)
+	}
+
+	// The default TTL is part of the configuration of a zone
+	cfg.SetDefaultTTL(60)
+	if completed, _ := loaded.Completed(cfg); len(completed) != 0 {
+		t.Errorf("Expected no completed zones with another default TTL, got %v", completed)
+	}
+
+	if err := Remove(path); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if j, err := Load(path); j != nil || err != nil {
+		t.Errorf("Expected no journal after Remove, got %+v, %v", j, err)
+	}
+	if err := Remove(path); err != nil {
+		t.Errorf("Expected no error removing a missing journal, got %v", err)
+	}
+}
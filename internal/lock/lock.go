@@ -0,0 +1,244 @@
+// Package lock implements per-zone apply locks, so that two apply runs do not
+// clobber each other's changes.
+//
+// A lock is a TXT RRset named RecordName in the zone itself, so it works with
+// any provider and is visible to every run that talks to the same server:
+//
+//	_zone-manager-lock.example.com. 60 IN TXT "run=20260101T120000-1a2b3c4d account=team-a expires=..."
+//
+// The RRset has no ownership comment, so it is never treated as managed.
+// Locks expire, so a crashed run only blocks others until its lock expires.
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+// RecordName is the owner name prefix of lock RRsets.
+const RecordName = "_zone-manager-lock"
+
+// ttl of lock RRsets, which are not meant to be cached by resolvers.
+const ttl = 60
+
+// ErrLocked is returned when a zone is locked by another run.
+var ErrLocked = errors.New("zone is locked by another run")
+
+// Store reads and patches zones; the DNS providers implement it.
+type Store interface {
+	GetZone(ctx context.Context, zoneID string) (*powerdns.Zone, error)
+	PatchZone(ctx context.Context, zoneID string, patch *powerdns.ZonePatch) error
+}
+
+// Lock is the content of a zone lock.
+type Lock struct {
+	Expires time.Time
+	RunID   string
+	Account string
+}
+
+// String renders the lock as TXT record content.
+func (l Lock) String() string {
+	return fmt.Sprintf(`"run=%s account=%s expires=%s"`, l.RunID, l.Account, l.Expires.UTC().Format(time.RFC3339))
+}
+
+// Parse parses TXT record content written by String.
+func Parse(content string) (Lock, bool) {
+	var l Lock
+	for _, field := range strings.Fields(strings.Trim(content, `"`)) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return Lock{}, false
+		}
+		switch key {
+		case "run":
+			l.RunID = value
+		case "account":
+			l.Account = value
+		case "expires":
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return Lock{}, false
+			}
+			l.Expires = t
+		}
+	}
+	return l, l.RunID != ""
+}
+
+// Name returns the lock RRset name of a canonical zone name.
+func Name(zoneID string) string {
+	return RecordName + "." + zoneID
+}
+
+// filteredStore is implemented by stores that can drop RRsets while reading a
+// zone, so that the lock of a huge zone is read without its other RRsets.
+type filteredStore interface {
+	GetZoneFiltered(ctx context.Context, zoneID string, keep powerdns.RRsetFilter) (*powerdns.Zone, error)
+}
+
+// Current returns the lock of a zone, or false if it is not locked.
+// Expired locks are returned as well.
+func Current(ctx context.Context, store Store, zoneID string) (Lock, bool, error) {
+	zone, err := readZone(ctx, store, zoneID)
+	if err != nil {
+		return Lock{}, false, err
+	}
+	l, ok := find(zone, zoneID)
+	return l, ok, nil
+}
+
+// readZone reads a zone with at least its lock RRset, nil if it does not exist.
+func readZone(ctx context.Context, store Store, zoneID string) (*powerdns.Zone, error) {
+	name := Name(zoneID)
+	var zone *powerdns.Zone
+	var err error
+	if fs, ok := store.(filteredStore); ok {
+		zone, err = fs.GetZoneFiltered(ctx, zoneID, func(rrset *powerdns.RRset) bool {
+			return rrset.Name == name && rrset.Type == "TXT"
+		})
+	} else {
+		zone, err = store.GetZone(ctx, zoneID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lock: %w", err)
+	}
+	return zone, nil
+}
+
+// find returns the lock in a zone read by readZone, or false if it has none.
+func find(zone *powerdns.Zone, zoneID string) (Lock, bool) {
+	if zone == nil {
+		return Lock{}, false
+	}
+	name := Name(zoneID)
+	for _, rrset := range zone.RRsets {
+		if rrset.Name != name || rrset.Type != "TXT" {
+			continue
+		}
+		for _, record := range rrset.Records {
+			if l, ok := Parse(record.Content); ok {
+				return l, true
+			}
+		}
+	}
+	return Lock{}, false
+}
+
+// settleDelay is how long Acquire waits after writing a lock before reading
+// it back. It must be longer than a run takes from reading the lock to writing
+// its own.
+var settleDelay = 2 * time.Second
+
+// writerStore is implemented by stores that read from a different server than
+// they write to (powerdns.RoleClient). Locks are read from the server they are
+// written to, so that the read-back sees the write. They are still written
+// through the store, which keeps its reads consistent with the write.
+type writerStore interface {
+	Writer() *powerdns.Client
+}
+
+// primary returns the store that locks are read from.
+func primary(store Store) Store {
+	if ws, ok := store.(writerStore); ok && ws.Writer() != nil {
+		return ws.Writer()
+	}
+	return store
+}
+
+// Acquire locks a zone for a run. It fails with ErrLocked if another run holds
+// an unexpired lock, unless force is set. It returns the unexpired lock of
+// another run that was taken over with force, if any, and the serial of the
+// zone when it was read before the lock was written, i.e. the serial of the
+// zone as it is applied by the run.
+//
+// The API has no conditional writes, so two runs that both read the zone as
+// unlocked both write their lock, and the later write wins. Acquire waits
+// settleDelay after writing and reads the lock again: the run whose lock was
+// overwritten fails, and as every contender reads after the last write, only
+// the last writer proceeds. This is not strict mutual exclusion: a run whose
+// write reaches the server more than settleDelay after its read (e.g. on a
+// stalled connection) can still overwrite the lock of a run that has already
+// proceeded.
+func Acquire(
+	ctx context.Context,
+	store Store,
+	zoneID string,
+	l Lock,
+	force bool,
+	now time.Time,
+) (*Lock, uint32, error) {
+	zone, err := readZone(ctx, primary(store), zoneID)
+	if err != nil {
+		return nil, 0, err
+	}
+	current, locked := find(zone, zoneID)
+	var replaced *Lock
+	if locked && current.RunID != l.RunID && now.Before(current.Expires) {
+		if !force {
+			return nil, 0, lockedError(current)
+		}
+		previous := current
+		replaced = &previous
+	}
+
+	patch := &powerdns.ZonePatch{RRsets: []powerdns.RRset{{
+		Name:       Name(zoneID),
+		Type:       "TXT",
+		TTL:        ttl,
+		ChangeType: "REPLACE",
+		Records:    []powerdns.Record{{Content: l.String()}},
+	}}}
+	if err := store.PatchZone(ctx, zoneID, patch); err != nil {
+		return nil, 0, fmt.Errorf("failed to write lock: %w", err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, 0, ctx.Err()
+	case <-time.After(settleDelay):
+	}
+	current, locked, err = Current(ctx, primary(store), zoneID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !locked || current.RunID != l.RunID {
+		return nil, 0, lockedError(current)
+	}
+	var serial uint32
+	if zone != nil {
+		serial = zone.Serial
+	}
+	return replaced, serial, nil
+}
+
+// Release removes the lock of a zone if it is held by runID.
+func Release(ctx context.Context, store Store, zoneID, runID string) error {
+	current, locked, err := Current(ctx, primary(store), zoneID)
+	if err != nil || !locked || current.RunID != runID {
+		return err
+	}
+	return remove(ctx, store, zoneID)
+}
+
+func remove(ctx context.Context, store Store, zoneID string) error {
+	patch := &powerdns.ZonePatch{RRsets: []powerdns.RRset{{
+		Name:       Name(zoneID),
+		Type:       "TXT",
+		ChangeType: "DELETE",
+	}}}
+	if err := store.PatchZone(ctx, zoneID, patch); err != nil {
+		return fmt.Errorf("failed to remove lock: %w", err)
+	}
+	return nil
+}
+
+func lockedError(l Lock) error {
+	return fmt.Errorf("%w: run %s (account %s) until %s",
+		ErrLocked, l.RunID, l.Account, l.Expires.UTC().Format(time.RFC3339))
+}
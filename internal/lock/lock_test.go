@@ -0,0 +1,142 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/fileprovider"
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+func TestMain(m *testing.M) {
+	settleDelay = 0
+	os.Exit(m.Run())
+}
+
+func TestParse(t *testing.T) {
+	l := Lock{RunID: "run-1", Account: "team-a", Expires: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	parsed, ok := Parse(l.String())
+	if !ok || parsed != l {
+		t.Errorf("Expected %+v to round-trip, got %+v (%v)", l, parsed, ok)
+	}
+
+	for _, content := range []string{`"v=spf1 -all"`, `"account=team-a"`, `"run=x expires=tomorrow"`} {
+		if _, ok := Parse(content); ok {
+			t.Errorf("Expected %s not to parse as a lock", content)
+		}
+	}
+}
+
+func TestAcquireRelease(t *testing.T) {
+	ctx := context.Background()
+	store := fileprovider.New(t.TempDir(), fileprovider.Options{})
+	if _, err := store.CreateZone(ctx, &powerdns.Zone{Name: "example.com.", Kind: "Native"}); err != nil {
+		t.Fatalf("CreateZone failed: %v", err)
+	}
+	now := time.Now()
+	first := Lock{RunID: "run-1", Account: "team-a", Expires: now.Add(time.Minute)}
+	second := Lock{RunID: "run-2", Account: "team-b", Expires: now.Add(time.Minute)}
+
+	if _, _, err := Acquire(ctx, store, "example.com.", first, false, now); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	// Acquiring again in the same run extends the lock
+	if _, _, err := Acquire(ctx, store, "example.com.", first, false, now); err != nil {
+		t.Fatalf("Acquire by the lock holder failed: %v", err)
+	}
+	if _, _, err := Acquire(ctx, store, "example.com.", second, false, now); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Expected ErrLocked, got %v", err)
+	}
+	// Expired locks are taken over
+	if _, _, err := Acquire(ctx, store, "example.com.", second, false, now.Add(2*time.Minute)); err != nil {
+		t.Fatalf("Acquire of an expired lock failed: %v", err)
+	}
+
+	// Release only removes the caller's lock
+	if err := Release(ctx, store, "example.com.", "run-1"); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if current, ok, _ := Current(ctx, store, "example.com."); !ok || current.RunID != "run-2" {
+		t.Fatalf("Expected lock of run-2 to remain, got %+v (%v)", current, ok)
+	}
+
+	replaced, _, err := Acquire(ctx, store, "example.com.", first, true, now)
+	if err != nil || replaced == nil || replaced.RunID != "run-2" {
+		t.Fatalf("Expected forced Acquire to take over run-2, got %+v, %v", replaced, err)
+	}
+	if err := Release(ctx, store, "example.com.", "run-1"); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if _, ok, _ := Current(ctx, store, "example.com."); ok {
+		t.Error("Expected zone to be unlocked after release")
+	}
+}
+
+// racingStore writes the lock of another run right after the first patch,
+// like a run that read the zone as unlocked before the patch.
+type racingStore struct {
+	Store
+	other Lock
+	raced bool
+}
+
+func (s *racingStore) PatchZone(ctx context.Context, zoneID string, patch *powerdns.ZonePatch) error {
+	if err := s.Store.PatchZone(ctx, zoneID, patch); err != nil || s.raced {
+		return err
+	}
+	s.raced = true
+	return s.Store.PatchZone(ctx, zoneID, &powerdns.ZonePatch{RRsets: []powerdns.RRset{{
+		Name:       Name(zoneID),
+		Type:       "TXT",
+		TTL:        ttl,
+		ChangeType: "REPLACE",
+		Records:    []powerdns.Record{{Content: s.other.String()}},
+	}}})
+}
+
+func TestAcquire_Overwritten(t *testing.T) {
+	ctx := context.Background()
+	files := fileprovider.New(t.TempDir(), fileprovider.Options{})
+	if _, err := files.CreateZone(ctx, &powerdns.Zone{Name: "example.com.", Kind: "Native"}); err != nil {
+		t.Fatalf("CreateZone failed: %v", err)
+	}
+	now := time.Now()
+	other := Lock{RunID: "run-2", Account: "team-b", Expires: now.Add(time.Minute)}
+	store := &racingStore{Store: files, other: other}
+
+	l := Lock{RunID: "run-1", Account: "team-a", Expires: now.Add(time.Minute)}
+	if _, _, err := Acquire(ctx, store, "example.com.", l, false, now); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Expected ErrLocked for an overwritten lock, got %v", err)
+	}
+	if current, ok, _ := Current(ctx, files, "example.com."); !ok || current.RunID != "run-2" {
+		t.Errorf("Expected lock of run-2 to remain, got %+v (%v)", current, ok)
+	}
+}
+
+func TestAcquire_Serial(t *testing.T) {
+	ctx := context.Background()
+	store := fileprovider.New(t.TempDir(), fileprovider.Options{})
+	soa := powerdns.RRset{Name: "example.com.", Type: "SOA", TTL: 3600, Records: []powerdns.Record{
+		{Content: "ns1.example.com. hostmaster.example.com. 2026010101 10800 3600 604800 3600"},
+	}}
+	zone := &powerdns.Zone{Name: "example.com.", Kind: "Native", RRsets: []powerdns.RRset{soa}}
+	if _, err := store.CreateZone(ctx, zone); err != nil {
+		t.Fatalf("CreateZone failed: %v", err)
+	}
+
+	l := Lock{RunID: "run-1", Account: "team-a", Expires: time.Now().Add(time.Minute)}
+	_, serial, err := Acquire(ctx, store, "example.com.", l, false, time.Now())
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	// The serial is the one before the lock was written
+	if serial != 2026010101 {
+		t.Errorf("Expected serial 2026010101, got %d", serial)
+	}
+	if locked, _ := store.GetZone(ctx, "example.com."); locked.Serial == serial {
+		t.Errorf("Expected the lock to change the serial, got %d", locked.Serial)
+	}
+}
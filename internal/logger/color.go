@@ -0,0 +1,19 @@
+package logger
+
+import "os"
+
+// colorEnabled reports whether the output is colored unless disabled by
+// the options: NO_COLOR turns colors off and FORCE_COLOR on, e.g. for CI logs
+// that render them, otherwise stdout and stderr must be terminals that
+// support ANSI escape codes.
+func colorEnabled() bool {
+	switch {
+	case os.Getenv("NO_COLOR") != "":
+		return false
+	case os.Getenv("FORCE_COLOR") != "":
+		return true
+	case os.Getenv("TERM") == "dumb":
+		return false
+	}
+	return enableANSI(os.Stdout) && enableANSI(os.Stderr)
+}
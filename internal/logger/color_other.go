@@ -0,0 +1,12 @@
+//go:build !windows
+
+package logger
+
+import "os"
+
+// enableANSI reports whether f is a terminal, which interprets ANSI escape
+// codes on these systems.
+func enableANSI(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
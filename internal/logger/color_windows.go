@@ -0,0 +1,31 @@
+//go:build windows
+
+package logger
+
+import (
+	"os"
+	"syscall"
+)
+
+// enableVirtualTerminalProcessing is the console mode that interprets ANSI
+// escape codes, ENABLE_VIRTUAL_TERMINAL_PROCESSING.
+const enableVirtualTerminalProcessing = 0x0004
+
+var setConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+// enableANSI turns on the interpretation of ANSI escape codes if f is a
+// console, and reports whether it is on. Consoles before Windows 10 do not
+// support it, redirected output is not a console.
+func enableANSI(f *os.File) bool {
+	handle := syscall.Handle(f.Fd())
+	var mode uint32
+	if err := syscall.GetConsoleMode(handle, &mode); err != nil {
+		return false
+	}
+	if mode&enableVirtualTerminalProcessing != 0 {
+		return true
+	}
+	// The first result is the return value of SetConsoleMode, nonzero on success
+	ok, _, _ := setConsoleMode.Call(uintptr(handle), uintptr(mode|enableVirtualTerminalProcessing))
+	return ok != 0
+}
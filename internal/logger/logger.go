@@ -6,8 +6,12 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
+
+	"github.com/kreigan/powerdns-zone-manager/pkg/output"
 )
 
 // Level represents logging verbosity.
@@ -39,22 +43,31 @@ const (
 	colorBold   = "\033[1m"
 )
 
-// LogEntry represents a structured log entry for JSON output.
+// LogEntry represents a structured log entry for JSON output, see
+// output.Entry for the schema.
 type LogEntry struct {
-	Data      map[string]interface{} `json:"data,omitempty"`
-	Timestamp string                 `json:"timestamp"`
-	Level     string                 `json:"level"`
-	Message   string                 `json:"message"`
+	Data          interface{} `json:"data,omitempty"`
+	Timestamp     string      `json:"timestamp"`
+	Level         string      `json:"level"`
+	Message       string      `json:"message"`
+	RunID         string      `json:"runId,omitempty"`
+	Partition     string      `json:"partition,omitempty"`
+	SchemaVersion int         `json:"schemaVersion"`
 }
 
 // Logger provides structured logging with verbosity control.
 type Logger struct {
 	out     io.Writer
 	errOut  io.Writer
+	runID   string
 	level   Level
 	format  OutputFormat
 	dryRun  bool
 	noColor bool
+	// partition is the config partition the entries belong to, see WithPartition
+	partition string
+	// deferred is an info message logged before the next entry, see Defer
+	deferred string
 }
 
 // Options configures the logger.
@@ -79,22 +92,66 @@ func New(opts Options) *Logger {
 		errOut:  os.Stderr,
 		level:   level,
 		format:  format,
-		noColor: opts.NoColor || opts.JSON, // No color in JSON mode
+		noColor: opts.NoColor || opts.JSON || !colorEnabled(), // No color in JSON mode or redirected output
 	}
 }
 
+// Quiet returns a copy of the logger that discards everything except errors.
+func (l *Logger) Quiet() *Logger {
+	quiet := *l
+	quiet.out = io.Discard
+	return &quiet
+}
+
+// WithPartition returns a copy of the logger whose entries are marked with
+// the config partition name: a prefix in text mode, a field in JSON mode.
+func (l *Logger) WithPartition(name string) *Logger {
+	partitioned := *l
+	partitioned.partition = name
+	return &partitioned
+}
+
 // SetDryRun sets dry-run mode for log prefix.
 func (l *Logger) SetDryRun(dryRun bool) {
 	l.dryRun = dryRun
 }
 
+// SetRunID sets the run ID included in JSON log entries.
+func (l *Logger) SetRunID(id string) {
+	l.runID = id
+}
+
+// Defer sets an informational message that is only logged right before the
+// next entry that is written, e.g. the heading of a section that is left out
+// if it has no entries. It replaces an earlier deferred message.
+func (l *Logger) Defer(format string, args ...interface{}) {
+	l.deferred = fmt.Sprintf(format, args...)
+}
+
+// DiscardDeferred drops the deferred message, if any, see Defer.
+func (l *Logger) DiscardDeferred() {
+	l.deferred = ""
+}
+
+// flushDeferred logs the deferred message, if any.
+func (l *Logger) flushDeferred() {
+	if l.deferred == "" {
+		return
+	}
+	msg := l.deferred
+	l.deferred = ""
+	l.log(LevelInfo, "%s", msg)
+}
+
 // Info logs informational messages (always shown).
 func (l *Logger) Info(format string, args ...interface{}) {
 	l.log(LevelInfo, format, args...)
 }
 
 // InfoWithData logs informational messages with additional structured data (for JSON output).
-func (l *Logger) InfoWithData(message string, data map[string]interface{}) {
+// Data is a map or a struct of package output.
+func (l *Logger) InfoWithData(message string, data interface{}) {
+	l.flushDeferred()
 	if l.format == FormatJSON {
 		l.writeJSON(l.out, "info", message, data)
 	} else {
@@ -111,6 +168,7 @@ func (l *Logger) Debug(format string, args ...interface{}) {
 
 // Error logs error messages to stderr.
 func (l *Logger) Error(format string, args ...interface{}) {
+	l.flushDeferred()
 	msg := fmt.Sprintf(format, args...)
 	if l.format == FormatJSON {
 		l.writeJSON(l.errOut, "error", msg, nil)
@@ -123,6 +181,7 @@ func (l *Logger) Error(format string, args ...interface{}) {
 
 // Warn logs warning messages (yellow in text mode).
 func (l *Logger) Warn(format string, args ...interface{}) {
+	l.flushDeferred()
 	msg := fmt.Sprintf(format, args...)
 	if l.format == FormatJSON {
 		l.writeJSON(l.out, "warn", msg, nil)
@@ -134,34 +193,38 @@ func (l *Logger) Warn(format string, args ...interface{}) {
 }
 
 // HTTPRequest logs an HTTP request (debug level).
-func (l *Logger) HTTPRequest(method, url string) {
+func (l *Logger) HTTPRequest(method, url, requestID string) {
 	if l.level < LevelDebug {
 		return
 	}
+	l.flushDeferred()
 	if l.format == FormatJSON {
 		l.writeJSON(l.out, "debug", "HTTP request", map[string]interface{}{
-			"type":   "request",
-			"method": method,
-			"url":    url,
+			"type":      "request",
+			"method":    method,
+			"url":       url,
+			"requestId": requestID,
 		})
 	} else {
 		prefix := l.getPrefix()
 		label := l.colorize(colorCyan, "REQUEST")
 		methodColored := l.colorize(colorBold, method)
-		fmt.Fprintf(l.out, "%s%s %s %s\n", prefix, label, methodColored, url)
+		fmt.Fprintf(l.out, "%s%s %s %s%s\n", prefix, label, methodColored, url, l.requestIDSuffix(requestID))
 	}
 }
 
 // HTTPResponse logs an HTTP response (debug level).
-func (l *Logger) HTTPResponse(method, url string, statusCode int) {
+func (l *Logger) HTTPResponse(method, url, requestID string, statusCode int) {
 	if l.level < LevelDebug {
 		return
 	}
+	l.flushDeferred()
 	if l.format == FormatJSON {
 		l.writeJSON(l.out, "debug", "HTTP response", map[string]interface{}{
 			"type":       "response",
 			"method":     method,
 			"url":        url,
+			"requestId":  requestID,
 			"statusCode": statusCode,
 		})
 	} else {
@@ -169,12 +232,22 @@ func (l *Logger) HTTPResponse(method, url string, statusCode int) {
 		label := l.colorize(colorCyan, "RESPONSE")
 		methodColored := l.colorize(colorBold, method)
 		statusColored := l.colorizeStatus(statusCode)
-		fmt.Fprintf(l.out, "%s%s %s %s -> %s\n", prefix, label, methodColored, url, statusColored)
+		fmt.Fprintf(l.out, "%s%s %s %s -> %s%s\n",
+			prefix, label, methodColored, url, statusColored, l.requestIDSuffix(requestID))
+	}
+}
+
+// requestIDSuffix formats the request ID of HTTP log lines, if any.
+func (l *Logger) requestIDSuffix(requestID string) string {
+	if requestID == "" {
+		return ""
 	}
+	return " " + l.colorize(colorGray, "["+requestID+"]")
 }
 
 // Table prints a table with headers and rows.
 func (l *Logger) Table(title string, headers []string, rows [][]string) {
+	l.flushDeferred()
 	if l.format == FormatJSON {
 		data := make([]map[string]string, len(rows))
 		for i, row := range rows {
@@ -195,15 +268,15 @@ func (l *Logger) Table(title string, headers []string, rows [][]string) {
 		return
 	}
 
-	// Calculate column widths
+	// Calculate column widths, in runes like the padding of fmt
 	widths := make([]int, len(headers))
 	for i, h := range headers {
-		widths[i] = len(h)
+		widths[i] = utf8.RuneCountInString(h)
 	}
 	for _, row := range rows {
 		for i, cell := range row {
-			if i < len(widths) && len(cell) > widths[i] {
-				widths[i] = len(cell)
+			if i < len(widths) {
+				widths[i] = max(widths[i], utf8.RuneCountInString(cell))
 			}
 		}
 	}
@@ -236,6 +309,7 @@ func (l *Logger) Diff(op, content string) {
 	if l.level < LevelDebug {
 		return
 	}
+	l.flushDeferred()
 	if l.format == FormatJSON {
 		l.writeJSON(l.out, "debug", "diff", map[string]interface{}{
 			"operation": op,
@@ -258,6 +332,7 @@ func (l *Logger) Diff(op, content string) {
 }
 
 func (l *Logger) log(level Level, format string, args ...interface{}) {
+	l.flushDeferred()
 	msg := fmt.Sprintf(format, args...)
 	if l.format == FormatJSON {
 		levelStr := "info"
@@ -276,10 +351,14 @@ func (l *Logger) log(level Level, format string, args ...interface{}) {
 }
 
 func (l *Logger) getPrefix() string {
+	prefix := ""
 	if l.dryRun {
-		return l.colorize(colorYellow, "[DRY RUN] ")
+		prefix = l.colorize(colorYellow, "[DRY RUN] ")
+	}
+	if l.partition != "" {
+		prefix += l.colorize(colorCyan, "["+l.partition+"] ")
 	}
-	return ""
+	return prefix
 }
 
 func (l *Logger) colorize(color, text string) string {
@@ -301,18 +380,18 @@ func (l *Logger) colorizeStatus(statusCode int) string {
 	}
 }
 
-func (l *Logger) writeJSON(out io.Writer, level, message string, data map[string]interface{}) {
+func (l *Logger) writeJSON(out io.Writer, level, message string, data interface{}) {
 	entry := LogEntry{
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
-		Level:     level,
-		Message:   message,
-		Data:      data,
+		Timestamp:     time.Now().UTC().Format(time.RFC3339),
+		Level:         level,
+		Message:       message,
+		Data:          data,
+		RunID:         l.runID,
+		Partition:     l.partition,
+		SchemaVersion: output.SchemaVersion,
 	}
 	if l.dryRun {
-		if entry.Data == nil {
-			entry.Data = make(map[string]interface{})
-		}
-		entry.Data["dryRun"] = true
+		entry.Data = withDryRun(data)
 	}
 	jsonData, err := json.Marshal(entry)
 	if err != nil {
@@ -323,6 +402,25 @@ func (l *Logger) writeJSON(out io.Writer, level, message string, data map[string
 	fmt.Fprintln(out, string(jsonData))
 }
 
+// withDryRun returns data as a map with "dryRun": true added. Structs are
+// converted through their JSON encoding.
+func withDryRun(data interface{}) map[string]interface{} {
+	m, ok := data.(map[string]interface{})
+	if !ok {
+		m = make(map[string]interface{})
+		if data != nil {
+			if encoded, err := json.Marshal(data); err == nil {
+				_ = json.Unmarshal(encoded, &m) //nolint:errcheck // encoded from a struct, cannot fail
+			}
+		}
+	}
+	if m == nil {
+		m = make(map[string]interface{})
+	}
+	m["dryRun"] = true
+	return m
+}
+
 // MaskSecret masks sensitive data, showing only first and last 2 chars.
 func MaskSecret(secret string) string {
 	if len(secret) <= 4 {
@@ -331,6 +429,18 @@ func MaskSecret(secret string) string {
 	return secret[:2] + strings.Repeat("*", len(secret)-4) + secret[len(secret)-2:]
 }
 
+// FormatBytes formats a size in bytes with a binary unit, e.g. 1.5 KiB.
+func FormatBytes(n int) string {
+	switch {
+	case n < 1<<10:
+		return strconv.Itoa(n) + " B"
+	case n < 1<<20:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	}
+}
+
 // MaskURL masks API key in URL if present.
 func MaskURL(url string) string {
 	// URLs shouldn't contain API keys, but just in case
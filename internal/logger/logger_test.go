@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"strings"
 	"testing"
+
+	"github.com/kreigan/powerdns-zone-manager/pkg/output"
 )
 
 func TestMaskSecret(t *testing.T) {
@@ -32,6 +34,47 @@ func TestMaskSecret(t *testing.T) {
 	}
 }
 
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		input    int
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1536, "1.5 KiB"},
+		{2 << 20, "2.0 MiB"},
+	}
+
+	for _, tt := range tests {
+		if result := FormatBytes(tt.input); result != tt.expected {
+			t.Errorf("FormatBytes(%d) = %q, want %q", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestColorEnabled(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected bool
+	}{
+		{"no color", map[string]string{"NO_COLOR": "1", "FORCE_COLOR": "1"}, false},
+		{"force color", map[string]string{"FORCE_COLOR": "1", "TERM": "dumb"}, true},
+		{"dumb terminal", map[string]string{"TERM": "dumb"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"NO_COLOR", "FORCE_COLOR", "TERM"} {
+				t.Setenv(name, tt.env[name])
+			}
+			if got := colorEnabled(); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
 func TestLogger_Info(t *testing.T) {
 	var buf bytes.Buffer
 	log := New(Options{Verbose: false, NoColor: true})
@@ -72,6 +115,26 @@ func TestLogger_Debug_NotVerbose(t *testing.T) {
 	}
 }
 
+func TestLogger_Defer(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(Options{Verbose: false, NoColor: true})
+	log.out = &buf
+
+	log.Defer("Zone %s", "a.example")
+	log.Debug("Not shown")
+	log.Defer("Zone %s", "b.example")
+	log.Info("Change")
+	log.Info("Another change")
+	log.Defer("Zone %s", "c.example")
+	log.DiscardDeferred()
+	log.Info("Summary")
+
+	expected := "Zone b.example\nChange\nAnother change\nSummary\n"
+	if output := buf.String(); output != expected {
+		t.Errorf("Expected output %q, got %q", expected, output)
+	}
+}
+
 func TestLogger_DryRunPrefix(t *testing.T) {
 	var buf bytes.Buffer
 	log := New(Options{Verbose: false, NoColor: true})
@@ -110,6 +173,71 @@ func TestLogger_JSON_Output(t *testing.T) {
 	}
 }
 
+func TestLogger_JSON_RunID(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(Options{JSON: true})
+	log.out = &buf
+	log.SetRunID("20260102T150405-9b1c")
+
+	log.Warn("Test warning")
+
+	var entry LogEntry
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+	if entry.RunID != "20260102T150405-9b1c" {
+		t.Errorf("Expected run ID to be set, got: %q", entry.RunID)
+	}
+}
+
+func TestLogger_WithPartition(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(Options{JSON: true})
+	log.out = &buf
+
+	log.WithPartition("payments").Info("Test message")
+	entry, err := output.Decode(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if entry.Partition != "payments" {
+		t.Errorf("Expected partition to be set, got: %q", entry.Partition)
+	}
+
+	buf.Reset()
+	text := New(Options{NoColor: true})
+	text.out = &buf
+	text.SetDryRun(true)
+	text.WithPartition("payments").Info("Test message")
+	if buf.String() != "[DRY RUN] [payments] Test message\n" {
+		t.Errorf("Unexpected output %q", buf.String())
+	}
+}
+
+func TestLogger_JSON_Data(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(Options{JSON: true})
+	log.out = &buf
+	log.SetDryRun(true)
+
+	log.InfoWithData(output.MessageApplyEvent, output.ApplyEvent{Event: output.EventPatchSent, RRsets: 2})
+
+	entry, err := output.Decode(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to decode JSON output: %v", err)
+	}
+	if entry.SchemaVersion != output.SchemaVersion || !entry.DryRun() {
+		t.Errorf("Expected versioned dry run entry, got: %+v", entry)
+	}
+	var event output.ApplyEvent
+	if err := entry.DecodeData(&event); err != nil {
+		t.Fatalf("Failed to decode data: %v", err)
+	}
+	if event.Event != output.EventPatchSent || event.RRsets != 2 {
+		t.Errorf("Unexpected event data: %+v", event)
+	}
+}
+
 func TestLogger_JSON_Debug(t *testing.T) {
 	var buf bytes.Buffer
 	log := New(Options{Verbose: true, JSON: true})
@@ -133,7 +261,7 @@ func TestLogger_HTTPRequest(t *testing.T) {
 	log := New(Options{Verbose: true, NoColor: true})
 	log.out = &buf
 
-	log.HTTPRequest("GET", "http://example.com/api")
+	log.HTTPRequest("GET", "http://example.com/api", "3f2a9c1e")
 
 	output := buf.String()
 	if !strings.Contains(output, "REQUEST") {
@@ -142,6 +270,9 @@ func TestLogger_HTTPRequest(t *testing.T) {
 	if !strings.Contains(output, "GET") {
 		t.Errorf("Expected output to contain 'GET', got: %s", output)
 	}
+	if !strings.Contains(output, "[3f2a9c1e]") {
+		t.Errorf("Expected output to contain the request ID, got: %s", output)
+	}
 }
 
 func TestLogger_HTTPResponse(t *testing.T) {
@@ -149,7 +280,7 @@ func TestLogger_HTTPResponse(t *testing.T) {
 	log := New(Options{Verbose: true, NoColor: true})
 	log.out = &buf
 
-	log.HTTPResponse("GET", "http://example.com/api", 200)
+	log.HTTPResponse("GET", "http://example.com/api", "3f2a9c1e", 200)
 
 	output := buf.String()
 	if !strings.Contains(output, "RESPONSE") {
@@ -184,3 +315,27 @@ func TestLogger_Table(t *testing.T) {
 		t.Errorf("Expected output to contain 'www', got: %s", output)
 	}
 }
+
+func TestLogger_Quiet(t *testing.T) {
+	var buf, errBuf bytes.Buffer
+	log := New(Options{Verbose: true, NoColor: true})
+	log.out = &buf
+	log.errOut = &errBuf
+
+	quiet := log.Quiet()
+	quiet.Info("info message")
+	quiet.Debug("debug message")
+	quiet.Error("error message")
+
+	if buf.String() != "" {
+		t.Errorf("Expected no output from quiet logger, got: %s", buf.String())
+	}
+	if !strings.Contains(errBuf.String(), "error message") {
+		t.Errorf("Expected errors to still be logged, got: %s", errBuf.String())
+	}
+
+	log.Info("still logging")
+	if !strings.Contains(buf.String(), "still logging") {
+		t.Error("Expected original logger to be unaffected")
+	}
+}
@@ -3,96 +3,551 @@ package manager
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/graph"
+	"github.com/kreigan/powerdns-zone-manager/internal/i18n"
+	"github.com/kreigan/powerdns-zone-manager/internal/lock"
 	"github.com/kreigan/powerdns-zone-manager/internal/logger"
+	"github.com/kreigan/powerdns-zone-manager/internal/ownership"
 	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
 )
 
 // ErrAborted is returned when user cancels the operation.
 var ErrAborted = errors.New("operation aborted by user")
 
-// PowerDNSClient defines the interface for PowerDNS operations.
-type PowerDNSClient interface {
+// ErrZoneModified is returned for zones whose serial changed since their
+// changes were planned, see ApplyOptions.Serials.
+var ErrZoneModified = errors.New("zone was modified since the changes were planned, plan them again")
+
+// Provider is a DNS backend that zones are reconciled against.
+// Zones and RRsets use the PowerDNS API data model; the PowerDNS API client is
+// the primary implementation.
+type Provider interface {
 	CreateZone(ctx context.Context, zone *powerdns.Zone) (*powerdns.Zone, error)
 	GetZone(ctx context.Context, zoneID string) (*powerdns.Zone, error)
+	GetZoneInfo(ctx context.Context, zoneID string) (*powerdns.Zone, error)
 	PatchZone(ctx context.Context, zoneID string, patch *powerdns.ZonePatch) error
+	AxfrRetrieve(ctx context.Context, zoneID string) (string, error)
+}
+
+// MetadataProvider is implemented by providers that support zone metadata.
+type MetadataProvider interface {
+	GetMetadata(ctx context.Context, zoneID string) ([]powerdns.Metadata, error)
+	SetMetadata(ctx context.Context, zoneID, kind string, values []string) error
+	DeleteMetadata(ctx context.Context, zoneID, kind string) error
+}
+
+// Rectifier is implemented by providers that can rectify DNSSEC zones.
+type Rectifier interface {
+	RectifyZone(ctx context.Context, zoneID string) (string, error)
+}
+
+// ServerProvider is implemented by providers that report the server version.
+type ServerProvider interface {
+	GetServer(ctx context.Context) (*powerdns.Server, error)
+}
+
+// QueryStatsProvider is implemented by providers that report how often names
+// are queried, see powerdns.Client.GetQueryCounts.
+type QueryStatsProvider interface {
+	GetQueryCounts(ctx context.Context) (map[string]int64, error)
+}
+
+// ZoneFilterProvider is implemented by providers that can drop RRsets while
+// reading a zone, see powerdns.Client.GetZoneFiltered.
+type ZoneFilterProvider interface {
+	GetZoneFiltered(ctx context.Context, zoneID string, keep powerdns.RRsetFilter) (*powerdns.Zone, error)
+}
+
+// RecursorProvider is implemented by providers that manage the zones of a
+// recursor, see config.Zone.Target.
+type RecursorProvider interface {
+	GetRecursorZone(ctx context.Context, zoneID string) (*powerdns.RecursorZone, error)
+	CreateRecursorZone(ctx context.Context, zone *powerdns.RecursorZone) error
+	ReplaceRecursorZone(ctx context.Context, zone *powerdns.RecursorZone) error
+}
+
+// Resolver looks up host names in DNS. *net.Resolver implements it.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
 }
 
 // Manager manages PowerDNS zones and records.
 type Manager struct {
-	client      PowerDNSClient
+	provider    Provider
 	log         *logger.Logger
-	confirmFn   ConfirmFunc
+	confirmer   Confirmer
+	eventFn     EventFunc
 	accountName string
+	toolVersion string
+	runID       string
+	// owner is the ownership marker written by the current Apply
+	owner ownership.Marker
+	// ptrs are the PTR rrsets generated for set_ptr records by zone, used
+	// when the server does not support set-ptr (see planPTRs)
+	ptrs map[string][]config.RRset
+	// locations are the configuration locations of the desired rrsets of
+	// the zone being applied by rrset key, see locateError
+	locations map[string]config.Location
+	// labels are the labels of the desired rrsets of the zone being applied
+	// by rrset key, and zoneLabels those of the zone, see changeLabels
+	labels     map[string]map[string]string
+	zoneLabels map[string]string
+	// createOnly are the keys of the desired rrsets of the zone being
+	// applied that are only created, see config.ChangePolicyCreateOnly
+	createOnly map[string]bool
+	// absent are the rrsets of the zone being applied that are declared
+	// absent by rrset key, see config.StateAbsent
+	absent map[string]powerdns.RRset
+	// resolver looks up targets outside of the configured zones, see checkTargets
+	resolver Resolver
+	// setPTR passes set_ptr through to the server
+	setPTR bool
+	// server is the server of the provider, loaded once, see getServer
+	server *powerdns.Server
+	// queryCounts are the recent query counts of the server, loaded by the
+	// first deletion checked with ApplyOptions.CheckQueries
+	queryCounts map[string]int64
 }
 
 // NewManager creates a new manager.
-func NewManager(client PowerDNSClient, accountName string, log *logger.Logger) *Manager {
+func NewManager(provider Provider, accountName string, log *logger.Logger) *Manager {
 	return &Manager{
-		client:      client,
+		provider:    provider,
 		accountName: accountName,
 		log:         log,
+		owner:       ownership.Marker{Account: accountName, Version: ownership.Version},
+		resolver:    net.DefaultResolver,
+	}
+}
+
+// SetToolVersion sets the tool version recorded in ownership comments.
+func (m *Manager) SetToolVersion(version string) {
+	m.toolVersion = version
+}
+
+// SetRunID sets the run ID recorded in ownership comments.
+func (m *Manager) SetRunID(id string) {
+	m.runID = id
+}
+
+// SetResolver sets the resolver used to look up dangling targets.
+func (m *Manager) SetResolver(r Resolver) {
+	m.resolver = r
+}
+
+// SetEventFunc sets a function that receives apply progress events as they happen.
+func (m *Manager) SetEventFunc(fn EventFunc) {
+	m.eventFn = fn
+}
+
+// EventType is the kind of an apply progress event.
+type EventType string
+
+// Event types.
+const (
+	EventZoneStarted  EventType = "zone_started"
+	EventZoneCreated  EventType = "zone_created"
+	EventRRset        EventType = "rrset"
+	EventPatchSent    EventType = "patch_sent"
+	EventZoneFinished EventType = "zone_finished"
+)
+
+// Event is an apply progress event. RRset events are sent when a change is
+// planned, before the zone is patched; in a dry run no patch is sent.
+type Event struct {
+	// Change is set for EventRRset
+	Change *Change
+	Type   EventType
+	Zone   string
+	// Status, Error and Duration are set for EventZoneFinished
+	Status   ZoneStatus
+	Error    string
+	Duration time.Duration
+	// RRsets is the number of RRsets in the patch for EventPatchSent
+	RRsets int
+}
+
+// EventFunc receives apply progress events.
+type EventFunc func(Event)
+
+func (m *Manager) emit(ev Event) {
+	if m.eventFn != nil {
+		m.eventFn(ev)
 	}
 }
 
+// emitChange sends an RRset event for the last change of the zone result.
+func (m *Manager) emitChange(zoneID string, zr *ZoneResult) {
+	change := zr.Changes[len(zr.Changes)-1]
+	change.Labels = m.changeLabels(change.Name, change.Type)
+	m.emit(Event{Type: EventRRset, Zone: zoneID, Change: &change})
+}
+
+// finishZone records a zone result and sends its EventZoneFinished.
+func (m *Manager) finishZone(result *ApplyResult, zr *ZoneResult) {
+	m.log.DiscardDeferred()
+	result.add(zr)
+	m.emit(Event{
+		Type:     EventZoneFinished,
+		Zone:     config.CanonicalZoneName(zr.Name),
+		Status:   zr.Status,
+		Error:    zr.Error,
+		Duration: zr.Duration,
+	})
+}
+
 // ApplyOptions contains options for the Apply operation.
 type ApplyOptions struct {
 	DryRun      bool
 	AutoConfirm bool
+	// Rectify rectifies changed DNSSEC zones that do not have API-RECTIFY enabled.
+	Rectify bool
+	// Lock locks existing zones while they are applied (see package lock).
+	// Locks expire after LockTTL; ForceUnlock takes over locks of other runs.
+	Lock        bool
+	ForceUnlock bool
+	LockTTL     time.Duration
+	// ResolveTargets looks up targets outside of the configured zones in DNS,
+	// reporting those that do not exist as dangling (see checkTargets).
+	ResolveTargets bool
+	// CheckQueries refuses to delete orphaned or absent rrsets that still
+	// receive queries according to the server statistics, unless Force is set.
+	CheckQueries bool
+	Force        bool
+	// Pacing sends the changes of large zones in batches, the zero value
+	// sends every zone in a single patch.
+	Pacing Pacing
+	// Completed are the configured names of the zones that a failed run
+	// applied already; resuming it, they are skipped (see package journal).
+	Completed map[string]bool
+	// Serials are the SOA serials of existing zones when their changes were
+	// planned, by configured zone name. A zone whose serial changed since was
+	// modified by someone else and is not applied, see ErrZoneModified.
+	Serials map[string]uint32
+	// OnlyChanges leaves out the tables of the current and desired records,
+	// and the zones without changes, from the output.
+	OnlyChanges bool
+}
+
+// Pacing limits the load that patches of large zones put on the server, e.g.
+// the lock contention of big transactions in MySQL backends. The changes of a
+// large zone are sent in patches of at most BatchSize rrsets, pausing between
+// them. Each batch is applied on its own: if one fails, the earlier batches
+// remain applied. The changes of an owner name are never split across
+// batches, so that e.g. a CNAME replaced by an A record is applied at once.
+type Pacing struct {
+	// LargeZoneRRsets is the number of existing or desired rrsets from which
+	// a zone is large, 0 disables pacing
+	LargeZoneRRsets int
+	// BatchSize is the maximum number of rrset changes per patch, exceeded
+	// only by the changes of a single owner name
+	BatchSize int
+	// Pause is the time between the patches of a large zone
+	Pause time.Duration
+	// SizeLimit is the largest request body in bytes that the server, or a
+	// proxy in front of it, accepts. Patches close to it are reported with a
+	// warning, 0 disables the check
+	SizeLimit int
+}
+
+// DefaultPacing is the pacing of apply unless configured otherwise. Pacing is
+// opt-in, as it gives up the atomic patch of a zone; the batch size and pause
+// apply once LargeZoneRRsets is set. The size limit is the default
+// webserver-max-bodysize of PowerDNS, 2 MB.
+var DefaultPacing = Pacing{BatchSize: 1000, Pause: time.Second, SizeLimit: 2 << 20}
+
+// patchSizeWarnRatio is the share of Pacing.SizeLimit from which a patch is
+// reported as close to the limit.
+const patchSizeWarnRatio = 0.8
+
+// batches splits the changes of a zone with size rrsets into the patches to
+// send, keeping the changes of each owner name in one patch.
+func (p Pacing) batches(size int, rrsets []powerdns.RRset) [][]powerdns.RRset {
+	if p.LargeZoneRRsets <= 0 || size < p.LargeZoneRRsets || p.BatchSize <= 0 || len(rrsets) <= p.BatchSize {
+		return [][]powerdns.RRset{rrsets}
+	}
+
+	var names []string
+	byName := make(map[string][]powerdns.RRset)
+	for _, rrset := range rrsets {
+		name := strings.ToLower(rrset.Name)
+		if _, ok := byName[name]; !ok {
+			names = append(names, name)
+		}
+		byName[name] = append(byName[name], rrset)
+	}
+
+	var batches [][]powerdns.RRset
+	var batch []powerdns.RRset
+	for _, name := range names {
+		if len(batch) > 0 && len(batch)+len(byName[name]) > p.BatchSize {
+			batches = append(batches, batch)
+			batch = nil
+		}
+		batch = append(batch, byName[name]...)
+	}
+	return append(batches, batch)
+}
+
+// ConfirmRequest describes the changes of a zone that need confirmation.
+type ConfirmRequest struct {
+	Zone   string
+	Prompt string
+	RunID  string
+	// Changes are the rrset changes, Metadata the metadata changes; one of
+	// them is set per request
+	Changes  []Change
+	Metadata []MetadataChange
+}
+
+// Confirmer decides whether the changes of a zone are applied, e.g. by asking
+// the user or evaluating a policy. An error aborts the apply, e.g. when no
+// decision could be obtained.
+type Confirmer interface {
+	Confirm(ctx context.Context, req *ConfirmRequest) (bool, error)
 }
 
 // ConfirmFunc is a function that asks for user confirmation.
 type ConfirmFunc func(prompt string) bool
 
+// Confirm implements Confirmer.
+func (f ConfirmFunc) Confirm(_ context.Context, req *ConfirmRequest) (bool, error) {
+	return f(req.Prompt), nil
+}
+
 // ApplyResult contains the results of an Apply operation.
 type ApplyResult struct {
-	ZonesCreated  int
+	Zones           []ZoneResult
+	ZonesCreated    int
+	RRsetsCreated   int
+	RRsetsUpdated   int
+	RRsetsDeleted   int
+	RRsetsScheduled int
+	MetadataUpdated int
+	// PatchRecords and PatchBytes are the totals of the zones
+	PatchRecords int
+	PatchBytes   int
+}
+
+// ZoneStatus is the outcome of applying a single zone.
+type ZoneStatus string
+
+// Zone statuses.
+const (
+	ZoneStatusOK      ZoneStatus = "ok"
+	ZoneStatusFailed  ZoneStatus = "failed"
+	ZoneStatusSkipped ZoneStatus = "skipped"
+	// ZoneStatusScheduled is a zone whose creation is scheduled for later.
+	ZoneStatusScheduled ZoneStatus = "scheduled"
+	// ZoneStatusCompleted is a zone applied by an earlier run that is
+	// resumed, see ApplyOptions.Completed.
+	ZoneStatusCompleted ZoneStatus = "completed"
+)
+
+// ZoneResult contains the results of applying a single zone.
+type ZoneResult struct {
+	Name          string
+	Status        ZoneStatus
+	Error         string
+	Changes       []Change
+	Duration      time.Duration
+	Created       bool
 	RRsetsCreated int
 	RRsetsUpdated int
 	RRsetsDeleted int
+	Metadata      []MetadataChange
+	// Scheduled are changes that are not applied before their ApplyAfter time
+	Scheduled []Change
+	// Labels are the labels of the zone in the configuration
+	Labels    map[string]string
+	Rectified bool
+	// Serial is the SOA serial of an existing zone before the apply
+	Serial uint32
+	// PatchRecords and PatchBytes are the number of records and the size of
+	// the JSON bodies of the patches that apply the changes of the zone
+	PatchRecords int
+	PatchBytes   int
+}
+
+// HasChanges reports whether the zone was created or changed, or has
+// scheduled changes.
+func (zr *ZoneResult) HasChanges() bool {
+	return zr.Created || zr.RRsetsCreated+zr.RRsetsUpdated+zr.RRsetsDeleted > 0 || len(zr.Scheduled) > 0 ||
+		len(zr.Metadata) > 0 || zr.Rectified
+}
+
+// MetadataChange describes a change of a zone metadata kind.
+// After is empty if the metadata is removed.
+type MetadataChange struct {
+	Kind   string
+	Before []string
+	After  []string
+}
+
+// ChangeAction is the kind of change made to an RRset.
+type ChangeAction string
+
+// Change actions.
+const (
+	ChangeCreate ChangeAction = "create"
+	ChangeUpdate ChangeAction = "update"
+	ChangeDelete ChangeAction = "delete"
+)
+
+// Change describes a single RRset change, planned or applied.
+// Before is empty for created RRsets, After is empty for deleted ones.
+type Change struct {
+	// ApplyAfter is set for scheduled changes, see ZoneResult.Scheduled
+	ApplyAfter time.Time
+	Action     ChangeAction
+	Name       string
+	Type       string
+	Before     []powerdns.Record
+	After      []powerdns.Record
+	// Location is where the rrset is defined in the configuration, or the
+	// zone for deletions of rrsets that are no longer configured
+	Location config.Location
+	// Labels are the labels of the rrset in the configuration, or of the
+	// zone for rrsets that are not configured
+	Labels map[string]string
+	OldTTL uint32
+	NewTTL uint32
+}
+
+func (zr *ZoneResult) addCreate(desired *powerdns.RRset) {
+	zr.Changes = append(zr.Changes, Change{
+		Action: ChangeCreate,
+		Name:   desired.Name,
+		Type:   desired.Type,
+		After:  desired.Records,
+		NewTTL: desired.TTL,
+	})
+	zr.RRsetsCreated++
+}
+
+func (zr *ZoneResult) addUpdate(existing, desired *powerdns.RRset) {
+	zr.Changes = append(zr.Changes, Change{
+		Action: ChangeUpdate,
+		Name:   desired.Name,
+		Type:   desired.Type,
+		Before: existing.Records,
+		After:  desired.Records,
+		OldTTL: existing.TTL,
+		NewTTL: desired.TTL,
+	})
+	zr.RRsetsUpdated++
+}
+
+func (zr *ZoneResult) addDelete(existing *powerdns.RRset) {
+	zr.Changes = append(zr.Changes, Change{
+		Action: ChangeDelete,
+		Name:   existing.Name,
+		Type:   existing.Type,
+		Before: existing.Records,
+		OldTTL: existing.TTL,
+	})
+	zr.RRsetsDeleted++
+}
+
+func (zr *ZoneResult) addScheduled(action ChangeAction, existing, desired *powerdns.RRset, at time.Time) {
+	change := Change{Action: action, ApplyAfter: at}
+	if existing != nil {
+		change.Name, change.Type = existing.Name, existing.Type
+		change.Before, change.OldTTL = existing.Records, existing.TTL
+	}
+	if desired != nil {
+		change.Name, change.Type = desired.Name, desired.Type
+		change.After, change.NewTTL = desired.Records, desired.TTL
+	}
+	zr.Scheduled = append(zr.Scheduled, change)
+}
+
+// add accumulates a zone result into the aggregate counters.
+func (r *ApplyResult) add(zr *ZoneResult) {
+	r.Zones = append(r.Zones, *zr)
+	if zr.Created {
+		r.ZonesCreated++
+	}
+	r.RRsetsCreated += zr.RRsetsCreated
+	r.RRsetsUpdated += zr.RRsetsUpdated
+	r.RRsetsDeleted += zr.RRsetsDeleted
+	r.RRsetsScheduled += len(zr.Scheduled)
+	r.MetadataUpdated += len(zr.Metadata)
+	r.PatchRecords += zr.PatchRecords
+	r.PatchBytes += zr.PatchBytes
 }
 
 // Apply applies the configuration to PowerDNS.
 // It first fetches all existing zones, validates the config, then applies changes.
+// If a zone fails, the remaining zones are skipped and the partial result is
+// returned along with the error.
 func (m *Manager) Apply(
 	ctx context.Context,
 	cfg *config.Config,
 	opts ApplyOptions,
 ) (*ApplyResult, error) {
 	result := &ApplyResult{}
+	m.owner = ownership.Marker{
+		Account:    m.accountName,
+		Version:    ownership.Version,
+		Tool:       m.toolVersion,
+		ConfigHash: cfg.Hash(),
+		RunID:      m.runID,
+		Time:       time.Now().UTC().Truncate(time.Second),
+	}
+	m.queryCounts = nil
 
-	// Step 1: Fetch current state of all zones in config
+	// Step 1: Fetch current state of all zones in config.
+	// Only zone metadata is fetched here; RRsets are loaded lazily per zone.
 	m.log.Info("Fetching current state of %d zone(s)...", len(cfg.Zones))
 	existingZones := make(map[string]config.ZoneState)
-	zoneData := make(map[string]*powerdns.Zone)
+	zoneInfos := make(map[string]*powerdns.Zone)
+	recursorZones := make(map[string]*powerdns.RecursorZone)
 
-	for zoneName := range cfg.Zones {
+	for _, zoneName := range cfg.ZoneNames() {
+		zoneConfig := cfg.Zones[zoneName]
 		canonicalName := config.CanonicalZoneName(zoneName)
 		m.log.Info("  Checking zone: %s", canonicalName)
-		zone, err := m.client.GetZone(ctx, canonicalName)
+		if zoneConfig.IsRecursor() {
+			zone, err := m.getRecursorZone(ctx, canonicalName)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check zone %s: %w", zoneName, err)
+			}
+			recursorZones[canonicalName] = zone
+			// Recursor zones have no account, they are managed by the configuration
+			existingZones[canonicalName] = config.ZoneState{Exists: zone != nil, IsManaged: zone != nil}
+			continue
+		}
+		zone, err := m.provider.GetZoneInfo(ctx, canonicalName)
 		if err != nil {
 			return nil, fmt.Errorf("failed to check zone %s: %w", zoneName, err)
 		}
 
 		if zone != nil {
+			zoneInfos[canonicalName] = zone
 			isManaged := zone.Account == m.accountName
 			existingZones[canonicalName] = config.ZoneState{
 				Exists:    true,
 				IsManaged: isManaged,
 			}
-			zoneData[canonicalName] = zone
 			if isManaged {
 				m.log.Info("    Zone exists (managed)")
 			} else {
 				m.log.Info("    Zone exists (not managed, account=%q)", zone.Account)
 			}
-			// Show existing managed records
-			m.printManagedRRsets("Current managed records", zone)
 		} else {
 			existingZones[canonicalName] = config.ZoneState{
 				Exists:    false,
@@ -107,26 +562,177 @@ func (m *Manager) Apply(
 	if validationErr := cfg.Validate(existingZones); validationErr != nil {
 		return nil, validationErr
 	}
+	if err := m.checkRecordTypes(ctx, cfg); err != nil {
+		return nil, err
+	}
+	if err := m.checkTargets(ctx, cfg, opts.ResolveTargets); err != nil {
+		return nil, err
+	}
+	if err := m.checkDualStack(cfg); err != nil {
+		return nil, err
+	}
+	if err := m.checkCAA(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := m.planPTRs(ctx, cfg); err != nil {
+		return nil, err
+	}
 
 	// Step 3: Apply changes
-	for zoneName, zoneConfig := range cfg.Zones {
+	var applyErr error
+	for _, zoneName := range cfg.ZoneNames() {
+		zr := &ZoneResult{Name: zoneName, Status: ZoneStatusSkipped, Labels: cfg.Zones[zoneName].Labels}
+		if applyErr != nil {
+			m.finishZone(result, zr)
+			continue
+		}
+
+		if opts.Completed[zoneName] {
+			m.log.Info("Skipping zone %s, applied by the resumed run", zoneName)
+			zr.Status = ZoneStatusCompleted
+			m.finishZone(result, zr)
+			continue
+		}
+
+		zoneConfig := cfg.Zones[zoneName]
 		zoneConfig.NormalizeZone()
 		canonicalName := config.CanonicalZoneName(zoneName)
 		state := existingZones[canonicalName]
+		if info := zoneInfos[canonicalName]; info != nil {
+			zr.Serial = info.Serial
+		}
 
-		m.log.Info("Processing zone: %s", zoneName)
-		err := m.applyZone(ctx, canonicalName, &zoneConfig, state, zoneData[canonicalName], opts, result)
+		if opts.OnlyChanges {
+			m.log.Defer("Processing zone: %s", zoneName)
+		} else {
+			m.log.Info("Processing zone: %s", zoneName)
+		}
+		m.emit(Event{Type: EventZoneStarted, Zone: canonicalName})
+		if zoneConfig.IsRecursor() {
+			start := time.Now()
+			err := m.applyRecursorZone(ctx, canonicalName, &zoneConfig, recursorZones[canonicalName], opts, zr)
+			zr.Duration = time.Since(start)
+			if err != nil {
+				zr.Status = ZoneStatusFailed
+				zr.Error = err.Error()
+				applyErr = fmt.Errorf("zone %s: %w", zoneName, err)
+			} else {
+				zr.Status = ZoneStatusOK
+			}
+			m.finishZone(result, zr)
+			continue
+		}
+		if at := zoneConfig.ApplyAfterTime(); !state.Exists && time.Now().Before(at) {
+			m.log.Info("  Zone creation scheduled after %s", at.Format(time.RFC3339))
+			zr.Status = ZoneStatusScheduled
+			m.finishZone(result, zr)
+			continue
+		}
+		start := time.Now()
+		unlock, err := m.lockZone(ctx, canonicalName, &zoneConfig, state, opts, zr)
+		// Checked once the lock is held, so that no other locking run can
+		// change the zone after the check
+		if planned, ok := opts.Serials[zoneName]; err == nil && ok && zr.Serial != planned {
+			unlock()
+			err = fmt.Errorf("%w (serial %d, planned at %d)", ErrZoneModified, zr.Serial, planned)
+		}
+		if err != nil {
+			zr.Status = ZoneStatusFailed
+			zr.Error = err.Error()
+			applyErr = fmt.Errorf("zone %s: %w", zoneName, err)
+			m.finishZone(result, zr)
+			continue
+		}
+		err = m.applyZone(ctx, canonicalName, &zoneConfig, state, opts, zr)
+		if err == nil {
+			err = m.applyMetadata(ctx, canonicalName, &zoneConfig, state, opts, zr)
+		}
+		if err == nil && opts.Rectify {
+			err = m.rectifyZone(ctx, canonicalName, zoneInfos[canonicalName], opts, zr)
+		}
+		unlock()
+		zr.Duration = time.Since(start)
 		if err != nil {
-			return nil, fmt.Errorf("zone %s: %w", zoneName, err)
+			zr.Status = ZoneStatusFailed
+			zr.Error = err.Error()
+			applyErr = fmt.Errorf("zone %s: %w", zoneName, err)
+		} else {
+			zr.Status = ZoneStatusOK
 		}
+		m.finishZone(result, zr)
 	}
 
-	return result, nil
+	return result, applyErr
+}
+
+// lockZone locks an existing zone for the current run if locking is enabled,
+// and returns a function that releases the lock. The serial of zr is updated
+// to the serial of the zone when it was locked. New zones are not locked: if
+// another run creates them first, see adoptZone.
+func (m *Manager) lockZone(
+	ctx context.Context,
+	zoneID string,
+	zoneConfig *config.Zone,
+	state config.ZoneState,
+	opts ApplyOptions,
+	zr *ZoneResult,
+) (func(), error) {
+	if !opts.Lock || opts.DryRun || !state.Exists || zoneConfig.Kind == config.KindSlave {
+		return func() {}, nil
+	}
+
+	runID := m.runID
+	if runID == "" {
+		runID = m.owner.Time.Format("20060102T150405")
+	}
+	l := lock.Lock{RunID: runID, Account: m.accountName, Expires: time.Now().Add(opts.LockTTL)}
+	replaced, serial, err := lock.Acquire(ctx, m.provider, zoneID, l, opts.ForceUnlock, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	zr.Serial = serial
+	if replaced != nil {
+		m.log.Warn("  Took over lock of run %s (account %s)", replaced.RunID, replaced.Account)
+	}
+	m.log.Debug("  Locked zone until %s", l.Expires.Format(time.RFC3339))
+
+	return func() {
+		if err := lock.Release(ctx, m.provider, zoneID, runID); err != nil {
+			m.log.Warn("  Failed to release lock: %v", err)
+		}
+	}, nil
 }
 
 // SetConfirmFunc sets the confirmation function for interactive prompts.
 func (m *Manager) SetConfirmFunc(fn ConfirmFunc) {
-	m.confirmFn = fn
+	if fn == nil {
+		m.confirmer = nil
+		return
+	}
+	m.confirmer = fn
+}
+
+// SetConfirmer sets the confirmation provider asked before changes are sent.
+func (m *Manager) SetConfirmer(c Confirmer) {
+	m.confirmer = c
+}
+
+// confirm asks the confirmation provider, unless confirmation is skipped,
+// and returns ErrAborted if the changes are rejected.
+func (m *Manager) confirm(ctx context.Context, req *ConfirmRequest, opts ApplyOptions) error {
+	if opts.AutoConfirm || m.confirmer == nil {
+		return nil
+	}
+	req.RunID = m.runID
+	ok, err := m.confirmer.Confirm(ctx, req)
+	if err != nil {
+		return fmt.Errorf("confirmation failed: %w", err)
+	}
+	if !ok {
+		return ErrAborted
+	}
+	return nil
 }
 
 func (m *Manager) applyZone(
@@ -134,10 +740,10 @@ func (m *Manager) applyZone(
 	zoneID string,
 	zoneConfig *config.Zone,
 	state config.ZoneState,
-	existingZone *powerdns.Zone,
 	opts ApplyOptions,
-	result *ApplyResult,
+	result *ZoneResult,
 ) error {
+	var existingZone *powerdns.Zone
 	if !state.Exists {
 		// Create new zone
 		m.log.Info("  Creating zone: %s (kind=%s)", zoneID, zoneConfig.Kind)
@@ -146,10 +752,19 @@ func (m *Manager) applyZone(
 				Name:        zoneID,
 				Kind:        zoneConfig.Kind,
 				Nameservers: m.normalizeNameservers(zoneConfig.Nameservers, zoneID),
+				Masters:     zoneConfig.Masters,
 				Account:     m.accountName, // Mark zone as managed
 			}
 
-			created, err := m.client.CreateZone(ctx, zone)
+			created, err := m.provider.CreateZone(ctx, zone)
+			if isConflict(err) {
+				// Another run created the zone since it was checked
+				if err := m.adoptZone(ctx, zoneID); err != nil {
+					return err
+				}
+				state = config.ZoneState{Exists: true, IsManaged: true}
+				return m.applyZone(ctx, zoneID, zoneConfig, state, opts, result)
+			}
 			if err != nil {
 				return fmt.Errorf("failed to create zone: %w", err)
 			}
@@ -165,11 +780,338 @@ func (m *Manager) applyZone(
 		// Update state since zone is now created and managed
 		state.Exists = true
 		state.IsManaged = true
-		result.ZonesCreated++
+		result.Created = true
+		m.emit(Event{Type: EventZoneCreated, Zone: zoneID})
+
+		if zoneConfig.Kind == config.KindSlave {
+			return m.retrieveZone(ctx, zoneID, opts)
+		}
+	}
+
+	// Slave zone content comes from zone transfers, there are no RRsets to manage
+	if zoneConfig.Kind == config.KindSlave {
+		m.log.Debug("  Skipping RRsets for %s zone", config.KindSlave)
+		return nil
+	}
+
+	// Apply RRsets (including NS records from nameservers property for managed zones),
+	// existing zones are loaded once the desired RRsets are known
+	return m.applyRRsets(ctx, zoneID, zoneConfig, existingZone, state, opts, result)
+}
+
+// adoptZone checks a zone that another run created after this run found it
+// missing, e.g. a concurrent run of the same configuration: creating it failed
+// with 409 Conflict. The zone is reconciled like an existing one if it has the
+// account of this run, and fails otherwise.
+func (m *Manager) adoptZone(ctx context.Context, zoneID string) error {
+	zone, err := m.provider.GetZoneInfo(ctx, zoneID)
+	if err != nil {
+		return fmt.Errorf("failed to check zone after create conflict: %w", err)
+	}
+	if zone == nil {
+		return errors.New("failed to create zone: the server reported a conflict, but the zone does not exist")
+	}
+	if zone.Account != m.accountName {
+		return fmt.Errorf("zone was created concurrently with account %q and is not managed by %s",
+			zone.Account, m.accountName)
+	}
+	m.log.Warn("  Zone was created concurrently by another run of %s, reconciling it", m.accountName)
+	return nil
+}
+
+// isConflict reports whether err is a 409 Conflict response of the API.
+func isConflict(err error) bool {
+	var statusErr *powerdns.StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusConflict
+}
+
+// Pseudo metadata kinds of the settings of recursor zones. Changes of existing
+// recursor zones are reported as metadata changes of these kinds.
+const (
+	RecursorZoneKind         = "KIND"
+	RecursorForwarders       = "FORWARDERS"
+	RecursorRecursionDesired = "RECURSION-DESIRED"
+)
+
+// errRecursorUnsupported is returned for recursor zones if the provider does
+// not implement RecursorProvider.
+var errRecursorUnsupported = errors.New("recursor zones are not supported by the provider")
+
+// getRecursorZone returns a zone of the recursor, nil if it does not exist.
+func (m *Manager) getRecursorZone(ctx context.Context, zoneID string) (*powerdns.RecursorZone, error) {
+	recursor, ok := m.provider.(RecursorProvider)
+	if !ok {
+		return nil, errRecursorUnsupported
+	}
+	zone, err := recursor.GetRecursorZone(ctx, zoneID)
+	if err != nil {
+		return nil, err
+	}
+	if zone != nil {
+		m.log.Info("    Zone exists on the recursor (kind=%s)", zone.Kind)
+	} else {
+		m.log.Info("    Zone does not exist on the recursor")
+	}
+	return zone, nil
+}
+
+// applyRecursorZone creates a zone of the recursor, or replaces an existing
+// one whose kind, forwarders or recursion desired setting changed: the
+// recursor API does not support partial updates.
+func (m *Manager) applyRecursorZone(
+	ctx context.Context,
+	zoneID string,
+	zoneConfig *config.Zone,
+	existing *powerdns.RecursorZone,
+	opts ApplyOptions,
+	result *ZoneResult,
+) error {
+	recursor, ok := m.provider.(RecursorProvider)
+	if !ok {
+		return errRecursorUnsupported
+	}
+	desired := &powerdns.RecursorZone{
+		Name:             zoneID,
+		Kind:             zoneConfig.RecursorKind(),
+		Servers:          normalizeForwarders(zoneConfig.Forwarders),
+		RecursionDesired: zoneConfig.RecursionDesired,
+	}
+	if existing == nil {
+		m.log.Info("  Creating recursor zone: %s (kind=%s)", zoneID, desired.Kind)
+		result.Created = true
+		m.emit(Event{Type: EventZoneCreated, Zone: zoneID})
+		if opts.DryRun {
+			return nil
+		}
+		if err := recursor.CreateRecursorZone(ctx, desired); err != nil {
+			return fmt.Errorf("failed to create zone: %w", err)
+		}
+		return nil
+	}
+
+	var changes []MetadataChange
+	if existing.Kind != desired.Kind {
+		changes = append(changes, MetadataChange{
+			Kind: RecursorZoneKind, Before: []string{existing.Kind}, After: []string{desired.Kind},
+		})
+	}
+	if before := normalizeForwarders(existing.Servers); !sameValues(before, desired.Servers) {
+		changes = append(changes, MetadataChange{Kind: RecursorForwarders, Before: before, After: desired.Servers})
+	}
+	if existing.RecursionDesired != desired.RecursionDesired {
+		changes = append(changes, MetadataChange{
+			Kind:   RecursorRecursionDesired,
+			Before: []string{strconv.FormatBool(existing.RecursionDesired)},
+			After:  []string{strconv.FormatBool(desired.RecursionDesired)},
+		})
+	}
+	if len(changes) == 0 {
+		m.log.Debug("  Recursor zone unchanged")
+		return nil
+	}
+	for _, change := range changes {
+		m.log.Info("  ~ Updating %s: %v -> %v", change.Kind, change.Before, change.After)
+	}
+	if opts.DryRun {
+		result.Metadata = changes
+		return nil
+	}
+
+	req := &ConfirmRequest{Zone: zoneID, Prompt: i18n.T("Replace the recursor zone?"), Metadata: changes}
+	if err := m.confirm(ctx, req, opts); err != nil {
+		return err
+	}
+	if err := recursor.ReplaceRecursorZone(ctx, desired); err != nil {
+		return fmt.Errorf("failed to replace zone: %w", err)
+	}
+	result.Metadata = changes
+	return nil
+}
+
+// normalizeForwarders adds the default port 53 to forwarders without a port,
+// as the recursor reports them with ports.
+func normalizeForwarders(forwarders []string) []string {
+	normalized := make([]string, 0, len(forwarders))
+	for _, forwarder := range forwarders {
+		if addr, err := netip.ParseAddr(forwarder); err == nil {
+			forwarder = netip.AddrPortFrom(addr, 53).String()
+		}
+		normalized = append(normalized, forwarder)
+	}
+	return normalized
+}
+
+// applyMetadata updates the zone metadata declared with the typed zone fields.
+// Metadata of existing zones that are not managed is left untouched.
+func (m *Manager) applyMetadata(
+	ctx context.Context,
+	zoneID string,
+	zoneConfig *config.Zone,
+	state config.ZoneState,
+	opts ApplyOptions,
+	result *ZoneResult,
+) error {
+	desired := zoneConfig.Metadata()
+	if len(desired) == 0 {
+		return nil
+	}
+	if state.Exists && !state.IsManaged {
+		m.log.Warn("  Skipping metadata (zone is not managed)")
+		return nil
+	}
+	if at := zoneConfig.ApplyAfterTime(); time.Now().Before(at) {
+		m.log.Info("  Skipping metadata until %s (scheduled)", at.Format(time.RFC3339))
+		return nil
+	}
+	provider, ok := m.provider.(MetadataProvider)
+	if !ok {
+		return errors.New("zone metadata is not supported by the provider")
+	}
+
+	current := make(map[string][]string)
+	// A zone created in a dry run does not exist yet and has no metadata
+	if !(result.Created && opts.DryRun) {
+		metadata, err := provider.GetMetadata(ctx, zoneID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch zone metadata: %w", err)
+		}
+		for _, md := range metadata {
+			current[md.Kind] = md.Metadata
+		}
+	}
+
+	kinds := make([]string, 0, len(desired))
+	for kind := range desired {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	var changes []MetadataChange
+	for _, kind := range kinds {
+		before, after := current[kind], desired[kind]
+		if sameValues(before, after) {
+			m.log.Debug("  = Metadata unchanged: %s", kind)
+			continue
+		}
+		m.log.Info("  ~ Updating metadata %s: %v -> %v", kind, before, after)
+		changes = append(changes, MetadataChange{Kind: kind, Before: before, After: after})
+	}
+	if len(changes) == 0 || opts.DryRun {
+		result.Metadata = changes
+		return nil
+	}
+
+	req := &ConfirmRequest{Zone: zoneID, Prompt: i18n.T("Apply these metadata changes?"), Metadata: changes}
+	if err := m.confirm(ctx, req, opts); err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		var err error
+		if len(change.After) == 0 {
+			err = provider.DeleteMetadata(ctx, zoneID, change.Kind)
+		} else {
+			err = provider.SetMetadata(ctx, zoneID, change.Kind, change.After)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update metadata %s: %w", change.Kind, err)
+		}
+		result.Metadata = append(result.Metadata, change)
+	}
+	return nil
+}
+
+// rectifyZone rectifies an existing DNSSEC zone after its RRsets changed,
+// unless PowerDNS already does so itself (API-RECTIFY). API-RECTIFY is taken
+// from the metadata changes of this apply if it was changed, as info was read
+// before.
+func (m *Manager) rectifyZone(
+	ctx context.Context,
+	zoneID string,
+	info *powerdns.Zone,
+	opts ApplyOptions,
+	result *ZoneResult,
+) error {
+	if info == nil || !info.DNSSEC {
+		return nil
+	}
+	apiRectify := info.APIRectify
+	for _, change := range result.Metadata {
+		if change.Kind == config.MetadataAPIRectify {
+			// A removed value falls back to the server default, rectifying
+			// does no harm then
+			apiRectify = len(change.After) > 0 && change.After[0] == "1"
+		}
+	}
+	if apiRectify {
+		return nil
+	}
+	if result.RRsetsCreated+result.RRsetsUpdated+result.RRsetsDeleted == 0 {
+		return nil
+	}
+
+	m.log.Info("  Rectifying DNSSEC zone")
+	if opts.DryRun {
+		return nil
+	}
+
+	rectifier, ok := m.provider.(Rectifier)
+	if !ok {
+		return errors.New("rectifying zones is not supported by the provider")
+	}
+	status, err := rectifier.RectifyZone(ctx, zoneID)
+	if err != nil {
+		return fmt.Errorf("failed to rectify zone: %w", err)
+	}
+	m.log.Debug("    Rectify status: %s", status)
+	result.Rectified = true
+	return nil
+}
+
+// sameValues returns true if both lists have the same values in any order.
+func sameValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
 	}
+	sortedA := append([]string(nil), a...)
+	sortedB := append([]string(nil), b...)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+	return slices.Equal(sortedA, sortedB)
+}
 
-	// Apply RRsets (including NS records from nameservers property for managed zones)
-	return m.applyRRsets(ctx, zoneID, zoneConfig, existingZone, state, opts, result)
+// loadZone fetches an existing zone including its RRsets. Providers that can
+// filter zones while reading them only return the RRsets that keep selects.
+func (m *Manager) loadZone(ctx context.Context, zoneID string, keep powerdns.RRsetFilter) (*powerdns.Zone, error) {
+	var zone *powerdns.Zone
+	var err error
+	if fp, ok := m.provider.(ZoneFilterProvider); ok {
+		zone, err = fp.GetZoneFiltered(ctx, zoneID, keep)
+	} else {
+		zone, err = m.provider.GetZone(ctx, zoneID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch zone records: %w", err)
+	}
+	if zone == nil {
+		return nil, fmt.Errorf("zone %s no longer exists", zoneID)
+	}
+	return zone, nil
+}
+
+// retrieveZone triggers the initial zone transfer of a newly created Slave zone.
+func (m *Manager) retrieveZone(ctx context.Context, zoneID string, opts ApplyOptions) error {
+	m.log.Info("  Triggering zone transfer from masters")
+	if opts.DryRun {
+		return nil
+	}
+
+	status, err := m.provider.AxfrRetrieve(ctx, zoneID)
+	if err != nil {
+		return fmt.Errorf("failed to trigger zone transfer: %w", err)
+	}
+	m.log.Info("    Transfer status: %s", status)
+	return nil
 }
 
 func (m *Manager) applyRRsets(
@@ -179,16 +1121,35 @@ func (m *Manager) applyRRsets(
 	existingZone *powerdns.Zone,
 	state config.ZoneState,
 	opts ApplyOptions,
-	result *ApplyResult,
+	result *ZoneResult,
 ) error {
 	// Build desired RRsets (skip NS for non-managed existing zones)
-	desiredRRsets, err := m.buildDesiredRRsets(zoneID, cfg, state)
+	desiredRRsets, schedule, err := m.buildDesiredRRsets(zoneID, cfg, state)
 	if err != nil {
 		return err
 	}
 
+	// Load the existing zone; RRsets that are neither desired nor managed are
+	// never changed, huge zones are read without them
+	if existingZone == nil {
+		keep := func(rrset *powerdns.RRset) bool {
+			key := rrsetKey(rrset.Name, rrset.Type)
+			_, desired := desiredRRsets[key]
+			_, absent := m.absent[key]
+			return desired || absent || m.isManaged(*rrset)
+		}
+		if existingZone, err = m.loadZone(ctx, zoneID, keep); err != nil {
+			return err
+		}
+		if !opts.OnlyChanges {
+			m.printManagedRRsets("Current managed records", existingZone)
+		}
+	}
+
 	// Show desired RRsets table
-	m.printDesiredRRsets("Desired records from config", desiredRRsets)
+	if !opts.OnlyChanges {
+		m.printDesiredRRsets("Desired records from config", desiredRRsets)
+	}
 
 	m.log.Debug("  Desired RRsets: %d, Existing RRsets: %d", len(desiredRRsets), len(existingZone.RRsets))
 
@@ -200,35 +1161,57 @@ func (m *Manager) applyRRsets(
 	}
 
 	var patchRRsets []powerdns.RRset
+	// Deleted rrsets that still receive queries, see checkQueries
+	var inUse []string
 
-	// Process desired RRsets
-	for key, desired := range desiredRRsets {
+	// Process desired RRsets, in a stable order for the output and the patch
+	for _, key := range sortedKeys(desiredRRsets) {
+		desired := desiredRRsets[key]
 		existing, exists := existingByKey[key]
 
 		switch {
 		case !exists:
+			if m.deferChange(schedule[key], ChangeCreate, nil, &desired, result) {
+				continue
+			}
 			// Create new RRset
-			m.log.Info("  + Creating RRset: %s %s", desired.Name, desired.Type)
+			m.log.Info("  + Creating RRset: %s %s%s", desired.Name, desired.Type,
+				m.labelsSuffix(desired.Name, desired.Type))
 			m.logRRsetDiff(nil, &desired)
-			patchRRsets = append(patchRRsets, m.createRRsetPatch(desired))
-			result.RRsetsCreated++
+			patch := m.createRRsetPatch(desired)
+			if m.createOnly[key] {
+				// Without the ownership comment, later runs leave it alone
+				patch.Comments = desired.Comments
+			}
+			patchRRsets = append(patchRRsets, patch)
+			result.addCreate(&desired)
+			m.emitChange(zoneID, result)
+		case m.createOnly[key]:
+			m.log.Debug("  = RRset exists, not changed (create only): %s %s", desired.Name, desired.Type)
 		case m.isManaged(existing):
 			// Update managed RRset if changed
-			if m.shouldUpdateRRset(desired, existing) {
-				m.log.Info("  ~ Updating RRset: %s %s", desired.Name, desired.Type)
+			if !m.shouldUpdateRRset(desired, existing) {
+				m.log.Debug("  = RRset unchanged: %s %s", desired.Name, desired.Type)
+			} else if !m.deferChange(schedule[key], ChangeUpdate, &existing, &desired, result) {
+				m.log.Info("  ~ Updating RRset: %s %s%s", desired.Name, desired.Type,
+					m.labelsSuffix(desired.Name, desired.Type))
 				m.logRRsetDiff(&existing, &desired)
 				patchRRsets = append(patchRRsets, m.createRRsetPatch(desired))
-				result.RRsetsUpdated++
-			} else {
-				m.log.Debug("  = RRset unchanged: %s %s", desired.Name, desired.Type)
+				result.addUpdate(&existing, &desired)
+				m.emitChange(zoneID, result)
 			}
 		default:
 			// Special case: allow updating NS records for managed zones to claim ownership
 			if desired.Type == "NS" && desired.Name == zoneID && state.IsManaged {
-				m.log.Info("  ~ Updating RRset: %s %s", desired.Name, desired.Type)
+				if m.deferChange(schedule[key], ChangeUpdate, &existing, &desired, result) {
+					continue
+				}
+				m.log.Info("  ~ Updating RRset: %s %s%s", desired.Name, desired.Type,
+					m.labelsSuffix(desired.Name, desired.Type))
 				m.logRRsetDiff(&existing, &desired)
 				patchRRsets = append(patchRRsets, m.createRRsetPatch(desired))
-				result.RRsetsUpdated++
+				result.addUpdate(&existing, &desired)
+				m.emitChange(zoneID, result)
 			} else {
 				// Config specifies a record that exists but is not managed - this is an error
 				return fmt.Errorf("RRset %s %s already exists but is not managed by %s",
@@ -237,32 +1220,174 @@ func (m *Manager) applyRRsets(
 		}
 	}
 
+	// Delete RRsets declared absent, whether they are managed or not
+	for _, key := range sortedKeys(m.absent) {
+		existing, exists := existingByKey[key]
+		if !exists {
+			m.log.Debug("  = RRset absent: %s %s", m.absent[key].Name, m.absent[key].Type)
+			continue
+		}
+		if m.deferChange(schedule[key], ChangeDelete, &existing, nil, result) {
+			continue
+		}
+		m.log.Info("  - Deleting absent RRset: %s %s%s", existing.Name, existing.Type,
+			m.labelsSuffix(existing.Name, existing.Type))
+		m.logRRsetDiff(&existing, nil)
+		queried, err := m.checkQueries(ctx, &existing, opts)
+		if err != nil {
+			return err
+		}
+		if queried {
+			inUse = append(inUse, existing.Name+" "+existing.Type)
+		}
+		patchRRsets = append(patchRRsets, powerdns.RRset{
+			Name:       existing.Name,
+			Type:       existing.Type,
+			ChangeType: "DELETE",
+		})
+		result.addDelete(&existing)
+		m.emitChange(zoneID, result)
+	}
+
 	// Find orphaned managed RRsets (managed RRsets not in desired state)
-	for key, existing := range existingByKey {
+	for _, key := range sortedKeys(existingByKey) {
+		existing := existingByKey[key]
+		if _, absent := m.absent[key]; absent || !cfg.InManagedSubtree(existing.Name, zoneID) {
+			continue
+		}
 		if m.isManaged(existing) {
-			if _, desired := desiredRRsets[key]; !desired {
+			_, desired := desiredRRsets[key]
+			if !desired && (cfg.IgnoresType(existing.Type) || cfg.IgnoresName(existing.Name, zoneID)) {
+				m.log.Debug("  = Ignoring RRset: %s %s", existing.Name, existing.Type)
+				continue
+			}
+			if !desired && !m.deferChange(cfg.ApplyAfterTime(), ChangeDelete, &existing, nil, result) {
 				// Delete orphaned managed RRset
-				m.log.Info("  - Deleting orphaned RRset: %s %s", existing.Name, existing.Type)
+				m.log.Info("  - Deleting orphaned RRset: %s %s%s", existing.Name, existing.Type,
+					m.labelsSuffix(existing.Name, existing.Type))
 				m.logRRsetDiff(&existing, nil)
+				queried, err := m.checkQueries(ctx, &existing, opts)
+				if err != nil {
+					return err
+				}
+				if queried {
+					inUse = append(inUse, existing.Name+" "+existing.Type)
+				}
 				patchRRsets = append(patchRRsets, powerdns.RRset{
 					Name:       existing.Name,
 					Type:       existing.Type,
 					ChangeType: "DELETE",
 				})
-				result.RRsetsDeleted++
+				result.addDelete(&existing)
+				m.emitChange(zoneID, result)
 			}
 		}
 	}
 
+	m.locateChanges(result, cfg)
+	if len(inUse) > 0 && !opts.Force {
+		sort.Strings(inUse)
+		return fmt.Errorf("refusing to delete %d rrset(s) that still receive queries (%s), use --force to delete them",
+			len(inUse), strings.Join(inUse, ", "))
+	}
+
 	// Apply changes
-	return m.sendPatch(ctx, zoneID, patchRRsets, opts)
+	size := max(existingZone.RRsetCount, len(existingZone.RRsets), len(desiredRRsets))
+	return m.sendPatch(ctx, zoneID, size, patchRRsets, opts, result)
+}
+
+// checkQueries returns true if an rrset about to be deleted still receives
+// queries according to the server statistics, and warns about it. It returns
+// false if opts.CheckQueries is not set.
+func (m *Manager) checkQueries(ctx context.Context, rrset *powerdns.RRset, opts ApplyOptions) (bool, error) {
+	if !opts.CheckQueries {
+		return false, nil
+	}
+	if m.queryCounts == nil {
+		stats, ok := m.provider.(QueryStatsProvider)
+		if !ok {
+			return false, errors.New("checking queries is not supported by the provider")
+		}
+		counts, err := stats.GetQueryCounts(ctx)
+		if err != nil {
+			return false, fmt.Errorf("failed to get query statistics: %w", err)
+		}
+		m.queryCounts = counts
+	}
+	count := m.queryCounts[powerdns.QueryKey(rrset.Name, rrset.Type)]
+	if count == 0 {
+		return false, nil
+	}
+	if opts.Force {
+		m.log.Warn("    %s %s still receives queries (%d recent), deleting it anyway", rrset.Name, rrset.Type, count)
+	} else {
+		m.log.Warn("    %s %s still receives queries (%d recent)", rrset.Name, rrset.Type, count)
+	}
+	return true, nil
+}
+
+// locateChanges sets the configuration locations and labels of the changes
+// of a zone.
+func (m *Manager) locateChanges(result *ZoneResult, cfg *config.Zone) {
+	for _, changes := range [][]Change{result.Changes, result.Scheduled} {
+		for i := range changes {
+			loc, ok := m.locations[rrsetKey(changes[i].Name, changes[i].Type)]
+			if !ok {
+				loc = cfg.Location()
+			}
+			changes[i].Location = loc
+			changes[i].Labels = m.changeLabels(changes[i].Name, changes[i].Type)
+		}
+	}
+}
+
+// changeLabels returns the labels of a desired rrset of the zone being
+// applied, or the labels of the zone if the rrset has none.
+func (m *Manager) changeLabels(name, rtype string) map[string]string {
+	if labels := m.labels[rrsetKey(name, rtype)]; labels != nil {
+		return labels
+	}
+	return m.zoneLabels
+}
+
+// labelsSuffix formats the labels of an rrset for the plan output, e.g.
+// " [team=core]", empty if it has none.
+func (m *Manager) labelsSuffix(name, rtype string) string {
+	if labels := m.changeLabels(name, rtype); len(labels) > 0 {
+		return " [" + config.FormatLabels(labels) + "]"
+	}
+	return ""
+}
+
+// deferChange records a change as scheduled and returns true if at is in the future.
+func (m *Manager) deferChange(
+	at time.Time,
+	action ChangeAction,
+	existing, desired *powerdns.RRset,
+	result *ZoneResult,
+) bool {
+	if !time.Now().Before(at) {
+		return false
+	}
+	rrset := desired
+	if rrset == nil {
+		rrset = existing
+	}
+	m.log.Info("  @ Scheduled %s of RRset: %s %s (after %s)%s", action, rrset.Name, rrset.Type,
+		at.Format(time.RFC3339), m.labelsSuffix(rrset.Name, rrset.Type))
+	result.addScheduled(action, existing, desired, at)
+	return true
 }
 
+// sendPatch sends the changes of a zone with size rrsets after confirmation,
+// in batches if the zone is large (see Pacing).
 func (m *Manager) sendPatch(
 	ctx context.Context,
 	zoneID string,
+	size int,
 	patchRRsets []powerdns.RRset,
 	opts ApplyOptions,
+	result *ZoneResult,
 ) error {
 	if len(patchRRsets) == 0 {
 		m.log.Debug("  No RRset changes needed")
@@ -270,31 +1395,140 @@ func (m *Manager) sendPatch(
 	}
 
 	m.log.Debug("  Applying %d RRset change(s)...", len(patchRRsets))
+	batches := opts.Pacing.batches(size, patchRRsets)
+	m.measurePatches(batches, opts.Pacing.SizeLimit, opts.DryRun, result)
 	if opts.DryRun {
 		return nil
 	}
 
 	// Ask for confirmation before sending changes to server
-	if !opts.AutoConfirm && m.confirmFn != nil {
-		if !m.confirmFn("Apply these changes?") {
-			return ErrAborted
-		}
+	req := &ConfirmRequest{Zone: zoneID, Prompt: i18n.T("Apply these changes?"), Changes: result.Changes}
+	if err := m.confirm(ctx, req, opts); err != nil {
+		return err
 	}
 
-	patch := &powerdns.ZonePatch{RRsets: patchRRsets}
-	if err := m.client.PatchZone(ctx, zoneID, patch); err != nil {
-		return fmt.Errorf("failed to patch zone: %w", err)
+	if len(batches) > 1 {
+		m.log.Info("  Large zone (%d rrsets), sending %d change(s) in %d patches", size, len(patchRRsets),
+			len(batches))
+	}
+	for i, batch := range batches {
+		if i > 0 {
+			if err := pause(ctx, opts.Pacing.Pause); err != nil {
+				return fmt.Errorf("failed to patch zone after %d of %d patches: %w", i, len(batches), err)
+			}
+		}
+		patch := &powerdns.ZonePatch{RRsets: batch}
+		if err := m.provider.PatchZone(ctx, zoneID, patch); err != nil {
+			err = m.locateError(err, batch)
+			if i > 0 {
+				return fmt.Errorf("failed to patch zone after %d of %d patches: %w", i, len(batches), err)
+			}
+			return fmt.Errorf("failed to patch zone: %w", err)
+		}
+		m.emit(Event{Type: EventPatchSent, Zone: zoneID, RRsets: len(batch)})
 	}
 
 	return nil
 }
 
+// measurePatches records the number of records and the size of the patches
+// of a zone in the result, and warns about a patch close to limit, the
+// request size limit in bytes: the server, or a proxy in front of it,
+// rejects larger ones with 413 Request Entity Too Large.
+func (m *Manager) measurePatches(batches [][]powerdns.RRset, limit int, dryRun bool, result *ZoneResult) {
+	largest := 0
+	for _, batch := range batches {
+		for i := range batch {
+			result.PatchRecords += len(batch[i].Records)
+		}
+		body, err := json.Marshal(&powerdns.ZonePatch{RRsets: batch})
+		if err != nil {
+			m.log.Debug("  Failed to measure the patch: %v", err)
+			return
+		}
+		result.PatchBytes += len(body)
+		largest = max(largest, len(body))
+	}
+
+	logf := m.log.Debug
+	if dryRun {
+		logf = m.log.Info
+	}
+	logf("  Patch: %d record(s), %s in %d request(s)", result.PatchRecords,
+		logger.FormatBytes(result.PatchBytes), len(batches))
+	if limit > 0 && float64(largest) >= patchSizeWarnRatio*float64(limit) {
+		relation := "is close to"
+		if largest > limit {
+			relation = "exceeds"
+		}
+		m.log.Warn("  Patch of %s %s the request size limit of %s, send large changes in batches "+
+			"(--large-zone-rrsets, --patch-batch-size) or raise the limit of the server (webserver-max-bodysize)",
+			logger.FormatBytes(largest), relation, logger.FormatBytes(limit))
+	}
+}
+
+// pause waits for d unless ctx is done first.
+func pause(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// locateError adds the configuration locations of the rrsets that a rejected
+// patch error refers to, e.g. "RRset www.example.com. IN MX: ...", so that
+// invalid record content can be traced back to the config. If the error names
+// no rrset and the patch contains a single one, its location is added.
+func (m *Manager) locateError(err error, patchRRsets []powerdns.RRset) error {
+	var statusErr *powerdns.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnprocessableEntity {
+		return err
+	}
+
+	var all, named []string
+	for _, rrset := range patchRRsets {
+		loc, ok := m.locations[rrsetKey(rrset.Name, rrset.Type)]
+		if rrset.ChangeType == "DELETE" || !ok || loc == (config.Location{}) {
+			continue
+		}
+		entry := fmt.Sprintf("%s %s at %s", rrset.Name, rrset.Type, loc)
+		all = append(all, entry)
+		for _, ref := range []string{rrset.Name + " IN " + rrset.Type, rrset.Name + "/" + rrset.Type} {
+			if strings.Contains(statusErr.Message, " "+ref) || strings.Contains(statusErr.Message, "'"+ref) {
+				named = append(named, entry)
+				break
+			}
+		}
+	}
+	if len(named) == 0 && len(all) == 1 {
+		named = all
+	}
+	if len(named) == 0 {
+		return err
+	}
+	return fmt.Errorf("%w (config: %s)", err, strings.Join(named, "; "))
+}
+
 func (m *Manager) buildDesiredRRsets(
 	zoneID string,
 	cfg *config.Zone,
 	state config.ZoneState,
-) (map[string]powerdns.RRset, error) {
+) (map[string]powerdns.RRset, map[string]time.Time, error) {
 	desired := make(map[string]powerdns.RRset)
+	// RRset key -> time before which changes are not applied
+	schedule := make(map[string]time.Time)
+	m.locations = make(map[string]config.Location)
+	m.labels = make(map[string]map[string]string)
+	m.zoneLabels = cfg.Labels
+	m.createOnly = make(map[string]bool)
+	m.absent = make(map[string]powerdns.RRset)
 
 	// Add NS RRset from nameservers property if provided
 	// Only if zone is new or managed (we own it)
@@ -312,9 +1546,12 @@ func (m *Manager) buildDesiredRRsets(
 			desired[key] = powerdns.RRset{
 				Name:    zoneID,
 				Type:    "NS",
-				TTL:     300, // Default TTL for NS records
+				TTL:     cfg.DefaultTTL(),
 				Records: nsRecords,
 			}
+			schedule[key] = cfg.ApplyAfterTime()
+			m.locations[key] = cfg.NameserversLocation()
+			m.labels[key] = cfg.Labels
 		} else {
 			// Zone exists but is not managed - warn about skipped nameservers
 			m.log.Warn("  Skipping nameservers (zone is not managed)")
@@ -324,8 +1561,10 @@ func (m *Manager) buildDesiredRRsets(
 	// Add RRsets from config
 	rrsets, err := cfg.NormalizeRRsets()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	// Generated PTR rrsets come first, so that PTR rrsets in the config replace them
+	rrsets = append(slices.Clone(m.ptrs[zoneID]), rrsets...)
 
 	for _, rrset := range rrsets {
 		fqdn := m.buildFQDN(rrset.Name, zoneID)
@@ -333,14 +1572,10 @@ func (m *Manager) buildDesiredRRsets(
 
 		records := make([]powerdns.Record, len(rrset.Records))
 		for i, rec := range rrset.Records {
-			content := rec.Content
-			// Normalize TXT records: wrap in quotes if not already quoted
-			if rrset.Type == "TXT" && !strings.HasPrefix(content, "\"") {
-				content = fmt.Sprintf("%q", content)
-			}
 			records[i] = powerdns.Record{
-				Content:  content,
+				Content:  config.RecordContent(rrset.Type, rec.Content),
 				Disabled: rec.Disabled,
+				SetPTR:   rec.SetPTR && m.setPTR,
 			}
 		}
 
@@ -351,9 +1586,410 @@ func (m *Manager) buildDesiredRRsets(
 			Records:  records,
 			Comments: m.makeComments(rrset),
 		}
+		schedule[key] = rrset.ApplyAfter
+		m.locations[key] = rrset.Location
+		m.labels[key] = rrset.Labels
+		if rrset.CreateOnly {
+			m.createOnly[key] = true
+		} else {
+			// A PTR rrset of the config replaces a generated one
+			delete(m.createOnly, key)
+		}
+	}
+
+	for _, rrset := range cfg.AbsentRRsets() {
+		fqdn := m.buildFQDN(rrset.Name, zoneID)
+		key := rrsetKey(fqdn, rrset.Type)
+		// A generated PTR rrset is not created where one is declared absent
+		delete(desired, key)
+		m.absent[key] = powerdns.RRset{Name: fqdn, Type: rrset.Type}
+		schedule[key] = rrset.ApplyAfter
+		m.locations[key] = rrset.Location
+		m.labels[key] = rrset.Labels
+	}
+
+	return desired, schedule, nil
+}
+
+// checkTargets reports CNAME, MX, SRV and NS targets that do not exist: names
+// in the configured zones without records, and with resolve, names outside of
+// them that DNS reports as nonexistent. Depending on the dangling_targets mode
+// of the configuration they are logged as warnings or returned as a
+// validation error.
+func (m *Manager) checkTargets(ctx context.Context, cfg *config.Config, resolve bool) error {
+	mode := cfg.DanglingTargets
+	if mode == config.DanglingOff {
+		return nil
+	}
+	g, err := graph.Build(cfg, nil)
+	if err != nil {
+		return err
+	}
+
+	dangling := g.Dangling()
+	if resolve {
+		nonexistent := make(map[string]bool)
+		for _, edge := range g.External() {
+			if _, seen := nonexistent[edge.To]; !seen {
+				nonexistent[edge.To] = m.nonexistent(ctx, edge.To)
+			}
+			if nonexistent[edge.To] {
+				dangling = append(dangling, edge)
+			}
+		}
+	}
+
+	validationErr := &config.ValidationError{}
+	for _, edge := range dangling {
+		msg := fmt.Sprintf("%s target %s of %s does not exist", edge.Label, edge.To, edge.From)
+		if mode == config.DanglingError {
+			validationErr.AddAt(edge.Location, "%s", msg)
+		} else if pos := edge.Location.Position(); pos != "" {
+			m.log.Warn("%s: %s", pos, msg)
+		} else {
+			m.log.Warn("%s", msg)
+		}
+	}
+	if validationErr.HasErrors() {
+		return validationErr
+	}
+	return nil
+}
+
+// checkDualStack reports names with enabled A records but no enabled AAAA
+// records, or the other way around. Depending on the dual_stack mode of the
+// zone, or else of the configuration, they are logged as warnings or returned
+// as a validation error.
+func (m *Manager) checkDualStack(cfg *config.Config) error {
+	validationErr := &config.ValidationError{}
+	for _, zoneName := range cfg.ZoneNames() {
+		zone := cfg.Zones[zoneName]
+		mode := zone.DualStack
+		if mode == "" {
+			mode = cfg.DualStack
+		}
+		if mode == "" || mode == config.DualStackOff {
+			continue
+		}
+		rrsets, err := zone.NormalizeRRsets()
+		if err != nil {
+			return err
+		}
+
+		zoneID := config.CanonicalZoneName(zoneName)
+		addresses := make(map[string]map[string]config.RRset)
+		var names []string
+		for _, rrset := range rrsets {
+			if rrset.Type != "A" && rrset.Type != "AAAA" || !hasEnabledRecord(rrset.Records) {
+				continue
+			}
+			fqdn := strings.ToLower(m.buildFQDN(rrset.Name, zoneID))
+			if addresses[fqdn] == nil {
+				addresses[fqdn] = make(map[string]config.RRset)
+				names = append(names, fqdn)
+			}
+			addresses[fqdn][rrset.Type] = rrset
+		}
+
+		for _, name := range names {
+			for have, missing := range map[string]string{"A": "AAAA", "AAAA": "A"} {
+				rrset, ok := addresses[name][have]
+				if !ok || addresses[name][missing].Type != "" {
+					continue
+				}
+				msg := fmt.Sprintf("%s has %s records but no %s records", name, have, missing)
+				if mode == config.DualStackError {
+					validationErr.AddAt(rrset.Location, "%s", msg)
+				} else if pos := rrset.Location.Position(); pos != "" {
+					m.log.Warn("%s: %s", pos, msg)
+				} else {
+					m.log.Warn("%s", msg)
+				}
+			}
+		}
+	}
+	if validationErr.HasErrors() {
+		return validationErr
+	}
+	return nil
+}
+
+// tlsTypes are the record types of names that serve TLS, see checkCAA.
+var tlsTypes = []string{"TLSA", "HTTPS", "SVCB"}
+
+// checkCAA warns about zones with TLSA, HTTPS or SVCB records whose names
+// have no CAA records at the name or a parent name in the configuration, so
+// that any CA may issue certificates for them. The name of a TLSA record is
+// that of its service, e.g. www.example.com. for _443._tcp.www.example.com.
+func (m *Manager) checkCAA(cfg *config.Config) error {
+	caa := make(map[string]bool)
+	// tlsNames are the TLS rrsets of each zone, checked once all CAA records
+	// are known, as parent zones may have them
+	tlsNames := make(map[string][]config.RRset)
+	for _, zoneName := range cfg.ZoneNames() {
+		zone := cfg.Zones[zoneName]
+		if zone.IsRecursor() {
+			continue
+		}
+		rrsets, err := zone.NormalizeRRsets()
+		if err != nil {
+			return err
+		}
+		zoneID := config.CanonicalZoneName(zoneName)
+		for _, rrset := range rrsets {
+			rrset.Name = strings.ToLower(m.buildFQDN(rrset.Name, zoneID))
+			switch {
+			case rrset.Type == "CAA":
+				caa[rrset.Name] = true
+			case slices.Contains(tlsTypes, rrset.Type):
+				tlsNames[zoneName] = append(tlsNames[zoneName], rrset)
+			}
+		}
+	}
+
+	for _, zoneName := range cfg.ZoneNames() {
+		var uncovered []config.RRset
+		for _, rrset := range tlsNames[zoneName] {
+			if !caaCovers(caa, rrset.Name) {
+				uncovered = append(uncovered, rrset)
+			}
+		}
+		if len(uncovered) == 0 {
+			continue
+		}
+		msg := fmt.Sprintf("zone %s has %d name(s) with TLSA, HTTPS or SVCB records but no CAA records, "+
+			"so any CA may issue certificates for them, e.g. %s", config.CanonicalZoneName(zoneName),
+			len(uncovered), uncovered[0].Name)
+		if pos := uncovered[0].Location.Position(); pos != "" {
+			m.log.Warn("%s: %s", pos, msg)
+		} else {
+			m.log.Warn("%s", msg)
+		}
+	}
+	return nil
+}
+
+// caaCovers returns true if there are CAA records for the name of a TLS
+// rrset or one of its parents, skipping the _port._proto labels of TLSA names.
+func caaCovers(caa map[string]bool, name string) bool {
+	for strings.HasPrefix(name, "_") {
+		_, name, _ = strings.Cut(name, ".")
+	}
+	for name != "" {
+		if caa[name] {
+			return true
+		}
+		_, name, _ = strings.Cut(name, ".")
+	}
+	return false
+}
+
+// hasEnabledRecord returns true if any of records is not disabled.
+func hasEnabledRecord(records []config.Record) bool {
+	for _, record := range records {
+		if !record.Disabled {
+			return true
+		}
+	}
+	return false
+}
+
+// nonexistent returns true if DNS reports that name does not exist. Other
+// lookup errors, e.g. timeouts, are logged and the name is assumed to exist.
+func (m *Manager) nonexistent(ctx context.Context, name string) bool {
+	_, err := m.resolver.LookupHost(ctx, name)
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return true
+	}
+	if err != nil {
+		m.log.Debug("Failed to resolve target %s: %v", name, err)
+	}
+	return false
+}
+
+// getServer returns the server of the provider, loaded by the first call,
+// or nil if the provider does not report it.
+func (m *Manager) getServer(ctx context.Context) (*powerdns.Server, error) {
+	sp, ok := m.provider.(ServerProvider)
+	if !ok {
+		return nil, nil
+	}
+	if m.server == nil {
+		server, err := sp.GetServer(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get server version: %w", err)
+		}
+		m.server = server
+	}
+	return m.server, nil
+}
+
+// checkRecordTypes checks the configured record types against the server
+// version, see CheckRecordTypes. Without a server version, e.g. with the file
+// provider, all types are accepted. A server version that cannot be read,
+// e.g. because the API key or a gateway does not permit it, only skips the
+// check with a warning.
+func (m *Manager) checkRecordTypes(ctx context.Context, cfg *config.Config) error {
+	server, err := m.getServer(ctx)
+	if err != nil {
+		m.log.Warn("Not checking record types against the server version: %v", err)
+		return nil
+	}
+	if server == nil {
+		return nil
+	}
+	return CheckRecordTypes(cfg, server.Version)
+}
+
+// CheckRecordTypes returns a validation error for configured record types
+// that a PowerDNS version does not support, with the version that added
+// them, e.g. SVCB records on a server older than 4.4.
+func CheckRecordTypes(cfg *config.Config, version string) error {
+	validationErr := cfg.CheckRecordTypes(func(rtype string) error {
+		if since, ok := powerdns.SupportsRecordType(version, rtype); !ok {
+			return fmt.Errorf("record type %s requires PowerDNS %s or later, the server runs %s",
+				strings.ToUpper(rtype), since, version)
+		}
+		return nil
+	})
+	if validationErr != nil {
+		return validationErr
+	}
+	return nil
+}
+
+// planPTRs decides how the PTR records of set_ptr records are created. Servers
+// that support set-ptr create them when the A/AAAA records are written;
+// otherwise the manager generates PTR rrsets in the reverse zones of the
+// configuration. Reverse zones that are not in the configuration are not
+// touched.
+func (m *Manager) planPTRs(ctx context.Context, cfg *config.Config) error {
+	m.setPTR, m.ptrs = false, nil
+
+	type source struct {
+		ip    net.IP
+		rrset config.RRset
+	}
+	var sources []source
+	for _, zoneName := range cfg.ZoneNames() {
+		zone := cfg.Zones[zoneName]
+		rrsets, err := zone.NormalizeRRsets()
+		if err != nil {
+			return fmt.Errorf("zone %s: %w", zoneName, err)
+		}
+		for _, rrset := range rrsets {
+			if rrset.Type != "A" && rrset.Type != "AAAA" {
+				continue
+			}
+			target := m.buildFQDN(rrset.Name, config.CanonicalZoneName(zoneName))
+			for _, rec := range rrset.Records {
+				ip := net.ParseIP(rec.Content)
+				if !rec.SetPTR || rec.Disabled || ip == nil {
+					continue
+				}
+				sources = append(sources, source{ip: ip, rrset: config.RRset{
+					Name:       target,
+					TTL:        rrset.TTL,
+					ApplyAfter: rrset.ApplyAfter,
+					Location:   rrset.Location,
+				}})
+			}
+		}
+	}
+	if len(sources) == 0 {
+		return nil
+	}
+
+	server, err := m.getServer(ctx)
+	if err != nil {
+		return err
+	}
+	if server != nil {
+		if powerdns.SupportsSetPTR(server.Version) {
+			m.log.Debug("PowerDNS %s supports set-ptr, PTR records are created by the server", server.Version)
+			m.setPTR = true
+			return nil
+		}
+		m.log.Debug("PowerDNS %s does not support set-ptr, generating PTR records", server.Version)
 	}
 
-	return desired, nil
+	m.ptrs = make(map[string][]config.RRset)
+	for _, src := range sources {
+		name := reverseName(src.ip)
+		zoneName, ok := reverseZone(cfg, name)
+		if !ok {
+			m.log.Warn("No reverse zone for %s in the configuration, PTR for %s not created", src.ip, src.rrset.Name)
+			continue
+		}
+		zoneID := config.CanonicalZoneName(zoneName)
+		m.ptrs[zoneID] = addPTR(m.ptrs[zoneID], name, src.rrset)
+	}
+	return nil
+}
+
+// addPTR adds a PTR record pointing to the source rrset name to the PTR
+// rrset named name, creating it with the source TTL and schedule.
+func addPTR(rrsets []config.RRset, name string, src config.RRset) []config.RRset {
+	record := config.Record{Content: src.Name}
+	for i := range rrsets {
+		if rrsets[i].Name == name {
+			if !slices.Contains(rrsets[i].Records, record) {
+				rrsets[i].Records = append(rrsets[i].Records, record)
+			}
+			return rrsets
+		}
+	}
+	return append(rrsets, config.RRset{
+		Name:       name,
+		Type:       "PTR",
+		TTL:        src.TTL,
+		Records:    []config.Record{record},
+		ApplyAfter: src.ApplyAfter,
+		Location:   src.Location,
+	})
+}
+
+// reverseZone returns the configured zone that PTR records for the reverse
+// name belong in: the most specific authoritative zone containing it, if it is
+// not a Slave zone and the name is within its managed subtree.
+func reverseZone(cfg *config.Config, name string) (string, bool) {
+	best := ""
+	for zoneName, zone := range cfg.Zones {
+		if zone.IsRecursor() {
+			continue
+		}
+		zoneID := strings.ToLower(config.CanonicalZoneName(zoneName))
+		if (name == zoneID || strings.HasSuffix(name, "."+zoneID)) &&
+			len(zoneID) > len(config.CanonicalZoneName(best)) {
+			best = zoneName
+		}
+	}
+	if best == "" {
+		return "", false
+	}
+	zone := cfg.Zones[best]
+	if zone.Kind == config.KindSlave || !zone.InManagedSubtree(name, config.CanonicalZoneName(best)) {
+		return "", false
+	}
+	return best, true
+}
+
+// reverseName returns the in-addr.arpa or ip6.arpa name of an address.
+func reverseName(ip net.IP) string {
+	if v4 := ip.To4(); v4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa.", v4[3], v4[2], v4[1], v4[0])
+	}
+	var b strings.Builder
+	const hexDigits = "0123456789abcdef"
+	for i := len(ip) - 1; i >= 0; i-- {
+		b.WriteByte(hexDigits[ip[i]&0x0f])
+		b.WriteByte('.')
+		b.WriteByte(hexDigits[ip[i]>>4])
+		b.WriteByte('.')
+	}
+	b.WriteString("ip6.arpa.")
+	return b.String()
 }
 
 func (m *Manager) createRRsetPatch(desired powerdns.RRset) powerdns.RRset {
@@ -375,7 +2011,7 @@ func (m *Manager) createRRsetPatch(desired powerdns.RRset) powerdns.RRset {
 
 // ownerComment returns the ownership marker comment content.
 func (m *Manager) ownerComment() string {
-	return "owner=" + m.accountName
+	return m.owner.String()
 }
 
 // makeComments converts config comments to PowerDNS comments, preserving order.
@@ -397,15 +2033,10 @@ func (m *Manager) makeComments(rrset config.RRset) []powerdns.Comment {
 }
 
 // isManaged returns true if the RRset has an ownership comment matching our account.
-// Ownership is indicated by a comment with content "owner=<account-name>".
+// Ownership is indicated by a comment with content "owner=<account-name>",
+// optionally followed by a versioned payload (see package ownership).
 func (m *Manager) isManaged(rrset powerdns.RRset) bool {
-	ownerMarker := m.ownerComment()
-	for _, comment := range rrset.Comments {
-		if comment.Content == ownerMarker {
-			return true
-		}
-	}
-	return false
+	return ownership.OwnedBy(&rrset, m.accountName)
 }
 
 func (m *Manager) shouldUpdateRRset(desired, existing powerdns.RRset) bool {
@@ -441,13 +2072,7 @@ func (m *Manager) shouldUpdateRRset(desired, existing powerdns.RRset) bool {
 }
 
 func (m *Manager) buildFQDN(name, zoneID string) string {
-	if name == "@" {
-		return zoneID
-	}
-	if strings.HasSuffix(name, ".") {
-		return name
-	}
-	return fmt.Sprintf("%s.%s", name, zoneID)
+	return config.QualifyName(name, zoneID)
 }
 
 func (m *Manager) normalizeNameservers(nameservers []string, zoneID string) []string {
@@ -523,22 +2148,22 @@ func (m *Manager) logUpdatedRRset(existing, desired *powerdns.RRset) {
 		desiredRecords[r.Content] = r
 	}
 
-	// Show removed records
-	for content, r := range existingRecords {
-		if _, exists := desiredRecords[content]; !exists {
-			m.log.Diff("-", formatRecord(content, r.Disabled))
+	// Show removed records, in the order of the server
+	for _, r := range existing.Records {
+		if _, exists := desiredRecords[r.Content]; !exists {
+			m.log.Diff("-", formatRecord(r.Content, r.Disabled))
 		}
 	}
 
-	// Show added or changed records
-	for content, r := range desiredRecords {
-		existingR, exists := existingRecords[content]
+	// Show added or changed records, in the order of the configuration
+	for _, r := range desired.Records {
+		existingR, exists := existingRecords[r.Content]
 		switch {
 		case !exists:
-			m.log.Diff("+", formatRecord(content, r.Disabled))
+			m.log.Diff("+", formatRecord(r.Content, r.Disabled))
 		case existingR.Disabled != r.Disabled:
-			oldFmt := formatRecord(content, existingR.Disabled)
-			newFmt := formatRecord(content, r.Disabled)
+			oldFmt := formatRecord(r.Content, existingR.Disabled)
+			newFmt := formatRecord(r.Content, r.Disabled)
 			m.log.Diff("~", oldFmt+" -> "+newFmt)
 		}
 	}
@@ -583,7 +2208,8 @@ func (m *Manager) printDesiredRRsets(title string, rrsets map[string]powerdns.RR
 
 	rows := make([][]string, 0, totalRecords)
 
-	for _, rrset := range rrsets {
+	for _, key := range sortedKeys(rrsets) {
+		rrset := rrsets[key]
 		for _, record := range rrset.Records {
 			status := ""
 			if record.Disabled {
@@ -606,12 +2232,24 @@ func (m *Manager) printDesiredRRsets(title string, rrsets map[string]powerdns.RR
 	m.log.Table(title, headers, rows)
 }
 
-// sortTableRows sorts rows by Type (index 1), then Name (index 0).
+// sortTableRows sorts rows by Type (index 1), then Name (index 0), keeping
+// the order of the records of an RRset.
 func sortTableRows(rows [][]string) {
-	sort.Slice(rows, func(i, j int) bool {
+	sort.SliceStable(rows, func(i, j int) bool {
 		if rows[i][1] != rows[j][1] {
 			return rows[i][1] < rows[j][1]
 		}
 		return rows[i][0] < rows[j][0]
 	})
 }
+
+// sortedKeys returns the keys of m in sorted order, iterating a map directly
+// would make the output and the order of the patches change from run to run.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
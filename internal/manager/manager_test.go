@@ -2,12 +2,20 @@ package manager
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/lock"
 	"github.com/kreigan/powerdns-zone-manager/internal/logger"
+	"github.com/kreigan/powerdns-zone-manager/internal/ownership"
 	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
 )
 
@@ -16,13 +24,20 @@ func testLogger() *logger.Logger {
 	return logger.New(logger.Options{Verbose: false, NoColor: true})
 }
 
-// MockClient implements PowerDNSClient for testing
+// MockClient implements Provider for testing
 type MockClient struct {
 	zones         map[string]*powerdns.Zone
 	createZoneErr error
+	// raced is a zone that another run creates right before CreateZone
+	raced         *powerdns.Zone
 	getZoneErr    error
 	patchZoneErr  error
 	patchCalls    []powerdns.ZonePatch
+	axfrCalls     []string
+	getZoneCalls  []string
+	metadata      map[string][]powerdns.Metadata
+	metadataCalls []string
+	rectifyCalls  []string
 }
 
 func NewMockClient() *MockClient {
@@ -36,12 +51,17 @@ func (m *MockClient) CreateZone(_ context.Context, zone *powerdns.Zone) (*powerd
 	if m.createZoneErr != nil {
 		return nil, m.createZoneErr
 	}
+	if m.raced != nil {
+		m.zones[m.raced.Name] = m.raced
+		return nil, &powerdns.StatusError{Message: "API error (status 409): Conflict", StatusCode: 409}
+	}
 	created := *zone
 	m.zones[zone.Name] = &created
 	return &created, nil
 }
 
 func (m *MockClient) GetZone(_ context.Context, zoneID string) (*powerdns.Zone, error) {
+	m.getZoneCalls = append(m.getZoneCalls, zoneID)
 	if m.getZoneErr != nil {
 		return nil, m.getZoneErr
 	}
@@ -51,6 +71,18 @@ func (m *MockClient) GetZone(_ context.Context, zoneID string) (*powerdns.Zone,
 	return nil, nil // Zone not found
 }
 
+func (m *MockClient) GetZoneInfo(_ context.Context, zoneID string) (*powerdns.Zone, error) {
+	if m.getZoneErr != nil {
+		return nil, m.getZoneErr
+	}
+	if zone, ok := m.zones[zoneID]; ok {
+		info := *zone
+		info.RRsets = nil
+		return &info, nil
+	}
+	return nil, nil // Zone not found
+}
+
 func (m *MockClient) PatchZone(_ context.Context, _ string, patch *powerdns.ZonePatch) error {
 	if m.patchZoneErr != nil {
 		return m.patchZoneErr
@@ -59,6 +91,30 @@ func (m *MockClient) PatchZone(_ context.Context, _ string, patch *powerdns.Zone
 	return nil
 }
 
+func (m *MockClient) AxfrRetrieve(_ context.Context, zoneID string) (string, error) {
+	m.axfrCalls = append(m.axfrCalls, zoneID)
+	return "Added retrieval request for '" + zoneID + "'", nil
+}
+
+func (m *MockClient) GetMetadata(_ context.Context, zoneID string) ([]powerdns.Metadata, error) {
+	return m.metadata[zoneID], nil
+}
+
+func (m *MockClient) SetMetadata(_ context.Context, zoneID, kind string, values []string) error {
+	m.metadataCalls = append(m.metadataCalls, fmt.Sprintf("PUT %s %s %v", zoneID, kind, values))
+	return nil
+}
+
+func (m *MockClient) DeleteMetadata(_ context.Context, zoneID, kind string) error {
+	m.metadataCalls = append(m.metadataCalls, fmt.Sprintf("DELETE %s %s", zoneID, kind))
+	return nil
+}
+
+func (m *MockClient) RectifyZone(_ context.Context, zoneID string) (string, error) {
+	m.rectifyCalls = append(m.rectifyCalls, zoneID)
+	return "Rectified", nil
+}
+
 func TestManager_Apply_CreateZone(t *testing.T) {
 	client := NewMockClient()
 	mgr := NewManager(client, "zone-manager", testLogger())
@@ -447,258 +503,1635 @@ func TestManager_Apply_ClientError(t *testing.T) {
 	}
 }
 
-func TestBuildFQDN(t *testing.T) {
-	mgr := &Manager{}
+func TestManager_Apply_CreateSlaveZone(t *testing.T) {
+	client := NewMockClient()
+	mgr := NewManager(client, "zone-manager", testLogger())
 
-	tests := []struct {
-		name     string
-		zoneID   string
-		expected string
-	}{
-		{"www", "example.com.", "www.example.com."},
-		{"@", "example.com.", "example.com."},
-		{"www.example.com.", "example.com.", "www.example.com."}, // Already FQDN
-		{"sub.domain", "example.com.", "sub.domain.example.com."},
+	cfg := &config.Config{
+		Zones: map[string]config.Zone{
+			"example.com": {
+				Kind:    "Slave",
+				Masters: []string{"192.0.2.1", "192.0.2.2:5300"},
+			},
+		},
 	}
 
-	for _, tt := range tests {
-		result := mgr.buildFQDN(tt.name, tt.zoneID)
-		if result != tt.expected {
-			t.Errorf("buildFQDN(%s, %s) = %s, want %s", tt.name, tt.zoneID, result, tt.expected)
-		}
+	result, err := mgr.Apply(context.Background(), cfg, ApplyOptions{})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
 	}
-}
 
-func TestIsManaged(t *testing.T) {
-	mgr := &Manager{accountName: "zone-manager"}
+	if result.ZonesCreated != 1 {
+		t.Errorf("Expected 1 zone created, got %d", result.ZonesCreated)
+	}
 
-	tests := []struct {
-		name     string
-		rrset    powerdns.RRset
-		expected bool
-	}{
-		{
-			name: "managed",
-			rrset: powerdns.RRset{
-				Comments: []powerdns.Comment{
-					{Content: "owner=zone-manager"},
-				},
-			},
-			expected: true,
-		},
-		{
-			name: "not managed - different account",
-			rrset: powerdns.RRset{
-				Comments: []powerdns.Comment{
-					{Content: "owner=other-account"},
-				},
-			},
-			expected: false,
-		},
-		{
-			name: "not managed - no comments",
-			rrset: powerdns.RRset{
-				Comments: []powerdns.Comment{},
-			},
-			expected: false,
-		},
-		{
-			name: "managed - one of many comments",
-			rrset: powerdns.RRset{
-				Comments: []powerdns.Comment{
-					{Content: "some other comment"},
-					{Content: "owner=zone-manager"},
-				},
-			},
-			expected: true,
-		},
+	zone, ok := client.zones["example.com."]
+	if !ok {
+		t.Fatal("Zone was not created")
+	}
+	if len(zone.Masters) != 2 || zone.Masters[0] != "192.0.2.1" {
+		t.Errorf("Expected masters to be passed to PowerDNS, got %v", zone.Masters)
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := mgr.isManaged(tt.rrset)
-			if result != tt.expected {
-				t.Errorf("isManaged() = %v, want %v", result, tt.expected)
-			}
-		})
+	if len(client.axfrCalls) != 1 || client.axfrCalls[0] != "example.com." {
+		t.Errorf("Expected zone transfer to be triggered for example.com., got %v", client.axfrCalls)
+	}
+	if len(client.patchCalls) != 0 {
+		t.Errorf("Expected no patches for Slave zone, got %d", len(client.patchCalls))
 	}
 }
 
-func TestShouldUpdateRRset(t *testing.T) {
-	mgr := &Manager{}
+func TestManager_Apply_SlaveZoneDryRun(t *testing.T) {
+	client := NewMockClient()
+	mgr := NewManager(client, "zone-manager", testLogger())
 
-	tests := []struct {
-		name     string
-		desired  powerdns.RRset
-		existing powerdns.RRset
-		expected bool
-	}{
-		{
-			name: "no change",
-			desired: powerdns.RRset{
-				TTL: 300,
-				Records: []powerdns.Record{
-					{Content: "192.168.1.1", Disabled: false},
-				},
-			},
-			existing: powerdns.RRset{
-				TTL: 300,
-				Records: []powerdns.Record{
-					{Content: "192.168.1.1", Disabled: false},
-				},
-			},
-			expected: false,
-		},
-		{
-			name: "TTL changed",
-			desired: powerdns.RRset{
-				TTL: 600,
-				Records: []powerdns.Record{
-					{Content: "192.168.1.1", Disabled: false},
-				},
-			},
-			existing: powerdns.RRset{
-				TTL: 300,
-				Records: []powerdns.Record{
-					{Content: "192.168.1.1", Disabled: false},
-				},
-			},
-			expected: true,
-		},
-		{
-			name: "content changed",
-			desired: powerdns.RRset{
-				TTL: 300,
-				Records: []powerdns.Record{
-					{Content: "192.168.1.2", Disabled: false},
-				},
-			},
-			existing: powerdns.RRset{
-				TTL: 300,
-				Records: []powerdns.Record{
-					{Content: "192.168.1.1", Disabled: false},
-				},
-			},
-			expected: true,
-		},
-		{
-			name: "disabled changed",
-			desired: powerdns.RRset{
-				TTL: 300,
-				Records: []powerdns.Record{
-					{Content: "192.168.1.1", Disabled: true},
-				},
-			},
-			existing: powerdns.RRset{
-				TTL: 300,
-				Records: []powerdns.Record{
-					{Content: "192.168.1.1", Disabled: false},
-				},
-			},
-			expected: true,
-		},
-		{
-			name: "record count changed",
-			desired: powerdns.RRset{
-				TTL: 300,
-				Records: []powerdns.Record{
-					{Content: "192.168.1.1", Disabled: false},
-					{Content: "192.168.1.2", Disabled: false},
-				},
-			},
-			existing: powerdns.RRset{
-				TTL: 300,
-				Records: []powerdns.Record{
-					{Content: "192.168.1.1", Disabled: false},
-				},
+	cfg := &config.Config{
+		Zones: map[string]config.Zone{
+			"example.com": {
+				Kind:    "Slave",
+				Masters: []string{"192.0.2.1"},
 			},
-			expected: true,
 		},
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := mgr.shouldUpdateRRset(tt.desired, tt.existing)
-			if result != tt.expected {
-				t.Errorf("shouldUpdateRRset() = %v, want %v", result, tt.expected)
-			}
-		})
+	if _, err := mgr.Apply(context.Background(), cfg, ApplyOptions{DryRun: true}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	if len(client.axfrCalls) != 0 {
+		t.Errorf("Expected no zone transfer in dry run, got %v", client.axfrCalls)
 	}
 }
 
-func TestManager_Apply_Comments(t *testing.T) {
+func TestManager_Apply_ZoneResults(t *testing.T) {
 	client := NewMockClient()
+	client.patchZoneErr = errors.New("server error")
 	mgr := NewManager(client, "zone-manager", testLogger())
 
 	cfg := &config.Config{
 		Zones: map[string]config.Zone{
-			"example.com": {
+			"a.example.com": {
+				Nameservers: []string{"ns1.example.com."},
+			},
+			"b.example.com": {
 				Nameservers: []string{"ns1.example.com."},
-				RRsets: []config.RRsetInput{
-					{
-						Name: "www",
-						Type: "A",
-						Records: []interface{}{
-							map[string]interface{}{
-								"content": "192.168.1.1",
-								"comment": "primary server",
-							},
-							"192.168.1.2",
-						},
-						Comment: "web servers",
-					},
-				},
 			},
 		},
 	}
 
-	_, err := mgr.Apply(context.Background(), cfg, ApplyOptions{})
-	if err != nil {
-		t.Fatalf("Apply failed: %v", err)
+	result, err := mgr.Apply(context.Background(), cfg, ApplyOptions{})
+	if err == nil {
+		t.Fatal("Expected error, got nil")
 	}
-
-	if len(client.patchCalls) != 1 {
-		t.Fatalf("Expected 1 patch call, got %d", len(client.patchCalls))
+	if result == nil {
+		t.Fatal("Expected partial result on failure, got nil")
 	}
 
-	patch := client.patchCalls[0]
-	if len(patch.RRsets) != 2 {
-		t.Fatalf("Expected 2 rrsets in patch, got %d", len(patch.RRsets))
+	if len(result.Zones) != 2 {
+		t.Fatalf("Expected 2 zone results, got %d", len(result.Zones))
 	}
 
-	// Find the www A rrset
-	var wwwRRset *powerdns.RRset
-	for i := range patch.RRsets {
-		if patch.RRsets[i].Name == "www.example.com." && patch.RRsets[i].Type == "A" {
-			wwwRRset = &patch.RRsets[i]
-			break
-		}
+	first, second := result.Zones[0], result.Zones[1]
+	if first.Name != "a.example.com" || first.Status != ZoneStatusFailed {
+		t.Errorf("Expected a.example.com to fail, got %s %s", first.Name, first.Status)
 	}
-	if wwwRRset == nil {
-		t.Fatal("www A rrset not found in patch")
+	if !strings.Contains(first.Error, "server error") {
+		t.Errorf("Expected failure reason to be recorded, got %q", first.Error)
+	}
+	if second.Name != "b.example.com" || second.Status != ZoneStatusSkipped {
+		t.Errorf("Expected b.example.com to be skipped, got %s %s", second.Name, second.Status)
 	}
+}
 
-	// Check comments: should have rrset comment, record comment, then ownership
-	expectedComments := []powerdns.Comment{
-		{Content: "web servers", Account: ""},
-		{Content: "primary server", Account: ""},
-		{Content: "owner=zone-manager", Account: "zone-manager"},
+func TestManager_Apply_LazyRRsetLoading(t *testing.T) {
+	client := NewMockClient()
+	client.zones["existing.com."] = &powerdns.Zone{
+		Name:    "existing.com.",
+		Account: "zone-manager",
 	}
-	if len(wwwRRset.Comments) != len(expectedComments) {
-		t.Errorf("Expected %d comments, got %d", len(expectedComments), len(wwwRRset.Comments))
-		for i, c := range wwwRRset.Comments {
-			t.Logf("Comment %d: %+v", i, c)
-		}
+	client.zones["slave.com."] = &powerdns.Zone{
+		Name: "slave.com.",
+		Kind: "Slave",
 	}
-	for i, expected := range expectedComments {
-		if i >= len(wwwRRset.Comments) {
+	mgr := NewManager(client, "zone-manager", testLogger())
+
+	cfg := &config.Config{
+		Zones: map[string]config.Zone{
+			"existing.com": {
+				RRsets: []config.RRsetInput{{Name: "www", Type: "A", Records: "192.168.1.1"}},
+			},
+			"new.com": {
+				Nameservers: []string{"ns1.example.com."},
+			},
+			"slave.com": {
+				Kind: "Slave",
+			},
+		},
+	}
+
+	if _, err := mgr.Apply(context.Background(), cfg, ApplyOptions{}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	// Full zone content is only needed for the existing non-Slave zone
+	if len(client.getZoneCalls) != 1 || client.getZoneCalls[0] != "existing.com." {
+		t.Errorf("Expected RRsets to be fetched only for existing.com., got %v", client.getZoneCalls)
+	}
+}
+
+func TestManager_Apply_Metadata(t *testing.T) {
+	client := NewMockClient()
+	client.zones["example.com."] = &powerdns.Zone{Name: "example.com.", Account: "zone-manager"}
+	client.zones["other.com."] = &powerdns.Zone{Name: "other.com.", Account: "someone-else"}
+	client.metadata = map[string][]powerdns.Metadata{
+		"example.com.": {
+			{Kind: "ALLOW-AXFR-FROM", Metadata: []string{"192.0.2.2", "192.0.2.0/24"}},
+			{Kind: "ALSO-NOTIFY", Metadata: []string{"192.0.2.53"}},
+			{Kind: "TSIG-ALLOW-AXFR", Metadata: []string{"old-key"}},
+		},
+	}
+	mgr := NewManager(client, "zone-manager", testLogger())
+
+	rectify := true
+	cfg := &config.Config{
+		Zones: map[string]config.Zone{
+			"example.com": {
+				AllowAxfrFrom: []string{"192.0.2.0/24", "192.0.2.2"}, // unchanged, different order
+				AlsoNotify:    []string{"192.0.2.53", "[2001:db8::53]:5300"},
+				TSIGAllowAxfr: []string{}, // removed
+				APIRectify:    &rectify,
+			},
+			"other.com": {
+				APIRectify: &rectify,
+			},
+		},
+	}
+
+	result, err := mgr.Apply(context.Background(), cfg, ApplyOptions{AutoConfirm: true})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	expected := []string{
+		"PUT example.com. ALSO-NOTIFY [192.0.2.53 [2001:db8::53]:5300]",
+		"PUT example.com. API-RECTIFY [1]",
+		"DELETE example.com. TSIG-ALLOW-AXFR",
+	}
+	if strings.Join(client.metadataCalls, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected metadata calls (the unmanaged zone must be skipped):\n%s",
+			strings.Join(client.metadataCalls, "\n"))
+	}
+	if result.MetadataUpdated != 3 {
+		t.Errorf("Expected 3 metadata changes, got %d", result.MetadataUpdated)
+	}
+}
+
+func TestManager_Apply_MetadataDryRunNewZone(t *testing.T) {
+	client := NewMockClient()
+	mgr := NewManager(client, "zone-manager", testLogger())
+
+	cfg := &config.Config{
+		Zones: map[string]config.Zone{
+			"example.com": {
+				Nameservers:   []string{"ns1.example.com."},
+				AllowAxfrFrom: []string{"AUTO-NS"},
+			},
+		},
+	}
+
+	result, err := mgr.Apply(context.Background(), cfg, ApplyOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if result.MetadataUpdated != 1 || len(client.metadataCalls) != 0 {
+		t.Errorf("Expected 1 planned metadata change and no calls, got %d, %v",
+			result.MetadataUpdated, client.metadataCalls)
+	}
+}
+
+func TestManager_Apply_Rectify(t *testing.T) {
+	client := NewMockClient()
+	for _, zone := range []*powerdns.Zone{
+		{Name: "signed.com.", Account: "zone-manager", DNSSEC: true},
+		{Name: "auto.com.", Account: "zone-manager", DNSSEC: true, APIRectify: true},
+		{Name: "plain.com.", Account: "zone-manager"},
+		{Name: "enabled.com.", Account: "zone-manager", DNSSEC: true},
+		{Name: "disabled.com.", Account: "zone-manager", DNSSEC: true, APIRectify: true},
+	} {
+		client.zones[zone.Name] = zone
+	}
+	client.zones["unchanged.com."] = &powerdns.Zone{
+		Name: "unchanged.com.", Account: "zone-manager", DNSSEC: true,
+		RRsets: []powerdns.RRset{{
+			Name: "www.unchanged.com.", Type: "A", TTL: 300,
+			Records:  []powerdns.Record{{Content: "192.0.2.1"}},
+			Comments: []powerdns.Comment{{Content: "owner=zone-manager", Account: "zone-manager"}},
+		}},
+	}
+	mgr := NewManager(client, "zone-manager", testLogger())
+
+	www := []config.RRsetInput{{Name: "www", Type: "A", Records: "192.0.2.1"}}
+	enabled, disabled := true, false
+	cfg := &config.Config{Zones: map[string]config.Zone{
+		"signed.com":    {RRsets: www},
+		"auto.com":      {RRsets: www},
+		"plain.com":     {RRsets: www},
+		"unchanged.com": {RRsets: www},
+		// API-RECTIFY changed by the same apply
+		"enabled.com":  {RRsets: www, APIRectify: &enabled},
+		"disabled.com": {RRsets: www, APIRectify: &disabled},
+	}}
+
+	result, err := mgr.Apply(context.Background(), cfg, ApplyOptions{AutoConfirm: true, Rectify: true})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	slices.Sort(client.rectifyCalls)
+	if !slices.Equal(client.rectifyCalls, []string{"disabled.com.", "signed.com."}) {
+		t.Errorf("Expected only disabled.com. and signed.com. to be rectified, got %v", client.rectifyCalls)
+	}
+	for _, zr := range result.Zones {
+		if zr.Rectified != (zr.Name == "signed.com" || zr.Name == "disabled.com") {
+			t.Errorf("Zone %s: unexpected Rectified=%v", zr.Name, zr.Rectified)
+		}
+	}
+}
+
+func TestManager_Apply_Labels(t *testing.T) {
+	client := NewMockClient()
+	client.zones["example.com."] = &powerdns.Zone{
+		Name: "example.com.", Account: "zone-manager",
+		RRsets: []powerdns.RRset{{
+			Name: "old.example.com.", Type: "A", TTL: 300,
+			Records:  []powerdns.Record{{Content: "192.0.2.9"}},
+			Comments: []powerdns.Comment{{Content: "owner=zone-manager", Account: "zone-manager"}},
+		}},
+	}
+	cfg := &config.Config{Zones: map[string]config.Zone{"example.com": {
+		Labels: map[string]string{"team": "core"},
+		RRsets: []config.RRsetInput{
+			{Name: "www", Type: "A", Records: "192.0.2.1", Labels: map[string]string{"ticket": "OPS-1"}},
+		},
+	}}}
+	mgr := NewManager(client, "zone-manager", testLogger())
+	events := make(map[string]string)
+	mgr.SetEventFunc(func(ev Event) {
+		if ev.Type == EventRRset {
+			events[ev.Change.Name] = config.FormatLabels(ev.Change.Labels)
+		}
+	})
+
+	result, err := mgr.Apply(context.Background(), cfg, ApplyOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	zr := result.Zones[0]
+	if config.FormatLabels(zr.Labels) != "team=core" {
+		t.Errorf("Unexpected zone labels %v", zr.Labels)
+	}
+	want := map[string]string{
+		"www.example.com.": "team=core, ticket=OPS-1",
+		// Deleted rrsets are no longer configured and get the zone labels
+		"old.example.com.": "team=core",
+	}
+	got := make(map[string]string)
+	for _, change := range zr.Changes {
+		got[change.Name] = config.FormatLabels(change.Labels)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected change labels %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("Unexpected event labels %v, want %v", events, want)
+	}
+}
+
+func TestManager_Apply_ManagedSubtree(t *testing.T) {
+	client := NewMockClient()
+	owned := []powerdns.Comment{{Content: "owner=zone-manager", Account: "zone-manager"}}
+	client.zones["example.com."] = &powerdns.Zone{
+		Name:    "example.com.",
+		Account: "other-team",
+		RRsets: []powerdns.RRset{
+			{
+				Name: "old.k8s.example.com.", Type: "A", TTL: 300,
+				Records: []powerdns.Record{{Content: "192.0.2.1"}}, Comments: owned,
+			},
+			{
+				Name: "www.example.com.", Type: "A", TTL: 300,
+				Records: []powerdns.Record{{Content: "192.0.2.2"}}, Comments: owned,
+			},
+		},
+	}
+	mgr := NewManager(client, "zone-manager", testLogger())
+
+	cfg := &config.Config{Zones: map[string]config.Zone{
+		"example.com": {
+			ManagedSubtree: "k8s",
+			RRsets:         []config.RRsetInput{{Name: "api.k8s", Type: "A", Records: "192.0.2.3"}},
+		},
+	}}
+
+	result, err := mgr.Apply(context.Background(), cfg, ApplyOptions{AutoConfirm: true})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if result.RRsetsCreated != 1 || result.RRsetsDeleted != 1 {
+		t.Errorf("Expected 1 rrset created and 1 deleted, got %d and %d", result.RRsetsCreated, result.RRsetsDeleted)
+	}
+	for _, change := range result.Zones[0].Changes {
+		if change.Name == "www.example.com." {
+			t.Errorf("Managed rrset outside of the subtree must not be touched, got %+v", change)
+		}
+	}
+}
+
+func TestManager_Apply_Ignore(t *testing.T) {
+	client := NewMockClient()
+	owned := []powerdns.Comment{{Content: "owner=zone-manager", Account: "zone-manager"}}
+	client.zones["example.com."] = &powerdns.Zone{
+		Name:    "example.com.",
+		Account: "zone-manager",
+		RRsets: []powerdns.RRset{
+			{
+				Name: "_acme-challenge.example.com.", Type: "TXT", TTL: 60,
+				Records: []powerdns.Record{{Content: `"token"`}}, Comments: owned,
+			},
+			{
+				Name: "old.example.com.", Type: "A", TTL: 300,
+				Records: []powerdns.Record{{Content: "192.0.2.1"}}, Comments: owned,
+			},
+			{
+				Name: "host.dyn.example.com.", Type: "A", TTL: 60,
+				Records: []powerdns.Record{{Content: "192.0.2.4"}}, Comments: owned,
+			},
+		},
+	}
+	mgr := NewManager(client, "zone-manager", testLogger())
+
+	cfg := &config.Config{Zones: map[string]config.Zone{
+		"example.com": {
+			IgnoreNames: []string{"*.dyn"},
+			IgnoreTypes: []string{"txt"},
+			RRsets:      []config.RRsetInput{{Name: "@", Type: "TXT", Records: `"v=spf1 -all"`}},
+		},
+	}}
+
+	result, err := mgr.Apply(context.Background(), cfg, ApplyOptions{AutoConfirm: true})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if result.RRsetsCreated != 1 || result.RRsetsDeleted != 1 {
+		t.Errorf("Expected 1 rrset created and 1 deleted, got %d and %d", result.RRsetsCreated, result.RRsetsDeleted)
+	}
+	for _, change := range result.Zones[0].Changes {
+		if change.Action == ChangeDelete && change.Name != "old.example.com." {
+			t.Errorf("Managed rrset of an ignored type or name must not be deleted, got %+v", change)
+		}
+	}
+}
+
+func TestManager_Apply_Scheduled(t *testing.T) {
+	client := NewMockClient()
+	owned := []powerdns.Comment{{Content: "owner=zone-manager", Account: "zone-manager"}}
+	client.zones["example.com."] = &powerdns.Zone{
+		Name:    "example.com.",
+		Account: "zone-manager",
+		RRsets: []powerdns.RRset{
+			{
+				Name: "www.example.com.", Type: "A", TTL: 300,
+				Records: []powerdns.Record{{Content: "192.0.2.1"}}, Comments: owned,
+			},
+			{
+				Name: "old.example.com.", Type: "A", TTL: 300,
+				Records: []powerdns.Record{{Content: "192.0.2.9"}}, Comments: owned,
+			},
+		},
+	}
+	mgr := NewManager(client, "zone-manager", testLogger())
+
+	past := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	future := time.Now().Add(24 * time.Hour)
+	cfg := &config.Config{Zones: map[string]config.Zone{
+		"example.com": {
+			ApplyAfter: &future,
+			RRsets: []config.RRsetInput{
+				{Name: "www", Type: "A", Records: "192.0.2.2"},
+				{Name: "api", Type: "A", Records: "192.0.2.3", ApplyAfter: &past},
+			},
+		},
+		"new.com": {
+			ApplyAfter:  &future,
+			Nameservers: []string{"ns1.example.com."},
+		},
+	}}
+
+	result, err := mgr.Apply(context.Background(), cfg, ApplyOptions{AutoConfirm: true})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	// Only api is due, the www update and old deletion follow the zone schedule
+	if result.RRsetsCreated != 1 || result.RRsetsUpdated != 0 || result.RRsetsDeleted != 0 {
+		t.Errorf("Expected only api to be created, got %+v", result)
+	}
+	if result.RRsetsScheduled != 2 {
+		t.Errorf("Expected 2 scheduled changes, got %d", result.RRsetsScheduled)
+	}
+	for _, change := range result.Zones[0].Scheduled {
+		if !change.ApplyAfter.Equal(future) {
+			t.Errorf("Expected %s %s to be scheduled after %s, got %s",
+				change.Action, change.Name, future, change.ApplyAfter)
+		}
+	}
+	if _, created := client.zones["new.com."]; result.Zones[1].Status != ZoneStatusScheduled || created {
+		t.Errorf("Expected creation of new.com to be scheduled, got status %s", result.Zones[1].Status)
+	}
+}
+
+func TestManager_Apply_Events(t *testing.T) {
+	client := NewMockClient()
+	client.zones["example.com."] = &powerdns.Zone{Name: "example.com.", Account: "zone-manager"}
+	mgr := NewManager(client, "zone-manager", testLogger())
+
+	var events []string
+	mgr.SetEventFunc(func(ev Event) {
+		entry := string(ev.Type) + " " + ev.Zone
+		switch ev.Type {
+		case EventRRset:
+			entry += " " + string(ev.Change.Action) + " " + ev.Change.Name
+		case EventPatchSent:
+			entry += fmt.Sprintf(" %d", ev.RRsets)
+		case EventZoneFinished:
+			entry += " " + string(ev.Status)
+		}
+		events = append(events, entry)
+	})
+
+	cfg := &config.Config{Zones: map[string]config.Zone{
+		"example.com": {RRsets: []config.RRsetInput{{Name: "www", Type: "A", Records: "192.0.2.1"}}},
+	}}
+	if _, err := mgr.Apply(context.Background(), cfg, ApplyOptions{AutoConfirm: true}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	expected := []string{
+		"zone_started example.com.",
+		"rrset example.com. create www.example.com.",
+		"patch_sent example.com. 1",
+		"zone_finished example.com. ok",
+	}
+	if strings.Join(events, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected events:\n%s", strings.Join(events, "\n"))
+	}
+}
+
+// recordingConfirmer records confirmation requests and rejects them.
+type recordingConfirmer struct {
+	requests []*ConfirmRequest
+}
+
+func (c *recordingConfirmer) Confirm(_ context.Context, req *ConfirmRequest) (bool, error) {
+	c.requests = append(c.requests, req)
+	return false, nil
+}
+
+func TestManager_Apply_Confirmer(t *testing.T) {
+	client := NewMockClient()
+	client.zones["example.com."] = &powerdns.Zone{Name: "example.com.", Account: "zone-manager"}
+	mgr := NewManager(client, "zone-manager", testLogger())
+	mgr.SetRunID("run-1")
+	confirmer := &recordingConfirmer{}
+	mgr.SetConfirmer(confirmer)
+
+	cfg := &config.Config{Zones: map[string]config.Zone{
+		"example.com": {RRsets: []config.RRsetInput{{Name: "www", Type: "A", Records: "192.0.2.1"}}},
+	}}
+	_, err := mgr.Apply(context.Background(), cfg, ApplyOptions{})
+	if !errors.Is(err, ErrAborted) {
+		t.Fatalf("Expected ErrAborted, got %v", err)
+	}
+	if len(client.patchCalls) != 0 {
+		t.Errorf("Expected no patch after rejection, got %d", len(client.patchCalls))
+	}
+	if len(confirmer.requests) != 1 {
+		t.Fatalf("Expected 1 confirmation request, got %d", len(confirmer.requests))
+	}
+	req := confirmer.requests[0]
+	if req.Zone != "example.com." || req.RunID != "run-1" || len(req.Changes) != 1 ||
+		req.Changes[0].Action != ChangeCreate || req.Changes[0].Name != "www.example.com." {
+		t.Errorf("Unexpected confirmation request %+v", req)
+	}
+
+	// Auto-confirmed applies do not ask
+	if _, err := mgr.Apply(context.Background(), cfg, ApplyOptions{AutoConfirm: true}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if len(confirmer.requests) != 1 || len(client.patchCalls) != 1 {
+		t.Errorf("Expected no confirmation and 1 patch, got %d, %d", len(confirmer.requests), len(client.patchCalls))
+	}
+}
+
+func TestManager_Apply_Locked(t *testing.T) {
+	client := NewMockClient()
+	held := lock.Lock{RunID: "other-run", Account: "zone-manager", Expires: time.Now().Add(time.Hour)}
+	client.zones["example.com."] = &powerdns.Zone{
+		Name:    "example.com.",
+		Account: "zone-manager",
+		RRsets: []powerdns.RRset{
+			{Name: lock.Name("example.com."), Type: "TXT", Records: []powerdns.Record{{Content: held.String()}}},
+		},
+	}
+	mgr := NewManager(client, "zone-manager", testLogger())
+	mgr.SetRunID("this-run")
+
+	cfg := &config.Config{Zones: map[string]config.Zone{
+		"example.com": {RRsets: []config.RRsetInput{{Name: "www", Type: "A", Records: "192.0.2.1"}}},
+	}}
+	opts := ApplyOptions{AutoConfirm: true, Lock: true, LockTTL: time.Minute}
+	result, err := mgr.Apply(context.Background(), cfg, opts)
+	if !errors.Is(err, lock.ErrLocked) {
+		t.Fatalf("Expected ErrLocked, got %v", err)
+	}
+	if len(client.patchCalls) != 0 {
+		t.Errorf("Expected no patches to a locked zone, got %+v", client.patchCalls)
+	}
+	if result.Zones[0].Status != ZoneStatusFailed {
+		t.Errorf("Expected zone to fail, got %s", result.Zones[0].Status)
+	}
+
+	// Without locking the lock is ignored, and never treated as a managed RRset
+	if _, err := mgr.Apply(context.Background(), cfg, ApplyOptions{AutoConfirm: true}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	for _, rrset := range client.patchCalls[0].RRsets {
+		if rrset.Name == lock.Name("example.com.") {
+			t.Errorf("Expected lock RRset not to be changed, got %+v", rrset)
+		}
+	}
+}
+
+func TestManager_Apply_PatchErrorLocation(t *testing.T) {
+	cfg, err := config.LoadFromNamedReader(strings.NewReader(`zones:
+  example.com:
+    rrsets:
+      - name: www
+        type: A
+        records: 192.0.2.1
+      - name: mail
+        type: MX
+        records: 10mail
+`), "zones.yml")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		patchErr error
+		want     string
+	}{
+		{
+			name: "rrset named in error",
+			patchErr: &powerdns.StatusError{StatusCode: 422, Message: "API error (status 422): " +
+				"RRset mail.example.com. IN MX: Record mail.example.com./MX '10mail': Not in expected format"},
+			want: "(config: mail.example.com. MX at zones.yml:7:9 zones.example.com.rrsets[1])",
+		},
+		{
+			name:     "other errors",
+			patchErr: &powerdns.StatusError{StatusCode: 500, Message: "RRset mail.example.com. IN MX: internal"},
+			want:     "failed to patch zone: RRset mail.example.com. IN MX: internal",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewMockClient()
+			client.zones["example.com."] = &powerdns.Zone{Name: "example.com.", Account: "zone-manager"}
+			client.patchZoneErr = tt.patchErr
+			mgr := NewManager(client, "zone-manager", testLogger())
+
+			_, err := mgr.Apply(context.Background(), cfg, ApplyOptions{AutoConfirm: true})
+			if err == nil || !strings.HasSuffix(err.Error(), tt.want) {
+				t.Fatalf("Expected error ending with %q, got %v", tt.want, err)
+			}
+			if strings.Contains(err.Error(), "www") {
+				t.Errorf("Expected only the rejected rrset to be located, got %v", err)
+			}
+		})
+	}
+}
+
+// fakeResolver reports the names in missing as nonexistent.
+type fakeResolver struct {
+	missing map[string]bool
+}
+
+func (r fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	if r.missing[host] {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return []string{"192.0.2.1"}, nil
+}
+
+func TestManager_Apply_DanglingTargets(t *testing.T) {
+	const zones = `zones:
+  example.com:
+    nameservers: [ns.example.net.]
+    mx: 10 mail.example.com.
+    rrsets:
+      - name: www
+        type: CNAME
+        records: web.example.com.
+      - name: web
+        type: A
+        records: 192.0.2.1
+      - name: cdn
+        type: CNAME
+        records: gone.example.net.
+`
+	tests := []struct {
+		name    string
+		mode    string
+		resolve bool
+		want    []string
+	}{
+		{name: "warn by default"},
+		{name: "off", mode: config.DanglingOff, resolve: true},
+		{
+			name: "error",
+			mode: config.DanglingError,
+			want: []string{"zones.yml:4:5: MX 10 target mail.example.com. of example.com. does not exist"},
+		},
+		{
+			name:    "error with resolved targets",
+			mode:    config.DanglingError,
+			resolve: true,
+			want: []string{
+				"zones.yml:4:5: MX 10 target mail.example.com. of example.com. does not exist",
+				"zones.yml:12:9: CNAME target gone.example.net. of cdn.example.com. does not exist",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := config.LoadFromNamedReader(strings.NewReader(zones), "zones.yml")
+			if err != nil {
+				t.Fatalf("Failed to load config: %v", err)
+			}
+			cfg.DanglingTargets = tt.mode
+			mgr := NewManager(NewMockClient(), "zone-manager", testLogger())
+			mgr.SetResolver(fakeResolver{missing: map[string]bool{"gone.example.net.": true}})
+
+			_, err = mgr.Apply(context.Background(), cfg, ApplyOptions{DryRun: true, ResolveTargets: tt.resolve})
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				return
+			}
+			var validationErr *config.ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Expected validation error, got %v", err)
+			}
+			if strings.Join(validationErr.Errors, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Expected errors %q, got %q", tt.want, validationErr.Errors)
+			}
+		})
+	}
+}
+
+func TestManager_Apply_DualStack(t *testing.T) {
+	const zones = `zones:
+  example.com:
+    nameservers: [ns.example.net.]
+    a: 192.0.2.1
+    aaaa: 2001:db8::1
+    rrsets:
+      - name: www
+        type: A
+        records: 192.0.2.2
+      - name: v6
+        type: AAAA
+        records: 2001:db8::2
+      - name: v6
+        type: A
+        records:
+          - content: 192.0.2.3
+            disabled: true
+  legacy.example.org:
+    nameservers: [ns.example.net.]
+    dual_stack: off
+    rrsets:
+      - name: www
+        type: A
+        records: 192.0.2.4
+`
+	tests := []struct {
+		name string
+		mode string
+		want []string
+	}{
+		{name: "off by default"},
+		{name: "warn", mode: config.DualStackWarn},
+		{
+			name: "error",
+			mode: config.DualStackError,
+			want: []string{
+				"zones.yml:7:9: www.example.com. has A records but no AAAA records",
+				"zones.yml:10:9: v6.example.com. has AAAA records but no A records",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := config.LoadFromNamedReader(strings.NewReader(zones), "zones.yml")
+			if err != nil {
+				t.Fatalf("Failed to load config: %v", err)
+			}
+			cfg.DualStack = tt.mode
+			mgr := NewManager(NewMockClient(), "zone-manager", testLogger())
+
+			_, err = mgr.Apply(context.Background(), cfg, ApplyOptions{DryRun: true})
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				return
+			}
+			var validationErr *config.ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Expected validation error, got %v", err)
+			}
+			if strings.Join(validationErr.Errors, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Expected errors %q, got %q", tt.want, validationErr.Errors)
+			}
+		})
+	}
+}
+
+func TestCAACovers(t *testing.T) {
+	caa := map[string]bool{"example.com.": true, "shop.example.net.": true}
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{"example.com.", true},
+		{"www.example.com.", true},
+		{"_443._tcp.www.example.com.", true},
+		{"example.net.", false},
+		{"_443._tcp.example.net.", false},
+		{"api.shop.example.net.", true},
+		{"_443._tcp.shop.example.net.", true},
+	}
+	for _, tt := range tests {
+		if got := caaCovers(caa, tt.name); got != tt.expected {
+			t.Errorf("caaCovers(%q) = %v, want %v", tt.name, got, tt.expected)
+		}
+	}
+}
+
+// serverMockClient is a MockClient that reports a server version, or fails
+// with err.
+type serverMockClient struct {
+	*MockClient
+	version string
+	err     error
+}
+
+func (m *serverMockClient) GetServer(_ context.Context) (*powerdns.Server, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &powerdns.Server{ID: "localhost", Version: m.version}, nil
+}
+
+func TestManager_Apply_SetPTR(t *testing.T) {
+	ptrConfig := func() *config.Config {
+		return &config.Config{Zones: map[string]config.Zone{
+			"example.com": {RRsets: []config.RRsetInput{
+				{Name: "www", Type: "A", Records: []interface{}{
+					map[string]interface{}{"content": "192.0.2.1", "set_ptr": true},
+				}},
+				{Name: "api", Type: "AAAA", Records: []interface{}{
+					map[string]interface{}{"content": "2001:db8::1", "set_ptr": true},
+				}},
+			}},
+			"2.0.192.in-addr.arpa": {},
+		}}
+	}
+	newClient := func() *MockClient {
+		client := NewMockClient()
+		client.zones["example.com."] = &powerdns.Zone{Name: "example.com.", Account: "zone-manager"}
+		client.zones["2.0.192.in-addr.arpa."] = &powerdns.Zone{Name: "2.0.192.in-addr.arpa.", Account: "zone-manager"}
+		return client
+	}
+	patched := func(client *MockClient) map[string]powerdns.RRset {
+		rrsets := make(map[string]powerdns.RRset)
+		for _, patch := range client.patchCalls {
+			for _, rrset := range patch.RRsets {
+				rrsets[rrset.Name+"/"+rrset.Type] = rrset
+			}
+		}
+		return rrsets
+	}
+
+	// Without set-ptr support, PTR records are generated in configured reverse zones
+	client := newClient()
+	mgr := NewManager(client, "zone-manager", testLogger())
+	if _, err := mgr.Apply(context.Background(), ptrConfig(), ApplyOptions{AutoConfirm: true}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	rrsets := patched(client)
+	ptr, ok := rrsets["1.2.0.192.in-addr.arpa./PTR"]
+	if !ok || len(ptr.Records) != 1 || ptr.Records[0].Content != "www.example.com." {
+		t.Errorf("Expected generated PTR to www.example.com., got %+v", rrsets)
+	}
+	if www := rrsets["www.example.com./A"]; www.Records[0].SetPTR {
+		t.Error("Expected set-ptr not to be sent to a server without support")
+	}
+	if len(rrsets) != 3 {
+		t.Errorf("Expected no PTR for the AAAA record without a reverse zone, got %+v", rrsets)
+	}
+
+	// Servers that support set-ptr create the PTR records themselves
+	client = newClient()
+	mgr = NewManager(&serverMockClient{MockClient: client, version: "4.4.1"}, "zone-manager", testLogger())
+	if _, err := mgr.Apply(context.Background(), ptrConfig(), ApplyOptions{AutoConfirm: true}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	rrsets = patched(client)
+	if www := rrsets["www.example.com./A"]; !www.Records[0].SetPTR {
+		t.Errorf("Expected set-ptr to be passed through, got %+v", www)
+	}
+	if _, ok := rrsets["1.2.0.192.in-addr.arpa./PTR"]; ok {
+		t.Error("Expected no generated PTR when the server supports set-ptr")
+	}
+}
+
+func TestManager_Apply_RecordTypes(t *testing.T) {
+	svcbConfig := &config.Config{Zones: map[string]config.Zone{
+		"example.com": {Nameservers: []string{"ns1.example.com."}, RRsets: []config.RRsetInput{
+			{Name: "www", Type: "A", Records: "192.0.2.1"},
+			{Name: "@", Type: "https", Records: "1 . alpn=h2"},
+			{Name: "old", Type: "SVCB", State: config.StateAbsent},
+		}},
+	}}
+
+	tests := []struct {
+		name    string
+		version string
+		err     error
+		want    string
+	}{
+		{"4.3.1", "4.3.1", nil, `zone "example.com", rrset[1] (@/https): record type HTTPS requires ` +
+			"PowerDNS 4.4 or later, the server runs 4.3.1"},
+		{"4.4.0", "4.4.0", nil, ""},
+		// Without a server version, the check is skipped
+		{"forbidden", "", errors.New("API error (status 403): Forbidden"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &serverMockClient{MockClient: NewMockClient(), version: tt.version, err: tt.err}
+			mgr := NewManager(client, "zone-manager", testLogger())
+			_, err := mgr.Apply(context.Background(), svcbConfig, ApplyOptions{DryRun: true, AutoConfirm: true})
+			var validationErr *config.ValidationError
+			switch {
+			case tt.want == "" && err != nil:
+				t.Fatalf("Apply failed: %v", err)
+			case tt.want != "" && (!errors.As(err, &validationErr) || len(validationErr.Errors) != 1):
+				t.Fatalf("Expected a validation error, got %v", err)
+			case tt.want != "" && validationErr.Errors[0] != tt.want:
+				t.Errorf("Expected %q, got %q", tt.want, validationErr.Errors[0])
+			}
+		})
+	}
+}
+
+// queryStatsMockClient is a MockClient that reports query counts.
+type queryStatsMockClient struct {
+	*MockClient
+	counts map[string]int64
+}
+
+func (m *queryStatsMockClient) GetQueryCounts(_ context.Context) (map[string]int64, error) {
+	return m.counts, nil
+}
+
+func TestManager_Apply_CheckQueries(t *testing.T) {
+	managed := func(name, content string) powerdns.RRset {
+		return powerdns.RRset{
+			Name:     name,
+			Type:     "A",
+			Records:  []powerdns.Record{{Content: content}},
+			Comments: []powerdns.Comment{{Content: "owner=zone-manager", Account: "zone-manager"}},
+		}
+	}
+	newClient := func() *MockClient {
+		client := NewMockClient()
+		client.zones["example.com."] = &powerdns.Zone{
+			Name:    "example.com.",
+			Account: "zone-manager",
+			RRsets: []powerdns.RRset{
+				managed("old.example.com.", "192.0.2.1"),
+				managed("idle.example.com.", "192.0.2.2"),
+				// Not managed, deleted because it is declared absent
+				{Name: "legacy.example.com.", Type: "A", Records: []powerdns.Record{{Content: "192.0.2.3"}}},
+			},
+		}
+		return client
+	}
+	cfg := &config.Config{Zones: map[string]config.Zone{"example.com": {RRsets: []config.RRsetInput{
+		{Name: "legacy", Type: "A", State: config.StateAbsent},
+	}}}}
+	counts := map[string]int64{"old.example.com./A": 42, "idle.example.com./AAAA": 7, "legacy.example.com./A": 3}
+
+	tests := []struct {
+		name    string
+		opts    ApplyOptions
+		stats   bool
+		wantErr string
+		patched int
+	}{
+		{name: "not checked", opts: ApplyOptions{AutoConfirm: true}, patched: 3},
+		{
+			name:  "still queried",
+			opts:  ApplyOptions{AutoConfirm: true, CheckQueries: true},
+			stats: true,
+			wantErr: "refusing to delete 2 rrset(s) that still receive queries " +
+				"(legacy.example.com. A, old.example.com. A)",
+		},
+		{
+			name:    "forced",
+			opts:    ApplyOptions{AutoConfirm: true, CheckQueries: true, Force: true},
+			stats:   true,
+			patched: 3,
+		},
+		{
+			name:    "unsupported provider",
+			opts:    ApplyOptions{AutoConfirm: true, CheckQueries: true},
+			wantErr: "checking queries is not supported by the provider",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newClient()
+			var provider Provider = client
+			if tt.stats {
+				provider = &queryStatsMockClient{MockClient: client, counts: counts}
+			}
+			_, err := NewManager(provider, "zone-manager", testLogger()).Apply(context.Background(), cfg, tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				if len(client.patchCalls) != 0 {
+					t.Errorf("Expected no patch, got %+v", client.patchCalls)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+			if len(client.patchCalls) != 1 || len(client.patchCalls[0].RRsets) != tt.patched {
+				t.Errorf("Expected %d deletions, got %+v", tt.patched, client.patchCalls)
+			}
+		})
+	}
+}
+
+func TestManager_Apply_Pacing(t *testing.T) {
+	tests := []struct {
+		name    string
+		pacing  Pacing
+		patches []int
+	}{
+		{name: "disabled", patches: []int{5}},
+		{name: "small zone", pacing: Pacing{LargeZoneRRsets: 6, BatchSize: 2}, patches: []int{5}},
+		{
+			name:    "large zone",
+			pacing:  Pacing{LargeZoneRRsets: 5, BatchSize: 2, Pause: time.Millisecond},
+			patches: []int{2, 2, 1},
+		},
+		{name: "fits in a batch", pacing: Pacing{LargeZoneRRsets: 5, BatchSize: 5}, patches: []int{5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewMockClient()
+			zone := &powerdns.Zone{Name: "example.com.", Account: "zone-manager"}
+			for i := range 5 {
+				zone.RRsets = append(zone.RRsets, powerdns.RRset{
+					Name:     fmt.Sprintf("host%d.example.com.", i),
+					Type:     "A",
+					Records:  []powerdns.Record{{Content: "192.0.2.1"}},
+					Comments: []powerdns.Comment{{Content: "owner=zone-manager", Account: "zone-manager"}},
+				})
+			}
+			client.zones["example.com."] = zone
+			cfg := &config.Config{Zones: map[string]config.Zone{"example.com": {}}}
+
+			mgr := NewManager(client, "zone-manager", testLogger())
+			opts := ApplyOptions{AutoConfirm: true, Pacing: tt.pacing}
+			if _, err := mgr.Apply(context.Background(), cfg, opts); err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+			var patches []int
+			for _, patch := range client.patchCalls {
+				patches = append(patches, len(patch.RRsets))
+			}
+			if !reflect.DeepEqual(patches, tt.patches) {
+				t.Errorf("Expected patches of %v rrsets, got %v", tt.patches, patches)
+			}
+		})
+	}
+}
+
+func TestPacing_Batches(t *testing.T) {
+	pacing := Pacing{LargeZoneRRsets: 1, BatchSize: 2}
+	rrsets := []powerdns.RRset{
+		{Name: "api.example.com.", Type: "A", ChangeType: "REPLACE"},
+		{Name: "www.example.com.", Type: "A", ChangeType: "REPLACE"},
+		{Name: "WWW.example.com.", Type: "CNAME", ChangeType: "DELETE"},
+		{Name: "mail.example.com.", Type: "MX", ChangeType: "REPLACE"},
+		{Name: "mail.example.com.", Type: "A", ChangeType: "REPLACE"},
+		{Name: "mail.example.com.", Type: "AAAA", ChangeType: "REPLACE"},
+	}
+
+	var got [][]string
+	for _, batch := range pacing.batches(len(rrsets), rrsets) {
+		var names []string
+		for _, rrset := range batch {
+			names = append(names, rrset.Name+" "+rrset.Type)
+		}
+		got = append(got, names)
+	}
+	expected := [][]string{
+		{"api.example.com. A"},
+		{"www.example.com. A", "WWW.example.com. CNAME"},
+		{"mail.example.com. MX", "mail.example.com. A", "mail.example.com. AAAA"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected batches %v, got %v", expected, got)
+	}
+}
+
+func TestManager_Apply_PatchSize(t *testing.T) {
+	for _, dryRun := range []bool{false, true} {
+		t.Run(fmt.Sprintf("dry run %v", dryRun), func(t *testing.T) {
+			client := NewMockClient()
+			client.zones["example.com."] = &powerdns.Zone{Name: "example.com.", Account: "zone-manager"}
+			cfg := &config.Config{Zones: map[string]config.Zone{"example.com": {RRsets: []config.RRsetInput{
+				{Name: "www", Type: "A", Records: []interface{}{"192.0.2.1", "192.0.2.2"}},
+				{Name: "api", Type: "A", Records: "192.0.2.3"},
+			}}}}
+
+			mgr := NewManager(client, "zone-manager", testLogger())
+			opts := ApplyOptions{AutoConfirm: true, DryRun: dryRun, Pacing: Pacing{SizeLimit: 100}}
+			result, err := mgr.Apply(context.Background(), cfg, opts)
+			if err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+			zr := result.Zones[0]
+			if zr.PatchRecords != 3 || result.PatchRecords != 3 {
+				t.Errorf("Expected 3 patch records, got %d (total %d)", zr.PatchRecords, result.PatchRecords)
+			}
+			if zr.PatchBytes == 0 || result.PatchBytes != zr.PatchBytes {
+				t.Errorf("Expected the patch size, got %d (total %d)", zr.PatchBytes, result.PatchBytes)
+			}
+			if dryRun {
+				return
+			}
+			body, err := json.Marshal(&client.patchCalls[0])
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			if zr.PatchBytes != len(body) {
+				t.Errorf("Expected a patch size of %d, got %d", len(body), zr.PatchBytes)
+			}
+		})
+	}
+}
+
+func TestManager_Apply_Completed(t *testing.T) {
+	client := NewMockClient()
+	for _, name := range []string{"a.example.", "b.example."} {
+		client.zones[name] = &powerdns.Zone{Name: name, Account: "zone-manager"}
+	}
+	rrsets := []config.RRsetInput{{Name: "www", Type: "A", Records: "192.0.2.1"}}
+	cfg := &config.Config{Zones: map[string]config.Zone{
+		"a.example": {RRsets: rrsets},
+		"b.example": {RRsets: rrsets},
+	}}
+
+	mgr := NewManager(client, "zone-manager", testLogger())
+	opts := ApplyOptions{AutoConfirm: true, Completed: map[string]bool{"a.example": true}}
+	result, err := mgr.Apply(context.Background(), cfg, opts)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if len(client.patchCalls) != 1 || client.patchCalls[0].RRsets[0].Name != "www.b.example." {
+		t.Errorf("Expected a single patch of b.example., got %+v", client.patchCalls)
+	}
+	statuses := make(map[string]ZoneStatus)
+	for _, zr := range result.Zones {
+		statuses[zr.Name] = zr.Status
+	}
+	expected := map[string]ZoneStatus{"a.example": ZoneStatusCompleted, "b.example": ZoneStatusOK}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("Expected statuses %v, got %v", expected, statuses)
+	}
+}
+
+func TestManager_Apply_CreateOnly(t *testing.T) {
+	client := NewMockClient()
+	client.zones["example.com."] = &powerdns.Zone{
+		Name:    "example.com.",
+		Account: "zone-manager",
+		RRsets: []powerdns.RRset{
+			// Tuned by hand after it was created
+			{Name: "example.com.", Type: "TXT", Records: []powerdns.Record{{Content: `"v=spf1 mx -all"`}}},
+			{
+				Name:     "www.example.com.",
+				Type:     "A",
+				Records:  []powerdns.Record{{Content: "192.0.2.1"}},
+				Comments: []powerdns.Comment{{Content: "owner=zone-manager", Account: "zone-manager"}},
+			},
+		},
+	}
+	createOnly := func(name, typ, records string) config.RRsetInput {
+		return config.RRsetInput{
+			Name: name, Type: typ, Records: records, Comment: "bootstrap",
+			ChangePolicy: config.ChangePolicyCreateOnly,
+		}
+	}
+	cfg := &config.Config{Zones: map[string]config.Zone{"example.com": {RRsets: []config.RRsetInput{
+		createOnly("@", "TXT", "v=spf1 -all"),
+		createOnly("www", "A", "192.0.2.2"),
+		createOnly("mail", "A", "192.0.2.3"),
+	}}}}
+
+	mgr := NewManager(client, "zone-manager", testLogger())
+	result, err := mgr.Apply(context.Background(), cfg, ApplyOptions{AutoConfirm: true})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if result.RRsetsCreated != 1 || result.RRsetsUpdated != 0 || result.RRsetsDeleted != 0 {
+		t.Errorf("Expected only mail to be created, got %+v", result)
+	}
+	if len(client.patchCalls) != 1 || len(client.patchCalls[0].RRsets) != 1 {
+		t.Fatalf("Expected a single patch with one rrset, got %+v", client.patchCalls)
+	}
+	created := client.patchCalls[0].RRsets[0]
+	expected := []powerdns.Comment{{Content: "bootstrap"}}
+	if created.Name != "mail.example.com." || !reflect.DeepEqual(created.Comments, expected) {
+		t.Errorf("Expected mail to be created without ownership comment, got %+v", created)
+	}
+}
+
+func TestManager_Apply_Absent(t *testing.T) {
+	client := NewMockClient()
+	owner := []powerdns.Comment{{Content: "owner=zone-manager", Account: "zone-manager"}}
+	client.zones["example.com."] = &powerdns.Zone{
+		Name:    "example.com.",
+		Account: "zone-manager",
+		RRsets: []powerdns.RRset{
+			// Added again by hand
+			{Name: "old.example.com.", Type: "A", Records: []powerdns.Record{{Content: "192.0.2.1"}}},
+			{Name: "ftp.example.com.", Type: "A", Records: []powerdns.Record{{Content: "192.0.2.2"}}, Comments: owner},
+			{Name: "www.example.com.", Type: "A", Records: []powerdns.Record{{Content: "192.0.2.3"}}},
+		},
+	}
+	cfg := &config.Config{Zones: map[string]config.Zone{"example.com": {RRsets: []config.RRsetInput{
+		{Name: "old", Type: "A", State: config.StateAbsent},
+		{Name: "ftp", Type: "A", State: config.StateAbsent},
+		{Name: "gone", Type: "TXT", State: config.StateAbsent},
+	}}}}
+
+	mgr := NewManager(client, "zone-manager", testLogger())
+	result, err := mgr.Apply(context.Background(), cfg, ApplyOptions{AutoConfirm: true})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if result.RRsetsDeleted != 2 || len(client.patchCalls) != 1 {
+		t.Fatalf("Expected 2 deletions in a single patch, got %+v, %+v", result, client.patchCalls)
+	}
+	var deleted []string
+	for _, rrset := range client.patchCalls[0].RRsets {
+		if rrset.ChangeType != "DELETE" {
+			t.Errorf("Expected only deletions, got %+v", rrset)
+		}
+		deleted = append(deleted, rrset.Name)
+	}
+	if expected := []string{"ftp.example.com.", "old.example.com."}; !reflect.DeepEqual(deleted, expected) {
+		t.Errorf("Expected %v to be deleted, got %v", expected, deleted)
+	}
+}
+
+// filterMockClient is a MockClient that filters zones while reading them.
+type filterMockClient struct {
+	*MockClient
+	kept []string
+}
+
+func (m *filterMockClient) GetZoneFiltered(
+	ctx context.Context,
+	zoneID string,
+	keep powerdns.RRsetFilter,
+) (*powerdns.Zone, error) {
+	zone, err := m.GetZone(ctx, zoneID)
+	if err != nil || zone == nil {
+		return zone, err
+	}
+	filtered := *zone
+	filtered.RRsets = nil
+	for _, rrset := range zone.RRsets {
+		if keep(&rrset) {
+			filtered.RRsets = append(filtered.RRsets, rrset)
+			m.kept = append(m.kept, rrset.Name+" "+rrset.Type)
+		}
+	}
+	return &filtered, nil
+}
+
+func TestManager_Apply_FilteredZone(t *testing.T) {
+	owner := []powerdns.Comment{{Content: "owner=zone-manager", Account: "zone-manager"}}
+	client := &filterMockClient{MockClient: NewMockClient()}
+	client.zones["example.com."] = &powerdns.Zone{
+		Name:    "example.com.",
+		Account: "zone-manager",
+		RRsets: []powerdns.RRset{
+			{Name: "www.example.com.", Type: "A", Records: []powerdns.Record{{Content: "192.0.2.9"}}, Comments: owner},
+			{Name: "old.example.com.", Type: "A", Records: []powerdns.Record{{Content: "192.0.2.2"}}, Comments: owner},
+			{Name: "legacy.example.com.", Type: "A", Records: []powerdns.Record{{Content: "192.0.2.3"}}},
+		},
+	}
+	cfg := &config.Config{Zones: map[string]config.Zone{
+		"example.com": {RRsets: []config.RRsetInput{{Name: "www", Type: "A", Records: "192.0.2.1"}}},
+	}}
+
+	result, err := NewManager(client, "zone-manager", testLogger()).
+		Apply(context.Background(), cfg, ApplyOptions{AutoConfirm: true})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if !reflect.DeepEqual(client.kept, []string{"www.example.com. A", "old.example.com. A"}) {
+		t.Errorf("Expected the desired and managed rrsets to be kept, got %v", client.kept)
+	}
+	if result.RRsetsUpdated != 1 || result.RRsetsDeleted != 1 {
+		t.Errorf("Expected 1 update and 1 deletion, got %+v", result)
+	}
+}
+
+// recursorMockClient is a MockClient that manages recursor zones.
+type recursorMockClient struct {
+	*MockClient
+	recursorZones map[string]*powerdns.RecursorZone
+	recursorCalls []string
+}
+
+func (m *recursorMockClient) GetRecursorZone(_ context.Context, zoneID string) (*powerdns.RecursorZone, error) {
+	return m.recursorZones[zoneID], nil
+}
+
+func (m *recursorMockClient) CreateRecursorZone(_ context.Context, zone *powerdns.RecursorZone) error {
+	m.recursorCalls = append(m.recursorCalls, fmt.Sprintf("POST %s %s %v %v",
+		zone.Name, zone.Kind, zone.Servers, zone.RecursionDesired))
+	return nil
+}
+
+func (m *recursorMockClient) ReplaceRecursorZone(_ context.Context, zone *powerdns.RecursorZone) error {
+	m.recursorCalls = append(m.recursorCalls, fmt.Sprintf("PUT %s %s %v %v",
+		zone.Name, zone.Kind, zone.Servers, zone.RecursionDesired))
+	return nil
+}
+
+func TestManager_Apply_RecursorZones(t *testing.T) {
+	cfg := &config.Config{Zones: map[string]config.Zone{
+		"corp.example":    {Target: config.TargetRecursor, Forwarders: []string{"192.0.2.1", "192.0.2.2:5300"}},
+		"blocked.example": {Target: config.TargetRecursor, Kind: config.KindNative},
+		"same.example":    {Target: config.TargetRecursor, Forwarders: []string{"192.0.2.9"}},
+	}}
+	client := &recursorMockClient{MockClient: NewMockClient(), recursorZones: map[string]*powerdns.RecursorZone{
+		"corp.example.": {Name: "corp.example.", Kind: "Forwarded", Servers: []string{"192.0.2.1:53"},
+			RecursionDesired: true},
+		"same.example.": {Name: "same.example.", Kind: "Forwarded", Servers: []string{"192.0.2.9:53"}},
+	}}
+
+	result, err := NewManager(client, "zone-manager", testLogger()).Apply(context.Background(), cfg,
+		ApplyOptions{AutoConfirm: true})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	expected := []string{
+		"POST blocked.example. Native [] false",
+		"PUT corp.example. Forwarded [192.0.2.1:53 192.0.2.2:5300] false",
+	}
+	if strings.Join(client.recursorCalls, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected recursor requests:\n%s", strings.Join(client.recursorCalls, "\n"))
+	}
+	if result.ZonesCreated != 1 || result.MetadataUpdated != 2 {
+		t.Errorf("Expected 1 zone created and 2 settings updated, got %+v", result)
+	}
+	if len(client.patchCalls) != 0 {
+		t.Errorf("Expected no authoritative changes, got %+v", client.patchCalls)
+	}
+
+	_, err = NewManager(NewMockClient(), "zone-manager", testLogger()).Apply(context.Background(), cfg,
+		ApplyOptions{DryRun: true})
+	if err == nil || !strings.Contains(err.Error(), "recursor zones are not supported by the provider") {
+		t.Errorf("Expected unsupported provider error, got %v", err)
+	}
+}
+
+func TestReverseName(t *testing.T) {
+	tests := map[string]string{
+		"192.0.2.1":   "1.2.0.192.in-addr.arpa.",
+		"2001:db8::1": "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.",
+	}
+	for ip, want := range tests {
+		if got := reverseName(net.ParseIP(ip)); got != want {
+			t.Errorf("reverseName(%s) = %s, want %s", ip, got, want)
+		}
+	}
+}
+
+func TestBuildFQDN(t *testing.T) {
+	mgr := &Manager{}
+
+	tests := []struct {
+		name     string
+		zoneID   string
+		expected string
+	}{
+		{"www", "example.com.", "www.example.com."},
+		{"@", "example.com.", "example.com."},
+		{"www.example.com.", "example.com.", "www.example.com."}, // Already FQDN
+		{"sub.domain", "example.com.", "sub.domain.example.com."},
+	}
+
+	for _, tt := range tests {
+		result := mgr.buildFQDN(tt.name, tt.zoneID)
+		if result != tt.expected {
+			t.Errorf("buildFQDN(%s, %s) = %s, want %s", tt.name, tt.zoneID, result, tt.expected)
+		}
+	}
+}
+
+func TestIsManaged(t *testing.T) {
+	mgr := &Manager{accountName: "zone-manager"}
+
+	tests := []struct {
+		name     string
+		rrset    powerdns.RRset
+		expected bool
+	}{
+		{
+			name: "managed",
+			rrset: powerdns.RRset{
+				Comments: []powerdns.Comment{
+					{Content: "owner=zone-manager"},
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "managed - versioned marker",
+			rrset: powerdns.RRset{
+				Comments: []powerdns.Comment{
+					{Content: `owner=zone-manager {"v":1,"tool":"1.4.0","config":"3f2a9c41d07e5b16"}`},
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "not managed - versioned marker of different account",
+			rrset: powerdns.RRset{
+				Comments: []powerdns.Comment{
+					{Content: `owner=other-account {"v":1}`},
+				},
+			},
+			expected: false,
+		},
+		{
+			name: "not managed - different account",
+			rrset: powerdns.RRset{
+				Comments: []powerdns.Comment{
+					{Content: "owner=other-account"},
+				},
+			},
+			expected: false,
+		},
+		{
+			name: "not managed - no comments",
+			rrset: powerdns.RRset{
+				Comments: []powerdns.Comment{},
+			},
+			expected: false,
+		},
+		{
+			name: "managed - one of many comments",
+			rrset: powerdns.RRset{
+				Comments: []powerdns.Comment{
+					{Content: "some other comment"},
+					{Content: "owner=zone-manager"},
+				},
+			},
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := mgr.isManaged(tt.rrset)
+			if result != tt.expected {
+				t.Errorf("isManaged() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestShouldUpdateRRset(t *testing.T) {
+	mgr := &Manager{}
+
+	tests := []struct {
+		name     string
+		desired  powerdns.RRset
+		existing powerdns.RRset
+		expected bool
+	}{
+		{
+			name: "no change",
+			desired: powerdns.RRset{
+				TTL: 300,
+				Records: []powerdns.Record{
+					{Content: "192.168.1.1", Disabled: false},
+				},
+			},
+			existing: powerdns.RRset{
+				TTL: 300,
+				Records: []powerdns.Record{
+					{Content: "192.168.1.1", Disabled: false},
+				},
+			},
+			expected: false,
+		},
+		{
+			name: "TTL changed",
+			desired: powerdns.RRset{
+				TTL: 600,
+				Records: []powerdns.Record{
+					{Content: "192.168.1.1", Disabled: false},
+				},
+			},
+			existing: powerdns.RRset{
+				TTL: 300,
+				Records: []powerdns.Record{
+					{Content: "192.168.1.1", Disabled: false},
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "content changed",
+			desired: powerdns.RRset{
+				TTL: 300,
+				Records: []powerdns.Record{
+					{Content: "192.168.1.2", Disabled: false},
+				},
+			},
+			existing: powerdns.RRset{
+				TTL: 300,
+				Records: []powerdns.Record{
+					{Content: "192.168.1.1", Disabled: false},
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "disabled changed",
+			desired: powerdns.RRset{
+				TTL: 300,
+				Records: []powerdns.Record{
+					{Content: "192.168.1.1", Disabled: true},
+				},
+			},
+			existing: powerdns.RRset{
+				TTL: 300,
+				Records: []powerdns.Record{
+					{Content: "192.168.1.1", Disabled: false},
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "record count changed",
+			desired: powerdns.RRset{
+				TTL: 300,
+				Records: []powerdns.Record{
+					{Content: "192.168.1.1", Disabled: false},
+					{Content: "192.168.1.2", Disabled: false},
+				},
+			},
+			existing: powerdns.RRset{
+				TTL: 300,
+				Records: []powerdns.Record{
+					{Content: "192.168.1.1", Disabled: false},
+				},
+			},
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := mgr.shouldUpdateRRset(tt.desired, tt.existing)
+			if result != tt.expected {
+				t.Errorf("shouldUpdateRRset() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestManager_Apply_Comments(t *testing.T) {
+	client := NewMockClient()
+	mgr := NewManager(client, "zone-manager", testLogger())
+	mgr.SetToolVersion("1.4.0")
+	mgr.SetRunID("20260102T150405-9b1c")
+
+	cfg := &config.Config{
+		Zones: map[string]config.Zone{
+			"example.com": {
+				Nameservers: []string{"ns1.example.com."},
+				RRsets: []config.RRsetInput{
+					{
+						Name: "www",
+						Type: "A",
+						Records: []interface{}{
+							map[string]interface{}{
+								"content": "192.168.1.1",
+								"comment": "primary server",
+							},
+							"192.168.1.2",
+						},
+						Comment: "web servers",
+					},
+				},
+			},
+		},
+	}
+
+	_, err := mgr.Apply(context.Background(), cfg, ApplyOptions{})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	if len(client.patchCalls) != 1 {
+		t.Fatalf("Expected 1 patch call, got %d", len(client.patchCalls))
+	}
+
+	patch := client.patchCalls[0]
+	if len(patch.RRsets) != 2 {
+		t.Fatalf("Expected 2 rrsets in patch, got %d", len(patch.RRsets))
+	}
+
+	// Find the www A rrset
+	var wwwRRset *powerdns.RRset
+	for i := range patch.RRsets {
+		if patch.RRsets[i].Name == "www.example.com." && patch.RRsets[i].Type == "A" {
+			wwwRRset = &patch.RRsets[i]
 			break
 		}
+	}
+	if wwwRRset == nil {
+		t.Fatal("www A rrset not found in patch")
+	}
+
+	// Check comments: should have rrset comment, record comment, then ownership
+	expectedComments := []powerdns.Comment{
+		{Content: "web servers", Account: ""},
+		{Content: "primary server", Account: ""},
+	}
+	if len(wwwRRset.Comments) != len(expectedComments)+1 {
+		t.Fatalf("Expected %d comments, got %+v", len(expectedComments)+1, wwwRRset.Comments)
+	}
+	for i, expected := range expectedComments {
 		actual := wwwRRset.Comments[i]
 		if actual.Content != expected.Content || actual.Account != expected.Account {
 			t.Errorf("Comment %d: expected %+v, got %+v", i, expected, actual)
 		}
 	}
+
+	owner := wwwRRset.Comments[len(expectedComments)]
+	if owner.Account != "zone-manager" {
+		t.Errorf("Expected ownership comment account zone-manager, got %q", owner.Account)
+	}
+	marker, ok := ownership.Parse(owner.Content)
+	if !ok {
+		t.Fatalf("Expected an ownership marker, got %q", owner.Content)
+	}
+	if marker.Account != "zone-manager" || marker.Version != ownership.Version || marker.Tool != "1.4.0" ||
+		marker.RunID != "20260102T150405-9b1c" || marker.Time.IsZero() {
+		t.Errorf("Unexpected ownership marker: %+v", marker)
+	}
 }
 
 func TestRRsetKey(t *testing.T) {
@@ -719,3 +2152,134 @@ func TestRRsetKey(t *testing.T) {
 		}
 	}
 }
+
+// BenchmarkManager_Apply_DryRun plans a zone with 100k rrsets against an
+// existing zone in which 1% of them differ and 1% are orphaned.
+func BenchmarkManager_Apply_DryRun(b *testing.B) {
+	const n = 100000
+	owner := []powerdns.Comment{{Content: "owner=zone-manager", Account: "zone-manager"}}
+	zone := &powerdns.Zone{Name: "example.com.", Account: "zone-manager", RRsets: make([]powerdns.RRset, 0, n)}
+	inputs := make([]config.RRsetInput, 0, n)
+	for i := range n {
+		name := fmt.Sprintf("host%d", i)
+		inputs = append(inputs, config.RRsetInput{Name: name, Type: "A", Records: "192.0.2.1"})
+		content := "192.0.2.1"
+		if i%100 == 0 {
+			content = "192.0.2.2"
+		}
+		if i%100 == 1 {
+			name = fmt.Sprintf("old%d", i)
+		}
+		zone.RRsets = append(zone.RRsets, powerdns.RRset{
+			Name:     name + ".example.com.",
+			Type:     "A",
+			TTL:      300,
+			Records:  []powerdns.Record{{Content: content}},
+			Comments: owner,
+		})
+	}
+	client := NewMockClient()
+	client.zones["example.com."] = zone
+	cfg := &config.Config{Zones: map[string]config.Zone{"example.com": {RRsets: inputs}}}
+	mgr := NewManager(client, "zone-manager", testLogger().Quiet())
+
+	b.ReportAllocs()
+	for b.Loop() {
+		result, err := mgr.Apply(context.Background(), cfg, ApplyOptions{DryRun: true})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if result.RRsetsUpdated != n/100 || result.RRsetsDeleted != n/100 || result.RRsetsCreated != n/100 {
+			b.Fatalf("Unexpected result: %+v", result)
+		}
+	}
+}
+
+func TestManager_Apply_CreateConflict(t *testing.T) {
+	tests := []struct {
+		name    string
+		account string
+		wantErr string
+	}{
+		{name: "created by another run of the account", account: "zone-manager"},
+		{name: "created by another account", account: "team-b", wantErr: `account "team-b" and is not managed`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewMockClient()
+			client.raced = &powerdns.Zone{
+				Name:    "example.com.",
+				Account: tt.account,
+				RRsets: []powerdns.RRset{
+					{Name: "www.example.com.", Type: "A", Records: []powerdns.Record{{Content: "192.0.2.1"}}},
+				},
+			}
+			cfg := &config.Config{Zones: map[string]config.Zone{"example.com": {
+				Nameservers: []string{"ns1.example.com."},
+				RRsets:      []config.RRsetInput{{Name: "mail", Type: "A", Records: "192.0.2.2"}},
+			}}}
+
+			mgr := NewManager(client, "zone-manager", testLogger())
+			result, err := mgr.Apply(context.Background(), cfg, ApplyOptions{AutoConfirm: true})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				if len(client.patchCalls) != 0 {
+					t.Errorf("Expected no changes to the zone of another account, got %+v", client.patchCalls)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+			if result.ZonesCreated != 0 || result.Zones[0].Created {
+				t.Errorf("Expected the zone not to be reported as created, got %+v", result)
+			}
+			if result.RRsetsCreated == 0 || len(client.patchCalls) == 0 {
+				t.Errorf("Expected the rrsets of the zone to be reconciled, got %+v", result)
+			}
+		})
+	}
+}
+
+func TestManager_Apply_Serials(t *testing.T) {
+	tests := []struct {
+		name    string
+		planned uint32
+		wantErr bool
+	}{
+		{name: "unchanged since planned", planned: 5},
+		{name: "modified since planned", planned: 4, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewMockClient()
+			client.zones["example.com."] = &powerdns.Zone{Name: "example.com.", Account: "zone-manager", Serial: 5}
+			cfg := &config.Config{Zones: map[string]config.Zone{"example.com": {
+				RRsets: []config.RRsetInput{{Name: "www", Type: "A", Records: "192.0.2.1"}},
+			}}}
+
+			mgr := NewManager(client, "zone-manager", testLogger())
+			opts := ApplyOptions{AutoConfirm: true, Serials: map[string]uint32{"example.com": tt.planned}}
+			result, err := mgr.Apply(context.Background(), cfg, opts)
+			if tt.wantErr {
+				if !errors.Is(err, ErrZoneModified) {
+					t.Fatalf("Expected ErrZoneModified, got %v", err)
+				}
+				if len(client.patchCalls) != 0 {
+					t.Errorf("Expected no changes to the modified zone, got %+v", client.patchCalls)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+			if result.Zones[0].Serial != 5 || len(client.patchCalls) != 1 {
+				t.Errorf("Expected the zone to be applied with serial 5, got %+v", result.Zones[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+// Package manifest builds, signs and verifies manifests of apply change sets.
+//
+// A manifest lists exactly which zones are created and which RRsets are
+// replaced or deleted. It is signed with HMAC-SHA256 so that an approved
+// change set can be tied to what is eventually applied.
+package manifest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+// Version is the current manifest format version. Version 1 manifests only
+// signed the change set and are no longer accepted.
+const Version = 2
+
+// ErrInvalidSignature is returned when a manifest signature does not match.
+var ErrInvalidSignature = errors.New("manifest signature is invalid")
+
+// Manifest describes a change set and its signature.
+type Manifest struct {
+	CreatedAt time.Time `json:"createdAt"`
+	Account   string    `json:"account"`
+	Digest    string    `json:"digest"`
+	Signature string    `json:"signature,omitempty"`
+	Zones     []Zone    `json:"zones"`
+	Version   int       `json:"version"`
+}
+
+// Zone lists the changes of a single zone.
+type Zone struct {
+	Name    string  `json:"name"`
+	Changes []Entry `json:"changes"`
+	Create  bool    `json:"create,omitempty"`
+	// Serial is the SOA serial of an existing zone when the change set was
+	// planned, if serials were recorded (see RecordSerials)
+	Serial uint32 `json:"serial,omitempty"`
+}
+
+// Entry is a single RRset change as sent to PowerDNS.
+// ChangeType is REPLACE for created and updated RRsets and DELETE otherwise.
+type Entry struct {
+	Name       string            `json:"name"`
+	Type       string            `json:"type"`
+	ChangeType string            `json:"changetype"`
+	Records    []powerdns.Record `json:"records,omitempty"`
+	TTL        uint32            `json:"ttl,omitempty"`
+}
+
+// New builds an unsigned manifest from the changes of an apply result.
+// Zones without changes are omitted.
+func New(account string, result *manager.ApplyResult) *Manifest {
+	m := &Manifest{
+		Version:   Version,
+		CreatedAt: time.Now().UTC(),
+		Account:   account,
+	}
+
+	for _, zr := range result.Zones {
+		if !zr.Created && len(zr.Changes) == 0 {
+			continue
+		}
+		z := Zone{Name: zr.Name, Create: zr.Created}
+		for _, c := range zr.Changes {
+			z.Changes = append(z.Changes, newEntry(&c))
+		}
+		sort.Slice(z.Changes, func(i, j int) bool {
+			if z.Changes[i].Name != z.Changes[j].Name {
+				return z.Changes[i].Name < z.Changes[j].Name
+			}
+			return z.Changes[i].Type < z.Changes[j].Type
+		})
+		m.Zones = append(m.Zones, z)
+	}
+	sort.Slice(m.Zones, func(i, j int) bool {
+		return m.Zones[i].Name < m.Zones[j].Name
+	})
+
+	m.Digest = m.computeDigest()
+	return m
+}
+
+func newEntry(c *manager.Change) Entry {
+	if c.Action == manager.ChangeDelete {
+		return Entry{Name: c.Name, Type: c.Type, ChangeType: "DELETE"}
+	}
+
+	records := append([]powerdns.Record(nil), c.After...)
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Content < records[j].Content
+	})
+	return Entry{
+		Name:       c.Name,
+		Type:       c.Type,
+		ChangeType: "REPLACE",
+		TTL:        c.NewTTL,
+		Records:    records,
+	}
+}
+
+// RecordSerials records the SOA serials of the existing zones of the change
+// set from the result that planned it, so that the change set is only applied
+// if no one modified the zones in between (see Serials). The serials are part
+// of the digest.
+func (m *Manifest) RecordSerials(result *manager.ApplyResult) {
+	serials := make(map[string]uint32, len(result.Zones))
+	for _, zr := range result.Zones {
+		serials[zr.Name] = zr.Serial
+	}
+	for i := range m.Zones {
+		if !m.Zones[i].Create {
+			m.Zones[i].Serial = serials[m.Zones[i].Name]
+		}
+	}
+	m.Digest = m.computeDigest()
+}
+
+// Serials returns the recorded serials by zone name, for
+// manager.ApplyOptions.Serials. It is empty if no serials were recorded.
+func (m *Manifest) Serials() map[string]uint32 {
+	serials := make(map[string]uint32)
+	for _, z := range m.Zones {
+		if z.Serial != 0 {
+			serials[z.Name] = z.Serial
+		}
+	}
+	return serials
+}
+
+// computeDigest returns the hex SHA-256 of the canonical change set.
+func (m *Manifest) computeDigest() string {
+	data, err := json.Marshal(m.Zones)
+	if err != nil {
+		// Zones only contain plain data types, marshaling cannot fail
+		panic(fmt.Sprintf("failed to marshal manifest zones: %v", err))
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Sign signs the manifest with the given key: the change set as well as the
+// account, version and creation time.
+func (m *Manifest) Sign(key []byte) {
+	m.Signature = m.sign(key)
+}
+
+// Verify checks that the digest matches the change set and that the
+// signature of the manifest was made with the given key.
+func (m *Manifest) Verify(key []byte) error {
+	if m.Version != Version {
+		return fmt.Errorf("unsupported manifest version %d", m.Version)
+	}
+	if m.computeDigest() != m.Digest {
+		return errors.New("manifest digest does not match its change set")
+	}
+	if !hmac.Equal([]byte(m.sign(key)), []byte(m.Signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Matches returns an error if the other manifest describes a different change set.
+func (m *Manifest) Matches(other *Manifest) error {
+	if m.Account != other.Account {
+		return fmt.Errorf("manifest account %q does not match %q", m.Account, other.Account)
+	}
+	if m.Digest != other.Digest {
+		return fmt.Errorf("change set differs from manifest (digest %s, expected %s)", other.Digest, m.Digest)
+	}
+	return nil
+}
+
+// sign returns the HMAC of the manifest without its digest, which is derived
+// from the zones, and its signature.
+func (m *Manifest) sign(key []byte) string {
+	unsigned := *m
+	unsigned.Digest, unsigned.Signature = "", ""
+	data, err := json.Marshal(&unsigned)
+	if err != nil {
+		// Manifests only contain plain data types, marshaling cannot fail
+		panic(fmt.Sprintf("failed to marshal manifest: %v", err))
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Load reads a manifest from a JSON file.
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path is from CLI argument
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// Save writes the manifest to a JSON file.
+func (m *Manifest) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package manifest
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+func testResult(content string) *manager.ApplyResult {
+	return &manager.ApplyResult{
+		Zones: []manager.ZoneResult{
+			{
+				Name: "example.com",
+				Changes: []manager.Change{
+					{
+						Action: manager.ChangeUpdate,
+						Name:   "www.example.com.",
+						Type:   "A",
+						After:  []powerdns.Record{{Content: content}},
+						NewTTL: 300,
+					},
+					{
+						Action: manager.ChangeDelete,
+						Name:   "old.example.com.",
+						Type:   "A",
+						Before: []powerdns.Record{{Content: "192.168.1.9"}},
+					},
+				},
+			},
+			{Name: "unchanged.com", Status: manager.ZoneStatusOK},
+		},
+	}
+}
+
+func TestManifest_SignAndVerify(t *testing.T) {
+	key := []byte("secret")
+	m := New("zone-manager", testResult("192.168.1.1"))
+	m.Sign(key)
+
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	if err := m.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if err := loaded.Verify(key); err != nil {
+		t.Errorf("Expected valid signature, got: %v", err)
+	}
+	if err := loaded.Verify([]byte("other")); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for wrong key, got: %v", err)
+	}
+
+	if len(loaded.Zones) != 1 {
+		t.Errorf("Expected zones without changes to be omitted, got %d zones", len(loaded.Zones))
+	}
+}
+
+func TestManifest_VerifyDetectsTampering(t *testing.T) {
+	key := []byte("secret")
+	m := New("zone-manager", testResult("192.168.1.1"))
+	m.Sign(key)
+
+	m.Zones[0].Changes[1].Records[0].Content = "10.0.0.1"
+	err := m.Verify(key)
+	if err == nil || !strings.Contains(err.Error(), "digest does not match") {
+		t.Errorf("Expected digest mismatch error, got: %v", err)
+	}
+}
+
+func TestManifest_VerifyDetectsMetadataTampering(t *testing.T) {
+	key := []byte("secret")
+	tests := []struct {
+		name   string
+		tamper func(*Manifest)
+	}{
+		{"account", func(m *Manifest) { m.Account = "other" }},
+		{"created at", func(m *Manifest) { m.CreatedAt = m.CreatedAt.Add(time.Hour) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New("zone-manager", testResult("192.168.1.1"))
+			m.Sign(key)
+			tt.tamper(m)
+			if err := m.Verify(key); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Expected ErrInvalidSignature, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestManifest_Matches(t *testing.T) {
+	approved := New("zone-manager", testResult("192.168.1.1"))
+
+	if err := approved.Matches(New("zone-manager", testResult("192.168.1.1"))); err != nil {
+		t.Errorf("Expected identical change sets to match, got: %v", err)
+	}
+	if err := approved.Matches(New("zone-manager", testResult("192.168.1.2"))); err == nil {
+		t.Error("Expected different change sets not to match")
+	}
+	if err := approved.Matches(New("other", testResult("192.168.1.1"))); err == nil {
+		t.Error("Expected different accounts not to match")
+	}
+}
+
+func TestManifest_RecordSerials(t *testing.T) {
+	key := []byte("secret")
+	result := testResult("192.168.1.1")
+	result.Zones[0].Serial = 2026101701
+	result.Zones = append(result.Zones, manager.ZoneResult{Name: "new.com", Created: true})
+
+	m := New("zone-manager", result)
+	if len(m.Serials()) != 0 {
+		t.Errorf("Expected no serials without RecordSerials, got %v", m.Serials())
+	}
+	unrecorded := m.Digest
+	m.RecordSerials(result)
+	m.Sign(key)
+	if m.Digest == unrecorded {
+		t.Error("Expected the serials to be part of the digest")
+	}
+	expected := map[string]uint32{"example.com": 2026101701}
+	if serials := m.Serials(); len(serials) != 1 || serials["example.com"] != expected["example.com"] {
+		t.Errorf("Expected serials %v of the existing zones, got %v", expected, serials)
+	}
+
+	m.Zones[0].Serial = 0
+	if err := m.Verify(key); err == nil {
+		t.Error("Expected removed serials to be detected")
+	}
+}
@@ -0,0 +1,102 @@
+// Package ownership encodes and parses the comments that mark RRsets as managed.
+//
+// A marker comment starts with "owner=<account>". Since version 1 it is
+// followed by a JSON payload recording which tool version wrote the RRset,
+// from which configuration, in which run and when:
+//
+//	owner=team-a {"v":1,"tool":"1.4.0","config":"3f2a9c41d07e5b16","run":"ci-4711","time":"2026-01-02T15:04:05Z"}
+//
+// Plain "owner=<account>" comments written by older versions are version 0.
+package ownership
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+// Version is the payload version written by Marker.String.
+const Version = 1
+
+const prefix = "owner="
+
+// Marker is the content of an ownership comment.
+type Marker struct {
+	// Account is the account name that owns the RRset.
+	Account string `json:"-"`
+	// Version is the payload version, 0 for plain markers.
+	Version int `json:"v"`
+	// Tool is the version of the tool that wrote the RRset.
+	Tool string `json:"tool,omitempty"`
+	// ConfigHash identifies the configuration the RRset was written from.
+	ConfigHash string `json:"config,omitempty"`
+	// RunID identifies the apply run that wrote the RRset.
+	RunID string `json:"run,omitempty"`
+	// Time is when the RRset was written.
+	Time time.Time `json:"time,omitzero"`
+}
+
+// String returns the comment content of the marker. Markers with version 0
+// are rendered in the plain format.
+func (m Marker) String() string {
+	if m.Version == 0 {
+		return prefix + m.Account
+	}
+	payload, err := json.Marshal(m)
+	if err != nil {
+		// The payload only has strings, numbers and a time, it always marshals
+		return prefix + m.Account
+	}
+	return prefix + m.Account + " " + string(payload)
+}
+
+// Parse parses an ownership comment. It returns false if content is not an
+// ownership marker. Payloads of newer versions are accepted, fields unknown to
+// this version are ignored.
+func Parse(content string) (Marker, bool) {
+	rest, ok := strings.CutPrefix(content, prefix)
+	if !ok {
+		return Marker{}, false
+	}
+
+	account, payload, hasPayload := strings.Cut(rest, " ")
+	if account == "" {
+		return Marker{}, false
+	}
+	if !hasPayload {
+		return Marker{Account: account}, true
+	}
+
+	var m Marker
+	if err := json.Unmarshal([]byte(payload), &m); err != nil || m.Version < 1 {
+		return Marker{}, false
+	}
+	m.Account = account
+	return m, true
+}
+
+// Accounts returns the accounts of the ownership markers among the comment
+// contents of an RRset, sorted and without duplicates.
+func Accounts(contents []string) []string {
+	var accounts []string
+	for _, content := range contents {
+		if m, ok := Parse(content); ok && !slices.Contains(accounts, m.Account) {
+			accounts = append(accounts, m.Account)
+		}
+	}
+	slices.Sort(accounts)
+	return accounts
+}
+
+// OwnedBy returns true if an ownership comment of the RRset names account.
+func OwnedBy(rrset *powerdns.RRset, account string) bool {
+	for _, comment := range rrset.Comments {
+		if m, ok := Parse(comment.Content); ok && m.Account == account {
+			return true
+		}
+	}
+	return false
+}
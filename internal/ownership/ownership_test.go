@@ -0,0 +1,157 @@
+package ownership
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+func TestMarker_String(t *testing.T) {
+	at := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name   string
+		marker Marker
+		want   string
+	}{
+		{
+			name:   "plain",
+			marker: Marker{Account: "team-a"},
+			want:   "owner=team-a",
+		},
+		{
+			name: "versioned",
+			marker: Marker{
+				Account: "team-a", Version: Version, Tool: "1.4.0", ConfigHash: "3f2a9c41d07e5b16",
+				RunID: "20260102T150405-9b1c", Time: at,
+			},
+			want: `owner=team-a {"v":1,"tool":"1.4.0","config":"3f2a9c41d07e5b16","run":"20260102T150405-9b1c",` +
+				`"time":"2026-01-02T15:04:05Z"}`,
+		},
+		{
+			name:   "versioned without details",
+			marker: Marker{Account: "team-a", Version: Version},
+			want:   `owner=team-a {"v":1}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.marker.String(); got != tt.want {
+				t.Errorf("String() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	at := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name    string
+		content string
+		want    Marker
+		wantOK  bool
+	}{
+		{
+			name:    "plain",
+			content: "owner=team-a",
+			want:    Marker{Account: "team-a"},
+			wantOK:  true,
+		},
+		{
+			name:    "versioned",
+			content: `owner=team-a {"v":1,"tool":"1.4.0","config":"3f2a9c41d07e5b16","time":"2026-01-02T15:04:05Z"}`,
+			want:    Marker{Account: "team-a", Version: 1, Tool: "1.4.0", ConfigHash: "3f2a9c41d07e5b16", Time: at},
+			wantOK:  true,
+		},
+		{
+			name:    "newer version with unknown fields",
+			content: `owner=team-a {"v":2,"tool":"2.0.0","source":"git"}`,
+			want:    Marker{Account: "team-a", Version: 2, Tool: "2.0.0"},
+			wantOK:  true,
+		},
+		{
+			name:    "not a marker",
+			content: "web servers",
+		},
+		{
+			name:    "no account",
+			content: "owner=",
+		},
+		{
+			name:    "free text after account",
+			content: "owner=team-a ask in #dns",
+		},
+		{
+			name:    "payload without version",
+			content: `owner=team-a {"tool":"1.4.0"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Parse(tt.content)
+			if ok != tt.wantOK {
+				t.Fatalf("Parse() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if got != tt.want {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_RoundTrip(t *testing.T) {
+	m := Marker{
+		Account:    "team-a",
+		Version:    Version,
+		Tool:       "dev",
+		ConfigHash: "3f2a9c41d07e5b16",
+		RunID:      "20260102T150405-9b1c",
+		Time:       time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC),
+	}
+	got, ok := Parse(m.String())
+	if !ok || got != m {
+		t.Errorf("Parse(String()) = %+v, %v, want %+v", got, ok, m)
+	}
+}
+
+func TestAccounts(t *testing.T) {
+	contents := []string{
+		"owner=team-b",
+		"maintenance window",
+		`owner=team-a {"v":1,"tool":"dev"}`,
+		"owner=team-b",
+	}
+	got := Accounts(contents)
+	if strings.Join(got, ",") != "team-a,team-b" {
+		t.Errorf("Accounts() = %v, want [team-a team-b]", got)
+	}
+	if got := Accounts([]string{"no marker"}); got != nil {
+		t.Errorf("Accounts() = %v, want nil", got)
+	}
+}
+
+func TestOwnedBy(t *testing.T) {
+	rrset := &powerdns.RRset{Comments: []powerdns.Comment{
+		{Content: "maintenance window"},
+		{Content: `owner=team-a {"v":1,"tool":"dev"}`},
+	}}
+	tests := []struct {
+		account string
+		want    bool
+	}{
+		{"team-a", true},
+		{"team-b", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := OwnedBy(rrset, tt.account); got != tt.want {
+			t.Errorf("OwnedBy(%q) = %v, want %v", tt.account, got, tt.want)
+		}
+	}
+	if OwnedBy(&powerdns.RRset{}, "team-a") {
+		t.Error("Expected an RRset without comments not to be owned")
+	}
+}
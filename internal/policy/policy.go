@@ -0,0 +1,298 @@
+// Package policy evaluates change governance rules against the changes an
+// apply would make, e.g. "no deletions in prod.example.com", "TTLs of at
+// least 60 seconds" or "MX changes require --allow mx".
+//
+// A policy file lists rules. Each rule selects changes by zone, rrset type
+// and action, and constrains them:
+//
+//	rules:
+//	  - name: no-prod-deletions
+//	    zones: [prod.example.com]
+//	    actions: [delete]
+//	    deny: true
+//	  - name: min-ttl
+//	    min_ttl: 60
+//	  - name: mx-guard
+//	    types: [MX]
+//	    require_allow: mx
+//	  - name: internal-addresses
+//	    zones: [internal.example.com]
+//	    allowed_cidrs: [10.0.0.0/8, 172.16.0.0/12, 192.168.0.0/16]
+package policy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+)
+
+// Policy is a set of rules.
+type Policy struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule constrains the changes it selects. Empty selectors select everything.
+type Rule struct {
+	// Zones are glob patterns of the zone names, e.g. "*.prod.example.com"
+	Zones []string `yaml:"zones,omitempty"`
+	// Types are rrset types, e.g. MX
+	Types []string `yaml:"types,omitempty"`
+	// Actions are create, update or delete
+	Actions []string `yaml:"actions,omitempty"`
+	// AllowedCIDRs and DeniedCIDRs constrain the contents of created and
+	// updated A and AAAA rrsets: every address must be in one of the allowed
+	// ranges, if any, and in none of the denied ones
+	AllowedCIDRs []string `yaml:"allowed_cidrs,omitempty"`
+	DeniedCIDRs  []string `yaml:"denied_cidrs,omitempty"`
+	// allowed and denied are the parsed AllowedCIDRs and DeniedCIDRs
+	allowed []netip.Prefix
+	denied  []netip.Prefix
+	Name    string `yaml:"name"`
+	// Message replaces the default violation message
+	Message string `yaml:"message,omitempty"`
+	// RequireAllow denies the changes unless this token is allowed
+	// (apply --allow)
+	RequireAllow string `yaml:"require_allow,omitempty"`
+	// MinTTL and MaxTTL bound the TTL of created and updated rrsets
+	MinTTL uint32 `yaml:"min_ttl,omitempty"`
+	MaxTTL uint32 `yaml:"max_ttl,omitempty"`
+	// Deny denies the changes
+	Deny bool `yaml:"deny,omitempty"`
+}
+
+// Violation is a change that a rule does not permit.
+type Violation struct {
+	Rule    string
+	Zone    string
+	Message string
+	// Location is where the rrset is defined in the configuration
+	Location config.Location
+}
+
+func (v Violation) String() string {
+	s := fmt.Sprintf("%s: %s", v.Rule, v.Message)
+	if v.Location != (config.Location{}) {
+		s += " (" + v.Location.String() + ")"
+	}
+	return s
+}
+
+// Load reads a policy file. Unknown keys are rejected.
+func Load(filePath string) (*Policy, error) {
+	data, err := os.ReadFile(filePath) //nolint:gosec // path is from CLI argument or settings
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+	p, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("policy %s: %w", filePath, err)
+	}
+	return p, nil
+}
+
+// Parse parses and validates a policy.
+func Parse(data []byte) (*Policy, error) {
+	var p Policy
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&p); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse policy: %w", err)
+	}
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+var actions = []string{string(manager.ChangeCreate), string(manager.ChangeUpdate), string(manager.ChangeDelete)}
+
+func (p *Policy) validate() error {
+	names := make(map[string]bool, len(p.Rules))
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if r.Name == "" {
+			return fmt.Errorf("rules[%d]: name is required", i)
+		}
+		if names[r.Name] {
+			return fmt.Errorf("rule %s: duplicate name", r.Name)
+		}
+		names[r.Name] = true
+
+		if !r.Deny && r.RequireAllow == "" && r.MinTTL == 0 && r.MaxTTL == 0 &&
+			len(r.AllowedCIDRs) == 0 && len(r.DeniedCIDRs) == 0 {
+			return fmt.Errorf("rule %s: one of deny, require_allow, min_ttl, max_ttl, "+
+				"allowed_cidrs or denied_cidrs is required", r.Name)
+		}
+		if r.MaxTTL > 0 && r.MinTTL > r.MaxTTL {
+			return fmt.Errorf("rule %s: min_ttl %d is greater than max_ttl %d", r.Name, r.MinTTL, r.MaxTTL)
+		}
+		for j, zone := range r.Zones {
+			r.Zones[j] = config.CanonicalZoneName(strings.ToLower(zone))
+			if _, err := path.Match(r.Zones[j], ""); err != nil {
+				return fmt.Errorf("rule %s: invalid zone pattern %q: %w", r.Name, zone, err)
+			}
+		}
+		for j, rtype := range r.Types {
+			r.Types[j] = strings.ToUpper(rtype)
+		}
+		var err error
+		if r.allowed, err = parsePrefixes(r.AllowedCIDRs); err != nil {
+			return fmt.Errorf("rule %s: allowed_cidrs: %w", r.Name, err)
+		}
+		if r.denied, err = parsePrefixes(r.DeniedCIDRs); err != nil {
+			return fmt.Errorf("rule %s: denied_cidrs: %w", r.Name, err)
+		}
+		for _, action := range r.Actions {
+			if !slices.Contains(actions, action) {
+				return fmt.Errorf("rule %s: invalid action %q, must be: %s",
+					r.Name, action, strings.Join(actions, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// parsePrefixes parses CIDR ranges, e.g. 10.0.0.0/8 or 2001:db8::/32.
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", cidr)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// Evaluate returns the violations of the changes of result. allowed are the
+// tokens of rules with require_allow that are allowed for this run.
+func (p *Policy) Evaluate(result *manager.ApplyResult, allowed []string) []Violation {
+	var violations []Violation
+	for _, zr := range result.Zones {
+		zone := config.CanonicalZoneName(zr.Name)
+		for i := range p.Rules {
+			r := &p.Rules[i]
+			if !r.matchesZone(zone) {
+				continue
+			}
+			for j := range zr.Changes {
+				change := &zr.Changes[j]
+				if !r.matches(change) {
+					continue
+				}
+				if msg := r.check(change, allowed); msg != "" {
+					violations = append(violations, Violation{
+						Rule:     r.Name,
+						Zone:     zone,
+						Message:  msg,
+						Location: change.Location,
+					})
+				}
+			}
+		}
+	}
+	return violations
+}
+
+func (r *Rule) matchesZone(zone string) bool {
+	if len(r.Zones) == 0 {
+		return true
+	}
+	for _, pattern := range r.Zones {
+		if ok, _ := path.Match(pattern, zone); ok { //nolint:errcheck // patterns are validated
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matches(change *manager.Change) bool {
+	if len(r.Types) > 0 && !slices.Contains(r.Types, strings.ToUpper(change.Type)) {
+		return false
+	}
+	return len(r.Actions) == 0 || slices.Contains(r.Actions, string(change.Action))
+}
+
+// check returns the violation message of a selected change, if any.
+func (r *Rule) check(change *manager.Change, allowed []string) string {
+	what := fmt.Sprintf("%s of %s %s", change.Action, change.Name, change.Type)
+	var msg string
+	switch {
+	case r.Deny:
+		msg = what + " is denied"
+	case r.RequireAllow != "" && !slices.Contains(allowed, r.RequireAllow):
+		msg = fmt.Sprintf("%s requires --allow %s", what, r.RequireAllow)
+	case change.Action == manager.ChangeDelete:
+		return ""
+	case r.MinTTL > 0 && change.NewTTL < r.MinTTL:
+		msg = fmt.Sprintf("%s: TTL %d is below the minimum of %d", what, change.NewTTL, r.MinTTL)
+	case r.MaxTTL > 0 && change.NewTTL > r.MaxTTL:
+		msg = fmt.Sprintf("%s: TTL %d is above the maximum of %d", what, change.NewTTL, r.MaxTTL)
+	case len(r.allowed)+len(r.denied) > 0:
+		if msg = r.checkAddresses(change); msg == "" {
+			return ""
+		}
+		msg = what + ": " + msg
+	default:
+		return ""
+	}
+	if r.Message != "" {
+		return what + ": " + r.Message
+	}
+	return msg
+}
+
+// checkAddresses returns the violation message of a created or updated A or
+// AAAA rrset with addresses outside of the allowed or inside of the denied
+// ranges of the rule, naming the offending records.
+func (r *Rule) checkAddresses(change *manager.Change) string {
+	if change.Type != "A" && change.Type != "AAAA" {
+		return ""
+	}
+	var outside, inside []string
+	for _, record := range change.After {
+		addr, err := netip.ParseAddr(record.Content)
+		if err != nil {
+			continue
+		}
+		addr = addr.Unmap()
+		if len(r.allowed) > 0 && !containsAddr(r.allowed, addr) {
+			outside = append(outside, record.Content)
+		}
+		if containsAddr(r.denied, addr) {
+			inside = append(inside, record.Content)
+		}
+	}
+	var msgs []string
+	if len(outside) > 0 {
+		msgs = append(msgs, fmt.Sprintf("%s not in the allowed ranges %s",
+			strings.Join(outside, ", "), strings.Join(r.AllowedCIDRs, ", ")))
+	}
+	if len(inside) > 0 {
+		msgs = append(msgs, fmt.Sprintf("%s in the denied ranges %s",
+			strings.Join(inside, ", "), strings.Join(r.DeniedCIDRs, ", ")))
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// containsAddr returns true if any of prefixes contains addr.
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package policy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+const testPolicy = `rules:
+  - name: no-prod-deletions
+    zones: [prod.example.com]
+    actions: [delete]
+    deny: true
+  - name: min-ttl
+    min_ttl: 60
+  - name: mx-guard
+    types: [mx]
+    require_allow: mx
+    message: MX changes need a change ticket
+`
+
+func TestEvaluate(t *testing.T) {
+	p, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	loc := config.Location{File: "zones.yml", Line: 7, Column: 9}
+	result := &manager.ApplyResult{Zones: []manager.ZoneResult{
+		{Name: "prod.example.com", Changes: []manager.Change{
+			{Action: manager.ChangeDelete, Name: "old.prod.example.com.", Type: "A", OldTTL: 30},
+			{Action: manager.ChangeCreate, Name: "www.prod.example.com.", Type: "A", NewTTL: 30, Location: loc},
+			{Action: manager.ChangeUpdate, Name: "prod.example.com.", Type: "MX", NewTTL: 300},
+		}},
+		{Name: "dev.example.com.", Changes: []manager.Change{
+			{Action: manager.ChangeDelete, Name: "old.dev.example.com.", Type: "A", OldTTL: 30},
+			{Action: manager.ChangeCreate, Name: "www.dev.example.com.", Type: "A", NewTTL: 3600},
+		}},
+	}}
+
+	var got []string
+	for _, v := range p.Evaluate(result, nil) {
+		got = append(got, v.String())
+	}
+	expected := []string{
+		"no-prod-deletions: delete of old.prod.example.com. A is denied",
+		"min-ttl: create of www.prod.example.com. A: TTL 30 is below the minimum of 60 (zones.yml:7:9)",
+		"mx-guard: update of prod.example.com. MX: MX changes need a change ticket",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected violations:\n%s", strings.Join(got, "\n"))
+	}
+
+	// Allowed tokens lift require_allow rules
+	violations := p.Evaluate(result, []string{"mx"})
+	if len(violations) != 2 {
+		t.Errorf("Expected 2 violations with --allow mx, got %v", violations)
+	}
+}
+
+func TestEvaluate_CIDRs(t *testing.T) {
+	p, err := Parse([]byte(`rules:
+  - name: internal-addresses
+    zones: [internal.example.com]
+    allowed_cidrs: [10.0.0.0/8, 192.168.0.0/16, fd00::/8]
+  - name: no-private-addresses
+    zones: [example.com]
+    denied_cidrs: [10.0.0.0/8, 192.168.0.0/16, fd00::/8]
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	loc := config.Location{File: "zones.yml", Line: 12, Column: 9}
+	records := func(contents ...string) []powerdns.Record {
+		var rs []powerdns.Record
+		for _, c := range contents {
+			rs = append(rs, powerdns.Record{Content: c})
+		}
+		return rs
+	}
+	result := &manager.ApplyResult{Zones: []manager.ZoneResult{
+		{Name: "internal.example.com.", Changes: []manager.Change{
+			{Action: manager.ChangeCreate, Name: "db.internal.example.com.", Type: "A",
+				After: records("10.1.2.3", "192.0.2.10"), Location: loc},
+			{Action: manager.ChangeUpdate, Name: "db.internal.example.com.", Type: "AAAA",
+				After: records("fd00::1")},
+			{Action: manager.ChangeDelete, Name: "old.internal.example.com.", Type: "A",
+				Before: records("192.0.2.20")},
+		}},
+		{Name: "example.com.", Changes: []manager.Change{
+			{Action: manager.ChangeCreate, Name: "www.example.com.", Type: "A", After: records("192.0.2.1")},
+			{Action: manager.ChangeUpdate, Name: "vpn.example.com.", Type: "AAAA", After: records("fd00::53")},
+			{Action: manager.ChangeCreate, Name: "example.com.", Type: "TXT", After: records("10.0.0.1")},
+		}},
+	}}
+
+	var got []string
+	for _, v := range p.Evaluate(result, nil) {
+		got = append(got, v.String())
+	}
+	expected := []string{
+		"internal-addresses: create of db.internal.example.com. A: 192.0.2.10 not in the allowed ranges " +
+			"10.0.0.0/8, 192.168.0.0/16, fd00::/8 (zones.yml:12:9)",
+		"no-private-addresses: update of vpn.example.com. AAAA: fd00::53 in the denied ranges " +
+			"10.0.0.0/8, 192.168.0.0/16, fd00::/8",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected violations:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"missing name", "rules:\n  - deny: true\n", "name is required"},
+		{"duplicate name", "rules:\n  - {name: a, deny: true}\n  - {name: a, deny: true}\n", "duplicate name"},
+		{"no effect", "rules:\n  - name: a\n    types: [MX]\n", "one of deny"},
+		{"ttl bounds", "rules:\n  - {name: a, min_ttl: 600, max_ttl: 60}\n", "greater than max_ttl"},
+		{"invalid action", "rules:\n  - {name: a, deny: true, actions: [remove]}\n", `invalid action "remove"`},
+		{"invalid zone", "rules:\n  - {name: a, deny: true, zones: ['[']}\n", "invalid zone pattern"},
+		{"invalid cidr", "rules:\n  - {name: a, allowed_cidrs: [10.0.0.0/33]}\n",
+			`allowed_cidrs: invalid CIDR "10.0.0.0/33"`},
+		{"unknown key", "rules:\n  - {name: a, deny: true, zone: x}\n", "field zone not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.content)); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
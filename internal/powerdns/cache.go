@@ -0,0 +1,63 @@
+package powerdns
+
+import (
+	"slices"
+	"sync"
+)
+
+// cachedZone is a zone response with the ETag to revalidate it with.
+type cachedZone struct {
+	zone *Zone
+	etag string
+}
+
+// zoneCache keeps GetZone responses in memory so that a zone the server
+// reports as not modified is not downloaded again. Entries are dropped
+// whenever the client modifies the zone, and all of them on reset.
+type zoneCache struct {
+	entries map[string]cachedZone
+	mu      sync.Mutex
+}
+
+func newZoneCache() *zoneCache {
+	return &zoneCache{entries: make(map[string]cachedZone)}
+}
+
+func (c *zoneCache) get(zoneID string) (cachedZone, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[zoneID]
+	return entry, ok
+}
+
+func (c *zoneCache) put(zoneID string, zone *Zone, etag string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[zoneID] = cachedZone{zone: zone, etag: etag}
+}
+
+func (c *zoneCache) invalidate(zoneID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, zoneID)
+}
+
+func (c *zoneCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]cachedZone)
+}
+
+// copyZone returns a deep copy of the zone that callers can modify
+// without affecting the cached entry.
+func copyZone(zone *Zone) *Zone {
+	cp := *zone
+	cp.Masters = slices.Clone(zone.Masters)
+	cp.Nameservers = slices.Clone(zone.Nameservers)
+	cp.RRsets = slices.Clone(zone.RRsets)
+	for i := range cp.RRsets {
+		cp.RRsets[i].Records = slices.Clone(cp.RRsets[i].Records)
+		cp.RRsets[i].Comments = slices.Clone(cp.RRsets[i].Comments)
+	}
+	return &cp
+}
@@ -4,11 +4,19 @@ package powerdns
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/kreigan/powerdns-zone-manager/internal/logger"
 )
@@ -17,20 +25,195 @@ import (
 type Client struct {
 	log        *logger.Logger
 	httpClient *http.Client
+	stats      *statsCollector
+	cache      *zoneCache
+	diskCache  *DiskCache
+	headers    map[string]string
 	baseURL    string
 	apiKey     string
+	basicUser  string
+	basicPass  string
+	// maxRateLimitWait is ClientOptions.MaxRateLimitWait
+	maxRateLimitWait time.Duration
+	// recursor is the client of the recursor API, see ClientOptions.RecursorURL
+	recursor *Client
+}
+
+// ClientOptions tunes the HTTP connections of a client.
+// Zero values keep the net/http defaults.
+type ClientOptions struct {
+	// Cache keeps GET responses of zones on disk across invocations.
+	// Only set it for read-only commands, see DiskCache.
+	Cache *DiskCache
+	// Headers are added to every request, e.g. X-Change-Ticket for the logs
+	// of a proxy in front of the API. See ValidateHeader.
+	Headers map[string]string
+	// IdleConnTimeout is how long an idle connection is kept open.
+	IdleConnTimeout time.Duration
+	// MaxIdleConns limits the idle connections kept for reuse. As the client
+	// talks to a single host, it also raises the per-host limit (2 by default).
+	MaxIdleConns int
+	// MaxConnsPerHost limits the connections to the API, including active ones.
+	MaxConnsPerHost int
+	// DisableKeepAlives opens a new connection for every request.
+	DisableKeepAlives bool
+	// DisableHTTP2 only uses HTTP/1.1, e.g. for proxies with broken HTTP/2.
+	DisableHTTP2 bool
+	// BasicAuthUser and BasicAuthPassword are sent as HTTP basic auth,
+	// e.g. for a reverse proxy in front of the API.
+	BasicAuthUser     string
+	BasicAuthPassword string
+	// RecursorURL and RecursorAPIKey are the API of the PowerDNS Recursor
+	// that manages the zones with target recursor, see GetRecursorZone.
+	RecursorURL    string
+	RecursorAPIKey string
+	// MaxRateLimitWait is how long a request waits in total for the rate
+	// limit of an API gateway in front of the API, see rateLimitWait, before
+	// it fails. Zero fails rate limited requests right away.
+	MaxRateLimitWait time.Duration
+}
+
+// unixScheme is the URL scheme of APIs listening on a Unix socket, e.g.
+// unix:///run/pdns/api.sock?path=/api/v1/servers/localhost.
+const unixScheme = "unix"
+
+// defaultServerPath is the API path of Unix socket URLs without a path parameter.
+const defaultServerPath = "/api/v1/servers/localhost"
+
+// reservedHeaders are set by the client and cannot be added with
+// ClientOptions.Headers.
+var reservedHeaders = []string{"X-Api-Key", "Authorization", "Content-Type", "Host", "If-None-Match"}
+
+// ValidateHeader checks that an extra request header has a valid name and
+// value and is not set by the client itself.
+func ValidateHeader(name, value string) error {
+	if name == "" {
+		return errors.New("header name must not be empty")
+	}
+	for _, r := range name {
+		if r <= ' ' || r >= 0x7f || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("header %s: value must not contain line breaks", name)
+	}
+	if slices.Contains(reservedHeaders, http.CanonicalHeaderKey(name)) {
+		return fmt.Errorf("header %s is set by the client and cannot be overridden", name)
+	}
+	return nil
+}
+
+// ParseHeader parses an extra request header in the form "Name: value".
+func ParseHeader(header string) (name, value string, err error) {
+	name, value, ok := strings.Cut(header, ":")
+	if !ok {
+		return "", "", fmt.Errorf("invalid header %q, must be in the form Name: value", header)
+	}
+	name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+	if err := ValidateHeader(name, value); err != nil {
+		return "", "", err
+	}
+	return name, value, nil
+}
+
+// transport returns an HTTP transport with the options applied to the
+// net/http default transport settings.
+func (o *ClientOptions) transport() *http.Transport {
+	t := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		DisableKeepAlives:     o.DisableKeepAlives,
+		MaxConnsPerHost:       o.MaxConnsPerHost,
+	}
+	if o.MaxIdleConns > 0 {
+		t.MaxIdleConns = o.MaxIdleConns
+		t.MaxIdleConnsPerHost = o.MaxIdleConns
+	}
+	if o.IdleConnTimeout > 0 {
+		t.IdleConnTimeout = o.IdleConnTimeout
+	}
+	if o.DisableHTTP2 {
+		t.ForceAttemptHTTP2 = false
+		t.Protocols = new(http.Protocols)
+		t.Protocols.SetHTTP1(true)
+	}
+	return t
 }
 
 // NewClient creates a new PowerDNS client.
 // baseURL should be the full API URL including server path, e.g.:
 // http://localhost:8081/api/v1/servers/localhost
 func NewClient(baseURL, apiKey string, log *logger.Logger) *Client {
-	return &Client{
+	return NewClientWithOptions(baseURL, apiKey, ClientOptions{}, log)
+}
+
+// NewClientWithOptions creates a new PowerDNS client with tuned HTTP connections.
+// baseURL may also be a unix:// URL with the socket path, and the API path in
+// the "path" query parameter (default: /api/v1/servers/localhost).
+func NewClientWithOptions(baseURL, apiKey string, opts ClientOptions, log *logger.Logger) *Client {
+	transport := opts.transport()
+	if u, err := url.Parse(baseURL); err == nil && u.Scheme == unixScheme {
+		socket := u.Path
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+		path := u.Query().Get("path")
+		if path == "" {
+			path = defaultServerPath
+		}
+		// The host is ignored when dialing the socket
+		baseURL = "http://localhost" + path
+	}
+
+	c := &Client{
 		baseURL:    strings.TrimSuffix(baseURL, "/"),
 		apiKey:     apiKey,
+		basicUser:  opts.BasicAuthUser,
+		basicPass:  opts.BasicAuthPassword,
 		log:        log,
-		httpClient: &http.Client{},
+		httpClient: &http.Client{Transport: transport},
+		stats:      newStatsCollector(),
+		cache:      newZoneCache(),
+		diskCache:  opts.Cache,
+		headers:    opts.Headers,
+
+		maxRateLimitWait: opts.MaxRateLimitWait,
+	}
+	if opts.RecursorURL != "" {
+		recursorOpts := opts
+		recursorOpts.RecursorURL, recursorOpts.Cache = "", nil
+		c.recursor = NewClientWithOptions(opts.RecursorURL, opts.RecursorAPIKey, recursorOpts, log)
+	}
+	return c
+}
+
+// ResetCache drops the cached zones, e.g. between the requests of a server,
+// so that the cache does not outlive the run that filled it.
+func (c *Client) ResetCache() {
+	c.cache.reset()
+}
+
+// Stats returns timing statistics of the requests made so far, per HTTP method.
+func (c *Client) Stats() []RequestStats {
+	if c.recursor != nil {
+		return mergeStats(c.stats.snapshot(), c.recursor.Stats())
 	}
+	return c.stats.snapshot()
+}
+
+// canonicalZoneID ensures the zone ID ends with a dot (PowerDNS requires canonical names).
+func canonicalZoneID(zoneID string) string {
+	if !strings.HasSuffix(zoneID, ".") {
+		return zoneID + "."
+	}
+	return zoneID
 }
 
 // doRequest performs an HTTP request to the PowerDNS API.
@@ -39,65 +222,162 @@ func (c *Client) doRequest(
 	method, path string,
 	body interface{},
 ) (*http.Response, error) {
-	var reqBody io.Reader
+	return c.doRequestWithHeaders(ctx, method, path, body, nil)
+}
+
+// doRequestWithHeaders performs an HTTP request with additional request headers.
+func (c *Client) doRequestWithHeaders(
+	ctx context.Context,
+	method, path string,
+	body interface{},
+	headers map[string]string,
+) (*http.Response, error) {
+	var data []byte
 	if body != nil {
-		data, err := json.Marshal(body)
-		if err != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
 			return nil, fmt.Errorf("failed to marshal request body: %w", err)
 		}
 		c.log.Debug("Request body: %s", string(data))
-		reqBody = bytes.NewReader(data)
 	}
 
 	url := c.baseURL + path
-	c.log.HTTPRequest(method, url)
+	requestID := ""
+	var waited time.Duration
+	for attempt := 0; ; attempt++ {
+		var reqBody io.Reader
+		if body != nil {
+			reqBody = bytes.NewReader(data)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
 
-	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
+		for name, value := range c.headers {
+			req.Header.Set(name, value)
+		}
+		req.Header.Set("X-API-Key", c.apiKey)
+		if c.basicUser != "" {
+			req.SetBasicAuth(c.basicUser, c.basicPass)
+		}
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		// A configured X-Request-ID header is sent as is, e.g. a CI job ID;
+		// retries keep the ID of the first attempt
+		if requestID != "" {
+			req.Header.Set(requestIDHeader, requestID)
+		} else if req.Header.Get(requestIDHeader) == "" {
+			req.Header.Set(requestIDHeader, newRequestID())
+		}
+		requestID = req.Header.Get(requestIDHeader)
+		c.log.HTTPRequest(method, url, requestID)
 
-	req.Header.Set("X-API-Key", c.apiKey)
-	if body != nil {
-		req.Header.Set("Content-Type", "application/json")
+		start := time.Now()
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			c.stats.record(method, time.Since(start), true)
+			c.log.Error("HTTP request failed: %s %s [%s]: %v", method, url, requestID, err)
+			return nil, fmt.Errorf("request failed (request ID %s): %w", requestID, err)
+		}
+		c.stats.record(method, time.Since(start), resp.StatusCode >= http.StatusBadRequest)
+		c.log.HTTPResponse(method, url, requestID, resp.StatusCode)
+
+		wait, limited := rateLimitWait(resp, attempt, time.Now())
+		if !limited || c.maxRateLimitWait <= 0 || waited+wait > c.maxRateLimitWait {
+			// Rate limited requests that waited too long fail with the
+			// status error of the gateway
+			return resp, nil
+		}
+		_, _ = io.Copy(io.Discard, resp.Body) //nolint:errcheck // drained for connection reuse
+		_ = resp.Body.Close()                 //nolint:errcheck // best effort close
+		c.log.Warn("API rate limit reached (status %d): waiting %s before retrying %s %s [%s]",
+			resp.StatusCode, wait.Round(time.Millisecond), method, url, requestID)
+		if err := sleep(ctx, wait); err != nil {
+			return nil, fmt.Errorf("request failed (request ID %s): %w", requestID, err)
+		}
+		waited += wait
 	}
+}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		c.log.Error("HTTP request failed: %s %s: %v", method, url, err)
-		return nil, fmt.Errorf("request failed: %w", err)
+// requestIDHeader identifies a request in the logs of the client and of the
+// PowerDNS webserver or a proxy in front of it.
+const requestIDHeader = "X-Request-ID"
+
+// newRequestID returns a random request ID.
+func newRequestID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		// crypto/rand does not fail on supported platforms
+		panic(fmt.Sprintf("failed to generate request ID: %v", err))
 	}
+	return hex.EncodeToString(id)
+}
 
-	c.log.HTTPResponse(method, url, resp.StatusCode)
-	return resp, nil
+// StatusError is returned when the API responds with an unexpected status code.
+type StatusError struct {
+	Message string
+	// RequestID is the X-Request-ID of the failed request
+	RequestID  string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	if e.RequestID == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s (request ID %s)", e.Message, e.RequestID)
 }
 
 // handleError processes API error responses and logs them.
 func (c *Client) handleError(method, path string, resp *http.Response) error {
+	requestID := ""
+	if resp.Request != nil {
+		requestID = resp.Request.Header.Get(requestIDHeader)
+	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		c.log.Error("API error: %s %s -> %d (failed to read body: %v)", method, path, resp.StatusCode, err)
-		return fmt.Errorf("API request failed with status %d", resp.StatusCode)
+		c.log.Error("API error: %s %s -> %d [%s] (failed to read body: %v)",
+			method, path, resp.StatusCode, requestID, err)
+		return &StatusError{
+			StatusCode: resp.StatusCode,
+			RequestID:  requestID,
+			Message:    fmt.Sprintf("API request failed with status %d", resp.StatusCode),
+		}
 	}
 
 	var apiErr APIError
 	if err := json.Unmarshal(body, &apiErr); err == nil && apiErr.Error != "" {
-		c.log.Error("API error: %s %s -> %d: %s", method, path, resp.StatusCode, apiErr.Error)
-		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, apiErr.Error)
+		c.log.Error("API error: %s %s -> %d [%s]: %s", method, path, resp.StatusCode, requestID, apiErr.Error)
+		return &StatusError{
+			StatusCode: resp.StatusCode,
+			RequestID:  requestID,
+			Message:    fmt.Sprintf("API error (status %d): %s", resp.StatusCode, apiErr.Error),
+		}
 	}
 
 	errMsg := string(body)
 	if len(errMsg) > 200 {
 		errMsg = errMsg[:200] + "..."
 	}
-	c.log.Error("API error: %s %s -> %d: %s", method, path, resp.StatusCode, errMsg)
-	return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	c.log.Error("API error: %s %s -> %d [%s]: %s", method, path, resp.StatusCode, requestID, errMsg)
+	return &StatusError{
+		StatusCode: resp.StatusCode,
+		RequestID:  requestID,
+		Message:    fmt.Sprintf("API request failed with status %d: %s", resp.StatusCode, string(body)),
+	}
 }
 
 // CreateZone creates a new DNS zone.
 // POST /zones
 // See: https://doc.powerdns.com/authoritative/http-api/zone.html
 func (c *Client) CreateZone(ctx context.Context, zone *Zone) (*Zone, error) {
+	c.cache.invalidate(canonicalZoneID(zone.Name))
+
 	path := "/zones"
 	resp, err := c.doRequest(ctx, "POST", path, zone)
 	if err != nil {
@@ -111,29 +391,126 @@ func (c *Client) CreateZone(ctx context.Context, zone *Zone) (*Zone, error) {
 		return nil, c.handleError("POST", path, resp)
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	return decodeZone(resp.Body, nil)
+}
+
+// GetZone retrieves zone information.
+// GET /zones/{zone_id}
+// Responses with an ETag are cached until ResetCache or a change by the client,
+// and only reused after the server answered a revalidation with If-None-Match
+// with 304 Not Modified. PowerDNS itself sends no ETags, so every call is a
+// request. With a disk cache, responses are taken from the disk cache instead.
+// See: https://doc.powerdns.com/authoritative/http-api/zone.html
+func (c *Client) GetZone(ctx context.Context, zoneID string) (*Zone, error) {
+	return c.getZone(ctx, zoneID, nil)
+}
+
+// GetZoneFiltered retrieves a zone like GetZone, keeping only the RRsets that
+// keep selects. RRsets are decoded one at a time while the response is read,
+// so the dropped RRsets of huge zones are never held in memory. Filtered zones
+// are not cached, but a cached zone that is not modified is filtered instead
+// of downloaded again.
+func (c *Client) GetZoneFiltered(ctx context.Context, zoneID string, keep RRsetFilter) (*Zone, error) {
+	return c.getZone(ctx, zoneID, keep)
+}
+
+// getZone retrieves a zone, keeping the RRsets selected by keep (all if nil).
+func (c *Client) getZone(ctx context.Context, zoneID string, keep RRsetFilter) (*Zone, error) {
+	zoneID = canonicalZoneID(zoneID)
+	path := fmt.Sprintf("/zones/%s", zoneID)
+	if c.diskCache != nil {
+		return c.getCachedZone(ctx, path, keep)
+	}
+
+	var headers map[string]string
+	cached, isCached := c.cache.get(zoneID)
+	if isCached {
+		headers = map[string]string{"If-None-Match": cached.etag}
+	}
+
+	resp, err := c.doRequestWithHeaders(ctx, "GET", path, nil, headers)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close() //nolint:errcheck // best effort close
+	}()
+
+	if resp.StatusCode == http.StatusNotModified && isCached {
+		c.log.Debug("Zone %s not modified, using cached copy", zoneID)
+		return filterZone(copyZone(cached.zone), keep), nil
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		c.stats.expected("GET")
+		return nil, nil // Zone not found is not an error
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.handleError("GET", path, resp)
+	}
+
+	zone, err := decodeZone(resp.Body, keep)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without an ETag the zone could not be revalidated, so it is not cached
+	if etag := resp.Header.Get("ETag"); keep == nil && etag != "" {
+		c.cache.put(zoneID, copyZone(zone), etag)
+	}
+	return zone, nil
+}
+
+// ListZones returns all zones of the server, without their RRsets.
+// GET /zones
+// See: https://doc.powerdns.com/authoritative/http-api/zone.html
+func (c *Client) ListZones(ctx context.Context) ([]Zone, error) {
+	path := "/zones"
+	var body []byte
+	if c.diskCache != nil {
+		var err error
+		if body, _, err = c.getCached(ctx, path); err != nil {
+			return nil, err
+		}
+	} else {
+		resp, err := c.doRequest(ctx, "GET", path, nil)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = resp.Body.Close() //nolint:errcheck // best effort close
+		}()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, c.handleError("GET", path, resp)
+		}
+
+		if body, err = io.ReadAll(resp.Body); err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
 	}
 
-	var created Zone
-	if err := json.Unmarshal(body, &created); err != nil {
+	var zones []Zone
+	if err := json.Unmarshal(body, &zones); err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	return &created, nil
+	return zones, nil
 }
 
-// GetZone retrieves zone information.
-// GET /zones/{zone_id}
+// GetZoneInfo retrieves zone information without its RRsets.
+// GET /zones/{zone_id}?rrsets=false
+// This is much cheaper than GetZone for large zones and is sufficient
+// for existence and ownership checks.
 // See: https://doc.powerdns.com/authoritative/http-api/zone.html
-func (c *Client) GetZone(ctx context.Context, zoneID string) (*Zone, error) {
-	// Ensure zone ID ends with a dot (PowerDNS requires canonical names)
-	if !strings.HasSuffix(zoneID, ".") {
-		zoneID += "."
+func (c *Client) GetZoneInfo(ctx context.Context, zoneID string) (*Zone, error) {
+	zoneID = canonicalZoneID(zoneID)
+	path := fmt.Sprintf("/zones/%s?rrsets=false", zoneID)
+	if c.diskCache != nil {
+		return c.getCachedZone(ctx, path, nil)
 	}
 
-	path := fmt.Sprintf("/zones/%s", zoneID)
 	resp, err := c.doRequest(ctx, "GET", path, nil)
 	if err != nil {
 		return nil, err
@@ -143,6 +520,7 @@ func (c *Client) GetZone(ctx context.Context, zoneID string) (*Zone, error) {
 	}()
 
 	if resp.StatusCode == http.StatusNotFound {
+		c.stats.expected("GET")
 		return nil, nil // Zone not found is not an error
 	}
 
@@ -150,17 +528,53 @@ func (c *Client) GetZone(ctx context.Context, zoneID string) (*Zone, error) {
 		return nil, c.handleError("GET", path, resp)
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	return decodeZone(resp.Body, nil)
+}
+
+// getCached returns the response body of a GET request from the disk cache,
+// or performs the request and caches a successful response. found is false if
+// the API responded with 404 Not Found, which is not cached.
+func (c *Client) getCached(ctx context.Context, path string) (body []byte, found bool, err error) {
+	key := c.diskCache.key(c.baseURL+path, c.apiKey)
+	if body, ok := c.diskCache.get(key); ok {
+		c.log.Debug("Using disk cache for GET %s", path)
+		return body, true, nil
+	}
+
+	resp, err := c.doRequest(ctx, "GET", path, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, false, err
+	}
+	defer func() {
+		_ = resp.Body.Close() //nolint:errcheck // best effort close
+	}()
+
+	if resp.StatusCode == http.StatusNotFound {
+		c.stats.expected("GET")
+		return nil, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, c.handleError("GET", path, resp)
+	}
+	if body, err = io.ReadAll(resp.Body); err != nil {
+		return nil, false, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	var zone Zone
-	if err := json.Unmarshal(body, &zone); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+	// A failing cache only slows down the next run
+	if err := c.diskCache.put(key, body); err != nil {
+		c.log.Debug("Failed to cache GET %s: %v", path, err)
 	}
+	return body, true, nil
+}
 
-	return &zone, nil
+// getCachedZone returns a zone through the disk cache with the RRsets selected
+// by keep, or nil if it does not exist.
+func (c *Client) getCachedZone(ctx context.Context, path string, keep RRsetFilter) (*Zone, error) {
+	body, found, err := c.getCached(ctx, path)
+	if err != nil || !found {
+		return nil, err
+	}
+	return decodeZone(bytes.NewReader(body), keep)
 }
 
 // PatchZone modifies RRsets in a zone.
@@ -168,9 +582,9 @@ func (c *Client) GetZone(ctx context.Context, zoneID string) (*Zone, error) {
 // Creates/modifies/deletes RRsets present in the payload and their comments.
 // See: https://doc.powerdns.com/authoritative/http-api/zone.html
 func (c *Client) PatchZone(ctx context.Context, zoneID string, patch *ZonePatch) error {
-	if !strings.HasSuffix(zoneID, ".") {
-		zoneID += "."
-	}
+	zoneID = canonicalZoneID(zoneID)
+
+	c.cache.invalidate(zoneID)
 
 	path := fmt.Sprintf("/zones/%s", zoneID)
 	resp, err := c.doRequest(ctx, "PATCH", path, patch)
@@ -187,3 +601,381 @@ func (c *Client) PatchZone(ctx context.Context, zoneID string, patch *ZonePatch)
 
 	return nil
 }
+
+// AxfrRetrieve asks the server to retrieve a Slave zone from its masters.
+// PUT /zones/{zone_id}/axfr-retrieve
+// Returns the result message reported by the server.
+// See: https://doc.powerdns.com/authoritative/http-api/zone.html
+func (c *Client) AxfrRetrieve(ctx context.Context, zoneID string) (string, error) {
+	zoneID = canonicalZoneID(zoneID)
+
+	c.cache.invalidate(zoneID)
+
+	path := fmt.Sprintf("/zones/%s/axfr-retrieve", zoneID)
+	resp, err := c.doRequest(ctx, "PUT", path, nil)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = resp.Body.Close() //nolint:errcheck // best effort close
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", c.handleError("PUT", path, resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var result OperationResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return result.Result, nil
+}
+
+// RectifyZone rectifies a DNSSEC zone, updating its ordering and auth data.
+// PUT /zones/{zone_id}/rectify
+// Returns the result message reported by the server.
+// See: https://doc.powerdns.com/authoritative/http-api/zone.html
+func (c *Client) RectifyZone(ctx context.Context, zoneID string) (string, error) {
+	zoneID = canonicalZoneID(zoneID)
+
+	path := fmt.Sprintf("/zones/%s/rectify", zoneID)
+	resp, err := c.doRequest(ctx, "PUT", path, nil)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = resp.Body.Close() //nolint:errcheck // best effort close
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", c.handleError("PUT", path, resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var result OperationResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return result.Result, nil
+}
+
+// GetServer returns the server the API belongs to, including its version.
+// GET /servers/{server_id}
+// See: https://doc.powerdns.com/authoritative/http-api/server.html
+func (c *Client) GetServer(ctx context.Context) (*Server, error) {
+	resp, err := c.doRequest(ctx, "GET", "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close() //nolint:errcheck // best effort close
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.handleError("GET", "", resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var server Server
+	if err := json.Unmarshal(body, &server); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &server, nil
+}
+
+// GetConfig returns the configuration settings of the server.
+// GET /servers/{server_id}/config
+// See: https://doc.powerdns.com/authoritative/http-api/server.html
+func (c *Client) GetConfig(ctx context.Context) ([]ConfigSetting, error) {
+	resp, err := c.doRequest(ctx, "GET", "/config", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close() //nolint:errcheck // best effort close
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.handleError("GET", "/config", resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var settings []ConfigSetting
+	if err := json.Unmarshal(body, &settings); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return settings, nil
+}
+
+// SupportsSetPTR reports whether a PowerDNS version supports set-ptr on
+// records, which was removed in 4.5. Unknown versions are assumed not to.
+func SupportsSetPTR(version string) bool {
+	v, ok := parseVersion(version)
+	return ok && v.before(serverVersion{4, 5})
+}
+
+// recordTypeVersions are the PowerDNS versions that added record types; the
+// other types are supported by all versions with API version 1.
+var recordTypeVersions = map[string]serverVersion{
+	"LUA":   {4, 2},
+	"APL":   {4, 4},
+	"SVCB":  {4, 4},
+	"HTTPS": {4, 4},
+}
+
+// SupportsRecordType reports whether a PowerDNS version supports a record
+// type, and returns the version that added the type if it does not. The
+// API does not list the supported types, so they are looked up by version;
+// unknown versions, e.g. of development builds, are assumed to support all.
+func SupportsRecordType(version, rtype string) (since string, ok bool) {
+	added, known := recordTypeVersions[strings.ToUpper(rtype)]
+	v, parsed := parseVersion(version)
+	if !known || !parsed || !v.before(added) {
+		return "", true
+	}
+	return fmt.Sprintf("%d.%d", added.major, added.minor), false
+}
+
+// serverVersion is the major and minor version of a PowerDNS server.
+type serverVersion struct {
+	major, minor int
+}
+
+// before reports whether v is older than other.
+func (v serverVersion) before(other serverVersion) bool {
+	return v.major < other.major || (v.major == other.major && v.minor < other.minor)
+}
+
+// parseVersion parses the major and minor version of a version string like
+// 4.8.3 or 4.9.0-beta1.
+func parseVersion(version string) (serverVersion, bool) {
+	major, rest, _ := strings.Cut(version, ".")
+	minor, _, _ := strings.Cut(rest, ".")
+	maj, err := strconv.Atoi(major)
+	if err != nil {
+		return serverVersion{}, false
+	}
+	minorDigits := strings.TrimRightFunc(minor, func(r rune) bool { return r < '0' || r > '9' })
+	minorVersion, err := strconv.Atoi(minorDigits)
+	if err != nil {
+		return serverVersion{}, false
+	}
+	return serverVersion{maj, minorVersion}, true
+}
+
+// QueryRing is the ring statistic of the most frequent queries.
+const QueryRing = "queries"
+
+// GetQueryCounts returns the number of recent queries by name and type, keyed
+// by QueryKey, as tracked by the query ring buffer of the server. The ring only
+// holds the most frequent queries, names missing from it may still be queried.
+// GET /statistics?statistic=queries&includerings=true
+// See: https://doc.powerdns.com/authoritative/http-api/statistics.html
+func (c *Client) GetQueryCounts(ctx context.Context) (map[string]int64, error) {
+	path := "/statistics?statistic=" + QueryRing + "&includerings=true"
+	resp, err := c.doRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close() //nolint:errcheck // best effort close
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.handleError("GET", path, resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var stats []RingStatistic
+	if err := json.Unmarshal(body, &stats); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	counts := make(map[string]int64)
+	for _, stat := range stats {
+		if stat.Name != QueryRing {
+			continue
+		}
+		for _, entry := range stat.Value {
+			// Split at the last slash, classless reverse names contain slashes
+			i := strings.LastIndex(entry.Name, "/")
+			count, err := strconv.ParseInt(entry.Value, 10, 64)
+			if i < 0 || err != nil {
+				continue
+			}
+			counts[QueryKey(entry.Name[:i], entry.Name[i+1:])] += count
+		}
+	}
+	return counts, nil
+}
+
+// QueryKey returns the key of a query name and type in the counts returned by
+// GetQueryCounts, e.g. "www.example.com./A".
+func QueryKey(name, rtype string) string {
+	return strings.ToLower(canonicalZoneID(name)) + "/" + strings.ToUpper(rtype)
+}
+
+// ListAutoprimaries returns the configured autoprimaries.
+// GET /autoprimaries
+// See: https://doc.powerdns.com/authoritative/http-api/autoprimaries.html
+func (c *Client) ListAutoprimaries(ctx context.Context) ([]Autoprimary, error) {
+	path := "/autoprimaries"
+	resp, err := c.doRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close() //nolint:errcheck // best effort close
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.handleError("GET", path, resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var autoprimaries []Autoprimary
+	if err := json.Unmarshal(body, &autoprimaries); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return autoprimaries, nil
+}
+
+// AddAutoprimary adds an autoprimary.
+// POST /autoprimaries
+// See: https://doc.powerdns.com/authoritative/http-api/autoprimaries.html
+func (c *Client) AddAutoprimary(ctx context.Context, autoprimary *Autoprimary) error {
+	path := "/autoprimaries"
+	resp, err := c.doRequest(ctx, "POST", path, autoprimary)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close() //nolint:errcheck // best effort close
+	}()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent {
+		return c.handleError("POST", path, resp)
+	}
+
+	return nil
+}
+
+// DeleteAutoprimary deletes the autoprimary with the given IP and nameserver.
+// DELETE /autoprimaries/{ip}/{nameserver}
+// See: https://doc.powerdns.com/authoritative/http-api/autoprimaries.html
+func (c *Client) DeleteAutoprimary(ctx context.Context, ip, nameserver string) error {
+	path := fmt.Sprintf("/autoprimaries/%s/%s", url.PathEscape(ip), url.PathEscape(nameserver))
+	resp, err := c.doRequest(ctx, "DELETE", path, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close() //nolint:errcheck // best effort close
+	}()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return c.handleError("DELETE", path, resp)
+	}
+
+	return nil
+}
+
+// GetMetadata returns all metadata of a zone.
+// GET /zones/{zone_id}/metadata
+// See: https://doc.powerdns.com/authoritative/http-api/metadata.html
+func (c *Client) GetMetadata(ctx context.Context, zoneID string) ([]Metadata, error) {
+	path := fmt.Sprintf("/zones/%s/metadata", canonicalZoneID(zoneID))
+	resp, err := c.doRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close() //nolint:errcheck // best effort close
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.handleError("GET", path, resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var metadata []Metadata
+	if err := json.Unmarshal(body, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return metadata, nil
+}
+
+// SetMetadata replaces the values of a zone metadata kind.
+// PUT /zones/{zone_id}/metadata/{metadata_kind}
+// See: https://doc.powerdns.com/authoritative/http-api/metadata.html
+func (c *Client) SetMetadata(ctx context.Context, zoneID, kind string, values []string) error {
+	path := fmt.Sprintf("/zones/%s/metadata/%s", canonicalZoneID(zoneID), url.PathEscape(kind))
+	resp, err := c.doRequest(ctx, "PUT", path, &Metadata{Kind: kind, Metadata: values})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close() //nolint:errcheck // best effort close
+	}()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return c.handleError("PUT", path, resp)
+	}
+
+	return nil
+}
+
+// DeleteMetadata deletes a zone metadata kind.
+// DELETE /zones/{zone_id}/metadata/{metadata_kind}
+// See: https://doc.powerdns.com/authoritative/http-api/metadata.html
+func (c *Client) DeleteMetadata(ctx context.Context, zoneID, kind string) error {
+	path := fmt.Sprintf("/zones/%s/metadata/%s", canonicalZoneID(zoneID), url.PathEscape(kind))
+	resp, err := c.doRequest(ctx, "DELETE", path, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close() //nolint:errcheck // best effort close
+	}()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return c.handleError("DELETE", path, resp)
+	}
+
+	return nil
+}
@@ -0,0 +1,667 @@
+package powerdns
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/logger"
+)
+
+// testLogger returns a quiet logger for tests
+func testLogger() *logger.Logger {
+	return logger.New(logger.Options{Verbose: false, NoColor: true})
+}
+
+// newTestServer starts a server that serves a single zone and counts GET requests.
+func newTestServer(t *testing.T, etag string, gets *int) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			*gets++
+			if etag != "" && r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			if etag != "" {
+				w.Header().Set("ETag", etag)
+			}
+			_, _ = w.Write([]byte(`{"name":"example.com.","account":"zone-manager"}`))
+		case http.MethodPatch:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClientOptions_Transport(t *testing.T) {
+	defaults := (&ClientOptions{}).transport()
+	if defaults.MaxIdleConns != 100 || defaults.IdleConnTimeout != 90*time.Second || !defaults.ForceAttemptHTTP2 {
+		t.Errorf("Expected net/http defaults, got %+v", defaults)
+	}
+
+	tuned := (&ClientOptions{
+		IdleConnTimeout:   time.Minute,
+		MaxIdleConns:      32,
+		MaxConnsPerHost:   64,
+		DisableKeepAlives: true,
+		DisableHTTP2:      true,
+	}).transport()
+	if tuned.MaxIdleConns != 32 || tuned.MaxIdleConnsPerHost != 32 || tuned.MaxConnsPerHost != 64 {
+		t.Errorf("Expected connection limits to be applied, got %+v", tuned)
+	}
+	if tuned.IdleConnTimeout != time.Minute || !tuned.DisableKeepAlives {
+		t.Errorf("Expected idle timeout and keep-alive settings to be applied, got %+v", tuned)
+	}
+	if tuned.ForceAttemptHTTP2 || tuned.Protocols.HTTP2() || !tuned.Protocols.HTTP1() {
+		t.Errorf("Expected HTTP/1.1 only, got protocols %v", tuned.Protocols)
+	}
+
+	gets := 0
+	srv := newTestServer(t, "", &gets)
+	client := NewClientWithOptions(srv.URL, "key", ClientOptions{DisableKeepAlives: true, DisableHTTP2: true},
+		testLogger())
+	if _, err := client.GetZone(context.Background(), "example.com"); err != nil {
+		t.Fatalf("GetZone with tuned transport failed: %v", err)
+	}
+}
+
+func TestClient_BasicAuthAndUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "api.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("Unix sockets not available: %v", err)
+	}
+	var gotPath, gotUser, gotPass string
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotUser, gotPass, _ = r.BasicAuth()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name":"example.com."}`)) //nolint:errcheck // test server
+	}))
+	srv.Listener = listener
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	opts := ClientOptions{BasicAuthUser: "proxy", BasicAuthPassword: "secret"}
+	tests := []struct {
+		name     string
+		url      string
+		wantPath string
+	}{
+		{"default server path", "unix://" + socket, "/api/v1/servers/localhost/zones/example.com."},
+		{"server path", "unix://" + socket + "?path=/api/v1/servers/other", "/api/v1/servers/other/zones/example.com."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClientWithOptions(tt.url, "key", opts, testLogger())
+			if _, err := client.GetZoneInfo(context.Background(), "example.com"); err != nil {
+				t.Fatalf("GetZoneInfo failed: %v", err)
+			}
+			if gotPath != tt.wantPath {
+				t.Errorf("Expected path %s, got %s", tt.wantPath, gotPath)
+			}
+			if gotUser != "proxy" || gotPass != "secret" {
+				t.Errorf("Expected basic auth proxy:secret, got %s:%s", gotUser, gotPass)
+			}
+		})
+	}
+}
+
+func TestClient_Headers(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		_, _ = w.Write([]byte(`{"name":"example.com."}`)) //nolint:errcheck // test server
+	}))
+	t.Cleanup(srv.Close)
+
+	opts := ClientOptions{Headers: map[string]string{
+		"X-Change-Ticket": "CHG-42", "X-Requested-By": "alice", "X-Request-ID": "job-7",
+	}}
+	// A configured X-Request-ID is sent instead of a generated one
+	if _, err := NewClientWithOptions(srv.URL, "key", opts, testLogger()).GetZone(context.Background(),
+		"example.com"); err != nil {
+		t.Fatalf("GetZone failed: %v", err)
+	}
+	if got.Get("X-Change-Ticket") != "CHG-42" || got.Get("X-Requested-By") != "alice" ||
+		got.Get("X-API-Key") != "key" || got.Get("X-Request-ID") != "job-7" {
+		t.Errorf("Expected extra headers and API key, got %v", got)
+	}
+
+	tests := []struct {
+		header  string
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{header: "X-Request-ID: run-1", name: "X-Request-ID", value: "run-1"},
+		{header: "X-Empty:", name: "X-Empty"},
+		{header: "X-Change-Ticket", wantErr: true},
+		{header: "X Ticket: 1", wantErr: true},
+		{header: "X-API-Key: secret", wantErr: true},
+		{header: "authorization: Bearer x", wantErr: true},
+	}
+	for _, tt := range tests {
+		name, value, err := ParseHeader(tt.header)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseHeader(%q) error = %v, wantErr %v", tt.header, err, tt.wantErr)
+			continue
+		}
+		if name != tt.name || value != tt.value {
+			t.Errorf("ParseHeader(%q) = %q, %q, want %q, %q", tt.header, name, value, tt.name, tt.value)
+		}
+	}
+}
+
+func TestClient_StatusError(t *testing.T) {
+	var gotID string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotID = r.Header.Get("X-Request-ID")
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":"Unauthorized"}`)) //nolint:errcheck // test server
+	}))
+	t.Cleanup(srv.Close)
+
+	_, err := NewClient(srv.URL, "wrong", testLogger()).GetServer(context.Background())
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected StatusError with status 401, got %v", err)
+	}
+	if statusErr.Message != "API error (status 401): Unauthorized" {
+		t.Errorf("Unexpected error message: %v", statusErr.Message)
+	}
+	if statusErr.RequestID != gotID || len(gotID) != 16 || !strings.HasSuffix(err.Error(), "(request ID "+gotID+")") {
+		t.Errorf("Expected request ID %q of the request in the error, got %v", gotID, err)
+	}
+}
+
+func TestClient_GetConfig(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/config" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`[{"name":"allow-axfr-ips","type":"ConfigSetting","value":"127.0.0.0/8,::1"},
+			{"name":"api","type":"ConfigSetting","value":"yes"}]`))
+	}))
+	t.Cleanup(srv.Close)
+
+	settings, err := NewClient(srv.URL, "key", testLogger()).GetConfig(context.Background())
+	if err != nil {
+		t.Fatalf("GetConfig failed: %v", err)
+	}
+	if len(settings) != 2 || settings[0].Name != "allow-axfr-ips" || settings[0].Value != "127.0.0.0/8,::1" {
+		t.Errorf("Unexpected settings: %+v", settings)
+	}
+}
+
+func TestSupportsSetPTR(t *testing.T) {
+	tests := map[string]bool{
+		"4.4.1":       true,
+		"4.3.0-rc1":   true,
+		"3.4.11":      true,
+		"4.5.0":       false,
+		"4.10.0-beta": false,
+		"5.0.0":       false,
+		"git-master":  false,
+	}
+	for version, want := range tests {
+		if got := SupportsSetPTR(version); got != want {
+			t.Errorf("SupportsSetPTR(%q) = %v, want %v", version, got, want)
+		}
+	}
+}
+
+func TestSupportsRecordType(t *testing.T) {
+	tests := []struct {
+		version string
+		rtype   string
+		since   string
+	}{
+		{"4.3.1", "A", ""},
+		{"4.3.1", "svcb", "4.4"},
+		{"4.3.1", "HTTPS", "4.4"},
+		{"4.4.0", "HTTPS", ""},
+		{"4.1.14", "LUA", "4.2"},
+		{"3.4.11", "APL", "4.4"},
+		{"4.10.0-beta", "SVCB", ""},
+		{"git-master", "SVCB", ""},
+	}
+	for _, tt := range tests {
+		since, ok := SupportsRecordType(tt.version, tt.rtype)
+		if since != tt.since || ok != (tt.since == "") {
+			t.Errorf("SupportsRecordType(%q, %q) = %q, %v, want %q", tt.version, tt.rtype, since, ok, tt.since)
+		}
+	}
+}
+
+func TestClient_GetZone_Cached(t *testing.T) {
+	// Without ETags, zones are fetched every time, so changes made by
+	// others are seen
+	gets := 0
+	srv := newTestServer(t, "", &gets)
+	client := NewClient(srv.URL, "key", testLogger())
+	ctx := context.Background()
+
+	for range 2 {
+		zone, err := client.GetZone(ctx, "example.com")
+		if err != nil {
+			t.Fatalf("GetZone failed: %v", err)
+		}
+		if zone.Account != "zone-manager" {
+			t.Errorf("Expected account zone-manager, got %q", zone.Account)
+		}
+		if _, err := client.GetZoneInfo(ctx, "example.com"); err != nil {
+			t.Fatalf("GetZoneInfo failed: %v", err)
+		}
+	}
+	if gets != 4 {
+		t.Errorf("Expected 4 GET requests, got %d", gets)
+	}
+}
+
+func TestClient_GetZone_CacheInvalidation(t *testing.T) {
+	var revalidations int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPatch {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if r.Header.Get("If-None-Match") != "" {
+			revalidations++
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(`{"name":"example.com.","account":"zone-manager"}`))
+	}))
+	t.Cleanup(srv.Close)
+	client := NewClient(srv.URL, "key", testLogger())
+	ctx := context.Background()
+
+	get := func() {
+		t.Helper()
+		if _, err := client.GetZone(ctx, "example.com."); err != nil {
+			t.Fatalf("GetZone failed: %v", err)
+		}
+	}
+	get()
+	get()
+	if revalidations != 1 {
+		t.Fatalf("Expected the cached zone to be revalidated, got %d revalidations", revalidations)
+	}
+
+	// Modifying the zone or resetting the cache drops the cached zone
+	if err := client.PatchZone(ctx, "example.com", &ZonePatch{}); err != nil {
+		t.Fatalf("PatchZone failed: %v", err)
+	}
+	get()
+	client.ResetCache()
+	get()
+	if revalidations != 1 {
+		t.Errorf("Expected the zone to be fetched again without revalidation, got %d revalidations", revalidations)
+	}
+}
+
+func TestCopyZone(t *testing.T) {
+	zone := &Zone{Name: "example.com.", RRsets: []RRset{{
+		Name:     "www.example.com.",
+		Type:     "A",
+		Records:  []Record{{Content: "192.0.2.1"}},
+		Comments: []Comment{{Content: "web", Account: "team-a"}},
+	}}}
+	cp := copyZone(zone)
+	cp.RRsets[0].Records[0].Content = "192.0.2.2"
+	cp.RRsets[0].Comments[0].Content = "changed"
+	if zone.RRsets[0].Records[0].Content != "192.0.2.1" || zone.RRsets[0].Comments[0].Content != "web" {
+		t.Errorf("Expected the copy not to share records and comments, got %+v", zone.RRsets[0])
+	}
+}
+
+func TestClient_GetZone_ETagRevalidation(t *testing.T) {
+	gets := 0
+	srv := newTestServer(t, `"v1"`, &gets)
+	client := NewClient(srv.URL, "key", testLogger())
+	ctx := context.Background()
+
+	for range 2 {
+		zone, err := client.GetZone(ctx, "example.com.")
+		if err != nil {
+			t.Fatalf("GetZone failed: %v", err)
+		}
+		if zone == nil || zone.Name != "example.com." {
+			t.Fatalf("Expected cached zone on 304, got %+v", zone)
+		}
+	}
+	if gets != 2 {
+		t.Errorf("Expected conditional revalidation request, got %d GET requests", gets)
+	}
+}
+
+func TestClient_GetZoneFiltered(t *testing.T) {
+	downloads := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(`{"name":"example.com.","rrsets":[
+			{"name":"www.example.com.","type":"A","ttl":300,"records":[{"content":"192.0.2.1","disabled":false}]},
+			{"name":"mail.example.com.","type":"MX","ttl":300,"records":[{"content":"10 mx.example.com."}]},
+			{"name":"www.example.com.","type":"AAAA","ttl":300,"records":[{"content":"2001:db8::1"}]}],
+			"account":"zone-manager","serial":2026101701,"masters":[]}`))
+	}))
+	t.Cleanup(srv.Close)
+	client := NewClient(srv.URL, "key", testLogger())
+	ctx := context.Background()
+	www := func(rrset *RRset) bool { return rrset.Name == "www.example.com." }
+
+	zone, err := client.GetZoneFiltered(ctx, "example.com", www)
+	if err != nil {
+		t.Fatalf("GetZoneFiltered failed: %v", err)
+	}
+	if zone.Account != "zone-manager" || zone.Serial != 2026101701 || zone.RRsetCount != 3 {
+		t.Errorf("Unexpected zone fields: %+v", zone)
+	}
+	if len(zone.RRsets) != 2 || zone.RRsets[0].Type != "A" || zone.RRsets[1].Records[0].Content != "2001:db8::1" {
+		t.Errorf("Expected the www rrsets, got %+v", zone.RRsets)
+	}
+
+	// Filtered zones are not cached, full zones not modified since are
+	// filtered from the cache
+	if _, err := client.GetZone(ctx, "example.com"); err != nil {
+		t.Fatalf("GetZone failed: %v", err)
+	}
+	zone, err = client.GetZoneFiltered(ctx, "example.com", www)
+	if err != nil || len(zone.RRsets) != 2 {
+		t.Fatalf("Expected the www rrsets from the cache, got %+v, %v", zone, err)
+	}
+	if full, _ := client.GetZone(ctx, "example.com"); len(full.RRsets) != 3 {
+		t.Errorf("Expected the cached zone to be unchanged, got %+v", full.RRsets)
+	}
+	if downloads != 2 {
+		t.Errorf("Expected 2 downloads, got %d", downloads)
+	}
+}
+
+func TestDecodeZone_Invalid(t *testing.T) {
+	for _, body := range []string{``, `[]`, `{"rrsets":{}}`, `{"rrsets":[{"name":1}]}`, `{"name":"example.com."`} {
+		if _, err := decodeZone(strings.NewReader(body), nil); err == nil {
+			t.Errorf("Expected error for %q", body)
+		}
+	}
+}
+
+func TestClient_DiskCache(t *testing.T) {
+	gets := 0
+	srv := newTestServer(t, `"v1"`, &gets)
+	dir := t.TempDir()
+	opts := ClientOptions{Cache: NewDiskCache(dir, time.Minute)}
+	ctx := context.Background()
+
+	// Responses are shared between clients, i.e. invocations
+	for range 2 {
+		client := NewClientWithOptions(srv.URL, "key", opts, testLogger())
+		zone, err := client.GetZone(ctx, "example.com")
+		if err != nil {
+			t.Fatalf("GetZone failed: %v", err)
+		}
+		if zone == nil || zone.Account != "zone-manager" {
+			t.Fatalf("Expected cached zone, got %+v", zone)
+		}
+	}
+	if gets != 1 {
+		t.Errorf("Expected 1 GET request, got %d", gets)
+	}
+
+	// Other credentials do not share the cached responses
+	other := NewClientWithOptions(srv.URL, "other", opts, testLogger())
+	if _, err := other.GetZone(ctx, "example.com"); err != nil {
+		t.Fatalf("GetZone failed: %v", err)
+	}
+	if gets != 2 {
+		t.Errorf("Expected a request with other credentials, got %d GET requests", gets)
+	}
+
+	// Expired entries are fetched again
+	expired := ClientOptions{Cache: NewDiskCache(dir, time.Nanosecond)}
+	time.Sleep(time.Millisecond)
+	if _, err := NewClientWithOptions(srv.URL, "key", expired, testLogger()).GetZone(ctx, "example.com"); err != nil {
+		t.Fatalf("GetZone failed: %v", err)
+	}
+	if gets != 3 {
+		t.Errorf("Expected expired entry to be fetched again, got %d GET requests", gets)
+	}
+}
+
+// newKeyServer starts a server that records the API keys used per HTTP method.
+func newKeyServer(t *testing.T, keys map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys[r.Method] = r.Header.Get("X-API-Key")
+		if r.Method == http.MethodPatch {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		_, _ = w.Write([]byte(`{"name":"example.com."}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRoleClient(t *testing.T) {
+	readKeys := make(map[string]string)
+	writeKeys := make(map[string]string)
+	readSrv := newKeyServer(t, readKeys)
+	writeSrv := newKeyServer(t, writeKeys)
+
+	client := NewRoleClient(
+		NewClient(readSrv.URL, "read-key", testLogger()),
+		NewClient(writeSrv.URL, "write-key", testLogger()),
+	)
+	ctx := context.Background()
+
+	if _, err := client.GetZone(ctx, "example.com."); err != nil {
+		t.Fatalf("GetZone failed: %v", err)
+	}
+	if err := client.PatchZone(ctx, "example.com.", &ZonePatch{}); err != nil {
+		t.Fatalf("PatchZone failed: %v", err)
+	}
+
+	if readKeys[http.MethodGet] != "read-key" {
+		t.Errorf("Expected GET with read key, got %q", readKeys[http.MethodGet])
+	}
+	if _, ok := readKeys[http.MethodPatch]; ok {
+		t.Error("Expected no PATCH on read endpoint")
+	}
+	if writeKeys[http.MethodPatch] != "write-key" {
+		t.Errorf("Expected PATCH with write key, got %q", writeKeys[http.MethodPatch])
+	}
+
+	stats := client.Stats()
+	if len(stats) != 2 {
+		t.Errorf("Expected combined stats for GET and PATCH, got %+v", stats)
+	}
+}
+
+func TestClient_Stats_NotFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "missing") {
+			http.Error(w, `{"error": "Not Found"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, `{"error": "Internal Server Error"}`, http.StatusInternalServerError)
+	}))
+	t.Cleanup(srv.Close)
+	client := NewClient(srv.URL, "key", testLogger())
+
+	// A zone that does not exist yet is an answer, not an error
+	if zone, err := client.GetZone(context.Background(), "missing.com."); zone != nil || err != nil {
+		t.Fatalf("GetZone() = %v, %v, want nil, nil", zone, err)
+	}
+	if _, err := client.GetZone(context.Background(), "broken.com."); err == nil {
+		t.Fatal("Expected GetZone to fail")
+	}
+	stats := client.Stats()
+	if len(stats) != 1 || stats[0].Count != 2 || stats[0].Errors != 1 {
+		t.Errorf("Expected 2 GET requests with 1 error, got %+v", stats)
+	}
+}
+
+func TestRoleClient_ReadOnly(t *testing.T) {
+	readSrv := newKeyServer(t, make(map[string]string))
+	client := NewRoleClient(NewClient(readSrv.URL, "read-key", testLogger()), nil)
+
+	err := client.PatchZone(context.Background(), "example.com.", &ZonePatch{})
+	if !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly, got: %v", err)
+	}
+}
+
+func TestClient_Autoprimaries(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.EscapedPath())
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = w.Write([]byte(`[{"ip":"192.0.2.1","nameserver":"ns1.example.com.","account":"zone-manager"}]`))
+		case http.MethodPost:
+			var ap Autoprimary
+			if err := json.NewDecoder(r.Body).Decode(&ap); err != nil || ap.IP != "192.0.2.2" {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	client := NewClient(srv.URL, "key", testLogger())
+	ctx := context.Background()
+
+	autoprimaries, err := client.ListAutoprimaries(ctx)
+	if err != nil {
+		t.Fatalf("ListAutoprimaries failed: %v", err)
+	}
+	if len(autoprimaries) != 1 || autoprimaries[0].Nameserver != "ns1.example.com." {
+		t.Errorf("Unexpected autoprimaries: %+v", autoprimaries)
+	}
+
+	if err := client.AddAutoprimary(ctx, &Autoprimary{IP: "192.0.2.2", Nameserver: "ns2.example.com."}); err != nil {
+		t.Errorf("AddAutoprimary failed: %v", err)
+	}
+	if err := client.DeleteAutoprimary(ctx, "2001:db8::1", "ns2.example.com."); err != nil {
+		t.Errorf("DeleteAutoprimary failed: %v", err)
+	}
+
+	expected := []string{
+		"GET /autoprimaries",
+		"POST /autoprimaries",
+		"DELETE /autoprimaries/2001:db8::1/ns2.example.com.",
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected requests:\n%s", strings.Join(requests, "\n"))
+	}
+
+	readOnly := NewRoleClient(client, nil)
+	if err := readOnly.AddAutoprimary(ctx, &Autoprimary{}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestClient_GetQueryCounts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/statistics" || r.URL.Query().Get("statistic") != "queries" ||
+			r.URL.Query().Get("includerings") != "true" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`[{"name":"queries","type":"RingStatisticItem","size":"10000","value":[
+			{"name":"www.example.com/A","value":"12"},
+			{"name":"WWW.example.com./a","value":"3"},
+			{"name":"0/26.2.0.192.in-addr.arpa/PTR","value":"1"},
+			{"name":"malformed","value":"5"}]}]`))
+	}))
+	t.Cleanup(srv.Close)
+
+	counts, err := NewClient(srv.URL, "key", testLogger()).GetQueryCounts(context.Background())
+	if err != nil {
+		t.Fatalf("GetQueryCounts failed: %v", err)
+	}
+	expected := map[string]int64{"www.example.com./A": 15, "0/26.2.0.192.in-addr.arpa./PTR": 1}
+	if len(counts) != len(expected) {
+		t.Errorf("Unexpected counts: %v", counts)
+	}
+	for key, count := range expected {
+		if counts[key] != count {
+			t.Errorf("Expected %d queries of %s, got %d", count, key, counts[key])
+		}
+	}
+}
+
+func TestClient_RecursorZones(t *testing.T) {
+	var requests []string
+	recursor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+r.Header.Get("X-API-Key"))
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/zones/corp.example.":
+			_, _ = w.Write([]byte(`{"id":"corp.example.","name":"corp.example.","kind":"Forwarded",` +
+				`"servers":["192.0.2.1:53"],"recursion_desired":true}`))
+		case r.Method == http.MethodGet:
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPut:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(recursor.Close)
+	opts := ClientOptions{RecursorURL: recursor.URL, RecursorAPIKey: "recursor-key"}
+	client := NewClientWithOptions("http://authoritative.invalid", "key", opts, testLogger())
+	ctx := context.Background()
+
+	zone, err := client.GetRecursorZone(ctx, "corp.example")
+	if err != nil {
+		t.Fatalf("GetRecursorZone failed: %v", err)
+	}
+	if zone == nil || zone.Kind != "Forwarded" || len(zone.Servers) != 1 || !zone.RecursionDesired {
+		t.Errorf("Unexpected zone: %+v", zone)
+	}
+	if zone, err := client.GetRecursorZone(ctx, "other.example."); err != nil || zone != nil {
+		t.Errorf("Expected no zone, got %+v, %v", zone, err)
+	}
+	if err := client.CreateRecursorZone(ctx, &RecursorZone{Name: "new.example", Kind: "Native"}); err != nil {
+		t.Errorf("CreateRecursorZone failed: %v", err)
+	}
+	if err := client.ReplaceRecursorZone(ctx, &RecursorZone{Name: "corp.example.", Kind: "Native"}); err != nil {
+		t.Errorf("ReplaceRecursorZone failed: %v", err)
+	}
+
+	expected := []string{
+		"GET /zones/corp.example. recursor-key",
+		"GET /zones/other.example. recursor-key",
+		"POST /zones recursor-key",
+		"PUT /zones/corp.example. recursor-key",
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected requests:\n%s", strings.Join(requests, "\n"))
+	}
+
+	_, err = NewClient(recursor.URL, "key", testLogger()).GetRecursorZone(ctx, "corp.example.")
+	if !errors.Is(err, ErrNoRecursor) {
+		t.Errorf("Expected ErrNoRecursor, got %v", err)
+	}
+}
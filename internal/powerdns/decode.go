@@ -0,0 +1,115 @@
+package powerdns
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// RRsetFilter selects the RRsets of a zone to keep, see Client.GetZoneFiltered.
+type RRsetFilter func(rrset *RRset) bool
+
+// decodeZone parses a zone from r, keeping the RRsets selected by keep (all if
+// nil). The rrsets array is decoded one RRset at a time instead of reading the
+// whole response first, which keeps the memory of huge zones proportional to
+// the RRsets kept.
+func decodeZone(r io.Reader, keep RRsetFilter) (*Zone, error) {
+	decoder := json.NewDecoder(r)
+	if err := expectDelim(decoder, '{'); err != nil {
+		return nil, err
+	}
+
+	var zone Zone
+	// The other fields are small, they are collected and parsed at the end
+	fields := make(map[string]json.RawMessage)
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+		key, ok := token.(string)
+		if !ok {
+			return nil, fmt.Errorf("failed to parse response: unexpected %v", token)
+		}
+		if key != "rrsets" {
+			var value json.RawMessage
+			if err := decoder.Decode(&value); err != nil {
+				return nil, fmt.Errorf("failed to parse response: %w", err)
+			}
+			fields[key] = value
+			continue
+		}
+		if err := decodeRRsets(decoder, &zone, keep); err != nil {
+			return nil, err
+		}
+	}
+	if err := expectDelim(decoder, '}'); err != nil {
+		return nil, err
+	}
+
+	rrsets, count := zone.RRsets, zone.RRsetCount
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if err := json.Unmarshal(data, &zone); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	zone.RRsets, zone.RRsetCount = rrsets, count
+	return &zone, nil
+}
+
+// decodeRRsets decodes the rrsets array into zone, one RRset at a time.
+func decodeRRsets(decoder *json.Decoder, zone *Zone, keep RRsetFilter) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	if token == nil {
+		return nil
+	}
+	if token != json.Delim('[') {
+		return fmt.Errorf("failed to parse response: rrsets is not an array")
+	}
+	for decoder.More() {
+		var rrset RRset
+		if err := decoder.Decode(&rrset); err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+		zone.RRsetCount++
+		if keep == nil || keep(&rrset) {
+			zone.RRsets = append(zone.RRsets, rrset)
+		}
+	}
+	return expectDelim(decoder, ']')
+}
+
+// expectDelim reads the next token, which must be delim.
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	if token != delim {
+		return fmt.Errorf("failed to parse response: expected %v, got %v", delim, token)
+	}
+	return nil
+}
+
+// filterZone drops the RRsets of zone that keep does not select.
+func filterZone(zone *Zone, keep RRsetFilter) *Zone {
+	if keep == nil {
+		return zone
+	}
+	if zone.RRsetCount == 0 {
+		zone.RRsetCount = len(zone.RRsets)
+	}
+	kept := zone.RRsets[:0]
+	for i := range zone.RRsets {
+		if keep(&zone.RRsets[i]) {
+			kept = append(kept, zone.RRsets[i])
+		}
+	}
+	zone.RRsets = kept
+	return zone
+}
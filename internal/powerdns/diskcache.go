@@ -0,0 +1,83 @@
+package powerdns
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultCacheMaxAge is the default maximum age of disk cache entries.
+const DefaultCacheMaxAge = 5 * time.Minute
+
+// DiskCache keeps GET responses of the API on disk, so that repeated
+// read-only commands do not download the same zones again. Entries older
+// than the maximum age are ignored and replaced on the next request.
+//
+// The cache is never invalidated by writes, so it must only be used by
+// read-only commands.
+type DiskCache struct {
+	dir    string
+	maxAge time.Duration
+}
+
+// NewDiskCache creates a cache in dir, keeping entries for maxAge.
+func NewDiskCache(dir string, maxAge time.Duration) *DiskCache {
+	return &DiskCache{dir: dir, maxAge: maxAge}
+}
+
+// DefaultCacheDir returns the cache directory in the user cache directory,
+// e.g. ~/.cache/powerdns-zone-manager.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find user cache directory: %w", err)
+	}
+	return filepath.Join(dir, "powerdns-zone-manager"), nil
+}
+
+// key returns the file name of the response of url. The API key is part of
+// the key, as responses may differ between credentials.
+func (c *DiskCache) key(url, apiKey string) string {
+	sum := sha256.Sum256([]byte(url + "\n" + apiKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// get returns a cached response body unless it is missing or expired.
+func (c *DiskCache) get(key string) ([]byte, bool) {
+	path := filepath.Join(c.dir, key)
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > c.maxAge {
+		return nil, false
+	}
+	data, err := os.ReadFile(path) //nolint:gosec // path is a hash in the cache directory
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// put stores a response body. The file is written to a temporary file first,
+// so concurrent commands never read partial responses.
+func (c *DiskCache) put(key string, body []byte) error {
+	if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create cache file: %w", err)
+	}
+	_, writeErr := tmp.Write(body)
+	if err := errors.Join(writeErr, tmp.Close()); err != nil {
+		_ = os.Remove(tmp.Name()) //nolint:errcheck // best effort cleanup
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(c.dir, key)); err != nil {
+		_ = os.Remove(tmp.Name()) //nolint:errcheck // best effort cleanup
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package powerdns
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMaxRateLimitWait is the default of ClientOptions.MaxRateLimitWait.
+const DefaultMaxRateLimitWait = 5 * time.Minute
+
+// rateLimitFallbackWait is the wait before the first retry of a rate limited
+// request without a Retry-After or rate limit reset header. It doubles with
+// every retry.
+const rateLimitFallbackWait = time.Second
+
+// unixTimeThreshold tells epoch timestamps from delays in rate limit reset
+// headers: values above it are a time, not seconds to wait.
+const unixTimeThreshold = 1_000_000_000
+
+// rateLimitWait returns how long to wait before retrying a request that an
+// API gateway rejected with 429 Too Many Requests, or with 503 Service
+// Unavailable and a Retry-After header. It returns false for other responses.
+//
+// The wait is taken from Retry-After (seconds or an HTTP date), or from the
+// RateLimit-Reset or X-RateLimit-Reset header (seconds, or a Unix time), and
+// falls back to a doubling delay for the retry attempt (starting at 0).
+func rateLimitWait(resp *http.Response, attempt int, now time.Time) (time.Duration, bool) {
+	retryAfter := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+	case resp.StatusCode == http.StatusServiceUnavailable && retryAfter != "":
+	default:
+		return 0, false
+	}
+
+	if retryAfter != "" {
+		if seconds, err := strconv.ParseInt(retryAfter, 10, 64); err == nil {
+			return max(time.Duration(seconds)*time.Second, 0), true
+		}
+		if at, err := http.ParseTime(retryAfter); err == nil {
+			return max(at.Sub(now), 0), true
+		}
+	}
+	for _, name := range []string{"RateLimit-Reset", "X-RateLimit-Reset"} {
+		seconds, err := strconv.ParseInt(strings.TrimSpace(resp.Header.Get(name)), 10, 64)
+		if err != nil {
+			continue
+		}
+		if seconds > unixTimeThreshold {
+			return max(time.Unix(seconds, 0).Sub(now), 0), true
+		}
+		return max(time.Duration(seconds)*time.Second, 0), true
+	}
+	return rateLimitFallbackWait << min(attempt, 8), true
+}
+
+// sleep waits for d unless ctx is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
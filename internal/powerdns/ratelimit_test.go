@@ -0,0 +1,126 @@
+package powerdns
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimitWait(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		status   int
+		headers  map[string]string
+		attempt  int
+		expected time.Duration
+		limited  bool
+	}{
+		{"not rate limited", http.StatusOK, nil, 0, 0, false},
+		{"unavailable without retry-after", http.StatusServiceUnavailable, nil, 0, 0, false},
+		{"retry-after seconds", http.StatusTooManyRequests,
+			map[string]string{"Retry-After": "7"}, 0, 7 * time.Second, true},
+		{"retry-after date", http.StatusTooManyRequests,
+			map[string]string{"Retry-After": now.Add(30 * time.Second).Format(http.TimeFormat)},
+			0, 30 * time.Second, true},
+		{"retry-after date in the past", http.StatusTooManyRequests,
+			map[string]string{"Retry-After": now.Add(-time.Minute).Format(http.TimeFormat)}, 0, 0, true},
+		{"unavailable with retry-after", http.StatusServiceUnavailable,
+			map[string]string{"Retry-After": "2"}, 0, 2 * time.Second, true},
+		{"rate limit reset seconds", http.StatusTooManyRequests,
+			map[string]string{"RateLimit-Reset": "3"}, 0, 3 * time.Second, true},
+		{"rate limit reset unix time", http.StatusTooManyRequests,
+			map[string]string{"X-RateLimit-Reset": "1792238405"}, 0, 5 * time.Second, true},
+		{"invalid retry-after", http.StatusTooManyRequests,
+			map[string]string{"Retry-After": "soon", "X-RateLimit-Reset": "4"}, 0, 4 * time.Second, true},
+		{"fallback", http.StatusTooManyRequests, nil, 0, time.Second, true},
+		{"fallback doubles", http.StatusTooManyRequests, nil, 3, 8 * time.Second, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: make(http.Header)}
+			for name, value := range tt.headers {
+				resp.Header.Set(name, value)
+			}
+			wait, limited := rateLimitWait(resp, tt.attempt, now)
+			if wait != tt.expected || limited != tt.limited {
+				t.Errorf("Expected %s, %v, got %s, %v", tt.expected, tt.limited, wait, limited)
+			}
+		})
+	}
+}
+
+func TestClient_RateLimited(t *testing.T) {
+	tests := []struct {
+		name     string
+		limited  int
+		maxWait  time.Duration
+		requests int
+		status   int
+	}{
+		{"retried after the wait", 2, time.Minute, 3, http.StatusOK},
+		{"no waiting", 1, 0, 1, http.StatusTooManyRequests},
+		{"wait too long", 1, 500 * time.Millisecond, 1, http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			var ids []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				ids = append(ids, r.Header.Get("X-Request-ID"))
+				if requests <= tt.limited {
+					// A second for "wait too long", no wait otherwise
+					if tt.maxWait > 0 && tt.maxWait < time.Second {
+						w.Header().Set("Retry-After", "1")
+					} else {
+						w.Header().Set("Retry-After", "0")
+					}
+					w.WriteHeader(http.StatusTooManyRequests)
+					_, _ = w.Write([]byte(`{"error":"Too Many Requests"}`)) //nolint:errcheck // test server
+					return
+				}
+				_, _ = w.Write([]byte(`{"id":"localhost"}`)) //nolint:errcheck // test server
+			}))
+			t.Cleanup(srv.Close)
+
+			client := NewClientWithOptions(srv.URL, "key", ClientOptions{MaxRateLimitWait: tt.maxWait}, testLogger())
+			_, err := client.GetServer(context.Background())
+			var statusErr *StatusError
+			switch {
+			case tt.status == http.StatusOK && err != nil:
+				t.Fatalf("GetServer failed: %v", err)
+			case tt.status != http.StatusOK && (!errors.As(err, &statusErr) || statusErr.StatusCode != tt.status):
+				t.Fatalf("Expected status error %d, got %v", tt.status, err)
+			}
+			if requests != tt.requests {
+				t.Errorf("Expected %d requests, got %d", tt.requests, requests)
+			}
+			for _, id := range ids {
+				if id != ids[0] {
+					t.Errorf("Expected retries with request ID %s, got %s", ids[0], id)
+				}
+			}
+		})
+	}
+}
+
+func TestClient_RateLimitedCanceled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	t.Cleanup(srv.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	client := NewClientWithOptions(srv.URL, "key", ClientOptions{MaxRateLimitWait: time.Hour}, testLogger())
+	if _, err := client.GetServer(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the wait to end with the context, got %v", err)
+	}
+}
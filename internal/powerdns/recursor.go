@@ -0,0 +1,111 @@
+package powerdns
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrNoRecursor is returned for recursor zones if the client has no recursor
+// API, see ClientOptions.RecursorURL.
+var ErrNoRecursor = errors.New("no recursor API URL configured")
+
+// RecursorZone is a zone of the PowerDNS Recursor API: a forward zone or an
+// auth-zone.
+// See: https://doc.powerdns.com/recursor/http-api/zone.html
+type RecursorZone struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name"`
+	// Kind is Forwarded for forward zones, Native for auth-zones
+	Kind string `json:"kind"`
+	// Servers are the addresses queries of a forward zone are sent to, with
+	// optional ports, e.g. 192.0.2.1:5300
+	Servers []string `json:"servers,omitempty"`
+	// RecursionDesired sets the RD bit on forwarded queries, for forwarding to
+	// resolvers instead of authoritative servers (forward-zones-recurse)
+	RecursionDesired bool `json:"recursion_desired"`
+}
+
+// GetRecursorZone returns a zone of the recursor, nil if it does not exist.
+// GET /zones/{zone_id}
+func (c *Client) GetRecursorZone(ctx context.Context, zoneID string) (*RecursorZone, error) {
+	r := c.recursor
+	if r == nil {
+		return nil, ErrNoRecursor
+	}
+	path := "/zones/" + canonicalZoneID(zoneID)
+	resp, err := r.doRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close() //nolint:errcheck // best effort close
+	}()
+
+	if resp.StatusCode == http.StatusNotFound {
+		r.stats.expected("GET")
+		return nil, nil // Zone not found is not an error
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, r.handleError("GET", path, resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	var zone RecursorZone
+	if err := json.Unmarshal(body, &zone); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return &zone, nil
+}
+
+// CreateRecursorZone creates a zone on the recursor.
+// POST /zones
+func (c *Client) CreateRecursorZone(ctx context.Context, zone *RecursorZone) error {
+	r := c.recursor
+	if r == nil {
+		return ErrNoRecursor
+	}
+	zone.Name = canonicalZoneID(zone.Name)
+	resp, err := r.doRequest(ctx, "POST", "/zones", zone)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close() //nolint:errcheck // best effort close
+	}()
+
+	if resp.StatusCode != http.StatusCreated {
+		return r.handleError("POST", "/zones", resp)
+	}
+	return nil
+}
+
+// ReplaceRecursorZone replaces a zone of the recursor; the recursor API does not
+// support partial updates.
+// PUT /zones/{zone_id}
+func (c *Client) ReplaceRecursorZone(ctx context.Context, zone *RecursorZone) error {
+	r := c.recursor
+	if r == nil {
+		return ErrNoRecursor
+	}
+	zone.Name = canonicalZoneID(zone.Name)
+	path := "/zones/" + zone.Name
+	resp, err := r.doRequest(ctx, "PUT", path, zone)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close() //nolint:errcheck // best effort close
+	}()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return r.handleError("PUT", path, resp)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package powerdns
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrReadOnly is returned when a write is attempted without a write client.
+var ErrReadOnly = errors.New("no write API credentials configured (read-only mode)")
+
+// RoleClient routes read operations to a read-only client and write operations
+// to a read-write client, so plans can be computed without the write key.
+type RoleClient struct {
+	reader *Client
+	writer *Client
+}
+
+// NewRoleClient creates a client that reads with reader and writes with writer.
+// writer may be nil, in which case all write operations fail with ErrReadOnly.
+func NewRoleClient(reader, writer *Client) *RoleClient {
+	return &RoleClient{reader: reader, writer: writer}
+}
+
+// Writer returns the write client, or nil in read-only mode.
+func (c *RoleClient) Writer() *Client {
+	return c.writer
+}
+
+// GetZone retrieves zone information using the read client.
+func (c *RoleClient) GetZone(ctx context.Context, zoneID string) (*Zone, error) {
+	return c.reader.GetZone(ctx, zoneID)
+}
+
+// GetZoneFiltered retrieves a zone with the selected RRsets using the read
+// client.
+func (c *RoleClient) GetZoneFiltered(ctx context.Context, zoneID string, keep RRsetFilter) (*Zone, error) {
+	return c.reader.GetZoneFiltered(ctx, zoneID, keep)
+}
+
+// GetZoneInfo retrieves zone information without RRsets using the read client.
+func (c *RoleClient) GetZoneInfo(ctx context.Context, zoneID string) (*Zone, error) {
+	return c.reader.GetZoneInfo(ctx, zoneID)
+}
+
+// CreateZone creates a new DNS zone using the write client.
+func (c *RoleClient) CreateZone(ctx context.Context, zone *Zone) (*Zone, error) {
+	if c.writer == nil {
+		return nil, ErrReadOnly
+	}
+	c.reader.cache.invalidate(canonicalZoneID(zone.Name))
+	return c.writer.CreateZone(ctx, zone)
+}
+
+// PatchZone modifies RRsets in a zone using the write client.
+func (c *RoleClient) PatchZone(ctx context.Context, zoneID string, patch *ZonePatch) error {
+	if c.writer == nil {
+		return ErrReadOnly
+	}
+	c.reader.cache.invalidate(canonicalZoneID(zoneID))
+	return c.writer.PatchZone(ctx, zoneID, patch)
+}
+
+// AxfrRetrieve triggers a zone transfer using the write client.
+func (c *RoleClient) AxfrRetrieve(ctx context.Context, zoneID string) (string, error) {
+	if c.writer == nil {
+		return "", ErrReadOnly
+	}
+	c.reader.cache.invalidate(canonicalZoneID(zoneID))
+	return c.writer.AxfrRetrieve(ctx, zoneID)
+}
+
+// RectifyZone rectifies a zone using the write client.
+func (c *RoleClient) RectifyZone(ctx context.Context, zoneID string) (string, error) {
+	if c.writer == nil {
+		return "", ErrReadOnly
+	}
+	return c.writer.RectifyZone(ctx, zoneID)
+}
+
+// ListAutoprimaries returns the configured autoprimaries using the read client.
+func (c *RoleClient) ListAutoprimaries(ctx context.Context) ([]Autoprimary, error) {
+	return c.reader.ListAutoprimaries(ctx)
+}
+
+// ListZones returns all zones using the read client.
+func (c *RoleClient) ListZones(ctx context.Context) ([]Zone, error) {
+	return c.reader.ListZones(ctx)
+}
+
+// GetServer returns the server information using the read client.
+func (c *RoleClient) GetServer(ctx context.Context) (*Server, error) {
+	return c.reader.GetServer(ctx)
+}
+
+// GetConfig returns the server configuration using the read client.
+func (c *RoleClient) GetConfig(ctx context.Context) ([]ConfigSetting, error) {
+	return c.reader.GetConfig(ctx)
+}
+
+// GetQueryCounts returns the recent query counts using the read client.
+func (c *RoleClient) GetQueryCounts(ctx context.Context) (map[string]int64, error) {
+	return c.reader.GetQueryCounts(ctx)
+}
+
+// GetRecursorZone returns a zone of the recursor using the read client.
+func (c *RoleClient) GetRecursorZone(ctx context.Context, zoneID string) (*RecursorZone, error) {
+	return c.reader.GetRecursorZone(ctx, zoneID)
+}
+
+// CreateRecursorZone creates a zone on the recursor using the write client.
+func (c *RoleClient) CreateRecursorZone(ctx context.Context, zone *RecursorZone) error {
+	if c.writer == nil {
+		return ErrReadOnly
+	}
+	return c.writer.CreateRecursorZone(ctx, zone)
+}
+
+// ReplaceRecursorZone replaces a zone of the recursor using the write client.
+func (c *RoleClient) ReplaceRecursorZone(ctx context.Context, zone *RecursorZone) error {
+	if c.writer == nil {
+		return ErrReadOnly
+	}
+	return c.writer.ReplaceRecursorZone(ctx, zone)
+}
+
+// AddAutoprimary adds an autoprimary using the write client.
+func (c *RoleClient) AddAutoprimary(ctx context.Context, autoprimary *Autoprimary) error {
+	if c.writer == nil {
+		return ErrReadOnly
+	}
+	return c.writer.AddAutoprimary(ctx, autoprimary)
+}
+
+// DeleteAutoprimary deletes an autoprimary using the write client.
+func (c *RoleClient) DeleteAutoprimary(ctx context.Context, ip, nameserver string) error {
+	if c.writer == nil {
+		return ErrReadOnly
+	}
+	return c.writer.DeleteAutoprimary(ctx, ip, nameserver)
+}
+
+// GetMetadata returns all metadata of a zone using the read client.
+func (c *RoleClient) GetMetadata(ctx context.Context, zoneID string) ([]Metadata, error) {
+	return c.reader.GetMetadata(ctx, zoneID)
+}
+
+// SetMetadata replaces a zone metadata kind using the write client.
+func (c *RoleClient) SetMetadata(ctx context.Context, zoneID, kind string, values []string) error {
+	if c.writer == nil {
+		return ErrReadOnly
+	}
+	return c.writer.SetMetadata(ctx, zoneID, kind, values)
+}
+
+// DeleteMetadata deletes a zone metadata kind using the write client.
+func (c *RoleClient) DeleteMetadata(ctx context.Context, zoneID, kind string) error {
+	if c.writer == nil {
+		return ErrReadOnly
+	}
+	return c.writer.DeleteMetadata(ctx, zoneID, kind)
+}
+
+// ResetCache drops the cached zones of the reader, see Client.ResetCache.
+func (c *RoleClient) ResetCache() {
+	c.reader.ResetCache()
+}
+
+// Stats returns the combined request statistics of both clients.
+func (c *RoleClient) Stats() []RequestStats {
+	if c.writer == nil {
+		return c.reader.Stats()
+	}
+	return mergeStats(c.reader.Stats(), c.writer.Stats())
+}
@@ -0,0 +1,115 @@
+package powerdns
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// RequestStats holds timing statistics for API requests with the same HTTP method.
+type RequestStats struct {
+	Method string
+	Count  int
+	Errors int
+	Min    time.Duration
+	Max    time.Duration
+	Total  time.Duration
+}
+
+// Avg returns the average request duration.
+func (s RequestStats) Avg() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+// ErrorRate returns the fraction of failed requests (0..1).
+func (s RequestStats) ErrorRate() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return float64(s.Errors) / float64(s.Count)
+}
+
+// statsCollector aggregates request timings per HTTP method.
+type statsCollector struct {
+	byMethod map[string]*RequestStats
+	mu       sync.Mutex
+}
+
+func newStatsCollector() *statsCollector {
+	return &statsCollector{byMethod: make(map[string]*RequestStats)}
+}
+
+// record adds a single request observation.
+// A request counts as failed if it returned a transport error or a 4xx/5xx status.
+func (c *statsCollector) record(method string, duration time.Duration, failed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, ok := c.byMethod[method]
+	if !ok {
+		s = &RequestStats{Method: method, Min: duration, Max: duration}
+		c.byMethod[method] = s
+	}
+
+	s.Count++
+	s.Total += duration
+	if duration < s.Min {
+		s.Min = duration
+	}
+	if duration > s.Max {
+		s.Max = duration
+	}
+	if failed {
+		s.Errors++
+	}
+}
+
+// expected stops counting a failed request of method as an error, for
+// responses that are answers, e.g. 404 for a zone that does not exist yet.
+func (c *statsCollector) expected(method string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if s, ok := c.byMethod[method]; ok && s.Errors > 0 {
+		s.Errors--
+	}
+}
+
+// snapshot returns a copy of the collected statistics sorted by method.
+func (c *statsCollector) snapshot() []RequestStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := make([]RequestStats, 0, len(c.byMethod))
+	for _, s := range c.byMethod {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Method < stats[j].Method
+	})
+	return stats
+}
+
+// mergeStats combines statistics of several clients per HTTP method.
+func mergeStats(sets ...[]RequestStats) []RequestStats {
+	c := newStatsCollector()
+	for _, stats := range sets {
+		for _, s := range stats {
+			existing, ok := c.byMethod[s.Method]
+			if !ok {
+				cp := s
+				c.byMethod[s.Method] = &cp
+				continue
+			}
+			existing.Count += s.Count
+			existing.Errors += s.Errors
+			existing.Total += s.Total
+			existing.Min = min(existing.Min, s.Min)
+			existing.Max = max(existing.Max, s.Max)
+		}
+	}
+	return c.snapshot()
+}
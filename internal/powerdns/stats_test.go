@@ -0,0 +1,42 @@
+package powerdns
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatsCollector(t *testing.T) {
+	c := newStatsCollector()
+	c.record("GET", 10*time.Millisecond, false)
+	c.record("GET", 30*time.Millisecond, true)
+	c.record("PATCH", 50*time.Millisecond, false)
+
+	stats := c.snapshot()
+	if len(stats) != 2 {
+		t.Fatalf("Expected 2 methods, got %d", len(stats))
+	}
+
+	get := stats[0]
+	if get.Method != "GET" {
+		t.Fatalf("Expected stats sorted by method, got %s first", get.Method)
+	}
+	if get.Count != 2 || get.Errors != 1 {
+		t.Errorf("Expected 2 requests with 1 error, got %d/%d", get.Count, get.Errors)
+	}
+	if get.Min != 10*time.Millisecond || get.Max != 30*time.Millisecond {
+		t.Errorf("Unexpected min/max: %s/%s", get.Min, get.Max)
+	}
+	if get.Avg() != 20*time.Millisecond {
+		t.Errorf("Expected avg 20ms, got %s", get.Avg())
+	}
+	if get.ErrorRate() != 0.5 {
+		t.Errorf("Expected error rate 0.5, got %f", get.ErrorRate())
+	}
+}
+
+func TestRequestStats_Empty(t *testing.T) {
+	var s RequestStats
+	if s.Avg() != 0 || s.ErrorRate() != 0 {
+		t.Errorf("Expected zero values for empty stats, got %s/%f", s.Avg(), s.ErrorRate())
+	}
+}
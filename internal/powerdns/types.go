@@ -11,6 +11,18 @@ type Zone struct {
 	Masters     []string `json:"masters,omitempty"`
 	Nameservers []string `json:"nameservers,omitempty"`
 	RRsets      []RRset  `json:"rrsets,omitempty"`
+	// LastCheck is the time of the last check of a secondary zone for
+	// updates, in seconds since the epoch; 0 if never checked.
+	LastCheck int64 `json:"last_check,omitempty"`
+	// Serial is the SOA serial of the zone, NotifiedSerial the serial of
+	// the last notification sent to secondaries. Both are read-only.
+	Serial         uint32 `json:"serial,omitempty"`
+	NotifiedSerial uint32 `json:"notified_serial,omitempty"`
+	DNSSEC         bool   `json:"dnssec,omitempty"`
+	APIRectify     bool   `json:"api_rectify,omitempty"`
+	// RRsetCount is the number of RRsets of the zone on the server, including
+	// those dropped by Client.GetZoneFiltered; 0 if unknown.
+	RRsetCount int `json:"-"`
 }
 
 // RRset represents a Resource Record Set (all records with the same name and type).
@@ -31,6 +43,9 @@ type Record struct {
 	Content string `json:"content"`
 	// Disabled indicates whether this record is disabled
 	Disabled bool `json:"disabled"`
+	// SetPTR makes the server create a PTR record in the matching reverse
+	// zone (A/AAAA records, PowerDNS before 4.5, see SupportsSetPTR)
+	SetPTR bool `json:"set-ptr,omitempty"`
 }
 
 // Comment represents a comment on an RRSet.
@@ -51,3 +66,62 @@ type ZonePatch struct {
 type APIError struct {
 	Error string `json:"error"`
 }
+
+// OperationResult represents the result message of a zone operation
+// such as axfr-retrieve or rectify.
+type OperationResult struct {
+	Result string `json:"result"`
+}
+
+// Server describes the PowerDNS server the API belongs to.
+// See: https://doc.powerdns.com/authoritative/http-api/server.html
+type Server struct {
+	ID         string `json:"id"`
+	DaemonType string `json:"daemon_type"`
+	Version    string `json:"version"`
+}
+
+// ConfigSetting is a configuration setting of the server as it is in effect,
+// e.g. allow-axfr-ips with the value "127.0.0.0/8,::1".
+// See: https://doc.powerdns.com/authoritative/http-api/server.html
+type ConfigSetting struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// Autoprimary represents an autoprimary (supermaster): a primary server that
+// may provision secondary zones on this server via NOTIFY.
+// See: https://doc.powerdns.com/authoritative/http-api/autoprimaries.html
+type Autoprimary struct {
+	// IP is the address of the primary server
+	IP string `json:"ip"`
+	// Nameserver is the NS name the primary must appear under in the zone
+	Nameserver string `json:"nameserver"`
+	// Account is the account assigned to provisioned zones
+	Account string `json:"account,omitempty"`
+}
+
+// Metadata represents a zone metadata kind and its values.
+// See: https://doc.powerdns.com/authoritative/http-api/metadata.html
+type Metadata struct {
+	Kind     string   `json:"kind"`
+	Metadata []string `json:"metadata"`
+}
+
+// RingStatistic is a ring buffer statistic of the server, such as the most
+// frequent queries with their counts.
+// See: https://doc.powerdns.com/authoritative/http-api/statistics.html
+type RingStatistic struct {
+	Name  string            `json:"name"`
+	Type  string            `json:"type"`
+	Size  string            `json:"size"`
+	Value []StatisticsEntry `json:"value"`
+}
+
+// StatisticsEntry is an entry of a ring statistic, e.g. "www.example.com./A" and
+// its count.
+type StatisticsEntry struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
@@ -0,0 +1,220 @@
+// Package report renders human-readable change reports for apply runs.
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+// Report holds everything needed to render a change report.
+type Report struct {
+	GeneratedAt time.Time
+	Result      *manager.ApplyResult
+	ConfigFile  string
+	RunID       string
+	Error       string
+	DryRun      bool
+}
+
+// diffLine is a single line of a rendered RRset diff.
+type diffLine struct {
+	Op   string
+	Text string
+}
+
+// changeView is the template representation of a single RRset change.
+type changeView struct {
+	Action string
+	Name   string
+	Type   string
+	TTL    string
+	Labels string
+	Lines  []diffLine
+}
+
+// zoneView is the template representation of a single zone result.
+type zoneView struct {
+	Name     string
+	Status   string
+	Error    string
+	Duration string
+	Labels   string
+	Changes  []changeView
+	Counts   [3]int // created, updated, deleted
+	Created  bool
+}
+
+// htmlView is the data passed to the HTML template.
+type htmlView struct {
+	Title       string
+	GeneratedAt string
+	ConfigFile  string
+	RunID       string
+	Error       string
+	Zones       []zoneView
+	Summary     manager.ApplyResult
+	DryRun      bool
+}
+
+// WriteHTML renders the report as a standalone HTML document.
+func WriteHTML(w io.Writer, r *Report) error {
+	view := htmlView{
+		Title:       "PowerDNS zone change report",
+		GeneratedAt: r.GeneratedAt.UTC().Format(time.RFC3339),
+		ConfigFile:  r.ConfigFile,
+		RunID:       r.RunID,
+		Error:       r.Error,
+		DryRun:      r.DryRun,
+	}
+	if r.DryRun {
+		view.Title += " (dry run)"
+	}
+
+	if r.Result != nil {
+		view.Summary = *r.Result
+		for _, zr := range r.Result.Zones {
+			view.Zones = append(view.Zones, newZoneView(&zr))
+		}
+	}
+
+	if err := htmlTemplate.Execute(w, view); err != nil {
+		return fmt.Errorf("failed to render HTML report: %w", err)
+	}
+	return nil
+}
+
+func newZoneView(zr *manager.ZoneResult) zoneView {
+	zv := zoneView{
+		Name:     zr.Name,
+		Status:   string(zr.Status),
+		Error:    zr.Error,
+		Duration: zr.Duration.Round(time.Millisecond).String(),
+		Counts:   [3]int{zr.RRsetsCreated, zr.RRsetsUpdated, zr.RRsetsDeleted},
+		Created:  zr.Created,
+		Labels:   config.FormatLabels(zr.Labels),
+	}
+	for _, c := range zr.Changes {
+		zv.Changes = append(zv.Changes, newChangeView(&c))
+	}
+	return zv
+}
+
+func newChangeView(c *manager.Change) changeView {
+	cv := changeView{
+		Action: string(c.Action),
+		Name:   c.Name,
+		Type:   c.Type,
+		Labels: config.FormatLabels(c.Labels),
+	}
+
+	switch {
+	case c.Action == manager.ChangeUpdate && c.OldTTL != c.NewTTL:
+		cv.TTL = fmt.Sprintf("%d → %d", c.OldTTL, c.NewTTL)
+	case c.Action == manager.ChangeDelete:
+		cv.TTL = fmt.Sprintf("%d", c.OldTTL)
+	default:
+		cv.TTL = fmt.Sprintf("%d", c.NewTTL)
+	}
+
+	cv.Lines = diffRecords(c.Before, c.After)
+	return cv
+}
+
+// diffRecords returns the removed, unchanged and added records in display order.
+func diffRecords(before, after []powerdns.Record) []diffLine {
+	afterSet := make(map[string]bool, len(after))
+	for _, r := range after {
+		afterSet[recordText(r)] = true
+	}
+	beforeSet := make(map[string]bool, len(before))
+	for _, r := range before {
+		beforeSet[recordText(r)] = true
+	}
+
+	lines := make([]diffLine, 0, len(before)+len(after))
+	for _, r := range before {
+		text := recordText(r)
+		if afterSet[text] {
+			lines = append(lines, diffLine{Op: " ", Text: text})
+		} else {
+			lines = append(lines, diffLine{Op: "-", Text: text})
+		}
+	}
+	for _, r := range after {
+		text := recordText(r)
+		if !beforeSet[text] {
+			lines = append(lines, diffLine{Op: "+", Text: text})
+		}
+	}
+	return lines
+}
+
+func recordText(r powerdns.Record) string {
+	if r.Disabled {
+		return r.Content + " [disabled]"
+	}
+	return r.Content
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+th { background: #f0f0f0; }
+pre { margin: 0; }
+.op-add { color: #1a7f37; }
+.op-del { color: #cf222e; }
+.status-failed { color: #cf222e; font-weight: bold; }
+.status-skipped { color: #9a6700; }
+.error { color: #cf222e; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>Generated at {{.GeneratedAt}}{{if .ConfigFile}} from <code>{{.ConfigFile}}</code>{{end}}
+{{- if .RunID}}, run <code>{{.RunID}}</code>{{end}}</p>
+{{if .Error}}<p class="error">Apply failed: {{.Error}}</p>{{end}}
+
+<h2>Summary</h2>
+<table>
+<tr><th>Zones created</th><td>{{.Summary.ZonesCreated}}</td></tr>
+<tr><th>RRsets created</th><td>{{.Summary.RRsetsCreated}}</td></tr>
+<tr><th>RRsets updated</th><td>{{.Summary.RRsetsUpdated}}</td></tr>
+<tr><th>RRsets deleted</th><td>{{.Summary.RRsetsDeleted}}</td></tr>
+</table>
+
+<table>
+<tr><th>Zone</th><th>Created</th><th>Updated</th><th>Deleted</th><th>Status</th><th>Duration</th></tr>
+{{range .Zones}}<tr>
+<td>{{.Name}}{{if .Created}} (new){{end}}</td>
+{{range .Counts}}<td>{{.}}</td>{{end}}
+<td class="status-{{.Status}}">{{.Status}}</td><td>{{.Duration}}</td>
+</tr>
+{{end}}</table>
+{{range .Zones}}
+<h2>{{.Name}}{{if .Created}} (new zone){{end}}</h2>
+<p class="status-{{.Status}}">Status: {{.Status}}{{if .Error}} — {{.Error}}{{end}}</p>
+{{if .Labels}}<p>Labels: {{.Labels}}</p>{{end}}
+{{if .Changes}}<table>
+<tr><th>Action</th><th>Name</th><th>Type</th><th>TTL</th><th>Labels</th><th>Records</th></tr>
+{{range .Changes}}<tr>
+<td>{{.Action}}</td><td>{{.Name}}</td><td>{{.Type}}</td><td>{{.TTL}}</td><td>{{.Labels}}</td>
+<td>{{range .Lines}}<pre class="{{if eq .Op "+"}}op-add{{else if eq .Op "-"}}op-del{{end}}">{{.Op}} {{.Text}}</pre>{{end}}</td>
+</tr>
+{{end}}</table>
+{{else}}<p>No changes.</p>
+{{end}}{{end}}
+</body>
+</html>
+`))
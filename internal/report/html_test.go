@@ -0,0 +1,100 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+func TestWriteHTML(t *testing.T) {
+	result := &manager.ApplyResult{
+		RRsetsUpdated: 1,
+		Zones: []manager.ZoneResult{
+			{
+				Name:          "example.com",
+				Status:        manager.ZoneStatusOK,
+				RRsetsUpdated: 1,
+				Labels:        map[string]string{"team": "core"},
+				Changes: []manager.Change{
+					{
+						Action: manager.ChangeUpdate,
+						Name:   "www.example.com.",
+						Type:   "A",
+						Before: []powerdns.Record{{Content: "192.168.1.1"}, {Content: "192.168.1.3"}},
+						After:  []powerdns.Record{{Content: "192.168.1.2"}, {Content: "192.168.1.3"}},
+						OldTTL: 300,
+						NewTTL: 600,
+						Labels: map[string]string{"team": "web", "ticket": "OPS-1"},
+					},
+				},
+			},
+			{
+				Name:   "other.com",
+				Status: manager.ZoneStatusSkipped,
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	err := WriteHTML(&buf, &Report{
+		GeneratedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Result:      result,
+		ConfigFile:  "zones.yml",
+		RunID:       "20240102T030405-9b1c",
+		DryRun:      true,
+	})
+	if err != nil {
+		t.Fatalf("WriteHTML failed: %v", err)
+	}
+
+	output := buf.String()
+	expected := []string{
+		"(dry run)",
+		"2024-01-02T03:04:05Z",
+		"run <code>20240102T030405-9b1c</code>",
+		"www.example.com.",
+		"300 → 600",
+		"Labels: team=core",
+		"<td>team=web, ticket=OPS-1</td>",
+		"- 192.168.1.1",
+		`op-add">&#43; 192.168.1.2`,
+		"  192.168.1.3",
+		"status-skipped",
+	}
+	for _, e := range expected {
+		if !strings.Contains(output, e) {
+			t.Errorf("Expected report to contain %q", e)
+		}
+	}
+}
+
+func TestWriteHTML_EscapesContent(t *testing.T) {
+	result := &manager.ApplyResult{
+		Zones: []manager.ZoneResult{
+			{
+				Name: "example.com",
+				Changes: []manager.Change{
+					{
+						Action: manager.ChangeCreate,
+						Name:   "txt.example.com.",
+						Type:   "TXT",
+						After:  []powerdns.Record{{Content: `"<script>alert(1)</script>"`}},
+					},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, &Report{Result: result}); err != nil {
+		t.Fatalf("WriteHTML failed: %v", err)
+	}
+
+	if strings.Contains(buf.String(), "<script>") {
+		t.Error("Expected record content to be HTML-escaped")
+	}
+}
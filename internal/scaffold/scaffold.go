@@ -0,0 +1,165 @@
+// Package scaffold renders commented starter zone configurations for the
+// init command.
+package scaffold
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Options describes the zone to scaffold.
+type Options struct {
+	// Zone is the zone name, with or without the trailing dot.
+	Zone string
+	// Nameservers are the zone's nameservers, at least one is required.
+	Nameservers []string
+	// IPv4 and IPv6 are the apex addresses; www is a CNAME to the apex if any is set.
+	IPv4 []string
+	IPv6 []string
+	// MX are mail exchangers as "<preference> <host>".
+	MX []string
+	// TTL of the apex, www and MX rrsets; 0 uses the default TTL.
+	TTL uint32
+}
+
+// DefaultTTL is the TTL used in scaffolded configs.
+const DefaultTTL = 3600
+
+var configTemplate = template.Must(template.New("config").Parse(`# PowerDNS Zone Manager configuration for {{.Zone}}
+# Generated by "powerdns-zone-manager init", see the README for all options.
+#
+# Preview and apply the changes with:
+#   powerdns-zone-manager apply --dry-run <this file>
+#   powerdns-zone-manager apply <this file>
+
+# Account marking managed zones and RRsets (default: zone-manager)
+# account: zone-manager
+
+zones:
+  {{.Zone}}:
+    # Zone type: Native, Master, Slave, Producer or Consumer
+    kind: Native
+
+    # Apex NS records; names must end with "." or the zone name is appended
+    nameservers:
+{{- range .Nameservers}}
+      - {{.}}
+{{- end}}
+
+    # Records of the zone. Use '@' for the zone apex; names are relative to the zone.
+    # records can be a single value, a list, or a list of {content, disabled, comment}.
+{{- if not .HasRRsets}}
+    rrsets: []
+    # rrsets:
+    #   - name: www
+    #     type: A
+    #     ttl: {{.TTL}}
+    #     records:
+    #       - 192.0.2.1
+{{- else}}
+    rrsets:
+{{- if .IPv4}}
+      - name: '@'
+        type: A
+        ttl: {{.TTL}}
+        records:
+{{- range .IPv4}}
+          - {{.}}
+{{- end}}
+{{- end}}
+{{- if .IPv6}}
+      - name: '@'
+        type: AAAA
+        ttl: {{.TTL}}
+        records:
+{{- range .IPv6}}
+          - {{.}}
+{{- end}}
+{{- end}}
+{{- if or .IPv4 .IPv6}}
+
+      # www serves the same content as the apex
+      - name: www
+        type: CNAME
+        ttl: {{.TTL}}
+        records: {{.Zone}}.
+{{- end}}
+{{- if .MX}}
+
+      # Mail exchangers as "<preference> <host>"; hosts must end with "."
+      - name: '@'
+        type: MX
+        ttl: {{.TTL}}
+        records:
+{{- range .MX}}
+          - {{.}}
+{{- end}}
+{{- end}}
+{{- end}}
+`))
+
+// Render validates the options and renders the configuration.
+func Render(opts Options) ([]byte, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if opts.TTL == 0 {
+		opts.TTL = DefaultTTL
+	}
+
+	data := struct {
+		Options
+		HasRRsets bool
+	}{opts, len(opts.IPv4)+len(opts.IPv6)+len(opts.MX) > 0}
+	data.Zone = strings.TrimSuffix(opts.Zone, ".")
+
+	var buf bytes.Buffer
+	if err := configTemplate.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (o *Options) validate() error {
+	var errs []error
+	zone := strings.TrimSuffix(o.Zone, ".")
+	if zone == "" || strings.ContainsAny(zone, " \t:#'\"") {
+		errs = append(errs, fmt.Errorf("invalid zone name %q", o.Zone))
+	}
+	if len(o.Nameservers) == 0 {
+		errs = append(errs, errors.New("at least one nameserver is required"))
+	}
+	for _, ns := range o.Nameservers {
+		if !validHost(ns) {
+			errs = append(errs, fmt.Errorf("invalid nameserver %q", ns))
+		}
+	}
+	for _, ip := range o.IPv4 {
+		if addr := net.ParseIP(ip); addr == nil || addr.To4() == nil {
+			errs = append(errs, fmt.Errorf("invalid IPv4 address %q", ip))
+		}
+	}
+	for _, ip := range o.IPv6 {
+		if addr := net.ParseIP(ip); addr == nil || addr.To4() != nil {
+			errs = append(errs, fmt.Errorf("invalid IPv6 address %q", ip))
+		}
+	}
+	for _, mx := range o.MX {
+		pref, host, ok := strings.Cut(mx, " ")
+		if _, err := strconv.ParseUint(pref, 10, 16); !ok || err != nil || !validHost(host) ||
+			!strings.HasSuffix(host, ".") {
+			errs = append(errs, fmt.Errorf("invalid MX %q, must be \"<preference> <host.>\"", mx))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validHost reports whether s is a plausible host name.
+func validHost(s string) bool {
+	return s != "" && s != "." && !strings.ContainsAny(s, " \t:#'\"/")
+}
@@ -0,0 +1,84 @@
+package scaffold
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+)
+
+func TestRender_MatchesSchema(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       Options
+		wantRRsets int
+	}{
+		{
+			name:       "nameservers only",
+			opts:       Options{Zone: "example.com", Nameservers: []string{"ns1.example.com."}},
+			wantRRsets: 0,
+		},
+		{
+			name: "full",
+			opts: Options{
+				Zone:        "example.com.",
+				Nameservers: []string{"ns1.example.com.", "ns2.example.net."},
+				IPv4:        []string{"192.0.2.1"},
+				IPv6:        []string{"2001:db8::1"},
+				MX:          []string{"10 mail.example.com.", "20 backup.example.net."},
+				TTL:         600,
+			},
+			wantRRsets: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Render(tt.opts)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			cfg, err := config.LoadFromReader(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("Rendered config does not parse: %v\n%s", err, data)
+			}
+			if verr := cfg.Validate(map[string]config.ZoneState{}); verr != nil {
+				t.Fatalf("Rendered config is invalid: %v\n%s", verr, data)
+			}
+			zone, ok := cfg.Zones["example.com"]
+			if !ok {
+				t.Fatalf("Expected zone example.com, got %v", cfg.Zones)
+			}
+			if len(zone.Nameservers) != len(tt.opts.Nameservers) || len(zone.RRsets) != tt.wantRRsets {
+				t.Errorf("Expected %d nameservers and %d rrsets, got %+v",
+					len(tt.opts.Nameservers), tt.wantRRsets, zone)
+			}
+		})
+	}
+}
+
+func TestRender_InvalidOptions(t *testing.T) {
+	ns := []string{"ns1.example.com."}
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr string
+	}{
+		{"missing zone", Options{Nameservers: ns}, "invalid zone name"},
+		{"missing nameservers", Options{Zone: "example.com"}, "at least one nameserver"},
+		{"IPv6 as IPv4", Options{Zone: "example.com", Nameservers: ns, IPv4: []string{"::1"}}, "IPv4"},
+		{"IPv4 as IPv6", Options{Zone: "example.com", Nameservers: ns, IPv6: []string{"192.0.2.1"}}, "IPv6"},
+		{"MX without preference", Options{Zone: "example.com", Nameservers: ns, MX: []string{"mail."}}, "MX"},
+		{"relative MX host", Options{Zone: "example.com", Nameservers: ns, MX: []string{"10 mail"}}, "MX"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Render(tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
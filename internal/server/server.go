@@ -0,0 +1,256 @@
+// Package server exposes plan, apply and check as an authenticated HTTP API,
+// so that other tools can change DNS through the same ownership and
+// validation logic as the command line.
+//
+// Every endpoint takes a zone configuration (YAML, as in a config file) as the
+// POST request body and requires an "Authorization: Bearer <token>" header.
+// An optional "X-Run-ID" header is recorded in ownership comments.
+//
+//	POST /v1/plan   changes that would be made (dry run)
+//	POST /v1/apply  applies the configuration
+//	POST /v1/check  validates the configuration and reports pending changes
+//
+// Configurations that fail validation or that the Runner rejects, e.g. for a
+// change policy, are answered with 422 Unprocessable Entity.
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/logger"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+)
+
+// MaxConfigSize is the maximum size of a posted configuration.
+const MaxConfigSize = 10 << 20
+
+// ErrRejected marks the errors of a Runner that rejects a configuration
+// before anything is applied, e.g. because of a change policy.
+var ErrRejected = errors.New("configuration rejected")
+
+// Runner applies a configuration, or only plans it if dryRun is set, with
+// runID recorded in ownership comments. The result is returned even if the
+// apply failed, as partial result.
+type Runner func(ctx context.Context, cfg *config.Config, runID string, dryRun bool) (*manager.ApplyResult, error)
+
+// Options configures the server.
+type Options struct {
+	// Token is the bearer token clients must send.
+	Token string
+	// Account is the account name that marks managed zones and RRsets.
+	// Configurations with a different top-level account are rejected.
+	Account string
+	// DefaultTTL overrides the TTL of rrsets without ttl, if not 0.
+	DefaultTTL uint32
+	// StrictNames enables strict name validation for all configurations.
+	StrictNames bool
+	// DanglingTargets is the dangling_targets mode of configurations that
+	// do not set one.
+	DanglingTargets string
+	// DualStack is the dual_stack mode of configurations that do not set one.
+	DualStack string
+}
+
+// Server handles API requests. Requests are processed one at a time, as
+// concurrent applies could interleave changes to the same zones.
+type Server struct {
+	run  Runner
+	log  *logger.Logger
+	opts Options
+	mu   sync.Mutex
+}
+
+// New creates a server applying configurations with run.
+func New(run Runner, opts Options, log *logger.Logger) (*Server, error) {
+	if opts.Token == "" {
+		return nil, errors.New("an API token is required")
+	}
+	return &Server{run: run, opts: opts, log: log}, nil
+}
+
+// Handler returns the HTTP handler of the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/plan", s.authorized(s.handlePlan))
+	mux.HandleFunc("POST /v1/apply", s.authorized(s.handleApply))
+	mux.HandleFunc("POST /v1/check", s.authorized(s.handleCheck))
+	return mux
+}
+
+func (s *Server) authorized(next http.HandlerFunc) http.HandlerFunc {
+	expected := []byte("Bearer " + s.opts.Token)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("invalid or missing API token"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) handlePlan(w http.ResponseWriter, r *http.Request) {
+	s.handle(w, r, "plan", true)
+}
+
+func (s *Server) handleApply(w http.ResponseWriter, r *http.Request) {
+	s.handle(w, r, "apply", false)
+}
+
+func (s *Server) handleCheck(w http.ResponseWriter, r *http.Request) {
+	s.handle(w, r, "check", true)
+}
+
+// handle applies the posted configuration and writes the result.
+func (s *Server) handle(w http.ResponseWriter, r *http.Request, action string, dryRun bool) {
+	cfg, err := s.readConfig(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.log.Info("%s: %d zone(s) from %s", action, len(cfg.Zones), r.RemoteAddr)
+	result, err := s.run(r.Context(), cfg, r.Header.Get("X-Run-ID"), dryRun)
+
+	var validationErr *config.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		resp := errorResponse{Error: "validation failed", Errors: validationErr.Errors}
+		writeJSON(w, http.StatusUnprocessableEntity, resp)
+		return
+	case errors.Is(err, ErrRejected):
+		s.log.Warn("%s rejected: %v", action, err)
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	case err != nil && result == nil:
+		s.log.Error("%s failed: %v", action, err)
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	resp := newResponse(result)
+	if action == "check" {
+		inSync := result.ZonesCreated+result.RRsetsCreated+result.RRsetsUpdated+result.RRsetsDeleted+
+			result.MetadataUpdated == 0
+		resp.InSync = &inSync
+		resp.Zones = nil
+	}
+	status := http.StatusOK
+	if err != nil {
+		s.log.Error("%s failed: %v", action, err)
+		resp.Error = err.Error()
+		status = http.StatusInternalServerError
+	}
+	writeJSON(w, status, resp)
+}
+
+// readConfig parses the configuration in the request body.
+func (s *Server) readConfig(w http.ResponseWriter, r *http.Request) (*config.Config, error) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxConfigSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration: %w", err)
+	}
+	cfg, err := config.LoadFromReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse configuration: %w", err)
+	}
+	if cfg.Account != "" && cfg.Account != s.opts.Account {
+		return nil, fmt.Errorf("configuration account %q does not match the server account %q",
+			cfg.Account, s.opts.Account)
+	}
+	if s.opts.DefaultTTL != 0 {
+		cfg.SetDefaultTTL(s.opts.DefaultTTL)
+	}
+	cfg.StrictNames = cfg.StrictNames || s.opts.StrictNames
+	if cfg.DanglingTargets == "" {
+		cfg.DanglingTargets = s.opts.DanglingTargets
+	}
+	if cfg.DualStack == "" {
+		cfg.DualStack = s.opts.DualStack
+	}
+	return cfg, nil
+}
+
+// response is the result of a plan, apply or check request.
+type response struct {
+	InSync          *bool          `json:"inSync,omitempty"`
+	Zones           []zoneResponse `json:"zones,omitempty"`
+	Error           string         `json:"error,omitempty"`
+	ZonesCreated    int            `json:"zonesCreated"`
+	RRsetsCreated   int            `json:"rrsetsCreated"`
+	RRsetsUpdated   int            `json:"rrsetsUpdated"`
+	RRsetsDeleted   int            `json:"rrsetsDeleted"`
+	RRsetsScheduled int            `json:"scheduled"`
+	MetadataUpdated int            `json:"metadata"`
+}
+
+type zoneResponse struct {
+	Zone    string           `json:"zone"`
+	Status  string           `json:"status"`
+	Error   string           `json:"error,omitempty"`
+	Changes []changeResponse `json:"changes,omitempty"`
+	Created bool             `json:"zoneCreated"`
+}
+
+type changeResponse struct {
+	Action  string   `json:"action"`
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Records []string `json:"records,omitempty"`
+	TTL     uint32   `json:"ttl,omitempty"`
+}
+
+type errorResponse struct {
+	Error  string   `json:"error"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+func newResponse(result *manager.ApplyResult) *response {
+	resp := &response{
+		ZonesCreated:    result.ZonesCreated,
+		RRsetsCreated:   result.RRsetsCreated,
+		RRsetsUpdated:   result.RRsetsUpdated,
+		RRsetsDeleted:   result.RRsetsDeleted,
+		RRsetsScheduled: result.RRsetsScheduled,
+		MetadataUpdated: result.MetadataUpdated,
+	}
+	for _, zr := range result.Zones {
+		z := zoneResponse{Zone: zr.Name, Status: string(zr.Status), Error: zr.Error, Created: zr.Created}
+		for _, c := range zr.Changes {
+			change := changeResponse{Action: string(c.Action), Name: c.Name, Type: c.Type, TTL: c.NewTTL}
+			for _, rec := range c.After {
+				content := rec.Content
+				if rec.Disabled {
+					content += " (disabled)"
+				}
+				change.Records = append(change.Records, content)
+			}
+			z.Changes = append(z.Changes, change)
+		}
+		resp.Zones = append(resp.Zones, z)
+	}
+	return resp
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: strings.TrimSpace(err.Error())})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	// The status is sent, a failed write can only be dropped
+	_ = json.NewEncoder(w).Encode(body) //nolint:errcheck // see above
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/fileprovider"
+	"github.com/kreigan/powerdns-zone-manager/internal/logger"
+	"github.com/kreigan/powerdns-zone-manager/internal/manager"
+)
+
+const testConfig = `zones:
+  example.com:
+    nameservers:
+      - ns1.example.net.
+    rrsets:
+      - name: www
+        type: A
+        ttl: 300
+        records:
+          - 192.0.2.1
+`
+
+// newRunner returns a runner applying configurations to a file provider in a
+// temporary directory without any checks.
+func newRunner(t *testing.T, log *logger.Logger) Runner {
+	t.Helper()
+	provider := fileprovider.New(t.TempDir(), fileprovider.Options{})
+	return func(ctx context.Context, cfg *config.Config, runID string, dryRun bool) (*manager.ApplyResult, error) {
+		mgr := manager.NewManager(provider, "zone-manager", log)
+		mgr.SetRunID(runID)
+		return mgr.Apply(ctx, cfg, manager.ApplyOptions{DryRun: dryRun, AutoConfirm: true})
+	}
+}
+
+func newTestServer(t *testing.T) http.Handler {
+	t.Helper()
+	log := logger.New(logger.Options{NoColor: true}).Quiet()
+	srv, err := New(newRunner(t, log), Options{
+		Token:   "secret",
+		Account: "zone-manager",
+	}, log)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return srv.Handler()
+}
+
+func post(t *testing.T, h http.Handler, path, token, body string) (int, map[string]interface{}) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Invalid JSON response %q: %v", rec.Body.String(), err)
+	}
+	return rec.Code, resp
+}
+
+func TestNew_RequiresToken(t *testing.T) {
+	if _, err := New(newRunner(t, nil), Options{}, nil); err == nil {
+		t.Error("Expected error without API token")
+	}
+}
+
+func TestServer_Requests(t *testing.T) {
+	h := newTestServer(t)
+	const unauthorized = "invalid or missing API token"
+
+	tests := []struct {
+		name       string
+		path       string
+		token      string
+		body       string
+		wantStatus int
+		wantKey    string
+		wantValue  interface{}
+	}{
+		{"missing token", "/v1/plan", "", testConfig, http.StatusUnauthorized, "error", unauthorized},
+		{"wrong token", "/v1/apply", "guess", testConfig, http.StatusUnauthorized, "error", unauthorized},
+		{"invalid YAML", "/v1/plan", "secret", "zones: [", http.StatusBadRequest, "", nil},
+		{"other account", "/v1/plan", "secret", "account: other\n" + testConfig, http.StatusBadRequest, "", nil},
+		{"plan", "/v1/plan", "secret", testConfig, http.StatusOK, "zonesCreated", float64(1)},
+		{"check before apply", "/v1/check", "secret", testConfig, http.StatusOK, "inSync", false},
+		{"apply", "/v1/apply", "secret", testConfig, http.StatusOK, "zonesCreated", float64(1)},
+		{"check after apply", "/v1/check", "secret", testConfig, http.StatusOK, "inSync", true},
+		{"plan after apply", "/v1/plan", "secret", testConfig, http.StatusOK, "zonesCreated", float64(0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := post(t, h, tt.path, tt.token, tt.body)
+			if status != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %v", tt.wantStatus, status, resp)
+			}
+			if tt.wantKey != "" && resp[tt.wantKey] != tt.wantValue {
+				t.Errorf("Expected %s=%v, got %v", tt.wantKey, tt.wantValue, resp[tt.wantKey])
+			}
+		})
+	}
+}
+
+func TestServer_ValidationErrors(t *testing.T) {
+	h := newTestServer(t)
+	config := "zones:\n  example.com:\n    rrsets:\n      - name: www\n        type: BOGUS\n        records: [x]\n"
+
+	status, resp := post(t, h, "/v1/apply", "secret", config)
+	if status != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422, got %d: %v", status, resp)
+	}
+	if errs, ok := resp["errors"].([]interface{}); !ok || len(errs) == 0 {
+		t.Errorf("Expected validation errors, got %v", resp)
+	}
+}
+
+func TestServer_Rejected(t *testing.T) {
+	log := logger.New(logger.Options{NoColor: true}).Quiet()
+	reject := func(context.Context, *config.Config, string, bool) (*manager.ApplyResult, error) {
+		return nil, fmt.Errorf("%w: 3 changes exceed the limit of 1", ErrRejected)
+	}
+	srv, err := New(reject, Options{Token: "secret", Account: "zone-manager"}, log)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	status, resp := post(t, srv.Handler(), "/v1/apply", "secret", testConfig)
+	if status != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422, got %d: %v", status, resp)
+	}
+	if msg, _ := resp["error"].(string); !strings.Contains(msg, "exceed the limit") {
+		t.Errorf("Expected the rejection in the error, got %v", resp)
+	}
+}
@@ -0,0 +1,197 @@
+// Package serverconfig audits the configuration of a PowerDNS server against
+// an expected baseline, so that settings like the API, DNSSEC defaults and
+// AXFR allowances can be verified along with the zones.
+//
+// A baseline file maps setting names to their expected values. Lists are
+// compared regardless of their order:
+//
+//	settings:
+//	  api: "yes"
+//	  default-ksk-algorithm: ecdsa256
+//	  allow-axfr-ips: [127.0.0.0/8, "::1"]
+package serverconfig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+// Baseline is the expected configuration of a server.
+type Baseline struct {
+	Settings map[string]Value `yaml:"settings"`
+}
+
+// Value is the expected value of a setting, a scalar or a list.
+type Value struct {
+	items []string
+	list  bool
+}
+
+// UnmarshalYAML accepts a scalar or a sequence of scalars.
+func (v *Value) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		v.items = []string{node.Value}
+		return nil
+	case yaml.SequenceNode:
+		v.list = true
+		v.items = make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return fmt.Errorf("line %d: list items must be scalars", item.Line)
+			}
+			v.items = append(v.items, item.Value)
+		}
+		return nil
+	default:
+		return fmt.Errorf("line %d: value must be a scalar or a list", node.Line)
+	}
+}
+
+// String returns the value the way the server reports it, lists joined by
+// commas.
+func (v Value) String() string {
+	return strings.Join(v.items, ",")
+}
+
+// Status of an audited setting.
+const (
+	StatusOK      = "ok"
+	StatusDrift   = "drift"
+	StatusMissing = "missing"
+)
+
+// Result is the audit result of a setting of the baseline.
+type Result struct {
+	Setting  string
+	Expected string
+	// Actual is the value of the server, empty if it does not report the
+	// setting
+	Actual string
+	Status string
+}
+
+// Load reads and validates a baseline file.
+func Load(filePath string) (*Baseline, error) {
+	data, err := os.ReadFile(filePath) //nolint:gosec // path is from CLI argument
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
+	b, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("baseline %s: %w", filePath, err)
+	}
+	return b, nil
+}
+
+// Parse parses and validates a baseline.
+func Parse(data []byte) (*Baseline, error) {
+	var b Baseline
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&b); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse baseline: %w", err)
+	}
+	if len(b.Settings) == 0 {
+		return nil, fmt.Errorf("no settings in baseline")
+	}
+	for name := range b.Settings {
+		if strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("setting name cannot be empty")
+		}
+	}
+	return &b, nil
+}
+
+// Audit compares the settings of the server against the baseline and returns
+// the results sorted by setting name.
+func (b *Baseline) Audit(settings []powerdns.ConfigSetting) []Result {
+	actual := make(map[string]string, len(settings))
+	for _, s := range settings {
+		actual[s.Name] = s.Value
+	}
+
+	names := make([]string, 0, len(b.Settings))
+	for name := range b.Settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]Result, 0, len(names))
+	for _, name := range names {
+		expected := b.Settings[name]
+		result := Result{Setting: name, Expected: expected.String(), Status: StatusOK}
+		value, ok := actual[name]
+		switch {
+		case !ok:
+			result.Status = StatusMissing
+		case !expected.matches(value):
+			result.Status = StatusDrift
+		}
+		result.Actual = value
+		results = append(results, result)
+	}
+	return results
+}
+
+// Drifted returns the number of results that do not match the baseline.
+func Drifted(results []Result) int {
+	n := 0
+	for _, r := range results {
+		if r.Status != StatusOK {
+			n++
+		}
+	}
+	return n
+}
+
+// matches reports whether a value reported by the server is the expected one.
+// Booleans match in any spelling, lists in any order.
+func (v Value) matches(actual string) bool {
+	if !v.list {
+		return normalize(v.String()) == normalize(actual)
+	}
+	items := splitList(actual)
+	expected := make([]string, len(v.items))
+	for i, item := range v.items {
+		expected[i] = normalize(item)
+	}
+	slices.Sort(items)
+	slices.Sort(expected)
+	return slices.Equal(items, expected)
+}
+
+// splitList splits a list setting of the server, separated by commas or
+// spaces.
+func splitList(value string) []string {
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+	for i, f := range fields {
+		fields[i] = normalize(f)
+	}
+	return fields
+}
+
+// normalize returns the canonical spelling of a value: PowerDNS accepts
+// yes/no, true/false and on/off for booleans.
+func normalize(value string) string {
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(value) {
+	case "yes", "true", "on":
+		return "yes"
+	case "no", "false", "off":
+		return "no"
+	}
+	return value
+}
@@ -0,0 +1,64 @@
+package serverconfig
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "empty", data: "", wantErr: "no settings in baseline"},
+		{name: "unknown field", data: "setting:\n  api: yes\n", wantErr: "field setting not found"},
+		{name: "nested value", data: "settings:\n  api:\n    enabled: yes\n", wantErr: "must be a scalar or a list"},
+		{name: "nested list item", data: "settings:\n  allow-axfr-ips: [[a]]\n", wantErr: "must be scalars"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.data)); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestBaseline_Audit(t *testing.T) {
+	baseline, err := Parse([]byte(`settings:
+  api: true
+  default-ksk-algorithm: ecdsa256
+  allow-axfr-ips: ["::1", 127.0.0.0/8]
+  also-notify: [192.0.2.1]
+  disable-axfr: "no"
+  webserver: "yes"
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	results := baseline.Audit([]powerdns.ConfigSetting{
+		{Name: "api", Type: "ConfigSetting", Value: "yes"},
+		{Name: "default-ksk-algorithm", Type: "ConfigSetting", Value: "rsasha256"},
+		{Name: "allow-axfr-ips", Type: "ConfigSetting", Value: "127.0.0.0/8, ::1"},
+		{Name: "also-notify", Type: "ConfigSetting", Value: "192.0.2.1,192.0.2.2"},
+		{Name: "disable-axfr", Type: "ConfigSetting", Value: "no"},
+	})
+	expected := []Result{
+		{Setting: "allow-axfr-ips", Expected: "::1,127.0.0.0/8", Actual: "127.0.0.0/8, ::1", Status: StatusOK},
+		{Setting: "also-notify", Expected: "192.0.2.1", Actual: "192.0.2.1,192.0.2.2", Status: StatusDrift},
+		{Setting: "api", Expected: "true", Actual: "yes", Status: StatusOK},
+		{Setting: "default-ksk-algorithm", Expected: "ecdsa256", Actual: "rsasha256", Status: StatusDrift},
+		{Setting: "disable-axfr", Expected: "no", Actual: "no", Status: StatusOK},
+		{Setting: "webserver", Expected: "yes", Status: StatusMissing},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("Unexpected results:\n%+v\nwant\n%+v", results, expected)
+	}
+	if n := Drifted(results); n != 3 {
+		t.Errorf("Expected 3 drifted settings, got %d", n)
+	}
+}
@@ -0,0 +1,22 @@
+//go:build unix
+
+package settings
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkOwner fails for settings files that are writable by the group or by
+// others, or that are owned by another user than the one running the command.
+func checkOwner(path string, info os.FileInfo) error {
+	if info.Mode().Perm()&0o022 != 0 {
+		return fmt.Errorf("settings %s are writable by other users (mode %s), refusing to load them",
+			path, info.Mode().Perm())
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("settings %s are owned by another user (uid %d), refusing to load them", path, stat.Uid)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+// Package settings loads project-level defaults from a .pdns-zm.yaml file
+// committed next to the zone configuration.
+package settings
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/config"
+	"github.com/kreigan/powerdns-zone-manager/internal/dnsdist"
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+// FileName is the name of the settings file.
+const FileName = ".pdns-zm.yaml"
+
+// Settings are project-level defaults. Command-line flags and environment
+// variables take precedence. API keys are deliberately not supported: the
+// file is meant to be committed.
+type Settings struct {
+	Account    string  `yaml:"account,omitempty"`
+	APIURL     string  `yaml:"api_url,omitempty"`
+	ReadAPIURL string  `yaml:"read_api_url,omitempty"`
+	DefaultTTL *uint32 `yaml:"default_ttl,omitempty"`
+
+	// RecursorAPIURL is the PowerDNS Recursor API managing the zones with
+	// target recursor.
+	RecursorAPIURL string `yaml:"recursor_api_url,omitempty"`
+
+	// APIHeaders are added to every API request. Environment variables in
+	// the values are expanded, e.g. X-Change-Ticket: ${CHANGE_TICKET}.
+	APIHeaders map[string]string `yaml:"api_headers,omitempty"`
+
+	// RequireExplicitAccount fails commands whose account name would fall
+	// back to the built-in default.
+	RequireExplicitAccount bool `yaml:"require_explicit_account,omitempty"`
+
+	// StrictNames rejects fully qualified rrset names outside of their zone
+	// that are not marked as external.
+	StrictNames bool `yaml:"strict_names,omitempty"`
+
+	// DanglingTargets is the default of the config dangling_targets key.
+	DanglingTargets string `yaml:"dangling_targets,omitempty"`
+
+	// DualStack is the default of the config dual_stack key.
+	DualStack string `yaml:"dual_stack,omitempty"`
+
+	// Policy is the policy file checked by apply, relative to the settings
+	// file (see package policy).
+	Policy string `yaml:"policy,omitempty"`
+
+	// Dnsdist are the dnsdist instances whose caches apply clears for the
+	// changed names (see package dnsdist).
+	Dnsdist []dnsdist.Endpoint `yaml:"dnsdist,omitempty"`
+
+	// Path is the file the settings were loaded from, empty if none was found.
+	Path string `yaml:"-"`
+
+	// Hooks are commands run by apply.
+	Hooks Hooks `yaml:"hooks,omitempty"`
+
+	// Pacing tunes how large zones are patched (see manager.Pacing).
+	Pacing Pacing `yaml:"pacing,omitempty"`
+
+	// Safety limits how much a single apply may change.
+	Safety Safety `yaml:"safety,omitempty"`
+}
+
+// Safety are thresholds that fail an apply planning more changes, e.g. after
+// a zone was left out of the configuration by mistake. 0 means no limit.
+type Safety struct {
+	// MaxChanges is the maximum number of rrsets created, updated and
+	// deleted by an apply.
+	MaxChanges int `yaml:"max_changes,omitempty"`
+	// MaxDeletions is the maximum number of rrsets deleted by an apply.
+	MaxDeletions int `yaml:"max_deletions,omitempty"`
+}
+
+// Pacing overrides the defaults of manager.DefaultPacing; unset values keep
+// them.
+type Pacing struct {
+	// LargeZoneRRsets is the number of rrsets from which a zone is large,
+	// 0 disables pacing.
+	LargeZoneRRsets *int `yaml:"large_zone_rrsets,omitempty"`
+	// BatchSize is the maximum number of rrset changes per patch.
+	BatchSize int `yaml:"batch_size,omitempty"`
+	// Pause is the time between the patches of a large zone, e.g. 2s.
+	Pause time.Duration `yaml:"pause,omitempty"`
+	// SizeLimit is the request body limit of the server in bytes, patches
+	// close to it are reported. 0 disables the check.
+	SizeLimit *int `yaml:"size_limit,omitempty"`
+}
+
+// Hooks are shell commands run in the directory of the settings file with a
+// JSON document on stdin (see package hook).
+type Hooks struct {
+	// PreApply commands run before changes are applied, with the planned
+	// changes. A failing command aborts the apply.
+	PreApply []string `yaml:"pre_apply,omitempty"`
+	// PostApply commands run after changes were applied, with the result.
+	PostApply []string `yaml:"post_apply,omitempty"`
+}
+
+// Discover looks for the settings file in dir and its parent directories up to
+// the root of the repository (the directory containing .git), and loads the
+// first one found. If there is none, empty settings are returned.
+// Settings can run hooks, so a file that other users could have written (see
+// checkOwner) is an error rather than being loaded: a file planted in a shared
+// parent directory such as /tmp must not take effect.
+func Discover(dir string) (*Settings, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve directory: %w", err)
+	}
+
+	for {
+		path := filepath.Join(dir, FileName)
+		if info, err := os.Stat(path); err == nil {
+			if err := checkOwner(path, info); err != nil {
+				return nil, err
+			}
+			return Load(path)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to check %s: %w", path, err)
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return &Settings{}, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return &Settings{}, nil
+		}
+		dir = parent
+	}
+}
+
+// Load loads settings from a file. Unknown keys are rejected.
+func Load(path string) (*Settings, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path is discovered from the config location
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings: %w", err)
+	}
+
+	s := &Settings{Path: path}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(s); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse settings %s: %w", path, err)
+	}
+	if s.DefaultTTL != nil {
+		if err := config.ValidateTTL("default_ttl", *s.DefaultTTL); err != nil {
+			return nil, fmt.Errorf("settings %s: %w", path, err)
+		}
+	}
+	if err := config.ValidateDanglingMode(s.DanglingTargets); err != nil {
+		return nil, fmt.Errorf("settings %s: %w", path, err)
+	}
+	if err := config.ValidateDualStackMode(s.DualStack); err != nil {
+		return nil, fmt.Errorf("settings %s: %w", path, err)
+	}
+	for _, command := range append(s.Hooks.PreApply, s.Hooks.PostApply...) {
+		if strings.TrimSpace(command) == "" {
+			return nil, fmt.Errorf("settings %s: hooks: empty command", path)
+		}
+	}
+	for i := range s.Dnsdist {
+		if err := s.Dnsdist[i].Validate(); err != nil {
+			return nil, fmt.Errorf("settings %s: dnsdist: %w", path, err)
+		}
+	}
+	if (s.Pacing.LargeZoneRRsets != nil && *s.Pacing.LargeZoneRRsets < 0) || s.Pacing.BatchSize < 0 ||
+		s.Pacing.Pause < 0 || (s.Pacing.SizeLimit != nil && *s.Pacing.SizeLimit < 0) {
+		return nil, fmt.Errorf("settings %s: pacing values cannot be negative", path)
+	}
+	if s.Safety.MaxChanges < 0 || s.Safety.MaxDeletions < 0 {
+		return nil, fmt.Errorf("settings %s: safety thresholds cannot be negative", path)
+	}
+	for name, value := range s.APIHeaders {
+		value = os.ExpandEnv(value)
+		if err := powerdns.ValidateHeader(name, value); err != nil {
+			return nil, fmt.Errorf("settings %s: api_headers: %w", path, err)
+		}
+		s.APIHeaders[name] = value
+	}
+	return s, nil
+}
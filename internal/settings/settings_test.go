@@ -0,0 +1,140 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "zones", "prod")
+	if err := os.MkdirAll(nested, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CHANGE_TICKET", "CHG-42")
+	writeFile(t, filepath.Join(root, FileName), "account: team-a\napi_url: http://pdns:8081/api/v1/servers/localhost\n"+
+		"default_ttl: 3600\nrequire_explicit_account: true\nstrict_names: true\n"+
+		"api_headers:\n  X-Change-Ticket: ${CHANGE_TICKET}\n"+
+		"hooks:\n  pre_apply: [./create-ticket.sh]\n  post_apply: [./purge-cache.sh, ./close-ticket.sh]\n"+
+		"dnsdist:\n  - url: http://edge1:8083\n    api_key_env: DNSDIST_API_KEY\n    pools: [\"\", resolvers]\n"+
+		"pacing:\n  large_zone_rrsets: 0\n  batch_size: 200\n  pause: 2s\n"+
+		"safety:\n  max_changes: 500\n  max_deletions: 20\n")
+
+	s, err := Discover(nested)
+	if err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	if s.Path != filepath.Join(root, FileName) {
+		t.Errorf("Expected settings from the parent directory, got %q", s.Path)
+	}
+	if s.Account != "team-a" || s.APIURL == "" || s.DefaultTTL == nil || *s.DefaultTTL != 3600 ||
+		!s.RequireExplicitAccount || !s.StrictNames || s.APIHeaders["X-Change-Ticket"] != "CHG-42" ||
+		len(s.Hooks.PreApply) != 1 || len(s.Hooks.PostApply) != 2 ||
+		len(s.Dnsdist) != 1 || len(s.Dnsdist[0].Pools) != 2 || s.Pacing.LargeZoneRRsets == nil ||
+		*s.Pacing.LargeZoneRRsets != 0 || s.Pacing.BatchSize != 200 || s.Pacing.Pause != 2*time.Second ||
+		s.Safety != (Safety{MaxChanges: 500, MaxDeletions: 20}) {
+		t.Errorf("Unexpected settings: %+v", s)
+	}
+
+	// The closest file wins
+	writeFile(t, filepath.Join(nested, FileName), "account: team-b\n")
+	s, err = Discover(nested)
+	if err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	if s.Account != "team-b" || s.APIURL != "" {
+		t.Errorf("Expected the nested settings only, got %+v", s)
+	}
+}
+
+func TestDiscover_None(t *testing.T) {
+	s, err := Discover(t.TempDir())
+	if err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	if s.Path != "" || s.Account != "" {
+		t.Errorf("Expected empty settings, got %+v", s)
+	}
+}
+
+func TestDiscover_RepositoryRoot(t *testing.T) {
+	// A file above the repository, e.g. planted in a shared directory
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, FileName), "hooks:\n  pre_apply: [./planted.sh]\n")
+	repo := filepath.Join(root, "repo")
+	nested := filepath.Join(repo, "zones")
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(nested, 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Discover(nested)
+	if err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	if s.Path != "" || len(s.Hooks.PreApply) != 0 {
+		t.Errorf("Expected discovery to stop at the repository root, got %+v", s)
+	}
+}
+
+func TestDiscover_WritableByOthers(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not checked on Windows")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+	writeFile(t, path, "account: team-a\n")
+	// Set explicitly, the umask would clear the group and other bits
+	if err := os.Chmod(path, 0o666); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Discover(dir); err == nil || !strings.Contains(err.Error(), "writable by other users") {
+		t.Errorf("Expected settings writable by others to be refused, got %v", err)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"unknown key", "api_key: secret\n"},
+		{"zero ttl", "default_ttl: 0\n"},
+		{"invalid dangling targets", "dangling_targets: fail\n"},
+		{"invalid dual stack", "dual_stack: ipv6\n"},
+		{"invalid header name", "api_headers:\n  \"X Ticket\": CHG-1\n"},
+		{"reserved header", "api_headers:\n  x-api-key: secret\n"},
+		{"empty hook", "hooks:\n  post_apply: [\"\"]\n"},
+		{"unknown hook", "hooks:\n  preApply: [./check.sh]\n"},
+		{"invalid dnsdist url", "dnsdist:\n  - url: edge1:8083\n    api_key_env: DNSDIST_API_KEY\n"},
+		{"dnsdist without key", "dnsdist:\n  - url: http://edge1:8083\n"},
+		{"negative pacing", "pacing:\n  batch_size: -1\n"},
+		{"invalid pacing pause", "pacing:\n  pause: soon\n"},
+		{"negative safety threshold", "safety:\n  max_deletions: -1\n"},
+		{"invalid yaml", "account: [\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), FileName)
+			writeFile(t, path, tt.content)
+			if _, err := Load(path); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
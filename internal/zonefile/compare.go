@@ -0,0 +1,79 @@
+package zonefile
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+// Compare returns the differences between the RRsets of a zone and the same
+// zone read back from a zone file, one line per RRset. Names are compared
+// case insensitively, records and comments regardless of their order. Only
+// the content of comments is compared, as zone files do not keep their
+// account.
+func Compare(want, got *powerdns.Zone) []string {
+	wantSets, gotSets := rrsetsByKey(want), rrsetsByKey(got)
+	keys := make([]string, 0, len(wantSets)+len(gotSets))
+	for key := range wantSets {
+		keys = append(keys, key)
+	}
+	for key := range gotSets {
+		if _, ok := wantSets[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	var diffs []string
+	for _, key := range keys {
+		w, inWant := wantSets[key]
+		g, inGot := gotSets[key]
+		switch {
+		case !inGot:
+			diffs = append(diffs, key+": missing from the zone file")
+		case !inWant:
+			diffs = append(diffs, key+": not on the server")
+		case w.TTL != g.TTL:
+			diffs = append(diffs, fmt.Sprintf("%s: TTL %d, zone file has %d", key, w.TTL, g.TTL))
+		case !slices.Equal(recordStrings(w), recordStrings(g)):
+			diffs = append(diffs, fmt.Sprintf("%s: records [%s], zone file has [%s]", key,
+				strings.Join(recordStrings(w), ", "), strings.Join(recordStrings(g), ", ")))
+		case !slices.Equal(commentStrings(w), commentStrings(g)):
+			diffs = append(diffs, key+": comments differ")
+		}
+	}
+	return diffs
+}
+
+// rrsetsByKey maps the RRsets of a zone by "name TYPE".
+func rrsetsByKey(zone *powerdns.Zone) map[string]*powerdns.RRset {
+	sets := make(map[string]*powerdns.RRset, len(zone.RRsets))
+	for i := range zone.RRsets {
+		rrset := &zone.RRsets[i]
+		sets[strings.ToLower(fqdn(rrset.Name))+" "+strings.ToUpper(rrset.Type)] = rrset
+	}
+	return sets
+}
+
+func recordStrings(rrset *powerdns.RRset) []string {
+	records := make([]string, len(rrset.Records))
+	for i, rec := range rrset.Records {
+		records[i] = rec.Content
+		if rec.Disabled {
+			records[i] += disabledSuffix
+		}
+	}
+	slices.Sort(records)
+	return records
+}
+
+func commentStrings(rrset *powerdns.RRset) []string {
+	comments := make([]string, len(rrset.Comments))
+	for i, c := range rrset.Comments {
+		comments[i] = c.Content
+	}
+	slices.Sort(comments)
+	return comments
+}
@@ -0,0 +1,273 @@
+package zonefile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+const disabledSuffix = " (disabled)"
+
+// Read parses a master file of the zone origin, as written by Write. It
+// supports the $ORIGIN and $TTL directives, relative names, omitted owners,
+// TTLs and classes, and records spanning lines in parentheses. Comment lines
+// directly before the first record of an RRset become its comments, and
+// commented out records marked "(disabled)" become disabled records.
+func Read(r io.Reader, origin string) (*powerdns.Zone, error) {
+	p := &parser{origin: fqdn(origin), zone: &powerdns.Zone{Name: fqdn(origin)}}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		if err := p.line(scanner.Text()); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read zone file: %w", err)
+	}
+	if p.pending != "" {
+		return nil, fmt.Errorf("line %d: unbalanced parentheses", lineNo)
+	}
+	return p.zone, nil
+}
+
+// parser keeps the state of Read between lines.
+type parser struct {
+	zone *powerdns.Zone
+	// comments are the comment lines since the last blank line
+	comments []string
+	origin   string
+	owner    string
+	// pending is the start of a record that continues in parentheses
+	pending    string
+	defaultTTL uint32
+	lastTTL    uint32
+	hasTTL     bool
+}
+
+func (p *parser) line(line string) error {
+	if p.pending != "" {
+		line = p.pending + " " + line
+		p.pending = ""
+	}
+	text, comment := splitComment(line)
+	if strings.Count(text, "(") != strings.Count(text, ")") {
+		p.pending = text
+		return nil
+	}
+	text = strings.NewReplacer("(", " ", ")", " ").Replace(text)
+
+	if strings.TrimSpace(text) == "" {
+		if strings.TrimSpace(line) == "" {
+			p.comments = nil
+			return nil
+		}
+		content := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(comment), ";"))
+		if inner, ok := strings.CutSuffix(content, disabledSuffix); ok {
+			if err := p.record(inner, true); err == nil {
+				return nil
+			}
+		}
+		p.comments = append(p.comments, content)
+		return nil
+	}
+	if strings.HasPrefix(text, "$") {
+		p.comments = nil
+		return p.directive(strings.Fields(text))
+	}
+	return p.record(text, false)
+}
+
+func (p *parser) directive(fields []string) error {
+	switch strings.ToUpper(fields[0]) {
+	case "$ORIGIN":
+		if len(fields) != 2 {
+			return fmt.Errorf("$ORIGIN requires a name")
+		}
+		p.origin = p.absolute(fields[1])
+	case "$TTL":
+		if len(fields) != 2 {
+			return fmt.Errorf("$TTL requires a TTL")
+		}
+		ttl, err := parseTTL(fields[1])
+		if err != nil {
+			return err
+		}
+		p.defaultTTL, p.hasTTL = ttl, true
+	default:
+		return fmt.Errorf("unsupported directive %s", fields[0])
+	}
+	return nil
+}
+
+// record parses a record line without comments and parentheses.
+func (p *parser) record(text string, disabled bool) error {
+	fields := fieldOffsets(text)
+	if len(fields) == 0 {
+		return fmt.Errorf("empty record")
+	}
+	owner := p.owner
+	i := 0
+	if text[0] != ' ' && text[0] != '\t' {
+		owner = p.absolute(text[fields[0][0]:fields[0][1]])
+		i++
+	}
+	if owner == "" {
+		return fmt.Errorf("record without owner name")
+	}
+
+	var ttl uint32
+	hasTTL := false
+	for ; i < len(fields)-1; i++ {
+		token := text[fields[i][0]:fields[i][1]]
+		if strings.EqualFold(token, "IN") {
+			continue
+		}
+		value, err := parseTTL(token)
+		if err != nil || hasTTL {
+			break
+		}
+		ttl, hasTTL = value, true
+	}
+	if i >= len(fields)-1 {
+		return fmt.Errorf("record of %s has no type or data", owner)
+	}
+	switch {
+	case hasTTL:
+	case p.hasTTL:
+		ttl = p.defaultTTL
+	case p.lastTTL > 0:
+		ttl = p.lastTTL
+	default:
+		return fmt.Errorf("record of %s has no TTL and there is no $TTL", owner)
+	}
+
+	rtype := strings.ToUpper(text[fields[i][0]:fields[i][1]])
+	content := strings.Join(strings.Fields(text[fields[i+1][0]:]), " ")
+	if strings.ContainsRune(content, '"') {
+		// keep the spacing of quoted strings
+		content = strings.TrimSpace(text[fields[i+1][0]:])
+	}
+
+	p.owner, p.lastTTL = owner, ttl
+	p.add(owner, rtype, ttl, powerdns.Record{Content: content, Disabled: disabled})
+	return nil
+}
+
+// add adds a record to its RRset, creating the RRset with the pending
+// comments if it does not exist yet.
+func (p *parser) add(name, rtype string, ttl uint32, rec powerdns.Record) {
+	idx := slices.IndexFunc(p.zone.RRsets, func(r powerdns.RRset) bool {
+		return strings.EqualFold(r.Name, name) && r.Type == rtype
+	})
+	if idx < 0 {
+		p.zone.RRsets = append(p.zone.RRsets, powerdns.RRset{Name: name, Type: rtype, TTL: ttl})
+		idx = len(p.zone.RRsets) - 1
+	}
+	rrset := &p.zone.RRsets[idx]
+	for _, c := range p.comments {
+		rrset.Comments = append(rrset.Comments, powerdns.Comment{Content: c})
+	}
+	p.comments = nil
+	rrset.Records = append(rrset.Records, rec)
+}
+
+// absolute returns the fully qualified form of a name of the zone file.
+func (p *parser) absolute(name string) string {
+	switch {
+	case name == "@":
+		return p.origin
+	case strings.HasSuffix(name, "."):
+		return name
+	default:
+		return name + "." + p.origin
+	}
+}
+
+// splitComment splits a line at the first semicolon outside of quotes.
+func splitComment(line string) (text, comment string) {
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				return line[:i], line[i:]
+			}
+		}
+	}
+	return line, ""
+}
+
+// fieldOffsets returns the start and end offsets of the whitespace separated
+// fields of text, keeping quoted strings together.
+func fieldOffsets(text string) [][2]int {
+	var fields [][2]int
+	start, quoted := -1, false
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case (c == ' ' || c == '\t') && !quoted:
+			if start >= 0 {
+				fields = append(fields, [2]int{start, i})
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		fields = append(fields, [2]int{start, len(text)})
+	}
+	return fields
+}
+
+// parseTTL parses a TTL in seconds or with BIND units, e.g. 1h30m.
+func parseTTL(s string) (uint32, error) {
+	if n, err := strconv.ParseUint(s, 10, 32); err == nil {
+		return uint32(n), nil
+	}
+	units := map[byte]uint64{'s': 1, 'm': 60, 'h': 3600, 'd': 86400, 'w': 604800}
+	var total, n uint64
+	digits := false
+	for i := 0; i < len(s); i++ {
+		c := s[i] | 0x20
+		switch {
+		case s[i] >= '0' && s[i] <= '9':
+			n = n*10 + uint64(s[i]-'0')
+			digits = true
+		case units[c] > 0 && digits:
+			total += n * units[c]
+			n, digits = 0, false
+		default:
+			return 0, fmt.Errorf("invalid TTL %q", s)
+		}
+	}
+	if digits || total == 0 || total > 1<<32-1 {
+		return 0, fmt.Errorf("invalid TTL %q", s)
+	}
+	return uint32(total), nil
+}
+
+func fqdn(name string) string {
+	if !strings.HasSuffix(name, ".") {
+		return name + "."
+	}
+	return name
+}
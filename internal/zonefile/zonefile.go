@@ -0,0 +1,109 @@
+// Package zonefile renders zones as RFC 1035 master files.
+package zonefile
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+// Write renders a zone as a master file with an $ORIGIN directive and names
+// relative to the zone. The SOA comes first, then the apex NS records, then
+// the remaining RRsets sorted by name and type. RRset comments are written
+// as comment lines, one per comment, and disabled records are commented out.
+func Write(w io.Writer, zone *powerdns.Zone) error {
+	origin := zone.Name
+	if !strings.HasSuffix(origin, ".") {
+		origin += "."
+	}
+
+	rrsets := sortedRRsets(zone.RRsets, origin)
+	width := 1
+	for i := range rrsets {
+		width = max(width, len(relativeName(rrsets[i].Name, origin)))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "; Zone %s generated by powerdns-zone-manager\n", origin)
+	fmt.Fprintf(&b, "$ORIGIN %s\n", origin)
+
+	for _, rrset := range rrsets {
+		b.WriteString("\n")
+		for _, comment := range rrset.Comments {
+			fmt.Fprintf(&b, "; %s\n", escapeComment(comment.Content))
+		}
+		name := relativeName(rrset.Name, origin)
+		for _, rec := range rrset.Records {
+			line := fmt.Sprintf("%-*s %-6d IN %-6s %s", width, name, rrset.TTL, rrset.Type, rec.Content)
+			if rec.Disabled {
+				line = "; " + line + " (disabled)"
+			}
+			b.WriteString(line + "\n")
+		}
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write zone file: %w", err)
+	}
+	return nil
+}
+
+// escapeComment escapes the control characters of a comment as \DDD, so
+// that a comment written by another tool cannot continue on a line that
+// reads as a record or an ownership marker.
+func escapeComment(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x20 || c == 0x7f {
+			fmt.Fprintf(&b, "\\%03d", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// sortedRRsets orders RRsets as they appear in the zone file.
+func sortedRRsets(rrsets []powerdns.RRset, origin string) []powerdns.RRset {
+	rank := func(r *powerdns.RRset) int {
+		switch {
+		case r.Type == "SOA":
+			return 0
+		case r.Type == "NS" && strings.EqualFold(r.Name, origin):
+			return 1
+		default:
+			return 2
+		}
+	}
+
+	sorted := append([]powerdns.RRset(nil), rrsets...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ri, rj := rank(&sorted[i]), rank(&sorted[j])
+		if ri != rj {
+			return ri < rj
+		}
+		if sorted[i].Name != sorted[j].Name {
+			return sorted[i].Name < sorted[j].Name
+		}
+		return sorted[i].Type < sorted[j].Type
+	})
+	return sorted
+}
+
+// relativeName returns name relative to origin, "@" for the apex.
+// Names outside the origin stay fully qualified.
+func relativeName(name, origin string) string {
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	if strings.EqualFold(name, origin) {
+		return "@"
+	}
+	if len(name) > len(origin) && strings.EqualFold(name[len(name)-len(origin)-1:], "."+origin) {
+		return name[:len(name)-len(origin)-1]
+	}
+	return name
+}
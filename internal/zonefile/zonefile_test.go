@@ -0,0 +1,196 @@
+package zonefile
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kreigan/powerdns-zone-manager/internal/powerdns"
+)
+
+func TestWrite(t *testing.T) {
+	zone := &powerdns.Zone{
+		Name: "example.com.",
+		RRsets: []powerdns.RRset{
+			{
+				Name: "www.example.com.", Type: "A", TTL: 300,
+				Records: []powerdns.Record{
+					{Content: "192.0.2.1"},
+					{Content: "192.0.2.2", Disabled: true},
+				},
+				Comments: []powerdns.Comment{
+					{Content: "web servers"},
+					{Content: "by another tool\nwww 300 IN A 198.51.100.1"},
+				},
+			},
+			{
+				Name: "example.com.", Type: "NS", TTL: 3600,
+				Records: []powerdns.Record{{Content: "ns1.example.com."}},
+			},
+			{
+				Name: "example.com.", Type: "MX", TTL: 3600,
+				Records: []powerdns.Record{{Content: "10 mail.example.com."}},
+			},
+			{
+				Name: "example.com.", Type: "SOA", TTL: 3600,
+				Records: []powerdns.Record{{Content: "ns1.example.com. hostmaster.example.com. 1 10800 3600 604800 3600"}},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, zone); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	expected := `; Zone example.com. generated by powerdns-zone-manager
+$ORIGIN example.com.
+
+@   3600   IN SOA    ns1.example.com. hostmaster.example.com. 1 10800 3600 604800 3600
+
+@   3600   IN NS     ns1.example.com.
+
+@   3600   IN MX     10 mail.example.com.
+
+; web servers
+; by another tool\010www 300 IN A 198.51.100.1
+www 300    IN A      192.0.2.1
+; www 300    IN A      192.0.2.2 (disabled)
+`
+	if buf.String() != expected {
+		t.Errorf("Unexpected zone file:\n%s\nwant:\n%s", buf.String(), expected)
+	}
+}
+
+func TestRelativeName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"example.com.", "@"},
+		{"www.example.com.", "www"},
+		{"a.b.example.com", "a.b"},
+		{"www.example.org.", "www.example.org."},
+		{"badexample.com.", "badexample.com."},
+	}
+
+	for _, tt := range tests {
+		if got := relativeName(tt.name, "example.com."); got != tt.expected {
+			t.Errorf("relativeName(%q) = %q, want %q", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestRead_RoundTrip(t *testing.T) {
+	zone := &powerdns.Zone{
+		Name: "example.com.",
+		RRsets: []powerdns.RRset{
+			{
+				Name: "example.com.", Type: "SOA", TTL: 3600,
+				Records: []powerdns.Record{
+					{Content: "ns1.example.com. hostmaster.example.com. 1 10800 3600 604800 3600"},
+				},
+			},
+			{
+				Name: "www.example.com.", Type: "A", TTL: 300,
+				Records: []powerdns.Record{
+					{Content: "192.0.2.1"},
+					{Content: "192.0.2.2", Disabled: true},
+				},
+				Comments: []powerdns.Comment{{Content: "web servers", Account: "zone-manager"}},
+			},
+			{
+				Name: "txt.example.com.", Type: "TXT", TTL: 60,
+				Records: []powerdns.Record{{Content: `"v=spf1 -all; with  spaces"`, Disabled: true}},
+			},
+			{
+				Name: "mail.example.org.", Type: "A", TTL: 300,
+				Records: []powerdns.Record{{Content: "192.0.2.25"}},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, zone); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	parsed, err := Read(&buf, "example.com")
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if diffs := Compare(zone, parsed); len(diffs) > 0 {
+		t.Errorf("Zone file is not equivalent:\n%s", strings.Join(diffs, "\n"))
+	}
+}
+
+func TestRead(t *testing.T) {
+	content := `$TTL 1h
+@       IN SOA ns1 hostmaster (
+            1     ; serial
+            3h 1h 1w 1h )
+        IN NS  ns1
+ns1  300 IN A   192.0.2.1
+             A  192.0.2.2
+$ORIGIN sub.example.com.
+www          CNAME @
+`
+	zone, err := Read(strings.NewReader(content), "example.com.")
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	expected := &powerdns.Zone{Name: "example.com.", RRsets: []powerdns.RRset{
+		{Name: "example.com.", Type: "SOA", TTL: 3600,
+			Records: []powerdns.Record{{Content: "ns1 hostmaster 1 3h 1h 1w 1h"}}},
+		{Name: "example.com.", Type: "NS", TTL: 3600, Records: []powerdns.Record{{Content: "ns1"}}},
+		{Name: "ns1.example.com.", Type: "A", TTL: 300,
+			Records: []powerdns.Record{{Content: "192.0.2.1"}, {Content: "192.0.2.2"}}},
+		{Name: "www.sub.example.com.", Type: "CNAME", TTL: 3600, Records: []powerdns.Record{{Content: "@"}}},
+	}}
+	if diffs := Compare(expected, zone); len(diffs) > 0 {
+		t.Errorf("Unexpected zone:\n%s", strings.Join(diffs, "\n"))
+	}
+}
+
+func TestRead_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"no ttl", "www IN A 192.0.2.1\n", "line 1: record of www.example.com. has no TTL"},
+		{"no owner", "  300 IN A 192.0.2.1\n", "record without owner name"},
+		{"include", "$INCLUDE other.zone\n", "unsupported directive $INCLUDE"},
+		{"invalid ttl", "$TTL 1x\n", `invalid TTL "1x"`},
+		{"parentheses", "@ 300 IN SOA ns1 hostmaster (1\n", "unbalanced parentheses"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Read(strings.NewReader(tt.content), "example.com."); err == nil ||
+				!strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	want := &powerdns.Zone{Name: "example.com.", RRsets: []powerdns.RRset{
+		{Name: "a.example.com.", Type: "A", TTL: 300, Records: []powerdns.Record{{Content: "192.0.2.1"}}},
+		{Name: "b.example.com.", Type: "A", TTL: 300, Records: []powerdns.Record{{Content: "192.0.2.2"}}},
+		{Name: "c.example.com.", Type: "A", TTL: 300, Records: []powerdns.Record{{Content: "192.0.2.3"}}},
+	}}
+	got := &powerdns.Zone{Name: "example.com.", RRsets: []powerdns.RRset{
+		{Name: "A.example.com.", Type: "a", TTL: 300, Records: []powerdns.Record{{Content: "192.0.2.1"}}},
+		{Name: "b.example.com.", Type: "A", TTL: 600, Records: []powerdns.Record{{Content: "192.0.2.2"}}},
+		{Name: "d.example.com.", Type: "A", TTL: 300, Records: []powerdns.Record{{Content: "192.0.2.4"}}},
+	}}
+	expected := []string{
+		"b.example.com. A: TTL 300, zone file has 600",
+		"c.example.com. A: missing from the zone file",
+		"d.example.com. A: not on the server",
+	}
+	if diffs := Compare(want, got); !slices.Equal(diffs, expected) {
+		t.Errorf("Unexpected differences:\n%s", strings.Join(diffs, "\n"))
+	}
+}
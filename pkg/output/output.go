@@ -0,0 +1,197 @@
+// Package output defines the JSON output of powerdns-zone-manager (--json):
+// one Entry per line, with typed data for the entries of apply runs.
+//
+// The schema is versioned with Entry.SchemaVersion. Within a schema version,
+// fields are only added: they are never renamed, removed or changed in type,
+// so parsers built on these types keep working with newer releases. Changes
+// that break this increment SchemaVersion.
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SchemaVersion is the current schema version. Entries of releases before
+// the schema was versioned have no schemaVersion (0) and the layout of
+// version 1.
+const SchemaVersion = 1
+
+// Messages of the entries with typed data.
+const (
+	// MessageApplyCompleted entries have ApplyCompleted data.
+	MessageApplyCompleted = "Apply completed"
+	// MessageApplyEvent entries have ApplyEvent data.
+	MessageApplyEvent = "Apply event"
+	// MessageAPIPerformance entries have APIPerformance data.
+	MessageAPIPerformance = "API performance"
+	// MessageSinceLast entries have SinceLast data.
+	MessageSinceLast = "Changes since last apply"
+)
+
+// Entry is a single line of JSON output. Data depends on the Message; in dry
+// runs it includes "dryRun": true for every entry.
+type Entry struct {
+	Timestamp     time.Time       `json:"timestamp"`
+	Data          json.RawMessage `json:"data,omitempty"`
+	Level         string          `json:"level"`
+	Message       string          `json:"message"`
+	RunID         string          `json:"runId,omitempty"`
+	Partition     string          `json:"partition,omitempty"` // set by partitioned applies
+	SchemaVersion int             `json:"schemaVersion"`
+}
+
+// Decode parses a line of JSON output. Entries of newer, incompatible schema
+// versions are rejected.
+func Decode(line []byte) (*Entry, error) {
+	var e Entry
+	if err := json.Unmarshal(line, &e); err != nil {
+		return nil, fmt.Errorf("failed to parse output entry: %w", err)
+	}
+	if e.SchemaVersion > SchemaVersion {
+		return nil, fmt.Errorf("unsupported output schema version %d, expected %d or lower",
+			e.SchemaVersion, SchemaVersion)
+	}
+	return &e, nil
+}
+
+// DecodeData decodes the entry data into v, e.g. *ApplyCompleted for
+// MessageApplyCompleted entries.
+func (e *Entry) DecodeData(v interface{}) error {
+	if len(e.Data) == 0 {
+		return fmt.Errorf("entry %q has no data", e.Message)
+	}
+	if err := json.Unmarshal(e.Data, v); err != nil {
+		return fmt.Errorf("failed to parse data of entry %q: %w", e.Message, err)
+	}
+	return nil
+}
+
+// DryRun returns true if the entry was written by a dry run.
+func (e *Entry) DryRun() bool {
+	var data struct {
+		DryRun bool `json:"dryRun"`
+	}
+	if len(e.Data) == 0 || json.Unmarshal(e.Data, &data) != nil {
+		return false
+	}
+	return data.DryRun
+}
+
+// ApplyCompleted is the data of MessageApplyCompleted entries: the totals and
+// per-zone results of an apply run.
+type ApplyCompleted struct {
+	Zones         []ZoneResult `json:"zones"`
+	ZonesCreated  int          `json:"zonesCreated"`
+	RRsetsCreated int          `json:"rrsetsCreated"`
+	RRsetsUpdated int          `json:"rrsetsUpdated"`
+	RRsetsDeleted int          `json:"rrsetsDeleted"`
+	Scheduled     int          `json:"scheduled"`
+	Metadata      int          `json:"metadata"`
+	// PatchRecords and PatchBytes are the number of records and the size of
+	// the patches of all zones
+	PatchRecords int `json:"patchRecords"`
+	PatchBytes   int `json:"patchBytes"`
+}
+
+// ZoneResult is the result of applying a single zone.
+type ZoneResult struct {
+	// Labels are the config labels of the zone
+	Labels map[string]string `json:"labels,omitempty"`
+	Zone   string            `json:"zone"`
+	// Status is ok, failed, skipped, scheduled or completed (applied by the
+	// resumed run)
+	Status        string `json:"status"`
+	Error         string `json:"error,omitempty"`
+	RRsetsCreated int    `json:"rrsetsCreated"`
+	RRsetsUpdated int    `json:"rrsetsUpdated"`
+	RRsetsDeleted int    `json:"rrsetsDeleted"`
+	Scheduled     int    `json:"scheduled"`
+	Metadata      int    `json:"metadata"`
+	// PatchRecords and PatchBytes are the number of records and the size of
+	// the JSON bodies of the patches of the zone
+	PatchRecords int   `json:"patchRecords"`
+	PatchBytes   int   `json:"patchBytes"`
+	DurationMs   int64 `json:"durationMs"`
+	ZoneCreated  bool  `json:"zoneCreated"`
+}
+
+// Apply event types.
+const (
+	EventZoneStarted  = "zone_started"
+	EventZoneCreated  = "zone_created"
+	EventRRset        = "rrset"
+	EventPatchSent    = "patch_sent"
+	EventZoneFinished = "zone_finished"
+)
+
+// ApplyEvent is the data of MessageApplyEvent entries, streamed while zones
+// are applied.
+type ApplyEvent struct {
+	Event string `json:"event"`
+	Zone  string `json:"zone"`
+	// Labels are the config labels of the rrset for EventRRset
+	Labels map[string]string `json:"labels,omitempty"`
+	// Action (create, update or delete), Name and Type are set for EventRRset
+	Action string `json:"action,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Type   string `json:"type,omitempty"`
+	// Status, Error and DurationMs are set for EventZoneFinished
+	Status     string `json:"status,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs,omitempty"`
+	// RRsets is the number of rrsets in the patch for EventPatchSent
+	RRsets int `json:"rrsets,omitempty"`
+}
+
+// APIPerformance is the data of MessageAPIPerformance entries.
+type APIPerformance struct {
+	Requests []RequestStats `json:"requests"`
+}
+
+// RequestStats are the timings of the API requests of a method.
+type RequestStats struct {
+	Method    string  `json:"method"`
+	Count     int     `json:"count"`
+	Errors    int     `json:"errors"`
+	ErrorRate float64 `json:"errorRate"`
+	MinMs     int64   `json:"minMs"`
+	AvgMs     int64   `json:"avgMs"`
+	MaxMs     int64   `json:"maxMs"`
+}
+
+// SinceLast is the data of MessageSinceLast entries: the configuration
+// changes since the last recorded apply.
+type SinceLast struct {
+	AppliedAt time.Time `json:"appliedAt"`
+	// Result are the totals of the last apply
+	Result    *ApplyTotals `json:"result,omitempty"`
+	Diff      Diff         `json:"diff"`
+	RunID     string       `json:"runId"`
+	StateHash string       `json:"stateHash"`
+}
+
+// ApplyTotals are the totals of an apply run.
+type ApplyTotals struct {
+	ZonesCreated  int `json:"zonesCreated"`
+	RRsetsCreated int `json:"rrsetsCreated"`
+	RRsetsUpdated int `json:"rrsetsUpdated"`
+	RRsetsDeleted int `json:"rrsetsDeleted"`
+}
+
+// Diff lists the zones and rrsets ("name TYPE") that differ between two
+// configurations.
+type Diff struct {
+	ZonesAdded   []string   `json:"zonesAdded,omitempty"`
+	ZonesRemoved []string   `json:"zonesRemoved,omitempty"`
+	Zones        []ZoneDiff `json:"zones,omitempty"`
+}
+
+// ZoneDiff lists the rrsets of a zone that differ between two configurations.
+type ZoneDiff struct {
+	Zone    string   `json:"zone"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+}
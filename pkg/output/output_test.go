@@ -0,0 +1,59 @@
+package output
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Entries as written by schema version 1. Fields of version 1 must keep
+// decoding like this in every later release of the same schema version.
+const (
+	appliedV1 = `{"data":{"dryRun":true,"metadata":0,"rrsetsCreated":1,"rrsetsDeleted":0,"rrsetsUpdated":0,` +
+		`"scheduled":0,"zones":[{"durationMs":12,"metadata":0,"rrsetsCreated":1,"rrsetsDeleted":0,` +
+		`"rrsetsUpdated":0,"scheduled":0,"status":"ok","zone":"example.com.","zoneCreated":true}],` +
+		`"zonesCreated":1},"timestamp":"2026-10-17T00:00:00Z","level":"info","message":"Apply completed",` +
+		`"runId":"run-1","schemaVersion":1}`
+	// Entries of releases before the schema was versioned
+	eventLegacy = `{"data":{"event":"rrset","zone":"example.com.","action":"create","name":"www.example.com.",` +
+		`"type":"A"},"timestamp":"2026-10-17T00:00:00Z","level":"info","message":"Apply event"}`
+)
+
+func TestDecode(t *testing.T) {
+	entry, err := Decode([]byte(appliedV1))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if entry.Message != MessageApplyCompleted || entry.RunID != "run-1" || !entry.DryRun() {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+	var applied ApplyCompleted
+	if err := entry.DecodeData(&applied); err != nil {
+		t.Fatalf("DecodeData failed: %v", err)
+	}
+	expected := ApplyCompleted{
+		ZonesCreated:  1,
+		RRsetsCreated: 1,
+		Zones: []ZoneResult{{
+			Zone: "example.com.", Status: "ok", RRsetsCreated: 1, DurationMs: 12, ZoneCreated: true,
+		}},
+	}
+	if !reflect.DeepEqual(applied, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, applied)
+	}
+
+	entry, err = Decode([]byte(eventLegacy))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	var event ApplyEvent
+	if err := entry.DecodeData(&event); err != nil {
+		t.Fatalf("DecodeData failed: %v", err)
+	}
+	if event.Event != EventRRset || event.Name != "www.example.com." || entry.DryRun() {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+
+	if _, err := Decode([]byte(`{"message":"x","schemaVersion":2}`)); err == nil {
+		t.Error("Expected error for a newer schema version")
+	}
+}